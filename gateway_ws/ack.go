@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Control messages may ask to be acknowledged, for pages and alerts that
+// must get through. A client relaying one sets an id and "ack":true; the
+// server does the same for its own, such as an announcement posted with
+// ?ack=true. The receiver answers with {"type":"ack","id":...}. Unanswered,
+// the message is sent again on the same connection every ack timeout, up to
+// the retry limit, and the originator is then told it failed. Audio is
+// never acknowledged.
+const (
+	defaultAckTimeout = 5 * time.Second
+	defaultAckRetries = 3
+)
+
+// Why an acknowledged message failed
+const (
+	ackTimedOut     = "timed out"
+	ackDisconnected = "receiver disconnected"
+	ackUnsupported  = "receiver doesn't acknowledge"
+)
+
+// errAckPending refuses a message whose id already awaits the receiver's
+// acknowledgement
+var errAckPending = errors.New("a message with this id already awaits acknowledgement")
+
+// WithAckPolicy sets how long a receiver has to acknowledge a message, and
+// how many times it is sent again before the originator is told it
+// failed. Zero leaves a setting at its default.
+func WithAckPolicy(timeout time.Duration, retries int) GatewayOption {
+	return func(g *Gateway) {
+		if timeout > 0 {
+			g.ackTimeout = timeout
+		}
+		if retries >= 0 {
+			g.ackRetries = retries
+		}
+	}
+}
+
+// pendingAck is a message sent to a client and not yet acknowledged
+type pendingAck struct {
+	// send queues the message for the client again
+	send  func()
+	timer *time.Timer
+	sent  int
+
+	// done is called once, with "" once the client acknowledges the
+	// message or with why it failed
+	done func(failure string)
+}
+
+// ackTracker holds the messages sent to a client awaiting acknowledgement,
+// by id
+type ackTracker struct {
+	mutex   sync.Mutex
+	pending map[string]*pendingAck
+	closed  bool
+}
+
+// expectAck tracks a message about to be sent to the client until the
+// client acknowledges id, or it has been sent again with resend the
+// gateway's retries times without, when done is told why. The caller sends
+// it the first time once expectAck returns without error, so an early
+// acknowledgement isn't missed. A client without the acks capability gets
+// the message once, and done is told at once.
+func (c *Client) expectAck(id string, resend func(), done func(failure string)) error {
+	if !c.caps.has(capAcks) {
+		done(ackUnsupported)
+		return nil
+	}
+	t := &c.acks
+	p := &pendingAck{send: resend, done: done}
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		done(ackDisconnected)
+		return nil
+	}
+	if t.pending[id] != nil {
+		t.mutex.Unlock()
+		return errAckPending
+	}
+	if t.pending == nil {
+		t.pending = make(map[string]*pendingAck)
+	}
+	t.pending[id] = p
+	p.sent = 1
+	p.timer = time.AfterFunc(c.gateway.ackTimeout, func() { c.retryAck(id, p) })
+	t.mutex.Unlock()
+
+	c.hub.acksRequested.Add(1)
+	return nil
+}
+
+// retryAck sends an unacknowledged message again, or gives up on it after
+// the gateway's retries
+func (c *Client) retryAck(id string, p *pendingAck) {
+	t := &c.acks
+	t.mutex.Lock()
+	if t.pending[id] != p {
+		t.mutex.Unlock()
+		return
+	}
+	if p.sent > c.gateway.ackRetries {
+		t.mutex.Unlock()
+		c.settleAck(id, p, ackTimedOut)
+		return
+	}
+	retry := p.sent
+	p.sent++
+	p.timer.Reset(c.gateway.ackTimeout)
+	t.mutex.Unlock()
+
+	c.hub.ackRetries.Add(1)
+	c.gateway.debugf("Client %s has not acknowledged %s, sending it again (%d of %d)", c.id, id, retry, c.gateway.ackRetries)
+	p.send()
+}
+
+// settleAck stops tracking a message, if p is still its pending entry, and
+// tells its originator the outcome
+func (c *Client) settleAck(id string, p *pendingAck, failure string) {
+	t := &c.acks
+	t.mutex.Lock()
+	if t.pending[id] != p {
+		t.mutex.Unlock()
+		return
+	}
+	delete(t.pending, id)
+	p.timer.Stop()
+	t.mutex.Unlock()
+
+	if failure != "" {
+		c.hub.ackFailures.Add(1)
+		log.Printf("Client %s did not acknowledge %s in room %s: %s", c.id, id, c.hub.room, failure)
+	}
+	p.done(failure)
+}
+
+// abandonAcks fails every message still awaiting the client's
+// acknowledgement, once it has left
+func (c *Client) abandonAcks() {
+	t := &c.acks
+	t.mutex.Lock()
+	t.closed = true
+	pending := t.pending
+	t.pending = nil
+	t.mutex.Unlock()
+
+	for id, p := range pending {
+		p.timer.Stop()
+		c.hub.ackFailures.Add(1)
+		c.gateway.debugf("Client %s left with %s unacknowledged", c.id, id)
+		p.done(ackDisconnected)
+	}
+}
+
+// ack carries the id of the message acknowledged, and for a multi-room
+// client its room
+func init() {
+	registerControl("ack", controlHandler{
+		validate: requireField("id", func(m controlMessage) bool { return m.ID != "" }),
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.acknowledge(m.ID)
+			}
+		},
+	})
+}
+
+// acknowledge settles the message id sent to the client. An id that isn't
+// pending, such as one that already failed, is ignored.
+func (c *Client) acknowledge(id string) {
+	c.acks.mutex.Lock()
+	p := c.acks.pending[id]
+	c.acks.mutex.Unlock()
+	if p != nil {
+		c.settleAck(id, p, "")
+	}
+}
+
+// ackMessage tells the originator of a relayed message that From
+// acknowledged it
+type ackMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Room string `json:"room"`
+	From string `json:"from"`
+}
+
+// relayAcked relays a request message from sender that asked for
+// acknowledgement to target, and tells sender whether target acknowledged
+// it
+func (h *Hub) relayAcked(sender, target *Client, request, id string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding control message for client %s: %v", target.id, err)
+		return
+	}
+	send := func() {
+		h.shardFor(target).send(h, target, outbound{messageType: websocket.TextMessage, data: data})
+	}
+	err = target.expectAck(id, send, func(failure string) {
+		if failure == "" {
+			h.sendControl(sender, ackMessage{Type: "ack", ID: id, Room: localRoom(h.room), From: target.id})
+			return
+		}
+		h.sendControl(sender, controlError{Type: "error", Code: "not_acknowledged", Request: request, Target: target.id, ID: id, Detail: failure})
+	})
+	if err != nil {
+		h.sendControl(sender, controlError{Type: "error", Code: "invalid", Request: request, Target: target.id, ID: id, Detail: err.Error()})
+		return
+	}
+	send()
+}
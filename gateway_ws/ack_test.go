@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// countControl reads the connection until it is quiet for wait, counting
+// the text messages of the given type
+func countControl(conn *websocket.Conn, typ string, wait time.Duration) int {
+	n := 0
+	for {
+		conn.SetReadDeadline(time.Now().Add(wait))
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return n
+		}
+		var message map[string]any
+		if messageType == websocket.TextMessage && json.Unmarshal(data, &message) == nil && message["type"] == typ {
+			n++
+		}
+	}
+}
+
+// A key exchange asking for acknowledgement is sent again every ack timeout
+// until the receiver acknowledges it, when the sender is told, or until the
+// retries run out or the receiver leaves, when the sender is told it failed
+func TestAckRelay(t *testing.T) {
+	const (
+		timeout = 100 * time.Millisecond
+		retries = 2
+	)
+	tests := []struct {
+		name string
+		path string
+		// How many copies the receiver ignores before acknowledging one,
+		// -1 for never
+		ignores int
+		leaves  bool
+		failure string
+	}{
+		{"acknowledged at once", "/ws", 0, false, ""},
+		{"acknowledged after a retry", "/ws", 1, false, ""},
+		{"never acknowledged", "/ws", -1, false, ackTimedOut},
+		{"receiver leaves", "/ws", -1, true, ackDisconnected},
+		{"receiver without acks", "/ws?capabilities=chat", -1, false, ackUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGateway(t, nil, WithAckPolicy(timeout, retries))
+			sender, _ := tg.dial(t, "/ws", "a")
+			readControl(t, sender, "joined")
+			receiver, _ := tg.dial(t, tt.path, "b")
+			readControl(t, receiver, "joined")
+			hub := tg.hub(t, defaultRoom)
+
+			keyx := map[string]any{"type": "keyx", "target": "b", "payload": map[string]string{"k": "v"}, "id": "k1", "ack": true}
+			if err := sender.WriteJSON(keyx); err != nil {
+				t.Fatal(err)
+			}
+			if tt.ignores >= 0 {
+				for i := 0; i <= tt.ignores; i++ {
+					if relayed := readControl(t, receiver, "keyx"); relayed["id"] != "k1" || relayed["ack"] != true {
+						t.Fatalf("relayed %v, want it to ask for acknowledgement of k1", relayed)
+					}
+				}
+				if err := receiver.WriteJSON(map[string]any{"type": "ack", "id": "k1"}); err != nil {
+					t.Fatal(err)
+				}
+				if ack := readControl(t, sender, "ack"); ack["id"] != "k1" || ack["from"] != "b" {
+					t.Errorf("sender told %v, want b acknowledging k1", ack)
+				}
+				stats := hub.Stats()
+				if stats.AckRetries != uint64(tt.ignores) || stats.AckFailures != 0 {
+					t.Errorf("%d retries and %d failures, want %d and none", stats.AckRetries, stats.AckFailures, tt.ignores)
+				}
+				return
+			}
+
+			if tt.leaves {
+				readControl(t, receiver, "keyx")
+				receiver.Close()
+			}
+			failed := readControl(t, sender, "error")
+			if failed["code"] != "not_acknowledged" || failed["id"] != "k1" || failed["target"] != "b" || failed["detail"] != tt.failure {
+				t.Errorf("sender told %v, want k1 to b failing with %q", failed, tt.failure)
+			}
+			if tt.leaves {
+				return
+			}
+			// Sent once and then once a retry, or just once to a receiver
+			// that doesn't acknowledge
+			want := 1 + retries
+			if tt.failure == ackUnsupported {
+				want = 1
+			}
+			if copies := countControl(receiver, "keyx", 2*timeout); copies != want {
+				t.Errorf("receiver got %d copies, want %d", copies, want)
+			}
+			if tt.failure == ackTimedOut {
+				if stats := hub.Stats(); stats.AckRetries != retries || stats.AckFailures != 1 {
+					t.Errorf("%d retries and %d failures, want %d and 1", stats.AckRetries, stats.AckFailures, retries)
+				}
+			}
+		})
+	}
+}
+
+// An announcement posted with ?ack=true answers once every client has
+// acknowledged it or run out of retries, naming those that never did
+func TestAnnouncementAcks(t *testing.T) {
+	tg := newTestGateway(t, nil, WithAdminToken("secret"), WithAckPolicy(100*time.Millisecond, 1))
+	acker, _ := tg.dial(t, "/ws", "acker")
+	readControl(t, acker, "joined")
+	silent, _ := tg.dial(t, "/ws", "silent")
+	readControl(t, silent, "joined")
+	go func() {
+		for {
+			_, data, err := acker.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message announcementMessage
+			if json.Unmarshal(data, &message) == nil && message.Type == "announcement" {
+				acker.WriteJSON(map[string]any{"type": "ack", "id": message.ID})
+			}
+		}
+	}()
+	go drain(silent, nil, nil)
+
+	request, err := http.NewRequest(http.MethodPost, tg.server.URL+"/announce?ack=true", strings.NewReader("evacuate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer secret")
+	request.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /announce: %s", resp.Status)
+	}
+	var result announcementResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Delivered != 2 || result.Acked == nil || *result.Acked != 1 {
+		t.Errorf("delivered %d and acknowledged by %v, want 2 and 1", result.Delivered, result.Acked)
+	}
+	want := []UnackedDelivery{{Room: defaultRoom, ID: "silent", Reason: ackTimedOut}}
+	if len(result.Unacked) != 1 || result.Unacked[0] != want[0] {
+		t.Errorf("unacknowledged by %v, want %v", result.Unacked, want)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// activitySeconds is the span of a hub's recent activity counters
+const activitySeconds = 60
+
+// activityBucket counts the broadcasts of one second
+type activityBucket struct {
+	second   atomic.Int64
+	messages atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// activityWindow counts the broadcasts of the last minute in one bucket per
+// second. Only the Run goroutine records; anyone may read it without
+// blocking Run, at the cost of a bucket being read mid-reset.
+type activityWindow struct {
+	buckets [activitySeconds]activityBucket
+
+	// When the last audio frame was broadcast, in Unix nanoseconds
+	lastAudio atomic.Int64
+}
+
+// record counts a broadcast. Only the Run goroutine may call it.
+func (a *activityWindow) record(message BroadcastMessage, now time.Time) {
+	second := now.Unix()
+	b := &a.buckets[second%activitySeconds]
+	if b.second.Load() != second {
+		b.messages.Store(0)
+		b.bytes.Store(0)
+		b.second.Store(second)
+	}
+	b.messages.Add(1)
+	b.bytes.Add(uint64(len(message.frame.data)))
+	if message.frame.messageType == websocket.BinaryMessage {
+		a.lastAudio.Store(now.UnixNano())
+	}
+}
+
+// lastMinute returns the broadcasts and bytes of the last minute
+func (a *activityWindow) lastMinute(now time.Time) (messages, bytes uint64) {
+	since := now.Unix() - activitySeconds
+	for i := range a.buckets {
+		b := &a.buckets[i]
+		if b.second.Load() > since {
+			messages += b.messages.Load()
+			bytes += b.bytes.Load()
+		}
+	}
+	return messages, bytes
+}
+
+// transmitting reports whether anyone in the hub is holding the talk floor
+func (a *activityWindow) transmitting(now time.Time) bool {
+	return now.Sub(time.Unix(0, a.lastAudio.Load())) < talkerQuiet
+}
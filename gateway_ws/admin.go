@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultDisconnectReason is the close frame text of a client the admin
+// disconnects without giving a reason
+const defaultDisconnectReason = "disconnected by admin"
+
+// maxCloseReason is the longest close frame text: a control frame's payload
+// is at most 125 bytes, two of them the code
+const maxCloseReason = 123
+
+// WithAdminToken protects the management endpoints with a bearer token.
+// Without one they are open, as they always were, unless there are tenant
+// keys: then only a key opens them.
+func WithAdminToken(token string) GatewayOption {
+	return func(g *Gateway) {
+		g.adminToken = token
+	}
+}
+
+// requireAdmin wraps a management endpoint so it answers 401 unless the
+// request carries the admin token as "Authorization: Bearer <token>". A
+// tenant's API key, in the same header or as X-API-Key, is let through
+// scoped to that tenant. Without an admin token the endpoints are open,
+// unless the gateway has tenant keys: a request without one is refused
+// too, or it would see every tenant's resources.
+func (g *Gateway) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !bearer {
+			token = ""
+		}
+		if g.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(g.adminToken)) == 1 {
+			next(w, r)
+			return
+		}
+		presented := r.Header.Get("X-API-Key")
+		if presented == "" {
+			presented = token
+		}
+		if key := g.keys.lookup(presented); key != nil {
+			next(w, withTenantScope(r, key.Tenant))
+			return
+		}
+		if g.adminToken != "" || g.keys != nil {
+			g.audit.record(AuditEvent{Event: "admin_auth", RemoteIP: auditIP(g.proxies.clientIP(r)), Outcome: auditDenied, Reason: r.Method + " " + r.URL.Path})
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// serveStats reports the gateway's stats, or just a tenant's when the
+// request is scoped to one
+func (g *Gateway) serveStats(w http.ResponseWriter, r *http.Request) {
+	if tenant := scopedTenant(r); tenant != "" {
+		writeJSON(w, http.StatusOK, g.TenantStats(tenant))
+		return
+	}
+	writeJSON(w, http.StatusOK, g.Stats())
+}
+
+// serveClients reports every client's stats, or just a tenant's
+func (g *Gateway) serveClients(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, g.Clients(scopedTenant(r)))
+}
+
+// DisconnectedClient is a connection the admin closed
+type DisconnectedClient struct {
+	ID         string `json:"id"`
+	Room       string `json:"room"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// disconnectResult is the body of a successful disconnect: what was closed,
+// and the ban added if one was asked for
+type disconnectResult struct {
+	Closed []DisconnectedClient `json:"closed"`
+	Ban    *Ban                 `json:"ban,omitempty"`
+}
+
+// disconnectClient closes every connection of the client with the ID, or
+// just the one in roomName if it isn't empty, with the reason. A multi-room
+// client's connection is closed whichever of its rooms it is found in. It is
+// safe against the client leaving at the same time: leave only takes effect
+// once.
+func (g *Gateway) disconnectClient(id, roomName string, reason closeReason) []DisconnectedClient {
+	closed := []DisconnectedClient{}
+	g.eachRoom(func(rm *room) {
+		if roomName != "" && rm.name != roomName {
+			return
+		}
+		client := rm.hub.lookup(id)
+		if client == nil {
+			return
+		}
+		if client.owner != nil {
+			if roomName == "" {
+				// Found again in its own room
+				return
+			}
+			client = client.owner
+		}
+		client.leave(reason)
+		disconnected := DisconnectedClient{ID: client.id, Room: client.hub.room}
+		if client.remoteIP != nil {
+			disconnected.RemoteAddr = client.remoteIP.String()
+		}
+		closed = append(closed, disconnected)
+	})
+	return closed
+}
+
+// serveAdminClient routes /admin/clients/{id}/tags to serveClientTags, and
+// the rest of /admin/clients/ to serveDisconnect
+func (g *Gateway) serveAdminClient(w http.ResponseWriter, r *http.Request) {
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/admin/clients/"), "/tags"); ok && id != "" {
+		g.serveClientTags(w, r, id)
+		return
+	}
+	g.serveDisconnect(w, r)
+}
+
+// serveDisconnect closes a client's connections (DELETE /admin/clients/{id}),
+// or its connection in one room with ?room=, sending ?reason= in the close
+// frame. ?ban= with a Go duration such as "1h" also bans the client ID for
+// that long. It answers 404 if the client isn't connected, and is for the
+// gateway's operator, not its tenants.
+func (g *Gateway) serveDisconnect(w http.ResponseWriter, r *http.Request) {
+	if tenantScoped(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/admin/clients/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+
+	room := ""
+	if name := query.Get("room"); name != "" {
+		var ok bool
+		room, ok = scopedRoom(r, name)
+		if !ok {
+			http.Error(w, "invalid room name", http.StatusBadRequest)
+			return
+		}
+	}
+	reason := reasonKicked
+	reason.text = defaultDisconnectReason
+	if text := query.Get("reason"); text != "" {
+		if len(text) > maxCloseReason {
+			http.Error(w, "reason longer than 123 bytes", http.StatusBadRequest)
+			return
+		}
+		reason.text = text
+	}
+	var ban *Ban
+	if duration := query.Get("ban"); duration != "" {
+		ttl, err := time.ParseDuration(duration)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "ban must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		expires := now.Add(ttl)
+		ban = &Ban{BanID: newToken()[:10], ClientID: id, Reason: reason.text, CreatedAt: now, ExpiresAt: &expires}
+		if err := ban.compile(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	closed := g.disconnectClient(id, room, reason)
+	if len(closed) == 0 {
+		http.Error(w, "no such client", http.StatusNotFound)
+		return
+	}
+	log.Printf("Client %s disconnected by admin, %d connections closed: %s", id, len(closed), reason)
+	for _, client := range closed {
+		g.audit.record(AuditEvent{Event: "kick", ClientID: client.ID, RemoteIP: client.RemoteAddr, Room: client.Room, Actor: auditActor(r), Outcome: auditOK, Reason: reason.text})
+	}
+
+	// Banned after closing, so it goes with the admin's reason rather than
+	// the ban's
+	if ban != nil {
+		if _, err := g.addBan(ban, auditActor(r)); err != nil {
+			log.Printf("Error saving ban: %v", err)
+			http.Error(w, "client disconnected, but error saving ban", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Ban %s of id %q added, reason %q, expiring %s", ban.BanID, ban.ClientID, ban.Reason, ban.ExpiresAt.Format(time.RFC3339))
+	}
+	writeJSON(w, http.StatusOK, disconnectResult{Closed: closed, Ban: ban})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultMaxAgeJitter spreads connection lifetimes by ±10% so a fleet
+	// started together doesn't reconnect in lockstep
+	defaultMaxAgeJitter = 0.1
+
+	// reconnectWarning is how long before its maximum age a client is told
+	// to reconnect
+	reconnectWarning = 30 * time.Second
+
+	// A client that sent audio within talkerQuiet is treated as holding the
+	// talk floor, and its close is deferred for up to maxTalkExtension
+	talkerQuiet      = 2 * time.Second
+	maxTalkExtension = time.Minute
+
+	// ageSweepInterval is how often Run checks connection ages
+	ageSweepInterval = time.Second
+)
+
+// reconnectMessage warns a client that the server will close its connection
+// for age, so it can reconnect at a convenient moment
+type reconnectMessage struct {
+	Type         string `json:"type"`
+	AfterSeconds int    `json:"after_seconds"`
+}
+
+// WithMaxAge caps how long any connection lives. Each client gets a lifetime
+// of age ± jitter (a fraction of age), is warned reconnectWarning before it
+// runs out and is then closed with 1000 "reconnect". A zero age disables it.
+func WithMaxAge(age time.Duration, jitter float64) HubOption {
+	return func(h *Hub) {
+		h.maxAge = age
+		if jitter >= 0 && jitter < 1 {
+			h.maxAgeJitter = jitter
+		}
+	}
+}
+
+// connectionLifetime picks a client's lifetime uniformly within
+// maxAge ± maxAgeJitter
+func (h *Hub) connectionLifetime() time.Duration {
+	spread := float64(h.maxAge) * h.maxAgeJitter
+	return h.maxAge + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// talking reports whether the client is holding the talk floor
+func (c *Client) talking(now time.Time) bool {
+	return now.Sub(time.Unix(0, c.lastSent.Load())) < talkerQuiet
+}
+
+// closeAged warns clients nearing their maximum age and closes the ones past
+// it, giving active talkers a short extension. Only the Run goroutine may
+// call it.
+func (h *Hub) closeAged(now time.Time) {
+	var warn, aged []*Client
+	h.eachClient(func(client *Client) {
+		switch {
+		case now.Before(client.expiresAt.Add(-reconnectWarning)):
+		case now.Before(client.expiresAt):
+			if !client.warnedAge {
+				warn = append(warn, client)
+			}
+		case client.talking(now) && now.Before(client.expiresAt.Add(maxTalkExtension)):
+			if !client.deferredAge {
+				client.deferredAge = true
+				log.Printf("Client %s reached its maximum age while talking, deferring close", client.id)
+			}
+		default:
+			aged = append(aged, client)
+		}
+	})
+
+	for _, client := range warn {
+		client.warnedAge = true
+		after := int(client.expiresAt.Sub(now).Round(time.Second) / time.Second)
+		data, err := json.Marshal(reconnectMessage{Type: "reconnect", AfterSeconds: after})
+		if err != nil {
+			log.Printf("Error encoding reconnect warning for client %s: %v", client.id, err)
+			continue
+		}
+		h.shardFor(client).send(h, client, outbound{messageType: websocket.TextMessage, data: data})
+	}
+	for _, client := range aged {
+		if h.removeClient(client, reasonMaxAge) {
+			log.Printf("Client %s closed at its maximum age. Total clients: %d", client.id, h.ClientCount())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// Lifetimes spread uniformly over the whole of maxAge ± jitter
+func TestConnectionLifetimeJitter(t *testing.T) {
+	const samples = 10000
+	tests := []struct {
+		age    time.Duration
+		jitter float64
+	}{
+		{4 * time.Hour, 0},
+		{4 * time.Hour, defaultMaxAgeJitter},
+		{time.Hour, 0.5},
+	}
+	for _, tt := range tests {
+		h := NewHub(WithMaxAge(tt.age, tt.jitter))
+		low := time.Duration(float64(tt.age) * (1 - tt.jitter))
+		high := time.Duration(float64(tt.age) * (1 + tt.jitter))
+		min, max, sum := time.Duration(math.MaxInt64), time.Duration(0), 0.0
+		for i := 0; i < samples; i++ {
+			lifetime := h.connectionLifetime()
+			if lifetime < low || lifetime > high {
+				t.Fatalf("age %v jitter %v: lifetime %v outside [%v, %v]", tt.age, tt.jitter, lifetime, low, high)
+			}
+			if lifetime < min {
+				min = lifetime
+			}
+			if lifetime > max {
+				max = lifetime
+			}
+			sum += float64(lifetime)
+		}
+		// Within 5% of the spread of either end and of the middle
+		slack := time.Duration(float64(high-low) * 0.05)
+		if min > low+slack || max < high-slack {
+			t.Errorf("age %v jitter %v: lifetimes span [%v, %v], want about [%v, %v]", tt.age, tt.jitter, min, max, low, high)
+		}
+		if mean := time.Duration(sum / samples); mean < tt.age-slack || mean > tt.age+slack {
+			t.Errorf("age %v jitter %v: mean lifetime %v, want about %v", tt.age, tt.jitter, mean, tt.age)
+		}
+	}
+}
+
+// A client nearing its maximum age is warned, and one past it is closed,
+// unless it is talking, which defers the close by up to maxTalkExtension
+func TestCloseAged(t *testing.T) {
+	tests := []struct {
+		name string
+		// When the sweep runs against the client's expiry, and whether the
+		// client is talking then
+		at      time.Duration
+		talking bool
+		warned  bool
+		closed  bool
+	}{
+		{"well before", -time.Hour, false, false, false},
+		{"within the warning", -reconnectWarning / 2, false, true, false},
+		{"past it", time.Second, false, false, true},
+		{"past it talking", time.Second, true, false, false},
+		{"talking past the extension", maxTalkExtension + time.Second, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(WithMaxAge(time.Hour, 0))
+			now := time.Now()
+			client := &Client{
+				id:        "c",
+				hub:       h,
+				send:      make(chan outbound, 4),
+				seq:       1,
+				expiresAt: now.Add(-tt.at),
+			}
+			if tt.talking {
+				client.lastSent.Store(now.UnixNano())
+			}
+			h.shardFor(client).add(client)
+
+			h.closeAged(now)
+			if closed := h.ClientCount() == 0; closed != tt.closed {
+				t.Fatalf("closed %t, want %t", closed, tt.closed)
+			}
+			if tt.closed && client.closeReason != reasonMaxAge {
+				t.Errorf("closed for %q, want %q", client.closeReason.text, reasonMaxAge.text)
+			}
+			if client.deferredAge != (tt.talking && !tt.closed) {
+				t.Errorf("close deferred %t", client.deferredAge)
+			}
+			warned := false
+			for len(client.send) > 0 {
+				var message reconnectMessage
+				if data := (<-client.send).data; json.Unmarshal(data, &message) == nil && message.Type == "reconnect" {
+					warned = true
+					if want := int(reconnectWarning / 2 / time.Second); message.AfterSeconds != want {
+						t.Errorf("told to reconnect after %ds, want %ds", message.AfterSeconds, want)
+					}
+				}
+			}
+			if warned != tt.warned {
+				t.Errorf("warned %t, want %t", warned, tt.warned)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxAnnouncementSize caps the body of an announcement
+const maxAnnouncementSize = 1 << 20
+
+// announcementMessage is an announcement pushed to every client in every
+// room: text the client renders or speaks, or audio to play over whatever
+// else is playing. It goes out as a control message in both subprotocols
+// so that it is marked as an announcement.
+type announcementMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Text string `json:"text,omitempty"`
+	Data []byte `json:"data,omitempty"`
+	Ack  bool   `json:"ack,omitempty"`
+}
+
+// announcementResult answers POST /announce with the clients reached in
+// each room, and for an announcement posted with ?ack=true the ones that
+// acknowledged it and the ones that never did
+type announcementResult struct {
+	ID        string            `json:"id"`
+	Delivered int               `json:"delivered"`
+	Rooms     map[string]int    `json:"rooms"`
+	Acked     *int              `json:"acked,omitempty"`
+	Unacked   []UnackedDelivery `json:"unacked,omitempty"`
+}
+
+// UnackedDelivery is a client that never acknowledged an announcement
+type UnackedDelivery struct {
+	Room   string `json:"room"`
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// announcementAcks collects the acknowledgements of an announcement
+type announcementAcks struct {
+	wait    sync.WaitGroup
+	mutex   sync.Mutex
+	acked   int
+	unacked []UnackedDelivery
+}
+
+// expect tracks the announcement id delivered to client until it is
+// acknowledged or fails
+func (a *announcementAcks) expect(h *Hub, client *Client, id string, message outbound) error {
+	a.wait.Add(1)
+	resend := func() { h.shardFor(client).send(h, client, message) }
+	err := client.expectAck(id, resend, func(failure string) {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		if failure == "" {
+			a.acked++
+		} else {
+			a.unacked = append(a.unacked, UnackedDelivery{Room: h.room, ID: client.id, Reason: failure})
+		}
+		a.wait.Done()
+	})
+	if err != nil {
+		a.wait.Done()
+	}
+	return err
+}
+
+// announce queues the message for every connection in the hub and returns
+// how many it reached. It ignores mutes and slow consumer policies,
+// dropping a client's oldest queued frame to make room if need be. A
+// multi-room client's memberships are skipped: its connection gets the
+// announcement in its own room. With acks, each connection must
+// acknowledge announcement id.
+func (h *Hub) announce(message outbound, id string, acks *announcementAcks) int {
+	delivered := 0
+	h.eachClient(func(client *Client) {
+		if client.owner != nil {
+			return
+		}
+		if acks != nil && acks.expect(h, client, id, message) != nil {
+			return
+		}
+		if h.deliverAnnouncement(client, message) {
+			delivered++
+		}
+	})
+	return delivered
+}
+
+// deliverAnnouncement queues the message for a client, evicting its oldest
+// queued frame if the buffer is full. The caller must hold the client's
+// shard read lock, which keeps its send channel open.
+func (h *Hub) deliverAnnouncement(client *Client, message outbound) bool {
+	for attempt := 0; attempt < 2; attempt++ {
+		select {
+		case client.send <- message:
+			return true
+		default:
+		}
+		// writePump may drain the buffer concurrently, so there may be
+		// nothing to evict
+		select {
+		case oldest := <-client.send:
+			oldest.release()
+			client.droppedOutbound.Add(1)
+		default:
+		}
+	}
+	return false
+}
+
+// Announce delivers an announcement to every room, or every room of the
+// tenant if it is set, and returns the clients reached in each. If the
+// announcement asks for acknowledgement, acks collects them.
+func (g *Gateway) Announce(announcement announcementMessage, tenant string, acks *announcementAcks) (map[string]int, error) {
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return nil, err
+	}
+	message := outbound{messageType: websocket.TextMessage, data: data}
+
+	rooms := make(map[string]int)
+	g.eachRoom(func(rm *room) {
+		if tenant == "" || roomTenant(rm.name) == tenant {
+			rooms[rm.name] = rm.hub.announce(message, announcement.ID, acks)
+		}
+	})
+	g.announcements.Add(1)
+	return rooms, nil
+}
+
+// serveAnnounce pushes the request body to every connected client as an
+// announcement: POST /announce with a text/plain body for text or an audio
+// or application/octet-stream body for audio. It is only enabled along with
+// the admin token, as it reaches everyone, or for a tenant's API key, which
+// reaches the tenant's rooms.
+func (g *Gateway) serveAnnounce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if g.adminToken == "" && !tenantScoped(r) {
+		http.Error(w, "announcements need the gateway started with -admin-token", http.StatusForbidden)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	audio := mediaType == "application/octet-stream" || strings.HasPrefix(mediaType, "audio/")
+	if !audio && mediaType != "text/plain" {
+		http.Error(w, "announcements are text/plain, audio/* or application/octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAnnouncementSize))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, "announcement too large", http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "reading announcement: "+err.Error(), http.StatusBadRequest)
+		return
+	case len(body) == 0:
+		http.Error(w, "empty announcement", http.StatusBadRequest)
+		return
+	}
+
+	announcement := announcementMessage{Type: "announcement", ID: newToken()[:10]}
+	if audio {
+		announcement.Data = body
+	} else {
+		announcement.Text = string(body)
+	}
+	var acks *announcementAcks
+	if r.URL.Query().Get("ack") == "true" {
+		announcement.Ack = true
+		acks = &announcementAcks{}
+	}
+	rooms, err := g.Announce(announcement, scopedTenant(r), acks)
+	if err != nil {
+		log.Printf("Error encoding announcement: %v", err)
+		http.Error(w, "encoding announcement", http.StatusInternalServerError)
+		return
+	}
+
+	result := announcementResult{ID: announcement.ID, Rooms: rooms}
+	for _, n := range rooms {
+		result.Delivered += n
+	}
+	if acks != nil {
+		// Every delivery is settled within the retries, or when its
+		// client leaves
+		settled := make(chan struct{})
+		go func() {
+			acks.wait.Wait()
+			close(settled)
+		}()
+		select {
+		case <-settled:
+		case <-r.Context().Done():
+			return
+		}
+		result.Acked = &acks.acked
+		result.Unacked = acks.unacked
+	}
+	log.Printf("Announcement %s (%d bytes, audio: %t) delivered to %d clients in %d rooms", result.ID, len(body), audio, result.Delivered, len(rooms))
+	writeJSON(w, http.StatusOK, result)
+}
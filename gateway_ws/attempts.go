@@ -0,0 +1,155 @@
+package main
+
+import (
+	"container/list"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAttemptsTracked is how many addresses the upgrade attempt
+	// limiter remembers
+	defaultAttemptsTracked = 10000
+
+	// throttledReported is how many of the most throttled addresses /stats
+	// lists
+	throttledReported = 10
+)
+
+// attemptBucket is an address's upgrade attempts budget
+type attemptBucket struct {
+	address string
+	tokens  float64
+	last    time.Time
+
+	// Attempts refused, in all and since the last one allowed
+	throttled uint64
+	streak    uint64
+}
+
+// attemptLimiter rate-limits upgrade attempts per IP bucket with a token
+// bucket each. It remembers only the most recently seen addresses, so a
+// flood of them can't exhaust memory; a forgotten address starts afresh.
+type attemptLimiter struct {
+	rate    float64
+	burst   float64
+	tracked int
+
+	mutex     sync.Mutex
+	buckets   map[string]*list.Element
+	recent    *list.List
+	throttled uint64
+}
+
+// newAttemptLimiter allows attempts per window from each address, in bursts
+// of up to attempts, remembering up to tracked addresses
+func newAttemptLimiter(attempts int, window time.Duration, tracked int) *attemptLimiter {
+	if tracked <= 0 {
+		tracked = defaultAttemptsTracked
+	}
+	return &attemptLimiter{
+		rate:    float64(attempts) / window.Seconds(),
+		burst:   float64(attempts),
+		tracked: tracked,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// allow spends an attempt from the address's bucket. If there is none left
+// it returns false and how long until there will be; first reports whether
+// this is the first attempt refused since one was allowed.
+func (l *attemptLimiter) allow(address string, now time.Time) (ok bool, retryAfter time.Duration, first bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var bucket *attemptBucket
+	if element, seen := l.buckets[address]; seen {
+		l.recent.MoveToFront(element)
+		bucket = element.Value.(*attemptBucket)
+		bucket.tokens = math.Min(l.burst, bucket.tokens+l.rate*now.Sub(bucket.last).Seconds())
+		bucket.last = now
+	} else {
+		bucket = &attemptBucket{address: address, tokens: l.burst, last: now}
+		l.buckets[address] = l.recent.PushFront(bucket)
+		if l.recent.Len() > l.tracked {
+			oldest := l.recent.Back()
+			l.recent.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*attemptBucket).address)
+		}
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.streak = 0
+		return true, 0, false
+	}
+	bucket.throttled++
+	bucket.streak++
+	l.throttled++
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait, bucket.streak == 1
+}
+
+// ThrottledAddress is an address whose upgrade attempts were throttled, and
+// how many were
+type ThrottledAddress struct {
+	Address   string `json:"address"`
+	Throttled uint64 `json:"throttled"`
+}
+
+// stats returns the attempts refused in all, and the addresses still
+// remembered that had the most refused
+func (l *attemptLimiter) stats() (uint64, []ThrottledAddress) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	addresses := []ThrottledAddress{}
+	for element := l.recent.Front(); element != nil; element = element.Next() {
+		if bucket := element.Value.(*attemptBucket); bucket.throttled > 0 {
+			addresses = append(addresses, ThrottledAddress{Address: bucket.address, Throttled: bucket.throttled})
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Throttled > addresses[j].Throttled
+	})
+	if len(addresses) > throttledReported {
+		addresses = addresses[:throttledReported]
+	}
+	return l.throttled, addresses
+}
+
+// WithUpgradeAttemptLimit allows each client address (per /64 for IPv6)
+// attempts upgrades per window, refusing more with 429, and remembers up to
+// tracked addresses. Zero attempts means no limit.
+func WithUpgradeAttemptLimit(attempts int, window time.Duration, tracked int) GatewayOption {
+	return func(g *Gateway) {
+		if attempts > 0 && window > 0 {
+			g.attempts = newAttemptLimiter(attempts, window, tracked)
+		}
+	}
+}
+
+// admitAttempt reports whether the client's address may attempt an upgrade,
+// having answered 429 if not. Only the first refusal in a row is logged, so
+// a client retrying in a tight loop doesn't flood the log.
+func (g *Gateway) admitAttempt(w http.ResponseWriter, r *http.Request) bool {
+	if g.attempts == nil {
+		return true
+	}
+	address := ipBucket(g.proxies.clientIP(r))
+	ok, retryAfter, first := g.attempts.allow(address, time.Now())
+	if ok {
+		return true
+	}
+	if first {
+		log.Printf("Throttling upgrade attempts from %s", address)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "too many connection attempts", http.StatusTooManyRequests)
+	return false
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultAuditQueue is how many events may wait for the disk, and for
+	// the webhook, before more are dropped
+	defaultAuditQueue = 4096
+
+	// auditWebhookBatch and auditWebhookInterval bound a webhook request:
+	// it carries up to a batch of events, and goes at least every interval
+	// while there are any
+	auditWebhookBatch    = 100
+	auditWebhookInterval = time.Second
+
+	// auditTail is how far back from the end of the last audit file the
+	// chain is picked up from at startup
+	auditTail = 64 << 10
+)
+
+// Audit event outcomes
+const (
+	auditOK     = "ok"
+	auditDenied = "denied"
+)
+
+// AuditEvent is one line of the audit log. Every field is always present,
+// so the schema doesn't depend on the event. Prev is the SHA-256 of the line
+// before, chaining the log so an edited or deleted line shows.
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Seq      uint64    `json:"seq"`
+	Event    string    `json:"event"`
+	ClientID string    `json:"client_id"`
+	RemoteIP string    `json:"remote_ip"`
+	Room     string    `json:"room"`
+	Actor    string    `json:"actor"`
+	Outcome  string    `json:"outcome"`
+	Reason   string    `json:"reason"`
+	Prev     string    `json:"prev"`
+}
+
+// auditLog appends security-relevant events to a JSON lines file per UTC
+// day, and optionally posts them to a webhook. Recording an event never
+// blocks: its own goroutines do the writing, and events they have no room
+// for are dropped and counted.
+type auditLog struct {
+	dir     string
+	webhook string
+	client  *http.Client
+
+	// Guards closed, so an event is never queued once events is closed
+	mutex   sync.RWMutex
+	closed  bool
+	events  chan AuditEvent
+	shipped chan AuditEvent
+
+	// Closed once the writers have finished
+	done        chan struct{}
+	webhookDone chan struct{}
+
+	// The open file and the chain, owned by the writing goroutine
+	file    *os.File
+	w       *bufio.Writer
+	day     string
+	seq     uint64
+	prev    string
+	failing bool
+
+	written        atomic.Uint64
+	dropped        atomic.Uint64
+	errors         atomic.Uint64
+	webhookSent    atomic.Uint64
+	webhookDropped atomic.Uint64
+	webhookErrors  atomic.Uint64
+}
+
+// OpenAuditLog starts an audit log writing into dir, which is created if
+// need be, and posting to webhook unless it is empty. The chain carries on
+// from the last line already in dir.
+func OpenAuditLog(dir, webhook string) (*auditLog, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating audit directory: %w", err)
+	}
+	a := &auditLog{
+		dir:     dir,
+		webhook: webhook,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		events:  make(chan AuditEvent, defaultAuditQueue),
+		done:    make(chan struct{}),
+	}
+	if err := a.resume(); err != nil {
+		return nil, err
+	}
+	go a.run()
+	if webhook != "" {
+		a.shipped = make(chan AuditEvent, defaultAuditQueue)
+		a.webhookDone = make(chan struct{})
+		go a.ship()
+	}
+	return a, nil
+}
+
+// resume picks up the sequence and chain from the last line of the newest
+// audit file
+func (a *auditLog) resume() error {
+	names, err := filepath.Glob(filepath.Join(a.dir, "audit-*.jsonl"))
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	sort.Strings(names)
+	file, err := os.Open(names[len(names)-1])
+	if err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+	offset := info.Size() - auditTail
+	if offset < 0 {
+		offset = 0
+	}
+	tail, err := io.ReadAll(io.NewSectionReader(file, offset, info.Size()-offset))
+	if err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	last := lines[len(lines)-1]
+	if len(last) == 0 {
+		return nil
+	}
+	var event AuditEvent
+	if err := json.Unmarshal(last, &event); err != nil {
+		// A line cut short by a crash; the chain restarts, which shows
+		log.Printf("Audit log %s ends in an unreadable line, starting a new chain: %v", file.Name(), err)
+		return nil
+	}
+	sum := sha256.Sum256(last)
+	a.seq, a.prev = event.Seq, hex.EncodeToString(sum[:])
+	return nil
+}
+
+// record queues an event, stamping its time, or drops it if the writer is
+// behind. A nil log records nothing.
+func (a *auditLog) record(event AuditEvent) {
+	if a == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.events <- event:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Close writes out the events already queued and stops the log
+func (a *auditLog) Close() {
+	a.mutex.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mutex.Unlock()
+	<-a.done
+	if a.webhookDone != nil {
+		<-a.webhookDone
+	}
+}
+
+// run writes queued events, flushing whenever the queue runs dry
+func (a *auditLog) run() {
+	defer close(a.done)
+	for event := range a.events {
+		a.write(event)
+		if len(a.events) == 0 && a.w != nil {
+			if err := a.w.Flush(); err != nil {
+				a.failed(err)
+			}
+		}
+	}
+	a.finish()
+	if a.shipped != nil {
+		close(a.shipped)
+	}
+}
+
+// write chains an event to the one before and appends it to the day's file
+func (a *auditLog) write(event AuditEvent) {
+	day := event.Time.Format("2006-01-02")
+	if a.file == nil || day != a.day {
+		a.finish()
+		if err := a.open(day); err != nil {
+			a.failed(err)
+			a.dropped.Add(1)
+			return
+		}
+	}
+
+	a.seq++
+	event.Seq = a.seq
+	event.Prev = a.prev
+	line, err := json.Marshal(event)
+	if err != nil {
+		a.failed(err)
+		return
+	}
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		a.failed(err)
+		a.dropped.Add(1)
+		return
+	}
+	sum := sha256.Sum256(line)
+	a.prev = hex.EncodeToString(sum[:])
+	a.written.Add(1)
+	a.failing = false
+
+	if a.shipped != nil {
+		select {
+		case a.shipped <- event:
+		default:
+			a.webhookDropped.Add(1)
+		}
+	}
+}
+
+// open appends to the file for the day
+func (a *auditLog) open(day string) error {
+	name := filepath.Join(a.dir, "audit-"+day+".jsonl")
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	a.file = file
+	a.w = bufio.NewWriter(file)
+	a.day = day
+	return nil
+}
+
+// finish flushes, syncs and closes the current file
+func (a *auditLog) finish() {
+	if a.file == nil {
+		return
+	}
+	err := a.w.Flush()
+	if err == nil {
+		err = a.file.Sync()
+	}
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		a.failed(err)
+	}
+	a.file = nil
+	a.w = nil
+}
+
+// failed counts a disk error, logging only the first of a run of them
+func (a *auditLog) failed(err error) {
+	a.errors.Add(1)
+	if !a.failing {
+		log.Printf("Error writing the audit log: %v", err)
+	}
+	a.failing = true
+}
+
+// ship posts the written events to the webhook in batches, as JSON arrays.
+// A batch the webhook doesn't take with a 2xx is dropped and counted; the
+// file has it.
+func (a *auditLog) ship() {
+	defer close(a.webhookDone)
+	ticker := time.NewTicker(auditWebhookInterval)
+	defer ticker.Stop()
+	var batch []AuditEvent
+	for {
+		select {
+		case event, ok := <-a.shipped:
+			if !ok {
+				a.post(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < auditWebhookBatch {
+				continue
+			}
+		case <-ticker.C:
+		}
+		a.post(batch)
+		batch = batch[:0]
+	}
+}
+
+// post sends a batch of events to the webhook
+func (a *auditLog) post(batch []AuditEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(batch)
+	if err == nil {
+		var resp *http.Response
+		resp, err = a.client.Post(a.webhook, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				err = fmt.Errorf("webhook answered %s", resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		a.webhookErrors.Add(1)
+		a.webhookDropped.Add(uint64(len(batch)))
+		log.Printf("Error posting %d audit events: %v", len(batch), err)
+		return
+	}
+	a.webhookSent.Add(uint64(len(batch)))
+}
+
+// AuditStats counts the audit log's events, written and dropped, and its
+// disk and webhook errors
+type AuditStats struct {
+	Written        uint64 `json:"written"`
+	Dropped        uint64 `json:"dropped"`
+	Errors         uint64 `json:"errors"`
+	WebhookSent    uint64 `json:"webhook_sent"`
+	WebhookDropped uint64 `json:"webhook_dropped"`
+	WebhookErrors  uint64 `json:"webhook_errors"`
+}
+
+// Stats returns the audit log's counters
+func (a *auditLog) Stats() AuditStats {
+	return AuditStats{
+		Written:        a.written.Load(),
+		Dropped:        a.dropped.Load(),
+		Errors:         a.errors.Load(),
+		WebhookSent:    a.webhookSent.Load(),
+		WebhookDropped: a.webhookDropped.Load(),
+		WebhookErrors:  a.webhookErrors.Load(),
+	}
+}
+
+// WithAuditLog records security-relevant events in the audit log
+func WithAuditLog(audit *auditLog) GatewayOption {
+	return func(g *Gateway) {
+		g.audit = audit
+	}
+}
+
+// auditActor names who made a management request: the admin, or a tenant
+// through its API key
+func auditActor(r *http.Request) string {
+	if tenantScoped(r) {
+		return "tenant:" + scopedTenant(r)
+	}
+	return "admin"
+}
+
+// auditIP formats an address for the audit log, empty if unknown
+func auditIP(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// auditClient records an event about a client as its room knows it
+func (g *Gateway) auditClient(event string, client *Client, actor, outcome, reason string) {
+	g.audit.record(AuditEvent{
+		Event:    event,
+		ClientID: client.id,
+		RemoteIP: auditIP(client.remoteIP),
+		Room:     client.hub.room,
+		Actor:    actor,
+		Outcome:  outcome,
+		Reason:   reason,
+	})
+}
+
+// auditJoinRefused records a switch or multi-room join the room refused
+func (c *Client) auditJoinRefused(room, reason string) {
+	c.gateway.audit.record(AuditEvent{
+		Event:    "join",
+		ClientID: c.id,
+		RemoteIP: auditIP(c.remoteIP),
+		Room:     room,
+		Outcome:  auditDenied,
+		Reason:   reason,
+	})
+}
+
+// auditLeave records a client leaving the hub: its connection closing, or
+// only its membership of the room when it switched or left
+func (h *Hub) auditLeave(client *Client, reason closeReason) {
+	event := "disconnect"
+	if client.owner != nil || reason == reasonSwitched || reason == reasonLeft {
+		event = "leave"
+	}
+	client.gateway.auditClient(event, client, "", auditOK, reason.text)
+}
+
+// auditRefused records an upgrade refused before the client got a
+// connection
+func (g *Gateway) auditRefused(r *http.Request, clientID, room, reason string) {
+	g.audit.record(AuditEvent{
+		Event:    "connect",
+		ClientID: clientID,
+		RemoteIP: auditIP(g.proxies.clientIP(r)),
+		Room:     room,
+		Outcome:  auditDenied,
+		Reason:   reason,
+	})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBanReason is the longest reason a ban may give
+const maxBanReason = 256
+
+var errNoSuchBan = errors.New("no such ban")
+
+// Ban refuses a client ID, an address or network, or a client ID only from
+// an address, until it expires or is lifted. Guarded by the gateway mutex.
+type Ban struct {
+	BanID     string     `json:"ban_id"`
+	ClientID  string     `json:"id,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	network *net.IPNet
+}
+
+// active reports whether the ban is in force at now
+func (b *Ban) active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
+
+// matches reports whether the ban covers a client with the ID at the
+// address. A ban naming both covers only clients matching both.
+func (b *Ban) matches(clientID string, ip net.IP) bool {
+	if b.ClientID != "" && b.ClientID != clientID {
+		return false
+	}
+	if b.network != nil && (ip == nil || !b.network.Contains(ip)) {
+		return false
+	}
+	return true
+}
+
+// compile checks a ban and parses its address
+func (b *Ban) compile() error {
+	if b.ClientID == "" && b.IP == "" {
+		return errors.New("a ban needs an id, an ip or both")
+	}
+	if len(b.Reason) > maxBanReason {
+		return fmt.Errorf("reason longer than %d bytes", maxBanReason)
+	}
+	if b.IP != "" {
+		network, err := parseNetwork(b.IP)
+		if err != nil {
+			return fmt.Errorf("invalid ip %q: want an IP or CIDR", b.IP)
+		}
+		b.network = network
+	}
+	return nil
+}
+
+// BanStore keeps bans across restarts. The bbolt room store is one.
+type BanStore interface {
+	LoadBans() ([]Ban, error)
+	SaveBan(ban Ban) error
+	DeleteBan(id string) error
+}
+
+// bansBucket holds one JSON Ban per ban ID
+var bansBucket = []byte("bans")
+
+// LoadBans puts the bans in the room store in force, dropping the ones that
+// expired while the gateway was down
+func (g *Gateway) LoadBans() error {
+	if g.banStore == nil {
+		return nil
+	}
+	bans, err := g.banStore.LoadBans()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for i := range bans {
+		ban := bans[i]
+		if err := ban.compile(); err != nil {
+			log.Printf("Skipping stored ban %s: %v", ban.BanID, err)
+			continue
+		}
+		g.bans[ban.BanID] = &ban
+	}
+	g.expireBans(now)
+	log.Printf("Loaded %d bans", len(g.bans))
+	return nil
+}
+
+// banned returns the ban in force at now that covers a client with the ID
+// at the address, or nil
+func (g *Gateway) banned(clientID string, ip net.IP, now time.Time) *Ban {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, ban := range g.bans {
+		if ban.active(now) && ban.matches(clientID, ip) {
+			return ban
+		}
+	}
+	return nil
+}
+
+// banError is the body of an upgrade refused by a ban
+type banError struct {
+	Error     string     `json:"error"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// admitBanned reports whether a client with the ID at the address may
+// upgrade, having answered 403 if a ban covers it
+func (g *Gateway) admitBanned(w http.ResponseWriter, clientID string, ip net.IP) bool {
+	ban := g.banned(clientID, ip, time.Now())
+	if ban == nil {
+		return true
+	}
+	log.Printf("Client %s at %s refused: banned by %s", clientID, ip, ban.BanID)
+	g.audit.record(AuditEvent{Event: "connect", ClientID: clientID, RemoteIP: auditIP(ip), Outcome: auditDenied, Reason: "banned by " + ban.BanID})
+	writeJSON(w, http.StatusForbidden, banError{Error: "banned", Reason: ban.Reason, ExpiresAt: ban.ExpiresAt})
+	return false
+}
+
+// addBan puts a ban, which must have compiled, in force, writes it to the
+// store and disconnects the clients it covers. The mutex is held across the
+// store write, as for room configs.
+func (g *Gateway) addBan(ban *Ban, actor string) (closed int, err error) {
+	g.mutex.Lock()
+	if g.banStore != nil {
+		if err := g.banStore.SaveBan(*ban); err != nil {
+			g.mutex.Unlock()
+			return 0, err
+		}
+	}
+	g.bans[ban.BanID] = ban
+	g.mutex.Unlock()
+	g.audit.record(AuditEvent{Event: "ban", ClientID: ban.ClientID, RemoteIP: ban.IP, Actor: actor, Outcome: auditOK, Reason: ban.BanID + ": " + ban.Reason})
+
+	g.eachRoom(func(rm *room) {
+		for _, client := range rm.hub.snapshotClients() {
+			if client.owner == nil && ban.matches(client.id, client.remoteIP) {
+				client.leave(reasonBanned)
+				closed++
+			}
+		}
+	})
+	return closed, nil
+}
+
+// liftBan removes a ban and deletes it from the store
+func (g *Gateway) liftBan(id, actor string) (*Ban, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	ban := g.bans[id]
+	if ban == nil {
+		return nil, errNoSuchBan
+	}
+	if g.banStore != nil {
+		if err := g.banStore.DeleteBan(id); err != nil {
+			return nil, err
+		}
+	}
+	delete(g.bans, id)
+	g.audit.record(AuditEvent{Event: "unban", ClientID: ban.ClientID, RemoteIP: ban.IP, Actor: actor, Outcome: auditOK, Reason: id})
+	return ban, nil
+}
+
+// expireBans forgets bans that have expired, in memory and in the store.
+// The caller must hold the mutex.
+func (g *Gateway) expireBans(now time.Time) {
+	for id, ban := range g.bans {
+		if ban.active(now) {
+			continue
+		}
+		if g.banStore != nil {
+			if err := g.banStore.DeleteBan(id); err != nil {
+				// Tried again on the next sweep
+				log.Printf("Error deleting expired ban %s: %v", id, err)
+				continue
+			}
+		}
+		delete(g.bans, id)
+		log.Printf("Ban %s expired", id)
+		g.audit.record(AuditEvent{Event: "unban", ClientID: ban.ClientID, RemoteIP: ban.IP, Actor: "system", Outcome: auditOK, Reason: id + " expired"})
+	}
+}
+
+// listBans returns the bans in force, oldest first
+func (g *Gateway) listBans(now time.Time) []Ban {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	bans := []Ban{}
+	for _, ban := range g.bans {
+		if ban.active(now) {
+			bans = append(bans, *ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.Before(bans[j].CreatedAt)
+	})
+	return bans
+}
+
+// banRequest is the body of a request to ban: a client ID, an address or
+// network, or both, and optionally a reason and a Go duration such as
+// "24h" after which the ban lapses
+type banRequest struct {
+	ClientID  string `json:"id"`
+	IP        string `json:"ip"`
+	Reason    string `json:"reason"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+// serveBans lists (GET /admin/bans) and adds (POST) bans, and lifts one
+// (DELETE /admin/bans/{ban_id}). Bans are for the gateway's operator, not
+// its tenants.
+func (g *Gateway) serveBans(w http.ResponseWriter, r *http.Request) {
+	if tenantScoped(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/admin/bans"), "/")
+	now := time.Now()
+
+	switch {
+	case id != "" && r.Method == http.MethodDelete:
+		ban, err := g.liftBan(id, auditActor(r))
+		if err == errNoSuchBan {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error lifting ban %s: %v", id, err)
+			http.Error(w, "error lifting ban", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Ban %s of id %q ip %q lifted", id, ban.ClientID, ban.IP)
+		w.WriteHeader(http.StatusNoContent)
+
+	case id != "":
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+	case r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g.listBans(now))
+
+	case r.Method == http.MethodPost:
+		var request banRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			http.Error(w, "invalid ban request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ban := &Ban{
+			BanID:     newToken()[:10],
+			ClientID:  request.ClientID,
+			IP:        request.IP,
+			Reason:    request.Reason,
+			CreatedAt: now,
+		}
+		if request.ExpiresIn != "" {
+			ttl, err := time.ParseDuration(request.ExpiresIn)
+			if err != nil || ttl <= 0 {
+				http.Error(w, "expires_in must be a positive duration", http.StatusBadRequest)
+				return
+			}
+			expires := now.Add(ttl)
+			ban.ExpiresAt = &expires
+		}
+		if err := ban.compile(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		closed, err := g.addBan(ban, auditActor(r))
+		if err != nil {
+			log.Printf("Error saving ban: %v", err)
+			http.Error(w, "error saving ban", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Ban %s of id %q ip %q added, reason %q, closed %d connections", ban.BanID, ban.ClientID, ban.IP, ban.Reason, closed)
+		writeJSON(w, http.StatusCreated, ban)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCoalescedFrames caps how many queued frames writePump flushes to the
+// network together, so a deep backlog doesn't delay the first frame
+const maxCoalescedFrames = 16
+
+// batchConn wraps a hijacked connection so writePump can write several
+// WebSocket frames back to back and flush them with a single write to the
+// socket. While uncorked, writes pass straight through.
+type batchConn struct {
+	net.Conn
+
+	mutex  sync.Mutex
+	buf    *bufio.Writer
+	corked bool
+
+	// Writes issued to the underlying connection
+	writes atomic.Uint64
+
+	// Bytes written, whether buffered or not
+	bytes atomic.Uint64
+}
+
+func newBatchConn(conn net.Conn) *batchConn {
+	b := &batchConn{Conn: conn}
+	b.buf = bufio.NewWriter(writerFunc(b.writeThrough))
+	return b
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// writeThrough writes to the underlying connection and counts the call
+func (b *batchConn) writeThrough(p []byte) (int, error) {
+	b.writes.Add(1)
+	return b.Conn.Write(p)
+}
+
+// Write implements net.Conn
+func (b *batchConn) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.bytes.Add(uint64(len(p)))
+	if b.corked {
+		return b.buf.Write(p)
+	}
+	return b.writeThrough(p)
+}
+
+// cork starts buffering writes
+func (b *batchConn) cork() {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	b.corked = true
+	b.mutex.Unlock()
+}
+
+// uncork flushes buffered writes and stops buffering
+func (b *batchConn) uncork() error {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.corked = false
+	return b.buf.Flush()
+}
+
+// socketWrites returns the number of writes issued to the socket
+func (b *batchConn) socketWrites() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.writes.Load()
+}
+
+// bytesWritten returns the number of bytes written to the connection
+func (b *batchConn) bytesWritten() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.bytes.Load()
+}
+
+// batchingResponseWriter wraps the connection the Upgrader hijacks in a
+// batchConn
+type batchingResponseWriter struct {
+	http.ResponseWriter
+	conn *batchConn
+}
+
+// Hijack implements http.Hijacker
+func (w *batchingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.conn = newBatchConn(conn)
+	return w.conn, brw, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkWriteCoalescing empties a 10-deep backlog for each of 100
+// listeners, writing each frame through to the socket against coalescing
+// the backlog into one flush, and reports the socket writes per backlog
+func BenchmarkWriteCoalescing(b *testing.B) {
+	const (
+		clients = 100
+		backlog = 10
+	)
+	h := NewHub()
+	payload := pcmFrame(1)
+	tests := []struct {
+		name  string
+		drain func(c *Client) error
+	}{
+		{"uncoalesced", func(c *Client) error {
+			for len(c.send) > 0 {
+				c.conn.SetWriteDeadline(c.writeDeadline())
+				if err := c.writeQueued(<-c.send); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"coalesced", func(c *Client) error {
+			_, err := c.writeBatch(<-c.send)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			listeners := make([]*Client, clients)
+			for i := range listeners {
+				conn, batch := discardWS(b, false)
+				listeners[i] = &Client{
+					id:   fmt.Sprintf("listener%d", i),
+					hub:  h,
+					conn: conn,
+					send: make(chan outbound, backlog),
+				}
+				listeners[i].batch.Store(batch)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, c := range listeners {
+					for j := 0; j < backlog; j++ {
+						frame := getFrame()
+						frame.data = append(frame.data[:0], payload...)
+						c.send <- outbound{frame: frame}
+					}
+					if err := tt.drain(c); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			var writes uint64
+			for _, c := range listeners {
+				writes += c.batch.Load().socketWrites()
+			}
+			b.ReportMetric(float64(writes)/float64(b.N*clients), "writes/backlog")
+		})
+	}
+}
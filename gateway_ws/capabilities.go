@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A client lists the capabilities it has in the capabilities query
+// parameter, comma separated, and its joined message lists those of them
+// the gateway will honor; the gateway only sends it what they cover, and
+// only expects of it what they say it can do. Capabilities the gateway
+// doesn't know are ignored, so a newer client can join an older gateway. A
+// client that lists none is taken to have them all, as clients had before
+// there were any, short of those that bring messages such clients never
+// got, which it must list.
+const (
+	// Answers messages that ask to be acknowledged
+	capAcks = "acks"
+	// Gets chat messages, and the room's chat history on joining
+	capChat = "chat"
+	// Gets presence messages, and who is present on joining
+	capPresence = "presence"
+	// Gets the frames it missed replayed when it resumes its session
+	capReplay = "replay"
+	// Gets talk messages
+	capTalk = "talk"
+	// Gets level messages for the room's senders; never assumed
+	capLevels = "levels"
+)
+
+// knownCapabilities is every capability the gateway honors, in the order
+// joined messages list them
+var knownCapabilities = []string{capAcks, capChat, capPresence, capReplay, capTalk, capLevels}
+
+// capabilities is a negotiated set of capabilities, one bit for each of
+// knownCapabilities
+type capabilities uint32
+
+// allCapabilities is the set of a client that lists none
+var allCapabilities = (capabilities(1)<<len(knownCapabilities) - 1) &^ capabilityBit(capLevels)
+
+// capabilityBit returns the set holding just the capability, or none if
+// the gateway doesn't know it
+func capabilityBit(name string) capabilities {
+	for i, known := range knownCapabilities {
+		if known == name {
+			return 1 << i
+		}
+	}
+	return 0
+}
+
+// has reports whether the set holds the capability
+func (s capabilities) has(name string) bool {
+	return s&capabilityBit(name) != 0
+}
+
+// names returns the set's capabilities
+func (s capabilities) names() []string {
+	names := []string{}
+	for i, known := range knownCapabilities {
+		if s&(1<<i) != 0 {
+			names = append(names, known)
+		}
+	}
+	return names
+}
+
+// requestedCapabilities returns the capabilities the client lists that the
+// gateway honors, or all of them if it lists none
+func requestedCapabilities(r *http.Request) capabilities {
+	query := r.URL.Query()
+	if !query.Has("capabilities") {
+		return allCapabilities
+	}
+	var set capabilities
+	for _, name := range strings.Split(query.Get("capabilities"), ",") {
+		set |= capabilityBit(strings.TrimSpace(name))
+	}
+	return set
+}
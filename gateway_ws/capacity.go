@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errRoomFull refuses a registration because the room has as many
+// participants as it allows
+var errRoomFull = errors.New("room full")
+
+// roomCapacities maps room names to their participant caps
+type roomCapacities map[string]int
+
+// ParseRoomCapacities parses a comma-separated list of room=max overrides
+func ParseRoomCapacities(list string) (roomCapacities, error) {
+	capacities := roomCapacities{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !validRoomName(name) {
+			return nil, fmt.Errorf("invalid room capacity %q: want room=max", entry)
+		}
+		max, err := strconv.Atoi(value)
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("invalid room capacity %q: max must be a non-negative integer", entry)
+		}
+		capacities[name] = max
+	}
+	return capacities, nil
+}
+
+// WithRoomCapacity caps how many participants a room admits: max by default,
+// and the given overrides for the rooms they name. Zero means no cap.
+func WithRoomCapacity(max int, overrides roomCapacities) GatewayOption {
+	return func(g *Gateway) {
+		if max >= 0 {
+			g.roomCapacity = max
+		}
+		for name, max := range overrides {
+			max := max
+			g.configureRoom(name, func(config *RoomConfig) {
+				config.Capacity = &max
+			})
+		}
+	}
+}
+
+// capacityFor returns the participant cap for the named room. The caller
+// must hold the gateway's mutex.
+func (g *Gateway) capacityFor(name string) int {
+	if max := g.roomConfigs[name].Capacity; max != nil {
+		return *max
+	}
+	return g.roomCapacity
+}
+
+// WithCapacity caps how many participants the hub's room admits, refusing
+// further registrations with errRoomFull. Unlike WithMaxClients, which
+// protects the server, this is a property of the room. Zero means no cap.
+func WithCapacity(max int) HubOption {
+	return func(h *Hub) {
+		if max >= 0 {
+			h.capacity.Store(int64(max))
+		}
+	}
+}
+
+// roomFullError is the body of the 409 refusing a join to a full room
+type roomFullError struct {
+	Error    string `json:"error"`
+	Room     string `json:"room"`
+	Clients  int    `json:"clients"`
+	Capacity int    `json:"capacity"`
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultChatMaxLength caps a chat message's text, in characters
+	defaultChatMaxLength = 500
+
+	// defaultChatRate and defaultChatBurst are how many chat messages a
+	// connection may send a second, sustained and at once
+	defaultChatRate  = 1
+	defaultChatBurst = 5
+)
+
+// chatLimits caps the chat messages each connection sends
+type chatLimits struct {
+	maxLength int
+	perSecond float64
+	burst     int
+}
+
+// WithChatLimits caps a chat message's text at maxLength characters, and
+// each connection to perSecond chat messages, sustained, in bursts of up to
+// burst. Zero leaves a setting at its default.
+func WithChatLimits(maxLength int, perSecond float64, burst int) GatewayOption {
+	return func(g *Gateway) {
+		if maxLength > 0 {
+			g.chat.maxLength = maxLength
+		}
+		if perSecond > 0 {
+			g.chat.perSecond = perSecond
+		}
+		if burst > 0 {
+			g.chat.burst = burst
+		}
+	}
+}
+
+// WithChatHistory keeps the last n chat messages of the room, which the
+// joined message carries so late joiners see what was said. Zero keeps
+// none.
+func WithChatHistory(n int) HubOption {
+	return func(h *Hub) {
+		if n >= 0 {
+			h.chatHistory = n
+		}
+	}
+}
+
+// chatMessage relays a text note to the room the way audio goes. From is
+// the sender as the server knows it; ts is when the server relayed it, in
+// Unix milliseconds.
+type chatMessage struct {
+	Type string `json:"type"`
+	Room string `json:"room"`
+	From string `json:"from"`
+	Text string `json:"text"`
+	TS   int64  `json:"ts"`
+
+	// Set on a whisper, for its target alone, and on a message for the
+	// clients with any of the tags
+	Private bool     `json:"private,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// chat carries its text, and may ask for the sender's own copy with echo,
+// or name a target to whisper to or tags to address
+func init() {
+	registerControl("chat", controlHandler{
+		validate: func(m controlMessage) error {
+			if m.Text == "" {
+				return errors.New("missing text")
+			}
+			return nil
+		},
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				c.chat(member, m)
+			}
+		},
+	})
+}
+
+// chat relays a chat message from the client, a member of the connection
+// c, to everyone else in its room, or to its target or tags alone, and to
+// itself as well if it asked. Like audio, a listener's or a muted client's is
+// refused. Only readPump may call it.
+func (c *Client) chat(sender *Client, message controlMessage) {
+	hub := sender.hub
+	room := localRoom(hub.room)
+	limits := c.gateway.chat
+	switch {
+	case sender.listener.Load():
+		hub.chatRefused.Add(1)
+		sender.fail("listen_only", "chat", room)
+		return
+	case sender.muted.Load():
+		hub.chatRefused.Add(1)
+		sender.fail("muted", "chat", room)
+		return
+	case utf8.RuneCountInString(message.Text) > limits.maxLength:
+		hub.chatRefused.Add(1)
+		sender.fail("too_large", "chat", room)
+		return
+	}
+
+	// The bucket belongs to the connection, so memberships can't multiply
+	// it
+	size := burst(limits.perSecond, limits.burst)
+	now := time.Now()
+	if c.chats.last.IsZero() {
+		c.chats = tokenBucket{tokens: size, last: now}
+	}
+	c.chats.refill(limits.perSecond, size, now)
+	if c.chats.tokens < 1 {
+		hub.chatRefused.Add(1)
+		sender.fail("rate_limited", "chat", room)
+		return
+	}
+	c.chats.tokens--
+
+	if message.Target != "" {
+		sender.whisperChat(message, now)
+		return
+	}
+	if len(message.Tags) > 0 {
+		sender.groupChat(message, now)
+		return
+	}
+	relayed := chatMessage{Type: "chat", Room: room, From: sender.id, Text: message.Text, TS: now.UnixMilli()}
+	data, err := json.Marshal(relayed)
+	if err != nil {
+		log.Printf("Error encoding chat message from client %s: %v", sender.id, err)
+		return
+	}
+	out := outbound{messageType: websocket.TextMessage, data: data}
+	for _, client := range hub.snapshotClients() {
+		if client == sender && !message.Echo || !client.caps.has(capChat) {
+			continue
+		}
+		hub.shardFor(client).send(hub, client, out)
+	}
+	hub.remember(relayed)
+	hub.chatMessages.Add(1)
+	c.gateway.debugf("Client %s sent a chat message in room %s", sender.id, hub.room)
+}
+
+// remember keeps a chat message in the room's history, dropping the oldest
+// once it holds chatHistory
+func (h *Hub) remember(message chatMessage) {
+	if h.chatHistory == 0 {
+		return
+	}
+	h.chatMutex.Lock()
+	defer h.chatMutex.Unlock()
+	if len(h.chatLog) == h.chatHistory {
+		h.chatLog = h.chatLog[:copy(h.chatLog, h.chatLog[1:])]
+	}
+	h.chatLog = append(h.chatLog, message)
+}
+
+// recentChat returns a copy of the room's chat history, oldest first
+func (h *Hub) recentChat() []chatMessage {
+	h.chatMutex.Lock()
+	defer h.chatMutex.Unlock()
+	if len(h.chatLog) == 0 {
+		return nil
+	}
+	return append([]chatMessage(nil), h.chatLog...)
+}
@@ -0,0 +1,912 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// outbound is a message queued for a single client. Broadcasts carry a
+// shared frame buffer that is framed once for every listener and holds a
+// reference on their behalf; targeted sends only set data. Audio for a
+// multi-room client is tagged with the room it comes from.
+type outbound struct {
+	messageType int
+	data        []byte
+	frame       *frameBuffer
+	room        string
+
+	// Replayed to a resumed client, not live
+	replayed bool
+
+	// Marks the end of a burst, which a full send buffer makes room for
+	eot bool
+}
+
+// write sends the message on the connection, framed for the client's
+// subprotocol and, for walkie.framed.v1, for whether it is in integrity mode
+func (m outbound) write(conn *websocket.Conn, protocol string, integrity bool) error {
+	if m.room != "" && m.frame != nil && m.frame.messageType == websocket.BinaryMessage {
+		return writeTagged(conn, protocol, m.room, m.frame, integrity, m.replayed)
+	}
+	if m.replayed && m.frame != nil && m.frame.messageType == websocket.BinaryMessage && (protocol == protocolJSON || protocol == protocolPB) {
+		return writeReplayed(conn, protocol, "", m.frame)
+	}
+	if m.frame != nil {
+		var prepared *websocket.PreparedMessage
+		var err error
+		switch protocol {
+		case protocolJSON:
+			prepared, err = m.frame.jsonMessage()
+		case protocolFramed:
+			if integrity {
+				prepared, err = m.frame.checksummedMessage()
+			} else {
+				prepared, err = m.frame.framedMessage()
+			}
+		case protocolPB:
+			prepared, err = m.frame.pbMessage()
+		default:
+			prepared, err = m.frame.preparedMessage()
+		}
+		if err != nil {
+			return err
+		}
+		return conn.WritePreparedMessage(prepared)
+	}
+	if protocol == protocolPB && m.messageType == websocket.TextMessage {
+		data, err := controlToPB(m.data)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.BinaryMessage, data)
+	}
+	return conn.WriteMessage(m.messageType, m.data)
+}
+
+// release drops the message's reference on its frame buffer, if any
+func (m outbound) release() {
+	if m.frame != nil {
+		m.frame.release()
+	}
+}
+
+// Client represents a connected websocket client
+type Client struct {
+	conn *websocket.Conn
+	send chan outbound
+	hub  *Hub
+	id   string
+
+	// Gateway the client joined through, for switching rooms
+	gateway *Gateway
+
+	// API key the client connected with, which confines it to the key's
+	// tenant's rooms; nil if the gateway requires none
+	key *apiKey
+
+	// Claims of the bearer token the client upgraded with, which limit the
+	// rooms it may join; nil if the gateway requires none
+	claims *tokenClaims
+
+	// The hijacked connection under conn, used to coalesce writes. Set once
+	// the client is upgraded, after it is registered and visible to stats.
+	batch atomic.Pointer[batchConn]
+
+	// Real address of the client and the func that returns its per-IP
+	// connection slot, and its API key's
+	remoteIP  net.IP
+	releaseIP func()
+
+	// Registration sequence number, assigned by the hub; selects the shard
+	seq uint64
+
+	// Session the client belongs to, and the token that resumes it: the one
+	// the client presented until the hub replaces it with a fresh one
+	session     *session
+	resumeToken string
+	resumed     bool
+
+	// Payload the hub broadcasts if the client disconnects abnormally
+	will string
+
+	// Negotiated subprotocol, which decides how frames are encoded
+	protocol string
+
+	// Whether permessage-deflate was negotiated
+	compression bool
+
+	// Broadcast sequence number up to which frames were replayed on resume,
+	// or are in a late-join replay
+	replayedThrough uint64
+
+	// Whether the client asked for the room's recent audio on joining, and
+	// how much faster than real time, 0 for as fast as the socket allows;
+	// and its replay once the hub starts it
+	lateJoinReplay bool
+	replaySpeed    float64
+	catchUp        *catchUp
+
+	// closeOnce guarantees send is closed exactly once, whichever of the
+	// eviction or unregister paths gets there first.
+	closeOnce sync.Once
+
+	// Close frame writePump sends once send is closed. Written before
+	// closing is set, so writePump can read it without locking once it
+	// observes closing.
+	closeReason closeReason
+	closing     atomic.Bool
+
+	// Write deadline for delivering the backlog during a graceful close.
+	// Only writePump touches it.
+	flushDeadline time.Time
+
+	// Closed when readPump and writePump return
+	readDone  chan struct{}
+	writeDone chan struct{}
+
+	// Successor a room switch registered, which readPump hands the
+	// connection off to, and whether writePump let go of the connection for
+	// it. Only readPump touches next.
+	next      *Client
+	handedOff atomic.Bool
+
+	// A multi-room client's memberships besides its own room, keyed by room.
+	// Each is a member client registered in that room whose frames reach
+	// writePump through fanIn; owner is the client holding the connection.
+	multiRoom    bool
+	membersMutex sync.Mutex
+	members      map[string]*Client
+	fanIn        chan outbound
+	owner        *Client
+
+	// Pongs, which writePump sends ahead of anything queued on send, then
+	// priority audio frames
+	priority chan outbound
+	urgent   chan outbound
+
+	// leaveOnce guarantees the hub is asked to unregister the client exactly
+	// once, even if both pumps exit or one of them panics.
+	leaveOnce sync.Once
+
+	// What the hub does when send is full
+	slowPolicy SlowConsumerPolicy
+
+	// The client's standing under its room's rate limit, and the frames it
+	// dropped
+	limiter     rateLimiter
+	rateLimited atomic.Uint64
+
+	// Holds the client's room audio while it runs ahead of its frame
+	// duration in a room that paces it
+	pacer pacer
+
+	// Unix nanoseconds of the last frame read from or written to the client
+	lastActivity atomic.Int64
+
+	// Unix nanoseconds of the last frame the client sent
+	lastSent atomic.Int64
+
+	// Whether the client moderates its room, whether a moderator or the
+	// admin API has muted it, so its audio is dropped, and the audio it sent
+	// muted
+	moderator    atomic.Bool
+	muted        atomic.Bool
+	mutedDropped atomic.Uint64
+
+	// Checks the connection's signed audio frames, nil if they aren't
+	// signed, and the frames that failed
+	verifier        *frameVerifier
+	frameAuthFailed atomic.Uint64
+
+	// Audio frames the connection sent that failed validation, by why, and
+	// its record of them. Only readPump touches frameGuard.
+	invalidFrames invalidFrames
+	frameGuard    frameGuard
+
+	// The connection's key exchange and chat budgets. Only readPump
+	// touches them.
+	keyExchanges tokenBucket
+	chats        tokenBucket
+
+	// The client the client whispers to, if any, the tags it addresses its
+	// audio to instead of its room, if any, and its number for the next
+	// audio frame it addresses to part of the room. Only readPump touches
+	// them.
+	whisper      string
+	group        []string
+	addressedSeq uint64
+
+	// Which senders' audio the client gets, all if nil, and the frames it
+	// kept out
+	filter   atomic.Pointer[senderFilter]
+	filtered atomic.Uint64
+
+	// The client's display name, if it gave one, whether it gets presence
+	// messages, and when it joined its room
+	name     string
+	presence bool
+	joinedAt time.Time
+
+	// Whether the connection's frames carry a CRC32C, and those from it that
+	// failed theirs
+	integrity     bool
+	corruptFrames atomic.Uint64
+
+	// The connection's VOX gate, if it keys its audio by level
+	vox *voxGate
+
+	// When the client last sent audio loud enough for its room's silence
+	// suppression, and the frames it dropped. Only readPump touches loudAt.
+	loudAt            time.Time
+	silenceSuppressed atomic.Uint64
+
+	// The level of the client's audio, for clients with the levels
+	// capability
+	levelMeter levelMeter
+
+	// The codec the client receives the room's pcm16 audio in, "" for as
+	// sent, and the encoders of the audio it sends for clients receiving
+	// another. Only readPump touches encoders.
+	receive  string
+	encoders encoders
+
+	// Whether the client takes its room's audio at the low tier, and
+	// whether the hub counts it among the clients the audio is encoded
+	// for, under the hub's receivers mutex
+	lowTier   atomic.Bool
+	receiving bool
+
+	// The capabilities negotiated with the client
+	caps capabilities
+
+	// The client's tags, and whether its hub indexes it by them. Guarded by
+	// the hub's tags mutex once the client registers.
+	tags    []string
+	indexed bool
+
+	// Audio frames and chat messages the client whispered and was
+	// whispered, and the clients its connection takes no whispers from
+	whispersSent     atomic.Uint64
+	whispersReceived atomic.Uint64
+
+	// Audio frames the client marked priority without being allowed to
+	priorityStripped atomic.Uint64
+	blocks           *blockList
+
+	// Control messages the client got wrong, and the connection's budgets
+	// of them and of error replies to them. Only readPump touches the
+	// budgets.
+	invalidControlMessages atomic.Uint64
+	controlFlood           tokenBucket
+	controlReplies         tokenBucket
+
+	// The protocol version negotiated with the client, and whether it
+	// stated none at upgrade so its first message may still be a hello.
+	// Only readPump touches versionPending.
+	version        atomic.Int32
+	versionPending bool
+
+	// Audio lost on the way from the client, by its own sequence numbers,
+	// and dropped on the way to it. Kept for the connection alone, not a
+	// multi-room client's other memberships.
+	upstream lossTracker
+	delivery deliveryLoss
+
+	// Messages sent to the client awaiting its acknowledgement
+	acks ackTracker
+
+	// Audio delay from the gateway reading a frame to writing it to the
+	// client's socket, and from the client capturing a frame to the
+	// gateway reading it, with the estimate of the client's clock that
+	// takes
+	queueDelay  latencyGauge
+	uplinkDelay latencyGauge
+	uplink      uplinkClock
+
+	// The number of the client's last audio frame in its room. Only
+	// readPump touches it.
+	framedSeq uint64
+
+	// The audio format the client declared at join, zero if none, and
+	// whether its room let it in despite not allowing the format
+	format         audioFormat
+	formatMismatch bool
+
+	// Whether the client may only listen, and the audio it sent anyway
+	listener        atomic.Bool
+	guard           listenerGuard
+	listenerDropped atomic.Uint64
+
+	// The client's current talk burst, and the bursts cut off for going on
+	// too long, and its burst as talk messages announce it
+	talk        talkBurst
+	talkCutoffs atomic.Uint64
+	speaking    speaking
+
+	// When the client raised its hand in Unix milliseconds, zero if it
+	// hasn't
+	hand atomic.Int64
+
+	// When the client may transmit again after its talk burst was cut off,
+	// in Unix nanoseconds, for other goroutines than its readPump
+	coolsAt atomic.Int64
+
+	// When the client was last told its audio was dropped for not holding
+	// the floor. Only the hub's Run goroutine touches it.
+	floorNoticed time.Time
+
+	// Application heartbeats: the last sequence number sent and when, the
+	// last one echoed, and the latest round trip time in nanoseconds.
+	// heartbeatMissed counts consecutive misses and belongs to writePump.
+	heartbeatSent   atomic.Uint64
+	heartbeatSentAt atomic.Int64
+	heartbeatAcked  atomic.Uint64
+	heartbeatRTT    atomic.Int64
+	heartbeatMissed int
+
+	// The round trip the client reports measuring from its pings, and its
+	// ping budget. Only readPump touches the budget.
+	clientRTT latencyGauge
+	pings     tokenBucket
+
+	// When the client reaches its maximum age, and whether it has been
+	// warned or given a talker's extension. Only the Run goroutine touches
+	// them once the client is registered.
+	expiresAt   time.Time
+	warnedAge   bool
+	deferredAge bool
+
+	// Frames from this client discarded because the broadcast queue was full
+	droppedFrames atomic.Uint64
+
+	// Frames for this client discarded by its slow consumer policy
+	droppedOutbound atomic.Uint64
+}
+
+// closeSend closes the send channel, signalling writePump to send a close
+// frame with the given reason and finish. Graceful reasons deliver what is
+// already queued first; abrupt ones discard it. It is safe to call more than
+// once; the first reason wins.
+func (c *Client) closeSend(reason closeReason) {
+	c.closeOnce.Do(func() {
+		c.closeReason = reason
+		c.closing.Store(true)
+		close(c.send)
+	})
+}
+
+// leave asks the hub to unregister the client with the given close reason.
+// It is safe to call more than once; the first reason wins.
+func (c *Client) leave(reason closeReason) {
+	c.leaveOnce.Do(func() {
+		c.hub.unregisterClient(c, reason)
+		c.abandonAcks()
+	})
+}
+
+// finishPump must be deferred directly by both pumps. It recovers from a
+// panic so a broken pump cannot leave a ghost client behind, then tears the
+// connection down.
+func (c *Client) finishPump(pump string) {
+	reason := reasonNormal
+	if r := recover(); r != nil {
+		log.Printf("Panic in %s for client %s: %v\n%s", pump, c.id, r, debug.Stack())
+		reason = reasonInternal
+	}
+	if !c.handedOff.Load() {
+		c.conn.Close()
+	}
+	c.leave(reason)
+}
+
+// discardQueued releases every message still queued for the client once the
+// hub closes its send channel. writePump runs it when it exits early so
+// frame buffer references aren't leaked.
+func (c *Client) discardQueued() {
+	for message := range c.send {
+		message.release()
+	}
+	c.discardUrgent()
+}
+
+// releaseAddr gives back the client's per-IP connection slot
+func (c *Client) releaseAddr() {
+	if c.releaseIP != nil {
+		c.releaseIP()
+	}
+}
+
+// touch records activity on the connection
+func (c *Client) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// idleFor returns how long the client has been without activity
+func (c *Client) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, c.lastActivity.Load()))
+}
+
+// stats returns a snapshot of the client's counters
+func (c *Client) stats() ClientStats {
+	stats := ClientStats{
+		ID:              c.id,
+		Name:            c.name,
+		Room:            c.hub.room,
+		SlowConsumer:    c.slowPolicy.String(),
+		SendBuffer:      cap(c.send),
+		Queued:          len(c.send),
+		IdleSeconds:     c.idleFor(time.Now()).Seconds(),
+		DroppedFrames:   c.droppedFrames.Load(),
+		DroppedOutbound: c.droppedOutbound.Load(),
+		Protocol:        c.protocol,
+		Version:         int(c.version.Load()),
+		Compression:     c.compression,
+		SocketWrites:    c.batch.Load().socketWrites(),
+		HeartbeatRTTMs:  float64(c.heartbeatRTT.Load()) / float64(time.Millisecond),
+		Moderator:       c.moderator.Load(),
+		Muted:           c.muted.Load(),
+		MutedDropped:    c.mutedDropped.Load(),
+		Role:            c.role(),
+		RateLimited:     c.rateLimited.Load(),
+		ListenerDropped: c.listenerDropped.Load(),
+		FrameAuthFailed: c.frameAuthFailed.Load(),
+		TalkCutoffs:     c.talkCutoffs.Load(),
+		InvalidControl:  c.invalidControlMessages.Load(),
+		InvalidFrames:   c.invalidFrames.stats(),
+
+		SilenceSuppressed: c.silenceSuppressed.Load(),
+		Receive:           c.receive,
+		Tier:              c.tier(),
+
+		WhispersSent:     c.whispersSent.Load(),
+		WhispersReceived: c.whispersReceived.Load(),
+		Blocked:          c.blocks.count(),
+		PriorityStripped: c.priorityStripped.Load(),
+		PaceQueue:        int(c.pacer.depth.Load()),
+		PaceDropped:      c.pacer.dropped.Load(),
+		Integrity:        c.integrity,
+		CorruptFrames:    c.corruptFrames.Load(),
+		Capabilities:     c.caps.names(),
+
+		Filter:   c.filter.Load().stats(),
+		Filtered: c.filtered.Load(),
+	}
+	if c.owner != nil {
+		stats.HomeRoom = c.owner.hub.room
+	}
+	stats.Tags = c.hub.tagsOf(c)
+	if c.format != (audioFormat{}) {
+		format := c.format
+		stats.Format = &format
+		stats.FormatMismatch = c.formatMismatch
+	}
+	// A multi-room client's memberships share their connection's delays
+	conn, now := c, time.Now()
+	if c.owner != nil {
+		conn = c.owner
+	}
+	queueAvg, queueMax := conn.queueDelay.read(now)
+	uplinkAvg, uplinkMax := conn.uplinkDelay.read(now)
+	clientRTT, _ := conn.clientRTT.read(now)
+	stats.ClientRTTMs = float64(clientRTT) / float64(time.Millisecond)
+	stats.QueueDelayAvgUs, stats.QueueDelayMaxUs = micros(queueAvg), micros(queueMax)
+	stats.UplinkDelayAvgUs, stats.UplinkDelayMaxUs = micros(uplinkAvg), micros(uplinkMax)
+	c.lossStats(&stats, now)
+	return stats
+}
+
+// readPump pumps messages from the websocket connection to the hub
+func (c *Client) readPump() {
+	defer c.finishPump("readPump")
+	defer close(c.readDone)
+	defer c.closeWhispers()
+
+	c.conn.SetReadLimit(c.hub.maxMessageSize)
+
+	// Any pong within pongWait keeps the connection alive
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		if c.hub.idleCountsPongs {
+			c.touch()
+		}
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	})
+
+	for {
+		frame, err := c.readFrame()
+		if err != nil {
+			if isTimeout(err) {
+				log.Printf("Client %s missed its pong deadline", c.id)
+				c.leave(reasonPingTimeout)
+			} else if errors.Is(err, websocket.ErrReadLimit) {
+				// The library has already sent 1009 to the peer
+				log.Printf("Client %s sent a message larger than %d bytes", c.id, c.hub.maxMessageSize)
+				c.leave(reasonTooBig)
+			} else if !isCleanClose(err) {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					log.Printf("Error reading message from client %s: %v", c.id, err)
+				}
+				c.leave(reasonLost)
+			}
+			break
+		}
+
+		room, audio, err := c.inbound(frame)
+		if err == errFramedCorrupt {
+			c.dropCorrupt(frame)
+			continue
+		}
+		if err == nil && audio {
+			err = c.checkSeq(frame)
+		}
+		if c.versionPending && !c.settleVersion() {
+			frame.release()
+			log.Printf("Client %s closed: protocol version %d is below %d", c.id, c.version.Load(), c.gateway.minVersion)
+			c.conn.WriteControl(websocket.CloseMessage, reasonOutdatedProtocol.message(), time.Now().Add(closeAckWait))
+			c.leave(reasonOutdatedProtocol)
+			break
+		}
+		if err == errControlFlood {
+			frame.release()
+			c.conn.WriteControl(websocket.CloseMessage, reasonControlFlood.message(), time.Now().Add(closeAckWait))
+			c.leave(reasonControlFlood)
+			break
+		}
+		if reason, invalid := invalidReason(err); invalid {
+			frame.release()
+			if c.rejectFrame(c, reason, err) {
+				c.conn.WriteControl(websocket.CloseMessage, reasonInvalidFrames.message(), time.Now().Add(closeAckWait))
+				c.leave(reasonInvalidFrames)
+				break
+			}
+			continue
+		}
+		if err != nil {
+			frame.release()
+			log.Printf("Client %s sent a frame %s does not allow: %v", c.id, c.protocol, err)
+			// Sent from here because the connection closes when readPump
+			// returns
+			c.conn.WriteControl(websocket.CloseMessage, reasonUnsupportedData.message(), time.Now().Add(closeAckWait))
+			c.leave(reasonUnsupportedData)
+			break
+		}
+		if !audio {
+			frame.release()
+			if c.next != nil {
+				c.handOff()
+				break
+			}
+			continue
+		}
+		arrived := time.Now()
+		c.upstream.observe(frame.header.seq, arrived)
+		c.measureUplink(frame.header.captured, arrived)
+		sender := c.memberFor("audio", room)
+		if sender == nil {
+			frame.release()
+			continue
+		}
+		if sender.muted.Load() {
+			sender.mutedDropped.Add(1)
+			sender.hub.mutedDropped.Add(1)
+			frame.release()
+			continue
+		}
+		if sender.listener.Load() {
+			frame.release()
+			if sender.listenOnly() {
+				c.conn.WriteControl(websocket.CloseMessage, reasonListenOnly.message(), time.Now().Add(closeAckWait))
+				c.leave(reasonListenOnly)
+				break
+			}
+			continue
+		}
+		verified, forged := c.verifyFrame(sender, frame)
+		if forged {
+			frame.release()
+			c.conn.WriteControl(websocket.CloseMessage, reasonFrameAuth.message(), time.Now().Add(closeAckWait))
+			c.leave(reasonFrameAuth)
+			break
+		}
+		if !verified {
+			frame.release()
+			continue
+		}
+		if reason, err := sender.checkAudio(frame); err != nil {
+			frame.release()
+			if c.rejectFrame(sender, reason, err) {
+				c.conn.WriteControl(websocket.CloseMessage, reasonInvalidFrames.message(), time.Now().Add(closeAckWait))
+				c.leave(reasonInvalidFrames)
+				break
+			}
+			continue
+		}
+		sender.checkPriority(frame)
+		if !c.voxAllows(sender, frame, time.Now()) {
+			frame.release()
+			continue
+		}
+		if !sender.audible(frame, time.Now()) {
+			frame.release()
+			continue
+		}
+		if sender.talkLimited() {
+			frame.release()
+			continue
+		}
+		allowed, exceeded := sender.rateLimit(len(frame.data))
+		if exceeded {
+			frame.release()
+			c.conn.WriteControl(websocket.CloseMessage, reasonRateLimited.message(), time.Now().Add(closeAckWait))
+			c.leave(reasonRateLimited)
+			break
+		}
+		if !allowed {
+			frame.release()
+			continue
+		}
+
+		now := time.Now()
+		c.touch()
+		sender.touch()
+		sender.lastSent.Store(now.UnixNano())
+		if sender.whisper != "" {
+			sender.whisperAudio(frame, now)
+			continue
+		}
+		if sender.group != nil {
+			sender.groupAudio(frame, now)
+			continue
+		}
+		sender.stamp(frame, now)
+		if !sender.hub.floorHeld(sender) || frame.header.priority {
+			sender.spoke(now, sender.framedSeq)
+			sender.meter(frame, now)
+		}
+
+		if sender.hub.messageHook != nil {
+			sender.hub.messageHook(sender, frame.data)
+		}
+
+		// Broadcast the audio data to all other clients (excluding sender),
+		// paced if the room paces it. The hub takes over the reader's
+		// reference.
+		sender.pace(BroadcastMessage{
+			frame:  frame,
+			sender: sender,
+		})
+	}
+}
+
+// readFrame reads the next message into a pooled frame buffer
+func (c *Client) readFrame() (*frameBuffer, error) {
+	messageType, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	frame := getFrame()
+	frame.messageType = messageType
+	if err := frame.readFrom(r); err != nil {
+		frame.release()
+		return nil, err
+	}
+	return frame, nil
+}
+
+// writePump pumps messages from the hub to the websocket connection
+func (c *Client) writePump() {
+	defer close(c.writeDone)
+	defer c.hub.pumps.Done()
+	defer c.finishPump("writePump")
+	defer func() {
+		go c.discardQueued()
+	}()
+
+	ticker := time.NewTicker(c.hub.pingPeriod)
+	defer ticker.Stop()
+
+	// A nil channel never fires, which disables application heartbeats
+	var heartbeat <-chan time.Time
+	if c.hub.heartbeatInterval > 0 {
+		hbTicker := time.NewTicker(c.hub.heartbeatInterval)
+		defer hbTicker.Stop()
+		heartbeat = hbTicker.C
+	}
+	var quality <-chan time.Time
+	if c.hub.qualityInterval > 0 {
+		qualityTicker := time.NewTicker(c.hub.qualityInterval)
+		defer qualityTicker.Stop()
+		quality = qualityTicker.C
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, c.writeDeadline()); err != nil {
+				c.writeFailed(err)
+				return
+			}
+
+		case <-heartbeat:
+			if err := c.sendHeartbeat(); err != nil {
+				c.writeFailed(err)
+				return
+			}
+
+		case <-quality:
+			if err := c.sendQuality(); err != nil {
+				c.writeFailed(err)
+				return
+			}
+
+		case message := <-c.priority:
+			closed, err := c.writeBatch(message)
+			if err != nil {
+				c.writeFailed(err)
+				return
+			}
+			if closed {
+				c.writeClose(c.closeReason)
+				return
+			}
+
+		case message := <-c.urgent:
+			closed, err := c.writeBatch(message)
+			if err != nil {
+				c.writeFailed(err)
+				return
+			}
+			if closed {
+				c.writeClose(c.closeReason)
+				return
+			}
+
+		case message := <-c.fanIn:
+			// From one of a multi-room client's other rooms
+			closed, err := c.writeBatch(message)
+			if err != nil {
+				c.writeFailed(err)
+				return
+			}
+			if closed {
+				c.writeClose(c.closeReason)
+				return
+			}
+
+		case message, ok := <-c.send:
+			if !ok {
+				// The hub closed the channel
+				c.writeClose(c.closeReason)
+				return
+			}
+
+			closed, err := c.writeBatch(message)
+			if err != nil {
+				c.writeFailed(err)
+				return
+			}
+			if closed {
+				c.writeClose(c.closeReason)
+				return
+			}
+		}
+	}
+}
+
+// writeBatch writes message plus whatever else is already queued, up to
+// maxCoalescedFrames, and flushes them to the socket together. It reports
+// whether the send channel turned out to be closed. Pongs waiting are taken
+// before each queued message, then priority frames.
+func (c *Client) writeBatch(message outbound) (closed bool, err error) {
+	// The batch reaches the socket when it is uncorked, so one deadline
+	// covers all of it
+	c.conn.SetWriteDeadline(c.writeDeadline())
+	batch := c.batch.Load()
+	batch.cork()
+	err = c.writeQueued(message)
+
+	for n := 1; err == nil && n < maxCoalescedFrames; n++ {
+		var ok bool
+		select {
+		case message = <-c.priority:
+			ok = true
+		case message = <-c.urgent:
+			ok = true
+		default:
+			select {
+			case message, ok = <-c.send:
+			default:
+				return false, batch.uncork()
+			}
+		}
+		if !ok {
+			return true, batch.uncork()
+		}
+		err = c.writeQueued(message)
+	}
+
+	if flushErr := batch.uncork(); err == nil {
+		err = flushErr
+	}
+	return false, err
+}
+
+// writeQueued writes a message taken off the send channel and releases it.
+// Once the client is closing, an abrupt close discards the message and a
+// graceful one delivers it within flushTimeout.
+func (c *Client) writeQueued(message outbound) error {
+	defer message.release()
+	if c.multiRoom && message.room == "" {
+		message.room = c.hub.room
+	}
+	if message.frame != nil && message.frame.messageType == websocket.BinaryMessage {
+		c.delivery.observe(message.room, message.frame.header.sender, message.frame.header.seq, time.Now())
+	}
+
+	if c.closing.Load() {
+		if !c.closeReason.flush {
+			return nil
+		}
+		if c.flushDeadline.IsZero() {
+			c.flushDeadline = time.Now().Add(flushTimeout)
+			c.conn.SetWriteDeadline(c.writeDeadline())
+		}
+	}
+	if err := c.writeCounted(message); err != nil {
+		return err
+	}
+	c.measureQueueing(message, time.Now())
+	c.touch()
+	return nil
+}
+
+// writeDeadline returns the deadline for the next write: the hub's write
+// wait, capped by the flush deadline of a graceful close in progress
+func (c *Client) writeDeadline() time.Time {
+	deadline := time.Now().Add(c.hub.writeWait)
+	if !c.flushDeadline.IsZero() && c.flushDeadline.Before(deadline) {
+		deadline = c.flushDeadline
+	}
+	return deadline
+}
+
+// writeFailed logs a failed write and unregisters the client. A write that
+// hit its deadline gets a best-effort close frame saying so.
+func (c *Client) writeFailed(err error) {
+	if isTimeout(err) {
+		log.Printf("Write to client %s timed out", c.id)
+		c.conn.WriteControl(websocket.CloseMessage, reasonWriteTimeout.message(), time.Now().Add(closeAckWait))
+		c.leave(reasonWriteTimeout)
+		return
+	}
+	log.Printf("Error writing message to client %s: %v", c.id, err)
+	c.leave(reasonLost)
+}
+
+// writeClose sends a close frame and waits briefly for the peer to
+// acknowledge it, which ends readPump
+func (c *Client) writeClose(reason closeReason) {
+	switch reason {
+	case reasonLost:
+		return
+	case reasonSwitched:
+		// The connection carries on in another room
+		c.handedOff.Store(true)
+		return
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(closeAckWait))
+	if err := c.conn.WriteMessage(websocket.CloseMessage, reason.message()); err != nil {
+		return
+	}
+	select {
+	case <-c.readDone:
+	case <-time.After(closeAckWait):
+	}
+}
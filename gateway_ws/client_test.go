@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Every path that closes a client's send channel may fire at once; the
+// first reason wins and the channel is closed once
+func TestCloseSendIdempotent(t *testing.T) {
+	reasons := []closeReason{reasonSlowConsumer, reasonNormal, reasonLost, reasonShutdown}
+	client := &Client{send: make(chan outbound, 1)}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(reason closeReason) {
+			defer wg.Done()
+			client.closeSend(reason)
+		}(reasons[i%len(reasons)])
+	}
+	wg.Wait()
+	if !client.closing.Load() {
+		t.Fatal("client not closing")
+	}
+	if _, open := <-client.send; open {
+		t.Fatal("send still open")
+	}
+	found := false
+	for _, reason := range reasons {
+		found = found || client.closeReason == reason
+	}
+	if !found {
+		t.Errorf("close reason %v is none of those given", client.closeReason)
+	}
+}
+
+// A slow consumer's eviction racing its own connection dropping unregisters
+// it once, without closing its send channel twice
+func TestEvictionRacesUnregister(t *testing.T) {
+	tg := newStallingGateway(t, nil)
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	hub := tg.hub(t, defaultRoom)
+	payload := make([]byte, 16<<10)
+
+	for i := 0; i < 20; i++ {
+		conn, _ := tg.dial(t, "/ws?send_buffer=1", fmt.Sprintf("slow%d", i))
+		waitFor(t, "the slow client to join", func() bool { return hub.ClientCount() == 2 })
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for j := 0; j < 20; j++ {
+				talker.WriteMessage(websocket.BinaryMessage, payload)
+			}
+		}()
+		time.Sleep(time.Duration(i%5) * time.Millisecond)
+		conn.UnderlyingConn().Close()
+		<-done
+		waitFor(t, "the slow client to leave", func() bool { return hub.ClientCount() == 1 })
+	}
+	if hub.lookup("talker") == nil {
+		t.Fatal("talker was unregistered")
+	}
+}
+
+// A panic in readPump, here from a message hook, force-closes the client's
+// connection and unregisters it, leaving the room empty
+func TestPanicUnregisters(t *testing.T) {
+	hook := func(client *Client, data []byte) {
+		if data[0] == 0xff {
+			panic("malformed frame")
+		}
+	}
+	tg := newTestGateway(t, []HubOption{WithMessageHook(hook)})
+	hub := tg.hub(t, defaultRoom)
+	var conns []*websocket.Conn
+	for i := 0; i < 3; i++ {
+		conn, _ := tg.dial(t, "/ws", fmt.Sprintf("c%d", i))
+		readControl(t, conn, "joined")
+		conns = append(conns, conn)
+	}
+	waitFor(t, "the clients to join", func() bool { return hub.ClientCount() == 3 })
+
+	for _, conn := range conns {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcmFrame(0xff)); err != nil {
+			t.Fatal(err)
+		}
+		expectClosed(t, conn)
+	}
+	waitFor(t, "the room to empty", func() bool { return hub.ClientCount() == 0 })
+	for i := range conns {
+		if hub.lookup(fmt.Sprintf("c%d", i)) != nil {
+			t.Errorf("c%d still registered", i)
+		}
+	}
+}
+
+// A client closed gracefully still gets the frames queued for it before
+// the close, then the close frame; one closed abruptly loses them
+func TestGracefulCloseFlushes(t *testing.T) {
+	const frames = 40
+	tests := []struct {
+		name   string
+		reason closeReason
+	}{
+		{"normal", reasonNormal},
+		{"room closed", reasonRoomClosed},
+		{"kicked", reasonKicked},
+		{"removed by moderator", reasonRemovedByModerator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newStallingGateway(t, []HubOption{WithShards(1), WithWorkers(1)})
+			talker, _ := tg.dial(t, "/ws", "talker")
+			readControl(t, talker, "joined")
+			witness, _ := tg.dial(t, "/ws", "witness")
+			readControl(t, witness, "joined")
+			listener, _ := tg.dial(t, "/ws", "listener")
+			readControl(t, listener, "joined")
+			hub := tg.hub(t, defaultRoom)
+
+			// The witness getting the last frame means the single worker
+			// is done queueing the ones before it for the listener too
+			payload := make([]byte, 16<<10)
+			for i := 0; i <= frames; i++ {
+				payload[0] = byte(i)
+				if err := talker.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for {
+				if frame := readAudio(t, witness); frame[0] == frames {
+					break
+				}
+			}
+			hub.removeClient(hub.lookup("listener"), tt.reason)
+
+			received := 0
+			listener.SetReadDeadline(time.Now().Add(testTimeout))
+			var err error
+			for {
+				var messageType int
+				var data []byte
+				if messageType, data, err = listener.ReadMessage(); err != nil {
+					break
+				}
+				if messageType == websocket.BinaryMessage && data[0] < frames {
+					received++
+				}
+			}
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) || closeErr.Code != tt.reason.code {
+				t.Fatalf("read ended with %v, want close code %d", err, tt.reason.code)
+			}
+			if tt.reason.flush && received != frames {
+				t.Errorf("received %d of %d queued frames", received, frames)
+			}
+			if !tt.reason.flush && received == frames {
+				t.Error("received every queued frame")
+			}
+		})
+	}
+}
+
+// A client whose connection stops answering pings is evicted once pongWait
+// passes without a pong, while one that answers stays
+func TestPingTimeout(t *testing.T) {
+	const (
+		pingPeriod = 50 * time.Millisecond
+		pongWait   = 200 * time.Millisecond
+	)
+	tests := []struct {
+		name    string
+		answers bool
+	}{
+		{"answers pings", true},
+		{"blackholed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGateway(t, []HubOption{WithKeepalive(pingPeriod, pongWait)})
+			conn, _ := tg.dial(t, "/ws", "c")
+			joined := time.Now()
+			hub := tg.hub(t, defaultRoom)
+			// The client library answers pings only while reading, so one
+			// that never reads plays a connection that went dark
+			if tt.answers {
+				go drain(conn, nil, nil)
+				time.Sleep(4 * pongWait)
+				if hub.ClientCount() != 1 {
+					t.Error("client answering pings was evicted")
+				}
+				return
+			}
+			waitFor(t, "the client to be evicted", func() bool { return hub.ClientCount() == 0 })
+			if took := time.Since(joined); took < pongWait || took > pongWait+time.Second {
+				t.Errorf("evicted after %v, want about %v", took, pongWait)
+			}
+		})
+	}
+}
+
+// A listener that stops reading without its connection closing stalls
+// writePump mid-write once the socket buffers fill; the write gives up at
+// the write wait and the listener leaves with a timeout
+func TestWriteTimeout(t *testing.T) {
+	const writeWait = 200 * time.Millisecond
+	tg := newStallingGateway(t, []HubOption{WithWriteWait(writeWait)})
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	// Dropping rather than evicting what overflows its send buffer leaves
+	// the listener to the write deadline
+	stuck, _ := tg.dial(t, "/ws?slow_consumer=drop-newest", "stuck")
+	readControl(t, talker, "presence")
+	hub := tg.hub(t, defaultRoom)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		payload := make([]byte, 16<<10)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				talker.WriteMessage(websocket.BinaryMessage, payload)
+			}
+		}
+	}()
+	start := time.Now()
+	var left map[string]any
+	for left == nil || left["event"] != presenceLeave {
+		left = readControl(t, talker, "presence")
+	}
+	if took := time.Since(start); took > writeWait+2*time.Second {
+		t.Errorf("writer gave up after %v, want within about %v", took, writeWait)
+	}
+	if left["id"] != "stuck" || left["reason"] != leftTimeout {
+		t.Errorf("got %v, want stuck leaving with a timeout", left)
+	}
+	if hub.lookup("stuck") != nil {
+		t.Error("stuck listener still registered")
+	}
+	// The close frame is best effort behind a full socket
+	if code := expectClosed(t, stuck); code != CloseWriteTimeout && code != websocket.CloseAbnormalClosure {
+		t.Errorf("closed with %d, want %d", code, CloseWriteTimeout)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Client certificate modes
+const (
+	// clientCertRequire fails the handshake of a client without a valid
+	// certificate (default)
+	clientCertRequire = "require"
+	// clientCertOptional verifies a certificate if one is given, and lets
+	// clients without one in to authenticate as before
+	clientCertOptional = "optional"
+)
+
+// ParseClientCertMode parses how the TLS listener treats client
+// certificates
+func ParseClientCertMode(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case clientCertRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	case clientCertOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	}
+	return 0, fmt.Errorf("unknown client certificate mode %q: want require or optional", mode)
+}
+
+// clientCAs holds the CA certificates client certificates must chain to,
+// read from a PEM file and reread on SIGHUP
+type clientCAs struct {
+	path string
+
+	mutex sync.RWMutex
+	pool  *x509.CertPool
+}
+
+// LoadClientCAs reads the PEM file of CA certificates at path
+func LoadClientCAs(path string) (*clientCAs, error) {
+	c := &clientCAs{path: path}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload rereads the CA file. If it doesn't load, the CAs already loaded
+// stay in use.
+func (c *clientCAs) reload() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("reading client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("client CA file %s holds no PEM certificates", c.path)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pool = pool
+	return nil
+}
+
+// apply makes the listener's config ask for client certificates as auth
+// says and verify them against the CAs loaded last. ACME TLS-ALPN-01
+// challenges are let through without one, since Let's Encrypt has none.
+func (c *clientCAs) apply(config *tls.Config, auth tls.ClientAuthType) {
+	base := config.Clone()
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+			return nil, nil
+		}
+		c.mutex.RLock()
+		pool := c.pool
+		c.mutex.RUnlock()
+		handshake := base.Clone()
+		handshake.ClientCAs = pool
+		handshake.ClientAuth = auth
+		return handshake, nil
+	}
+}
+
+// CertRule maps client certificates to a role and the rooms they may join.
+// A rule matches a certificate whose client ID, organization and
+// organizational unit match every one it sets; the ID may be a prefix
+// ending in '*'. Role and rooms are as in a bearer token.
+type CertRule struct {
+	ID                 string `json:"id,omitempty"`
+	Organization       string `json:"organization,omitempty"`
+	OrganizationalUnit string `json:"organizational_unit,omitempty"`
+
+	Role  string    `json:"role,omitempty"`
+	Rooms *[]string `json:"rooms,omitempty"`
+}
+
+// matches reports whether the rule applies to the certificate of the client
+// with the given ID
+func (rule CertRule) matches(id string, cert *x509.Certificate) bool {
+	if rule.ID != "" {
+		prefix, wildcard := strings.CutSuffix(rule.ID, "*")
+		if wildcard && !strings.HasPrefix(id, prefix) || !wildcard && id != rule.ID {
+			return false
+		}
+	}
+	if rule.Organization != "" && !slices.Contains(cert.Subject.Organization, rule.Organization) {
+		return false
+	}
+	if rule.OrganizationalUnit != "" && !slices.Contains(cert.Subject.OrganizationalUnit, rule.OrganizationalUnit) {
+		return false
+	}
+	return true
+}
+
+// LoadCertMapping reads a JSON array of certificate rules from path
+func LoadCertMapping(path string) ([]CertRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate mapping: %w", err)
+	}
+	var rules []CertRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("certificate mapping %s: %w", path, err)
+	}
+	for i, rule := range rules {
+		switch rule.Role {
+		case "", roleParticipant, "moderator", roleListener:
+		default:
+			return nil, fmt.Errorf("certificate mapping %s: rule %d: unknown role %q", path, i+1, rule.Role)
+		}
+	}
+	return rules, nil
+}
+
+// WithClientCertAuth lets a client identify itself with a verified TLS
+// client certificate in place of a bearer token. The first of rules the
+// certificate matches gives its role and rooms; one matching none may join
+// any room as a participant.
+func WithClientCertAuth(rules []CertRule) GatewayOption {
+	return func(g *Gateway) {
+		g.clientCerts = true
+		g.certRules = rules
+	}
+}
+
+// errCertificateUnnamed refuses a certificate naming no client
+var errCertificateUnnamed = errors.New("client certificate has no common name or subject alternative name")
+
+// certificateID returns the client ID a certificate names: its common name,
+// or else its first DNS name, email address or URI
+func certificateID(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return ""
+}
+
+// certificateClaims returns the claims of the request's verified client
+// certificate, or nil if it has none
+func (g *Gateway) certificateClaims(r *http.Request) (*tokenClaims, error) {
+	if !g.clientCerts || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	id := certificateID(cert)
+	if id == "" {
+		return nil, errCertificateUnnamed
+	}
+	claims := &tokenClaims{Subject: id}
+	for _, rule := range g.certRules {
+		if !rule.matches(id, cert) {
+			continue
+		}
+		switch rule.Role {
+		case "moderator":
+			claims.Moderator = true
+		case roleListener:
+			claims.Listener = true
+		}
+		if rule.Rooms != nil {
+			claims.Rooms = append([]string{}, *rule.Rooms...)
+		}
+		break
+	}
+	return claims, nil
+}
+
+// admitCertificate returns the claims of the request's verified client
+// certificate, nil if it has none. It answers 403 and reports false for a
+// certificate naming no client.
+func (g *Gateway) admitCertificate(w http.ResponseWriter, r *http.Request) (*tokenClaims, bool) {
+	claims, err := g.certificateClaims(r)
+	if err != nil {
+		log.Printf("Upgrade from %s refused: %v", r.RemoteAddr, err)
+		g.auditRefused(r, "", "", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
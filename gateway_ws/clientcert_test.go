@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testCA issues certificates for the tests, made on the fly
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t testing.TB) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for the subject signed by the CA, for a
+// server at 127.0.0.1 or a client
+func (ca *testCA) issue(t testing.TB, subject pkix.Name, dnsNames []string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if usage == x509.ExtKeyUsageServerAuth {
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTLSGateway serves a gateway taking client certificates from the CA as
+// auth says over TLS, and returns it and a dialer that trusts it
+func newTLSGateway(t *testing.T, ca *testCA, auth tls.ClientAuthType, rules []CertRule) (*testGateway, websocket.Dialer) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, ca.pem, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := LoadClientCAs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(nil, WithClientCertAuth(rules))
+	server := httptest.NewUnstartedServer(testMux(g))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, pkix.Name{CommonName: "gateway"}, nil, x509.ExtKeyUsageServerAuth)}}
+	cas.apply(server.TLS, auth)
+	server.StartTLS()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dialer := websocket.Dialer{HandshakeTimeout: testTimeout, TLSClientConfig: &tls.Config{RootCAs: roots}}
+	return stopWithTest(t, g, server), dialer
+}
+
+// A verified client certificate names the client and, through the mapping,
+// its role and rooms; without one the handshake fails unless certificates
+// are optional, and one from another CA always fails it
+func TestClientCertAuth(t *testing.T) {
+	ca, other := newTestCA(t), newTestCA(t)
+	fire := []string{"fire-*"}
+	rules := []CertRule{{Organization: "Fire", Role: "moderator", Rooms: &fire}}
+	radio := ca.issue(t, pkix.Name{CommonName: "radio-7"}, nil, x509.ExtKeyUsageClientAuth)
+	engine := ca.issue(t, pkix.Name{CommonName: "engine-1", Organization: []string{"Fire"}}, nil, x509.ExtKeyUsageClientAuth)
+	unnamed := ca.issue(t, pkix.Name{}, []string{"handset.example"}, x509.ExtKeyUsageClientAuth)
+	foreign := other.issue(t, pkix.Name{CommonName: "radio-7"}, nil, x509.ExtKeyUsageClientAuth)
+	tests := []struct {
+		name string
+		auth tls.ClientAuthType
+		cert *tls.Certificate
+		room string
+		// The client ID registered, empty if the handshake fails, and for
+		// a refused upgrade its status
+		id        string
+		status    int
+		moderator bool
+	}{
+		{"named by its certificate", tls.RequireAndVerifyClientCert, &radio, "ops", "radio-7", 0, false},
+		{"named by its SAN", tls.RequireAndVerifyClientCert, &unnamed, "ops", "handset.example", 0, false},
+		{"mapped to moderator", tls.RequireAndVerifyClientCert, &engine, "fire-north", "engine-1", 0, true},
+		{"mapped room refused", tls.RequireAndVerifyClientCert, &engine, "ops", "", http.StatusForbidden, false},
+		{"no certificate", tls.RequireAndVerifyClientCert, nil, "ops", "", 0, false},
+		{"another CA", tls.RequireAndVerifyClientCert, &foreign, "ops", "", 0, false},
+		{"optional without one", tls.VerifyClientCertIfGiven, nil, "ops", "header-id", 0, false},
+		{"optional with one", tls.VerifyClientCertIfGiven, &radio, "ops", "radio-7", 0, false},
+		{"optional from another CA", tls.VerifyClientCertIfGiven, &foreign, "ops", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg, dialer := newTLSGateway(t, ca, tt.auth, rules)
+			if tt.cert != nil {
+				dialer.TLSClientConfig.Certificates = []tls.Certificate{*tt.cert}
+			}
+			header := http.Header{"X-Client-ID": {"header-id"}}
+			conn, resp, err := dialer.Dial(tg.wsURL("/ws/"+tt.room), header)
+			switch {
+			case tt.status != 0:
+				if resp == nil || resp.StatusCode != tt.status {
+					t.Fatalf("got %v, want %d", err, tt.status)
+				}
+				return
+			case tt.id == "":
+				if err == nil {
+					conn.Close()
+					t.Fatal("handshake succeeded")
+				}
+				if resp != nil {
+					t.Fatalf("got %s from the server, want the handshake to fail", resp.Status)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			readControl(t, conn, "joined")
+			client := tg.hub(t, tt.room).lookup(tt.id)
+			if client == nil {
+				t.Fatalf("no client %s", tt.id)
+			}
+			if moderator := client.claims != nil && client.claims.Moderator; moderator != tt.moderator {
+				t.Errorf("moderator %t, want %t", moderator, tt.moderator)
+			}
+		})
+	}
+}
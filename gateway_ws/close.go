@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Application close codes (4000-4999 is reserved for private use by RFC 6455)
+const (
+	// CloseSlowConsumer means the client could not keep up with the audio
+	// stream and its send buffer overflowed
+	CloseSlowConsumer = 4000
+
+	// CloseTakenOver means another connection registered with the same
+	// client ID and replaced this one
+	CloseTakenOver = 4001
+
+	// ClosePingTimeout means the client stopped answering keepalive pings
+	ClosePingTimeout = 4002
+
+	// CloseWriteTimeout means a write to the client blocked for longer than
+	// the write deadline
+	CloseWriteTimeout = 4003
+
+	// CloseIdleTimeout means the client neither sent nor received any audio
+	// for longer than the idle timeout
+	CloseIdleTimeout = 4004
+
+	// CloseHeartbeatTimeout means the client stopped echoing application
+	// heartbeats
+	CloseHeartbeatTimeout = 4005
+
+	// CloseRemovedByModerator means a moderator of the client's room kicked
+	// it
+	CloseRemovedByModerator = 4006
+
+	// CloseRoomClosed means the client's room closed by its schedule
+	CloseRoomClosed = 4007
+
+	// CloseKeyRevoked means the API key the client connected with was
+	// revoked
+	CloseKeyRevoked = 4008
+
+	// CloseAddressDenied means the client's address stopped being allowed
+	// to connect when the address lists were reloaded
+	CloseAddressDenied = 4009
+
+	// CloseOutdatedProtocol means the client speaks a protocol version
+	// below the gateway's minimum and must be updated
+	CloseOutdatedProtocol = 4010
+)
+
+const (
+	// closeAckWait is how long writePump waits for the peer to acknowledge
+	// a server-initiated close frame before tearing down the connection
+	closeAckWait = time.Second
+
+	// flushTimeout bounds how long a graceful close spends delivering the
+	// frames that were already queued
+	flushTimeout = 2 * time.Second
+)
+
+// closeReason is the close frame sent when the server disconnects a client
+type closeReason struct {
+	code int
+	text string
+
+	// Graceful closes deliver already queued frames before the close
+	// frame; abrupt ones discard them
+	flush bool
+}
+
+// Close reasons for every server-initiated disconnect
+var (
+	reasonNormal             = closeReason{websocket.CloseNormalClosure, "normal closure", true}
+	reasonShutdown           = closeReason{websocket.CloseGoingAway, "server shutting down", true}
+	reasonKicked             = closeReason{websocket.ClosePolicyViolation, "kicked", false}
+	reasonBanned             = closeReason{websocket.ClosePolicyViolation, "banned", false}
+	reasonSlowConsumer       = closeReason{CloseSlowConsumer, "slow consumer", false}
+	reasonTakenOver          = closeReason{CloseTakenOver, "session taken over", false}
+	reasonPingTimeout        = closeReason{ClosePingTimeout, "ping timeout", false}
+	reasonWriteTimeout       = closeReason{CloseWriteTimeout, "write timeout", false}
+	reasonTooBig             = closeReason{websocket.CloseMessageTooBig, "message too big", false}
+	reasonUnsupportedData    = closeReason{websocket.CloseUnsupportedData, "unsupported data", false}
+	reasonRateLimited        = closeReason{websocket.ClosePolicyViolation, "rate limit exceeded", false}
+	reasonListenOnly         = closeReason{websocket.ClosePolicyViolation, "listen only", false}
+	reasonFrameAuth          = closeReason{websocket.ClosePolicyViolation, "frame authentication failed", false}
+	reasonControlFlood       = closeReason{websocket.ClosePolicyViolation, "too many invalid control messages", false}
+	reasonInvalidFrames      = closeReason{websocket.ClosePolicyViolation, "too many invalid audio frames", false}
+	reasonIdle               = closeReason{CloseIdleTimeout, "idle timeout", true}
+	reasonHeartbeatTimeout   = closeReason{CloseHeartbeatTimeout, "heartbeat timeout", false}
+	reasonInternal           = closeReason{websocket.CloseInternalServerErr, "internal error", false}
+	reasonRemovedByModerator = closeReason{CloseRemovedByModerator, "removed by moderator", false}
+	reasonRoomClosed         = closeReason{CloseRoomClosed, "room closed", true}
+	reasonKeyRevoked         = closeReason{CloseKeyRevoked, "key revoked", false}
+	reasonAddressDenied      = closeReason{CloseAddressDenied, "address denied", false}
+	reasonOutdatedProtocol   = closeReason{CloseOutdatedProtocol, "update your app", false}
+
+	// The handshake failed after the client was registered; nothing is
+	// sent and the client never gets a session
+	reasonUpgradeFailed = closeReason{websocket.CloseNormalClosure, "upgrade failed", false}
+
+	// The client moved to another room over the same connection; nothing is
+	// sent and the connection stays open
+	reasonSwitched = closeReason{websocket.CloseNormalClosure, "switched rooms", false}
+
+	// A multi-room client left one of its rooms, or its connection closed;
+	// only the membership ends
+	reasonLeft = closeReason{websocket.CloseNormalClosure, "left room", false}
+
+	// Clients should reconnect immediately on this one
+	reasonMaxAge = closeReason{websocket.CloseNormalClosure, "reconnect", true}
+
+	// The connection failed without a close frame. 1006 may not be sent on
+	// the wire, so writePump sends nothing for it.
+	reasonLost = closeReason{websocket.CloseAbnormalClosure, "connection lost", false}
+)
+
+// isCleanClose reports whether a read error is the peer's close frame, as
+// opposed to the connection failing. The library reports a connection that
+// ended without one as 1006.
+func isCleanClose(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr) && closeErr.Code != websocket.CloseAbnormalClosure
+}
+
+// isTimeout reports whether err is a network deadline expiry
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// resumable reports whether a client disconnected for this reason may
+// resume its session. Disconnects the server meant to be final end it, as
+// does any policy violation, whatever its text.
+func (r closeReason) resumable() bool {
+	if r.code == websocket.ClosePolicyViolation {
+		return false
+	}
+	switch r {
+	case reasonShutdown, reasonKicked, reasonBanned, reasonRemovedByModerator, reasonTakenOver, reasonIdle, reasonRoomClosed, reasonKeyRevoked, reasonAddressDenied, reasonOutdatedProtocol, reasonUpgradeFailed, reasonSwitched, reasonLeft:
+		return false
+	}
+	return true
+}
+
+// abnormal reports whether a client disconnected for this reason vanished,
+// rather than leaving cleanly or being removed on purpose. Only abnormal
+// disconnects publish the client's last will.
+func (r closeReason) abnormal() bool {
+	switch r {
+	case reasonLost, reasonPingTimeout, reasonHeartbeatTimeout, reasonWriteTimeout, reasonSlowConsumer, reasonInternal:
+		return true
+	}
+	return false
+}
+
+// message returns the close frame payload for the reason
+func (r closeReason) message() []byte {
+	return websocket.FormatCloseMessage(r.code, r.text)
+}
+
+// String implements fmt.Stringer for log lines
+func (r closeReason) String() string {
+	return r.text
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"compress/flate"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultCompressionLevel favours CPU over ratio; control messages are small
+const defaultCompressionLevel = flate.BestSpeed
+
+// compressionStats counts bytes written to clients with and without
+// permessage-deflate, so the CPU spent can be weighed against the bytes saved
+type compressionStats struct {
+	// Payload bytes of compressed messages, and what they took on the wire
+	payload    atomic.Uint64
+	compressed atomic.Uint64
+
+	// Wire bytes of messages sent uncompressed
+	uncompressed atomic.Uint64
+}
+
+// offersCompression reports whether the handshake offers permessage-deflate,
+// which the upgrader then accepts if compression is enabled
+func offersCompression(r *http.Request) bool {
+	for _, value := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name := strings.TrimSpace(strings.SplitN(ext, ";", 2)[0])
+			if strings.EqualFold(name, "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressible reports whether the message is worth compressing. Control
+// messages are JSON and shrink well; audio is already compressed by its
+// codec, so deflating it only costs CPU.
+func (m outbound) compressible() bool {
+	if m.frame != nil {
+		return m.frame.messageType == websocket.TextMessage
+	}
+	return m.messageType == websocket.TextMessage
+}
+
+// payloadSize returns the size of the message before framing
+func (m outbound) payloadSize() int {
+	if m.frame != nil {
+		return len(m.frame.data)
+	}
+	return len(m.data)
+}
+
+// writeCounted writes the message with compression switched on or off as
+// the message deserves and adds it to the hub's compression counters
+func (c *Client) writeCounted(message outbound) error {
+	compress := c.compression && message.compressible()
+	c.conn.EnableWriteCompression(compress)
+
+	batch := c.batch.Load()
+	before := batch.bytesWritten()
+	err := message.write(c.conn, c.protocol, c.integrity)
+	wire := batch.bytesWritten() - before
+
+	stats := &c.hub.compression
+	if compress {
+		stats.payload.Add(uint64(message.payloadSize()))
+		stats.compressed.Add(wire)
+	} else {
+		stats.uncompressed.Add(wire)
+	}
+	return err
+}
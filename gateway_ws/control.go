@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Audio travels in binary frames. Control messages are JSON objects in text
+// frames, told apart by their type field, in both directions; the ones
+// clients send are handled by the server and never broadcast.
+
+const (
+	// maxWillSize caps the last-will payload a client may register
+	maxWillSize = 1024
+
+	// controlErrorReplyRate and controlErrorReplyBurst are how many errors a
+	// second, sustained and at once, a connection is sent for control
+	// messages that don't parse, have an unknown type or fail validation.
+	// The rest are dropped silently, so a broken client can't turn the
+	// server into an echo.
+	controlErrorReplyRate  = 1
+	controlErrorReplyBurst = 10
+)
+
+// errControlFlood closes a connection over its limit of invalid control
+// messages
+var errControlFlood = errors.New("too many invalid control messages")
+
+// willMessage is broadcast when a client that registered a last will
+// disconnects abnormally
+type willMessage struct {
+	Type    string `json:"type"`
+	Room    string `json:"room"`
+	ID      string `json:"id"`
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Payload string `json:"payload"`
+}
+
+// controlMessage is a control message from a client. Seq belongs to
+// heartbeat echoes, Target to moderator actions and key exchanges, Payload
+// to key exchanges, Room and JoinToken to joining and leaving rooms, and
+// Version to hellos. A
+// multi-room client also names the room a moderator action or key exchange
+// is for.
+type controlMessage struct {
+	Type      string          `json:"type"`
+	Seq       uint64          `json:"seq"`
+	Target    string          `json:"target"`
+	Room      string          `json:"room"`
+	JoinToken string          `json:"join_token"`
+	Payload   json.RawMessage `json:"payload"`
+	Version   int             `json:"version"`
+
+	// Set on a message to relay that asks to be acknowledged, and on the
+	// acknowledgement
+	ID  string `json:"id"`
+	Ack bool   `json:"ack"`
+
+	// A ping's nonce and the client's clock in Unix milliseconds, echoed in
+	// the pong, and the round trip it measured from its last pong in
+	// milliseconds
+	Nonce json.RawMessage `json:"nonce"`
+	T     int64           `json:"t"`
+	RTT   float64         `json:"rtt"`
+
+	// The audio format a join declares, if other than the connection's
+	Format *audioFormat `json:"format"`
+
+	// A chat message's text, and whether the sender gets its own copy
+	Text string `json:"text"`
+	Echo bool   `json:"echo"`
+
+	// The tags a join declares, or a group or chat message addresses
+	Tags []string `json:"tags"`
+
+	// A sender filter's mode and senders
+	Mode    string   `json:"mode"`
+	Senders []string `json:"senders"`
+
+	// The priority a floor request asks for
+	Priority int `json:"priority"`
+
+	// The tier a tier message switches to
+	Tier string `json:"tier"`
+}
+
+// controlError answers a control message the server refused
+type controlError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Request string `json:"request"`
+	Target  string `json:"target,omitempty"`
+
+	// The id of a message that asked to be acknowledged
+	ID string `json:"id,omitempty"`
+
+	// What was wrong with a message refused as malformed or invalid
+	Detail string `json:"detail,omitempty"`
+
+	// When a room refused as closed by its schedule next opens
+	OpensAt *time.Time `json:"opens_at,omitempty"`
+}
+
+// controlHandler handles one type of control message from a client.
+// validate, if set, checks the message first; one failing it gets an
+// invalid error. Only readPump calls them.
+type controlHandler struct {
+	validate func(message controlMessage) error
+	handle   func(c *Client, message controlMessage)
+}
+
+// controlHandlers holds the handler for each type of control message
+// clients may send. registerControl adds more.
+var controlHandlers = map[string]controlHandler{}
+
+// registerControl installs the handler for a type of control message. It
+// must be called before the gateway serves, from the type's own file.
+func registerControl(messageType string, handler controlHandler) {
+	if _, exists := controlHandlers[messageType]; exists {
+		panic("control message type registered twice: " + messageType)
+	}
+	controlHandlers[messageType] = handler
+}
+
+// requireField returns a validation that the field named name, read by
+// value, is set
+func requireField(name string, value func(controlMessage) bool) func(controlMessage) error {
+	err := fmt.Errorf("missing %s", name)
+	return func(message controlMessage) error {
+		if !value(message) {
+			return err
+		}
+		return nil
+	}
+}
+
+// handleControl parses and dispatches a control message from the client.
+// A message that doesn't parse, has an unknown type or fails validation is
+// counted and answered with an error, at most controlErrorReplyRate a
+// second. It returns errControlFlood once the connection is over the
+// gateway's limit of them.
+func (c *Client) handleControl(data []byte) error {
+	var message controlMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return c.invalidControl("malformed", "", err)
+	}
+	return c.dispatchControl(message)
+}
+
+// dispatchControl hands a parsed control message to its type's handler, as
+// handleControl does
+func (c *Client) dispatchControl(message controlMessage) error {
+	if message.Type == "" {
+		return c.invalidControl("malformed", "", errors.New("missing type"))
+	}
+	handler, ok := controlHandlers[message.Type]
+	if !ok {
+		return c.invalidControl("unknown_type", message.Type, nil)
+	}
+	if handler.validate != nil {
+		if err := handler.validate(message); err != nil {
+			return c.invalidControl("invalid", message.Type, err)
+		}
+	}
+	handler.handle(c, message)
+	return nil
+}
+
+// invalidControl counts a control message the client's connection got
+// wrong, and answers it with code unless the connection has had its share
+// of error replies. Only readPump may call it.
+func (c *Client) invalidControl(code, request string, err error) error {
+	c.invalidControlMessages.Add(1)
+	c.hub.invalidControl.Add(1)
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	c.gateway.debugf("Client %s sent a control message refused as %s: %s %s", c.id, code, request, detail)
+
+	now := time.Now()
+	if limit := c.gateway.controlErrorLimit; limit > 0 {
+		if c.controlFlood.last.IsZero() {
+			c.controlFlood = tokenBucket{tokens: float64(limit), last: now}
+		}
+		c.controlFlood.refill(float64(limit)/60, float64(limit), now)
+		if c.controlFlood.tokens < 1 {
+			log.Printf("Client %s closed after %d invalid control messages", c.id, c.invalidControlMessages.Load())
+			return errControlFlood
+		}
+		c.controlFlood.tokens--
+	}
+
+	if c.controlReplies.last.IsZero() {
+		c.controlReplies = tokenBucket{tokens: controlErrorReplyBurst, last: now}
+	}
+	if c.version.Load() < versionControlErrors {
+		return nil
+	}
+	c.controlReplies.refill(controlErrorReplyRate, controlErrorReplyBurst, now)
+	if c.controlReplies.tokens < 1 {
+		return nil
+	}
+	c.controlReplies.tokens--
+	c.hub.sendControl(c, controlError{Type: "error", Code: code, Request: request, Detail: detail})
+	return nil
+}
+
+// WithControlErrorLimit closes a connection that sends more than limit
+// control messages a minute that don't parse, have an unknown type or fail
+// validation, in bursts of up to limit. Zero never closes it.
+func WithControlErrorLimit(limit int) GatewayOption {
+	return func(g *Gateway) {
+		if limit >= 0 {
+			g.controlErrorLimit = limit
+		}
+	}
+}
+
+// publishWill broadcasts the client's last will along with why it
+// disconnected. It is called from removeClient, which may run on the Run
+// goroutine itself, so the frame is handed to the hub from a new goroutine.
+func (h *Hub) publishWill(client *Client, reason closeReason) {
+	data, err := json.Marshal(willMessage{
+		Type:    "will",
+		Room:    localRoom(h.room),
+		ID:      client.id,
+		Code:    reason.code,
+		Reason:  reason.text,
+		Payload: client.will,
+	})
+	if err != nil {
+		log.Printf("Error encoding last will for client %s: %v", client.id, err)
+		return
+	}
+
+	frame := getFrame()
+	frame.messageType = websocket.TextMessage
+	frame.data = append(frame.data, data...)
+	message := BroadcastMessage{frame: frame, sender: client, queued: time.Now()}
+
+	go func() {
+		select {
+		case h.broadcast <- message:
+		case <-h.done:
+			frame.release()
+		}
+	}()
+	log.Printf("Publishing last will of client %s (%s)", client.id, reason)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Audio delay is measured in three legs. The gateway stamps every frame
+// with when it arrived, which walkie.framed.v1, walkie.pb.v1 and
+// walkie.json.v1 listeners receive, so they can measure the downlink
+// themselves. Inside the gateway, each listener's delay from arrival to
+// the frame being handed to its socket is tracked. And a sender that puts
+// its capture time on its frames gets its uplink delay measured too.
+
+// delayBuckets are the upper bounds of the gateway delay histogram's
+// buckets
+var delayBuckets = [...]time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// delayHistogram counts delays by bucket. Any goroutine may observe and
+// read it.
+type delayHistogram struct {
+	// The last bucket counts delays past every bound
+	counts [len(delayBuckets) + 1]atomic.Uint64
+	// Nanoseconds
+	sum atomic.Int64
+}
+
+// observe adds a delay
+func (h *delayHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(delayBuckets) && d > delayBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// writeMetric writes the histogram in the Prometheus text format
+func (h *delayHistogram) writeMetric(out *bufio.Writer, name, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative uint64
+	for i, bound := range delayBuckets {
+		cumulative += h.counts[i].Load()
+		fmt.Fprintf(out, "%s_bucket{le=\"%g\"} %d\n", name, bound.Seconds(), cumulative)
+	}
+	cumulative += h.counts[len(delayBuckets)].Load()
+	fmt.Fprintf(out, "%s_bucket{le=\"+Inf\"} %d\n", name, cumulative)
+	fmt.Fprintf(out, "%s_sum %g\n", name, time.Duration(h.sum.Load()).Seconds())
+	fmt.Fprintf(out, "%s_count %d\n", name, cumulative)
+}
+
+// uplinkFloorWindow is how long the least apparent uplink delay takes to be
+// forgotten
+const uplinkFloorWindow = time.Minute
+
+// uplinkClock estimates how far a sender's clock is from the gateway's. The
+// apparent delay of a frame, from the capture time the sender put on it to
+// its arrival, is the true delay plus the clocks' offset; the least one
+// seen recently is taken to be the offset plus half the round trip. Only
+// readPump touches it.
+type uplinkClock struct {
+	// The least apparent delay of the window starting at windowStart, and
+	// of the window before
+	floor       time.Duration
+	prevFloor   time.Duration
+	windowStart time.Time
+}
+
+// delay returns the skew-adjusted uplink delay of a frame captured at
+// captured and arriving at now, given the connection's round trip
+func (u *uplinkClock) delay(captured, now time.Time, rtt time.Duration) time.Duration {
+	apparent := now.Sub(captured)
+	switch {
+	case u.windowStart.IsZero():
+		u.windowStart = now
+		u.floor, u.prevFloor = apparent, apparent
+	case now.Sub(u.windowStart) >= uplinkFloorWindow:
+		u.windowStart = now
+		u.prevFloor, u.floor = u.floor, apparent
+	case apparent < u.floor:
+		u.floor = apparent
+	}
+	return apparent - min(u.floor, u.prevFloor) + rtt/2
+}
+
+// measureUplink records the uplink delay of a frame the client put its
+// capture time on, in Unix milliseconds. Only readPump may call it.
+func (c *Client) measureUplink(captured int64, now time.Time) {
+	if captured == 0 {
+		return
+	}
+	c.uplinkDelay.record(c.uplink.delay(time.UnixMilli(captured), now, c.roundTrip(now)), now)
+}
+
+// measureQueueing records the delay of a live audio frame from arriving at
+// the gateway to being written to the client's socket. Only writePump may
+// call it.
+func (c *Client) measureQueueing(message outbound, now time.Time) {
+	if message.frame == nil || message.replayed || message.frame.receivedAt.IsZero() {
+		return
+	}
+	delay := now.Sub(message.frame.receivedAt)
+	c.queueDelay.record(delay, now)
+	c.gateway.delay.observe(delay)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A listener's player can't tell a burst that ended from one whose next
+// frame is late, so when a client's burst ends the room hears an eot marker
+// naming the sender and the sequence number of the burst's last frame, and
+// why it ended: the sender released the floor, went quiet for the talk
+// silence, or left mid-burst. The marker goes through the hub behind the
+// burst's audio, so each listener gets it after the last frame, and a full
+// send buffer gives up its oldest frame for it rather than dropping it.
+const (
+	eotReleased = "released"
+	eotSilence  = "silence"
+	eotAborted  = "aborted"
+)
+
+// eotMessage marks the end of a client's burst, Seq being its last frame's
+type eotMessage struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	ID     string `json:"id"`
+	Seq    uint64 `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// endBurst ends the client's burst, if it is talking, for the reason
+func (c *Client) endBurst(reason string) {
+	s := &c.speaking
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.active {
+		return
+	}
+	s.active = false
+	s.timer.Stop()
+	c.hub.stopTalking(c, reason)
+}
+
+// publishEOT sends the room the marker for the end of the client's burst,
+// through the hub so that it follows the burst's audio. The caller must
+// hold the client's speaking mutex.
+func (h *Hub) publishEOT(client *Client, reason string) {
+	data, err := json.Marshal(eotMessage{
+		Type:   "eot",
+		Room:   localRoom(h.room),
+		ID:     client.id,
+		Seq:    client.speaking.seq,
+		Reason: reason,
+	})
+	if err != nil {
+		log.Printf("Error encoding end of transmission of client %s: %v", client.id, err)
+		return
+	}
+	h.eotMarkers.Add(1)
+	if reason == eotAborted {
+		h.eotAborted.Add(1)
+	}
+
+	frame := getFrame()
+	frame.messageType = websocket.TextMessage
+	frame.data = append(frame.data, data...)
+	message := BroadcastMessage{frame: frame, sender: client, eot: true}
+	if client.holdEOT(message) {
+		return
+	}
+	go h.queueEOT(message)
+}
+
+// queueEOT hands the hub an end of transmission marker, waiting for room
+// in its broadcast queue rather than dropping it
+func (h *Hub) queueEOT(message BroadcastMessage) {
+	message.queued = time.Now()
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+		message.frame.release()
+	}
+}
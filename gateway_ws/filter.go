@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gorilla/websocket"
+)
+
+// A client may filter the audio it gets by sender, to save bandwidth on a
+// monitor that only follows the dispatcher. A filter message sets an
+// allowlist or a denylist of sender IDs, replacing any filter before it,
+// and one without a mode clears it. The fan-out skips a filtered sender's
+// frames before they are queued, so they take none of the client's send
+// buffer. A filter belongs to the client's membership of its room, and a
+// room switch clears it.
+const (
+	filterAllow = "allow"
+	filterDeny  = "deny"
+
+	// maxFilterSenders caps how many senders a filter lists
+	maxFilterSenders = 256
+)
+
+// senderFilter is an allowlist or denylist of sender IDs. It never changes
+// once set, so the fan-out reads it without locking.
+type senderFilter struct {
+	mode    string
+	senders map[string]bool
+}
+
+// allows reports whether the filter lets through audio from sender
+func (f *senderFilter) allows(sender string) bool {
+	if f == nil {
+		return true
+	}
+	return f.senders[sender] == (f.mode == filterAllow)
+}
+
+// FilterStats describes a client's sender filter
+type FilterStats struct {
+	Mode    string   `json:"mode"`
+	Senders []string `json:"senders"`
+}
+
+// stats returns the filter as /clients reports it, sorted, or nil if there
+// is none
+func (f *senderFilter) stats() *FilterStats {
+	if f == nil {
+		return nil
+	}
+	stats := &FilterStats{Mode: f.mode, Senders: make([]string, 0, len(f.senders))}
+	for sender := range f.senders {
+		stats.Senders = append(stats.Senders, sender)
+	}
+	sort.Strings(stats.Senders)
+	return stats
+}
+
+// filter sets the mode, allow or deny, and the senders, or clears the
+// filter without a mode
+func init() {
+	registerControl("filter", controlHandler{
+		validate: func(m controlMessage) error {
+			switch {
+			case m.Mode == "" && len(m.Senders) > 0:
+				return errors.New("missing mode")
+			case m.Mode != "" && m.Mode != filterAllow && m.Mode != filterDeny:
+				return fmt.Errorf("invalid mode %q: want %s or %s", m.Mode, filterAllow, filterDeny)
+			case len(m.Senders) > maxFilterSenders:
+				return fmt.Errorf("more than %d senders", maxFilterSenders)
+			}
+			return nil
+		},
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.setFilter(m.Mode, m.Senders)
+			}
+		},
+	})
+}
+
+// setFilter replaces the client's sender filter, or clears it if mode is
+// empty
+func (c *Client) setFilter(mode string, senders []string) {
+	if mode == "" {
+		c.filter.Store(nil)
+		c.gateway.debugf("Client %s cleared its sender filter in room %s", c.id, c.hub.room)
+		return
+	}
+	filter := &senderFilter{mode: mode, senders: make(map[string]bool, len(senders))}
+	for _, sender := range senders {
+		filter.senders[sender] = true
+	}
+	c.filter.Store(filter)
+	c.gateway.debugf("Client %s set a sender %s filter of %d in room %s", c.id, mode, len(filter.senders), c.hub.room)
+}
+
+// filters reports whether the client's filter keeps it from getting the
+// message, counting it if so. Only audio is filtered.
+func (c *Client) filters(message outbound, sender *Client) bool {
+	if sender == nil || message.frame == nil || message.frame.messageType != websocket.BinaryMessage {
+		return false
+	}
+	if c.filter.Load().allows(sender.id) {
+		return false
+	}
+	c.filtered.Add(1)
+	return true
+}
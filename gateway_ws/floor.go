@@ -0,0 +1,411 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A room under floor control has one talker at a time, as a real
+// walkie-talkie channel does. A client asks for the floor with a
+// floor_request message and is granted it if it is free, when the room is
+// told who holds it, or denied it with why. Only the holder's audio is then
+// relayed to the room; anyone else's is dropped, and they are told so at
+// most once a second. The holder gives the floor back with floor_release,
+// or loses it once it has sent nothing for the floor timeout. Moderators'
+// priority audio gets through regardless, and whispers and audio addressed
+// to tags aren't floor controlled. The floor is the Run goroutine's alone.
+const (
+	defaultFloorTimeout = 3 * time.Second
+
+	// floorNoticeInterval is the least time between the notices a client
+	// gets for its audio being dropped
+	floorNoticeInterval = time.Second
+)
+
+// The reason codes answers to floor requests carry, so a client can tell
+// its user exactly why it may or may not talk. Every floor request is
+// answered: floor_granted, floor_queued with the client's place in line, or
+// floor_denied with the holder if the floor is busy.
+const (
+	floorGranted  = "granted"
+	floorInLine   = "queued"
+	floorBusy     = "busy"
+	floorListener = "denied_role"
+	floorMuted    = "denied_muted"
+	floorCooldown = "denied_cooldown"
+	floorDisabled = "disabled"
+)
+
+// floorLeaveRequest is the kind of floor request removeClient makes for a
+// client leaving the room, which no client can send
+const floorLeaveRequest = "floor_leave"
+
+// Why the floor was freed
+const (
+	floorReleased     = "released"
+	floorTimedOut     = "timeout"
+	floorLeft         = "left"
+	floorUncontrolled = "uncontrolled"
+)
+
+// floorRequest asks the Run goroutine to act on a client's control message
+// of type kind, or on its leaving the room for reason. A request without a
+// client frees the floor, as the room stops being floor controlled.
+type floorRequest struct {
+	client   *Client
+	kind     string
+	priority int
+	reason   closeReason
+
+	// The moderator granting the client the floor for its raised hand
+	actor *Client
+}
+
+// floorMessage tells the room who holds the floor, or that nobody does and
+// why
+type floorMessage struct {
+	Type     string `json:"type"`
+	Room     string `json:"room"`
+	Holder   string `json:"holder"`
+	Priority int    `json:"priority,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Locked   bool   `json:"locked,omitempty"`
+}
+
+// floorGrantMessage answers a floor request, or takes the floor back from a
+// client, with who holds the floor and the reason code
+type floorGrantMessage struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	Holder string `json:"holder,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// WithFloorControl puts the hub's room under floor control. The room admin
+// API may change it while the hub runs.
+func WithFloorControl(enabled bool) HubOption {
+	return func(h *Hub) {
+		h.floorControl.Store(enabled)
+	}
+}
+
+// WithFloorTimeout sets how long the floor holder may send nothing before
+// it loses the floor
+func WithFloorTimeout(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.floorTimeout = d
+		}
+	}
+}
+
+// WithFloorResume lets a floor holder whose connection is lost keep the
+// floor if it resumes its session before the floor timeout frees it
+func WithFloorResume(enabled bool) HubOption {
+	return func(h *Hub) {
+		h.floorResume = enabled
+	}
+}
+
+// WithRoomFloorControl puts every room under floor control, with a line
+// for the floor of up to queue clients, unless its config says otherwise
+func WithRoomFloorControl(enabled bool, queue int) GatewayOption {
+	return func(g *Gateway) {
+		g.floorControl = enabled
+		g.floorQueue = queue
+	}
+}
+
+// floorControlFor reports whether the named room is under floor control.
+// The caller must hold the gateway's mutex.
+func (g *Gateway) floorControlFor(name string) bool {
+	if enabled := g.roomConfigs[name].FloorControl; enabled != nil {
+		return *enabled
+	}
+	return g.floorControl
+}
+
+// updateFloorControl brings an open room's floor control and line for the
+// floor in line with its config. The caller must hold the mutex.
+func (g *Gateway) updateFloorControl(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.floorQueueLimit.Store(int32(g.floorQueueFor(name)))
+		rm.hub.setFloorControl(g.floorControlFor(name))
+	}
+}
+
+// setFloorControl turns the room's floor control on or off, freeing the
+// floor when it goes off. The gateway's mutex may be held, which Run can be
+// waiting on, so the floor is freed from another goroutine.
+func (h *Hub) setFloorControl(enabled bool) {
+	if h.floorControl.Swap(enabled) && !enabled {
+		go h.requestFloor(floorRequest{})
+	}
+}
+
+// floor_request asks for the floor, floor_release gives it back and
+// floor_cancel leaves the line for it; on a multi-room connection they name
+// the room
+func init() {
+	floor := controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.hub.requestFloor(floorRequest{client: member, kind: m.Type, priority: m.Priority})
+			}
+		},
+	}
+	registerControl("floor_request", floor)
+	registerControl("floor_release", floor)
+	registerControl("floor_cancel", floor)
+}
+
+// requestFloor hands the request to the Run goroutine
+func (h *Hub) requestFloor(request floorRequest) {
+	select {
+	case h.floorRequests <- request:
+	case <-h.done:
+	}
+}
+
+// floorHeld reports whether the room is under floor control and someone
+// other than the client holds the floor, or nobody does
+func (h *Hub) floorHeld(client *Client) bool {
+	return h.floorControl.Load() && h.floorHolder.Load() != client
+}
+
+// handleFloor grants, releases or queues for the floor. Only the Run
+// goroutine may call it.
+func (h *Hub) handleFloor(request floorRequest, now time.Time) {
+	client := request.client
+	switch {
+	case client == nil:
+		h.floorQueue = nil
+		h.floorOffered = nil
+		h.floorOfferedPriority = 0
+		h.floorLock.Store(false)
+		if h.floorHolder.Load() != nil {
+			h.freeFloor(floorUncontrolled, now)
+		}
+	case request.kind == "floor_release":
+		if h.floorHolder.Load() == client {
+			client.endBurst(eotReleased)
+			h.freeFloor(floorReleased, now)
+		}
+	case request.kind == "floor_cancel":
+		h.leaveFloorQueue(client, now)
+	case request.kind == floorLeaveRequest:
+		h.floorLeave(client, request.reason, now)
+	case request.kind == "hand_grant":
+		h.handFloor(client, request.actor, now)
+	case request.actor != nil:
+		h.floorModerated(request.actor, request.kind, now)
+	default:
+		if reason := h.floorRefusal(client); reason != "" {
+			h.denyFloor(client, reason)
+			return
+		}
+		priority := client.floorPriority(request.priority)
+		switch {
+		case !h.floorBusy(client):
+			h.grantFloor(client, priority, now)
+		case h.floorOffered != nil && priority > h.floorOfferedPriority:
+			h.preemptOffer(client, priority, now)
+		case h.floorOffered == nil && priority > h.floorPriority:
+			h.outrankFloor(client, priority, now)
+		default:
+			h.queueForFloor(client, priority, false)
+		}
+	}
+}
+
+// floorRefusal returns why the client may not have the floor however free
+// it is, or "" if it may
+func (h *Hub) floorRefusal(client *Client) string {
+	switch {
+	case !h.floorControl.Load():
+		return floorDisabled
+	case h.floorLock.Load():
+		return floorDeniedLocked
+	case client.listener.Load():
+		return floorListener
+	case client.muted.Load():
+		return floorMuted
+	case client.coolingDown(time.Now()):
+		return floorCooldown
+	}
+	return ""
+}
+
+// floorBusy reports whether someone other than the client holds the floor
+// or is being offered it. Only the Run goroutine may call it.
+func (h *Hub) floorBusy(client *Client) bool {
+	if h.floorOffered != nil && h.floorOffered != client {
+		return true
+	}
+	holder := h.floorHolder.Load()
+	return holder != nil && holder != client
+}
+
+// grantFloor gives the client the floor at the priority. Only the Run
+// goroutine may call it.
+func (h *Hub) grantFloor(client *Client, priority int, now time.Time) {
+	if h.floorOffered == client {
+		h.floorOffered = nil
+		h.floorOfferedPriority = 0
+	}
+	h.unqueueFloor(client)
+	holder := h.floorHolder.Swap(client)
+	if holder != client || priority != h.floorPriority {
+		h.floorGrantedAt = now
+	}
+	h.floorPriority = priority
+	h.floorLast = now
+	h.floorGrants.Add(1)
+	h.scheduleFloor(now)
+	h.sendControl(client, floorGrantMessage{Type: "floor_granted", Room: localRoom(h.room), Holder: client.id, Reason: floorGranted})
+	if holder != client {
+		h.announceFloor(client.id, priority, "")
+	}
+	log.Printf("Client %s holds the floor of room %s", client.id, h.room)
+}
+
+// denyFloor refuses the client the floor for the reason
+func (h *Hub) denyFloor(client *Client, reason string) {
+	denied := floorGrantMessage{Type: "floor_denied", Room: localRoom(h.room), Reason: reason}
+	if holder := h.floorHolder.Load(); holder != nil && reason == floorBusy {
+		denied.Holder = holder.id
+	}
+	h.floorDenials.Add(1)
+	h.sendControl(client, denied)
+}
+
+// freeFloor takes the floor from its holder for the reason, tells the room
+// and offers the floor to the head of the line for it. Only the Run
+// goroutine may call it.
+func (h *Hub) freeFloor(reason string, now time.Time) {
+	holder := h.floorHolder.Swap(nil)
+	h.floorPriority = 0
+	h.floorDeparted = false
+	h.floorTimer.Stop()
+	if holder != nil {
+		log.Printf("Client %s no longer holds the floor of room %s (%s)", holder.id, h.room, reason)
+	}
+	h.announceFloor("", 0, reason)
+	h.offerFloor(now)
+}
+
+// expireFloor frees the floor if its holder has sent nothing for the floor
+// timeout, hands it to whoever outranks the holder at the head of the line
+// once the holder has had it for the minimum hold, or passes it down the
+// line if whoever it is offered to hasn't taken it in the offer window, and
+// otherwise waits until the soonest of them. Only the Run goroutine may
+// call it.
+func (h *Hub) expireFloor(now time.Time) {
+	if h.floorOffered != nil {
+		h.expireOffer(now)
+		return
+	}
+	if h.floorHolder.Load() == nil {
+		return
+	}
+	if h.floorOutranked() && now.Sub(h.floorGrantedAt) >= h.floorMinHold {
+		h.preemptFloor(h.floorQueue[0].client, h.floorQueue[0].priority, now)
+		return
+	}
+	if now.Sub(h.floorLast) >= h.floorTimeout {
+		reason := floorTimedOut
+		if h.floorDeparted {
+			reason = floorLeft
+		}
+		h.freeFloor(reason, now)
+		return
+	}
+	h.scheduleFloor(now)
+}
+
+// scheduleFloor sets the floor timer for when the holder will have sent
+// nothing for the floor timeout or, if that is sooner and someone
+// outranking it waits, will have had the floor for the minimum hold. Only
+// the Run goroutine may call it.
+func (h *Hub) scheduleFloor(now time.Time) {
+	next := h.floorLast.Add(h.floorTimeout)
+	if hold := h.floorGrantedAt.Add(h.floorMinHold); h.floorOutranked() && hold.Before(next) {
+		next = hold
+	}
+	h.floorTimer.Reset(next.Sub(now))
+}
+
+// announceFloor tells every client in the hub who holds the floor, and at
+// what priority, or whether it is locked
+func (h *Hub) announceFloor(holder string, priority int, reason string) {
+	locked := h.floorLock.Load()
+	for _, client := range h.snapshotClients() {
+		h.sendControl(client, floorMessage{Type: "floor", Room: localRoom(h.room), Holder: holder, Priority: priority, Reason: reason, Locked: locked})
+	}
+}
+
+// floorAllows reports whether the room's floor control lets a broadcast
+// through, noting the holder's audio and telling anyone else theirs was
+// dropped. Only the Run goroutine may call it.
+func (h *Hub) floorAllows(message BroadcastMessage, now time.Time) bool {
+	if !h.floorControl.Load() || message.frame.messageType != websocket.BinaryMessage || message.frame.header.priority {
+		return true
+	}
+	sender := message.sender
+	if sender == h.floorOffered {
+		h.grantFloor(sender, h.floorOfferedPriority, now)
+	}
+	if sender == h.floorHolder.Load() {
+		h.floorLast = now
+		return true
+	}
+	h.floorDropped.Add(1)
+	if now.Sub(sender.floorNoticed) >= floorNoticeInterval {
+		sender.floorNoticed = now
+		sender.fail("not_granted", "audio", localRoom(h.room))
+	}
+	return false
+}
+
+// leftFloor tells the Run goroutine the client left the room for reason.
+// removeClient runs on the fan-out workers as well as Run, so it can't
+// touch the floor itself, nor wait on Run.
+func (h *Hub) leftFloor(client *Client, reason closeReason) {
+	if h.floorControl.Load() {
+		go h.requestFloor(floorRequest{client: client, kind: floorLeaveRequest, reason: reason})
+	}
+}
+
+// resumeFloor hands the floor to a client resuming the session of its
+// holder, ahead of its joined message naming it the holder. Only the Run
+// goroutine may call it.
+func (h *Hub) resumeFloor(client *Client, now time.Time) {
+	holder := h.floorHolder.Load()
+	if !h.floorResume || holder == nil || holder == client || holder.session != client.session {
+		return
+	}
+	h.floorHolder.Store(client)
+	h.floorDeparted = false
+	h.floorLast = now
+	h.scheduleFloor(now)
+	log.Printf("Client %s resumed its session holding the floor of room %s", client.id, h.room)
+}
+
+// floorLeave frees the floor if the client leaving holds it, and takes it
+// out of the line for it otherwise. A holder whose connection was lost
+// keeps the floor for its session to resume, with floor resume on, until
+// the floor timeout frees it. Only the Run goroutine may call it.
+func (h *Hub) floorLeave(client *Client, reason closeReason, now time.Time) {
+	if h.floorHolder.Load() != client {
+		h.leaveFloorQueue(client, now)
+		return
+	}
+	if h.floorResume && client.session != nil && reason.abnormal() {
+		h.floorDeparted = true
+		log.Printf("Client %s keeps the floor of room %s for its session to resume", client.id, h.room)
+		return
+	}
+	h.freeFloor(floorLeft, now)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Every floor request is answered with why the client may or may not talk:
+// granted, queued with its place in line, or denied with the reason code
+// and, for a busy floor, who holds it
+func TestFloorAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		control bool
+		queue   int
+		// Puts the room in the state the request meets, with a moderator
+		setup func(t *testing.T, tg *testGateway, moderator *websocket.Conn)
+		path  string
+		// Whether someone holds the floor before the request
+		held     bool
+		answer   string
+		reason   string
+		holder   string
+		position float64
+	}{
+		{name: "granted", control: true, answer: "floor_granted", reason: floorGranted, holder: "c"},
+		{name: "busy", control: true, held: true, answer: "floor_denied", reason: floorBusy, holder: "holder"},
+		{name: "queued", control: true, queue: 2, held: true, answer: "floor_queued", reason: floorInLine, position: 1},
+		{name: "queue full", control: true, queue: 1, held: true, answer: "floor_denied", reason: floorQueueFull,
+			setup: func(t *testing.T, tg *testGateway, _ *websocket.Conn) {
+				waiting, _ := tg.dial(t, "/ws", "waiting")
+				readControl(t, waiting, "joined")
+				waiting.WriteJSON(map[string]any{"type": "floor_request"})
+				readControl(t, waiting, "floor_queued")
+			}},
+		{name: "listener", control: true, path: "/ws?role=listener", answer: "floor_denied", reason: floorListener},
+		{name: "muted", control: true, answer: "floor_denied", reason: floorMuted,
+			setup: func(t *testing.T, tg *testGateway, moderator *websocket.Conn) {
+				moderator.WriteJSON(map[string]any{"type": "mute", "target": "c"})
+			}},
+		{name: "locked", control: true, answer: "floor_denied", reason: floorDeniedLocked,
+			setup: func(t *testing.T, tg *testGateway, moderator *websocket.Conn) {
+				moderator.WriteJSON(map[string]any{"type": "floor_lock"})
+				readControl(t, moderator, "floor_moderated")
+			}},
+		{name: "not floor controlled", answer: "floor_denied", reason: floorDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGateway(t, nil, WithRoomFloorControl(tt.control, tt.queue), WithModeratorSecrets(map[string]string{defaultRoom: "mod"}))
+			moderator, _ := tg.dial(t, "/ws?join_token=mod", "moderator")
+			readControl(t, moderator, "joined")
+			if tt.held {
+				holder, _ := tg.dial(t, "/ws", "holder")
+				readControl(t, holder, "joined")
+				holder.WriteJSON(map[string]any{"type": "floor_request"})
+				readControl(t, holder, "floor_granted")
+			}
+			path := tt.path
+			if path == "" {
+				path = "/ws"
+			}
+			conn, _ := tg.dial(t, path, "c")
+			readControl(t, conn, "joined")
+			if tt.setup != nil {
+				tt.setup(t, tg, moderator)
+			}
+			if tt.reason == floorMuted {
+				readControl(t, conn, "muted")
+			}
+
+			if err := conn.WriteJSON(map[string]any{"type": "floor_request"}); err != nil {
+				t.Fatal(err)
+			}
+			answer := readControl(t, conn, tt.answer)
+			if answer["reason"] != tt.reason {
+				t.Errorf("reason %v, want %s", answer["reason"], tt.reason)
+			}
+			if got, _ := answer["holder"].(string); got != tt.holder {
+				t.Errorf("holder %q, want %q", got, tt.holder)
+			}
+			if tt.position != 0 && answer["position"] != tt.position {
+				t.Errorf("position %v, want %v", answer["position"], tt.position)
+			}
+		})
+	}
+}
+
+// A client cut off by the talk limit is denied the floor until its cooldown
+// is over
+func TestFloorDeniedCooldown(t *testing.T) {
+	tg := newTestGateway(t, nil, WithRoomTalkLimit(TalkLimit{MaxBurst: "100ms", Cooldown: "10s"}), WithRoomFloorControl(true, 0))
+	conn, _ := tg.dial(t, "/ws", "c")
+	readControl(t, conn, "joined")
+	conn.WriteJSON(map[string]any{"type": "floor_request"})
+	readControl(t, conn, "floor_granted")
+	for i := 0; i < 10; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcmFrame(1)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	readControl(t, conn, "transmission_cut_off")
+	conn.WriteJSON(map[string]any{"type": "floor_request"})
+	if denied := readControl(t, conn, "floor_denied"); denied["reason"] != floorCooldown {
+		t.Errorf("reason %v, want %s", denied["reason"], floorCooldown)
+	}
+}
+
+// Audio from a client that doesn't hold the floor is dropped with a
+// not_granted notice, at most one a second however much it sends
+func TestFloorNotGranted(t *testing.T) {
+	tg := newTestGateway(t, nil, WithRoomFloorControl(true, 0))
+	holder, _ := tg.dial(t, "/ws", "holder")
+	readControl(t, holder, "joined")
+	holder.WriteJSON(map[string]any{"type": "floor_request"})
+	readControl(t, holder, "floor_granted")
+	conn, _ := tg.dial(t, "/ws", "c")
+	readControl(t, conn, "joined")
+
+	for i := 0; i < 10; i++ {
+		if err := conn.WriteMessage(websocket.BinaryMessage, pcmFrame(byte(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notice := readControl(t, conn, "error")
+	if notice["code"] != "not_granted" || notice["request"] != "audio" {
+		t.Errorf("got %v, want a not_granted notice for audio", notice)
+	}
+	if more := countControl(conn, "error", 300*time.Millisecond); more != 0 {
+		t.Errorf("got %d more notices within the second", more)
+	}
+	hub := tg.hub(t, defaultRoom)
+	waitFor(t, "the frames to be dropped", func() bool { return hub.Stats().FloorDropped == 10 })
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// A moderator can take the floor from its holder with floor_revoke, without
+// disconnecting it, and lock the floor with floor_lock so nobody can take
+// it until floor_unlock, as during an announcement. Locking a held floor
+// revokes it first. While the floor is locked every floor request is
+// denied, the line for it keeps its order but isn't offered the floor, and
+// only priority audio gets through. The room hears of the lock in floor
+// messages, and a joining client in its joined message, so a UI can disable
+// its push-to-talk button. The moderator is answered with floor_moderated.
+const (
+	// Why the floor was freed
+	floorRevoked  = "revoked"
+	floorLocked   = "locked"
+	floorUnlocked = "unlocked"
+
+	// Why the floor was denied
+	floorDeniedLocked = "denied_locked"
+
+	// What a floor_revoke finds when nobody holds the floor
+	floorNotHeld = "not_held"
+)
+
+// floorModeratedMessage answers a moderator's floor_revoke, floor_lock or
+// floor_unlock, Action, with who it took the floor from, if anyone, and
+// whether the floor is now locked
+type floorModeratedMessage struct {
+	Type    string `json:"type"`
+	Room    string `json:"room"`
+	Action  string `json:"action"`
+	Revoked string `json:"revoked,omitempty"`
+	Locked  bool   `json:"locked"`
+}
+
+// floor_revoke, floor_lock and floor_unlock have a moderator take the floor
+// from its holder, lock it and unlock it; on a multi-room connection they
+// name the room
+func init() {
+	moderate := controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.moderateFloor(m)
+			}
+		},
+	}
+	registerControl("floor_revoke", moderate)
+	registerControl("floor_lock", moderate)
+	registerControl("floor_unlock", moderate)
+}
+
+// moderateFloor hands a moderator's floor_revoke, floor_lock or
+// floor_unlock to the Run goroutine
+func (c *Client) moderateFloor(message controlMessage) {
+	if !c.moderates(message) {
+		return
+	}
+	c.hub.requestFloor(floorRequest{client: c, kind: message.Type, actor: c})
+}
+
+// floorModerated carries out a moderator's floor_revoke, floor_lock or
+// floor_unlock, kind, and answers it. Only the Run goroutine may call it.
+func (h *Hub) floorModerated(actor *Client, kind string, now time.Time) {
+	if !h.floorControl.Load() {
+		actor.fail(floorDisabled, kind, "")
+		return
+	}
+	var revoked *Client
+	switch kind {
+	case "floor_revoke":
+		if revoked = h.floorHolder.Load(); revoked == nil {
+			actor.fail(floorNotHeld, kind, "")
+			return
+		}
+		h.revokeFloor(revoked, floorRevoked, now)
+	case "floor_lock":
+		if h.floorLock.Swap(true) {
+			break
+		}
+		if revoked = h.floorHolder.Load(); revoked != nil {
+			h.revokeFloor(revoked, floorLocked, now)
+		} else {
+			h.withdrawOffer()
+			h.announceFloor("", 0, floorLocked)
+		}
+	case "floor_unlock":
+		if !h.floorLock.Swap(false) {
+			break
+		}
+		h.announceFloor("", 0, floorUnlocked)
+		h.offerFloor(now)
+	}
+
+	answer := floorModeratedMessage{Type: "floor_moderated", Room: localRoom(h.room), Action: kind, Locked: h.floorLock.Load()}
+	event := AuditEvent{Event: kind, Room: h.room, Actor: actor.id, Outcome: auditOK}
+	if revoked != nil {
+		answer.Revoked = revoked.id
+		event.ClientID = revoked.id
+	}
+	log.Printf("Moderator %s sent %s in room %s", actor.id, kind, h.room)
+	actor.gateway.audit.record(event)
+	h.sendControl(actor, answer)
+}
+
+// revokeFloor takes the floor from its holder for the reason, telling it
+// and ending its burst, and frees it. Only the Run goroutine may call it.
+func (h *Hub) revokeFloor(holder *Client, reason string, now time.Time) {
+	h.floorRevocations.Add(1)
+	h.sendControl(holder, floorGrantMessage{Type: "floor_revoked", Room: localRoom(h.room), Reason: reason})
+	holder.endBurst(eotReleased)
+	h.freeFloor(reason, now)
+}
+
+// withdrawOffer puts whoever the floor is offered to back at the head of the
+// line, as the floor is locked. Only the Run goroutine may call it.
+func (h *Hub) withdrawOffer() {
+	if h.floorOffered == nil {
+		return
+	}
+	h.floorQueue = append([]floorWaiter{{client: h.floorOffered, priority: h.floorOfferedPriority}}, h.floorQueue...)
+	h.floorOffered = nil
+	h.floorOfferedPriority = 0
+	h.floorTimer.Stop()
+	h.tellFloorPositions(0)
+}
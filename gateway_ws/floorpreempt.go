@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// A supervisor can break into a room under floor control while someone
+// else is talking. A floor request may carry a priority, from 0 up to
+// maxFloorPriority for a moderator; anyone else's is 0 whatever it asks.
+// A request that outranks the holder preempts it: the holder is told the
+// floor was revoked, its audio is dropped from then on like anyone else's
+// without the floor, and the floor is granted at once. So that preemptions
+// can't bounce the floor back and forth, a holder keeps it for the minimum
+// hold, and a request that outranks it before then waits at the head of
+// the line until the hold is up. A request at the holder's priority or
+// below queues as any other, ahead of those waiting at lower priorities.
+const (
+	defaultFloorMinHold = time.Second
+
+	// maxFloorPriority is the highest priority a moderator may ask for the
+	// floor at
+	maxFloorPriority = 9
+
+	// Why the floor was revoked
+	floorPreempted = "preempted"
+)
+
+// WithFloorMinHold sets how long a floor holder keeps the floor before
+// someone outranking it may take it
+func WithFloorMinHold(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d >= 0 {
+			h.floorMinHold = d
+		}
+	}
+}
+
+// floorPriority returns the priority the client may ask for the floor at,
+// given what it asked for
+func (c *Client) floorPriority(requested int) int {
+	switch {
+	case !c.moderator.Load() || requested < 0:
+		return 0
+	case requested > maxFloorPriority:
+		return maxFloorPriority
+	}
+	return requested
+}
+
+// floorOutranked reports whether the head of the line outranks the holder.
+// Only the Run goroutine may call it.
+func (h *Hub) floorOutranked() bool {
+	return len(h.floorQueue) > 0 && h.floorQueue[0].priority > h.floorPriority
+}
+
+// outrankFloor preempts the holder for a client that outranks it, or puts
+// the client at the head of the line if the holder hasn't had the floor for
+// the minimum hold yet. Only the Run goroutine may call it.
+func (h *Hub) outrankFloor(client *Client, priority int, now time.Time) {
+	if now.Sub(h.floorGrantedAt) >= h.floorMinHold {
+		h.preemptFloor(client, priority, now)
+		return
+	}
+	h.queueForFloor(client, priority, true)
+	h.scheduleFloor(now)
+}
+
+// preemptFloor takes the floor from its holder and grants it to a client
+// that outranks it. Only the Run goroutine may call it.
+func (h *Hub) preemptFloor(client *Client, priority int, now time.Time) {
+	holder := h.floorHolder.Load()
+	h.floorPreemptions.Add(1)
+	log.Printf("Client %s preempted client %s for the floor of room %s at priority %d", client.id, holder.id, h.room, priority)
+	h.sendControl(holder, floorGrantMessage{Type: "floor_revoked", Room: localRoom(h.room), Holder: client.id, Reason: floorPreempted})
+	h.grantFloor(client, priority, now)
+}
+
+// preemptOffer grants the floor to a client that outranks whoever it is
+// offered to, who goes back to the head of the line. Only the Run goroutine
+// may call it.
+func (h *Hub) preemptOffer(client *Client, priority int, now time.Time) {
+	h.floorPreemptions.Add(1)
+	log.Printf("Client %s preempted the offer of the floor of room %s to client %s at priority %d", client.id, h.room, h.floorOffered.id, priority)
+	h.returnOffer(client)
+	h.grantFloor(client, priority, now)
+	h.tellFloorPositions(0)
+}
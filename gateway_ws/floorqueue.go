@@ -0,0 +1,204 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// A room may let clients wait in line for a busy floor rather than asking
+// again and again. A floor request while someone else has the floor puts
+// the client at the back of the line, if it isn't full, and the client is
+// told its place, and again whenever the line moves. When the floor frees
+// it is offered to the head of the line, who takes it with a floor request
+// or by talking within the offer window; one that doesn't loses its place
+// to the next. A client leaves the line with floor_cancel, or by leaving
+// the room. The line is the Run goroutine's alone, like the floor.
+const (
+	defaultFloorOfferWindow = 3 * time.Second
+
+	// Why the floor was denied
+	floorQueueFull    = "queue_full"
+	floorOfferExpired = "offer_expired"
+)
+
+// floorWaiter is a client in line for the floor, and the priority it asked
+// for it at
+type floorWaiter struct {
+	client   *Client
+	priority int
+}
+
+// floorQueuedMessage tells a client waiting for the floor its place in
+// line, 1 being next
+type floorQueuedMessage struct {
+	Type     string `json:"type"`
+	Room     string `json:"room"`
+	Position int    `json:"position"`
+	Reason   string `json:"reason"`
+}
+
+// floorOfferedMessage offers the floor to the head of the line, until
+// ExpiresAt
+type floorOfferedMessage struct {
+	Type      string    `json:"type"`
+	Room      string    `json:"room"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WithFloorQueue lets up to limit clients wait in line for the floor of
+// the hub's room, none if it is zero. The room admin API may change it
+// while the hub runs.
+func WithFloorQueue(limit int) HubOption {
+	return func(h *Hub) {
+		h.floorQueueLimit.Store(int32(limit))
+	}
+}
+
+// WithFloorOfferWindow sets how long the head of the line has to take the
+// floor once it is offered it
+func WithFloorOfferWindow(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.floorOfferWindow = d
+		}
+	}
+}
+
+// floorQueueFor returns how many clients may wait in line for the named
+// room's floor. The caller must hold the gateway's mutex.
+func (g *Gateway) floorQueueFor(name string) int {
+	if limit := g.roomConfigs[name].FloorQueue; limit != nil {
+		return *limit
+	}
+	return g.floorQueue
+}
+
+// queueForFloor puts the client in line for the busy floor, behind everyone
+// waiting at its priority or above, or tells it its place if it is already
+// in line at that priority. A client that outranks the holder is let in
+// however long the line is. Only the Run goroutine may call it.
+func (h *Hub) queueForFloor(client *Client, priority int, outranks bool) {
+	for i, waiting := range h.floorQueue {
+		if waiting.client == client && waiting.priority == priority {
+			h.tellFloorPosition(client, i+1)
+			return
+		}
+	}
+	h.unqueueFloor(client)
+	if !outranks {
+		limit := int(h.floorQueueLimit.Load())
+		switch {
+		case limit == 0:
+			h.denyFloor(client, floorBusy)
+			return
+		case len(h.floorQueue) >= limit:
+			h.denyFloor(client, floorQueueFull)
+			return
+		}
+	}
+	i := len(h.floorQueue)
+	for i > 0 && h.floorQueue[i-1].priority < priority {
+		i--
+	}
+	h.floorQueue = append(h.floorQueue, floorWaiter{})
+	copy(h.floorQueue[i+1:], h.floorQueue[i:])
+	h.floorQueue[i] = floorWaiter{client: client, priority: priority}
+	h.floorQueued.Add(1)
+	h.tellFloorPositions(i)
+}
+
+// leaveFloorQueue takes the client out of the line for the floor, passing
+// the floor on if it was being offered it. Only the Run goroutine may call
+// it.
+func (h *Hub) leaveFloorQueue(client *Client, now time.Time) {
+	if h.floorOffered == client {
+		h.floorOffered = nil
+		h.floorOfferedPriority = 0
+		h.offerFloor(now)
+		return
+	}
+	h.unqueueFloor(client)
+}
+
+// unqueueFloor takes the client out of the line for the floor if it is in
+// it, telling those behind it their new places. Only the Run goroutine may
+// call it.
+func (h *Hub) unqueueFloor(client *Client) {
+	for i, waiting := range h.floorQueue {
+		if waiting.client == client {
+			h.floorQueue = append(h.floorQueue[:i], h.floorQueue[i+1:]...)
+			h.tellFloorPositions(i)
+			return
+		}
+	}
+}
+
+// returnOffer puts whoever the floor is offered to back at the head of the
+// line, and takes client out of it to be granted the floor instead, telling
+// nobody their places yet. Only the Run goroutine may call it.
+func (h *Hub) returnOffer(client *Client) {
+	offered := floorWaiter{client: h.floorOffered, priority: h.floorOfferedPriority}
+	h.floorOffered = nil
+	h.floorOfferedPriority = 0
+	for i, waiting := range h.floorQueue {
+		if waiting.client == client {
+			h.floorQueue = append(h.floorQueue[:i], h.floorQueue[i+1:]...)
+			break
+		}
+	}
+	h.floorQueue = append([]floorWaiter{offered}, h.floorQueue...)
+}
+
+// offerFloor offers the free floor to the head of the line, skipping
+// clients that may no longer have it. Only the Run goroutine may call it.
+func (h *Hub) offerFloor(now time.Time) {
+	if h.floorOffered != nil || h.floorHolder.Load() != nil || h.floorLock.Load() {
+		return
+	}
+	for len(h.floorQueue) > 0 {
+		head := h.floorQueue[0]
+		h.floorQueue[0] = floorWaiter{}
+		h.floorQueue = h.floorQueue[1:]
+		if reason := h.floorRefusal(head.client); reason != "" {
+			h.denyFloor(head.client, reason)
+			continue
+		}
+		h.floorOffered = head.client
+		h.floorOfferedPriority = head.priority
+		h.floorOfferedAt = now
+		h.floorTimer.Reset(h.floorOfferWindow)
+		h.sendControl(head.client, floorOfferedMessage{Type: "floor_offered", Room: localRoom(h.room), ExpiresAt: now.Add(h.floorOfferWindow)})
+		break
+	}
+	h.tellFloorPositions(0)
+}
+
+// expireOffer passes the floor down the line if whoever it is offered to
+// hasn't taken it in the offer window, and otherwise waits until then.
+// Only the Run goroutine may call it.
+func (h *Hub) expireOffer(now time.Time) {
+	if waited := now.Sub(h.floorOfferedAt); waited < h.floorOfferWindow {
+		h.floorTimer.Reset(h.floorOfferWindow - waited)
+		return
+	}
+	offered := h.floorOffered
+	h.floorOffered = nil
+	h.floorOfferedPriority = 0
+	h.floorOffersExpired.Add(1)
+	log.Printf("Client %s didn't take the floor of room %s in time", offered.id, h.room)
+	h.denyFloor(offered, floorOfferExpired)
+	h.offerFloor(now)
+}
+
+// tellFloorPositions tells the clients in line from index from on their
+// places
+func (h *Hub) tellFloorPositions(from int) {
+	for i := from; i < len(h.floorQueue); i++ {
+		h.tellFloorPosition(h.floorQueue[i].client, i+1)
+	}
+}
+
+// tellFloorPosition tells a client waiting for the floor its place in line
+func (h *Hub) tellFloorPosition(client *Client, position int) {
+	h.sendControl(client, floorQueuedMessage{Type: "floor_queued", Room: localRoom(h.room), Position: position, Reason: floorInLine})
+}
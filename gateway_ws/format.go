@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// What a room does with a client declaring an audio format it doesn't
+// allow: refuse it, or let it in flagged
+const (
+	mismatchReject = "reject"
+	mismatchFlag   = "flag"
+)
+
+// defaultAudioFormat is the format rooms advertise unless told otherwise
+var defaultAudioFormat = audioFormat{Codec: defaultCodec, SampleRate: defaultSampleRate, Channels: defaultChannels}
+
+// AudioPolicy is the audio a room's clients send. Every client is told the
+// canonical format; one declaring its own at join must send that or one of
+// the allowed formats, in which case the room is mixed and receivers decode
+// each sender by the format it declared.
+type AudioPolicy struct {
+	audioFormat
+	Allowed []audioFormat `json:"allowed,omitempty"`
+
+	// mismatchReject, the default, or mismatchFlag
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// validate checks the policy
+func (p AudioPolicy) validate() error {
+	if err := p.audioFormat.validate(); err != nil {
+		return err
+	}
+	for _, format := range p.Allowed {
+		if format.Codec == "" {
+			return errors.New("allowed audio formats must name a codec")
+		}
+		if format.SampleRate < 0 || format.Channels < 0 || format.FrameMs < 0 {
+			return fmt.Errorf("invalid allowed audio format %s", format)
+		}
+	}
+	switch p.Mismatch {
+	case "", mismatchReject, mismatchFlag:
+		return nil
+	}
+	return fmt.Errorf("invalid audio mismatch policy %q: want %s or %s", p.Mismatch, mismatchReject, mismatchFlag)
+}
+
+// allows reports whether a client may send audio in the declared format
+func (p AudioPolicy) allows(declared audioFormat) bool {
+	if declared.fits(p.audioFormat) {
+		return true
+	}
+	for _, format := range p.Allowed {
+		if declared.fits(format) {
+			return true
+		}
+	}
+	return false
+}
+
+// fits reports whether the format matches allowed in every field both
+// state
+func (f audioFormat) fits(allowed audioFormat) bool {
+	same := func(a, b int) bool { return a == 0 || b == 0 || a == b }
+	return (f.Codec == "" || allowed.Codec == "" || strings.EqualFold(f.Codec, allowed.Codec)) &&
+		same(f.SampleRate, allowed.SampleRate) &&
+		same(f.Channels, allowed.Channels) &&
+		same(f.FrameMs, allowed.FrameMs)
+}
+
+// String formats the format as codec/sample rate/channels/frame duration,
+// leaving off what it doesn't state
+func (f audioFormat) String() string {
+	parts := []string{f.Codec}
+	if f.Codec == "" {
+		parts[0] = "any"
+	}
+	for _, n := range []int{f.SampleRate, f.Channels, f.FrameMs} {
+		if n == 0 {
+			parts = append(parts, "any")
+		} else {
+			parts = append(parts, strconv.Itoa(n))
+		}
+	}
+	for len(parts) > 1 && parts[len(parts)-1] == "any" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParseAudioFormats parses a comma-separated list of
+// codec/sample_rate/channels/frame_ms formats, where all but the codec may
+// be left off
+func ParseAudioFormats(s string) ([]audioFormat, error) {
+	var formats []audioFormat
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "/")
+		if parts[0] == "" || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid audio format %q: want codec/sample_rate/channels/frame_ms", entry)
+		}
+		format := audioFormat{Codec: parts[0]}
+		fields := []*int{&format.SampleRate, &format.Channels, &format.FrameMs}
+		for i, part := range parts[1:] {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid audio format %q: want codec/sample_rate/channels/frame_ms", entry)
+			}
+			*fields[i] = n
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// requestedFormat returns the audio format a client declares in its
+// upgrade's codec, sample_rate, channels and frame_ms parameters, zero if
+// it declares none
+func requestedFormat(r *http.Request) (audioFormat, error) {
+	query := r.URL.Query()
+	format := audioFormat{Codec: query.Get("codec")}
+	fields := []struct {
+		name  string
+		value *int
+	}{
+		{"sample_rate", &format.SampleRate},
+		{"channels", &format.Channels},
+		{"frame_ms", &format.FrameMs},
+	}
+	for _, field := range fields {
+		if value := query.Get(field.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return audioFormat{}, fmt.Errorf("invalid %s %q", field.name, value)
+			}
+			*field.value = n
+		}
+	}
+	return format, nil
+}
+
+// WithAudioPolicy sets the audio format advertised to joining clients and
+// the ones they may declare. The room admin API may change it while the
+// hub runs; clients already in the room keep the format they joined with.
+func WithAudioPolicy(policy AudioPolicy) HubOption {
+	return func(h *Hub) {
+		h.audio.Store(&policy)
+	}
+}
+
+// admitFormat checks the format a joining client declared against the
+// room's policy. It reports whether the client is let in flagged, or why it
+// is refused. A client declaring nothing is taken to send the canonical
+// format.
+func (h *Hub) admitFormat(declared audioFormat) (mismatch bool, err error) {
+	policy := h.audio.Load()
+	if declared == (audioFormat{}) || policy.allows(declared) {
+		return false, nil
+	}
+	if policy.Mismatch == mismatchFlag {
+		return true, nil
+	}
+	return false, fmt.Errorf("audio format %s not allowed in this room", declared)
+}
+
+// WithRoomAudio sets the audio policy of rooms whose config sets none
+func WithRoomAudio(policy AudioPolicy) GatewayOption {
+	return func(g *Gateway) {
+		g.audio = policy
+	}
+}
+
+// audioFor returns the named room's audio policy. The caller must hold the
+// gateway's mutex.
+func (g *Gateway) audioFor(name string) AudioPolicy {
+	if policy := g.roomConfigs[name].Audio; policy != nil {
+		return *policy
+	}
+	return g.audio
+}
+
+// updateAudio brings an open room's audio policy in line with its config.
+// The caller must hold the mutex.
+func (g *Gateway) updateAudio(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		policy := g.audioFor(name)
+		rm.hub.audio.Store(&policy)
+	}
+}
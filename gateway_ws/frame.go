@@ -0,0 +1,161 @@
+package main
+
+import (
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Initial capacity of pooled frame buffers
+	frameBufferSize = 4096
+
+	// Buffers that grew beyond this are left to the garbage collector so one
+	// oversized frame doesn't pin memory in the pool
+	maxPooledFrameSize = 64 * 1024
+)
+
+var framePool = sync.Pool{
+	New: func() interface{} {
+		return &frameBuffer{data: make([]byte, 0, frameBufferSize)}
+	},
+}
+
+// frameBuffer is a pooled inbound frame. It is reference counted: the reader
+// holds one reference, every fan-out worker and every listener it is queued
+// for holds another, and the buffer goes back to the pool when the last one
+// is released.
+type frameBuffer struct {
+	data []byte
+	refs atomic.Int32
+
+	// Binary for audio; server control messages are text
+	messageType int
+
+	// Framed once, by whichever listener writes the frame first
+	prepareOnce sync.Once
+	prepared    *websocket.PreparedMessage
+	prepareErr  error
+
+	// The frame as sent to walkie.json.v1 clients, framed on first use
+	jsonOnce     sync.Once
+	jsonPrepared *websocket.PreparedMessage
+	jsonErr      error
+
+	// Who sent the audio, and its number and arrival, for walkie.framed.v1
+	// clients, which get it framed with them on first use
+	header         frameHeader
+	framedOnce     sync.Once
+	framedPrepared *websocket.PreparedMessage
+	framedErr      error
+
+	// The same for walkie.framed.v1 clients in integrity mode
+	checksummedOnce     sync.Once
+	checksummedPrepared *websocket.PreparedMessage
+	checksummedErr      error
+
+	// When the gateway read the audio from its sender
+	receivedAt time.Time
+
+	// The audio's level in dBFS, once its sender's readPump has read it,
+	// and whether it is known
+	level      float64
+	levelRead  bool
+	levelKnown bool
+
+	// The frame as sent to walkie.pb.v1 clients, framed on first use
+	pbOnce     sync.Once
+	pbPrepared *websocket.PreparedMessage
+	pbErr      error
+
+	// The audio encoded for listeners receiving other codecs, each holding
+	// a reference
+	renditions []rendition
+}
+
+// getFrame returns an empty frame buffer holding one reference
+func getFrame() *frameBuffer {
+	f := framePool.Get().(*frameBuffer)
+	f.refs.Store(1)
+	f.messageType = websocket.BinaryMessage
+	return f
+}
+
+// readFrom replaces the buffer's contents with everything read from r
+func (f *frameBuffer) readFrom(r io.Reader) error {
+	f.data = f.data[:0]
+	for {
+		if len(f.data) == cap(f.data) {
+			f.data = append(f.data, 0)[:len(f.data)]
+		}
+		n, err := r.Read(f.data[len(f.data):cap(f.data)])
+		f.data = f.data[:len(f.data)+n]
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// retain adds a reference
+func (f *frameBuffer) retain() {
+	f.refs.Add(1)
+}
+
+// release drops a reference, returning the buffer to the pool when it was
+// the last one
+func (f *frameBuffer) release() {
+	switch n := f.refs.Add(-1); {
+	case n > 0:
+		return
+	case n < 0:
+		log.Printf("frame buffer released too many times")
+		return
+	}
+
+	for i, encoded := range f.renditions {
+		if encoded.frame != nil {
+			encoded.frame.release()
+		}
+		f.renditions[i] = rendition{}
+	}
+	f.renditions = f.renditions[:0]
+	if cap(f.data) > maxPooledFrameSize {
+		return
+	}
+	f.data = f.data[:0]
+	f.prepareOnce = sync.Once{}
+	f.prepared = nil
+	f.prepareErr = nil
+	f.jsonOnce = sync.Once{}
+	f.jsonPrepared = nil
+	f.jsonErr = nil
+	f.header = frameHeader{}
+	f.receivedAt = time.Time{}
+	f.level, f.levelRead, f.levelKnown = 0, false, false
+	f.framedOnce = sync.Once{}
+	f.framedPrepared = nil
+	f.framedErr = nil
+	f.checksummedOnce = sync.Once{}
+	f.checksummedPrepared = nil
+	f.checksummedErr = nil
+	f.pbOnce = sync.Once{}
+	f.pbPrepared = nil
+	f.pbErr = nil
+	framePool.Put(f)
+}
+
+// preparedMessage returns the frame as a PreparedMessage, framing it on
+// first use
+func (f *frameBuffer) preparedMessage() (*websocket.PreparedMessage, error) {
+	f.prepareOnce.Do(func() {
+		f.prepared, f.prepareErr = websocket.NewPreparedMessage(f.messageType, f.data)
+	})
+	return f.prepared, f.prepareErr
+}
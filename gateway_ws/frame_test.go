@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BenchmarkFanOutWrite writes a frame to 1000 listeners, framing it for
+// each with WriteMessage against once for all as a PreparedMessage, plain
+// and compressed as it would be if audio were ever sent deflated
+func BenchmarkFanOutWrite(b *testing.B) {
+	const clients = 1000
+	payload := pcmFrame(1)
+	tests := []struct {
+		name  string
+		write func(conn *websocket.Conn, frame *frameBuffer) error
+	}{
+		{"WriteMessage", func(conn *websocket.Conn, frame *frameBuffer) error {
+			return conn.WriteMessage(frame.messageType, frame.data)
+		}},
+		{"PreparedMessage", func(conn *websocket.Conn, frame *frameBuffer) error {
+			return outbound{frame: frame}.write(conn, protocolRaw, false)
+		}},
+	}
+	for _, compress := range []bool{false, true} {
+		conns := make([]*websocket.Conn, clients)
+		for i := range conns {
+			conns[i], _ = discardWS(b, compress)
+		}
+		for _, tt := range tests {
+			b.Run(fmt.Sprintf("%s/compress=%t", tt.name, compress), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					frame := getFrame()
+					frame.data = append(frame.data[:0], payload...)
+					for _, conn := range conns {
+						if err := tt.write(conn, frame); err != nil {
+							b.Fatal(err)
+						}
+					}
+					frame.release()
+				}
+			})
+		}
+	}
+}
+
+// Frames broadcast while listeners register and unregister and slow ones
+// are evicted mid fan-out are each released as many times as they were
+// retained, no more and no fewer. Run with -race.
+func TestFrameRefsUnderChurn(t *testing.T) {
+	const frames = 300
+	th := newTestHub(t, WithShards(4), WithWorkers(4), WithSendBuffer(8, 8), WithBroadcastPolicy(BroadcastBlock))
+	th.attach(20, SlowDropOldest)
+	th.attachSlow(10, SlowDisconnect, time.Millisecond)
+	sender := &Client{id: "talker", hub: th.Hub}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			client := th.newClient(fmt.Sprintf("churn%d", i), SlowDropNewest, 0)
+			if err := th.registerClient(client); err != nil {
+				t.Errorf("registering %s: %v", client.id, err)
+				client.closeSend(reasonNormal)
+				continue
+			}
+			th.unregisterClient(client, reasonNormal)
+			// In place of the writePump registration counted
+			th.pumps.Done()
+		}
+	}()
+	// Too large for the pool, so none is reused and each can be checked
+	// once the hub is done with it
+	payload := make([]byte, maxPooledFrameSize+1)
+	sent := make([]*frameBuffer, 0, frames)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < frames; i++ {
+			frame := getFrame()
+			frame.data = append(frame.data[:0], payload...)
+			sent = append(sent, frame)
+			th.submit(BroadcastMessage{frame: frame, sender: sender})
+		}
+	}()
+	wg.Wait()
+
+	waitFor(t, "the slow clients to be evicted", func() bool { return th.ClientCount() == 20 })
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := th.Stop(ctx); err != nil {
+		t.Fatalf("stopping: %v", err)
+	}
+	th.draining.Wait()
+	for i, frame := range sent {
+		if refs := frame.refs.Load(); refs != 0 {
+			t.Errorf("frame %d left with %d references", i, refs)
+		}
+	}
+}
+
+// BenchmarkBroadcastAllocs broadcasts to 100 listeners from pooled frame
+// buffers against allocating a buffer for each frame
+func BenchmarkBroadcastAllocs(b *testing.B) {
+	const clients = 100
+	payload := pcmFrame(1)
+	tests := []struct {
+		name     string
+		newFrame func() *frameBuffer
+	}{
+		{"pooled", getFrame},
+		{"allocated", func() *frameBuffer {
+			frame := &frameBuffer{data: make([]byte, 0, frameBufferSize), messageType: websocket.BinaryMessage}
+			frame.refs.Store(1)
+			return frame
+		}},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			th := newTestHub(b, WithSendBuffer(1024, 1024), WithBroadcastPolicy(BroadcastBlock))
+			th.attach(clients, SlowDropNewest)
+			sender := &Client{id: "talker", hub: th.Hub}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				frame := tt.newFrame()
+				frame.data = append(frame.data[:0], payload...)
+				th.submit(BroadcastMessage{frame: frame, sender: sender})
+			}
+			for th.handled() < int64(b.N)*clients {
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+const (
+	// A signed audio frame starts with the sender's sequence number, big
+	// endian, and the HMAC-SHA256 of the sender's ID, the sequence number
+	// and the audio
+	frameSeqSize    = 8
+	frameMACSize    = sha256.Size
+	frameHeaderSize = frameSeqSize + frameMACSize
+
+	// defaultFrameAuthFailures is how many frames failing verification
+	// close a connection
+	defaultFrameAuthFailures = 10
+)
+
+// Why a signed frame failed verification
+const (
+	frameMalformed = "malformed"
+	frameReplayed  = "replayed"
+	frameForged    = "bad_mac"
+)
+
+// frameKeyring holds the clients' frame keys from a JSON file, reread on
+// SIGHUP
+type frameKeyring struct {
+	path string
+
+	mutex sync.RWMutex
+	keys  map[string][]byte
+}
+
+// LoadFrameKeys reads the frame key file at path: a JSON object of client
+// ID to base64 key
+func LoadFrameKeys(path string) (*frameKeyring, error) {
+	k := &frameKeyring{path: path}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// reload rereads the key file. If it doesn't parse, the keys already loaded
+// stay in use. Connections keep the key they were admitted with.
+func (k *frameKeyring) reload() error {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("reading frame key file: %w", err)
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return fmt.Errorf("frame key file %s: %w", k.path, err)
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(key) == 0 {
+			return fmt.Errorf("frame key file %s: invalid key for %q: want base64", k.path, id)
+		}
+		keys[id] = key
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+	return nil
+}
+
+// lookup returns the client's frame key, or nil
+func (k *frameKeyring) lookup(id string) []byte {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.keys[id]
+}
+
+// WithFrameAuth makes every client sign its audio frames with a key from
+// keys, which may be nil, or its token's frame_key claim. A connection is
+// closed after failures frames fail verification; zero never closes it.
+func WithFrameAuth(keys *frameKeyring, failures int) GatewayOption {
+	return func(g *Gateway) {
+		g.frameAuth = true
+		g.frameKeys = keys
+		if failures >= 0 {
+			g.frameAuthFailures = failures
+		}
+	}
+}
+
+// ReloadFrameKeys rereads the frame key file
+func (g *Gateway) ReloadFrameKeys() error {
+	if g.frameKeys == nil {
+		return nil
+	}
+	if err := g.frameKeys.reload(); err != nil {
+		return err
+	}
+	g.frameKeys.mutex.RLock()
+	defer g.frameKeys.mutex.RUnlock()
+	log.Printf("Frame keys reloaded for %d clients", len(g.frameKeys.keys))
+	return nil
+}
+
+// admitFrameKey returns a verifier for the client's signed frames, or nil
+// if frames aren't signed. It answers 403 and reports false for a client
+// without a key. The token's key wins over the file's.
+func (g *Gateway) admitFrameKey(w http.ResponseWriter, clientID string, claims *tokenClaims) (*frameVerifier, bool) {
+	if !g.frameAuth {
+		return nil, true
+	}
+	var key []byte
+	if claims != nil {
+		key = claims.FrameKey
+	}
+	if key == nil && g.frameKeys != nil {
+		key = g.frameKeys.lookup(clientID)
+	}
+	if key == nil {
+		log.Printf("Client %s refused: no frame key", clientID)
+		g.audit.record(AuditEvent{Event: "connect", ClientID: clientID, Outcome: auditDenied, Reason: "no frame key"})
+		http.Error(w, "no frame key for this client", http.StatusForbidden)
+		return nil, false
+	}
+	return newFrameVerifier(key, clientID), true
+}
+
+// frameVerifier checks a connection's signed audio frames. Only the readPump
+// of the connection touches it, so its hash is reused from frame to frame.
+type frameVerifier struct {
+	mac hash.Hash
+
+	// The signed ID, as a 2-byte big endian length then the ID, and room
+	// for the computed MAC
+	prefix []byte
+	sum    [frameMACSize]byte
+
+	// The last sequence number verified, and the frames failing so far
+	seq      uint64
+	started  bool
+	failures int
+}
+
+// newFrameVerifier verifies frames signed with key by the client with the ID
+// it connected with
+func newFrameVerifier(key []byte, id string) *frameVerifier {
+	prefix := make([]byte, 2, 2+len(id))
+	binary.BigEndian.PutUint16(prefix, uint16(len(id)))
+	return &frameVerifier{
+		mac:    hmac.New(sha256.New, key),
+		prefix: append(prefix, id...),
+	}
+}
+
+// verify checks a signed frame and returns its audio, or why it failed.
+// Sequence numbers must increase over the connection, so a captured frame
+// can't be replayed on it.
+func (v *frameVerifier) verify(data []byte) ([]byte, string) {
+	if len(data) < frameHeaderSize {
+		return nil, frameMalformed
+	}
+	seq := binary.BigEndian.Uint64(data)
+	audio := data[frameHeaderSize:]
+
+	v.mac.Reset()
+	v.mac.Write(v.prefix)
+	v.mac.Write(data[:frameSeqSize])
+	v.mac.Write(audio)
+	if !hmac.Equal(v.mac.Sum(v.sum[:0]), data[frameSeqSize:frameHeaderSize]) {
+		return nil, frameForged
+	}
+	// Checked only once the MAC holds, so a forged frame can't push the
+	// sequence on
+	if v.started && seq <= v.seq {
+		return nil, frameReplayed
+	}
+	v.seq, v.started = seq, true
+	return audio, ""
+}
+
+// verifyFrame checks a signed audio frame the client's connection sent into
+// sender's room, and strips the header off it. It reports whether the frame
+// may be broadcast, and whether the connection must close.
+func (c *Client) verifyFrame(sender *Client, frame *frameBuffer) (ok, exceeded bool) {
+	if c.verifier == nil {
+		return true, false
+	}
+	audio, failure := c.verifier.verify(frame.data)
+	if failure == "" {
+		// Shifted down rather than resliced so the pooled buffer keeps its
+		// capacity
+		frame.data = frame.data[:copy(frame.data, audio)]
+		return true, false
+	}
+
+	c.frameAuthFailed.Add(1)
+	sender.hub.frameAuthFailed.Add(1)
+	c.verifier.failures++
+	if c.verifier.failures == 1 {
+		log.Printf("Client %s sent a frame failing verification (%s) in room %s", c.id, failure, sender.hub.room)
+	} else {
+		c.gateway.debugf("Client %s sent a frame failing verification (%s) in room %s", c.id, failure, sender.hub.room)
+	}
+	if limit := c.gateway.frameAuthFailures; limit > 0 && c.verifier.failures >= limit {
+		sender.hub.frameAuthDisconnects.Add(1)
+		log.Printf("Client %s closed after %d frames failed verification", c.id, c.verifier.failures)
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testFrameKey = []byte("0123456789abcdef0123456789abcdef")
+
+// signFrame returns audio with the header the sender id signs it with
+func signFrame(key []byte, id string, seq uint64, audio []byte) []byte {
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(audio))
+	binary.BigEndian.PutUint64(frame, seq)
+	mac := hmac.New(sha256.New, key)
+	binary.Write(mac, binary.BigEndian, uint16(len(id)))
+	mac.Write([]byte(id))
+	mac.Write(frame[:frameSeqSize])
+	mac.Write(audio)
+	copy(frame[frameSeqSize:], mac.Sum(nil))
+	return append(frame, audio...)
+}
+
+func TestFrameVerify(t *testing.T) {
+	audio := pcmFrame(1)
+	tampered := signFrame(testFrameKey, "radio", 3, audio)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		failure string
+	}{
+		{"signed", [][]byte{signFrame(testFrameKey, "radio", 1, audio)}, ""},
+		{"sequence increasing", [][]byte{signFrame(testFrameKey, "radio", 1, audio), signFrame(testFrameKey, "radio", 5, audio)}, ""},
+		{"replayed", [][]byte{signFrame(testFrameKey, "radio", 2, audio), signFrame(testFrameKey, "radio", 2, audio)}, frameReplayed},
+		{"sequence going back", [][]byte{signFrame(testFrameKey, "radio", 2, audio), signFrame(testFrameKey, "radio", 1, audio)}, frameReplayed},
+		{"tampered", [][]byte{tampered}, frameForged},
+		{"wrong key", [][]byte{signFrame([]byte("another key"), "radio", 1, audio)}, frameForged},
+		{"signed as someone else", [][]byte{signFrame(testFrameKey, "dispatch", 1, audio)}, frameForged},
+		{"short", [][]byte{make([]byte, frameHeaderSize-1)}, frameMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newFrameVerifier(testFrameKey, "radio")
+			var got []byte
+			var failure string
+			for _, frame := range tt.frames {
+				got, failure = v.verify(frame)
+			}
+			if failure != tt.failure {
+				t.Fatalf("failure %q, want %q", failure, tt.failure)
+			}
+			if failure == "" && string(got) != string(audio) {
+				t.Error("verified frame's audio differs")
+			}
+		})
+	}
+}
+
+// BenchmarkFrameVerify verifies signed 20ms frames, 640 bytes of 16kHz
+// pcm16 and a typical opus packet, and reports what share of a core a
+// talker sending 50 frames a second costs
+func BenchmarkFrameVerify(b *testing.B) {
+	const framesPerSecond = 50
+	for _, size := range []int{640, 80} {
+		b.Run(fmt.Sprintf("bytes=%d", size), func(b *testing.B) {
+			v := newFrameVerifier(testFrameKey, "radio")
+			frames := make([][]byte, 1024)
+			for i := range frames {
+				frames[i] = signFrame(testFrameKey, "radio", uint64(i+1), make([]byte, size))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%len(frames) == 0 && i > 0 {
+					// Sequence numbers must keep increasing
+					v.started = false
+				}
+				if _, failure := v.verify(frames[i%len(frames)]); failure != "" {
+					b.Fatal(failure)
+				}
+			}
+			perFrame := float64(b.Elapsed()) / float64(b.N)
+			b.ReportMetric(perFrame*framesPerSecond/float64(time.Second)*100, "%core/talker")
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// protocolFramed carries audio as binary frames behind a header naming the
+// sender, and control messages as JSON text frames. The header is:
+//
+//	byte 0      version, framedVersion
+//	bytes 1-2   sender ID length n, big endian
+//	bytes 3-    sender ID, n bytes
+//	next 8      sequence number, big endian
+//	next 8      time the gateway received the frame, Unix milliseconds, big
+//	            endian
+//
+// then the audio, after a multi-room connection's room tag and any signed
+// audio header. A connection in integrity mode uses version 2, 0xA2, whose
+// header ends with the CRC32C of the rest of the frame, big endian. In a
+// sender's header the time is when it captured the audio, if it says. The
+// gateway numbers each sender's frames in each room from 1, so a gap is a
+// frame lost on the way. Clients send the same header; the gateway checks
+// the version and that the ID is empty or their own, counts the frames
+// missing from their numbers, and fills in its own number and time.
+const protocolFramed = "walkie.framed.v1"
+
+const (
+	// framedVersion is the first byte of a walkie.framed.v1 header: 0xA
+	// marks the header, and the low nibble is its version
+	framedVersion = 0xA1
+
+	// framedFixedSize is the header's size without the sender ID
+	framedFixedSize = 1 + 2 + 8 + 8
+
+	// maxFramedID is the longest sender ID a header carries; longer IDs
+	// are cut short
+	maxFramedID = 1<<16 - 1
+)
+
+var (
+	errFramedShort   = errors.New("audio frame header is truncated")
+	errFramedVersion = errors.New("audio frame header has an unknown version")
+	errFramedSender  = errors.New("audio frame header names another sender")
+)
+
+// frameHeader is the walkie.framed.v1 header of an audio frame
+type frameHeader struct {
+	sender string
+	seq    uint64
+	// Unix milliseconds
+	received int64
+
+	// When the sender captured the audio, by its clock in Unix
+	// milliseconds, if it said. Not part of the header, whose time field
+	// carries it from a sender.
+	captured int64
+
+	// Whether the audio is a whisper, for one listener alone, and whether
+	// it is priority traffic. Not part of the header either.
+	private  bool
+	priority bool
+
+	// The RMS level of the frame's audio, as a share of full scale, if its
+	// sender declared it for VOX. Not part of the header either.
+	level float32
+
+	// The CRC32C a version 2 header carries, as a client sent it
+	crc uint32
+}
+
+// size returns the header's encoded size
+func (h frameHeader) size() int {
+	return framedFixedSize + min(len(h.sender), maxFramedID)
+}
+
+// appendFrameHeader appends the encoded header to dst
+func appendFrameHeader(dst []byte, h frameHeader) []byte {
+	sender := h.sender
+	if len(sender) > maxFramedID {
+		sender = sender[:maxFramedID]
+	}
+	dst = append(dst, framedVersion)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(sender)))
+	dst = append(dst, sender...)
+	dst = binary.BigEndian.AppendUint64(dst, h.seq)
+	return binary.BigEndian.AppendUint64(dst, uint64(h.received))
+}
+
+// parseFrameHeader decodes the header at the start of data, a version 2
+// header if checksummed, and returns it with its size
+func parseFrameHeader(data []byte, checksummed bool) (frameHeader, int, error) {
+	version, fixed := byte(framedVersion), 16
+	if checksummed {
+		version, fixed = framedChecksummed, 16+framedCRCSize
+	}
+	if len(data) < 3 {
+		return frameHeader{}, 0, errFramedShort
+	}
+	if data[0] != version {
+		return frameHeader{}, 0, errFramedVersion
+	}
+	n := 3 + int(binary.BigEndian.Uint16(data[1:]))
+	if len(data) < n+fixed {
+		return frameHeader{}, 0, errFramedShort
+	}
+	header := frameHeader{
+		sender:   string(data[3:n]),
+		seq:      binary.BigEndian.Uint64(data[n:]),
+		received: int64(binary.BigEndian.Uint64(data[n+8:])),
+	}
+	if checksummed {
+		header.crc = binary.BigEndian.Uint32(data[n+16:])
+	}
+	return header, n + fixed, nil
+}
+
+// unframe strips the header off an audio frame a walkie.framed.v1 client
+// sent, checking its CRC in integrity mode
+func (c *Client) unframe(frame *frameBuffer) error {
+	header, n, err := parseFrameHeader(frame.data, c.integrity)
+	if err != nil {
+		return err
+	}
+	if header.sender != "" && header.sender != c.id {
+		return errFramedSender
+	}
+	if c.integrity && frameCRC(frame.data[n:]) != header.crc {
+		return errFramedCorrupt
+	}
+	// The client's own number counts its upstream loss until stamp
+	// replaces it, and its time is when it captured the audio
+	frame.header.seq = header.seq
+	frame.header.captured = header.received
+	// Shifted down rather than resliced so the pooled buffer keeps its
+	// capacity
+	frame.data = frame.data[:copy(frame.data, frame.data[n:])]
+	return nil
+}
+
+// stamp numbers an audio frame the client is about to broadcast, and records
+// its sender and arrival for walkie.framed.v1 listeners. Only the readPump
+// of the client's connection may call it.
+func (c *Client) stamp(frame *frameBuffer, now time.Time) {
+	c.framedSeq++
+	frame.header = frameHeader{sender: c.id, seq: c.framedSeq, received: now.UnixMilli(), captured: frame.header.captured, priority: frame.header.priority}
+	frame.receivedAt = now
+	c.transcode(frame)
+}
+
+// framedMessage returns the frame as a walkie.framed.v1 PreparedMessage,
+// framing it on first use. Audio gets its header; text frames go as they
+// are.
+func (f *frameBuffer) framedMessage() (*websocket.PreparedMessage, error) {
+	if f.messageType == websocket.TextMessage {
+		return f.preparedMessage()
+	}
+	f.framedOnce.Do(func() {
+		data := make([]byte, 0, f.header.size()+len(f.data))
+		data = append(appendFrameHeader(data, f.header), f.data...)
+		f.framedPrepared, f.framedErr = websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+	})
+	return f.framedPrepared, f.framedErr
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// A header decodes to what was encoded, in either version, and its size is
+// what it takes up ahead of the audio
+func TestFrameHeaderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      frameHeader
+		checksummed bool
+	}{
+		{"no sender", frameHeader{seq: 1, received: 1700000000000}, false},
+		{"sender", frameHeader{sender: "alice", seq: 42, received: 1700000000123}, false},
+		{"largest numbers", frameHeader{sender: "b", seq: 1<<64 - 1, received: 1<<63 - 1}, false},
+		{"negative time", frameHeader{sender: "c", seq: 7, received: -1}, false},
+		{"longest sender", frameHeader{sender: strings.Repeat("x", maxFramedID), seq: 3}, false},
+		{"checksummed", frameHeader{sender: "alice", seq: 42, received: 1700000000123}, true},
+	}
+	audio := []byte{1, 2, 3, 4}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data []byte
+			if tt.checksummed {
+				data = appendChecksummedHeader(nil, tt.header, audio)
+			} else {
+				data = appendFrameHeader(nil, tt.header)
+			}
+			want := tt.header.size()
+			if tt.checksummed {
+				want += framedCRCSize
+			}
+			if len(data) != want {
+				t.Fatalf("encoded %d bytes, want %d", len(data), want)
+			}
+			got, n, err := parseFrameHeader(append(data, audio...), tt.checksummed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(data) {
+				t.Errorf("header size %d, want %d", n, len(data))
+			}
+			if got.sender != tt.header.sender || got.seq != tt.header.seq || got.received != tt.header.received {
+				t.Errorf("decoded %+v, want %+v", got, tt.header)
+			}
+			if tt.checksummed && got.crc != frameCRC(audio) {
+				t.Errorf("decoded CRC %08x, want %08x", got.crc, frameCRC(audio))
+			}
+		})
+	}
+}
+
+// A sender ID too long for its length field is cut short, not wrapped
+func TestFrameHeaderLongSender(t *testing.T) {
+	h := frameHeader{sender: strings.Repeat("y", maxFramedID+10), seq: 5}
+	data := appendFrameHeader(nil, h)
+	got, n, err := parseFrameHeader(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != h.size() || len(got.sender) != maxFramedID || got.seq != 5 {
+		t.Errorf("decoded a %d byte sender and seq %d in %d bytes", len(got.sender), got.seq, n)
+	}
+}
+
+// Every truncation of a header is short, and a header of another version or
+// none at all is refused
+func TestParseFrameHeaderErrors(t *testing.T) {
+	for _, checksummed := range []bool{false, true} {
+		h := frameHeader{sender: "alice", seq: 9, received: 1700000000000}
+		var data []byte
+		if checksummed {
+			data = appendChecksummedHeader(nil, h)
+		} else {
+			data = appendFrameHeader(nil, h)
+		}
+		for i := 0; i < len(data); i++ {
+			if _, _, err := parseFrameHeader(data[:i], checksummed); err != errFramedShort {
+				t.Errorf("checksummed %t, %d of %d bytes: got %v, want %v", checksummed, i, len(data), err, errFramedShort)
+			}
+		}
+		if _, _, err := parseFrameHeader(data, !checksummed); err != errFramedVersion {
+			t.Errorf("checksummed %t header parsed as the other version: got %v, want %v", checksummed, err, errFramedVersion)
+		}
+	}
+	if _, _, err := parseFrameHeader([]byte("raw audio, no header"), false); err != errFramedVersion {
+		t.Errorf("raw audio: got %v, want %v", err, errFramedVersion)
+	}
+}
+
+// parseFrameHeader never panics or reads past what it is given, and what it
+// decodes encodes back to the bytes it came from
+func FuzzParseFrameHeader(f *testing.F) {
+	h := frameHeader{sender: "alice", seq: 42, received: 1700000000123}
+	v1 := appendFrameHeader(nil, h)
+	v2 := appendChecksummedHeader(nil, h, []byte{1, 2, 3})
+	f.Add(v1, false)
+	f.Add(append(v1, 1, 2, 3), false)
+	f.Add(v1[:len(v1)-1], false)
+	f.Add(v2, true)
+	f.Add(v2[:5], true)
+	f.Add(appendFrameHeader(nil, frameHeader{}), false)
+	f.Add([]byte{framedVersion, 0xff, 0xff}, false)
+	f.Add([]byte{}, false)
+	f.Fuzz(func(t *testing.T, data []byte, checksummed bool) {
+		header, n, err := parseFrameHeader(data, checksummed)
+		if err != nil {
+			if err != errFramedShort && err != errFramedVersion {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return
+		}
+		if n > len(data) {
+			t.Fatalf("header size %d past the %d bytes given", n, len(data))
+		}
+		encoded := appendFrameHeader(nil, header)
+		if checksummed {
+			encoded[0] = framedChecksummed
+			encoded = append(encoded, data[n-framedCRCSize:n]...)
+		}
+		if !bytes.Equal(encoded, data[:n]) {
+			t.Fatalf("re-encoded %x, want %x", encoded, data[:n])
+		}
+	})
+}
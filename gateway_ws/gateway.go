@@ -0,0 +1,778 @@
+package main
+
+import (
+	"compress/flate"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway is the HTTP front end of the rooms: it identifies clients, enforces
+// admission limits, routes them to their room's hub and upgrades connections
+type Gateway struct {
+	// Options every room's hub is created with
+	hubOpts []HubOption
+
+	// Guards rooms and stopped, and every room's joining count
+	mutex   sync.Mutex
+	rooms   map[string]*room
+	stopped bool
+
+	// Closed by Stop to end the room sweep
+	quit chan struct{}
+
+	// How long an empty room stays open, and rooms created and destroyed
+	roomLinger     time.Duration
+	roomsCreated   atomic.Uint64
+	roomsDestroyed atomic.Uint64
+
+	// Participant cap, rate limit, talk limit, floor control and line for
+	// the floor, and audio policy for rooms without an override
+	roomCapacity int
+	rateLimit    RateLimit
+	talkLimit    TalkLimit
+	floorControl bool
+	floorQueue   int
+	audio        AudioPolicy
+
+	// The VOX thresholds and hold time of clients that set none
+	vox voxSettings
+
+	// The silence suppression of rooms without an override
+	silence SilenceSuppression
+
+	// Whether rooms without an override mix overlapping talkers, and pace
+	// their senders' audio
+	mixing bool
+	pacing bool
+
+	// Room configs, set by options, the room store and the room admin API,
+	// the store persistent ones are written to, and joins refused per room
+	// for a bad credential. Guarded by mutex.
+	roomConfigs map[string]RoomConfig
+	store       RoomStore
+	joinDenied  map[string]uint64
+
+	// Invites by token, guarded by mutex
+	invites map[string]*invite
+
+	// Bans by ID, guarded by mutex, and the store they are kept in, if any
+	bans     map[string]*Ban
+	banStore BanStore
+
+	// Compiled schedules of scheduled rooms and when admins forced them
+	// open until, guarded by mutex, and how long a closing room's clients
+	// have before they are disconnected
+	schedules     map[string]*schedule
+	forcedOpen    map[string]time.Time
+	scheduleGrace time.Duration
+
+	proxies  trustedProxies
+	perIP    *ipLimiter
+	attempts *attemptLimiter
+
+	// Addresses that may and may not upgrade, if restricted
+	ipAccess *ipAccess
+
+	// Whether audio frames must be signed, the keys they may be signed
+	// with besides tokens' claims, and the failures that close a connection
+	frameAuth         bool
+	frameKeys         *frameKeyring
+	frameAuthFailures int
+
+	// Invalid audio frames in a row after which a connection is closed, 0
+	// for never
+	frameViolations int
+
+	// Origins browsers may upgrade from, any if empty, whether clients that
+	// send no Origin may upgrade when the list is set, and upgrades refused
+	// for their origin
+	origins        originAllowlist
+	allowNoOrigin  bool
+	rejectedOrigin atomic.Uint64
+
+	// Whether debug messages are logged
+	debug bool
+
+	// Where security-relevant events are recorded, if anywhere
+	audit *auditLog
+
+	// How long the hub loop may stall before the gateway reports not ready
+	stallThreshold time.Duration
+
+	// Set once a drain or shutdown begins
+	draining atomic.Bool
+
+	// Upgrader used by serveWS, configured by the options
+	upgrader websocket.Upgrader
+
+	// flate level for clients that negotiate permessage-deflate
+	compressionLevel int
+
+	// Subprotocol for clients that offer none the server speaks; empty
+	// refuses them
+	defaultProtocol string
+
+	// Bearer token the management endpoints require, if set
+	adminToken string
+
+	// Key join tokens are signed with
+	joinKey []byte
+
+	// API keys upgrades must present, if required, each confining its
+	// connections to a tenant's rooms
+	keys *keyring
+
+	// Verifier of the bearer tokens upgrades must present, if required, and
+	// upgrades refused for a missing or invalid one
+	jwt          *jwtVerifier
+	unauthorized atomic.Uint64
+
+	// Whether a verified client certificate identifies its client, and the
+	// rules mapping certificates to roles and rooms
+	clientCerts bool
+	certRules   []CertRule
+
+	// Most rooms a multi-room client may be in at once
+	maxRoomsPerConnection int
+
+	// Caps on the key exchange messages each connection sends
+	keyExchange keyExchangeLimits
+	chat        chatLimits
+
+	// Invalid control messages a minute that close a connection, 0 for
+	// no limit
+	controlErrorLimit int
+
+	// How long a receiver has to acknowledge a message, and how many times
+	// it is sent again
+	ackTimeout time.Duration
+	ackRetries int
+
+	// Delays of audio frames from arriving to being written to a socket
+	delay delayHistogram
+
+	// Protocol version clients must speak, and the versions they offered
+	minVersion int
+	versions   versionCounts
+
+	// Where and how rooms configured to be recorded are recorded, and
+	// where finished recordings are uploaded, if anywhere
+	recording recordSettings
+	uploader  *uploader
+
+	// Handshakes the upgrader refused
+	upgradeErrors atomic.Uint64
+
+	// Upgrades refused because the client's address was at its limit
+	rejectedPerIP atomic.Uint64
+
+	// Announcements pushed to every room
+	announcements atomic.Uint64
+}
+
+// GatewayOption configures optional Gateway behaviour
+type GatewayOption func(*Gateway)
+
+// WithTrustedProxies sets the proxies whose X-Forwarded-For headers are
+// believed when working out a client's address
+func WithTrustedProxies(proxies trustedProxies) GatewayOption {
+	return func(g *Gateway) {
+		g.proxies = proxies
+	}
+}
+
+// WithDebugLog logs debug messages, such as every address access decision
+func WithDebugLog(enabled bool) GatewayOption {
+	return func(g *Gateway) {
+		g.debug = enabled
+	}
+}
+
+// debugf logs a debug message if they are enabled
+func (g *Gateway) debugf(format string, args ...interface{}) {
+	if g.debug {
+		log.Printf("DEBUG "+format, args...)
+	}
+}
+
+// WithPerIPLimit caps concurrent connections per client address (per /64 for
+// IPv6). Zero means no limit.
+func WithPerIPLimit(limit int) GatewayOption {
+	return func(g *Gateway) {
+		if limit > 0 {
+			g.perIP = newIPLimiter(limit)
+		}
+	}
+}
+
+// WithUpgradeBuffers sets the upgrader's read and write buffer sizes in
+// bytes. Zero keeps the library default; larger write buffers let big audio
+// frames go out in fewer writes.
+func WithUpgradeBuffers(read, write int) GatewayOption {
+	return func(g *Gateway) {
+		g.upgrader.ReadBufferSize = read
+		g.upgrader.WriteBufferSize = write
+	}
+}
+
+// WithHandshakeTimeout bounds how long the upgrade handshake may take
+func WithHandshakeTimeout(d time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		g.upgrader.HandshakeTimeout = d
+	}
+}
+
+// WithCompression lets clients negotiate permessage-deflate. Only control
+// messages are compressed, at the given flate level; audio never is.
+func WithCompression(enabled bool, level int) GatewayOption {
+	return func(g *Gateway) {
+		g.upgrader.EnableCompression = enabled
+		if level >= flate.HuffmanOnly && level <= flate.BestCompression {
+			g.compressionLevel = level
+		}
+	}
+}
+
+// NewGateway creates a Gateway whose rooms are hubs created with hubOpts. The
+// default room is opened straight away.
+func NewGateway(hubOpts []HubOption, opts ...GatewayOption) *Gateway {
+	g := &Gateway{
+		hubOpts:          hubOpts,
+		rooms:            make(map[string]*room),
+		roomConfigs:      make(map[string]RoomConfig),
+		joinDenied:       make(map[string]uint64),
+		invites:          make(map[string]*invite),
+		bans:             make(map[string]*Ban),
+		schedules:        make(map[string]*schedule),
+		forcedOpen:       make(map[string]time.Time),
+		scheduleGrace:    defaultScheduleGrace,
+		quit:             make(chan struct{}),
+		roomLinger:       defaultRoomLinger,
+		stallThreshold:   defaultStallThreshold,
+		defaultProtocol:  protocolRaw,
+		frameViolations:  defaultFrameViolations,
+		audio:            AudioPolicy{audioFormat: defaultAudioFormat},
+		vox:              voxSettings{Attack: defaultVOXAttack, Release: defaultVOXRelease, HoldMs: defaultVOXHold.Milliseconds()},
+		minVersion:       legacyProtocolVersion,
+		ackTimeout:       defaultAckTimeout,
+		ackRetries:       defaultAckRetries,
+		compressionLevel: defaultCompressionLevel,
+
+		maxRoomsPerConnection: defaultMaxRoomsPerConnection,
+		keyExchange: keyExchangeLimits{
+			maxBytes:  defaultKeyExchangeBytes,
+			perSecond: defaultKeyExchangeRate,
+			burst:     defaultKeyExchangeBurst,
+		},
+		chat: chatLimits{
+			maxLength: defaultChatMaxLength,
+			perSecond: defaultChatRate,
+			burst:     defaultChatBurst,
+		},
+		upgrader: websocket.Upgrader{
+			HandshakeTimeout: defaultHandshakeTimeout,
+			Subprotocols:     subprotocols,
+		},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.upgrader.CheckOrigin = g.checkOrigin
+	g.upgrader.Error = g.upgradeError
+
+	rm, _ := g.joinRoom(defaultRoom, nil)
+	g.joined(rm)
+	go g.sweepRooms()
+	go g.enforceSchedules()
+	if g.keys != nil {
+		go g.watchKeys()
+	}
+	return g
+}
+
+// upgradeError is the upgrader's error handler. It logs why the handshake
+// failed and counts it, and sends the client only the status text.
+func (g *Gateway) upgradeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	g.upgradeErrors.Add(1)
+	log.Printf("WebSocket upgrade from %s failed with %d: %v", r.RemoteAddr, status, reason)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// RoomStats is a room's name and its hub's counters
+type RoomStats struct {
+	Name string `json:"room"`
+	HubStats
+}
+
+// GatewayStats lists every room along with the gateway's own counters
+type GatewayStats struct {
+	Clients        int         `json:"clients"`
+	Rooms          []RoomStats `json:"rooms"`
+	RoomsCreated   uint64      `json:"rooms_created"`
+	RoomsDestroyed uint64      `json:"rooms_destroyed"`
+	RejectedPerIP  uint64      `json:"rejected_per_ip"`
+	RejectedOrigin uint64      `json:"rejected_origin"`
+	UpgradeErrors  uint64      `json:"upgrade_errors"`
+	Announcements  uint64      `json:"announcements"`
+	Unauthorized   uint64      `json:"unauthorized"`
+
+	// Upgrade attempts refused for coming too fast, and the addresses that
+	// had the most refused
+	ThrottledAttempts  uint64             `json:"throttled_attempts"`
+	ThrottledAddresses []ThrottledAddress `json:"throttled_addresses,omitempty"`
+
+	// Address lists and their decisions, if upgrades are restricted by
+	// address
+	IPAccess *IPAccessStats `json:"ip_access,omitempty"`
+
+	// Audit events written and dropped, if there is an audit log
+	Audit *AuditStats `json:"audit,omitempty"`
+
+	// Recordings uploaded and waiting to, if they are uploaded
+	Uploads *UploadStats `json:"uploads,omitempty"`
+
+	// Joins refused per room that requires a credential
+	JoinDenied map[string]uint64 `json:"join_denied"`
+
+	// Clients by the protocol version they offered: "none" for those that
+	// stated none, and "newer" for versions past the gateway's own
+	ProtocolVersions map[string]uint64 `json:"protocol_versions"`
+}
+
+// Stats returns counters for the gateway and each of its rooms
+func (g *Gateway) Stats() GatewayStats {
+	stats := GatewayStats{
+		Rooms:          []RoomStats{},
+		RoomsCreated:   g.roomsCreated.Load(),
+		RoomsDestroyed: g.roomsDestroyed.Load(),
+		RejectedPerIP:  g.rejectedPerIP.Load(),
+		RejectedOrigin: g.rejectedOrigin.Load(),
+		UpgradeErrors:  g.upgradeErrors.Load(),
+		Announcements:  g.announcements.Load(),
+		Unauthorized:   g.unauthorized.Load(),
+		JoinDenied:     g.deniedJoins(),
+
+		ProtocolVersions: g.versions.stats(),
+	}
+	if g.attempts != nil {
+		stats.ThrottledAttempts, stats.ThrottledAddresses = g.attempts.stats()
+	}
+	if g.ipAccess != nil {
+		ipStats := g.ipAccess.Stats()
+		stats.IPAccess = &ipStats
+	}
+	if g.audit != nil {
+		auditStats := g.audit.Stats()
+		stats.Audit = &auditStats
+	}
+	if g.uploader != nil {
+		uploadStats := g.uploader.Stats()
+		stats.Uploads = &uploadStats
+	}
+	g.eachRoom(func(rm *room) {
+		hubStats := rm.hub.Stats()
+		stats.Clients += hubStats.Clients
+		stats.Rooms = append(stats.Rooms, RoomStats{Name: rm.name, HubStats: hubStats})
+	})
+	return stats
+}
+
+// defaultHandshakeTimeout bounds the upgrade handshake
+const defaultHandshakeTimeout = 10 * time.Second
+
+// retryAfterSeconds is the Retry-After hint sent with capacity rejections
+const retryAfterSeconds = 5
+
+// requestedSendBuffer returns the send buffer size the client asked for with
+// the send_buffer query parameter or X-Send-Buffer header, or zero
+func requestedSendBuffer(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("send_buffer")
+	if value == "" {
+		value = r.Header.Get("X-Send-Buffer")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid send buffer size %q", value)
+	}
+	return size, nil
+}
+
+// serveWS handles websocket requests from the peer
+func (g *Gateway) serveWS(w http.ResponseWriter, r *http.Request) {
+	// A client retrying in a tight loop is turned away before any work
+	if !g.admitAttempt(w, r) {
+		return
+	}
+
+	// Generate a simple client ID (in production, use proper UUID)
+	clientID := r.Header.Get("X-Client-ID")
+	if clientID == "" {
+		clientID = r.RemoteAddr
+	}
+
+	if g.draining.Load() {
+		http.Error(w, "server draining", http.StatusServiceUnavailable)
+		return
+	}
+	if !g.admitIP(w, r) {
+		return
+	}
+	if !g.admitOrigin(w, r) {
+		return
+	}
+
+	// A verified client certificate, or else a bearer token, names the
+	// client
+	claims, ok := g.admitCertificate(w, r)
+	if !ok {
+		return
+	}
+	if claims == nil {
+		if claims, ok = g.authenticate(w, r); !ok {
+			return
+		}
+	}
+	if claims != nil {
+		clientID = claims.Subject
+	}
+	if !g.admitBanned(w, clientID, g.proxies.clientIP(r)) {
+		return
+	}
+	verifier, ok := g.admitFrameKey(w, clientID, claims)
+	if !ok {
+		return
+	}
+
+	name, err := roomName(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !claims.allows(name) {
+		log.Printf("Client %s refused from room %s: not in its token's rooms", clientID, name)
+		g.auditRefused(r, clientID, name, "room not in token")
+		http.Error(w, "token does not allow this room", http.StatusForbidden)
+		return
+	}
+
+	// An API key confines the client to its tenant's rooms, which it names
+	// as if they were the only ones
+	key, ok := g.admitKey(w, r)
+	if !ok {
+		return
+	}
+	if key != nil {
+		name = tenantRoom(key.Tenant, name)
+	}
+
+	protocol, ok := g.negotiateSubprotocol(r)
+	if !ok {
+		log.Printf("Client %s rejected: no supported subprotocol offered", clientID)
+		g.refuseSubprotocol(w)
+		return
+	}
+
+	offered, err := offeredVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	version := negotiateVersion(offered)
+	if offered != 0 && g.outdated(version) {
+		g.versions.count(offered, 1)
+		g.refuseOutdated(w, r, clientID, version)
+		return
+	}
+
+	if !g.admitScheduled(w, name, clientID) {
+		return
+	}
+
+	// An invite stands in for the room's credential. Its use is given back
+	// unless the client gets connected.
+	var moderator bool
+	refund := func() {}
+	if invitation(r) != "" {
+		refund, ok = g.admitInvited(w, r, name, clientID)
+	} else {
+		moderator, ok = g.admitToRoom(w, r, name, clientID)
+	}
+	if !ok {
+		return
+	}
+	if claims != nil && claims.Moderator {
+		moderator = true
+	}
+	connected := false
+	defer func() {
+		if !connected {
+			refund()
+		}
+	}()
+
+	ip := g.proxies.clientIP(r)
+	releaseIP, ok := g.perIP.acquire(ipBucket(ip))
+	if !ok {
+		g.rejectedPerIP.Add(1)
+		log.Printf("Client %s rejected: too many connections from %s", clientID, ipBucket(ip))
+		http.Error(w, "too many connections from this address", http.StatusTooManyRequests)
+		return
+	}
+	if key != nil {
+		releaseKey, ok := g.keys.acquire(key)
+		if !ok {
+			releaseIP()
+			log.Printf("Client %s rejected: API key %s at its %d connections", clientID, key.ID, key.MaxClients)
+			http.Error(w, "too many connections for this API key", http.StatusTooManyRequests)
+			return
+		}
+		// The client gives both slots back together
+		releaseAddr := releaseIP
+		releaseIP = func() {
+			releaseAddr()
+			releaseKey()
+		}
+	}
+
+	rm, err := g.joinRoom(name, key)
+	if err == errTooManyRooms {
+		releaseIP()
+		log.Printf("Client %s rejected: tenant %s at API key %s's %d rooms", clientID, key.Tenant, key.ID, key.MaxRooms)
+		http.Error(w, errTooManyRooms.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		releaseIP()
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer g.joined(rm)
+	hub := rm.hub
+
+	slowPolicy := hub.slowConsumers
+	if name := r.URL.Query().Get("slow_consumer"); name != "" {
+		policy, err := ParseSlowConsumerPolicy(name)
+		if err != nil {
+			releaseIP()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slowPolicy = policy
+	}
+
+	sendBuffer, err := requestedSendBuffer(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	multiRoom, err := requestedMultiRoom(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listener, err := requestedRole(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if claims != nil && claims.Listener {
+		listener = true
+	}
+
+	format, err := requestedFormat(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	formatMismatch, err := hub.admitFormat(format)
+	if err != nil {
+		releaseIP()
+		log.Printf("Client %s rejected from room %s: %v", clientID, name, err)
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	tags, err := requestedTags(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if claims.tagsFixed() {
+		tags = claims.Tags
+	}
+	displayName, err := requestedName(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if claims != nil && claims.Name != "" {
+		displayName = claims.Name
+	}
+	presence, err := requestedPresence(r)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	integrity, err := requestedIntegrity(r, protocol)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vox, err := requestedVOX(r, g.vox)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	receive, err := requestedReceive(r, hub.audio.Load().audioFormat)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lowTier, err := requestedTier(r, hub.audio.Load().audioFormat, receive)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lateJoinReplay, replaySpeed, err := requestedReplay(r, hub)
+	if err != nil {
+		releaseIP()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	caps := requestedCapabilities(r)
+	presence = presence && caps.has(capPresence)
+
+	if len(r.URL.Query().Get("will")) > maxWillSize {
+		releaseIP()
+		http.Error(w, fmt.Sprintf("last will larger than %d bytes", maxWillSize), http.StatusBadRequest)
+		return
+	}
+
+	client := &Client{
+		send:        make(chan outbound, hub.sendBufferSize(sendBuffer)),
+		priority:    make(chan outbound, pingBurst),
+		urgent:      make(chan outbound, maxUrgentFrames),
+		hub:         hub,
+		gateway:     g,
+		id:          clientID,
+		readDone:    make(chan struct{}),
+		writeDone:   make(chan struct{}),
+		slowPolicy:  slowPolicy,
+		remoteIP:    ip,
+		protocol:    protocol,
+		compression: g.upgrader.EnableCompression && offersCompression(r),
+		releaseIP:   releaseIP,
+		claims:      claims,
+		key:         key,
+		verifier:    verifier,
+		format:      format,
+		tags:        tags,
+		blocks:      &blockList{},
+		name:        displayName,
+		presence:    presence,
+		integrity:   integrity,
+		vox:         vox,
+		receive:     receive,
+		caps:        caps,
+	}
+	client.formatMismatch = formatMismatch
+	client.moderator.Store(moderator)
+	client.listener.Store(listener)
+	client.lowTier.Store(lowTier)
+	client.lateJoinReplay, client.replaySpeed = lateJoinReplay, replaySpeed
+	client.version.Store(int32(version))
+	client.versionPending = offered == 0
+	if multiRoom {
+		client.multiRoom = true
+		client.members = make(map[string]*Client)
+		client.fanIn = make(chan outbound)
+	}
+	client.will = r.URL.Query().Get("will")
+	client.resumeToken = r.URL.Query().Get("resume_token")
+	if client.resumeToken == "" {
+		client.resumeToken = r.Header.Get("X-Resume-Token")
+	}
+	client.touch()
+
+	// Register before upgrading so a refused client gets a plain HTTP error
+	err = hub.registerClient(client)
+	if err != nil {
+		releaseIP()
+	}
+	switch err {
+	case nil:
+	case errDuplicateID:
+		http.Error(w, "client ID already connected", http.StatusConflict)
+		return
+	case errRoomFull:
+		writeJSON(w, http.StatusConflict, roomFullError{
+			Error:    "room_full",
+			Room:     localRoom(rm.name),
+			Clients:  hub.ClientCount(),
+			Capacity: int(hub.capacity.Load()),
+		})
+		return
+	case errHubFull:
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, "server at capacity", http.StatusServiceUnavailable)
+		return
+	default:
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Tell well-behaved clients how large a frame may be so they can chunk
+	header := http.Header{}
+	header.Set("X-Max-Message-Size", strconv.FormatInt(hub.maxMessageSize, 10))
+	if client.session != nil {
+		// The hub has replaced any token the client presented
+		header.Set("X-Client-ID", client.id)
+		header.Set("X-Resume-Token", client.resumeToken)
+		header.Set("X-Session-Resumed", strconv.FormatBool(client.resumed))
+	}
+
+	bw := &batchingResponseWriter{ResponseWriter: w}
+	conn, err := g.upgrader.Upgrade(bw, r, header)
+	if err != nil {
+		// Handshake failures were logged by upgradeError
+		client.leave(reasonUpgradeFailed)
+		// writePump will never run
+		hub.pumps.Done()
+		return
+	}
+	connected = true
+	g.versions.count(offered, 1)
+	g.auditClient("connect", client, "", auditOK, "")
+	client.conn = conn
+	client.batch.Store(bw.conn)
+	if client.compression {
+		conn.SetCompressionLevel(g.compressionLevel)
+	}
+
+	// Start goroutines for reading and writing
+	go client.writePump()
+	go client.readPump()
+}
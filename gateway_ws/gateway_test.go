@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// A client's requested send buffer is honored up to the hub's maximum, and
+// one that isn't a positive number is refused
+func TestSendBufferOverride(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"default", "/ws", "", 32},
+		{"query", "/ws?send_buffer=8", "", 8},
+		{"header", "/ws", "16", 16},
+		{"query over header", "/ws?send_buffer=4", "16", 4},
+		{"at the maximum", "/ws?send_buffer=64", "", 64},
+		{"clamped", "/ws?send_buffer=1000", "", 64},
+		{"clamped header", "/ws", "1000", 64},
+		{"zero", "/ws?send_buffer=0", "", 0},
+		{"negative", "/ws", "-1", 0},
+		{"not a number", "/ws?send_buffer=deep", "", 0},
+	}
+	tg := newTestGateway(t, []HubOption{WithSendBuffer(32, 64)})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("X-Send-Buffer", tt.header)
+			}
+			conn, resp, err := tg.tryDial(tt.path, "c", header)
+			if tt.want == 0 {
+				if err == nil {
+					conn.Close()
+					t.Fatal("upgrade succeeded")
+				}
+				if resp == nil || resp.StatusCode != http.StatusBadRequest {
+					t.Fatalf("got %v, want %d", err, http.StatusBadRequest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			readControl(t, conn, "joined")
+			hub := tg.hub(t, defaultRoom)
+			if got := tg.clientStats(t, "c").SendBuffer; got != tt.want {
+				t.Errorf("send buffer %d, want %d", got, tt.want)
+			}
+			if got := cap(hub.lookup("c").send); got != tt.want {
+				t.Errorf("send channel holds %d, want %d", got, tt.want)
+			}
+			conn.Close()
+			waitFor(t, "the client to leave", func() bool { return hub.lookup("c") == nil })
+		})
+	}
+}
+
+// With tenant keys and no admin token, the management endpoints open to a
+// tenant's key alone: a request without one doesn't see every tenant
+func TestTenantKeysWithoutAdminToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`[{"id":"k1","tenant":"acme","key":"acme-key-0123456789"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadTenantKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := newTestGateway(t, nil, WithTenantKeys(keys))
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"wrong key", "X-API-Key", "wrong-key-0123456789", http.StatusUnauthorized},
+		{"key", "X-API-Key", "acme-key-0123456789", http.StatusOK},
+		{"key as bearer token", "Authorization", "Bearer acme-key-0123456789", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, tg.server.URL+"/clients", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.value)
+			}
+			resp, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET /clients: %s, want %d", resp.Status, tt.status)
+			}
+		})
+	}
+}
+
+// With compression enabled, a client that doesn't offer permessage-deflate
+// gets everything intact and uncompressed, alongside one that compresses
+func TestCompressionInterop(t *testing.T) {
+	tg := newTestGateway(t, nil, WithCompression(true, flate.BestSpeed))
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	listeners := map[string]bool{"plain": false, "deflate": true}
+	conns := make(map[string]*websocket.Conn)
+	for id, compress := range listeners {
+		dialer := websocket.Dialer{HandshakeTimeout: testTimeout, EnableCompression: compress}
+		conn, resp, err := dialer.Dial(tg.wsURL("/ws"), http.Header{"X-Client-ID": {id}})
+		if err != nil {
+			t.Fatalf("dialing as %s: %v", id, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if negotiated := strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate"); negotiated != compress {
+			t.Fatalf("%s negotiated compression %v, want %v", id, negotiated, compress)
+		}
+		readControl(t, conn, "joined")
+		conns[id] = conn
+	}
+	hub := tg.hub(t, defaultRoom)
+	waitFor(t, "everyone to join", func() bool { return hub.ClientCount() == 3 })
+	for id, compress := range listeners {
+		if got := tg.clientStats(t, id).Compression; got != compress {
+			t.Errorf("/clients reports compression=%v for %s, want %v", got, id, compress)
+		}
+	}
+	before := hub.Stats()
+
+	text := strings.Repeat("over and out ", 30)
+	if err := talker.WriteJSON(map[string]any{"type": "chat", "text": text}); err != nil {
+		t.Fatal(err)
+	}
+	audio := pcmFrame(7)
+	if err := talker.WriteMessage(websocket.BinaryMessage, audio); err != nil {
+		t.Fatal(err)
+	}
+	for id, conn := range conns {
+		// The chat and the audio, in whichever order they come
+		var gotChat, gotAudio bool
+		for !gotChat || !gotAudio {
+			messageType, data := readMessage(t, conn)
+			if messageType == websocket.BinaryMessage {
+				if !bytes.Equal(data, audio) {
+					t.Errorf("%s got %d bytes of audio, want the %d sent", id, len(data), len(audio))
+				}
+				gotAudio = true
+				continue
+			}
+			var message map[string]any
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Fatalf("%s: decoding %s: %v", id, data, err)
+			}
+			if message["type"] == "chat" {
+				if message["text"] != text {
+					t.Errorf("%s got chat %q, want %q", id, message["text"], text)
+				}
+				gotChat = true
+			}
+		}
+	}
+	// The plain listener's chat and everyone's audio go uncompressed, the
+	// compressing listener's chat deflated. Counted once written.
+	uncompressed := uint64(len(text) + 2*len(audio))
+	waitFor(t, "the writes to be counted", func() bool {
+		after := hub.Stats()
+		return after.UncompressedWireBytes-before.UncompressedWireBytes >= uncompressed &&
+			after.CompressedPayloadBytes > before.CompressedPayloadBytes
+	})
+	after := hub.Stats()
+	if compressed := after.CompressedWireBytes - before.CompressedWireBytes; compressed == 0 || compressed >= uint64(len(text)) {
+		t.Errorf("compressed chat took %d wire bytes, want fewer than its %d", compressed, len(text))
+	}
+}
@@ -2,4 +2,16 @@ module walkie-talkie-gateway
 
 go 1.21
 
-require github.com/gorilla/websocket v1.5.0
+require (
+	github.com/gorilla/websocket v1.5.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.31.0
+	google.golang.org/protobuf v1.34.2
+	gopkg.in/hraban/opus.v2 v2.0.0-20230925203106-0188a62cb302
+)
+
+require (
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
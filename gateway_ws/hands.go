@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// In a moderated briefing a participant raises its hand to ask to speak
+// without contending for the floor. The room hears of each hand raised or
+// lowered in presence messages, which carry when it was raised so a UI can
+// list hands in order, and so does the roster. A moderator grants the floor
+// to a raised hand, lowering it, or lowers every hand at once. Hands are
+// apart from the line for the floor, so a room may use both. A client's
+// hand goes with it when it leaves the room, and a client switching rooms
+// arrives with its hand lowered.
+const (
+	presenceHandRaised  = "hand_raised"
+	presenceHandLowered = "hand_lowered"
+
+	// Why the floor was revoked
+	floorHandGranted = "hand_granted"
+)
+
+// hand_raise and hand_lower raise and lower the client's hand, hand_grant
+// grants a moderator's target the floor for its raised hand and hands_clear
+// lowers every hand; on a multi-room connection they name the room
+func init() {
+	hand := controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.hub.setHand(member, m.Type == "hand_raise", time.Now())
+			}
+		},
+	}
+	registerControl("hand_raise", hand)
+	registerControl("hand_lower", hand)
+	registerControl("hand_grant", controlHandler{
+		validate: requireField("target", func(m controlMessage) bool { return m.Target != "" }),
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.grantHand(m)
+			}
+		},
+	})
+	registerControl("hands_clear", controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.clearHands(m)
+			}
+		},
+	})
+}
+
+// setHand raises or lowers the client's hand and tells the room, and reports
+// whether it changed. It is taken under the presence mutex so a hand is
+// never announced for a client whose leave was.
+func (h *Hub) setHand(client *Client, raised bool, now time.Time) bool {
+	h.presenceMutex.Lock()
+	defer h.presenceMutex.Unlock()
+	if !h.shardFor(client).has(client) {
+		return false
+	}
+	if raised {
+		if !client.hand.CompareAndSwap(0, now.UnixMilli()) {
+			return false
+		}
+		h.handsRaised.Add(1)
+		h.announcePresence(client, presenceHandRaised, closeReason{})
+		return true
+	}
+	if client.hand.Swap(0) == 0 {
+		return false
+	}
+	h.announcePresence(client, presenceHandLowered, closeReason{})
+	return true
+}
+
+// grantHand has a moderator grant its target the floor for its raised hand,
+// lowering it
+func (c *Client) grantHand(message controlMessage) {
+	if !c.moderates(message) {
+		return
+	}
+	target := c.hub.lookup(message.Target)
+	switch {
+	case target == nil:
+		c.fail("not_found", message.Type, message.Target)
+		return
+	case !c.hub.floorControl.Load():
+		c.fail(floorDisabled, message.Type, message.Target)
+		return
+	case !c.hub.setHand(target, false, time.Now()):
+		c.fail("not_raised", message.Type, message.Target)
+		return
+	}
+	c.hub.requestFloor(floorRequest{client: target, kind: message.Type, actor: c})
+	c.gateway.auditClient(message.Type, target, c.id, auditOK, "")
+}
+
+// clearHands has a moderator lower every hand in its room
+func (c *Client) clearHands(message controlMessage) {
+	if !c.moderates(message) {
+		return
+	}
+	now := time.Now()
+	lowered := 0
+	for _, client := range c.hub.snapshotClients() {
+		if client.hand.Load() != 0 && c.hub.setHand(client, false, now) {
+			lowered++
+		}
+	}
+	log.Printf("Moderator %s lowered %d hands in room %s", c.id, lowered, c.hub.room)
+	c.gateway.audit.record(AuditEvent{Event: message.Type, Room: c.hub.room, Actor: c.id, Outcome: auditOK})
+}
+
+// handFloor grants the floor to a client whose raised hand a moderator,
+// actor, granted, taking it from whoever holds it or is being offered it.
+// Only the Run goroutine may call it.
+func (h *Hub) handFloor(client, actor *Client, now time.Time) {
+	if reason := h.floorRefusal(client); reason != "" {
+		actor.fail(reason, "hand_grant", client.id)
+		return
+	}
+	returned := h.floorOffered != nil && h.floorOffered != client
+	if returned {
+		h.returnOffer(client)
+	}
+	if holder := h.floorHolder.Load(); holder != nil && holder != client {
+		h.sendControl(holder, floorGrantMessage{Type: "floor_revoked", Room: localRoom(h.room), Holder: client.id, Reason: floorHandGranted})
+	}
+	log.Printf("Moderator %s granted client %s the floor of room %s for its raised hand", actor.id, client.id, h.room)
+	h.grantFloor(client, 0, now)
+	if returned {
+		h.tellFloorPositions(0)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// loopHeartbeat is how often Run records that it is servicing its
+	// channels
+	loopHeartbeat = time.Second
+
+	// defaultStallThreshold is how long the hub loop may go without a
+	// heartbeat before it is considered wedged
+	defaultStallThreshold = 5 * time.Second
+)
+
+// healthStatus is the body of /livez and /readyz
+type healthStatus struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// WithStallThreshold sets how long the hub loop may go unresponsive before
+// the gateway stops reporting ready
+func WithStallThreshold(d time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		if d > 0 {
+			g.stallThreshold = d
+		}
+	}
+}
+
+// Drain marks the gateway as going away: /readyz fails and new upgrades are
+// refused, while connected clients are left alone
+func (g *Gateway) Drain() {
+	g.draining.Store(true)
+}
+
+// stopping reports whether Stop has been called
+func (h *Hub) stopping() bool {
+	select {
+	case <-h.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// stalledFor returns how long ago Run last recorded a heartbeat, or false if
+// it hasn't started
+func (h *Hub) stalledFor(now time.Time) (time.Duration, bool) {
+	last := h.lastLoop.Load()
+	if last == 0 {
+		return 0, false
+	}
+	return now.Sub(time.Unix(0, last)), true
+}
+
+// hubUnresponsive returns why a room's hub loop is not keeping up, or ""
+func (g *Gateway) hubUnresponsive() string {
+	now := time.Now()
+	reason := ""
+	g.eachRoom(func(rm *room) {
+		// A room that was only just opened may not have started yet
+		stalled, started := rm.hub.stalledFor(now)
+		if reason == "" && started && stalled > g.stallThreshold && !rm.hub.stopping() {
+			reason = "room " + rm.name + " hub loop unresponsive for " + stalled.Round(time.Second).String()
+		}
+	})
+	return reason
+}
+
+// stopping reports whether Stop has been called
+func (g *Gateway) stopping() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stopped
+}
+
+// notReady returns why the gateway should not receive new clients, or ""
+func (g *Gateway) notReady() string {
+	switch {
+	case g.stopping():
+		return "shutting down"
+	case g.draining.Load():
+		return "draining"
+	}
+	return g.hubUnresponsive()
+}
+
+// serveLivez reports whether the process is up and its hub loop responsive.
+// It stays healthy through a drain or shutdown, when the loop is expected
+// to stop.
+func (g *Gateway) serveLivez(w http.ResponseWriter, r *http.Request) {
+	if !g.stopping() {
+		if reason := g.hubUnresponsive(); reason != "" {
+			writeJSON(w, http.StatusServiceUnavailable, healthStatus{Status: "unhealthy", Reason: reason})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// serveReadyz reports whether the gateway is accepting new WebSocket upgrades
+func (g *Gateway) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	if reason := g.notReady(); reason != "" {
+		writeJSON(w, http.StatusServiceUnavailable, healthStatus{Status: "unavailable", Reason: reason})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ok"})
+}
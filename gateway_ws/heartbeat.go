@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultHeartbeatMisses is how many consecutive heartbeats a client may
+// leave unanswered before it is closed
+const defaultHeartbeatMisses = 3
+
+// heartbeatMessage is the application-level heartbeat. The server sends it
+// with its clock in ts (Unix milliseconds) and the client echoes it back
+// unchanged, which gives both sides a round trip time and the client a
+// clock skew estimate.
+type heartbeatMessage struct {
+	Type string `json:"type"`
+	Seq  uint64 `json:"seq"`
+	TS   int64  `json:"ts"`
+}
+
+// WithHeartbeat sends every client an application-level heartbeat each
+// interval, alongside protocol pings, and closes clients that leave misses
+// consecutive heartbeats unanswered. A zero interval disables it.
+func WithHeartbeat(interval time.Duration, misses int) HubOption {
+	return func(h *Hub) {
+		h.heartbeatInterval = interval
+		if misses > 0 {
+			h.heartbeatMisses = misses
+		}
+	}
+}
+
+// sendHeartbeat is called by writePump every heartbeat interval. It closes
+// the client once too many heartbeats in a row went unanswered, and
+// otherwise writes the next one.
+func (c *Client) sendHeartbeat() error {
+	if last := c.heartbeatSent.Load(); last > c.heartbeatAcked.Load() {
+		c.heartbeatMissed++
+		if c.heartbeatMissed >= c.hub.heartbeatMisses {
+			log.Printf("Client %s missed %d heartbeats", c.id, c.heartbeatMissed)
+			c.leave(reasonHeartbeatTimeout)
+			return nil
+		}
+	} else {
+		c.heartbeatMissed = 0
+	}
+
+	now := time.Now()
+	seq := c.heartbeatSent.Load() + 1
+	data, err := json.Marshal(heartbeatMessage{Type: "hb", Seq: seq, TS: now.UnixMilli()})
+	if err != nil {
+		return err
+	}
+	c.heartbeatSentAt.Store(now.UnixNano())
+	c.heartbeatSent.Store(seq)
+
+	c.conn.SetWriteDeadline(c.writeDeadline())
+	return c.writeCounted(outbound{messageType: websocket.TextMessage, data: data})
+}
+
+// hb echoes a heartbeat
+func init() {
+	registerControl("hb", controlHandler{
+		validate: requireField("seq", func(m controlMessage) bool { return m.Seq != 0 }),
+		handle:   func(c *Client, m controlMessage) { c.heartbeatEcho(m.Seq) },
+	})
+}
+
+// heartbeatEcho records the client's echo of heartbeat seq. Only an echo of
+// the latest heartbeat counts, so a late echo can't mask a string of misses.
+func (c *Client) heartbeatEcho(seq uint64) {
+	if seq == 0 || seq != c.heartbeatSent.Load() {
+		return
+	}
+	rtt := time.Since(time.Unix(0, c.heartbeatSentAt.Load()))
+	c.heartbeatRTT.Store(int64(rtt))
+	c.heartbeatAcked.Store(seq)
+	if c.hub.idleCountsPongs {
+		c.touch()
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testTimeout bounds every wait in the tests, so a regression fails rather
+// than hangs
+const testTimeout = 5 * time.Second
+
+// stallBuffer is the socket buffer size of stalling connections
+const stallBuffer = 4096
+
+// TestMain keeps the gateway's logging out of the test output unless -v
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// testGateway is a gateway served over httptest with the routes main sets
+// up, stopped when the test ends
+type testGateway struct {
+	*Gateway
+	server *httptest.Server
+}
+
+func newTestGateway(t testing.TB, hubOpts []HubOption, opts ...GatewayOption) *testGateway {
+	t.Helper()
+	return serveTestGateway(t, NewGateway(hubOpts, opts...), false)
+}
+
+// newStallingGateway is newTestGateway with small socket send buffers, so
+// the writes to a client that stops reading back up after its receive
+// buffer fills rather than the megabytes loopback would otherwise take
+func newStallingGateway(t testing.TB, hubOpts []HubOption, opts ...GatewayOption) *testGateway {
+	t.Helper()
+	return serveTestGateway(t, NewGateway(hubOpts, opts...), true)
+}
+
+func serveTestGateway(t testing.TB, g *Gateway, stall bool) *testGateway {
+	t.Helper()
+	server := httptest.NewUnstartedServer(testMux(g))
+	if stall {
+		server.Listener = stallingListener{server.Listener}
+	}
+	server.Start()
+	return stopWithTest(t, g, server)
+}
+
+// testMux routes requests to the gateway as main does
+func testMux(g *Gateway) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", g.serveWS)
+	mux.HandleFunc("/ws/", g.serveWS)
+	mux.HandleFunc("/stats", g.requireAdmin(g.serveStats))
+	mux.HandleFunc("/clients", g.requireAdmin(g.serveClients))
+	mux.HandleFunc("/rooms", g.requireAdmin(g.serveRooms))
+	mux.HandleFunc("/rooms/", g.requireAdmin(g.serveRoom))
+	mux.HandleFunc("/announce", g.requireAdmin(g.serveAnnounce))
+	mux.HandleFunc("/recordings", g.requireAdmin(g.serveRecordings))
+	mux.HandleFunc("/recordings/", g.requireAdmin(g.serveRecordings))
+	return mux
+}
+
+// stopWithTest stops the gateway and closes its started server when the
+// test ends
+func stopWithTest(t testing.TB, g *Gateway, server *httptest.Server) *testGateway {
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		g.Stop(ctx)
+		server.Close()
+	})
+	return &testGateway{Gateway: g, server: server}
+}
+
+// stallingListener shrinks the send buffer of the connections it accepts
+type stallingListener struct {
+	net.Listener
+}
+
+func (l stallingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetWriteBuffer(stallBuffer)
+	}
+	return conn, err
+}
+
+// wsURL returns the websocket URL of path on the test server
+func (tg *testGateway) wsURL(path string) string {
+	return "ws" + strings.TrimPrefix(tg.server.URL, "http") + path
+}
+
+// dial connects to path as the client id, failing the test if the upgrade
+// fails. The connection is closed when the test ends.
+func (tg *testGateway) dial(t testing.TB, path, id string) (*websocket.Conn, *http.Response) {
+	t.Helper()
+	conn, resp, err := tg.tryDial(path, id, nil)
+	if err != nil {
+		t.Fatalf("dialing %s as %s: %v", path, id, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, resp
+}
+
+// tryDial connects to path as the client id with the extra headers, if any
+func (tg *testGateway) tryDial(path, id string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+	if id != "" {
+		header.Set("X-Client-ID", id)
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: testTimeout}
+	return dialer.Dial(tg.wsURL(path), header)
+}
+
+// hub returns the hub of the named open room
+func (tg *testGateway) hub(t testing.TB, name string) *Hub {
+	t.Helper()
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+	rm := tg.rooms[name]
+	if rm == nil {
+		t.Fatalf("room %s is not open", name)
+	}
+	return rm.hub
+}
+
+// getJSON decodes the JSON the test server answers path with into v
+func (tg *testGateway) getJSON(t testing.TB, path string, v any) {
+	t.Helper()
+	resp, err := http.Get(tg.server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("GET %s: %s: %s", path, resp.Status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding %s: %v", path, err)
+	}
+}
+
+// clientStats returns the /clients entry of the client id, or fails the
+// test if it isn't connected
+func (tg *testGateway) clientStats(t testing.TB, id string) ClientStats {
+	t.Helper()
+	var clients []ClientStats
+	tg.getJSON(t, "/clients", &clients)
+	for _, stats := range clients {
+		if stats.ID == id {
+			return stats
+		}
+	}
+	t.Fatalf("client %s not in /clients", id)
+	return ClientStats{}
+}
+
+// readMessage reads the connection's next message within testTimeout
+func readMessage(t testing.TB, conn *websocket.Conn) (int, []byte) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return messageType, data
+}
+
+// readControl reads messages until a text message of the given type, which
+// it returns decoded, skipping others
+func readControl(t testing.TB, conn *websocket.Conn, typ string) map[string]any {
+	t.Helper()
+	for {
+		messageType, data := readMessage(t, conn)
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var message map[string]any
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("decoding %s: %v", data, err)
+		}
+		if message["type"] == typ {
+			return message
+		}
+	}
+}
+
+// readAudio reads messages until a binary one, which it returns, skipping
+// control messages
+func readAudio(t testing.TB, conn *websocket.Conn) []byte {
+	t.Helper()
+	for {
+		messageType, data := readMessage(t, conn)
+		if messageType == websocket.BinaryMessage {
+			return data
+		}
+	}
+}
+
+// expectClosed reads until the connection fails and returns its close code,
+// -1 if it ended without a close frame
+func expectClosed(t testing.TB, conn *websocket.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				return closeErr.Code
+			}
+			if isTimeout(err) {
+				t.Fatalf("connection still open after %v", testTimeout)
+			}
+			return -1
+		}
+	}
+}
+
+// waitFor polls cond until it holds, failing the test with what if it
+// doesn't within testTimeout
+func waitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// testHub is a running hub with in-memory clients attached, for tests and
+// benchmarks of the fan-out that don't need sockets
+type testHub struct {
+	*Hub
+	clients []*Client
+
+	// Frames the clients took off their send buffers, and the goroutines
+	// taking them, which end once the hub closes the buffers
+	received atomic.Int64
+	draining sync.WaitGroup
+}
+
+// newTestHub starts a hub with the options, stopped when the test ends
+func newTestHub(t testing.TB, opts ...HubOption) *testHub {
+	t.Helper()
+	h := NewHub(opts...)
+	go h.Run()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+		h.Stop(ctx)
+	})
+	return &testHub{Hub: h}
+}
+
+// attach adds n clients to the hub's shards, bypassing registration, each
+// with a goroutine that empties its send buffer the way a writePump that
+// writes instantly would. The hub closes their buffers when it stops.
+func (th *testHub) attach(n int, policy SlowConsumerPolicy) {
+	th.attachSlow(n, policy, 0)
+}
+
+// attachSlow is attach for clients that take pause over each message
+func (th *testHub) attachSlow(n int, policy SlowConsumerPolicy, pause time.Duration) {
+	for i := 0; i < n; i++ {
+		client := th.newClient(fmt.Sprintf("listener%d", len(th.clients)), policy, pause)
+		client.seq = uint64(len(th.clients) + 1)
+		th.clients = append(th.clients, client)
+		th.shardFor(client).add(client)
+	}
+}
+
+// newClient returns an in-memory client of the hub whose send buffer a
+// goroutine empties, taking pause over each message. It belongs to a bare
+// gateway, with no audit log, so the hub may register it.
+func (th *testHub) newClient(id string, policy SlowConsumerPolicy, pause time.Duration) *Client {
+	client := &Client{
+		id:         id,
+		hub:        th.Hub,
+		gateway:    &Gateway{},
+		send:       make(chan outbound, th.sendBuffer),
+		slowPolicy: policy,
+	}
+	th.draining.Add(1)
+	go func() {
+		defer th.draining.Done()
+		for message := range client.send {
+			time.Sleep(pause)
+			message.release()
+			th.received.Add(1)
+		}
+	}()
+	return client
+}
+
+// handled returns how many frames the clients took or dropped
+func (th *testHub) handled() int64 {
+	n := th.received.Load()
+	for _, client := range th.clients {
+		n += int64(client.droppedOutbound.Load())
+	}
+	return n
+}
+
+// broadcast submits a pooled copy of payload from sender
+func (th *testHub) broadcast(sender *Client, payload []byte) {
+	frame := getFrame()
+	frame.data = append(frame.data[:0], payload...)
+	th.submit(BroadcastMessage{frame: frame, sender: sender})
+}
+
+// discardConn is a connection that takes every write and never reads
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Read(p []byte) (int, error)       { return 0, io.EOF }
+func (discardConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (discardConn) Close() error                     { return nil }
+func (discardConn) SetDeadline(time.Time) error      { return nil }
+func (discardConn) SetReadDeadline(time.Time) error  { return nil }
+func (discardConn) SetWriteDeadline(time.Time) error { return nil }
+
+// hijackableRecorder hands the Upgrader a discardConn to hijack
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (w hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn := discardConn{}
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+// discardWS returns a server side websocket connection whose writes go
+// nowhere, through a batchConn as serveWS sets one up, for benchmarks of
+// writing to many listeners without sockets. With compress, it negotiates
+// permessage-deflate and compresses what it writes.
+func discardWS(t testing.TB, compress bool) (*websocket.Conn, *batchConn) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if compress {
+		r.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
+	}
+	w := &batchingResponseWriter{ResponseWriter: hijackableRecorder{httptest.NewRecorder()}}
+	upgrader := websocket.Upgrader{EnableCompression: compress}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		t.Fatalf("upgrading: %v", err)
+	}
+	conn.EnableWriteCompression(compress)
+	return conn, w.conn
+}
+
+// pcmFrame returns a 20ms frame of 16kHz mono pcm16 audio filled with b
+func pcmFrame(b byte) []byte {
+	frame := make([]byte, 640)
+	for i := range frame {
+		frame[i] = b
+	}
+	return frame
+}
@@ -0,0 +1,1642 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BroadcastPolicy controls what a reader does when the hub's broadcast queue
+// is full
+type BroadcastPolicy int
+
+const (
+	// BroadcastBlock makes the reader wait until the hub has room (default)
+	BroadcastBlock BroadcastPolicy = iota
+	// BroadcastDrop discards the frame and counts it as dropped
+	BroadcastDrop
+)
+
+const defaultBroadcastQueue = 256
+
+// Keepalive defaults
+const (
+	defaultPongWait   = 60 * time.Second
+	defaultPingPeriod = defaultPongWait * 9 / 10
+
+	// Time allowed for any single write to the peer
+	defaultWriteWait = 10 * time.Second
+)
+
+// defaultMaxMessageSize comfortably fits 20-60ms audio frames
+const defaultMaxMessageSize = 64 * 1024
+
+// Per-client send buffer sizes, in messages
+const (
+	defaultSendBuffer    = 256
+	defaultMaxSendBuffer = 4096
+)
+
+// DuplicateIDPolicy controls what happens when a client registers with an ID
+// that is already connected
+type DuplicateIDPolicy int
+
+const (
+	// DuplicateAllow keeps both connections (default)
+	DuplicateAllow DuplicateIDPolicy = iota
+	// DuplicateTakeover closes the existing connection and admits the new one
+	DuplicateTakeover
+	// DuplicateReject refuses the new connection
+	DuplicateReject
+	// DuplicateRename admits the new connection under a suffixed ID
+	DuplicateRename
+)
+
+// ParseDuplicateIDPolicy converts a policy name as used on the command line
+func ParseDuplicateIDPolicy(name string) (DuplicateIDPolicy, error) {
+	switch name {
+	case "allow":
+		return DuplicateAllow, nil
+	case "takeover":
+		return DuplicateTakeover, nil
+	case "reject":
+		return DuplicateReject, nil
+	case "rename":
+		return DuplicateRename, nil
+	}
+	return 0, fmt.Errorf("unknown duplicate ID policy %q", name)
+}
+
+// SlowConsumerPolicy controls what happens when a listener's send buffer is
+// full
+type SlowConsumerPolicy int
+
+const (
+	// SlowDisconnect evicts the listener (default)
+	SlowDisconnect SlowConsumerPolicy = iota
+	// SlowDropNewest skips the new frame for that listener
+	SlowDropNewest
+	// SlowDropOldest discards the oldest queued frame to make room for the
+	// new one, keeping the listener roughly live
+	SlowDropOldest
+)
+
+// String returns the policy name accepted by ParseSlowConsumerPolicy
+func (p SlowConsumerPolicy) String() string {
+	switch p {
+	case SlowDropNewest:
+		return "drop-newest"
+	case SlowDropOldest:
+		return "drop-oldest"
+	}
+	return "disconnect"
+}
+
+// ParseSlowConsumerPolicy converts a policy name as used on the command line
+// and in the slow_consumer query parameter
+func ParseSlowConsumerPolicy(name string) (SlowConsumerPolicy, error) {
+	switch name {
+	case "disconnect":
+		return SlowDisconnect, nil
+	case "drop-newest":
+		return SlowDropNewest, nil
+	case "drop-oldest":
+		return SlowDropOldest, nil
+	}
+	return 0, fmt.Errorf("unknown slow consumer policy %q", name)
+}
+
+// Registration errors
+var (
+	errHubStopped  = errors.New("hub stopped")
+	errDuplicateID = errors.New("client ID already connected")
+	errHubFull     = errors.New("hub at capacity")
+)
+
+// unregistration is a request to remove a client, with the close frame to
+// send it
+type unregistration struct {
+	client *Client
+	reason closeReason
+}
+
+// registration is a request to add a client, answered by the Run goroutine
+type registration struct {
+	client *Client
+	result chan error
+}
+
+// Hub maintains the set of active clients and broadcasts messages to them
+type Hub struct {
+	// Registered clients, partitioned by client sequence number
+	shards []*shard
+
+	// Sequence number for the next registered client
+	nextSeq uint64
+
+	// Sequence number of the last broadcast. Only Run touches it.
+	broadcastSeq uint64
+
+	// Resumable sessions; nil when resumption is disabled
+	sessions *sessionStore
+
+	// Recent audio for late joiners; nil when late-join replay is disabled
+	recent *recentAudio
+
+	// Registered clients by ID. With DuplicateAllow this holds the most
+	// recent client for each ID.
+	byID map[string]*Client
+
+	// Inbound messages from the clients
+	broadcast chan BroadcastMessage
+
+	// Register requests from the clients
+	register chan registration
+
+	// Unregister requests from clients
+	unregister chan unregistration
+
+	// Closed by Stop to ask Run to shut down
+	quit     chan struct{}
+	stopOnce sync.Once
+
+	// Closed when Run has returned
+	done chan struct{}
+
+	// Unix nanoseconds of Run's last heartbeat, zero until it starts
+	lastLoop atomic.Int64
+
+	// Running writePumps. Added to by Run when a client registers, so every
+	// Add happens before done is closed.
+	pumps sync.WaitGroup
+
+	// Guards byID. Only the Run goroutine writes the map; other goroutines
+	// may take the read lock to inspect it.
+	mutex sync.RWMutex
+
+	// Serializes the joins and leaves of the hub's clients with the presence
+	// messages announcing them, and counts them for the roster
+	presenceMutex sync.Mutex
+	rosterVersion uint64
+
+	// Registered clients by tag, and the tags of every client of the hub
+	tagged    map[string]map[*Client]bool
+	tagsMutex sync.RWMutex
+
+	// Options
+	room              string
+	emptyHook         func()
+	broadcastQueue    int
+	broadcastPolicy   BroadcastPolicy
+	messageHook       MessageHook
+	duplicateIDs      DuplicateIDPolicy
+	slowConsumers     SlowConsumerPolicy
+	workers           int
+	pingPeriod        time.Duration
+	pongWait          time.Duration
+	writeWait         time.Duration
+	maxMessageSize    int64
+	idleTimeout       time.Duration
+	idleCountsPongs   bool
+	maxClients        int
+	warnClients       int
+	muteWindow        time.Duration
+	listenerStrikes   int
+	maxAge            time.Duration
+	maxAgeJitter      float64
+	heartbeatInterval time.Duration
+	heartbeatMisses   int
+	qualityInterval   time.Duration
+	sendBuffer        int
+	maxSendBuffer     int
+
+	// Broadcast fan-out, started by Run
+	fanOutWorkers []*fanOutWorker
+
+	// Frames discarded because the broadcast queue was full
+	droppedFrames atomic.Uint64
+
+	// Bytes written with and without compression
+	compression compressionStats
+
+	// Broadcasts of the last minute, for the room listing
+	activity activityWindow
+
+	// Time Run spends on each thing it services, and time broadcasts wait
+	// in its queue. Only Run records them.
+	loopLatency latencyGauge
+	queueWait   latencyGauge
+
+	// The room's recorder while it is being recorded, and what recording
+	// has done. recordMutex serializes starting and stopping it, and
+	// recordingDone is set once Run has stopped it for good.
+	recorder      atomic.Pointer[recorder]
+	recordMutex   sync.Mutex
+	recordingDone bool
+	recording     recordStats
+
+	// Muted client IDs and when their mutes lapse, zero while the client is
+	// connected
+	muteMutex sync.Mutex
+	mutes     map[string]time.Time
+
+	// The room's participant cap, 0 for none. The room admin API may change
+	// it while the hub runs.
+	capacity atomic.Int64
+
+	// Audio each client may send into the room, nil for no limit. The room
+	// admin API may change it while the hub runs.
+	rateLimit atomic.Pointer[RateLimit]
+
+	// Frames dropped for going over the rate limit, and clients closed for
+	// it
+	rateLimited          atomic.Uint64
+	rateLimitDisconnects atomic.Uint64
+
+	// How long each client may talk without a break, nil for no limit, and
+	// the bursts cut off for going on longer. The room admin API may change
+	// the limit while the hub runs.
+	talkLimit   atomic.Pointer[talkLimit]
+	talkCutoffs atomic.Uint64
+
+	// The audio format the room advertises and the ones clients may
+	// declare. The room admin API may change it while the hub runs.
+	audio atomic.Pointer[AudioPolicy]
+
+	// Audio frames dropped because listeners sent them, and listeners
+	// closed for it
+	listenerDropped     atomic.Uint64
+	listenerDisconnects atomic.Uint64
+
+	// Audio frames dropped because muted clients sent them
+	mutedDropped atomic.Uint64
+
+	// Audio frames dropped for failing verification, and clients closed for
+	// it
+	frameAuthFailed      atomic.Uint64
+	frameAuthDisconnects atomic.Uint64
+
+	// Audio frames dropped as invalid, by why, and clients closed for
+	// sending them
+	invalidFrames           invalidFrames
+	invalidFrameDisconnects atomic.Uint64
+
+	// Key exchange messages relayed, and refused
+	keyExchanges        atomic.Uint64
+	keyExchangesRefused atomic.Uint64
+
+	// Chat messages relayed and refused, and the last chatHistory relayed,
+	// oldest first
+	chatMessages atomic.Uint64
+	chatRefused  atomic.Uint64
+	chatHistory  int
+	chatMutex    sync.Mutex
+	chatLog      []chatMessage
+
+	// Audio frames and chat messages whispered, and whispers refused
+	whispers        atomic.Uint64
+	whispersRefused atomic.Uint64
+
+	// Audio frames and chat messages addressed to tags
+	tagAddressed atomic.Uint64
+
+	// Joins, leaves and hands raised or lowered announced
+	presenceEvents atomic.Uint64
+
+	// Hands raised
+	handsRaised atomic.Uint64
+
+	// The gap in a client's audio that ends its burst, the clients talking
+	// by when their bursts started, and the bursts announced
+	talkSilence  time.Duration
+	talkersMutex sync.Mutex
+	talkers      map[*Client]time.Time
+	talkBursts   atomic.Uint64
+
+	// Clients with the levels capability, and the level messages sent
+	meterListeners atomic.Int32
+	levelMessages  atomic.Uint64
+
+	// End of transmission markers sent, and those for bursts cut off by
+	// their sender leaving
+	eotMarkers atomic.Uint64
+	eotAborted atomic.Uint64
+
+	// Audio frames VOX gates dropped for falling outside a burst
+	voxDropped atomic.Uint64
+
+	// What audio is dropped as silence, nil for nothing, and the frames
+	// dropped. The room admin API may change it while the hub runs.
+	silence           atomic.Pointer[silenceSuppression]
+	silenceSuppressed atomic.Uint64
+
+	// Whether the room mixes overlapping talkers, and its mixer, nil before
+	// it has audio to mix. Only Run touches mixer. Then the frames relayed
+	// untouched and mixed, talkers' audio running short of a tick or too
+	// far ahead, frames dropped for a full mixer, and the ticks mixed and
+	// the time they took in nanoseconds.
+	mixing         atomic.Bool
+	mixer          *mixer
+	mixPassthrough atomic.Uint64
+	mixedFrames    atomic.Uint64
+	mixUnderruns   atomic.Uint64
+	mixOverruns    atomic.Uint64
+	mixDropped     atomic.Uint64
+	mixTicks       atomic.Uint64
+	mixNanos       atomic.Uint64
+
+	// Whether the room paces its senders' audio, how many frames a sender
+	// may have waiting, and the frames held back and dropped for a full
+	// pace queue
+	pacing      atomic.Bool
+	paceQueue   int
+	pacedFrames atomic.Uint64
+	paceDropped atomic.Uint64
+
+	// The clients receiving audio in another codec or at the low tier, by
+	// rendition, the renditions the room's audio is encoded to for them,
+	// nil for none, the bitrate it is encoded at, and the frames encoded,
+	// downsampled and that failed to
+	receiversMutex   sync.Mutex
+	receivers        map[string]int
+	receiveCodecs    atomic.Pointer[[]string]
+	transcodeBitrate int
+	transcoded       atomic.Uint64
+	downsampled      atomic.Uint64
+	transcodeFailed  atomic.Uint64
+
+	// Priority audio frames relayed, and marks stripped
+	priorityFrames   atomic.Uint64
+	priorityStripped atomic.Uint64
+
+	// Audio frames dropped for failing their CRC32C
+	corruptFrames atomic.Uint64
+
+	// Floor control: whether the room is under it, who holds the floor,
+	// requests for the Run goroutine, and the timer that frees the floor
+	// once its holder has sent nothing for floorTimeout since floorLast.
+	// Only Run writes floorHolder or touches floorLast and floorTimer.
+	floorControl  atomic.Bool
+	floorHolder   atomic.Pointer[Client]
+	floorRequests chan floorRequest
+	floorTimeout  time.Duration
+	floorTimer    *time.Timer
+	floorLast     time.Time
+
+	// The line for the floor and how long it may grow, and who the floor
+	// was offered to at floorOfferedAt and at what priority, for
+	// floorOfferWindow on floorTimer. Only Run touches the line and the
+	// offer.
+	floorQueue           []floorWaiter
+	floorQueueLimit      atomic.Int32
+	floorOffered         *Client
+	floorOfferedPriority int
+	floorOfferedAt       time.Time
+	floorOfferWindow     time.Duration
+
+	// The priority the floor is held at and since when, and how long it is
+	// held before someone outranking the holder may take it. Only Run
+	// touches them.
+	floorPriority  int
+	floorGrantedAt time.Time
+	floorMinHold   time.Duration
+
+	// Whether a moderator locked the floor. Only Run changes it.
+	floorLock atomic.Bool
+
+	// Whether a holder whose connection is lost keeps the floor for its
+	// session to resume, and whether the holder is gone doing so. Only Run
+	// touches floorDeparted.
+	floorResume   bool
+	floorDeparted bool
+
+	// Floor grants and denials, clients put in line, offers not taken in
+	// time, holders preempted and holders a moderator revoked, and audio
+	// frames dropped from clients not holding the floor
+	floorGrants        atomic.Uint64
+	floorDenials       atomic.Uint64
+	floorQueued        atomic.Uint64
+	floorOffersExpired atomic.Uint64
+	floorPreemptions   atomic.Uint64
+	floorRevocations   atomic.Uint64
+	floorDropped       atomic.Uint64
+
+	// Control messages that didn't parse, had an unknown type or failed
+	// validation
+	invalidControl atomic.Uint64
+
+	// Messages sent asking to be acknowledged, sent again for want of an
+	// acknowledgement, and never acknowledged
+	acksRequested atomic.Uint64
+	ackRetries    atomic.Uint64
+	ackFailures   atomic.Uint64
+
+	// Registrations refused because the hub was at capacity, or the room
+	// at its participant cap
+	rejectedFull     atomic.Uint64
+	rejectedRoomFull atomic.Uint64
+
+	// Whether the soft client limit warning has been logged since the count
+	// last dropped below it. Only Run touches it.
+	warnedClients bool
+}
+
+// BroadcastMessage contains the message and the sender client. Whoever holds
+// a BroadcastMessage owns one reference on its frame.
+type BroadcastMessage struct {
+	frame  *frameBuffer
+	sender *Client
+
+	// Assigned by Run so a resumed client can skip frames it was replayed
+	seq uint64
+
+	// When the message was queued for the hub, for latency stats
+	queued time.Time
+
+	// Marks the end of the sender's burst
+	eot bool
+
+	// Whether the room's mixer sent the message, and for a tick of mixed
+	// audio, which has no sender, the frame each talker in it gets
+	// instead, nil for none
+	mixed bool
+	mix   map[*Client]*frameBuffer
+}
+
+// outbound returns the message as queued for each listener. It does not take
+// a reference.
+func (m BroadcastMessage) outbound() outbound {
+	return outbound{messageType: m.frame.messageType, frame: m.frame, eot: m.eot}
+}
+
+// retain adds a reference to the message's frames
+func (m BroadcastMessage) retain() {
+	m.frame.retain()
+	for _, frame := range m.mix {
+		if frame != nil {
+			frame.retain()
+		}
+	}
+}
+
+// release drops a reference to the message's frames
+func (m BroadcastMessage) release() {
+	m.frame.release()
+	for _, frame := range m.mix {
+		if frame != nil {
+			frame.release()
+		}
+	}
+}
+
+// MessageHook is called from the sender's readPump for every inbound message
+// before it is broadcast
+type MessageHook func(client *Client, data []byte)
+
+// HubOption configures optional Hub behaviour
+type HubOption func(*Hub)
+
+// WithBroadcastQueue sets the depth of the inbound broadcast queue
+func WithBroadcastQueue(depth int) HubOption {
+	return func(h *Hub) {
+		if depth >= 0 {
+			h.broadcastQueue = depth
+		}
+	}
+}
+
+// WithBroadcastPolicy selects whether readers block or drop frames when the
+// broadcast queue is full
+func WithBroadcastPolicy(policy BroadcastPolicy) HubOption {
+	return func(h *Hub) {
+		h.broadcastPolicy = policy
+	}
+}
+
+// WithMessageHook installs a hook that observes every inbound message
+func WithMessageHook(hook MessageHook) HubOption {
+	return func(h *Hub) {
+		h.messageHook = hook
+	}
+}
+
+// WithDuplicateIDPolicy selects how a registration with an already connected
+// client ID is handled
+func WithDuplicateIDPolicy(policy DuplicateIDPolicy) HubOption {
+	return func(h *Hub) {
+		h.duplicateIDs = policy
+	}
+}
+
+// WithSlowConsumerPolicy sets the default policy for listeners whose send
+// buffer is full. Clients may override it at registration.
+func WithSlowConsumerPolicy(policy SlowConsumerPolicy) HubOption {
+	return func(h *Hub) {
+		h.slowConsumers = policy
+	}
+}
+
+// WithShards partitions the client registry into n independently locked
+// shards
+func WithShards(n int) HubOption {
+	return func(h *Hub) {
+		if n > 0 {
+			h.shards = make([]*shard, n)
+		}
+	}
+}
+
+// WithWorkers sets how many goroutines fan broadcasts out. Each worker owns a
+// disjoint set of shards, so there is no point in more workers than shards.
+func WithWorkers(n int) HubOption {
+	return func(h *Hub) {
+		h.workers = n
+	}
+}
+
+// WithSendBuffer sets the default per-client send buffer size and the largest
+// size a client may request at registration
+func WithSendBuffer(size, max int) HubOption {
+	return func(h *Hub) {
+		if size > 0 {
+			h.sendBuffer = size
+		}
+		if max > 0 {
+			h.maxSendBuffer = max
+		}
+	}
+}
+
+// sendBufferSize clamps a requested send buffer size to the hub's limits. A
+// request of zero selects the default.
+func (h *Hub) sendBufferSize(requested int) int {
+	switch {
+	case requested <= 0:
+		requested = h.sendBuffer
+	case requested > h.maxSendBuffer:
+		requested = h.maxSendBuffer
+	}
+	return requested
+}
+
+// WithKeepalive sets how often clients are pinged and how long the server
+// waits for a pong (or any other read) before evicting the client.
+// pingPeriod must be shorter than pongWait.
+func WithKeepalive(pingPeriod, pongWait time.Duration) HubOption {
+	return func(h *Hub) {
+		if pingPeriod > 0 && pongWait > pingPeriod {
+			h.pingPeriod = pingPeriod
+			h.pongWait = pongWait
+		}
+	}
+}
+
+// WithWriteWait bounds how long a single write to a client may block before
+// the client is disconnected
+func WithWriteWait(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.writeWait = d
+		}
+	}
+}
+
+// WithMaxMessageSize sets the largest inbound message a client may send.
+// Clients that exceed it are closed with 1009 (message too big).
+func WithMaxMessageSize(n int64) HubOption {
+	return func(h *Hub) {
+		if n > 0 {
+			h.maxMessageSize = n
+		}
+	}
+}
+
+// WithIdleTimeout closes clients that have neither sent a frame nor had one
+// written to them for longer than timeout. Pongs only count as activity if
+// countPongs is set, so parked connections that merely answer pings still
+// expire by default.
+func WithIdleTimeout(timeout time.Duration, countPongs bool) HubOption {
+	return func(h *Hub) {
+		h.idleTimeout = timeout
+		h.idleCountsPongs = countPongs
+	}
+}
+
+// WithMaxClients caps the number of registered clients. Registrations beyond
+// max are refused; once the count reaches warnFraction of max, a capacity
+// warning is logged. A max of zero means no limit.
+func WithMaxClients(max int, warnFraction float64) HubOption {
+	return func(h *Hub) {
+		h.maxClients = max
+		if max > 0 && warnFraction > 0 && warnFraction < 1 {
+			h.warnClients = int(float64(max) * warnFraction)
+		}
+	}
+}
+
+// WithResume lets a client that drops reconnect within window and pick up
+// its session, getting the frames it missed replayed from a buffer capped at
+// maxFrames and maxBytes. A window of zero disables resumption.
+func WithResume(window time.Duration, maxFrames, maxBytes int) HubOption {
+	return func(h *Hub) {
+		if window <= 0 {
+			h.sessions = nil
+			return
+		}
+		if maxFrames <= 0 {
+			maxFrames = defaultReplayFrames
+		}
+		if maxBytes <= 0 {
+			maxBytes = defaultReplayBytes
+		}
+		h.sessions = newSessionStore(window, maxFrames, maxBytes)
+	}
+}
+
+// NewHub creates a new Hub instance
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		register:         make(chan registration),
+		unregister:       make(chan unregistration),
+		shards:           make([]*shard, defaultShards),
+		byID:             make(map[string]*Client),
+		tagged:           make(map[string]map[*Client]bool),
+		quit:             make(chan struct{}),
+		done:             make(chan struct{}),
+		broadcastQueue:   defaultBroadcastQueue,
+		workers:          defaultWorkers,
+		pingPeriod:       defaultPingPeriod,
+		pongWait:         defaultPongWait,
+		writeWait:        defaultWriteWait,
+		maxMessageSize:   defaultMaxMessageSize,
+		sendBuffer:       defaultSendBuffer,
+		maxSendBuffer:    defaultMaxSendBuffer,
+		maxAgeJitter:     defaultMaxAgeJitter,
+		heartbeatMisses:  defaultHeartbeatMisses,
+		sessions:         newSessionStore(defaultResumeWindow, defaultReplayFrames, defaultReplayBytes),
+		muteWindow:       defaultMuteWindow,
+		mutes:            make(map[string]time.Time),
+		talkSilence:      defaultTalkSilence,
+		talkers:          make(map[*Client]time.Time),
+		floorRequests:    make(chan floorRequest),
+		floorTimeout:     defaultFloorTimeout,
+		floorOfferWindow: defaultFloorOfferWindow,
+		floorMinHold:     defaultFloorMinHold,
+		transcodeBitrate: defaultTranscodeBitrate,
+		paceQueue:        defaultPaceQueue,
+	}
+	h.audio.Store(&AudioPolicy{audioFormat: defaultAudioFormat})
+	for _, opt := range opts {
+		opt(h)
+	}
+	for i := range h.shards {
+		h.shards[i] = newShard()
+	}
+	h.fanOutWorkers = h.newWorkers(h.workers)
+	h.broadcast = make(chan BroadcastMessage, h.broadcastQueue)
+	return h
+}
+
+// Run starts the hub and handles client registration, unregistration, and
+// broadcasting. It returns after Stop has been called and every client has
+// been unregistered.
+func (h *Hub) Run() {
+	defer close(h.done)
+	defer h.stopRecording()
+	defer h.stopMixing()
+	defer h.recent.clear()
+
+	wg := h.startWorkers()
+	defer func() {
+		for _, w := range h.fanOutWorkers {
+			close(w.queue)
+		}
+		wg.Wait()
+	}()
+
+	// A nil channel never fires, which disables the idle sweep
+	var idleSweep <-chan time.Time
+	if h.idleTimeout > 0 {
+		interval := h.idleTimeout / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		idleSweep = ticker.C
+	}
+
+	heartbeat := time.NewTicker(loopHeartbeat)
+	defer heartbeat.Stop()
+	h.lastLoop.Store(time.Now().UnixNano())
+
+	h.floorTimer = time.NewTimer(h.floorTimeout)
+	h.floorTimer.Stop()
+	defer h.floorTimer.Stop()
+
+	var ageSweep <-chan time.Time
+	if h.maxAge > 0 {
+		ticker := time.NewTicker(ageSweepInterval)
+		defer ticker.Stop()
+		ageSweep = ticker.C
+	}
+
+	var resumeSweep <-chan time.Time
+	if h.sessions != nil {
+		interval := h.sessions.window / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		resumeSweep = ticker.C
+	}
+
+	for {
+		// When the case being serviced started, for the loop latency
+		var busy time.Time
+
+		select {
+		case <-h.quit:
+			h.closeAll()
+			return
+
+		case now := <-idleSweep:
+			busy = time.Now()
+			h.closeIdle(now)
+
+		case now := <-heartbeat.C:
+			h.lastLoop.Store(now.UnixNano())
+
+		case now := <-ageSweep:
+			busy = time.Now()
+			h.closeAged(now)
+
+		case now := <-resumeSweep:
+			busy = time.Now()
+			if n := h.sessions.expire(now); n > 0 {
+				log.Printf("Expired %d unclaimed sessions", n)
+			}
+
+		case request := <-h.floorRequests:
+			busy = time.Now()
+			h.handleFloor(request, busy)
+
+		case now := <-h.floorTimer.C:
+			busy = time.Now()
+			h.expireFloor(now)
+
+		case reg := <-h.register:
+			busy = time.Now()
+			reg.result <- h.addClient(reg.client)
+
+		case unreg := <-h.unregister:
+			busy = time.Now()
+			if h.removeClient(unreg.client, unreg.reason) {
+				log.Printf("Client %s disconnected (%s). Total clients: %d", unreg.client.id, unreg.reason, h.ClientCount())
+				h.auditLeave(unreg.client, unreg.reason)
+			}
+
+		case message := <-h.broadcast:
+			busy = time.Now()
+			if !message.queued.IsZero() {
+				h.queueWait.record(busy.Sub(message.queued), busy)
+			}
+			if !message.mixed {
+				if !h.floorAllows(message, busy) {
+					message.frame.release()
+					break
+				}
+				if h.mix(message, busy) {
+					break
+				}
+				h.record(message, busy)
+			}
+
+			// Hand the frame to the workers and get straight back to
+			// servicing registrations. Each worker gets its own reference.
+			h.broadcastSeq++
+			message.seq = h.broadcastSeq
+			h.activity.record(message, busy)
+			h.sessions.buffer(message)
+			h.recent.buffer(message, busy)
+			for _, w := range h.fanOutWorkers {
+				message.retain()
+				w.queue <- message
+			}
+			message.release()
+		}
+
+		if !busy.IsZero() {
+			now := time.Now()
+			h.loopLatency.record(now.Sub(busy), now)
+		}
+	}
+}
+
+// shardFor returns the shard that owns the client
+func (h *Hub) shardFor(client *Client) *shard {
+	return h.shards[client.seq%uint64(len(h.shards))]
+}
+
+// deliver queues the message on the client's send buffer, applying its slow
+// consumer policy when the buffer is full. A queued message holds its own
+// frame reference until writePump releases it. It reports false if the
+// client should be evicted.
+func (h *Hub) deliver(client *Client, message outbound) bool {
+	message, encoded := client.rendered(message)
+	if !encoded {
+		return true
+	}
+	if message.frame != nil {
+		message.frame.retain()
+	}
+	if message.urgent() && client.queueUrgent(message) {
+		return true
+	}
+
+	select {
+	case client.send <- message:
+		return true
+	default:
+	}
+
+	// An end of transmission marker must follow whatever is queued of the
+	// burst, so it takes the oldest frame's place whatever the policy
+	if message.eot {
+		if !h.deliverAnnouncement(client, message) {
+			client.droppedOutbound.Add(1)
+			message.release()
+		}
+		return true
+	}
+
+	switch client.slowPolicy {
+	case SlowDropNewest:
+		client.droppedOutbound.Add(1)
+		message.release()
+		return true
+	case SlowDropOldest:
+		// writePump may drain the buffer concurrently, so neither step is
+		// guaranteed to be needed
+		select {
+		case oldest := <-client.send:
+			oldest.release()
+			client.droppedOutbound.Add(1)
+		default:
+		}
+		select {
+		case client.send <- message:
+		default:
+			client.droppedOutbound.Add(1)
+			message.release()
+		}
+		return true
+	}
+	message.release()
+	return false
+}
+
+// closeIdle unregisters every client that has been idle for longer than the
+// idle timeout. Only the Run goroutine may call it.
+func (h *Hub) closeIdle(now time.Time) {
+	var idle []*Client
+	h.eachClient(func(client *Client) {
+		if client.idleFor(now) > h.idleTimeout {
+			idle = append(idle, client)
+		}
+	})
+	for _, client := range idle {
+		if h.removeClient(client, reasonIdle) {
+			log.Printf("Client %s closed after %s idle. Total clients: %d", client.id, h.idleTimeout, h.ClientCount())
+		}
+	}
+}
+
+// addClient resumes the client's session if it presented a valid token,
+// applies the duplicate ID policy and adds the client to the registry. Only
+// the Run goroutine may call it.
+func (h *Hub) addClient(client *Client) error {
+	previous, replay, resumed := h.sessions.claim(client, time.Now())
+	if previous != nil {
+		h.removeClient(previous, reasonTakenOver)
+		log.Printf("Client %s resumed its session from a new connection", client.id)
+	}
+
+	h.mutex.RLock()
+	existing := h.byID[client.id]
+	h.mutex.RUnlock()
+
+	if existing != nil {
+		switch h.duplicateIDs {
+		case DuplicateReject:
+			log.Printf("Client %s rejected: ID already connected", client.id)
+			h.sessions.abandon(client, replay)
+			return errDuplicateID
+		case DuplicateTakeover:
+			h.removeClient(existing, reasonTakenOver)
+			log.Printf("Client %s taken over by a new connection", client.id)
+		case DuplicateRename:
+			client.id = h.freeID(client.id)
+		}
+	}
+
+	// Checked after a takeover so replacing a connection never counts
+	// against the limit. Run serializes registrations, so the limit can't be
+	// overshot by concurrent upgrades.
+	count := h.ClientCount()
+	if h.maxClients > 0 && count >= h.maxClients {
+		h.rejectedFull.Add(1)
+		log.Printf("Client %s rejected: hub at capacity (%d clients)", client.id, count)
+		h.sessions.abandon(client, replay)
+		return errHubFull
+	}
+	if capacity := int(h.capacity.Load()); capacity > 0 && count >= capacity {
+		h.rejectedRoomFull.Add(1)
+		log.Printf("Client %s rejected: room %s is full (%d/%d)", client.id, h.room, count, capacity)
+		h.sessions.abandon(client, replay)
+		return errRoomFull
+	}
+	if h.warnClients > 0 {
+		if count+1 >= h.warnClients && !h.warnedClients {
+			log.Printf("Warning: %d clients connected, approaching the limit of %d", count+1, h.maxClients)
+		}
+		h.warnedClients = count+1 >= h.warnClients
+	}
+
+	muted := h.restoreMute(client.id, time.Now()) || client.claims.joinsMuted()
+	client.muted.Store(muted)
+
+	if resumed {
+		client.resumed = true
+		h.sessions.rotate(client)
+		h.resumeFloor(client, time.Now())
+	} else {
+		h.sessions.open(client)
+	}
+	// Held until the join is announced, so the clients the joined message
+	// lists agree with the presence messages that follow it
+	h.presenceMutex.Lock()
+	defer h.presenceMutex.Unlock()
+
+	// Queued before the client joins its shard so the ack, then the replay,
+	// precede live frames
+	h.acknowledgeJoin(client, count+1)
+	if resumed {
+		h.replay(client, replay)
+	}
+	var recent []recentFrame
+	if client.lateJoinReplay && !resumed && h.recent != nil {
+		recent = h.startCatchUp(client, time.Now())
+	}
+
+	client.joinedAt = time.Now()
+	if h.maxAge > 0 {
+		client.expiresAt = client.joinedAt.Add(h.connectionLifetime())
+	}
+
+	h.nextSeq++
+	client.seq = h.nextSeq
+	h.shardFor(client).add(client)
+	h.pumps.Add(1)
+
+	h.mutex.Lock()
+	h.byID[client.id] = client
+	h.mutex.Unlock()
+	h.index(client)
+	h.addReceiver(client)
+	if client.caps.has(capLevels) {
+		h.meterListeners.Add(1)
+	}
+	if client.catchUp != nil {
+		go h.replayRecent(client, recent)
+	}
+	h.announcePresence(client, presenceJoin, closeReason{})
+	if muted {
+		log.Printf("Client %s joined room %s muted", client.id, h.room)
+		h.sendControl(client, muteMessage{Type: "muted", Room: localRoom(h.room)})
+	}
+	log.Printf("Client %s connected to room %s (%s). Clients in room: %d", client.id, h.room, client.protocol, h.ClientCount())
+	return nil
+}
+
+// replay queues the frames a resumed client missed, taking over their
+// references. Frames that don't fit what is left of its send buffer, after
+// the join ack, are dropped: its writePump isn't running yet, so nothing
+// would make room for them.
+func (h *Hub) replay(client *Client, replay []outbound) {
+	if !client.caps.has(capReplay) {
+		for _, message := range replay {
+			message.release()
+		}
+		log.Printf("Client %s resumed without replay, dropping %d frames", client.id, len(replay))
+		return
+	}
+	queued := 0
+	for _, message := range replay {
+		if rendered, encoded := client.rendered(message); rendered.frame != message.frame {
+			if encoded {
+				rendered.frame.retain()
+			}
+			message.release()
+			if !encoded {
+				continue
+			}
+			message = rendered
+		}
+		message.replayed = true
+		select {
+		case client.send <- message:
+			queued++
+		default:
+			client.droppedOutbound.Add(1)
+			message.release()
+		}
+	}
+	log.Printf("Client %s resumed, replaying %d of %d frames", client.id, queued, len(replay))
+}
+
+// freeID returns id with the lowest numeric suffix not yet in use
+func (h *Hub) freeID(id string) string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s#%d", id, n)
+		if _, taken := h.byID[candidate]; !taken {
+			return candidate
+		}
+	}
+}
+
+// Stop asks Run to exit. Registrations are refused from then on and every
+// connected client is unregistered and sent a 1001 close frame. Stop returns
+// once Run has finished and every writePump has flushed its backlog and
+// exited, or when ctx is done, whichever comes first.
+func (h *Hub) Stop(ctx context.Context) error {
+	h.stopOnce.Do(func() {
+		close(h.quit)
+	})
+
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeAll unregisters every client. Only the Run goroutine may call it.
+func (h *Hub) closeAll() {
+	h.mutex.Lock()
+	h.byID = make(map[string]*Client)
+	h.mutex.Unlock()
+	h.tagsMutex.Lock()
+	h.tagged = make(map[string]map[*Client]bool)
+	h.tagsMutex.Unlock()
+
+	closed := 0
+	for _, s := range h.shards {
+		for client := range s.drain() {
+			client.releaseAddr()
+			client.closeSend(reasonShutdown)
+			closed++
+		}
+	}
+	h.sessions.clear()
+	log.Printf("Hub for room %s stopped, closed %d clients", h.room, closed)
+}
+
+// registerClient hands the client to the hub and waits for the outcome. The
+// client's ID may have been changed by the duplicate ID policy when it
+// returns nil.
+func (h *Hub) registerClient(client *Client) error {
+	reg := registration{client: client, result: make(chan error, 1)}
+	select {
+	case h.register <- reg:
+		return <-reg.result
+	case <-h.done:
+		return errHubStopped
+	}
+}
+
+// unregisterClient asks the hub to remove the client. It never blocks once
+// the hub has stopped.
+func (h *Hub) unregisterClient(client *Client, reason closeReason) {
+	select {
+	case h.unregister <- unregistration{client: client, reason: reason}:
+	case <-h.done:
+	}
+}
+
+// submit queues a frame for broadcasting according to the hub's broadcast
+// policy, taking over the caller's frame reference. It reports whether the
+// frame was queued.
+func (h *Hub) submit(message BroadcastMessage) bool {
+	message.queued = time.Now()
+	if h.broadcastPolicy == BroadcastBlock {
+		select {
+		case h.broadcast <- message:
+			return true
+		case <-h.done:
+			message.frame.release()
+			return false
+		}
+	}
+
+	select {
+	case h.broadcast <- message:
+		return true
+	case <-h.done:
+		message.frame.release()
+		return false
+	default:
+		message.frame.release()
+		h.droppedFrames.Add(1)
+		if n := message.sender.droppedFrames.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("Broadcast queue full, dropped %d frames from client %s", n, message.sender.id)
+		}
+		return false
+	}
+}
+
+// removeClient deletes the client from the registry and closes its send
+// channel, so writePump sends a close frame with the given reason. It
+// reports whether the client was still registered. Every eviction and
+// unregistration goes through here. It is safe to call from the Run
+// goroutine and the fan-out workers, but never while holding the client's
+// shard lock.
+func (h *Hub) removeClient(client *Client, reason closeReason) bool {
+	h.presenceMutex.Lock()
+	ok := client.seq != 0 && h.shardFor(client).remove(client)
+	if ok {
+		h.announcePresence(client, presenceLeave, reason)
+	}
+	h.presenceMutex.Unlock()
+	if ok && client.will != "" && reason.abnormal() {
+		h.publishWill(client, reason)
+	}
+
+	h.mutex.Lock()
+	// A client that was taken over no longer owns its ID entry
+	if h.byID[client.id] == client {
+		delete(h.byID, client.id)
+	}
+	h.mutex.Unlock()
+	h.unindex(client)
+	if ok {
+		h.releaseMute(client, time.Now())
+		h.removeReceiver(client)
+		if client.caps.has(capLevels) {
+			h.meterListeners.Add(-1)
+		}
+	}
+
+	if ok && h.emptyHook != nil && h.ClientCount() == 0 {
+		h.emptyHook()
+	}
+
+	// A client switching rooms keeps its slot; its successor returns it
+	if reason != reasonSwitched {
+		client.releaseAddr()
+	}
+	h.sessions.detach(client, reason, time.Now())
+	client.closeSend(reason)
+	if ok {
+		client.endBurst(eotAborted)
+		h.leftFloor(client, reason)
+	}
+	return ok
+}
+
+// ClientCount returns the number of registered clients. It is safe to call
+// from any goroutine.
+func (h *Hub) ClientCount() int {
+	total := 0
+	for _, s := range h.shards {
+		total += s.len()
+	}
+	return total
+}
+
+// HubStats is a point-in-time summary of the hub
+type HubStats struct {
+	Clients        int    `json:"clients"`
+	Shards         int    `json:"shards"`
+	Workers        int    `json:"workers"`
+	QueuedFanOut   int    `json:"queued_fan_out"`
+	BroadcastQueue int    `json:"broadcast_queue"`
+	QueuedFrames   int    `json:"queued_frames"`
+	DroppedFrames  uint64 `json:"dropped_frames"`
+	MaxClients     int    `json:"max_clients"`
+	RejectedFull   uint64 `json:"rejected_full"`
+
+	// The room's participant cap, 0 for none, and joins it refused
+	Capacity         int    `json:"capacity"`
+	RejectedRoomFull uint64 `json:"rejected_room_full"`
+
+	// Audio frames dropped for going over the room's rate limit, and
+	// clients closed for it
+	RateLimited          uint64 `json:"rate_limited"`
+	RateLimitDisconnects uint64 `json:"rate_limit_disconnects"`
+
+	// Talk bursts cut off for going over the room's talk limit
+	TalkCutoffs uint64 `json:"talk_cutoffs"`
+
+	// Audio frames listeners sent, dropped, and listeners closed for it
+	ListenerDropped     uint64 `json:"listener_dropped"`
+	ListenerDisconnects uint64 `json:"listener_disconnects"`
+
+	// Audio frames muted clients sent, dropped
+	MutedDropped uint64 `json:"muted_dropped"`
+
+	// Signed audio frames that failed verification, and clients closed for
+	// sending them
+	FrameAuthFailed      uint64 `json:"frame_auth_failed"`
+	FrameAuthDisconnects uint64 `json:"frame_auth_disconnects"`
+
+	// Audio frames dropped as invalid, by why, and clients closed for
+	// sending them
+	InvalidFrames           map[string]uint64 `json:"invalid_frames,omitempty"`
+	InvalidFrameDisconnects uint64            `json:"invalid_frame_disconnects"`
+
+	// Key exchange messages relayed, and refused as too large, too many,
+	// or for no one
+	KeyExchanges        uint64 `json:"key_exchanges"`
+	KeyExchangesRefused uint64 `json:"key_exchanges_refused"`
+
+	// Chat messages relayed, and refused from listeners, muted clients, or
+	// as too long or too many
+	ChatMessages uint64 `json:"chat_messages"`
+	ChatRefused  uint64 `json:"chat_refused"`
+
+	// Audio frames and chat messages whispered to one client, and whispers
+	// refused as their target was gone or had blocked the sender
+	Whispers        uint64 `json:"whispers"`
+	WhispersRefused uint64 `json:"whispers_refused"`
+
+	// Audio frames and chat messages addressed to tags
+	TagAddressed uint64 `json:"tag_addressed"`
+
+	// Joins, leaves and hands raised or lowered announced to the room
+	PresenceEvents uint64 `json:"presence_events"`
+
+	// Talk bursts announced to the room
+	TalkBursts uint64 `json:"talk_bursts"`
+
+	// Level messages sent for the room's senders
+	LevelMessages uint64 `json:"level_messages"`
+
+	// End of transmission markers sent, and those for bursts cut off by
+	// their sender leaving
+	EOTMarkers uint64 `json:"eot_markers"`
+	EOTAborted uint64 `json:"eot_aborted"`
+
+	// Audio frames from VOX clients dropped for falling outside a burst
+	VOXDropped uint64 `json:"vox_dropped"`
+
+	// Audio frames dropped as silence
+	SilenceSuppressed uint64 `json:"silence_suppressed"`
+
+	// Whether the room mixes overlapping talkers, audio frames relayed
+	// unmixed from a lone talker and ticks of mixed audio sent, talkers'
+	// audio running short of a tick and too far ahead, frames dropped for
+	// a full mixer, and the mean time a tick took to mix
+	Mixing         bool    `json:"mixing"`
+	MixPassthrough uint64  `json:"mix_passthrough"`
+	MixedFrames    uint64  `json:"mixed_frames"`
+	MixUnderruns   uint64  `json:"mix_underruns"`
+	MixOverruns    uint64  `json:"mix_overruns"`
+	MixDropped     uint64  `json:"mix_dropped"`
+	MixTickMicros  float64 `json:"mix_tick_us"`
+
+	// Whether the room paces its senders' audio, audio frames held back
+	// for running ahead, and those dropped for a full pace queue
+	Pacing      bool   `json:"pacing"`
+	PacedFrames uint64 `json:"paced_frames"`
+	PaceDropped uint64 `json:"pace_dropped"`
+
+	// Audio frames encoded for listeners receiving another codec,
+	// downsampled for those at the low tier, and those that failed to encode
+	Transcoded      uint64 `json:"transcoded"`
+	Downsampled     uint64 `json:"downsampled"`
+	TranscodeFailed uint64 `json:"transcode_failed"`
+
+	// Hands raised
+	HandsRaised uint64 `json:"hands_raised"`
+
+	// Priority audio frames relayed, and priority marks stripped from
+	// clients not allowed them
+	PriorityFrames   uint64 `json:"priority_frames"`
+	PriorityStripped uint64 `json:"priority_stripped"`
+
+	// Audio frames dropped for failing their CRC32C
+	CorruptFrames uint64 `json:"corrupt_frames"`
+
+	// Floor grants and denials, clients put in line for the floor, offers
+	// of it not taken in time, holders preempted and holders a moderator
+	// revoked, and audio frames dropped from clients not holding it
+	FloorGrants        uint64 `json:"floor_grants"`
+	FloorDenials       uint64 `json:"floor_denials"`
+	FloorQueued        uint64 `json:"floor_queued"`
+	FloorOffersExpired uint64 `json:"floor_offers_expired"`
+	FloorPreemptions   uint64 `json:"floor_preemptions"`
+	FloorRevocations   uint64 `json:"floor_revocations"`
+	FloorDropped       uint64 `json:"floor_dropped"`
+
+	// Control messages that didn't parse, had an unknown type or failed
+	// validation
+	InvalidControl uint64 `json:"invalid_control"`
+
+	// Messages sent asking to be acknowledged, sent again for want of an
+	// acknowledgement, and never acknowledged
+	AcksRequested uint64 `json:"acks_requested"`
+	AckRetries    uint64 `json:"ack_retries"`
+	AckFailures   uint64 `json:"ack_failures"`
+
+	// Whether the room is being recorded, and the audio frames and bytes
+	// recorded, files written, frames dropped because the disk fell behind
+	// or failed, and disk errors
+	Recording        bool   `json:"recording"`
+	RecordedFrames   uint64 `json:"recorded_frames"`
+	RecordedBytes    uint64 `json:"recorded_bytes"`
+	RecordingFiles   uint64 `json:"recording_files"`
+	RecordingDropped uint64 `json:"recording_dropped"`
+	RecordingErrors  uint64 `json:"recording_errors"`
+
+	// Averages and maximums over the last 10 seconds or so of the time the
+	// hub loop spends on each thing it services, the time broadcasts wait
+	// in its queue, and the time from a broadcast being queued to reaching
+	// every listener's send buffer
+	LoopLatencyAvgUs      float64 `json:"loop_latency_us_avg"`
+	LoopLatencyMaxUs      float64 `json:"loop_latency_us_max"`
+	QueueWaitAvgUs        float64 `json:"queue_wait_us_avg"`
+	QueueWaitMaxUs        float64 `json:"queue_wait_us_max"`
+	BroadcastLatencyAvgUs float64 `json:"broadcast_latency_us_avg"`
+	BroadcastLatencyMaxUs float64 `json:"broadcast_latency_us_max"`
+
+	Sessions int    `json:"detached_sessions"`
+	Resumed  uint64 `json:"resumed_sessions"`
+
+	// The audio kept for late joiners, its cap, the replays started and
+	// the frames they sent
+	LateJoinFrames   int    `json:"late_join_frames"`
+	LateJoinBytes    int64  `json:"late_join_bytes"`
+	LateJoinMaxBytes int    `json:"late_join_max_bytes"`
+	LateJoinReplays  uint64 `json:"late_join_replays"`
+	LateJoinReplayed uint64 `json:"late_join_replayed_frames"`
+
+	// Payload bytes of compressed messages and their size on the wire, and
+	// the wire size of everything sent uncompressed
+	CompressedPayloadBytes uint64 `json:"compressed_payload_bytes"`
+	CompressedWireBytes    uint64 `json:"compressed_wire_bytes"`
+	UncompressedWireBytes  uint64 `json:"uncompressed_wire_bytes"`
+}
+
+// ClientStats describes a single registered client
+type ClientStats struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name,omitempty"`
+	Room            string  `json:"room"`
+	SlowConsumer    string  `json:"slow_consumer"`
+	SendBuffer      int     `json:"send_buffer"`
+	Queued          int     `json:"queued"`
+	IdleSeconds     float64 `json:"idle_seconds"`
+	DroppedFrames   uint64  `json:"dropped_frames"`
+	DroppedOutbound uint64  `json:"dropped_outbound"`
+	Protocol        string  `json:"protocol"`
+	Version         int     `json:"version"`
+	Compression     bool    `json:"compression"`
+	SocketWrites    uint64  `json:"socket_writes"`
+	HeartbeatRTTMs  float64 `json:"heartbeat_rtt_ms"`
+	ClientRTTMs     float64 `json:"client_rtt_ms"`
+	Moderator       bool    `json:"moderator"`
+	Muted           bool    `json:"muted"`
+	MutedDropped    uint64  `json:"muted_dropped"`
+	Role            string  `json:"role"`
+	RateLimited     uint64  `json:"rate_limited"`
+	ListenerDropped uint64  `json:"listener_dropped"`
+	FrameAuthFailed uint64  `json:"frame_auth_failed"`
+	TalkCutoffs     uint64  `json:"talk_cutoffs"`
+	InvalidControl  uint64  `json:"invalid_control"`
+
+	// Audio frames the client sent that were dropped as silence, the codec
+	// it receives audio in if not as sent, and its tier, full or low
+	SilenceSuppressed uint64 `json:"silence_suppressed"`
+	Receive           string `json:"receive,omitempty"`
+	Tier              string `json:"tier"`
+
+	// Audio frames the client sent that were dropped as invalid, by why
+	InvalidFrames map[string]uint64 `json:"invalid_frames,omitempty"`
+
+	// The client's tags
+	Tags []string `json:"tags,omitempty"`
+
+	// The audio format the client declared, and whether its room doesn't
+	// allow it
+	Format         *audioFormat `json:"format,omitempty"`
+	FormatMismatch bool         `json:"format_mismatch,omitempty"`
+
+	// Audio frames and chat messages the client whispered and was
+	// whispered, apart from its room's traffic, and how many clients its
+	// connection blocks
+	WhispersSent     uint64 `json:"whispers_sent"`
+	WhispersReceived uint64 `json:"whispers_received"`
+	Blocked          int    `json:"blocked,omitempty"`
+
+	// Audio frames the client marked priority without being allowed to
+	PriorityStripped uint64 `json:"priority_stripped,omitempty"`
+
+	// Audio frames the client has waiting in its pace queue, and those
+	// dropped for a full one
+	PaceQueue   int    `json:"pace_queue,omitempty"`
+	PaceDropped uint64 `json:"pace_dropped,omitempty"`
+
+	// Whether the client's frames carry a CRC32C, and those it sent that
+	// failed theirs
+	Integrity     bool   `json:"integrity,omitempty"`
+	CorruptFrames uint64 `json:"corrupt_frames,omitempty"`
+
+	// The capabilities negotiated with the client
+	Capabilities []string `json:"capabilities"`
+
+	// The client's sender filter, if it has one, and the audio frames it
+	// kept out
+	Filter   *FilterStats `json:"filter,omitempty"`
+	Filtered uint64       `json:"filtered"`
+
+	// Audio delay from the gateway reading a frame to writing it to the
+	// client's socket, and from the client capturing a frame it put its
+	// capture time on to the gateway reading it, clock skew allowed for,
+	// in microseconds over roughly the last ten seconds
+	QueueDelayAvgUs  float64 `json:"queue_delay_us_avg"`
+	QueueDelayMaxUs  float64 `json:"queue_delay_us_max"`
+	UplinkDelayAvgUs float64 `json:"uplink_delay_us_avg"`
+	UplinkDelayMaxUs float64 `json:"uplink_delay_us_max"`
+
+	// Audio frames the client numbered that never reached the gateway, and
+	// the share lost over the last minute
+	UpstreamLost        uint64  `json:"upstream_lost"`
+	UpstreamLossPercent float64 `json:"upstream_loss_pct"`
+
+	// Audio frames the gateway dropped on the way to the client, in all
+	// and from each sender, and the share over the last minute
+	DownstreamDropped     uint64                `json:"downstream_dropped"`
+	DownstreamLossPercent float64               `json:"downstream_loss_pct"`
+	SenderLoss            map[string]SenderLoss `json:"sender_loss,omitempty"`
+
+	// For a multi-room client's membership of another room, the room its
+	// connection was opened in
+	HomeRoom string `json:"home_room,omitempty"`
+}
+
+// Stats returns aggregate counters for the hub
+func (h *Hub) Stats() HubStats {
+	latency := h.latency(time.Now())
+	return HubStats{
+		Clients:          h.ClientCount(),
+		Shards:           len(h.shards),
+		Workers:          len(h.fanOutWorkers),
+		QueuedFanOut:     h.queuedFanOut(),
+		BroadcastQueue:   cap(h.broadcast),
+		QueuedFrames:     len(h.broadcast),
+		DroppedFrames:    h.droppedFrames.Load(),
+		MaxClients:       h.maxClients,
+		RejectedFull:     h.rejectedFull.Load(),
+		Capacity:         int(h.capacity.Load()),
+		RejectedRoomFull: h.rejectedRoomFull.Load(),
+
+		RateLimited:          h.rateLimited.Load(),
+		RateLimitDisconnects: h.rateLimitDisconnects.Load(),
+
+		TalkCutoffs: h.talkCutoffs.Load(),
+
+		ListenerDropped:     h.listenerDropped.Load(),
+		MutedDropped:        h.mutedDropped.Load(),
+		ListenerDisconnects: h.listenerDisconnects.Load(),
+
+		FrameAuthFailed:         h.frameAuthFailed.Load(),
+		FrameAuthDisconnects:    h.frameAuthDisconnects.Load(),
+		InvalidFrames:           h.invalidFrames.stats(),
+		InvalidFrameDisconnects: h.invalidFrameDisconnects.Load(),
+
+		KeyExchanges:        h.keyExchanges.Load(),
+		KeyExchangesRefused: h.keyExchangesRefused.Load(),
+		ChatMessages:        h.chatMessages.Load(),
+		ChatRefused:         h.chatRefused.Load(),
+		Whispers:            h.whispers.Load(),
+		WhispersRefused:     h.whispersRefused.Load(),
+		TagAddressed:        h.tagAddressed.Load(),
+		PresenceEvents:      h.presenceEvents.Load(),
+		TalkBursts:          h.talkBursts.Load(),
+		LevelMessages:       h.levelMessages.Load(),
+		EOTMarkers:          h.eotMarkers.Load(),
+		EOTAborted:          h.eotAborted.Load(),
+		VOXDropped:          h.voxDropped.Load(),
+		SilenceSuppressed:   h.silenceSuppressed.Load(),
+		Mixing:              h.mixing.Load(),
+		MixPassthrough:      h.mixPassthrough.Load(),
+		MixedFrames:         h.mixedFrames.Load(),
+		MixUnderruns:        h.mixUnderruns.Load(),
+		MixOverruns:         h.mixOverruns.Load(),
+		MixDropped:          h.mixDropped.Load(),
+		MixTickMicros:       h.mixTickMicros(),
+		Pacing:              h.pacing.Load(),
+		PacedFrames:         h.pacedFrames.Load(),
+		PaceDropped:         h.paceDropped.Load(),
+		Transcoded:          h.transcoded.Load(),
+		Downsampled:         h.downsampled.Load(),
+		TranscodeFailed:     h.transcodeFailed.Load(),
+		HandsRaised:         h.handsRaised.Load(),
+		PriorityFrames:      h.priorityFrames.Load(),
+		PriorityStripped:    h.priorityStripped.Load(),
+		CorruptFrames:       h.corruptFrames.Load(),
+		FloorGrants:         h.floorGrants.Load(),
+		FloorDenials:        h.floorDenials.Load(),
+		FloorQueued:         h.floorQueued.Load(),
+		FloorOffersExpired:  h.floorOffersExpired.Load(),
+		FloorPreemptions:    h.floorPreemptions.Load(),
+		FloorRevocations:    h.floorRevocations.Load(),
+		FloorDropped:        h.floorDropped.Load(),
+
+		InvalidControl: h.invalidControl.Load(),
+		AcksRequested:  h.acksRequested.Load(),
+		AckRetries:     h.ackRetries.Load(),
+		AckFailures:    h.ackFailures.Load(),
+
+		Recording:        h.recorder.Load() != nil,
+		RecordedFrames:   h.recording.frames.Load(),
+		RecordedBytes:    h.recording.bytes.Load(),
+		RecordingFiles:   h.recording.files.Load(),
+		RecordingDropped: h.recording.dropped.Load(),
+		RecordingErrors:  h.recording.errors.Load(),
+		Sessions:         h.sessions.pending(),
+		Resumed:          h.sessions.resumes(),
+
+		LateJoinFrames:   h.recent.frameCount(),
+		LateJoinBytes:    h.recent.byteCount(),
+		LateJoinMaxBytes: h.recent.capacity(),
+		LateJoinReplays:  h.recent.replayCount(),
+		LateJoinReplayed: h.recent.replayedCount(),
+
+		CompressedPayloadBytes: h.compression.payload.Load(),
+		CompressedWireBytes:    h.compression.compressed.Load(),
+		UncompressedWireBytes:  h.compression.uncompressed.Load(),
+
+		LoopLatencyAvgUs:      micros(latency.loopAvg),
+		LoopLatencyMaxUs:      micros(latency.loopMax),
+		QueueWaitAvgUs:        micros(latency.queueWaitAvg),
+		QueueWaitMaxUs:        micros(latency.queueWaitMax),
+		BroadcastLatencyAvgUs: micros(latency.broadcastAvg),
+		BroadcastLatencyMaxUs: micros(latency.broadcastMax),
+	}
+}
+
+// Clients returns per-client statistics for every registered client
+func (h *Hub) Clients() []ClientStats {
+	stats := make([]ClientStats, 0, h.ClientCount())
+	h.eachClient(func(client *Client) {
+		stats = append(stats, client.stats())
+	})
+	return stats
+}
+
+// queuedFanOut returns the number of broadcasts waiting in worker queues
+func (h *Hub) queuedFanOut() int {
+	total := 0
+	for _, w := range h.fanOutWorkers {
+		total += len(w.queue)
+	}
+	return total
+}
+
+// snapshotClients returns every registered client, for callers that go on
+// to send to them or remove them, which eachClient's shard locks rule out
+func (h *Hub) snapshotClients() []*Client {
+	var clients []*Client
+	h.eachClient(func(client *Client) {
+		clients = append(clients, client)
+	})
+	return clients
+}
+
+// eachClient calls fn for every registered client, one shard at a time
+func (h *Hub) eachClient(fn func(*Client)) {
+	for _, s := range h.shards {
+		s.each(fn)
+	}
+}
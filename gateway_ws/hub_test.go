@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// drain reads the connection until it fails, counting the audio frames
+func drain(conn *websocket.Conn, frames *sync.WaitGroup, counted *int) {
+	for {
+		messageType, _, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType == websocket.BinaryMessage && counted != nil {
+			*counted++
+			frames.Done()
+		}
+	}
+}
+
+// Clients that stop reading are evicted as slow consumers while the rest of
+// a busy room carries on. Run with -race: eviction and fan-out touch the
+// registry from different goroutines.
+func TestSlowClientsEvicted(t *testing.T) {
+	const (
+		clients = 50
+		slow    = 5
+		frames  = 200
+	)
+	tg := newStallingGateway(t, []HubOption{WithShards(4), WithWorkers(4)})
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	hub := tg.hub(t, defaultRoom)
+
+	var stalled []*websocket.Conn
+	for i := 0; i < slow; i++ {
+		conn, _ := tg.dial(t, "/ws?send_buffer=8", fmt.Sprintf("slow%d", i))
+		stalled = append(stalled, conn)
+	}
+	var received sync.WaitGroup
+	counts := make([]int, clients-slow-1)
+	for i := range counts {
+		conn, _ := tg.dial(t, "/ws", fmt.Sprintf("fast%d", i))
+		readControl(t, conn, "joined")
+		go drain(conn, &received, &counts[i])
+	}
+	waitFor(t, "every client to join", func() bool { return hub.ClientCount() == clients })
+	received.Add(len(counts) * frames)
+
+	payload := make([]byte, 16<<10)
+	for i := 0; i < frames; i++ {
+		if err := talker.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the slow clients to be evicted", func() bool { return hub.ClientCount() == clients-slow })
+	received.Wait()
+
+	for i, n := range counts {
+		if n != frames {
+			t.Errorf("fast%d got %d frames, want %d", i, n, frames)
+		}
+	}
+	for i := 0; i < slow; i++ {
+		if hub.lookup(fmt.Sprintf("slow%d", i)) != nil {
+			t.Errorf("slow%d still registered", i)
+		}
+	}
+	for _, conn := range stalled {
+		if code := expectClosed(t, conn); code != CloseSlowConsumer && code != -1 {
+			t.Errorf("slow client closed with %d, want %d", code, CloseSlowConsumer)
+		}
+	}
+}
+
+// submit never blocks a reader under the drop policy, counting what the
+// full queue turns away against the hub and the sender, and blocks under
+// the block policy until the hub takes the frame or stops
+func TestSubmitFullQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BroadcastPolicy
+		queued  int
+		dropped uint64
+	}{
+		{"drop", BroadcastDrop, 4, 6},
+		{"block", BroadcastBlock, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(WithBroadcastQueue(4), WithBroadcastPolicy(tt.policy))
+			sender := &Client{id: "s", hub: h}
+			results := make(chan bool, 10)
+			go func() {
+				for i := 0; i < 10; i++ {
+					results <- h.submit(BroadcastMessage{frame: getFrame(), sender: sender})
+				}
+			}()
+			queued := 0
+			for i := 0; i < tt.queued+int(tt.dropped); i++ {
+				select {
+				case ok := <-results:
+					if ok {
+						queued++
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("submit %d blocked", i)
+				}
+			}
+			if queued != tt.queued {
+				t.Errorf("queued %d frames, want %d", queued, tt.queued)
+			}
+			if got := h.droppedFrames.Load(); got != tt.dropped {
+				t.Errorf("hub dropped %d frames, want %d", got, tt.dropped)
+			}
+			if got := sender.droppedFrames.Load(); got != tt.dropped {
+				t.Errorf("sender dropped %d frames, want %d", got, tt.dropped)
+			}
+			if tt.policy == BroadcastBlock {
+				select {
+				case <-results:
+					t.Fatal("submit to a full queue returned under the block policy")
+				case <-time.After(50 * time.Millisecond):
+				}
+				close(h.done)
+				for i := tt.queued; i < 10; i++ {
+					if ok := <-results; ok {
+						t.Error("submit to a stopped hub queued the frame")
+					}
+				}
+			}
+			for len(h.broadcast) > 0 {
+				(<-h.broadcast).frame.release()
+			}
+		})
+	}
+}
+
+// A talker's readPump keeps reading while its room's hub loop is held up,
+// dropping what doesn't fit the broadcast queue
+func TestReaderDoesNotStallOnFullQueue(t *testing.T) {
+	tg := newTestGateway(t, []HubOption{WithBroadcastQueue(4), WithBroadcastPolicy(BroadcastDrop)})
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	hub := tg.hub(t, defaultRoom)
+
+	// A join blocks the hub loop while the test holds the presence mutex
+	hub.presenceMutex.Lock()
+	joined := make(chan error, 1)
+	go func() {
+		conn, _, err := tg.tryDial("/ws", "late", nil)
+		if err == nil {
+			conn.Close()
+		}
+		joined <- err
+	}()
+	// Long enough for the loop to take the registration; if it hasn't, the
+	// frames go through and the drops never add up
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < 20; i++ {
+		if err := talker.WriteMessage(websocket.BinaryMessage, pcmFrame(byte(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var talkerClient *Client
+	waitFor(t, "the talker's frames to be dropped", func() bool {
+		talkerClient = hub.lookup("talker")
+		return talkerClient != nil && talkerClient.droppedFrames.Load() == 16
+	})
+	hub.presenceMutex.Unlock()
+	if err := <-joined; err != nil {
+		t.Fatalf("late join: %v", err)
+	}
+	if got := hub.Stats().DroppedFrames; got != 16 {
+		t.Errorf("hub dropped %d frames, want 16", got)
+	}
+}
+
+// A listener that pauses for a second while a talker sends is evicted, or
+// keeps the oldest or the newest frames, by its slow consumer policy
+func TestSlowConsumerPolicies(t *testing.T) {
+	const frames = 100
+	tests := []struct {
+		policy  string
+		evicted bool
+		// Whether the listener gets the talker's last frame once it reads
+		// again
+		gotLast bool
+	}{
+		{"disconnect", true, false},
+		{"drop-newest", false, false},
+		{"drop-oldest", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			tg := newStallingGateway(t, nil)
+			talker, _ := tg.dial(t, "/ws", "talker")
+			readControl(t, talker, "joined")
+			hub := tg.hub(t, defaultRoom)
+			listener, _ := tg.dial(t, "/ws?send_buffer=8&slow_consumer="+tt.policy, "listener")
+			waitFor(t, "the listener to join", func() bool { return hub.ClientCount() == 2 })
+
+			payload := make([]byte, 16<<10)
+			for i := 0; i < frames; i++ {
+				payload[0] = byte(i)
+				if err := talker.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if tt.evicted {
+				waitFor(t, "the listener to be evicted", func() bool { return hub.ClientCount() == 1 })
+				if code := expectClosed(t, listener); code != CloseSlowConsumer && code != -1 {
+					t.Errorf("closed with %d, want %d", code, CloseSlowConsumer)
+				}
+				return
+			}
+			if tg.clientStats(t, "listener").DroppedOutbound == 0 {
+				t.Fatal("no frames dropped")
+			}
+			received, last := 0, -1
+			for {
+				listener.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+				messageType, data, err := listener.ReadMessage()
+				if err != nil {
+					break
+				}
+				if messageType == websocket.BinaryMessage {
+					received++
+					last = int(data[0])
+				}
+			}
+			if hub.ClientCount() != 2 {
+				t.Error("listener was evicted")
+			}
+			if received == 0 || received == frames {
+				t.Errorf("received %d of %d frames", received, frames)
+			}
+			if gotLast := last == frames-1; gotLast != tt.gotLast {
+				t.Errorf("last frame received %d of %d", last, frames)
+			}
+		})
+	}
+}
+
+// A second connection with a connected client's ID takes over its entry and
+// closes it, is refused with 409, or joins under a suffixed ID, as the
+// duplicate ID policy says. The old connection's unregister arriving after
+// a takeover leaves the new connection's entry alone.
+func TestDuplicateIDPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicateIDPolicy
+	}{
+		{"takeover", DuplicateTakeover},
+		{"reject", DuplicateReject},
+		{"rename", DuplicateRename},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGateway(t, []HubOption{WithDuplicateIDPolicy(tt.policy)})
+			first, _ := tg.dial(t, "/ws", "c")
+			readControl(t, first, "joined")
+			hub := tg.hub(t, defaultRoom)
+			old := hub.lookup("c")
+
+			if tt.policy == DuplicateReject {
+				conn, resp, err := tg.tryDial("/ws", "c", nil)
+				if err == nil {
+					conn.Close()
+				}
+				if resp == nil || resp.StatusCode != http.StatusConflict {
+					t.Fatalf("got %v, %v, want %d", resp, err, http.StatusConflict)
+				}
+				if hub.lookup("c") != old {
+					t.Error("the connected client lost its ID")
+				}
+				return
+			}
+
+			second, _ := tg.dial(t, "/ws", "c")
+			joined := readControl(t, second, "joined")
+			if tt.policy == DuplicateRename {
+				if joined["id"] != "c#2" {
+					t.Errorf("joined as %v, want c#2", joined["id"])
+				}
+				if hub.lookup("c") != old || hub.lookup("c#2") == nil {
+					t.Error("want both c and c#2 registered")
+				}
+				return
+			}
+
+			if code := expectClosed(t, first); code != CloseTakenOver {
+				t.Errorf("old connection closed with %d, want %d", code, CloseTakenOver)
+			}
+			current := hub.lookup("c")
+			if current == nil || current == old {
+				t.Fatal("the new connection doesn't hold the ID")
+			}
+			// Late, as the old readPump's would be. A registration after it
+			// is handled by Run after it too.
+			hub.unregisterClient(old, reasonLost)
+			other, _ := tg.dial(t, "/ws", "d")
+			readControl(t, other, "joined")
+			if hub.lookup("c") != current {
+				t.Error("the old connection's unregister evicted the new one")
+			}
+			if n := hub.ClientCount(); n != 2 {
+				t.Errorf("%d clients, want 2", n)
+			}
+		})
+	}
+}
+
+// Stop closes every connected client with 1001 and returns once their
+// writePumps have exited, refusing registrations from then on
+func TestHubStop(t *testing.T) {
+	tg := newTestGateway(t, nil)
+	conns := make([]*websocket.Conn, 3)
+	for i := range conns {
+		conns[i], _ = tg.dial(t, "/ws", fmt.Sprintf("c%d", i))
+		readControl(t, conns[i], "joined")
+	}
+	hub := tg.hub(t, defaultRoom)
+	clients := make([]*Client, len(conns))
+	for i := range clients {
+		if clients[i] = hub.lookup(fmt.Sprintf("c%d", i)); clients[i] == nil {
+			t.Fatalf("c%d not registered", i)
+		}
+	}
+
+	// Read throughout, so each close frame is answered and no writePump
+	// waits out its grace period
+	codes := make(chan int, len(conns))
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(testTimeout))
+		go func(conn *websocket.Conn) {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					code := -1
+					if closeErr, ok := err.(*websocket.CloseError); ok {
+						code = closeErr.Code
+					}
+					codes <- code
+					return
+				}
+			}
+		}(conn)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := hub.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	for i, client := range clients {
+		select {
+		case <-client.writeDone:
+		default:
+			t.Errorf("Stop returned before c%d's writePump exited", i)
+		}
+	}
+	for range conns {
+		if code := <-codes; code != websocket.CloseGoingAway {
+			t.Errorf("closed with %d, want %d", code, websocket.CloseGoingAway)
+		}
+	}
+	if n := hub.ClientCount(); n != 0 {
+		t.Errorf("%d clients still registered", n)
+	}
+	if err := hub.registerClient(&Client{id: "late", hub: hub}); err != errHubStopped {
+		t.Errorf("registering after Stop: %v, want %v", err, errHubStopped)
+	}
+}
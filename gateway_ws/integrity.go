@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Some links corrupt audio without breaking TCP's checksum, so a
+// walkie.framed.v1 connection may ask for integrity mode with
+// ?integrity=crc32c. Its frames then carry a version 2 header, which ends
+// with the CRC32C of the rest of the frame, in both directions: the
+// gateway drops and counts a frame whose CRC doesn't match, and puts a
+// fresh one on every frame it sends. Other connections' frames are
+// unchanged.
+const (
+	integrityCRC32C = "crc32c"
+
+	// framedChecksummed is the first byte of a version 2 header
+	framedChecksummed = 0xA2
+
+	// framedCRCSize is what a version 2 header adds to version 1's
+	framedCRCSize = 4
+)
+
+var errFramedCorrupt = errors.New("audio frame fails its CRC32C")
+
+// castagnoli is the CRC32C table, which the crc32 package computes with the
+// CPU's instructions for it where it has them
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// frameCRC returns the CRC32C of parts, one after another
+func frameCRC(parts ...[]byte) uint32 {
+	var crc uint32
+	for _, part := range parts {
+		crc = crc32.Update(crc, castagnoli, part)
+	}
+	return crc
+}
+
+// requestedIntegrity reports whether the client asks for integrity mode,
+// which only walkie.framed.v1 has
+func requestedIntegrity(r *http.Request, protocol string) (bool, error) {
+	switch mode := r.URL.Query().Get("integrity"); mode {
+	case "":
+		return false, nil
+	case integrityCRC32C:
+		if protocol != protocolFramed {
+			return false, fmt.Errorf("integrity mode needs %s", protocolFramed)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid integrity mode %q: want %s", mode, integrityCRC32C)
+	}
+}
+
+// appendChecksummedHeader appends the encoded version 2 header to dst, with
+// the CRC32C of what follows it, parts
+func appendChecksummedHeader(dst []byte, h frameHeader, parts ...[]byte) []byte {
+	start := len(dst)
+	dst = appendFrameHeader(dst, h)
+	dst[start] = framedChecksummed
+	return binary.BigEndian.AppendUint32(dst, frameCRC(parts...))
+}
+
+// checksummedMessage returns the frame as a walkie.framed.v1 PreparedMessage
+// for a connection in integrity mode, framing it on first use
+func (f *frameBuffer) checksummedMessage() (*websocket.PreparedMessage, error) {
+	if f.messageType == websocket.TextMessage {
+		return f.preparedMessage()
+	}
+	f.checksummedOnce.Do(func() {
+		data := make([]byte, 0, f.header.size()+framedCRCSize+len(f.data))
+		data = append(appendChecksummedHeader(data, f.header, f.data), f.data...)
+		f.checksummedPrepared, f.checksummedErr = websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+	})
+	return f.checksummedPrepared, f.checksummedErr
+}
+
+// dropCorrupt counts a frame from the client that failed its CRC. The link
+// is to blame rather than the client, so it counts toward no disconnect.
+func (c *Client) dropCorrupt(frame *frameBuffer) {
+	frame.release()
+	c.hub.corruptFrames.Add(1)
+	if n := c.corruptFrames.Add(1); n == 1 || n%100 == 0 {
+		log.Printf("Dropped %d corrupt frames from client %s", n, c.id)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialFramed connects to path as the client id over walkie.framed.v1
+func (tg *testGateway) dialFramed(t *testing.T, path, id string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := tg.tryDial(path, id, http.Header{"Sec-WebSocket-Protocol": {protocolFramed}})
+	if err != nil {
+		t.Fatalf("dialing %s as %s: %v", path, id, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	readControl(t, conn, "joined")
+	return conn
+}
+
+// Frames corrupted on the way in fail their CRC and are dropped and counted
+// without closing the connection; the rest reach integrity mode listeners
+// with a fresh CRC, and everyone else as before
+func TestCorruptFramesDropped(t *testing.T) {
+	const frames = 50
+	tg := newTestGateway(t, nil)
+	talker := tg.dialFramed(t, "/ws?integrity=crc32c", "talker")
+	checked := tg.dialFramed(t, "/ws?integrity=crc32c", "checked")
+	plain := tg.dialFramed(t, "/ws", "plain")
+	raw, _ := tg.dial(t, "/ws", "raw")
+	readControl(t, raw, "joined")
+	hub := tg.hub(t, defaultRoom)
+	waitFor(t, "everyone to join", func() bool { return hub.ClientCount() == 4 })
+
+	// Every fifth frame has a byte flipped after its CRC was taken, in the
+	// audio or in the CRC itself
+	var want []byte
+	for i := 0; i < frames; i++ {
+		audio := make([]byte, 640)
+		audio[0] = byte(i)
+		data := append(appendChecksummedHeader(nil, frameHeader{sender: "talker", seq: uint64(i + 1)}, audio), audio...)
+		switch {
+		case i%10 == 4:
+			data[len(data)-1] ^= 0x10
+		case i%10 == 9:
+			data[len(data)-len(audio)-1] ^= 0x01
+		default:
+			want = append(want, byte(i))
+		}
+		if err := talker.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupted := uint64(frames - len(want))
+
+	for _, i := range want {
+		data := readAudio(t, checked)
+		header, n, err := parseFrameHeader(data, true)
+		if err != nil {
+			t.Fatalf("checked listener: %v", err)
+		}
+		if header.crc != frameCRC(data[n:]) {
+			t.Fatalf("frame %d carries CRC %08x, want %08x", i, header.crc, frameCRC(data[n:]))
+		}
+		if data[n] != i {
+			t.Fatalf("checked listener got frame %d, want %d", data[n], i)
+		}
+
+		data = readAudio(t, plain)
+		_, n, err = parseFrameHeader(data, false)
+		if err != nil {
+			t.Fatalf("plain listener: %v", err)
+		}
+		if data[n] != i || len(data) != n+640 {
+			t.Fatalf("plain listener got frame %d of %d bytes, want %d without a CRC", data[n], len(data)-n, i)
+		}
+
+		if data = readAudio(t, raw); data[0] != i || len(data) != 640 {
+			t.Fatalf("raw listener got frame %d of %d bytes, want %d bare", data[0], len(data), i)
+		}
+	}
+	// The last frame is one of the corrupted ones
+	waitFor(t, "every corrupt frame to be counted", func() bool { return hub.Stats().CorruptFrames == corrupted })
+	if got := tg.clientStats(t, "talker").CorruptFrames; got != corrupted {
+		t.Errorf("talker counted %d corrupt frames, want %d", got, corrupted)
+	}
+	if hub.lookup("talker") == nil {
+		t.Error("talker closed for corrupt frames")
+	}
+}
+
+// Integrity mode is walkie.framed.v1's alone, and crc32c its only mode
+func TestIntegrityNegotiation(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		protocol string
+		status   int
+	}{
+		{"framed", "/ws?integrity=crc32c", protocolFramed, http.StatusSwitchingProtocols},
+		{"raw", "/ws?integrity=crc32c", protocolRaw, http.StatusBadRequest},
+		{"json", "/ws?integrity=crc32c", protocolJSON, http.StatusBadRequest},
+		{"unknown mode", "/ws?integrity=md5", protocolFramed, http.StatusBadRequest},
+	}
+	tg := newTestGateway(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := tg.tryDial(tt.path, "c", http.Header{"Sec-WebSocket-Protocol": {tt.protocol}})
+			if err == nil {
+				conn.Close()
+			}
+			if resp == nil || resp.StatusCode != tt.status {
+				t.Fatalf("got %v, %v, want %d", resp, err, tt.status)
+			}
+		})
+	}
+}
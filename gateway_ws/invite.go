@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Invites last an hour unless minted for longer, up to a week, and are
+// single-use unless minted with another count. Spent ones are remembered
+// for inviteRetention past their expiry so they are refused with the
+// reason rather than as unknown.
+const (
+	defaultInviteTTL = time.Hour
+	maxInviteTTL     = 7 * 24 * time.Hour
+	inviteRetention  = time.Hour
+)
+
+// invite admits whoever presents its token to one room, as a participant,
+// until it expires, is used up or is revoked. Guarded by the gateway mutex.
+type invite struct {
+	id      string
+	token   string
+	room    string
+	created time.Time
+	expires time.Time
+	maxUses int
+	uses    int
+	revoked bool
+}
+
+// state says whether the invite still admits anyone, and if not why
+func (inv *invite) state(now time.Time) string {
+	switch {
+	case inv.revoked:
+		return "revoked"
+	case !now.Before(inv.expires):
+		return "expired"
+	case inv.maxUses > 0 && inv.uses >= inv.maxUses:
+		return "exhausted"
+	}
+	return "active"
+}
+
+// InviteInfo describes an invite in the admin API. The token is only shown
+// when the invite is minted.
+type InviteInfo struct {
+	ID        string    `json:"id"`
+	Room      string    `json:"room"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	State     string    `json:"state"`
+}
+
+// info describes the invite, without its token
+func (inv *invite) info(now time.Time) InviteInfo {
+	return InviteInfo{
+		ID:        inv.id,
+		Room:      inv.room,
+		CreatedAt: inv.created,
+		ExpiresAt: inv.expires,
+		MaxUses:   inv.maxUses,
+		Uses:      inv.uses,
+		State:     inv.state(now),
+	}
+}
+
+// inviteRequest is the body of a request to mint an invite. ExpiresIn is a
+// Go duration such as "2h"; MaxUses defaults to 1 and 0 means no limit.
+type inviteRequest struct {
+	ExpiresIn string `json:"expires_in"`
+	MaxUses   *int   `json:"max_uses"`
+}
+
+// inviteError is the body of a refused invite
+type inviteError struct {
+	Error string `json:"error"`
+	Room  string `json:"room"`
+}
+
+// invitation is the request's invite token, from the invite query parameter
+func invitation(r *http.Request) string {
+	return r.URL.Query().Get("invite")
+}
+
+// mintInvite creates an invite to the room
+func (g *Gateway) mintInvite(room string, ttl time.Duration, maxUses int, now time.Time) InviteInfo {
+	inv := &invite{
+		id:      newToken()[:10],
+		token:   newToken(),
+		room:    room,
+		created: now,
+		expires: now.Add(ttl),
+		maxUses: maxUses,
+	}
+	g.mutex.Lock()
+	g.invites[inv.token] = inv
+	g.mutex.Unlock()
+
+	info := inv.info(now)
+	info.Token = inv.token
+	return info
+}
+
+// useInvite spends one use of the invite for the room, or reports why it
+// doesn't admit: "invalid", "revoked", "expired" or "exhausted". The
+// returned func gives the use back, for a join that fails after the invite
+// was accepted. Refusals are counted with the room's denied joins.
+func (g *Gateway) useInvite(room, token string, now time.Time) (refund func(), refused string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	refused = "invalid"
+	inv := g.invites[token]
+	if inv != nil && inv.room == room {
+		refused = inv.state(now)
+	}
+	if refused != "active" {
+		g.joinDenied[room]++
+		return nil, refused
+	}
+	inv.uses++
+	return func() {
+		g.mutex.Lock()
+		inv.uses--
+		g.mutex.Unlock()
+	}, ""
+}
+
+// roomInvites lists the room's invites, oldest first
+func (g *Gateway) roomInvites(room string, now time.Time) []InviteInfo {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	invites := []InviteInfo{}
+	for _, inv := range g.invites {
+		if inv.room == room {
+			invites = append(invites, inv.info(now))
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites
+}
+
+// revokeInvite revokes the room's invite with the given ID, reporting false
+// if there is none
+func (g *Gateway) revokeInvite(room, id string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, inv := range g.invites {
+		if inv.room == room && inv.id == id {
+			inv.revoked = true
+			return true
+		}
+	}
+	return false
+}
+
+// expireInvites forgets invites that expired more than inviteRetention ago.
+// The caller must hold the mutex.
+func (g *Gateway) expireInvites(now time.Time) {
+	for token, inv := range g.invites {
+		if now.Sub(inv.expires) > inviteRetention {
+			delete(g.invites, token)
+		}
+	}
+}
+
+// admitInvited checks the invite the request carries to the room. ok is
+// false, having answered 403 with the reason, if the invite doesn't
+// admit; otherwise refund gives back the use if the join goes no further.
+func (g *Gateway) admitInvited(w http.ResponseWriter, r *http.Request, room, clientID string) (refund func(), ok bool) {
+	refund, refused := g.useInvite(room, invitation(r), time.Now())
+	if refused != "" {
+		log.Printf("Client %s refused from room %s: invite %s", clientID, room, refused)
+		writeJSON(w, http.StatusForbidden, inviteError{Error: "invite_" + refused, Room: localRoom(room)})
+		return nil, false
+	}
+	log.Printf("Client %s admitted to room %s by invite", clientID, room)
+	return refund, true
+}
+
+// serveInvites mints (POST) and lists (GET) a room's invites, and revokes
+// one (DELETE /rooms/{room}/invites/{id})
+func (g *Gateway) serveInvites(w http.ResponseWriter, r *http.Request, room, id string) {
+	now := time.Now()
+
+	switch {
+	case id != "" && r.Method == http.MethodDelete:
+		if !g.revokeInvite(room, id) {
+			http.Error(w, "no such invite", http.StatusNotFound)
+			return
+		}
+		log.Printf("Invite %s to room %s revoked", id, room)
+		w.WriteHeader(http.StatusNoContent)
+
+	case id != "":
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+	case r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g.roomInvites(room, now))
+
+	case r.Method == http.MethodPost:
+		var request inviteRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		// An empty body mints an invite with the defaults
+		if err := decoder.Decode(&request); err != nil && err != io.EOF {
+			http.Error(w, "invalid invite request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl := defaultInviteTTL
+		if request.ExpiresIn != "" {
+			var err error
+			ttl, err = time.ParseDuration(request.ExpiresIn)
+			if err != nil || ttl <= 0 || ttl > maxInviteTTL {
+				http.Error(w, "expires_in must be a duration up to "+maxInviteTTL.String(), http.StatusBadRequest)
+				return
+			}
+		}
+		maxUses := 1
+		if request.MaxUses != nil {
+			maxUses = *request.MaxUses
+		}
+		if maxUses < 0 {
+			http.Error(w, "max_uses must not be negative", http.StatusBadRequest)
+			return
+		}
+		info := g.mintInvite(room, ttl, maxUses, now)
+		log.Printf("Invite %s to room %s minted, expiring %s, max uses %d", info.ID, room, info.ExpiresAt.Format(time.RFC3339), maxUses)
+		writeJSON(w, http.StatusCreated, info)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
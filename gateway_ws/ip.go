@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// trustedProxies lists the networks whose X-Forwarded-For headers are
+// believed when working out a client's real address
+type trustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or bare IPs
+func ParseTrustedProxies(list string) (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// parseNetwork parses a CIDR, or a bare IP as the network of just itself
+func parseNetwork(entry string) (*net.IPNet, error) {
+	if !strings.Contains(entry, "/") {
+		if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+			entry += "/32"
+		} else {
+			entry += "/128"
+		}
+	}
+	_, network, err := net.ParseCIDR(entry)
+	return network, err
+}
+
+// contains reports whether ip belongs to a trusted proxy
+func (t trustedProxies) contains(ip net.IP) bool {
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client behind r. X-Forwarded-For is
+// only consulted when the direct peer is a trusted proxy, and is walked from
+// the right so a client can't spoof its way past the proxies.
+func (t trustedProxies) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !t.contains(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !t.contains(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// ipBucket groups addresses that belong to one host: the address itself for
+// IPv4 and the /64 for IPv6, since a single host typically owns a whole /64
+func ipBucket(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// ipLimiter caps concurrent connections per IP bucket
+type ipLimiter struct {
+	limit int
+
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func newIPLimiter(limit int) *ipLimiter {
+	return &ipLimiter{limit: limit, counts: make(map[string]int)}
+}
+
+// acquire reserves a connection slot for the bucket. It returns false if the
+// bucket is at its limit; otherwise the returned release func gives the slot
+// back and is safe to call more than once.
+func (l *ipLimiter) acquire(bucket string) (func(), bool) {
+	if l == nil || l.limit <= 0 {
+		return func() {}, true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.counts[bucket] >= l.limit {
+		return nil, false
+	}
+	l.counts[bucket]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			if l.counts[bucket]--; l.counts[bucket] <= 0 {
+				delete(l.counts, bucket)
+			}
+		})
+	}, true
+}
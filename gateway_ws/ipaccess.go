@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Access decisions for a client's address
+const (
+	accessAllowed    = "allowed"
+	accessDenied     = "denied"
+	accessNotAllowed = "not_allowed"
+)
+
+var errNoIPAccess = errors.New("no address access file configured")
+
+// ipLists is the address access file: networks that may not connect, and,
+// if any are listed, the only networks that may
+type ipLists struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// ipAccess decides which client addresses may upgrade, from lists that are
+// reread from their file on SIGHUP or through the admin API
+type ipAccess struct {
+	path string
+
+	mutex sync.RWMutex
+	lists ipLists
+	allow []*net.IPNet
+	deny  []*net.IPNet
+
+	// Decisions made, by outcome
+	allowed    atomic.Uint64
+	denied     atomic.Uint64
+	notAllowed atomic.Uint64
+}
+
+// LoadIPAccess reads the address access file at path: a JSON object with
+// allow and deny lists of CIDRs or bare IPs
+func LoadIPAccess(path string) (*ipAccess, error) {
+	a := &ipAccess{path: path}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// parseIPLists parses the networks in an address access file
+func parseIPLists(data []byte) (lists ipLists, allow, deny []*net.IPNet, err error) {
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return lists, nil, nil, err
+	}
+	for _, entry := range lists.Allow {
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return lists, nil, nil, fmt.Errorf("invalid allowed address %q: %v", entry, err)
+		}
+		allow = append(allow, network)
+	}
+	for _, entry := range lists.Deny {
+		network, err := parseNetwork(entry)
+		if err != nil {
+			return lists, nil, nil, fmt.Errorf("invalid denied address %q: %v", entry, err)
+		}
+		deny = append(deny, network)
+	}
+	if lists.Allow == nil {
+		lists.Allow = []string{}
+	}
+	if lists.Deny == nil {
+		lists.Deny = []string{}
+	}
+	return lists, allow, deny, nil
+}
+
+// reload rereads the address access file. If it doesn't parse, the lists
+// already loaded stay in force.
+func (a *ipAccess) reload() error {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("reading address access file: %w", err)
+	}
+	lists, allow, deny, err := parseIPLists(data)
+	if err != nil {
+		return fmt.Errorf("address access file %s: %w", a.path, err)
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.lists, a.allow, a.deny = lists, allow, deny
+	return nil
+}
+
+// decide returns the access decision for a client's address. The deny list
+// wins over the allow list, and with an allow list an address that can't be
+// worked out is not allowed.
+func (a *ipAccess) decide(ip net.IP) string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	for _, network := range a.deny {
+		if ip != nil && network.Contains(ip) {
+			return accessDenied
+		}
+	}
+	if len(a.allow) == 0 {
+		return accessAllowed
+	}
+	for _, network := range a.allow {
+		if ip != nil && network.Contains(ip) {
+			return accessAllowed
+		}
+	}
+	return accessNotAllowed
+}
+
+// count tallies a decision made for an upgrade
+func (a *ipAccess) count(decision string) {
+	switch decision {
+	case accessAllowed:
+		a.allowed.Add(1)
+	case accessDenied:
+		a.denied.Add(1)
+	default:
+		a.notAllowed.Add(1)
+	}
+}
+
+// IPAccessStats is the address lists in force and the decisions made with
+// them
+type IPAccessStats struct {
+	Allow      []string `json:"allow"`
+	Deny       []string `json:"deny"`
+	Allowed    uint64   `json:"allowed"`
+	Denied     uint64   `json:"denied"`
+	NotAllowed uint64   `json:"not_allowed"`
+}
+
+// Stats returns the lists and decision counters
+func (a *ipAccess) Stats() IPAccessStats {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return IPAccessStats{
+		Allow:      a.lists.Allow,
+		Deny:       a.lists.Deny,
+		Allowed:    a.allowed.Load(),
+		Denied:     a.denied.Load(),
+		NotAllowed: a.notAllowed.Load(),
+	}
+}
+
+// WithIPAccess refuses upgrades from client addresses the lists in access
+// don't allow. The address is the one -trusted-proxies works out.
+func WithIPAccess(access *ipAccess) GatewayOption {
+	return func(g *Gateway) {
+		g.ipAccess = access
+	}
+}
+
+// admitIP reports whether the client's address may upgrade, having answered
+// 403 if not
+func (g *Gateway) admitIP(w http.ResponseWriter, r *http.Request) bool {
+	if g.ipAccess == nil {
+		return true
+	}
+	ip := g.proxies.clientIP(r)
+	decision := g.ipAccess.decide(ip)
+	g.ipAccess.count(decision)
+	if decision == accessAllowed {
+		g.debugf("Upgrade from %s (peer %s): address allowed", ip, r.RemoteAddr)
+		return true
+	}
+	log.Printf("Upgrade from %s refused: address %s", ip, decision)
+	g.auditRefused(r, r.Header.Get("X-Client-ID"), "", "address "+decision)
+	http.Error(w, "address not allowed", http.StatusForbidden)
+	return false
+}
+
+// ReloadIPAccess rereads the address lists and closes the connections whose
+// addresses they no longer allow. Connections still allowed are untouched.
+func (g *Gateway) ReloadIPAccess() error {
+	if g.ipAccess == nil {
+		return errNoIPAccess
+	}
+	if err := g.ipAccess.reload(); err != nil {
+		return err
+	}
+	stats := g.ipAccess.Stats()
+	closed := 0
+	g.eachRoom(func(rm *room) {
+		for _, client := range rm.hub.snapshotClients() {
+			if client.owner != nil {
+				continue
+			}
+			if decision := g.ipAccess.decide(client.remoteIP); decision != accessAllowed {
+				g.debugf("Client %s at %s: address %s after reload", client.id, client.remoteIP, decision)
+				client.leave(reasonAddressDenied)
+				closed++
+			}
+		}
+	})
+	log.Printf("Address lists reloaded with %d allowed and %d denied networks, closed %d connections", len(stats.Allow), len(stats.Deny), closed)
+	return nil
+}
+
+// serveIPAccess shows the address lists and decision counters, and reloads
+// the lists on POST. It is for the gateway's operator, not its tenants.
+func (g *Gateway) serveIPAccess(w http.ResponseWriter, r *http.Request) {
+	if tenantScoped(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if g.ipAccess == nil {
+		http.Error(w, errNoIPAccess.Error(), http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := g.ReloadIPAccess(); err != nil {
+			log.Printf("Error reloading address lists: %v", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, g.ipAccess.Stats())
+}
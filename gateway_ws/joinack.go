@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The audio format the reference app records and plays: 16 kHz mono 16-bit
+// PCM
+const (
+	defaultCodec      = "pcm16"
+	defaultSampleRate = 16000
+	defaultChannels   = 1
+)
+
+// audioFormat is what clients are told to send and should expect to hear,
+// or what a client declares it sends. The server relays audio untouched,
+// so it only advertises and checks the format. A field left zero is
+// unstated.
+type audioFormat struct {
+	Codec      string `json:"codec,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	FrameMs    int    `json:"frame_ms,omitempty"`
+}
+
+// ParseAudioFormat checks the audio format settings
+func ParseAudioFormat(codec string, sampleRate, channels, frameMs int) (audioFormat, error) {
+	format := audioFormat{Codec: codec, SampleRate: sampleRate, Channels: channels, FrameMs: frameMs}
+	return format, format.validate()
+}
+
+// validate checks that the format states a codec, sample rate and channel
+// count
+func (f audioFormat) validate() error {
+	switch {
+	case f.Codec == "":
+		return fmt.Errorf("audio codec must not be empty")
+	case f.SampleRate <= 0:
+		return fmt.Errorf("invalid sample rate %d", f.SampleRate)
+	case f.Channels <= 0:
+		return fmt.Errorf("invalid channel count %d", f.Channels)
+	case f.FrameMs < 0:
+		return fmt.Errorf("invalid frame duration %d ms", f.FrameMs)
+	}
+	return nil
+}
+
+// joinedMessage acknowledges a join, to a new connection or by a room
+// switch. It is the first message the client receives in the room, ahead of
+// any replayed audio, so the client can set up its audio pipeline and UI
+// before anything else arrives. After a switch the client's session, and so
+// its resume token, belong to the new room.
+type joinedMessage struct {
+	Type        string `json:"type"`
+	Room        string `json:"room"`
+	ID          string `json:"id"`
+	ResumeToken string `json:"resume_token,omitempty"`
+	Resumed     bool   `json:"resumed"`
+	Protocol    string `json:"protocol"`
+	Version     int    `json:"version"`
+	audioFormat
+	MaxMessageSize int64 `json:"max_message_size"`
+
+	// The other formats a mixed room allows, and whether the client
+	// declared a format outside them and was let in flagged
+	AllowedFormats []audioFormat `json:"allowed_formats,omitempty"`
+	FormatMismatch bool          `json:"format_mismatch,omitempty"`
+
+	// Participants including the client, and whether anyone is holding the
+	// talk floor
+	Clients      int  `json:"clients"`
+	Transmitting bool `json:"transmitting"`
+
+	// Whether the room is under floor control, who holds the floor and
+	// whether a moderator locked it
+	FloorControl bool   `json:"floor_control"`
+	FloorHolder  string `json:"floor_holder,omitempty"`
+	FloorLocked  bool   `json:"floor_locked,omitempty"`
+
+	// Whether the room mixes overlapping talkers, sending audio with no
+	// sender
+	Mixing bool `json:"mixing,omitempty"`
+
+	Moderator bool   `json:"moderator"`
+	Muted     bool   `json:"muted"`
+	Role      string `json:"role"`
+
+	// The client's tags
+	Tags []string `json:"tags,omitempty"`
+
+	// The integrity mode the client's frames are in, if any, and the
+	// capabilities the gateway honors for it
+	Integrity    string   `json:"integrity,omitempty"`
+	Capabilities []string `json:"capabilities"`
+
+	// The VOX thresholds and hold time the client's audio is keyed by, if
+	// it asked for VOX
+	VOX *voxSettings `json:"vox,omitempty"`
+
+	// The codec the client receives the room's pcm16 audio in, if it asked
+	// for another
+	Receive string `json:"receive,omitempty"`
+
+	// The client's tier, full or low, and the sample rate and channel
+	// count it gets the room's audio at
+	Tier              string `json:"tier"`
+	ReceiveSampleRate int    `json:"receive_sample_rate"`
+	ReceiveChannels   int    `json:"receive_channels"`
+
+	// The other clients already in the room, for a client that wants
+	// presence messages, and the roster version they make
+	Present       []presentClient `json:"present,omitempty"`
+	RosterVersion uint64          `json:"roster_version"`
+
+	// The room's recent chat, oldest first
+	Chat []chatMessage `json:"chat,omitempty"`
+}
+
+// acknowledgeJoin queues the joined message for a client being registered,
+// with clients participants once it is in. Only addClient may call it,
+// before the client joins its shard and before any replay is queued, so
+// nothing can get ahead of it.
+func (h *Hub) acknowledgeJoin(client *Client, clients int) {
+	audio := h.audio.Load()
+	var present []presentClient
+	if client.presence {
+		present = h.presentClients(client)
+	}
+	var chat []chatMessage
+	if client.caps.has(capChat) {
+		chat = h.recentChat()
+	}
+	var holder string
+	if floor := h.floorHolder.Load(); floor != nil {
+		holder = floor.id
+	}
+	var vox *voxSettings
+	if gate := client.vox; gate != nil {
+		vox = &gate.voxSettings
+	}
+	var integrity string
+	if client.integrity {
+		integrity = integrityCRC32C
+	}
+	receiveSampleRate, receiveChannels := client.deliveredFormat()
+	data, err := json.Marshal(joinedMessage{
+		Type:              "joined",
+		Room:              localRoom(h.room),
+		ID:                client.id,
+		ResumeToken:       client.resumeToken,
+		Resumed:           client.resumed,
+		Protocol:          client.protocol,
+		Version:           int(client.version.Load()),
+		audioFormat:       audio.audioFormat,
+		MaxMessageSize:    h.maxMessageSize,
+		AllowedFormats:    audio.Allowed,
+		FormatMismatch:    client.formatMismatch,
+		Clients:           clients,
+		Transmitting:      h.activity.transmitting(time.Now()),
+		FloorControl:      h.floorControl.Load(),
+		FloorHolder:       holder,
+		FloorLocked:       h.floorLock.Load(),
+		Mixing:            h.mixing.Load(),
+		Moderator:         client.moderator.Load(),
+		Muted:             client.muted.Load(),
+		Role:              client.role(),
+		Tags:              client.tags,
+		Integrity:         integrity,
+		Capabilities:      client.caps.names(),
+		VOX:               vox,
+		Receive:           client.receive,
+		Tier:              client.tier(),
+		ReceiveSampleRate: receiveSampleRate,
+		ReceiveChannels:   receiveChannels,
+		Present:           present,
+		RosterVersion:     h.rosterVersion,
+		Chat:              chat,
+	})
+	if err != nil {
+		log.Printf("Error encoding joined message for client %s: %v", client.id, err)
+		return
+	}
+	// The ack is the first message queued for the client, so this only
+	// fails for a send buffer with no room at all. It takes a slot a
+	// resumed client's replay then can't use.
+	select {
+	case client.send <- outbound{messageType: websocket.TextMessage, data: data}:
+	default:
+		client.droppedOutbound.Add(1)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// roomCredential is what a room requires of joining clients: a shared
+// secret, or a join token signed with the gateway's key
+type roomCredential struct {
+	secret string
+	signed bool
+}
+
+// roomCredentials maps room names to their credentials. Rooms without one
+// are open.
+type roomCredentials map[string]roomCredential
+
+// ParseRoomCredentials parses a comma-separated list of room=secret:<secret>
+// and room=token entries
+func ParseRoomCredentials(list string) (roomCredentials, error) {
+	credentials := roomCredentials{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, kind, ok := strings.Cut(entry, "=")
+		if !ok || !validRoomName(name) {
+			return nil, fmt.Errorf("invalid room credential %q: want room=secret:<secret> or room=token", entry)
+		}
+		switch {
+		case kind == "token":
+			credentials[name] = roomCredential{signed: true}
+		case strings.HasPrefix(kind, "secret:") && len(kind) > len("secret:"):
+			credentials[name] = roomCredential{secret: strings.TrimPrefix(kind, "secret:")}
+		default:
+			return nil, fmt.Errorf("invalid room credential %q: want room=secret:<secret> or room=token", entry)
+		}
+	}
+	return credentials, nil
+}
+
+// WithRoomCredentials makes the rooms named in credentials require them to
+// join, verifying signed join tokens with key
+func WithRoomCredentials(credentials roomCredentials, key []byte) GatewayOption {
+	return func(g *Gateway) {
+		for name, credential := range credentials {
+			credential := credential
+			g.configureRoom(name, func(config *RoomConfig) {
+				config.Secret = credential.secret
+				config.RequireToken = credential.signed
+			})
+		}
+		g.joinKey = key
+	}
+}
+
+// joinClaims is the signed part of a join token
+type joinClaims struct {
+	Room      string `json:"room"`
+	Expires   int64  `json:"exp"`
+	ClientID  string `json:"client_id,omitempty"`
+	Moderator bool   `json:"moderator,omitempty"`
+}
+
+// joinCredential returns the secret or token the client presented with the
+// join_token query parameter or X-Join-Token header
+func joinCredential(r *http.Request) string {
+	if value := r.URL.Query().Get("join_token"); value != "" {
+		return value
+	}
+	return r.Header.Get("X-Join-Token")
+}
+
+// verifyJoinToken checks that token is base64url(claims) "." base64url(mac),
+// where mac is the HMAC-SHA256 of the encoded claims under key, and that the
+// claims admit clientID to room at now. It returns the claims, and why the
+// token is refused or "" if it isn't.
+func verifyJoinToken(token string, key []byte, room, clientID string, now time.Time) (joinClaims, string) {
+	var claims joinClaims
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, "malformed token"
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return claims, "malformed token"
+	}
+	expected := hmac.New(sha256.New, key)
+	expected.Write([]byte(payload))
+	if !hmac.Equal(mac, expected.Sum(nil)) {
+		return claims, "bad signature"
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return claims, "malformed token"
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return claims, "malformed token"
+	}
+	switch {
+	case claims.Room != room:
+		return claims, "token is for another room"
+	case !now.Before(time.Unix(claims.Expires, 0)):
+		return claims, "token expired"
+	case claims.ClientID != "" && claims.ClientID != clientID:
+		return claims, "token is for another client"
+	}
+	return claims, ""
+}
+
+// admitToRoom checks the request's join credential with checkCredential. ok
+// is false, having answered 403, if the room refuses it.
+func (g *Gateway) admitToRoom(w http.ResponseWriter, r *http.Request, room, clientID string) (moderator, ok bool) {
+	moderator, reason := g.checkCredential(room, clientID, joinCredential(r))
+	if reason != "" {
+		g.auditRefused(r, clientID, room, reason)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false, false
+	}
+	return moderator, true
+}
+
+// checkCredential checks a presented join credential against the room's and
+// reports whether it makes the client a moderator, or why the room refuses
+// it. The room's moderator secret admits anywhere; otherwise secret rooms
+// take their secret, and token and open rooms take join tokens. An open room
+// admits clients whose credential is missing or invalid, as plain
+// participants. Refusals are logged and counted.
+func (g *Gateway) checkCredential(room, clientID, presented string) (moderator bool, refused string) {
+	config := g.roomConfig(room)
+	var reason string
+	switch {
+	case presented == "":
+		reason = "missing credential"
+	case config.ModeratorSecret != "" && secretMatches(presented, config.ModeratorSecret):
+		return true, ""
+	case config.Secret != "":
+		if !secretMatches(presented, config.Secret) {
+			reason = "wrong secret"
+		}
+	case len(g.joinKey) > 0:
+		var claims joinClaims
+		claims, reason = verifyJoinToken(presented, g.joinKey, room, clientID, time.Now())
+		moderator = reason == "" && claims.Moderator
+	default:
+		reason = "unexpected credential"
+	}
+	if reason == "" || !config.guarded() {
+		return moderator, ""
+	}
+
+	g.mutex.Lock()
+	g.joinDenied[room]++
+	g.mutex.Unlock()
+	log.Printf("Client %s refused from room %s: %s", clientID, room, reason)
+	return false, reason
+}
+
+// secretMatches compares a presented secret in constant time
+func secretMatches(presented, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(secret)) == 1
+}
+
+// deniedJoins returns the joins refused per room that requires a credential
+func (g *Gateway) deniedJoins() map[string]uint64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	denied := make(map[string]uint64)
+	for name, config := range g.roomConfigs {
+		if config.guarded() {
+			denied[name] = g.joinDenied[name]
+		}
+	}
+	return denied
+}
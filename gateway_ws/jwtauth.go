@@ -0,0 +1,499 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultJWTLeeway is the clock skew tolerated on a bearer token's exp
+	// and nbf
+	defaultJWTLeeway = 30 * time.Second
+
+	// The key set is refetched when it is older than jwksRefresh, or holds
+	// no key for a token's kid, but at most once per jwksMinRefetch so bad
+	// tokens can't hammer the key server
+	jwksRefresh    = 15 * time.Minute
+	jwksMinRefetch = time.Minute
+	jwksTimeout    = 5 * time.Second
+)
+
+// Reasons a bearer token is refused with 401
+var (
+	errTokenMissing     = errors.New("missing bearer token")
+	errTokenMalformed   = errors.New("malformed token")
+	errTokenAlgorithm   = errors.New("unsupported signing algorithm")
+	errTokenSignature   = errors.New("bad signature")
+	errTokenExpired     = errors.New("token expired")
+	errTokenNotYetValid = errors.New("token not yet valid")
+	errTokenIssuer      = errors.New("wrong issuer")
+	errTokenAudience    = errors.New("wrong audience")
+	errTokenClaims      = errors.New("invalid claims")
+)
+
+// tokenClaims is what a verified bearer token says about its client: its
+// ID, the rooms it may join, nil for any, and whether it moderates them
+type tokenClaims struct {
+	Subject   string
+	Rooms     []string
+	Moderator bool
+	Listener  bool
+
+	// Whether the client joins its rooms muted
+	Muted bool
+
+	// The client's tags, if the token sets them, and its display name
+	Tags []string
+	Name string
+
+	// The key the client signs its audio frames with, if the token gives one
+	FrameKey []byte
+}
+
+// allows reports whether the claims let the client into the room. A rooms
+// entry is a room name, or a prefix ending in '*'.
+func (c *tokenClaims) allows(room string) bool {
+	if c == nil || c.Rooms == nil {
+		return true
+	}
+	for _, pattern := range c.Rooms {
+		if pattern == room {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(room, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtHeader is the part of a token's header that says how it was signed
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwtPayload is a token's claims. Rooms, role, tags and frame_key are ours;
+// name is OpenID Connect's; the rest are registered claims.
+type jwtPayload struct {
+	Subject   string    `json:"sub"`
+	Issuer    string    `json:"iss"`
+	Audience  audience  `json:"aud"`
+	Expires   *float64  `json:"exp"`
+	NotBefore *float64  `json:"nbf"`
+	Rooms     *[]string `json:"rooms"`
+	Role      string    `json:"role"`
+	Tags      *[]string `json:"tags"`
+	Name      string    `json:"name"`
+	FrameKey  string    `json:"frame_key"`
+	Muted     bool      `json:"muted"`
+}
+
+// audience is the aud claim, which may be one string or a list of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or a list of strings")
+	}
+	*a = many
+	return nil
+}
+
+// jwtVerifier checks bearer tokens signed with an HMAC secret, HS256 to
+// HS512, or with a key from a JWKS, RS256 to RS512 and ES256 to ES512
+type jwtVerifier struct {
+	secret   []byte
+	keys     *jwks
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// WithJWTAuth makes every upgrade present a bearer token signed with secret
+// or a key from the JWKS at jwksURL, from issuer and for audience when they
+// are set, allowing leeway for clock skew. Without a secret or a URL,
+// upgrades need no token.
+func WithJWTAuth(secret []byte, jwksURL, issuer, audience string, leeway time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		if len(secret) == 0 && jwksURL == "" {
+			return
+		}
+		v := &jwtVerifier{secret: secret, issuer: issuer, audience: audience, leeway: leeway}
+		if jwksURL != "" {
+			v.keys = &jwks{url: jwksURL, client: &http.Client{Timeout: jwksTimeout}}
+		}
+		g.jwt = v
+	}
+}
+
+// bearerToken returns the token from the Authorization header, or from the
+// token query parameter as browsers can't set headers on a WebSocket
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// verify checks the token's signature and claims at now
+func (v *jwtVerifier) verify(token string, now time.Time) (*tokenClaims, error) {
+	if token == "" {
+		return nil, errTokenMissing
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errTokenMalformed
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errTokenMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errTokenMalformed
+	}
+	if err := v.checkSignature(header, parts[0]+"."+parts[1], signature, now); err != nil {
+		return nil, err
+	}
+
+	var payload jwtPayload
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", errTokenClaims, err)
+	}
+	return v.checkClaims(payload, now)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkSignature verifies the signature over signed with the key the
+// header's algorithm and key ID pick
+func (v *jwtVerifier) checkSignature(header jwtHeader, signed string, signature []byte, now time.Time) error {
+	if len(header.Algorithm) != 5 {
+		return errTokenAlgorithm
+	}
+	var newHash func() hash.Hash
+	var hashID crypto.Hash
+	switch header.Algorithm[2:] {
+	case "256":
+		newHash, hashID = sha256.New, crypto.SHA256
+	case "384":
+		newHash, hashID = sha512.New384, crypto.SHA384
+	case "512":
+		newHash, hashID = sha512.New, crypto.SHA512
+	default:
+		return errTokenAlgorithm
+	}
+
+	family := header.Algorithm[:2]
+	if family == "HS" {
+		if len(v.secret) == 0 {
+			return errTokenAlgorithm
+		}
+		mac := hmac.New(newHash, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errTokenSignature
+		}
+		return nil
+	}
+	if (family != "RS" && family != "ES") || v.keys == nil {
+		return errTokenAlgorithm
+	}
+
+	key, err := v.keys.key(header.KeyID, now)
+	if err != nil {
+		return err
+	}
+	digest := newHash()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if family != "RS" || rsa.VerifyPKCS1v15(key, hashID, sum, signature) != nil {
+			return errTokenSignature
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are r and s, each the size of the curve
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if family != "ES" || key.Curve.Params().BitSize != curveBits[header.Algorithm] || len(signature) != 2*size {
+			return errTokenSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, sum, r, s) {
+			return errTokenSignature
+		}
+	default:
+		return errTokenSignature
+	}
+	return nil
+}
+
+// curveBits is the curve each ES algorithm signs with
+var curveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// checkClaims checks a signed token's claims and returns what they say
+// about the client. Tokens must carry sub and exp.
+func (v *jwtVerifier) checkClaims(payload jwtPayload, now time.Time) (*tokenClaims, error) {
+	switch {
+	case payload.Subject == "":
+		return nil, fmt.Errorf("%w: missing sub", errTokenClaims)
+	case payload.Expires == nil:
+		return nil, fmt.Errorf("%w: missing exp", errTokenClaims)
+	case !now.Add(-v.leeway).Before(unixTime(*payload.Expires)):
+		return nil, errTokenExpired
+	case payload.NotBefore != nil && now.Add(v.leeway).Before(unixTime(*payload.NotBefore)):
+		return nil, errTokenNotYetValid
+	case v.issuer != "" && payload.Issuer != v.issuer:
+		return nil, errTokenIssuer
+	case v.audience != "" && !payload.Audience.contains(v.audience):
+		return nil, errTokenAudience
+	}
+
+	claims := &tokenClaims{Subject: payload.Subject, Muted: payload.Muted}
+	switch payload.Role {
+	case "", "participant":
+	case "moderator":
+		claims.Moderator = true
+	case roleListener:
+		claims.Listener = true
+	default:
+		return nil, fmt.Errorf("%w: unknown role %q", errTokenClaims, payload.Role)
+	}
+	if payload.FrameKey != "" {
+		key, err := base64.StdEncoding.DecodeString(payload.FrameKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: frame_key is not base64", errTokenClaims)
+		}
+		claims.FrameKey = key
+	}
+	if payload.Rooms != nil {
+		// An empty list allows no room at all, unlike a missing one
+		claims.Rooms = append([]string{}, *payload.Rooms...)
+	}
+	if !validDisplayName(payload.Name) {
+		return nil, fmt.Errorf("%w: invalid name", errTokenClaims)
+	}
+	claims.Name = payload.Name
+	if payload.Tags != nil {
+		tags, err := parseTags(*payload.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errTokenClaims, err)
+		}
+		claims.Tags = tags
+	}
+	return claims, nil
+}
+
+// unixTime converts a NumericDate claim
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// contains reports whether the audience names the given one
+func (a audience) contains(name string) bool {
+	for _, aud := range a {
+		if aud == name {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate verifies the request's bearer token when the gateway
+// requires one. ok is false, having answered 401, if the token is refused;
+// claims is nil if the gateway requires none.
+func (g *Gateway) authenticate(w http.ResponseWriter, r *http.Request) (claims *tokenClaims, ok bool) {
+	if g.jwt == nil {
+		return nil, true
+	}
+	claims, err := g.jwt.verify(bearerToken(r), time.Now())
+	if err == nil {
+		return claims, true
+	}
+
+	g.unauthorized.Add(1)
+	log.Printf("Upgrade from %s refused: %v", r.RemoteAddr, err)
+	g.auditRefused(r, r.Header.Get("X-Client-ID"), "", "unauthorized: "+err.Error())
+	if errors.Is(err, errTokenMissing) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="walkie"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="walkie", error="invalid_token", error_description=%q`, err.Error()))
+	}
+	http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+	return nil, false
+}
+
+// jwks caches the signing keys published at a JWKS URL, by key ID
+type jwks struct {
+	url    string
+	client *http.Client
+
+	mutex   sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	loaded  time.Time
+}
+
+// key returns the key with the given ID, refetching the set if it is stale
+// or lacks the key. A token without a kid may use a set's only key.
+func (s *jwks) key(id string, now time.Time) (crypto.PublicKey, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key, ok := s.lookup(id)
+	if (!ok || now.Sub(s.loaded) > jwksRefresh) && now.Sub(s.fetched) >= jwksMinRefetch {
+		s.fetched = now
+		keys, err := s.fetch()
+		if err != nil {
+			// Keep the keys we have until the server is back
+			log.Printf("Error fetching JWKS %s: %v", s.url, err)
+		} else {
+			s.keys, s.loaded = keys, now
+			key, ok = s.lookup(id)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", errTokenSignature, id)
+	}
+	return key, nil
+}
+
+// lookup finds a key in the cached set. The caller must hold the mutex.
+func (s *jwks) lookup(id string) (crypto.PublicKey, bool) {
+	if key, ok := s.keys[id]; ok {
+		return key, true
+	}
+	if id == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// jsonWebKey is a key in a JWKS, RSA or EC
+type jsonWebKey struct {
+	Type  string `json:"kty"`
+	ID    string `json:"kid"`
+	Use   string `json:"use"`
+	N     string `json:"n"`
+	E     string `json:"e"`
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// fetch downloads the key set. Keys of other types, or not for signatures,
+// are skipped.
+func (s *jwks) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", jwk.ID, err)
+			continue
+		}
+		if key != nil {
+			keys[jwk.ID] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, or returns nil for a type verify doesn't use
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(field string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(field)
+		if err != nil || len(data) == 0 {
+			return nil, errors.New("bad key parameter")
+		}
+		return new(big.Int).SetBytes(data), nil
+	}
+
+	switch k.Type {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
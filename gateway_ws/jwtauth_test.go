@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testJWTSecret = []byte("test secret")
+
+// signToken returns an HS256 token carrying the claims, signed with secret
+func signToken(t testing.TB, secret []byte, claims map[string]any) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validClaims returns claims the test verifier accepts at now, with the
+// changes applied; a nil value deletes the claim
+func validClaims(now time.Time, changes map[string]any) map[string]any {
+	claims := map[string]any{
+		"sub": "alice",
+		"iss": "walkie",
+		"aud": "gateway",
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Now()
+	v := &jwtVerifier{secret: testJWTSecret, issuer: "walkie", audience: "gateway", leeway: 30 * time.Second}
+	tests := []struct {
+		name   string
+		token  string
+		err    error
+		claims *tokenClaims
+	}{
+		{"valid", signToken(t, testJWTSecret, validClaims(now, nil)), nil, &tokenClaims{Subject: "alice"}},
+		{"audience list", signToken(t, testJWTSecret, validClaims(now, map[string]any{"aud": []string{"other", "gateway"}})), nil, &tokenClaims{Subject: "alice"}},
+		{"rooms and role", signToken(t, testJWTSecret, validClaims(now, map[string]any{"rooms": []string{"ops", "fire-*"}, "role": "moderator"})), nil,
+			&tokenClaims{Subject: "alice", Rooms: []string{"ops", "fire-*"}, Moderator: true}},
+		{"listener", signToken(t, testJWTSecret, validClaims(now, map[string]any{"role": "listener"})), nil, &tokenClaims{Subject: "alice", Listener: true}},
+		{"expired", signToken(t, testJWTSecret, validClaims(now, map[string]any{"exp": now.Add(-time.Minute).Unix()})), errTokenExpired, nil},
+		{"expired within leeway", signToken(t, testJWTSecret, validClaims(now, map[string]any{"exp": now.Add(-10 * time.Second).Unix()})), nil, &tokenClaims{Subject: "alice"}},
+		{"not yet valid", signToken(t, testJWTSecret, validClaims(now, map[string]any{"nbf": now.Add(time.Minute).Unix()})), errTokenNotYetValid, nil},
+		{"not yet valid within leeway", signToken(t, testJWTSecret, validClaims(now, map[string]any{"nbf": now.Add(10 * time.Second).Unix()})), nil, &tokenClaims{Subject: "alice"}},
+		{"wrong audience", signToken(t, testJWTSecret, validClaims(now, map[string]any{"aud": "billing"})), errTokenAudience, nil},
+		{"missing audience", signToken(t, testJWTSecret, validClaims(now, map[string]any{"aud": nil})), errTokenAudience, nil},
+		{"wrong issuer", signToken(t, testJWTSecret, validClaims(now, map[string]any{"iss": "elsewhere"})), errTokenIssuer, nil},
+		{"missing sub", signToken(t, testJWTSecret, validClaims(now, map[string]any{"sub": nil})), errTokenClaims, nil},
+		{"missing exp", signToken(t, testJWTSecret, validClaims(now, map[string]any{"exp": nil})), errTokenClaims, nil},
+		{"unknown role", signToken(t, testJWTSecret, validClaims(now, map[string]any{"role": "admin"})), errTokenClaims, nil},
+		{"aud not a string", signToken(t, testJWTSecret, validClaims(now, map[string]any{"aud": 7})), errTokenClaims, nil},
+		{"wrong secret", signToken(t, []byte("other secret"), validClaims(now, nil)), errTokenSignature, nil},
+		{"unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.", errTokenAlgorithm, nil},
+		{"malformed", "not.a-token", errTokenMalformed, nil},
+		{"missing", "", errTokenMissing, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.verify(tt.token, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if tt.claims == nil {
+				return
+			}
+			if claims.Subject != tt.claims.Subject || claims.Moderator != tt.claims.Moderator || claims.Listener != tt.claims.Listener ||
+				strings.Join(claims.Rooms, ",") != strings.Join(tt.claims.Rooms, ",") {
+				t.Errorf("got claims %+v, want %+v", claims, tt.claims)
+			}
+		})
+	}
+}
+
+// The upgrade needs a valid token, from the Authorization header or the
+// token query parameter; the token's subject becomes the client ID and its
+// rooms claim limits where the client may go
+func TestJWTUpgrade(t *testing.T) {
+	now := time.Now()
+	tg := newTestGateway(t, nil, WithJWTAuth(testJWTSecret, "", "walkie", "gateway", time.Second))
+	valid := signToken(t, testJWTSecret, validClaims(now, map[string]any{"rooms": []string{"ops"}}))
+	expired := signToken(t, testJWTSecret, validClaims(now, map[string]any{"exp": now.Add(-time.Minute).Unix()}))
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{"no token", "/ws/ops", "", http.StatusUnauthorized},
+		{"expired", "/ws/ops?token=" + expired, "", http.StatusUnauthorized},
+		{"expired header", "/ws/ops", "Bearer " + expired, http.StatusUnauthorized},
+		{"room not allowed", "/ws/other?token=" + valid, "", http.StatusForbidden},
+		{"query", "/ws/ops?token=" + valid, "", http.StatusSwitchingProtocols},
+		{"header", "/ws/ops", "Bearer " + valid, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Authorization", tt.header)
+			}
+			conn, resp, err := tg.tryDial(tt.path, "mallory", header)
+			if resp == nil {
+				t.Fatalf("dialing: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got %s, want %d", resp.Status, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("WWW-Authenticate %q", resp.Header.Get("WWW-Authenticate"))
+			}
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			readControl(t, conn, "joined")
+			hub := tg.hub(t, "ops")
+			if hub.lookup("alice") == nil || hub.lookup("mallory") != nil {
+				t.Error("client not registered under its token's subject")
+			}
+			conn.Close()
+			waitFor(t, "the client to leave", func() bool { return hub.lookup("alice") == nil })
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// defaultKeyExchangeBytes caps a key exchange message's payload
+	defaultKeyExchangeBytes = 4096
+
+	// defaultKeyExchangeRate and defaultKeyExchangeBurst are how many key
+	// exchange messages a connection may send a second, sustained and at
+	// once
+	defaultKeyExchangeRate  = 5
+	defaultKeyExchangeBurst = 20
+
+	// keyExchangeAll addresses every other client in the room
+	keyExchangeAll = "all"
+)
+
+// keyExchangeLimits caps the key exchange messages each connection sends
+type keyExchangeLimits struct {
+	maxBytes  int
+	perSecond float64
+	burst     int
+}
+
+// WithKeyExchangeLimits caps the payload of a key exchange message at
+// maxBytes, and each connection to perSecond of them, sustained, in bursts
+// of up to burst. Zero leaves a setting at its default.
+func WithKeyExchangeLimits(maxBytes int, perSecond float64, burst int) GatewayOption {
+	return func(g *Gateway) {
+		if maxBytes > 0 {
+			g.keyExchange.maxBytes = maxBytes
+		}
+		if perSecond > 0 {
+			g.keyExchange.perSecond = perSecond
+		}
+		if burst > 0 {
+			g.keyExchange.burst = burst
+		}
+	}
+}
+
+// keyExchangeMessage relays a key exchange payload to its target. From is
+// the sender as the server knows it, whatever the payload claims.
+type keyExchangeMessage struct {
+	Type    string          `json:"type"`
+	Room    string          `json:"room"`
+	From    string          `json:"from"`
+	Payload json.RawMessage `json:"payload"`
+
+	// Set if the sender asked for acknowledgement
+	ID  string `json:"id,omitempty"`
+	Ack bool   `json:"ack,omitempty"`
+}
+
+// keyx names its target and carries a payload
+func init() {
+	registerControl("keyx", controlHandler{
+		validate: func(m controlMessage) error {
+			switch {
+			case m.Target == "":
+				return errors.New("missing target")
+			case len(m.Payload) == 0:
+				return errors.New("missing payload")
+			case m.Ack && m.ID == "":
+				return errors.New("missing id")
+			}
+			return nil
+		},
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				c.keyExchange(member, m)
+			}
+		},
+	})
+}
+
+// keyExchange relays a key exchange payload from the client, a member of
+// the connection c, to message.Target in its room, or to everyone else in
+// it for "all", each acknowledging it if the sender asked. The server never
+// looks inside the payload. Only readPump may call it.
+func (c *Client) keyExchange(sender *Client, message controlMessage) {
+	limits := c.gateway.keyExchange
+	if len(message.Payload) > limits.maxBytes {
+		sender.hub.keyExchangesRefused.Add(1)
+		sender.fail("too_large", "keyx", message.Target)
+		return
+	}
+
+	// The bucket belongs to the connection, so memberships can't multiply
+	// it
+	size := burst(limits.perSecond, limits.burst)
+	now := time.Now()
+	if c.keyExchanges.last.IsZero() {
+		c.keyExchanges = tokenBucket{tokens: size, last: now}
+	}
+	c.keyExchanges.refill(limits.perSecond, size, now)
+	if c.keyExchanges.tokens < 1 {
+		sender.hub.keyExchangesRefused.Add(1)
+		sender.fail("rate_limited", "keyx", message.Target)
+		return
+	}
+	c.keyExchanges.tokens--
+
+	hub := sender.hub
+	relayed := keyExchangeMessage{Type: "keyx", Room: localRoom(hub.room), From: sender.id, Payload: message.Payload}
+	relay := hub.sendControl
+	if message.Ack {
+		relayed.ID, relayed.Ack = message.ID, true
+		relay = func(target *Client, relayed interface{}) {
+			hub.relayAcked(sender, target, "keyx", message.ID, relayed)
+		}
+	}
+	if message.Target != keyExchangeAll {
+		target := hub.lookup(message.Target)
+		if target == nil {
+			hub.keyExchangesRefused.Add(1)
+			sender.fail("not_found", "keyx", message.Target)
+			return
+		}
+		relay(target, relayed)
+		hub.keyExchanges.Add(1)
+		return
+	}
+
+	delivered := 0
+	for _, client := range hub.snapshotClients() {
+		if client == sender {
+			continue
+		}
+		relay(client, relayed)
+		delivered++
+	}
+	if delivered == 0 {
+		hub.keyExchangesRefused.Add(1)
+		sender.fail("not_found", "keyx", message.Target)
+		return
+	}
+	hub.keyExchanges.Add(1)
+	c.gateway.debugf("Client %s sent a key exchange to %d clients in room %s", sender.id, delivered, hub.room)
+}
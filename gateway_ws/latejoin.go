@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Someone joining mid-incident has missed what was said. With a late-join
+// window each room keeps the audio it broadcast over the window, up to a
+// byte cap, and a client that joins with ?replay=true gets it first, marked
+// as replayed, then the live audio. The hub snapshots the buffer in Run as
+// the client joins, so the replay ends with the frame before the first the
+// client gets live. Live audio arriving while the replay is paced out is
+// held for the client and follows it.
+const (
+	defaultLateJoinBytes = 2 << 20
+
+	// defaultReplaySpeed is how much faster than real time a replay is
+	// paced unless the client asks otherwise, and maxReplaySpeed the most
+	// it may ask for short of replaySpeedMax
+	defaultReplaySpeed = 2
+	maxReplaySpeed     = 16
+
+	// replaySpeedMax dumps the replay as fast as the socket takes it
+	replaySpeedMax = "max"
+
+	// replayPoll is how often a replay waiting on a full send buffer looks
+	// again
+	replayPoll = 10 * time.Millisecond
+)
+
+// recentFrame is a broadcast audio frame kept for late joiners, holding a
+// reference on its frame
+type recentFrame struct {
+	message outbound
+	sender  *Client
+	at      time.Time
+	size    int
+}
+
+// recentAudio is a room's late-join buffer: its broadcast audio over the
+// window, oldest first, capped at maxBytes. Only Run touches the frames;
+// the counts are read for stats. A nil buffer disables late-join replay.
+type recentAudio struct {
+	window   time.Duration
+	maxBytes int
+
+	frames []recentFrame
+	size   int
+
+	bytes    atomic.Int64
+	count    atomic.Int64
+	replays  atomic.Uint64
+	replayed atomic.Uint64
+}
+
+// WithLateJoin keeps each room's broadcast audio over the last window, up
+// to maxBytes, for clients that join with ?replay=true. A window of zero
+// disables it.
+func WithLateJoin(window time.Duration, maxBytes int) HubOption {
+	return func(h *Hub) {
+		if window <= 0 {
+			h.recent = nil
+			return
+		}
+		if maxBytes <= 0 {
+			maxBytes = defaultLateJoinBytes
+		}
+		h.recent = &recentAudio{window: window, maxBytes: maxBytes}
+	}
+}
+
+// frameSize returns the memory a kept frame pins: its audio and that of
+// its renditions
+func frameSize(frame *frameBuffer) int {
+	size := len(frame.data)
+	for _, encoded := range frame.renditions {
+		if encoded.frame != nil {
+			size += len(encoded.frame.data)
+		}
+	}
+	return size
+}
+
+// buffer keeps a broadcast audio frame, taking a reference on it, and drops
+// what has fallen out of the window or past the cap. Only the Run
+// goroutine may call it.
+func (b *recentAudio) buffer(message BroadcastMessage, now time.Time) {
+	if b == nil || message.eot || message.frame.messageType != websocket.BinaryMessage {
+		return
+	}
+	message.frame.retain()
+	size := frameSize(message.frame)
+	b.frames = append(b.frames, recentFrame{message: message.outbound(), sender: message.sender, at: now, size: size})
+	b.size += size
+	b.trim(now)
+}
+
+// trim drops the frames older than the window, and the oldest beyond the
+// cap. Only the Run goroutine may call it.
+func (b *recentAudio) trim(now time.Time) {
+	n := 0
+	for n < len(b.frames) && (now.Sub(b.frames[n].at) > b.window || b.size > b.maxBytes) {
+		b.frames[n].message.release()
+		b.size -= b.frames[n].size
+		b.frames[n] = recentFrame{}
+		n++
+	}
+	b.frames = b.frames[n:]
+	b.bytes.Store(int64(b.size))
+	b.count.Store(int64(len(b.frames)))
+}
+
+// snapshot returns the frames in the window, each with a reference of its
+// own for the caller. Only the Run goroutine may call it.
+func (b *recentAudio) snapshot(now time.Time) []recentFrame {
+	b.trim(now)
+	frames := make([]recentFrame, len(b.frames))
+	copy(frames, b.frames)
+	for _, f := range frames {
+		f.message.frame.retain()
+	}
+	return frames
+}
+
+// clear drops every kept frame. Only the Run goroutine may call it.
+func (b *recentAudio) clear() {
+	if b == nil {
+		return
+	}
+	for _, f := range b.frames {
+		f.message.release()
+	}
+	b.frames, b.size = nil, 0
+	b.bytes.Store(0)
+	b.count.Store(0)
+}
+
+// requestedReplay reads whether the client asked for the room's recent
+// audio on joining, with ?replay=true, and how fast: ?replay_speed= times
+// real time, or max for as fast as the socket allows, which is 0
+func requestedReplay(r *http.Request, hub *Hub) (bool, float64, error) {
+	query := r.URL.Query()
+	if query.Get("replay") == "" {
+		return false, 0, nil
+	}
+	replay, err := strconv.ParseBool(query.Get("replay"))
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid replay %q", query.Get("replay"))
+	}
+	if !replay {
+		return false, 0, nil
+	}
+	if hub.recent == nil {
+		return false, 0, fmt.Errorf("late-join replay is disabled; start the gateway with -late-join-window")
+	}
+	speed := query.Get("replay_speed")
+	switch {
+	case speed == "":
+		return true, defaultReplaySpeed, nil
+	case strings.EqualFold(speed, replaySpeedMax):
+		return true, 0, nil
+	}
+	factor, err := strconv.ParseFloat(speed, 64)
+	if err != nil || factor <= 1 || factor > maxReplaySpeed {
+		return false, 0, fmt.Errorf("invalid replay_speed %q: want more than 1 and at most %d, or %s", speed, maxReplaySpeed, replaySpeedMax)
+	}
+	return true, factor, nil
+}
+
+// replayMessage brackets a late-join replay: state start before the
+// replayed frames, saying how many and since when in Unix milliseconds,
+// and live once they are through, saying how many were sent
+type replayMessage struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	State  string `json:"state"`
+	Frames int    `json:"frames"`
+	Since  int64  `json:"since,omitempty"`
+}
+
+// catchUp is a late joiner's replay in progress: the live audio the room
+// broadcast since it joined, held until the replay is through
+type catchUp struct {
+	speed float64
+
+	mutex sync.Mutex
+	held  []outbound
+	size  int
+	done  bool
+}
+
+// holding holds a live broadcast for the client if its replay is still
+// going, taking a reference on it, and reports whether it did. Past the
+// room's late-join cap the oldest held frame is dropped.
+func (c *Client) holding(message outbound) bool {
+	catchUp := c.catchUp
+	if catchUp == nil {
+		return false
+	}
+	catchUp.mutex.Lock()
+	defer catchUp.mutex.Unlock()
+	if catchUp.done {
+		return false
+	}
+	message.retain()
+	catchUp.held = append(catchUp.held, message)
+	catchUp.size += frameSize(message.frame)
+	for len(catchUp.held) > 1 && catchUp.size > c.hub.recent.maxBytes {
+		catchUp.size -= frameSize(catchUp.held[0].frame)
+		catchUp.held[0].release()
+		catchUp.held[0] = outbound{}
+		catchUp.held = catchUp.held[1:]
+		c.droppedOutbound.Add(1)
+	}
+	return true
+}
+
+// retain adds a reference to the message's frame buffer, if any
+func (m outbound) retain() {
+	if m.frame != nil {
+		m.frame.retain()
+	}
+}
+
+// startCatchUp snapshots the room's recent audio for a client that asked
+// for it, for replayRecent once the client is in its shard, so the client
+// no longer gets the frames in it live and holds what comes after. Only
+// the Run goroutine may call it, before the client joins its shard.
+func (h *Hub) startCatchUp(client *Client, now time.Time) []recentFrame {
+	client.catchUp = &catchUp{speed: client.replaySpeed}
+	client.replayedThrough = max(client.replayedThrough, h.broadcastSeq)
+	h.recent.replays.Add(1)
+	return h.recent.snapshot(now)
+}
+
+// replayRecent delivers the client's snapshot of the room's recent audio,
+// paced at its replay speed and never faster than its send buffer drains,
+// then the live audio held meanwhile, and hands the client over to the
+// live audio. It stops if the client leaves.
+func (h *Hub) replayRecent(client *Client, frames []recentFrame) {
+	speed := client.catchUp.speed
+	room := localRoom(h.room)
+	var since int64
+	if len(frames) > 0 {
+		since = frames[0].at.UnixMilli()
+	}
+	h.sendControl(client, replayMessage{Type: "replay", Room: room, State: "start", Frames: len(frames), Since: since})
+
+	start := time.Now()
+	sent := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for i, f := range frames {
+		if !h.waitReplay(client, timer, start, frames[0].at, f.at, speed) {
+			for _, rest := range frames[i:] {
+				rest.message.release()
+			}
+			h.endCatchUp(client)
+			return
+		}
+		message := f.message
+		message.replayed = true
+		if (f.sender == nil || f.sender.id != client.id) && !client.filters(message, f.sender) {
+			if h.shardFor(client).send(h, client, message) {
+				sent++
+			}
+		}
+		f.message.release()
+	}
+	h.recent.replayed.Add(uint64(sent))
+	h.sendControl(client, replayMessage{Type: "replay", Room: room, State: "live", Frames: sent})
+	h.endCatchUp(client)
+	log.Printf("Client %s caught up on room %s, replaying %d frames", client.id, h.room, sent)
+}
+
+// waitReplay waits until a replayed frame received at is due, after the
+// first received at first was sent at start, and there is room for it in
+// the client's send buffer. It reports false if the client left.
+func (h *Hub) waitReplay(client *Client, timer *time.Timer, start, first, at time.Time, speed float64) bool {
+	wait := time.Duration(0)
+	if speed > 0 {
+		wait = time.Until(start.Add(time.Duration(float64(at.Sub(first)) / speed)))
+	}
+	for {
+		if wait <= 0 && len(client.send) < cap(client.send)/2 {
+			return true
+		}
+		if wait <= 0 {
+			wait = replayPoll
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-client.writeDone:
+			return false
+		case <-timer.C:
+		}
+		wait = 0
+	}
+}
+
+// endCatchUp delivers the live audio held for the client during its
+// replay, oldest first, until none is left, and then lets it through
+// unheld
+func (h *Hub) endCatchUp(client *Client) {
+	catchUp := client.catchUp
+	for {
+		catchUp.mutex.Lock()
+		held := catchUp.held
+		catchUp.held, catchUp.size = nil, 0
+		if len(held) == 0 {
+			catchUp.done = true
+		}
+		catchUp.mutex.Unlock()
+		if len(held) == 0 {
+			return
+		}
+		// Delivered outside the mutex: a fan-out worker takes it while
+		// holding its shard's read lock
+		for _, message := range held {
+			h.shardFor(client).send(h, client, message)
+			message.release()
+		}
+	}
+}
+
+// writeReplayed writes a replayed audio frame to a walkie.json.v1 or
+// walkie.pb.v1 client with its envelope marked, tagged with room for a
+// multi-room client. The frame's cached encodings are of the live audio,
+// so it is encoded afresh.
+func writeReplayed(conn *websocket.Conn, protocol, room string, frame *frameBuffer) error {
+	if protocol == protocolPB {
+		data, err := audioToPB(room, frame, true)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.BinaryMessage, data)
+	}
+	envelope := frame.envelope(room)
+	envelope.Replay = true
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// frameCount returns the number of frames kept, for stats
+func (b *recentAudio) frameCount() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.count.Load())
+}
+
+// byteCount returns the bytes kept, for stats
+func (b *recentAudio) byteCount() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.bytes.Load()
+}
+
+// capacity returns the most bytes kept, for stats
+func (b *recentAudio) capacity() int {
+	if b == nil {
+		return 0
+	}
+	return b.maxBytes
+}
+
+// replayCount returns the number of late-join replays started
+func (b *recentAudio) replayCount() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.replays.Load()
+}
+
+// replayedCount returns the number of frames late-join replays sent
+func (b *recentAudio) replayedCount() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.replayed.Load()
+}
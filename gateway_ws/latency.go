@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyWindow is the span a latencyGauge's maximum covers
+const latencyWindow = 10 * time.Second
+
+// latencyGauge tracks a smoothed average and the recent maximum of a
+// latency. One goroutine records; any may read without blocking it.
+type latencyGauge struct {
+	// Exponentially weighted average in nanoseconds
+	avg atomic.Int64
+
+	// Largest sample of the window starting at windowStart, in nanoseconds
+	// and Unix nanoseconds
+	max         atomic.Int64
+	windowStart atomic.Int64
+}
+
+// record adds a sample
+func (l *latencyGauge) record(d time.Duration, now time.Time) {
+	sample := int64(d)
+	avg := l.avg.Load()
+	l.avg.Store(avg + (sample-avg)/16)
+
+	if now.UnixNano()-l.windowStart.Load() >= int64(latencyWindow) {
+		l.windowStart.Store(now.UnixNano())
+		l.max.Store(sample)
+	} else if sample > l.max.Load() {
+		l.max.Store(sample)
+	}
+}
+
+// read returns the average and the maximum of the last latencyWindow or
+// so, zero if nothing was recorded in it
+func (l *latencyGauge) read(now time.Time) (avg, max time.Duration) {
+	avg = time.Duration(l.avg.Load())
+	if now.UnixNano()-l.windowStart.Load() < 2*int64(latencyWindow) {
+		max = time.Duration(l.max.Load())
+	}
+	return avg, max
+}
+
+// micros converts a latency to fractional microseconds for stats
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// hubLatency is a snapshot of a hub's latency gauges
+type hubLatency struct {
+	loopAvg, loopMax           time.Duration
+	queueWaitAvg, queueWaitMax time.Duration
+	broadcastAvg, broadcastMax time.Duration
+}
+
+// latency reads the hub's gauges. Broadcast latency, measured per fan-out
+// worker, is the workers' mean average and overall maximum.
+func (h *Hub) latency(now time.Time) hubLatency {
+	var l hubLatency
+	l.loopAvg, l.loopMax = h.loopLatency.read(now)
+	l.queueWaitAvg, l.queueWaitMax = h.queueWait.read(now)
+	for _, w := range h.fanOutWorkers {
+		avg, max := w.latency.read(now)
+		l.broadcastAvg += avg / time.Duration(len(h.fanOutWorkers))
+		if max > l.broadcastMax {
+			l.broadcastMax = max
+		}
+	}
+	return l
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Client roles
+const (
+	roleParticipant = "participant"
+	// roleListener receives its rooms' audio but may not send any
+	roleListener = "listener"
+)
+
+// requestedRole returns the role the client asked for with the role query
+// parameter. It may only give up the right to talk, never gain one.
+func requestedRole(r *http.Request) (listener bool, err error) {
+	switch role := r.URL.Query().Get("role"); role {
+	case "", roleParticipant:
+		return false, nil
+	case roleListener:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid role %q: want participant or listener", role)
+	}
+}
+
+// role returns the client's role
+func (c *Client) role() string {
+	if c.listener.Load() {
+		return roleListener
+	}
+	return roleParticipant
+}
+
+// WithListenerViolations closes a listener that went on sending audio after
+// that many warnings in a row. Zero only ever drops its audio.
+func WithListenerViolations(violations int) HubOption {
+	return func(h *Hub) {
+		if violations >= 0 {
+			h.listenerStrikes = violations
+		}
+	}
+}
+
+// listenerGuard is a listener's record of sending audio anyway. Only the
+// readPump of the client's connection touches it.
+type listenerGuard struct {
+	// Frames dropped since the last warning, when it was sent, and the
+	// warnings in a row up to it
+	dropped    uint64
+	warned     time.Time
+	violations int
+}
+
+// listenOnly drops an audio frame a listener sent, warning it at most every
+// rateLimitWarnInterval. It reports whether the connection must close.
+func (c *Client) listenOnly() (exceeded bool) {
+	c.listenerDropped.Add(1)
+	c.hub.listenerDropped.Add(1)
+	guard := &c.guard
+	guard.dropped++
+	now := time.Now()
+	if now.Sub(guard.warned) < rateLimitWarnInterval {
+		return false
+	}
+	// As for rate limits, warnings count one after the other unless a whole
+	// interval passed without any audio
+	if now.Sub(guard.warned) > 2*rateLimitWarnInterval {
+		guard.violations = 0
+	}
+	guard.violations++
+	guard.warned = now
+
+	if limit := c.hub.listenerStrikes; limit > 0 && guard.violations >= limit {
+		c.hub.listenerDisconnects.Add(1)
+		log.Printf("Client %s closed for sending audio as a listener in room %s", c.id, c.hub.room)
+		return true
+	}
+	log.Printf("Client %s sent audio as a listener in room %s, dropped %d frames", c.id, c.hub.room, guard.dropped)
+	guard.dropped = 0
+	c.fail("listen_only", "audio", localRoom(c.hub.room))
+	return false
+}
+
+// serveListener makes a client of an open room a listener or a participant
+// again: POST or DELETE /rooms/{room}/listeners/{client}
+func (g *Gateway) serveListener(w http.ResponseWriter, r *http.Request, name, id string) {
+	var listener bool
+	switch r.Method {
+	case http.MethodPost:
+		listener = true
+	case http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	g.mutex.Lock()
+	rm := g.rooms[name]
+	g.mutex.Unlock()
+	if rm == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+	client := rm.hub.lookup(id)
+	if client == nil {
+		http.Error(w, "no such client", http.StatusNotFound)
+		return
+	}
+
+	client.listener.Store(listener)
+	log.Printf("Client %s in room %s is now a %s", client.id, rm.name, client.role())
+	g.auditClient("role", client, auditActor(r), auditOK, client.role())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Audio loss is counted from sequence numbers in two places. On the read
+// path the numbers a client puts on its own frames, in a walkie.framed.v1
+// header, a walkie.pb.v1 Audio or a walkie.json.v1 envelope's seq, show the
+// frames lost between it and the gateway. On the write path the numbers the
+// gateway stamps on each sender's frames show the ones the gateway dropped
+// on the way to each listener, to backpressure or a full broadcast queue.
+const (
+	// lossSlot and lossSlots make up the rolling window loss percentages
+	// cover: a minute, in ten second steps
+	lossSlot  = 10 * time.Second
+	lossSlots = 6
+
+	// maxSeqGap is the largest jump in sequence numbers counted as lost
+	// frames. A larger one, or one going backwards, is a sender that
+	// started numbering again, after a reconnect or a restart of its app.
+	maxSeqGap = 1000
+)
+
+// seqGap returns how many frames are missing between sequence numbers last
+// and seq, and reports false if seq doesn't follow on from last. Counters
+// may wrap at 16, 32 or 64 bits; the narrowest both numbers fit in is
+// taken to be the counter's.
+func seqGap(last, seq uint64) (uint64, bool) {
+	delta := (seq - last) & seqMask(last, seq)
+	if delta == 0 || delta > maxSeqGap {
+		return 0, false
+	}
+	return delta - 1, true
+}
+
+// seqMask returns the mask of the narrowest counter, of 16, 32 or 64 bits,
+// both sequence numbers fit in
+func seqMask(last, seq uint64) uint64 {
+	switch {
+	case last < 1<<16 && seq < 1<<16:
+		return 1<<16 - 1
+	case last < 1<<32 && seq < 1<<32:
+		return 1<<32 - 1
+	}
+	return ^uint64(0)
+}
+
+// lossCount is frames received and lost
+type lossCount struct {
+	received uint64
+	lost     uint64
+}
+
+// percent returns the share of frames lost
+func (c lossCount) percent() float64 {
+	if c.received+c.lost == 0 {
+		return 0
+	}
+	return 100 * float64(c.lost) / float64(c.received+c.lost)
+}
+
+// lossTracker follows one sender's sequence numbers and counts the frames
+// missing from them, in total and over the rolling window. observe is
+// called from one goroutine; stats from any.
+type lossTracker struct {
+	mutex sync.Mutex
+	last  uint64
+	total lossCount
+
+	// The window's slots, current the one now filling, which began at
+	// slotStart
+	slots     [lossSlots]lossCount
+	current   int
+	slotStart time.Time
+}
+
+// observe records a frame numbered seq arriving at now. Frames without a
+// number are ignored.
+func (t *lossTracker) observe(seq uint64, now time.Time) {
+	if seq == 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.advance(now)
+	var lost uint64
+	if t.last != 0 {
+		lost, _ = seqGap(t.last, seq)
+	}
+	t.last = seq
+	t.total.received++
+	t.total.lost += lost
+	t.slots[t.current].received++
+	t.slots[t.current].lost += lost
+}
+
+// advance moves the window on to now, clearing slots that have passed. The
+// caller must hold the mutex.
+func (t *lossTracker) advance(now time.Time) {
+	if t.slotStart.IsZero() {
+		t.slotStart = now
+		return
+	}
+	for n := 0; n < lossSlots && now.Sub(t.slotStart) >= lossSlot; n++ {
+		t.current = (t.current + 1) % lossSlots
+		t.slots[t.current] = lossCount{}
+		t.slotStart = t.slotStart.Add(lossSlot)
+	}
+	if now.Sub(t.slotStart) >= lossSlot {
+		// Idle for longer than the window, which is now empty
+		t.slotStart = now
+	}
+}
+
+// window returns the counts over the rolling window up to now, and the ones
+// since the tracker started
+func (t *lossTracker) window(now time.Time) (window, total lossCount) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.advance(now)
+	for _, slot := range t.slots {
+		window.received += slot.received
+		window.lost += slot.lost
+	}
+	return window, t.total
+}
+
+// rewound reports whether seq jumps back from the last number by more than
+// maxSeqRewind, which no sender numbering its frames does. Starting over
+// from 1, or wrapping, is fine.
+func (t *lossTracker) rewound(seq uint64) bool {
+	t.mutex.Lock()
+	last := t.last
+	t.mutex.Unlock()
+	if last == 0 || seq <= maxSeqGap {
+		return false
+	}
+	if _, ok := seqGap(last, seq); ok {
+		return false
+	}
+	return (last-seq)&seqMask(last, seq) > maxSeqRewind
+}
+
+// idle reports whether the tracker has seen nothing in the rolling window
+func (t *lossTracker) idle(now time.Time) bool {
+	window, _ := t.window(now)
+	return window.received == 0
+}
+
+// lossKey names a sender a connection hears, in the room it hears it in
+type lossKey struct {
+	room   string
+	sender string
+}
+
+// deliveryLoss tracks the frames the gateway dropped on the way to one
+// connection, per sender it hears. Only writePump observes; stats may be
+// read from any goroutine.
+type deliveryLoss struct {
+	mutex   sync.Mutex
+	senders map[lossKey]*lossTracker
+
+	// When senders unheard for the window were last pruned
+	pruned time.Time
+}
+
+// observe records the frame from sender in room numbered seq being written
+// at now
+func (d *deliveryLoss) observe(room, sender string, seq uint64, now time.Time) {
+	if seq == 0 {
+		return
+	}
+	key := lossKey{room, sender}
+	d.mutex.Lock()
+	if d.senders == nil {
+		d.senders = make(map[lossKey]*lossTracker)
+	}
+	if now.Sub(d.pruned) >= lossSlot {
+		d.pruned = now
+		for k, t := range d.senders {
+			if k != key && t.idle(now) {
+				delete(d.senders, k)
+			}
+		}
+	}
+	t := d.senders[key]
+	if t == nil {
+		t = &lossTracker{}
+		d.senders[key] = t
+	}
+	d.mutex.Unlock()
+	t.observe(seq, now)
+}
+
+// SenderLoss is the audio from one sender the gateway dropped on the way to
+// a client
+type SenderLoss struct {
+	Dropped     uint64  `json:"dropped"`
+	LossPercent float64 `json:"loss_pct"`
+}
+
+// stats returns the loss from each sender heard in room, and the window and
+// total counts over all of them
+func (d *deliveryLoss) stats(room string, now time.Time) (map[string]SenderLoss, lossCount, lossCount) {
+	d.mutex.Lock()
+	trackers := make(map[string]*lossTracker)
+	for k, t := range d.senders {
+		if k.room == room {
+			trackers[k.sender] = t
+		}
+	}
+	d.mutex.Unlock()
+
+	var window, total lossCount
+	senders := make(map[string]SenderLoss, len(trackers))
+	for sender, t := range trackers {
+		w, all := t.window(now)
+		senders[sender] = SenderLoss{Dropped: all.lost, LossPercent: w.percent()}
+		window.received += w.received
+		window.lost += w.lost
+		total.received += all.received
+		total.lost += all.lost
+	}
+	return senders, window, total
+}
+
+// all returns the window counts over every room the connection hears
+func (d *deliveryLoss) all(now time.Time) lossCount {
+	d.mutex.Lock()
+	trackers := make([]*lossTracker, 0, len(d.senders))
+	for _, t := range d.senders {
+		trackers = append(trackers, t)
+	}
+	d.mutex.Unlock()
+
+	var window lossCount
+	for _, t := range trackers {
+		w, _ := t.window(now)
+		window.received += w.received
+		window.lost += w.lost
+	}
+	return window
+}
+
+// lossStats fills in the client's loss counters as of now. A multi-room
+// client's memberships share their connection's upstream counts.
+func (c *Client) lossStats(stats *ClientStats, now time.Time) {
+	conn, room := c, ""
+	if c.owner != nil {
+		conn, room = c.owner, c.hub.room
+	} else if c.multiRoom {
+		room = c.hub.room
+	}
+	window, total := conn.upstream.window(now)
+	stats.UpstreamLost = total.lost
+	stats.UpstreamLossPercent = window.percent()
+	senders, window, total := conn.delivery.stats(room, now)
+	stats.DownstreamDropped = total.lost
+	stats.DownstreamLossPercent = window.percent()
+	if len(senders) > 0 {
+		stats.SenderLoss = senders
+	}
+}
+
+// qualityMessage tells a client the share of the audio it sent, and of the
+// audio it hears, lost over the last window_seconds, and its round trip, for
+// a signal quality bar
+type qualityMessage struct {
+	Type                  string  `json:"type"`
+	UpstreamLossPercent   float64 `json:"upstream_loss_pct"`
+	DownstreamLossPercent float64 `json:"downstream_loss_pct"`
+	WindowSeconds         int     `json:"window_seconds"`
+	RTTMs                 float64 `json:"rtt_ms"`
+}
+
+// WithQualityReports sends every client a quality message each interval. A
+// zero interval disables them.
+func WithQualityReports(interval time.Duration) HubOption {
+	return func(h *Hub) {
+		h.qualityInterval = interval
+	}
+}
+
+// sendQuality is called by writePump every quality interval
+func (c *Client) sendQuality() error {
+	now := time.Now()
+	upstream, _ := c.upstream.window(now)
+	data, err := json.Marshal(qualityMessage{
+		Type:                  "quality",
+		UpstreamLossPercent:   upstream.percent(),
+		DownstreamLossPercent: c.delivery.all(now).percent(),
+		WindowSeconds:         int((lossSlots * lossSlot).Seconds()),
+		RTTMs:                 float64(c.roundTrip(now)) / float64(time.Millisecond),
+	})
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(c.writeDeadline())
+	return c.writeCounted(outbound{messageType: websocket.TextMessage, data: data})
+}
@@ -1,190 +1,512 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
-	"sync"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/acme/autocert"
 )
 
-// Client represents a connected websocket client
-type Client struct {
-	conn *websocket.Conn
-	send chan []byte
-	hub  *Hub
-	id   string
-}
-
-// Hub maintains the set of active clients and broadcasts messages to them
-type Hub struct {
-	// Registered clients
-	clients map[*Client]bool
-
-	// Inbound messages from the clients
-	broadcast chan BroadcastMessage
-
-	// Register requests from the clients
-	register chan *Client
-
-	// Unregister requests from clients
-	unregister chan *Client
-
-	// Mutex for thread-safe operations
-	mutex sync.RWMutex
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
 }
 
-// BroadcastMessage contains the message and the sender client
-type BroadcastMessage struct {
-	data   []byte
-	sender *Client
-}
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain to serve TLS with, reread on SIGHUP; needs -tls-key")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsMinVersion := flag.String("tls-min-version", "1.2", "lowest TLS version accepted: 1.2 or 1.3")
+	clientCA := flag.String("tls-client-ca", "", "PEM file of the CAs client certificates must chain to, reread on SIGHUP; a verified certificate's name becomes the client ID in place of a bearer token")
+	clientCertMode := flag.String("tls-client-auth", clientCertRequire, "with -tls-client-ca, require a client certificate in the handshake, or optional to also let clients without one in")
+	certMapping := flag.String("tls-client-map", "", "JSON file of rules mapping client certificates to roles and rooms")
+	acmeHosts := flag.String("acme-hosts", "", "comma-separated host names to serve TLS for with certificates obtained from Let's Encrypt, instead of -tls-cert")
+	acmeCache := flag.String("acme-cache", "acme-cache", "directory certificates from Let's Encrypt are cached in")
+	acmeEmail := flag.String("acme-email", "", "contact address given to Let's Encrypt for expiry and problem notices")
+	httpAddr := flag.String("http-addr", "", "with TLS, a plain HTTP address, e.g. :80, serving only the health probes, ACME challenges and a redirect to HTTPS")
+	shutdownGrace := flag.Duration("shutdown-grace", 10*time.Second, "time allowed on SIGTERM/SIGINT for clients to drain before exiting")
+	broadcastQueue := flag.Int("broadcast-queue", defaultBroadcastQueue, "depth of the hub's inbound broadcast queue")
+	dropWhenFull := flag.Bool("drop-when-full", false, "drop frames instead of blocking the reader when the broadcast queue is full")
+	duplicateIDs := flag.String("duplicate-ids", "allow", "policy for a client ID that is already connected: allow, takeover, reject or rename")
+	slowConsumers := flag.String("slow-consumer", "disconnect", "policy for a listener whose send buffer is full: disconnect, drop-newest or drop-oldest")
+	shards := flag.Int("shards", defaultShards, "number of client registry shards")
+	workers := flag.Int("workers", defaultWorkers, "number of goroutines fanning broadcasts out, each owning a subset of the shards")
+	sendBuffer := flag.Int("send-buffer", defaultSendBuffer, "default per-client send buffer size in messages")
+	maxSendBuffer := flag.Int("max-send-buffer", defaultMaxSendBuffer, "largest send buffer a client may request")
+	pingPeriod := flag.Duration("ping-period", defaultPingPeriod, "interval between keepalive pings")
+	pongWait := flag.Duration("pong-wait", defaultPongWait, "time to wait for a pong before evicting a client; must exceed -ping-period")
+	writeWait := flag.Duration("write-wait", defaultWriteWait, "time allowed for a single write before the client is disconnected")
+	maxMessageSize := flag.Int64("max-message-size", defaultMaxMessageSize, "largest inbound message in bytes; larger ones close the client with 1009")
+	idleTimeout := flag.Duration("idle-timeout", 0, "close clients with no audio sent or received for this long (0 disables)")
+	idleCountsPongs := flag.Bool("idle-count-pongs", false, "treat keepalive pongs as activity for -idle-timeout")
+	maxClients := flag.Int("max-clients", 0, "maximum concurrent clients; further upgrades get 503 (0 means no limit)")
+	warnClients := flag.Float64("max-clients-warn", 0.8, "fraction of -max-clients at which a capacity warning is logged")
+	allowedOrigins := flag.String("allowed-origins", "", "comma-separated origins browsers may connect from, e.g. https://app.example.com or https://*.example.com; others get 403 (empty allows any)")
+	allowNoOrigin := flag.Bool("allow-no-origin", false, "with -allowed-origins, also admit clients that send no Origin header, such as native apps")
+	trusted := flag.String("trusted-proxies", "", "comma-separated CIDRs of proxies whose X-Forwarded-For header is trusted")
+	ipAccessFile := flag.String("ip-access-file", "", "JSON file of CIDRs client addresses must be on (allow) and must not be on (deny) to connect, reread on SIGHUP; others get 403")
+	frameAuth := flag.Bool("frame-auth", false, "require every audio frame to carry an HMAC-SHA256 with the sender's frame key from -frame-keys or its token's frame_key claim, dropping frames that fail")
+	frameKeys := flag.String("frame-keys", "", "with -frame-auth, JSON file of client ID to base64 frame key, reread on SIGHUP")
+	frameViolations := flag.Int("frame-violations", defaultFrameViolations, "invalid audio frames in a row after which a connection is closed with 1008 (0 never closes it)")
+	frameAuthFailures := flag.Int("frame-auth-failures", defaultFrameAuthFailures, "with -frame-auth, frames failing verification after which a connection is closed with 1008 (0 never closes it)")
+	keyxBytes := flag.Int("keyx-max-bytes", defaultKeyExchangeBytes, "largest payload of a keyx key exchange message in bytes, as JSON")
+	keyxRate := flag.Float64("keyx-rate", defaultKeyExchangeRate, "keyx messages each connection may send per second, sustained")
+	keyxBurst := flag.Int("keyx-burst", defaultKeyExchangeBurst, "keyx messages a connection may send at once above -keyx-rate")
+	chatMaxLength := flag.Int("chat-max-length", defaultChatMaxLength, "longest chat message text in characters")
+	chatRate := flag.Float64("chat-rate", defaultChatRate, "chat messages each connection may send per second, sustained")
+	chatBurst := flag.Int("chat-burst", defaultChatBurst, "chat messages a connection may send at once above -chat-rate")
+	chatHistory := flag.Int("chat-history", 0, "chat messages each room keeps for the joined message, so late joiners see them (0 keeps none)")
+	ackTimeout := flag.Duration("ack-timeout", defaultAckTimeout, "how long a client has to acknowledge a control message that asks for it before it is sent again")
+	ackRetries := flag.Int("ack-retries", defaultAckRetries, "times a control message asking for acknowledgement is sent again before its originator is told it failed")
+	controlErrors := flag.Int("control-error-limit", 0, "control messages a minute a connection may send that don't parse, have an unknown type or fail validation before it is closed with 1008 (0 only answers them with errors)")
+	minProtocol := flag.Int("min-protocol-version", legacyProtocolVersion, "lowest protocol version clients may speak; older ones are closed with 4010 update your app")
+	auditDir := flag.String("audit-dir", "", "directory to write the audit log of connections, joins, kicks, bans and auth failures into, a JSON lines file per UTC day (empty disables it)")
+	auditWebhook := flag.String("audit-webhook", "", "with -audit-dir, URL audit events are also POSTed to in batches, as JSON arrays")
+	debug := flag.Bool("debug", false, "log debug messages, such as every address access decision")
+	perIP := flag.Int("max-clients-per-ip", 0, "maximum concurrent clients per address, per /64 for IPv6; further upgrades get 429 (0 means no limit)")
+	attempts := flag.Int("upgrade-attempts", 0, "upgrade attempts allowed per -upgrade-attempts-window from each address, per /64 for IPv6; more get 429 (0 means no limit)")
+	attemptsWindow := flag.Duration("upgrade-attempts-window", 10*time.Second, "window -upgrade-attempts are counted over")
+	attemptsTracked := flag.Int("upgrade-attempts-tracked", defaultAttemptsTracked, "most addresses whose upgrade attempts are remembered; the least recently seen are forgotten")
+	resumeWindow := flag.Duration("resume-window", defaultResumeWindow, "how long a dropped client may reconnect with its resume token and pick up its session (0 disables)")
+	replayFrames := flag.Int("resume-buffer-frames", defaultReplayFrames, "most frames buffered for a dropped client awaiting resume")
+	replayBytes := flag.Int("resume-buffer-bytes", defaultReplayBytes, "most bytes buffered for a dropped client awaiting resume")
+	lateJoinWindow := flag.Duration("late-join-window", 0, "how much of each room's recent audio is kept for clients joining with ?replay=true (0 disables)")
+	lateJoinBytes := flag.Int("late-join-bytes", defaultLateJoinBytes, "most bytes of recent audio kept for late joiners in each room")
+	maxAge := flag.Duration("max-connection-age", 0, "close connections after about this long so clients reconnect (0 disables)")
+	maxAgeJitter := flag.Float64("max-connection-age-jitter", defaultMaxAgeJitter, "random spread of -max-connection-age, as a fraction of it")
+	stallThreshold := flag.Duration("hub-stall-threshold", defaultStallThreshold, "how long the hub loop may go unresponsive before /readyz and /livez fail")
+	qualityInterval := flag.Duration("quality-interval", 0, "interval between quality messages telling each client its share of audio lost over the last minute (0 disables)")
+	heartbeatInterval := flag.Duration("heartbeat-interval", 0, "interval between application-level heartbeat messages, sent alongside protocol pings (0 disables)")
+	heartbeatMisses := flag.Int("heartbeat-misses", defaultHeartbeatMisses, "consecutive unanswered heartbeats before a client is closed with 4005")
+	readBuffer := flag.Int("read-buffer-size", 0, "WebSocket read buffer size in bytes (0 uses the library default)")
+	writeBuffer := flag.Int("write-buffer-size", 0, "WebSocket write buffer size in bytes (0 uses the library default)")
+	handshakeTimeout := flag.Duration("handshake-timeout", defaultHandshakeTimeout, "time allowed for the WebSocket upgrade handshake")
+	compression := flag.Bool("enable-compression", false, "let clients negotiate permessage-deflate for control messages; audio is never compressed")
+	compressionLevel := flag.Int("compression-level", defaultCompressionLevel, "flate level for compressed messages, from -2 (Huffman only) to 9")
+	defaultProtocol := flag.String("default-subprotocol", protocolRaw, "subprotocol assumed for clients that offer none the server speaks; empty refuses them with 426")
+	roomLinger := flag.Duration("room-linger", defaultRoomLinger, "how long an empty room stays open before it is torn down (0 tears it down at once)")
+	scheduleGrace := flag.Duration("schedule-grace", defaultScheduleGrace, "how long clients of a scheduled room are warned before being disconnected when its window closes")
+	roomCapacity := flag.Int("room-capacity", 0, "maximum participants per room; further joins get 409 room_full (0 means no cap)")
+	rateMessages := flag.Float64("rate-limit-messages", 0, "audio messages per second each client may send into a room, sustained (0 means no limit)")
+	rateMessageBurst := flag.Int("rate-limit-message-burst", 0, "audio messages a client may send at once above -rate-limit-messages (0 means a second's worth)")
+	rateBytes := flag.Float64("rate-limit-bytes", 0, "audio bytes per second each client may send into a room, sustained (0 means no limit)")
+	rateByteBurst := flag.Int("rate-limit-byte-burst", 0, "audio bytes a client may send at once above -rate-limit-bytes (0 means a second's worth)")
+	ratePolicy := flag.String("rate-limit-policy", rateLimitDrop, "what happens to audio over the rate limit: drop, or disconnect to also close clients that keep exceeding it with 1008")
+	rateViolations := flag.Int("rate-limit-violations", defaultRateLimitViolations, "with -rate-limit-policy disconnect, warnings in a row after which a client is closed")
+	maxTalkBurst := flag.Duration("max-talk-burst", 0, "longest a client may transmit into a room without a break before its audio is cut off (0 means no limit)")
+	talkSilence := flag.Duration("talk-silence", defaultTalkSilence, "gap in a client's audio that ends its talk burst, for talk messages and the talk limit")
+	voxAttack := flag.Float64("vox-attack", defaultVOXAttack, "level in dBFS that opens a burst for clients joining with ?vox=true and no vox_attack")
+	voxRelease := flag.Float64("vox-release", defaultVOXRelease, "level in dBFS that VOX clients' audio must stay below for -vox-hold to end a burst, unless they set vox_release")
+	voxHold := flag.Duration("vox-hold", defaultVOXHold, "how long a VOX client's audio must stay below the release level to end its burst, unless it sets vox_hold")
+	silenceThreshold := flag.Float64("silence-threshold", 0, "level in dBFS below which rooms drop audio as silence, pcm16 audio's read off its samples and other codecs' declared by their senders (0 means no suppression; rooms' configs may override it)")
+	silenceHangover := flag.Duration("silence-hangover", defaultSilenceHangover, "how long after a client's last audio at or above -silence-threshold its quieter audio is still relayed")
+	talkCooldown := flag.Duration("talk-cooldown", 0, "how long a client whose burst was cut off must wait, once it stops, before transmitting again")
+	transcodeBitrate := flag.Int("transcode-bitrate", defaultTranscodeBitrate, "bits per second pcm16 audio is encoded at for listeners joining with ?receive, such as opus in a build with -tags opus")
+	mixing := flag.Bool("mix", false, "have pcm16 rooms mix clients talking over each other into one stream per listener (rooms' configs may override it)")
+	pacing := flag.Bool("pace", false, "have rooms release each sender's audio no faster than its frame duration, queueing frames sent in bursts (rooms' configs may override it)")
+	paceQueue := flag.Int("pace-queue", defaultPaceQueue, "most audio frames a paced sender running ahead may have waiting before more are dropped")
+	floorControl := flag.Bool("floor-control", false, "put rooms under floor control, where only the client granted the floor may talk (rooms' configs may override it)")
+	floorTimeout := flag.Duration("floor-timeout", defaultFloorTimeout, "how long the floor holder may send no audio before it loses the floor")
+	floorResume := flag.Bool("floor-resume", false, "let a floor holder whose connection is lost keep the floor if it resumes its session before -floor-timeout frees it")
+	floorMinHold := flag.Duration("floor-min-hold", defaultFloorMinHold, "how long a floor holder keeps the floor before a higher-priority request may preempt it")
+	floorQueue := flag.Int("floor-queue", 0, "clients that may wait in line for a busy floor under floor control (0 denies them instead)")
+	floorOfferWindow := flag.Duration("floor-offer-window", defaultFloorOfferWindow, "how long the head of the line has to take the floor once it is offered it")
+	capacities := flag.String("room-capacities", "", "comma-separated room=max overrides of -room-capacity")
+	adminToken := flag.String("admin-token", "", "bearer token required by the management endpoints /stats, /clients, /rooms/... and /announce (empty leaves them open, or to tenant keys alone with -tenant-keys, and disables /announce)")
+	credentials := flag.String("room-credentials", "", "comma-separated room=secret:<secret> or room=token entries; those rooms refuse joins without the secret or a signed join token with 403")
+	joinKey := flag.String("join-token-key", "", "HMAC-SHA256 key join tokens are signed with")
+	jwtSecret := flag.String("jwt-secret", "", "HMAC secret bearer tokens may be signed with; setting it or -jwt-jwks-url makes every upgrade present a token")
+	jwksURL := flag.String("jwt-jwks-url", "", "URL of the JWKS whose RSA and EC keys bearer tokens may be signed with")
+	jwtIssuer := flag.String("jwt-issuer", "", "iss claim bearer tokens must carry (empty accepts any)")
+	jwtAudience := flag.String("jwt-audience", "", "aud claim bearer tokens must carry (empty accepts any)")
+	jwtLeeway := flag.Duration("jwt-leeway", defaultJWTLeeway, "clock skew tolerated on bearer token exp and nbf claims")
+	moderators := flag.String("room-moderators", "", "comma-separated room=secret entries; a client presenting the secret as its join credential moderates the room")
+	muteWindow := flag.Duration("mute-window", defaultMuteWindow, "how long a muted client stays muted after disconnecting, so rejoining doesn't lift it")
+	listenerViolations := flag.Int("listener-violations", 0, "warnings in a row after which a listener that keeps sending audio is closed with 1008 (0 only drops its audio)")
+	tenantKeys := flag.String("tenant-keys", "", "JSON file of tenant API keys, reloaded when it changes; every upgrade must then present one (empty disables tenants)")
+	storePath := flag.String("room-store", "", "bbolt file persistent room configs are kept in (empty disables persistence)")
+	codec := flag.String("audio-codec", defaultCodec, "audio codec clients are told to use in the joined message; audio is relayed untouched")
+	sampleRate := flag.Int("sample-rate", defaultSampleRate, "audio sample rate in Hz advertised in the joined message")
+	channels := flag.Int("channels", defaultChannels, "audio channel count advertised in the joined message")
+	frameMs := flag.Int("frame-ms", 0, "audio frame duration in milliseconds advertised in the joined message (0 leaves it unstated)")
+	audioFormats := flag.String("audio-formats", "", "comma-separated codec/sample_rate/channels/frame_ms formats clients may declare besides the advertised one, making rooms mixed")
+	audioMismatch := flag.String("audio-mismatch", mismatchReject, "what happens to a client declaring an audio format its room doesn't allow: reject, or flag to let it in flagged")
+	recordDir := flag.String("record-dir", "", "directory rooms configured to be recorded are recorded into (empty disables recording)")
+	recordFormat := flag.String("record-format", recordAuto, "recording file format: raw, keeping each frame's time and sender, wav for pcm16 audio, or auto for wav in pcm16 rooms and raw in the rest")
+	recordGaps := flag.String("record-gaps", recordGapsSilence, "what fills a gap in transmission in a wav recording: silence, keeping the file to the wall clock, or split, starting a new file")
+	recordMaxBytes := flag.Int64("record-max-bytes", defaultRecordMaxBytes, "size at which a recording file is rotated")
+	recordMaxDuration := flag.Duration("record-max-duration", defaultRecordMaxDuration, "age at which a recording file is rotated")
+	recordRooms := flag.String("record-rooms", "", "comma-separated rooms recorded from the start")
+	uploadEndpoint := flag.String("upload-endpoint", "", "with -record-dir, URL of the S3-compatible object store finished recordings are uploaded to and then removed from disk (empty keeps them on disk)")
+	uploadBucket := flag.String("upload-bucket", "", "bucket recordings are uploaded into")
+	uploadPrefix := flag.String("upload-prefix", "", "prefix of the keys recordings are uploaded under")
+	uploadRegion := flag.String("upload-region", defaultUploadRegion, "region uploads are signed for")
+	uploadAccessKey := flag.String("upload-access-key", "", "access key uploads are signed with (default $AWS_ACCESS_KEY_ID)")
+	uploadSecretKey := flag.String("upload-secret-key", "", "secret key uploads are signed with (default $AWS_SECRET_ACCESS_KEY)")
+	uploadArchiveDir := flag.String("upload-archive-dir", "", "directory uploaded recordings are moved into rather than deleted")
+	uploadConcurrency := flag.Int("upload-concurrency", defaultUploadConcurrency, "most recordings uploaded at once")
+	dump := flag.String("dump-recording", "", "print the frames of a raw recording file and exit")
+	maxRooms := flag.Int("max-rooms-per-connection", defaultMaxRoomsPerConnection, "most rooms a multi_room connection may be in at once, its own included")
+	flag.Parse()
 
-// NewHub creates a new Hub instance
-func NewHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan BroadcastMessage),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+	if *dump != "" {
+		if err := dumpRecording(*dump); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-}
 
-// Run starts the hub and handles client registration, unregistration, and broadcasting
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mutex.Lock()
-			h.clients[client] = true
-			h.mutex.Unlock()
-			log.Printf("Client %s connected. Total clients: %d", client.id, len(h.clients))
-
-		case client := <-h.unregister:
-			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				log.Printf("Client %s disconnected. Total clients: %d", client.id, len(h.clients))
-			}
-			h.mutex.Unlock()
-
-		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.clients {
-				// Don't send the message back to the sender
-				if client == message.sender {
-					continue
-				}
-				select {
-				case client.send <- message.data:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mutex.RUnlock()
+	minVersion, err := ParseTLSVersion(*tlsMinVersion)
+	if err != nil {
+		log.Fatal(err)
+	}
+	hosts := ParseHostList(*acmeHosts)
+	switch {
+	case (*tlsCert == "") != (*tlsKey == ""):
+		log.Fatal("-tls-cert and -tls-key go together")
+	case *tlsCert != "" && len(hosts) > 0:
+		log.Fatal("-acme-hosts and -tls-cert are alternatives")
+	case *httpAddr != "" && *tlsCert == "" && len(hosts) == 0:
+		log.Fatal("-http-addr needs -tls-cert or -acme-hosts")
+	case *clientCA != "" && *tlsCert == "" && len(hosts) == 0:
+		log.Fatal("-tls-client-ca needs -tls-cert or -acme-hosts")
+	case *certMapping != "" && *clientCA == "":
+		log.Fatal("-tls-client-map needs -tls-client-ca")
+	}
+	var certs *certReloader
+	if *tlsCert != "" {
+		certs, err = LoadCertificate(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
-}
 
-// readPump pumps messages from the websocket connection to the hub
-func (c *Client) readPump() {
-	defer func() {
-		c.hub.unregister <- c
-		c.conn.Close()
-	}()
-
-	for {
-		_, message, err := c.conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("Error reading message from client %s: %v", c.id, err)
+	var cas *clientCAs
+	var clientAuth tls.ClientAuthType
+	var certRules []CertRule
+	if *clientCA != "" {
+		if clientAuth, err = ParseClientCertMode(*clientCertMode); err != nil {
+			log.Fatal(err)
+		}
+		if cas, err = LoadClientCAs(*clientCA); err != nil {
+			log.Fatal(err)
+		}
+		if *certMapping != "" {
+			if certRules, err = LoadCertMapping(*certMapping); err != nil {
+				log.Fatal(err)
 			}
-			break
 		}
+	}
 
-		// Broadcast the audio data to all other clients (excluding sender)
-		c.hub.broadcast <- BroadcastMessage{
-			data:   message,
-			sender: c,
+	duplicatePolicy, err := ParseDuplicateIDPolicy(*duplicateIDs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slowPolicy, err := ParseSlowConsumerPolicy(*slowConsumers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *defaultProtocol != "" && !isSubprotocol(*defaultProtocol) {
+		log.Fatalf("unknown subprotocol %q", *defaultProtocol)
+	}
+	audio, err := ParseAudioFormat(*codec, *sampleRate, *channels, *frameMs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	allowedFormats, err := ParseAudioFormats(*audioFormats)
+	if err != nil {
+		log.Fatal(err)
+	}
+	audioPolicy := AudioPolicy{audioFormat: audio, Allowed: allowedFormats, Mismatch: *audioMismatch}
+	if err := audioPolicy.validate(); err != nil {
+		log.Fatal(err)
+	}
+	proxies, err := ParseTrustedProxies(*trusted)
+	if err != nil {
+		log.Fatal(err)
+	}
+	origins, err := ParseOriginAllowlist(*allowedOrigins)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *allowNoOrigin && len(origins) == 0 {
+		log.Printf("-allow-no-origin has no effect without -allowed-origins")
+	}
+	rateLimit := RateLimit{
+		MessagesPerSecond: *rateMessages,
+		MessageBurst:      *rateMessageBurst,
+		BytesPerSecond:    *rateBytes,
+		ByteBurst:         *rateByteBurst,
+		Policy:            *ratePolicy,
+		Violations:        *rateViolations,
+	}
+	if err := rateLimit.validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := (voxSettings{Attack: *voxAttack, Release: *voxRelease, HoldMs: voxHold.Milliseconds()}).validate(); err != nil {
+		log.Fatal(err)
+	}
+	var talkLimit TalkLimit
+	if *maxTalkBurst > 0 {
+		talkLimit = TalkLimit{MaxBurst: maxTalkBurst.String(), Silence: talkSilence.String(), Cooldown: talkCooldown.String()}
+		if err := talkLimit.validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	silence := SilenceSuppression{Threshold: *silenceThreshold, Hangover: silenceHangover.String()}
+	if err := silence.validate(); err != nil {
+		log.Fatal(err)
+	}
+	overrides, err := ParseRoomCapacities(*capacities)
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderatorSecrets, err := ParseModeratorSecrets(*moderators)
+	if err != nil {
+		log.Fatal(err)
+	}
+	roomCredentials, err := ParseRoomCredentials(*credentials)
+	if err != nil {
+		log.Fatal(err)
+	}
+	format, err := ParseRecordFormat(*recordFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if format == recordWAV && audio.Codec != defaultCodec {
+		log.Fatalf("-record-format wav needs %s audio, not %s", defaultCodec, audio.Codec)
+	}
+	gaps, err := ParseRecordGaps(*recordGaps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *mixing && audio.Codec != defaultCodec {
+		log.Fatalf("-mix needs %s audio, not %s", defaultCodec, audio.Codec)
+	}
+	recorded, err := ParseRoomNames(*recordRooms)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(recorded) > 0 && *recordDir == "" {
+		log.Fatal("-record-rooms needs -record-dir")
+	}
+	if *uploadEndpoint != "" && *recordDir == "" {
+		log.Fatal("-upload-endpoint needs -record-dir")
+	}
+	if *auditWebhook != "" && *auditDir == "" {
+		log.Fatal("-audit-webhook needs -audit-dir")
+	}
+	if *frameKeys != "" && !*frameAuth {
+		log.Fatal("-frame-keys needs -frame-auth")
+	}
+	for name, credential := range roomCredentials {
+		if credential.signed && *joinKey == "" {
+			log.Fatalf("room %s requires join tokens but -join-token-key is not set", name)
 		}
 	}
-}
 
-// writePump pumps messages from the hub to the websocket connection
-func (c *Client) writePump() {
-	defer c.conn.Close()
-
-	for {
-		select {
-		case message, ok := <-c.send:
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+	opts := []HubOption{
+		WithBroadcastQueue(*broadcastQueue),
+		WithDuplicateIDPolicy(duplicatePolicy),
+		WithSlowConsumerPolicy(slowPolicy),
+		WithShards(*shards),
+		WithWorkers(*workers),
+		WithSendBuffer(*sendBuffer, *maxSendBuffer),
+		WithKeepalive(*pingPeriod, *pongWait),
+		WithWriteWait(*writeWait),
+		WithMaxMessageSize(*maxMessageSize),
+		WithIdleTimeout(*idleTimeout, *idleCountsPongs),
+		WithMaxClients(*maxClients, *warnClients),
+		WithMaxAge(*maxAge, *maxAgeJitter),
+		WithHeartbeat(*heartbeatInterval, *heartbeatMisses),
+		WithQualityReports(*qualityInterval),
+		WithResume(*resumeWindow, *replayFrames, *replayBytes),
+		WithLateJoin(*lateJoinWindow, *lateJoinBytes),
+		WithMuteWindow(*muteWindow),
+		WithListenerViolations(*listenerViolations),
+		WithChatHistory(*chatHistory),
+		WithTalkSilence(*talkSilence),
+		WithFloorTimeout(*floorTimeout),
+		WithFloorOfferWindow(*floorOfferWindow),
+		WithFloorMinHold(*floorMinHold),
+		WithFloorResume(*floorResume),
+		WithTranscodeBitrate(*transcodeBitrate),
+		WithPaceQueue(*paceQueue),
+	}
+	if *dropWhenFull {
+		opts = append(opts, WithBroadcastPolicy(BroadcastDrop))
+	}
 
-			if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
-				log.Printf("Error writing message to client %s: %v", c.id, err)
-				return
+	gatewayOpts := []GatewayOption{
+		WithTrustedProxies(proxies),
+		WithAllowedOrigins(origins, *allowNoOrigin),
+		WithDebugLog(*debug),
+		WithPerIPLimit(*perIP),
+		WithUpgradeAttemptLimit(*attempts, *attemptsWindow, *attemptsTracked),
+		WithStallThreshold(*stallThreshold),
+		WithUpgradeBuffers(*readBuffer, *writeBuffer),
+		WithHandshakeTimeout(*handshakeTimeout),
+		WithCompression(*compression, *compressionLevel),
+		WithDefaultSubprotocol(*defaultProtocol),
+		WithRoomLinger(*roomLinger),
+		WithScheduleGrace(*scheduleGrace),
+		WithRoomCapacity(*roomCapacity, overrides),
+		WithRoomRateLimit(rateLimit),
+		WithRoomTalkLimit(talkLimit),
+		WithRoomSilenceSuppression(silence),
+		WithRoomFloorControl(*floorControl, *floorQueue),
+		WithRoomMixing(*mixing),
+		WithRoomPacing(*pacing),
+		WithVOXDefaults(*voxAttack, *voxRelease, *voxHold),
+		WithRoomAudio(audioPolicy),
+		WithAdminToken(*adminToken),
+		WithRoomCredentials(roomCredentials, []byte(*joinKey)),
+		WithJWTAuth([]byte(*jwtSecret), *jwksURL, *jwtIssuer, *jwtAudience, *jwtLeeway),
+		WithModeratorSecrets(moderatorSecrets),
+		WithMaxRoomsPerConnection(*maxRooms),
+		WithKeyExchangeLimits(*keyxBytes, *keyxRate, *keyxBurst),
+		WithChatLimits(*chatMaxLength, *chatRate, *chatBurst),
+		WithControlErrorLimit(*controlErrors),
+		WithFrameViolations(*frameViolations),
+		WithAckPolicy(*ackTimeout, *ackRetries),
+		WithMinProtocolVersion(*minProtocol),
+		WithRecording(*recordDir, format, gaps, *recordMaxBytes, *recordMaxDuration),
+		WithRecordedRooms(recorded),
+	}
+	if cas != nil {
+		gatewayOpts = append(gatewayOpts, WithClientCertAuth(certRules))
+	}
+	if *tenantKeys != "" {
+		keys, err := LoadTenantKeys(*tenantKeys)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gatewayOpts = append(gatewayOpts, WithTenantKeys(keys))
+	}
+	if *ipAccessFile != "" {
+		access, err := LoadIPAccess(*ipAccessFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gatewayOpts = append(gatewayOpts, WithIPAccess(access))
+	}
+	var audit *auditLog
+	if *auditDir != "" {
+		audit, err = OpenAuditLog(*auditDir, *auditWebhook)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gatewayOpts = append(gatewayOpts, WithAuditLog(audit))
+	}
+	if *frameAuth {
+		var keys *frameKeyring
+		if *frameKeys != "" {
+			keys, err = LoadFrameKeys(*frameKeys)
+			if err != nil {
+				log.Fatal(err)
 			}
 		}
+		gatewayOpts = append(gatewayOpts, WithFrameAuth(keys, *frameAuthFailures))
 	}
-}
-
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from any origin for simplicity
-		// In production, you should validate the origin
-		return true
-	},
-}
-
-// serveWS handles websocket requests from the peer
-func serveWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
-		return
+	var store RoomStore
+	if *storePath != "" {
+		store, err = OpenBoltRoomStore(*storePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gatewayOpts = append(gatewayOpts, WithRoomStore(store))
 	}
-
-	// Generate a simple client ID (in production, use proper UUID)
-	clientID := r.Header.Get("X-Client-ID")
-	if clientID == "" {
-		clientID = r.RemoteAddr
+	if *recordDir != "" {
+		// Patch the wav files a crash left open before rooms record again
+		if repaired, err := repairRecordings(*recordDir); err != nil {
+			log.Fatalf("Repairing recordings: %v", err)
+		} else if repaired > 0 {
+			log.Printf("Repaired %d recordings in %s", repaired, *recordDir)
+		}
 	}
-
-	client := &Client{
-		conn: conn,
-		send: make(chan []byte, 256),
-		hub:  hub,
-		id:   clientID,
+	var uploads *uploader
+	if *uploadEndpoint != "" {
+		// Credentials come from the environment unless given
+		settings := UploadSettings{
+			Endpoint:     *uploadEndpoint,
+			Bucket:       *uploadBucket,
+			Prefix:       *uploadPrefix,
+			Region:       *uploadRegion,
+			AccessKey:    *uploadAccessKey,
+			SecretKey:    *uploadSecretKey,
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			ArchiveDir:   *uploadArchiveDir,
+			Concurrency:  *uploadConcurrency,
+		}
+		if settings.AccessKey == "" {
+			settings.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if settings.SecretKey == "" {
+			settings.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		uploads, err = StartUploader(*recordDir, settings)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gatewayOpts = append(gatewayOpts, WithUploader(uploads))
 	}
 
-	client.hub.register <- client
-
-	// Start goroutines for reading and writing
-	go client.writePump()
-	go client.readPump()
-}
-
-func main() {
-	hub := NewHub()
-	go hub.Run()
+	gateway := NewGateway(opts, gatewayOpts...)
+	if err := gateway.LoadRooms(); err != nil {
+		log.Fatalf("Loading persistent rooms: %v", err)
+	}
+	if err := gateway.LoadBans(); err != nil {
+		log.Fatalf("Loading bans: %v", err)
+	}
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWS(hub, w, r)
-	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", gateway.serveWS)
+	mux.HandleFunc("/ws/", gateway.serveWS)
 
-	// Simple health check endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	// Simple health check endpoint, and orchestrator probes: /readyz fails
+	// as soon as a drain begins, /livez only if the hub loop wedges. They
+	// are served on the plain listener too.
+	probes := http.NewServeMux()
+	probes.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	probes.HandleFunc("/livez", gateway.serveLivez)
+	probes.HandleFunc("/readyz", gateway.serveReadyz)
+	for _, probe := range []string{"/health", "/livez", "/readyz"} {
+		mux.Handle(probe, probes)
+	}
+
+	// Management endpoints: hub and per-client counters, the open rooms, and
+	// announcements to all of them
+	mux.HandleFunc("/stats", gateway.requireAdmin(gateway.serveStats))
+	mux.HandleFunc("/clients", gateway.requireAdmin(gateway.serveClients))
+	mux.HandleFunc("/metrics", gateway.requireAdmin(gateway.serveMetrics))
+	mux.HandleFunc("/rooms", gateway.requireAdmin(gateway.serveRooms))
+	mux.HandleFunc("/rooms/", gateway.requireAdmin(gateway.serveRoom))
+	mux.HandleFunc("/announce", gateway.requireAdmin(gateway.serveAnnounce))
+	mux.HandleFunc("/recordings", gateway.requireAdmin(gateway.serveRecordings))
+	mux.HandleFunc("/recordings/", gateway.requireAdmin(gateway.serveRecordings))
+	mux.HandleFunc("/ip-access", gateway.requireAdmin(gateway.serveIPAccess))
+	mux.HandleFunc("/admin/bans", gateway.requireAdmin(gateway.serveBans))
+	mux.HandleFunc("/admin/bans/", gateway.requireAdmin(gateway.serveBans))
+	mux.HandleFunc("/admin/clients/", gateway.requireAdmin(gateway.serveAdminClient))
 
 	// Serve basic info about the server
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(`
 			<h1>Walkie Talkie Gateway</h1>
@@ -193,11 +515,115 @@ func main() {
 		`))
 	})
 
-	port := ":8080"
-	log.Printf("Starting walkie talkie gateway server on port %s", port)
-	log.Printf("WebSocket endpoint: ws://localhost%s/ws", port)
+	server := &http.Server{Addr: *addr, Handler: mux}
 
-	if err := http.ListenAndServe(port, nil); err != nil {
+	// The plain listener next to a TLS one redirects to it, and answers
+	// ACME HTTP-01 challenges when Let's Encrypt provides the certificates
+	var plain *http.Server
+	plainHandler := redirectHandler(*addr, probes)
+	switch {
+	case certs != nil:
+		server.TLSConfig = serverTLSConfig(minVersion, certs.getCertificate)
+	case len(hosts) > 0:
+		var manager *autocert.Manager
+		server.TLSConfig, manager = acmeTLSConfig(minVersion, hosts, *acmeCache, *acmeEmail)
+		plainHandler = manager.HTTPHandler(plainHandler)
+	}
+	if cas != nil {
+		cas.apply(server.TLSConfig, clientAuth)
+	}
+	if *httpAddr != "" {
+		plain = &http.Server{Addr: *httpAddr, Handler: plainHandler}
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		log.Printf("Starting walkie talkie gateway server on %s", *addr)
+		if server.TLSConfig != nil {
+			log.Printf("WebSocket endpoint: wss://localhost%s/ws", *addr)
+			serveErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		log.Printf("WebSocket endpoint: ws://localhost%s/ws", *addr)
+		serveErr <- server.ListenAndServe()
+	}()
+	if plain != nil {
+		go func() {
+			log.Printf("Redirecting plain HTTP on %s to HTTPS", *httpAddr)
+			serveErr <- plain.ListenAndServe()
+		}()
+	}
+
+	// SIGHUP rereads the address lists and the TLS certificate and client
+	// CAs
+	if *ipAccessFile != "" || *frameKeys != "" || certs != nil || cas != nil {
+		hangups := make(chan os.Signal, 1)
+		signal.Notify(hangups, syscall.SIGHUP)
+		go func() {
+			for range hangups {
+				if *ipAccessFile != "" {
+					if err := gateway.ReloadIPAccess(); err != nil {
+						log.Printf("Error reloading address lists: %v", err)
+					}
+				}
+				if err := gateway.ReloadFrameKeys(); err != nil {
+					log.Printf("Error reloading frame keys: %v", err)
+				}
+				if certs != nil {
+					if err := certs.reload(); err != nil {
+						log.Printf("Error reloading the TLS certificate: %v", err)
+					} else {
+						log.Printf("TLS certificate reloaded")
+					}
+				}
+				if cas != nil {
+					if err := cas.reload(); err != nil {
+						log.Printf("Error reloading the client CAs: %v", err)
+					} else {
+						log.Printf("Client CAs reloaded")
+					}
+				}
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-serveErr:
 		log.Fatal("ListenAndServe: ", err)
+	case sig := <-signals:
+		log.Printf("Received %s, draining %d clients", sig, gateway.ClientCount())
+	}
+	gateway.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownGrace)
+	defer cancel()
+
+	// Close the WebSockets first: hijacked connections are invisible to
+	// server.Shutdown
+	if err := gateway.Stop(ctx); err != nil {
+		log.Printf("Clients did not drain within %s: %v", *shutdownGrace, err)
+	}
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if plain != nil {
+		if err := plain.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Plain HTTP server shutdown: %v", err)
+		}
+	}
+	if store != nil {
+		if err := store.Close(); err != nil {
+			log.Printf("Closing the room store: %v", err)
+		}
+	}
+	if audit != nil {
+		audit.Close()
+	}
+	if uploads != nil {
+		uploads.Close()
 	}
+	log.Printf("Shutdown complete")
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Clients with the levels capability get a level message a few times a
+// second for each sender transmitting in the room, to drive a meter
+// without decoding audio they aren't playing. The level is the one VOX and
+// silence suppression read off each frame, read at most once a frame, and
+// smoothed like a VU meter: it rises at once and falls back over
+// levelRelease. A sender whose level isn't known, in a codec other than
+// pcm16 and declaring none, is not metered, and nothing is sent while no
+// one transmits. A room no client meters skips the work.
+const (
+	// levelInterval is the least time between a sender's level messages
+	levelInterval = 200 * time.Millisecond
+
+	// levelRelease is how long a smoothed level takes to fall to 1/e of
+	// the way to a quieter one
+	levelRelease = 300 * time.Millisecond
+
+	// levelFloor is the lowest level reported, in dBFS, silence included
+	levelFloor = -90
+)
+
+// levelMessage reports a sender's smoothed level in dBFS, rounded
+type levelMessage struct {
+	Type string `json:"type"`
+	Room string `json:"room"`
+	ID   string `json:"id"`
+	DB   int    `json:"db"`
+}
+
+// levelMeter smooths the level of a client's audio. Only readPump touches
+// it.
+type levelMeter struct {
+	// Smoothed level in dBFS, and when it was last updated and reported
+	db   float64
+	at   time.Time
+	sent time.Time
+}
+
+// meter adds an audio frame the client is broadcasting to its level, and
+// every levelInterval tells the room's metering clients. Only the readPump
+// of the client's connection may call it.
+func (c *Client) meter(frame *frameBuffer, now time.Time) {
+	if c.hub.meterListeners.Load() == 0 {
+		return
+	}
+	level, known := c.frameLevel(frame)
+	if !known {
+		return
+	}
+	level = max(level, levelFloor)
+	m := &c.levelMeter
+	if level >= m.db || m.at.IsZero() {
+		m.db = level
+	} else {
+		decay := math.Exp(-float64(now.Sub(m.at)) / float64(levelRelease))
+		m.db = level + (m.db-level)*decay
+	}
+	m.at = now
+	if now.Sub(m.sent) < levelInterval {
+		return
+	}
+	m.sent = now
+	c.hub.announceLevel(c, int(math.Round(m.db)))
+}
+
+// announceLevel sends a level message for the client to every other client
+// in the room with the levels capability
+func (h *Hub) announceLevel(client *Client, db int) {
+	data, err := json.Marshal(levelMessage{Type: "level", Room: localRoom(h.room), ID: client.id, DB: db})
+	if err != nil {
+		log.Printf("Error encoding level message of client %s: %v", client.id, err)
+		return
+	}
+	out := outbound{messageType: websocket.TextMessage, data: data}
+	for _, other := range h.snapshotClients() {
+		if other != client && other.caps.has(capLevels) {
+			h.shardFor(other).send(h, other, out)
+		}
+	}
+	h.levelMessages.Add(1)
+}
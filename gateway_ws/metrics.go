@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// clientMetric is a per-client series in the Prometheus exposition
+type clientMetric struct {
+	name  string
+	kind  string
+	help  string
+	value func(ClientStats) float64
+
+	// Reported once per connection, left off a multi-room client's other
+	// memberships
+	perConnection bool
+}
+
+// clientMetrics are the per-client series /metrics exposes
+var clientMetrics = []clientMetric{
+	{"walkie_client_upstream_lost_frames_total", "counter", "Audio frames the client numbered that never reached the gateway.",
+		func(s ClientStats) float64 { return float64(s.UpstreamLost) }, true},
+	{"walkie_client_upstream_loss_percent", "gauge", "Share of the client's audio lost on the way to the gateway over the last minute.",
+		func(s ClientStats) float64 { return s.UpstreamLossPercent }, true},
+	{"walkie_client_downstream_dropped_frames_total", "counter", "Audio frames the gateway dropped on the way to the client.",
+		func(s ClientStats) float64 { return float64(s.DownstreamDropped) }, false},
+	{"walkie_client_downstream_loss_percent", "gauge", "Share of the audio for the client the gateway dropped over the last minute.",
+		func(s ClientStats) float64 { return s.DownstreamLossPercent }, false},
+	{"walkie_client_queue_delay_seconds", "gauge", "Average delay of audio for the client from reaching the gateway to being written to its socket.",
+		func(s ClientStats) float64 { return s.QueueDelayAvgUs / 1e6 }, true},
+	{"walkie_client_uplink_delay_seconds", "gauge", "Average delay of the client's audio from its capture to reaching the gateway, for clients that send capture times.",
+		func(s ClientStats) float64 { return s.UplinkDelayAvgUs / 1e6 }, true},
+}
+
+// labelEscaper escapes a Prometheus label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// serveMetrics reports the gateway's audio delay, and every client's audio
+// loss and delay, in the Prometheus text format
+func (g *Gateway) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	clients := g.Clients(scopedTenant(r))
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	if scopedTenant(r) == "" {
+		g.delay.writeMetric(out, "walkie_gateway_delay_seconds", "Delay of audio frames from reaching the gateway to being written to a listener's socket.")
+		if g.uploader != nil {
+			uploads := g.uploader.Stats()
+			for _, metric := range []struct {
+				name, kind, help string
+				value            float64
+			}{
+				{"walkie_recording_uploads_pending", "gauge", "Finished recordings waiting to upload or uploading.", float64(uploads.Pending)},
+				{"walkie_recording_uploads_total", "counter", "Recordings uploaded to object storage.", float64(uploads.Uploaded)},
+				{"walkie_recording_upload_failures_total", "counter", "Recordings that ran out of upload attempts, to be tried again by the next scan.", float64(uploads.Failed)},
+			} {
+				fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+			}
+		}
+	}
+	for _, metric := range clientMetrics {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, client := range clients {
+			if metric.perConnection && client.HomeRoom != "" {
+				continue
+			}
+			fmt.Fprintf(out, "%s{room=\"%s\",client=\"%s\"} %g\n", metric.name,
+				labelEscaper.Replace(client.Room), labelEscaper.Replace(client.ID), metric.value(client))
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A pcm16 room without floor control may have the gateway mix clients
+// talking over each other, so listeners don't get their frames interleaved
+// and garbled. Audio from a lone talker is relayed untouched. Once a second
+// client talks within mixOverlap of the first, the room's mixer goroutine
+// takes both, buffers each for mixJitterTicks to ride out its jitter, and
+// every mixTick sums what each has buffered, saturating at full scale. A
+// listener gets one frame a tick: the whole mix, or for a talker the mix
+// without its own audio. When all but one talker have gone quiet for
+// mixIdleTicks the last one's buffered audio is flushed and it is relayed
+// untouched again. Only audio in the room's canonical pcm16 format is
+// mixed; everything else, priority audio included, goes as it is.
+const (
+	mixTick        = 20 * time.Millisecond
+	mixOverlap     = 3 * mixTick
+	mixJitterTicks = 1
+	mixIdleTicks   = 5
+
+	// The most a talker may have buffered before its oldest audio is
+	// dropped, beyond its jitter allowance
+	mixMaxLagTicks = 3
+)
+
+// errMixingCodec refuses mixing in a room whose audio isn't pcm16
+var errMixingCodec = errors.New("mixing needs the room's audio codec to be " + defaultCodec)
+
+// mixer mixes a room's overlapping talkers. The Run goroutine hands it
+// frames on in and it hands what listeners are to get back to the hub.
+// Everything else belongs to its own goroutine.
+type mixer struct {
+	hub *Hub
+	in  chan BroadcastMessage
+
+	// Samples per tick, and the room's sample rate and channel count it
+	// mixes
+	samples    int
+	sampleRate int
+	channels   int
+
+	// The talkers being mixed, and when not mixing the last one relayed
+	// untouched
+	streams map[*Client]*mixStream
+	solo    *Client
+	soloAt  time.Time
+
+	// A tick's running sum and contributors, reused, and what it has to
+	// send once mixed
+	sum          []int32
+	contributors []*Client
+	out          []BroadcastMessage
+
+	// The encoders of the whole mix for clients receiving another codec
+	encoders encoders
+}
+
+// mixStream is a talker's audio waiting to be mixed
+type mixStream struct {
+	samples []int16
+
+	// Whether its jitter allowance has filled, the ticks it has waited for
+	// that, and the ticks in a row it had nothing
+	primed bool
+	waited int
+	idle   int
+
+	// Its end of transmission marker, held until its audio is out
+	eot *BroadcastMessage
+
+	// The encoders of the mix without its audio for clients receiving
+	// another codec
+	encoders encoders
+}
+
+// WithMixing has the room mix overlapping talkers. The room admin API may
+// change it while the hub runs.
+func WithMixing(enabled bool) HubOption {
+	return func(h *Hub) {
+		h.mixing.Store(enabled)
+	}
+}
+
+// WithRoomMixing sets whether rooms whose config doesn't say mix their
+// talkers
+func WithRoomMixing(enabled bool) GatewayOption {
+	return func(g *Gateway) {
+		g.mixing = enabled
+	}
+}
+
+// mixingFor reports whether the named room mixes its talkers. The caller
+// must hold the gateway's mutex.
+func (g *Gateway) mixingFor(name string) bool {
+	if mixing := g.roomConfigs[name].Mixing; mixing != nil {
+		return *mixing
+	}
+	return g.mixing
+}
+
+// updateMixing brings an open room's mixing in line with its config. The
+// caller must hold the mutex.
+func (g *Gateway) updateMixing(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.mixing.Store(g.mixingFor(name))
+	}
+}
+
+// mix hands a broadcast to the room's mixer if the room mixes and it is
+// audio the mixer takes, or an end of transmission marker that must follow
+// such audio, starting the mixer on the first. It reports whether the
+// mixer took the message, recording the audio as it was sent since what
+// comes back isn't. Only the Run goroutine may call it.
+func (h *Hub) mix(message BroadcastMessage, now time.Time) bool {
+	if !h.mixing.Load() {
+		h.stopMixing()
+		return false
+	}
+	if message.eot {
+		if h.mixer == nil {
+			return false
+		}
+	} else {
+		policy := h.audio.Load()
+		if !mixable(message, policy.audioFormat) {
+			return false
+		}
+		if h.mixer == nil || h.mixer.sampleRate != policy.SampleRate || h.mixer.channels != policy.Channels {
+			h.stopMixing()
+			h.mixer = h.newMixer(policy.SampleRate, policy.Channels)
+			go h.mixer.run()
+		}
+	}
+	h.record(message, now)
+	select {
+	case h.mixer.in <- message:
+	default:
+		message.frame.release()
+		h.mixDropped.Add(1)
+	}
+	return true
+}
+
+// stopMixing stops the room's mixer, if it runs. Only the Run goroutine may
+// call it.
+func (h *Hub) stopMixing() {
+	if h.mixer != nil {
+		close(h.mixer.in)
+		h.mixer = nil
+	}
+}
+
+// mixable reports whether the message is audio in the room's canonical
+// format, pcm16, from a sender declaring no other
+func mixable(message BroadcastMessage, canonical audioFormat) bool {
+	if message.frame.messageType != websocket.BinaryMessage || message.frame.header.priority || message.sender == nil {
+		return false
+	}
+	if !strings.EqualFold(canonical.Codec, defaultCodec) {
+		return false
+	}
+	declared := message.sender.format
+	return declared.fits(audioFormat{Codec: canonical.Codec, SampleRate: canonical.SampleRate, Channels: canonical.Channels})
+}
+
+// newMixer returns a mixer for audio at the sample rate and channel count
+func (h *Hub) newMixer(sampleRate, channels int) *mixer {
+	samples := sampleRate * channels * int(mixTick/time.Millisecond) / 1000
+	return &mixer{
+		hub:        h,
+		in:         make(chan BroadcastMessage, h.broadcastQueue),
+		samples:    samples,
+		sampleRate: sampleRate,
+		channels:   channels,
+		streams:    make(map[*Client]*mixStream),
+		sum:        make([]int32, samples),
+	}
+}
+
+// run mixes until in is closed, ticking only while there are talkers to
+// mix
+func (m *mixer) run() {
+	ticker := time.NewTicker(mixTick)
+	ticker.Stop()
+	defer ticker.Stop()
+	var tick <-chan time.Time
+
+	for {
+		select {
+		case message, ok := <-m.in:
+			if !ok {
+				m.drop()
+				return
+			}
+			mixing := len(m.streams) > 0
+			m.take(message, time.Now())
+			if !mixing && len(m.streams) > 0 {
+				ticker.Reset(mixTick)
+				tick = ticker.C
+			}
+
+		case now := <-tick:
+			m.tick(now)
+			if len(m.streams) == 0 {
+				ticker.Stop()
+				tick = nil
+			}
+
+		case <-m.hub.done:
+			m.drop()
+			return
+		}
+	}
+}
+
+// take accepts a frame or end of transmission marker from the hub
+func (m *mixer) take(message BroadcastMessage, now time.Time) {
+	sender := message.sender
+	stream := m.streams[sender]
+	if message.eot {
+		if stream == nil {
+			m.forward(message)
+		} else if stream.eot == nil {
+			stream.eot = &message
+		} else {
+			message.frame.release()
+		}
+		return
+	}
+
+	if stream == nil && len(m.streams) == 0 {
+		if m.solo == nil || m.solo == sender || now.Sub(m.soloAt) > mixOverlap {
+			m.solo, m.soloAt = sender, now
+			m.hub.mixPassthrough.Add(1)
+			m.forward(message)
+			return
+		}
+		m.streams[m.solo] = &mixStream{}
+		m.solo = nil
+	}
+	if stream == nil {
+		stream = &mixStream{}
+		m.streams[sender] = stream
+	}
+
+	data := message.frame.data
+	for i := 0; i+1 < len(data); i += 2 {
+		stream.samples = append(stream.samples, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	if excess := len(stream.samples) - (1+mixJitterTicks+mixMaxLagTicks)*m.samples; excess > 0 {
+		stream.samples = stream.samples[:copy(stream.samples, stream.samples[excess:])]
+		m.hub.mixOverruns.Add(1)
+	}
+	message.frame.release()
+}
+
+// tick mixes a tick's worth of each primed talker's audio and sends it
+// out, then lets go of talkers done talking. Only the mixing counts towards
+// its time, not waiting on the hub to take what it sends.
+func (m *mixer) tick(now time.Time) {
+	started := time.Now()
+	for i := range m.sum {
+		m.sum[i] = 0
+	}
+	contributors := m.contributors[:0]
+	for client, stream := range m.streams {
+		if !stream.primed {
+			stream.waited++
+			if len(stream.samples) < (1+mixJitterTicks)*m.samples && stream.waited <= mixJitterTicks && stream.eot == nil {
+				continue
+			}
+			stream.primed = true
+		}
+		if len(stream.samples) == 0 {
+			stream.idle++
+			continue
+		}
+		stream.idle = 0
+		if len(stream.samples) < m.samples {
+			m.hub.mixUnderruns.Add(1)
+		}
+		for i, sample := range stream.samples[:min(len(stream.samples), m.samples)] {
+			m.sum[i] += int32(sample)
+		}
+		contributors = append(contributors, client)
+	}
+	if len(contributors) > 0 {
+		m.send(contributors, m.samples, now)
+	}
+	for _, client := range contributors {
+		stream := m.streams[client]
+		stream.samples = stream.samples[:copy(stream.samples, stream.samples[min(len(stream.samples), m.samples):])]
+	}
+
+	for client, stream := range m.streams {
+		if len(stream.samples) == 0 && (stream.eot != nil || stream.idle >= mixIdleTicks) {
+			m.end(client, stream)
+		}
+	}
+	if len(m.streams) == 1 {
+		for client, stream := range m.streams {
+			m.flush(client, stream, now)
+		}
+	}
+	m.contributors = contributors[:0]
+	m.hub.mixTicks.Add(1)
+	m.hub.mixNanos.Add(uint64(time.Since(started)))
+
+	for i, message := range m.out {
+		m.forward(message)
+		m.out[i] = BroadcastMessage{}
+	}
+	m.out = m.out[:0]
+}
+
+// mixTickMicros returns the mean time the room's mixer took over a tick, in
+// microseconds
+func (h *Hub) mixTickMicros() float64 {
+	ticks := h.mixTicks.Load()
+	if ticks == 0 {
+		return 0
+	}
+	return float64(h.mixNanos.Load()) / float64(ticks) / 1e3
+}
+
+// flush sends out the last talker's buffered audio in one frame and
+// relays it untouched from here on
+func (m *mixer) flush(client *Client, stream *mixStream, now time.Time) {
+	if n := len(stream.samples); n > 0 {
+		if n > len(m.sum) {
+			m.sum = make([]int32, n)
+		}
+		for i, sample := range stream.samples {
+			m.sum[i] = int32(sample)
+		}
+		m.send([]*Client{client}, n, now)
+		m.sum = m.sum[:m.samples]
+		stream.samples = stream.samples[:0]
+	}
+	m.end(client, stream)
+	m.solo, m.soloAt = client, now
+}
+
+// end stops mixing the talker, sending on its end of transmission marker
+// after its audio
+func (m *mixer) end(client *Client, stream *mixStream) {
+	if stream.eot != nil {
+		m.out = append(m.out, *stream.eot)
+	}
+	delete(m.streams, client)
+}
+
+// send queues the first n samples of the mix to go out: the whole of it for
+// listeners, and for each contributor the mix without its own audio, which
+// for a lone contributor is nothing
+func (m *mixer) send(contributors []*Client, n int, now time.Time) {
+	frame := m.frame(now, n, nil)
+	m.hub.transcode(frame, &m.encoders, m.sampleRate, m.channels)
+	message := BroadcastMessage{frame: frame, queued: now, mix: make(map[*Client]*frameBuffer, len(contributors))}
+	for _, client := range contributors {
+		if len(contributors) == 1 {
+			message.mix[client] = nil
+			continue
+		}
+		stream := m.streams[client]
+		message.mix[client] = m.frame(now, n, stream.samples)
+		m.hub.transcode(message.mix[client], &stream.encoders, m.sampleRate, m.channels)
+	}
+	m.hub.mixedFrames.Add(1)
+	m.out = append(m.out, message)
+}
+
+// frame encodes the first n samples of the mix, less own, saturating at
+// full scale
+func (m *mixer) frame(now time.Time, n int, own []int16) *frameBuffer {
+	frame := getFrame()
+	frame.header = frameHeader{received: now.UnixMilli()}
+	frame.receivedAt = now
+	for i, sample := range m.sum[:n] {
+		if i < len(own) {
+			sample -= int32(own[i])
+		}
+		sample = max(math.MinInt16, min(math.MaxInt16, sample))
+		frame.data = binary.LittleEndian.AppendUint16(frame.data, uint16(int16(sample)))
+	}
+	return frame
+}
+
+// forward hands the hub a message to fan out as it is
+func (m *mixer) forward(message BroadcastMessage) {
+	message.mixed = true
+	select {
+	case m.hub.broadcast <- message:
+	case <-m.hub.done:
+		message.release()
+	}
+}
+
+// drop lets go of what the mixer holds as it stops, sending on the
+// markers of talkers it was mixing
+func (m *mixer) drop() {
+	for client, stream := range m.streams {
+		if stream.eot != nil {
+			select {
+			case <-m.hub.done:
+				stream.eot.frame.release()
+			default:
+				m.forward(*stream.eot)
+			}
+		}
+		delete(m.streams, client)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// BenchmarkMixTick runs a room's mixer over a tick of overlapping talkers:
+// taking each one's frame and mixing the lot, whole and less each talker's
+// own audio. It reports the share of a core one room's mixer takes in real
+// time, a tick every mixTick.
+func BenchmarkMixTick(b *testing.B) {
+	for _, rate := range []int{defaultSampleRate, 48000} {
+		for _, talkers := range []int{2, 4, 8} {
+			b.Run(fmt.Sprintf("rate=%d/talkers=%d", rate, talkers), func(b *testing.B) {
+				h := NewHub()
+				m := h.newMixer(rate, defaultChannels)
+				clients := make([]*Client, talkers)
+				for i := range clients {
+					clients[i] = &Client{id: fmt.Sprintf("t%d", i), hub: h}
+				}
+				// A tone a talker, loud enough for the sum to clip
+				audio := make([][]byte, talkers)
+				for i := range audio {
+					for n := 0; n < m.samples; n++ {
+						sample := int16(20000 * math.Sin(2*math.Pi*float64(300*(i+1)*n)/float64(rate)))
+						audio[i] = binary.LittleEndian.AppendUint16(audio[i], uint16(sample))
+					}
+				}
+				take := func(now time.Time) {
+					for i, client := range clients {
+						frame := getFrame()
+						frame.data = append(frame.data, audio[i]...)
+						m.take(BroadcastMessage{frame: frame, sender: client}, now)
+					}
+				}
+				// Past passthrough and the jitter allowance, every talker
+				// mixed
+				now := time.Now()
+				for i := 0; i <= mixJitterTicks; i++ {
+					take(now)
+					m.tick(now)
+					for len(h.broadcast) > 0 {
+						(<-h.broadcast).release()
+					}
+				}
+				if len(m.streams) != talkers {
+					b.Fatalf("mixing %d talkers, want %d", len(m.streams), talkers)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					take(now)
+					m.tick(now)
+					for len(h.broadcast) > 0 {
+						(<-h.broadcast).release()
+					}
+				}
+				perTick := float64(b.Elapsed()) / float64(b.N)
+				b.ReportMetric(perTick/float64(mixTick)*100, "%core/room")
+			})
+		}
+	}
+}
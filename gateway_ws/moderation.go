@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultMuteWindow is how long a muted client stays muted after it
+// disconnects, so it can't shed the mute by rejoining
+const defaultMuteWindow = 10 * time.Minute
+
+// ParseModeratorSecrets parses a comma-separated list of room=secret entries
+func ParseModeratorSecrets(list string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, secret, ok := strings.Cut(entry, "=")
+		if !ok || !validRoomName(name) || secret == "" {
+			return nil, fmt.Errorf("invalid moderator secret %q: want room=secret", entry)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// WithModeratorSecrets sets the secrets that admit clients to the rooms they
+// name as moderators
+func WithModeratorSecrets(secrets map[string]string) GatewayOption {
+	return func(g *Gateway) {
+		for name, secret := range secrets {
+			secret := secret
+			g.configureRoom(name, func(config *RoomConfig) {
+				config.ModeratorSecret = secret
+			})
+		}
+	}
+}
+
+// WithMuteWindow sets how long a mute outlives the muted client's
+// connection. Zero lifts it when the client disconnects.
+func WithMuteWindow(window time.Duration) HubOption {
+	return func(h *Hub) {
+		if window >= 0 {
+			h.muteWindow = window
+		}
+	}
+}
+
+// lookup returns the registered client with the given ID, or nil
+func (h *Hub) lookup(id string) *Client {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.byID[id]
+}
+
+// sendControl queues a control message for a single client. It is safe to
+// call from any goroutine that doesn't hold the client's shard lock.
+func (h *Hub) sendControl(client *Client, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding control message for client %s: %v", client.id, err)
+		return
+	}
+	h.shardFor(client).send(h, client, outbound{messageType: websocket.TextMessage, data: data})
+}
+
+// kick, mute and unmute name their target, and for a multi-room client
+// the room
+func init() {
+	moderate := controlHandler{
+		validate: requireField("target", func(m controlMessage) bool { return m.Target != "" }),
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.moderate(m)
+			}
+		},
+	}
+	registerControl("kick", moderate)
+	registerControl("mute", moderate)
+	registerControl("unmute", moderate)
+}
+
+// moderates reports whether the client is a moderator of its room, which
+// message needs, refusing message if it isn't
+func (c *Client) moderates(message controlMessage) bool {
+	if c.moderator.Load() {
+		return true
+	}
+	log.Printf("Client %s is not a moderator of room %s, refused its %s", c.id, c.hub.room, message.Type)
+	c.gateway.audit.record(AuditEvent{Event: message.Type, ClientID: message.Target, Room: c.hub.room, Actor: c.id, Outcome: auditDenied, Reason: "not a moderator"})
+	c.hub.sendControl(c, controlError{Type: "error", Code: "forbidden", Request: message.Type, Target: message.Target})
+	return false
+}
+
+// moderate carries out a moderator's kick, mute or unmute of message.Target
+func (c *Client) moderate(message controlMessage) {
+	if !c.moderates(message) {
+		return
+	}
+	target := c.hub.lookup(message.Target)
+	if target == nil {
+		c.hub.sendControl(c, controlError{Type: "error", Code: "not_found", Request: message.Type, Target: message.Target})
+		return
+	}
+
+	switch message.Type {
+	case "kick":
+		log.Printf("Client %s removed from room %s by moderator %s", target.id, c.hub.room, c.id)
+		target.leave(reasonRemovedByModerator)
+	case "mute", "unmute":
+		state := c.hub.setMuted(target, message.Type == "mute")
+		log.Printf("Client %s %s in room %s by moderator %s", target.id, state, c.hub.room, c.id)
+	}
+	c.gateway.auditClient(message.Type, target, c.id, auditOK, "")
+}
+
+// setMuted mutes or unmutes the client and tells it so, and the room if
+// that changed it. A mute lasts while the client is connected and for the
+// mute window after. It returns the notice's type, "muted" or "unmuted".
+func (h *Hub) setMuted(client *Client, muted bool) string {
+	h.muteMutex.Lock()
+	if muted {
+		h.mutes[client.id] = time.Time{}
+	} else {
+		delete(h.mutes, client.id)
+	}
+	h.muteMutex.Unlock()
+
+	notice := muteMessage{Type: "unmuted", Room: localRoom(h.room)}
+	event := presenceUnmuted
+	if muted {
+		notice.Type = "muted"
+		event = presenceMuted
+	}
+	// Taken under the presence mutex so the roster agrees with the
+	// presence messages, and a mute is never announced for a client whose
+	// leave was
+	h.presenceMutex.Lock()
+	if client.muted.Swap(muted) != muted && h.shardFor(client).has(client) {
+		h.announcePresence(client, event, closeReason{})
+	}
+	h.presenceMutex.Unlock()
+	h.sendControl(client, notice)
+	return notice.Type
+}
+
+// releaseMute starts the mute window of a muted client that disconnected
+func (h *Hub) releaseMute(client *Client, now time.Time) {
+	if !client.muted.Load() {
+		return
+	}
+	h.muteMutex.Lock()
+	defer h.muteMutex.Unlock()
+	if _, ok := h.mutes[client.id]; !ok {
+		return
+	}
+	if h.muteWindow == 0 {
+		delete(h.mutes, client.id)
+		return
+	}
+	h.mutes[client.id] = now.Add(h.muteWindow)
+}
+
+// restoreMute reports whether a joining client is still serving a mute from
+// an earlier connection, and renews it for the new one. Expired mutes are
+// forgotten on the way.
+func (h *Hub) restoreMute(id string, now time.Time) bool {
+	h.muteMutex.Lock()
+	defer h.muteMutex.Unlock()
+	for muted, until := range h.mutes {
+		if !until.IsZero() && !now.Before(until) {
+			delete(h.mutes, muted)
+		}
+	}
+	if _, ok := h.mutes[id]; !ok {
+		return false
+	}
+	h.mutes[id] = time.Time{}
+	return true
+}
+
+// joinsMuted reports whether the claims have the client join its rooms
+// muted
+func (c *tokenClaims) joinsMuted() bool {
+	return c != nil && c.Muted
+}
+
+// muteMessage tells a client it was muted or unmuted, and in which room
+type muteMessage struct {
+	Type string `json:"type"`
+	Room string `json:"room"`
+}
+
+// serveModerator promotes or demotes a client of an open room:
+// POST or DELETE /rooms/{room}/moderators/{client}
+func (g *Gateway) serveModerator(w http.ResponseWriter, r *http.Request, name, id string) {
+	var moderator bool
+	switch r.Method {
+	case http.MethodPost:
+		moderator = true
+	case http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	g.mutex.Lock()
+	rm := g.rooms[name]
+	g.mutex.Unlock()
+	if rm == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+	client := rm.hub.lookup(id)
+	if client == nil {
+		http.Error(w, "no such client", http.StatusNotFound)
+		return
+	}
+
+	client.moderator.Store(moderator)
+	log.Printf("Client %s moderator of room %s: %t", client.id, rm.name, moderator)
+	event := "moderator_added"
+	if !moderator {
+		event = "moderator_removed"
+	}
+	g.auditClient(event, client, auditActor(r), auditOK, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveMute mutes or unmutes a client of an open room, as a moderator
+// would: POST or DELETE /rooms/{room}/mutes/{client}
+func (g *Gateway) serveMute(w http.ResponseWriter, r *http.Request, name, id string) {
+	var muted bool
+	switch r.Method {
+	case http.MethodPost:
+		muted = true
+	case http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	g.mutex.Lock()
+	rm := g.rooms[name]
+	g.mutex.Unlock()
+	if rm == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+	client := rm.hub.lookup(id)
+	if client == nil {
+		http.Error(w, "no such client", http.StatusNotFound)
+		return
+	}
+
+	state := rm.hub.setMuted(client, muted)
+	log.Printf("Client %s %s in room %s by the admin API", client.id, state, rm.name)
+	event := "mute"
+	if !muted {
+		event = "unmute"
+	}
+	g.auditClient(event, client, auditActor(r), auditOK, "")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultMaxRoomsPerConnection caps the rooms a multi-room client may be in
+// at once, its own included
+const defaultMaxRoomsPerConnection = 8
+
+// errMissingRoomTag is returned for a multi-room client's raw audio frame
+// too short to hold its room tag
+var errMissingRoomTag = errors.New("audio frame has no room tag")
+
+// WithMaxRoomsPerConnection caps the rooms a multi-room client may be in at
+// once, the one it connected to included
+func WithMaxRoomsPerConnection(max int) GatewayOption {
+	return func(g *Gateway) {
+		if max > 0 {
+			g.maxRoomsPerConnection = max
+		}
+	}
+}
+
+// requestedMultiRoom reports whether the client asked with the multi_room
+// query parameter or X-Multi-Room header to join several rooms at once
+func requestedMultiRoom(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("multi_room")
+	if value == "" {
+		value = r.Header.Get("X-Multi-Room")
+	}
+	if value == "" {
+		return false, nil
+	}
+	multi, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid multi_room value %q", value)
+	}
+	return multi, nil
+}
+
+// leftMessage tells a multi-room client that one of its memberships ended,
+// at its own request or the room's
+type leftMessage struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// untag strips the room tag off a multi-room client's raw audio frame and
+// returns the room: one byte of length, then the name. An empty name means
+// the client's own room.
+func untag(frame *frameBuffer) (string, error) {
+	data := frame.data
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return "", errMissingRoomTag
+	}
+	n := 1 + int(data[0])
+	room := string(data[1:n])
+	// Shifted down rather than resliced so the pooled buffer keeps its
+	// capacity
+	frame.data = data[:copy(data, data[n:])]
+	return room, nil
+}
+
+// writeTagged writes audio for a multi-room client tagged with the room it
+// comes from, as untag expects raw frames, or in the JSON envelope's room
+// field. walkie.framed.v1 frames carry their header before the tag, whose
+// CRC in integrity mode covers the tag too. Tagged frames differ per room,
+// so they aren't prepared once for every listener. Tenants' clients see
+// their rooms' names without the tenant.
+func writeTagged(conn *websocket.Conn, protocol, room string, frame *frameBuffer, integrity, replayed bool) error {
+	room = localRoom(room)
+	audio := frame.data
+	if replayed && (protocol == protocolJSON || protocol == protocolPB) {
+		return writeReplayed(conn, protocol, room, frame)
+	}
+	if protocol == protocolPB {
+		data, err := audioToPB(room, frame, false)
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.BinaryMessage, data)
+	}
+	if protocol == protocolJSON {
+		data, err := json.Marshal(frame.envelope(room))
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+	var data []byte
+	switch {
+	case protocol == protocolFramed && integrity:
+		data = make([]byte, 0, frame.header.size()+framedCRCSize+1+len(room)+len(audio))
+		data = appendChecksummedHeader(data, frame.header, []byte{byte(len(room))}, []byte(room), audio)
+	case protocol == protocolFramed:
+		data = make([]byte, 0, frame.header.size()+1+len(room)+len(audio))
+		data = appendFrameHeader(data, frame.header)
+	default:
+		data = make([]byte, 0, 1+len(room)+len(audio))
+	}
+	data = append(data, byte(len(room)))
+	data = append(data, room...)
+	data = append(data, audio...)
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// member returns a client that holds the connection's membership of another
+// room. It has no pumps or connection of its own: forward passes what the
+// room sends it on to the owner.
+func (c *Client) member(hub *Hub, moderator bool) *Client {
+	member := &Client{
+		send:        make(chan outbound, cap(c.send)),
+		urgent:      make(chan outbound, maxUrgentFrames),
+		hub:         hub,
+		gateway:     c.gateway,
+		claims:      c.claims,
+		key:         c.key,
+		id:          c.id,
+		remoteIP:    c.remoteIP,
+		protocol:    c.protocol,
+		compression: c.compression,
+		slowPolicy:  c.slowPolicy,
+		owner:       c,
+		blocks:      c.blocks,
+		name:        c.name,
+		presence:    c.presence,
+		integrity:   c.integrity,
+		vox:         c.vox,
+		receive:     c.receive,
+		caps:        c.caps,
+	}
+	member.batch.Store(c.batch.Load())
+	member.moderator.Store(moderator)
+	member.listener.Store(c.listener.Load())
+	member.lowTier.Store(c.lowTier.Load())
+	member.version.Store(c.version.Load())
+	member.touch()
+	return member
+}
+
+// lookupMember returns the client's membership of the named room besides
+// its own, or nil
+func (c *Client) lookupMember(room string) *Client {
+	c.membersMutex.Lock()
+	defer c.membersMutex.Unlock()
+	return c.members[room]
+}
+
+// memberFor returns the client's membership a request for the named room
+// acts through: the client itself for its own room, or for any room unless
+// it is multi-room. A multi-room client that isn't in the room gets an
+// error and memberFor returns nil.
+func (c *Client) memberFor(request, room string) *Client {
+	name := c.qualify(room)
+	if !c.multiRoom || name == "" || name == c.hub.room {
+		return c
+	}
+	if member := c.lookupMember(name); member != nil {
+		return member
+	}
+	c.fail("not_joined", request, room)
+	return nil
+}
+
+// subscribe handles a multi-room client's join by adding a membership of
+// the named room, subject to the room's credential, capacity and audio
+// formats, declaring format and tags. Only readPump may call it.
+func (c *Client) subscribe(name, credential string, format audioFormat, tags []string) {
+	c.membersMutex.Lock()
+	rooms := 1 + len(c.members)
+	c.membersMutex.Unlock()
+	if rooms >= c.gateway.maxRoomsPerConnection {
+		c.fail("too_many_rooms", "join", name)
+		return
+	}
+
+	member := c.admit("join", name, credential, format, tags, c.member)
+	if member == nil {
+		return
+	}
+	c.membersMutex.Lock()
+	c.members[member.hub.room] = member
+	c.membersMutex.Unlock()
+	log.Printf("Client %s joined room %s as well as %s", c.id, name, c.hub.room)
+	go member.forward()
+}
+
+// unsubscribe handles a multi-room client's leave by ending its membership
+// of the named room. The client's own room can't be left, as it holds the
+// connection.
+func (c *Client) unsubscribe(name string) {
+	if c.qualify(name) == c.hub.room {
+		c.fail("home_room", "leave", name)
+		return
+	}
+	member := c.lookupMember(c.qualify(name))
+	if member == nil {
+		c.fail("not_joined", "leave", name)
+		return
+	}
+	member.closeWhisper()
+	member.leave(reasonLeft)
+}
+
+// forward stands in for a member client's writePump. It hands what the
+// room sends to the owner's writePump, tagged with the room, until the room
+// closes the membership or the connection goes away.
+func (c *Client) forward() {
+	defer c.hub.pumps.Done()
+	owner := c.owner
+
+	for {
+		select {
+		case message := <-c.urgent:
+			message.room = c.hub.room
+			if !owner.funnel(message) {
+				c.leave(reasonLeft)
+				c.discardQueued()
+				return
+			}
+			c.touch()
+
+		case message, ok := <-c.send:
+			if !ok {
+				owner.dropMember(c)
+				c.discardUrgent()
+				return
+			}
+			if c.closing.Load() && !c.closeReason.flush {
+				message.release()
+				continue
+			}
+			message.room = c.hub.room
+			if !owner.funnel(message) {
+				c.leave(reasonLeft)
+				c.discardQueued()
+				return
+			}
+			c.touch()
+
+		case <-owner.writeDone:
+			c.leave(reasonLeft)
+			c.discardQueued()
+			return
+		}
+	}
+}
+
+// funnel hands a message from another room to writePump. It reports false,
+// having released the message, if writePump has exited.
+func (c *Client) funnel(message outbound) bool {
+	select {
+	case c.fanIn <- message:
+		return true
+	case <-c.writeDone:
+		message.release()
+		return false
+	}
+}
+
+// dropMember forgets a membership its room has closed and tells the client
+// with a left message
+func (c *Client) dropMember(member *Client) {
+	room := member.hub.room
+	c.membersMutex.Lock()
+	if c.members[room] == member {
+		delete(c.members, room)
+	}
+	c.membersMutex.Unlock()
+
+	reason := member.closeReason
+	log.Printf("Client %s left room %s (%s)", c.id, room, reason)
+	data, err := json.Marshal(leftMessage{Type: "left", Room: localRoom(room), Code: reason.code, Reason: reason.text})
+	if err != nil {
+		log.Printf("Error encoding left message for client %s: %v", c.id, err)
+		return
+	}
+	c.funnel(outbound{messageType: websocket.TextMessage, data: data})
+}
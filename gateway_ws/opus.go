@@ -0,0 +1,52 @@
+//go:build opus
+
+package main
+
+import (
+	"encoding/binary"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// opusPacketBuffer is the room libopus recommends for an encoded packet
+const opusPacketBuffer = 4000
+
+// Built with -tags opus, listeners may receive pcm16 audio as Opus, which
+// needs libopus through cgo. So that its stream decoding doesn't need
+// libopusfile as well, build with -tags opus,nolibopusfile.
+func init() {
+	RegisterTranscoder("opus", newOpusTranscoder)
+}
+
+// opusTranscoder encodes a stream of pcm16 audio to Opus, a frame to a
+// packet. Opus takes frames of 2.5, 5, 10, 20, 40 or 60ms.
+type opusTranscoder struct {
+	encoder *opus.Encoder
+	samples []int16
+	packet  []byte
+}
+
+// newOpusTranscoder opens a VoIP Opus encoder
+func newOpusTranscoder(sampleRate, channels, bitrate int) (Transcoder, error) {
+	encoder, err := opus.NewEncoder(sampleRate, channels, opus.AppVoIP)
+	if err != nil {
+		return nil, err
+	}
+	if err := encoder.SetBitrate(bitrate); err != nil {
+		return nil, err
+	}
+	return &opusTranscoder{encoder: encoder, packet: make([]byte, opusPacketBuffer)}, nil
+}
+
+// Encode encodes a frame of little-endian 16-bit samples to an Opus packet
+func (t *opusTranscoder) Encode(pcm []byte) ([]byte, error) {
+	t.samples = t.samples[:0]
+	for i := 0; i+1 < len(pcm); i += 2 {
+		t.samples = append(t.samples, int16(binary.LittleEndian.Uint16(pcm[i:])))
+	}
+	n, err := t.encoder.Encode(t.samples, t.packet)
+	if err != nil {
+		return nil, err
+	}
+	return t.packet[:n], nil
+}
@@ -0,0 +1,154 @@
+//go:build opus
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/hraban/opus.v2"
+)
+
+const (
+	// sineFrames is how many 20ms frames of tone each round trip sends
+	sineFrames = 50
+	// sineWarmUp is how many frames the encoder may take to settle,
+	// left out of the comparison
+	sineWarmUp = 5
+	// minSNR is the signal to noise ratio in dB a decoded tone must keep
+	minSNR = 10.0
+)
+
+// sineFrame returns the n'th 20ms frame of a 440Hz tone at sampleRate, mono
+func sineFrame(sampleRate, n int) []int16 {
+	samples := sampleRate / 50
+	frame := make([]int16, samples)
+	for i := range frame {
+		t := float64(n*samples+i) / float64(sampleRate)
+		frame[i] = int16(10000 * math.Sin(2*math.Pi*440*t))
+	}
+	return frame
+}
+
+// pcmBytes returns samples as little-endian pcm16
+func pcmBytes(samples []int16) []byte {
+	data := make([]byte, 0, 2*len(samples))
+	for _, sample := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(sample))
+	}
+	return data
+}
+
+// decodeOpus decodes an Opus packet a frame at a time to samples
+func decodeOpus(t *testing.T, decoder *opus.Decoder, packet []byte) []int16 {
+	t.Helper()
+	pcm := make([]int16, 5760)
+	n, err := decoder.Decode(packet, pcm)
+	if err != nil {
+		t.Fatalf("decoding a %d byte packet: %v", len(packet), err)
+	}
+	return pcm[:n]
+}
+
+// snr returns the signal to noise ratio in dB of got against sent, aligned
+// at whichever lag of up to maxLag samples compares best, for the codec's
+// delay. The first skip samples are left out.
+func snr(sent, got []int16, skip, maxLag int) float64 {
+	best := math.Inf(-1)
+	for lag := 0; lag <= maxLag; lag++ {
+		var signal, noise float64
+		for i := skip; i+maxLag < len(sent) && i+lag < len(got); i++ {
+			s, g := float64(sent[i]), float64(got[i+lag])
+			signal += s * s
+			noise += (s - g) * (s - g)
+		}
+		if noise == 0 {
+			return math.Inf(1)
+		}
+		if ratio := 10 * math.Log10(signal/noise); ratio > best {
+			best = ratio
+		}
+	}
+	return best
+}
+
+// A tone encoded a frame to a packet decodes back to nearly the tone, past
+// the codec's delay, in narrow, wide and full band
+func TestOpusSineRoundTrip(t *testing.T) {
+	for _, rate := range []int{8000, defaultSampleRate, 48000} {
+		t.Run(fmt.Sprintf("rate=%d", rate), func(t *testing.T) {
+			transcoder, err := newOpusTranscoder(rate, 1, defaultTranscodeBitrate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder, err := opus.NewDecoder(rate, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sent, got []int16
+			for n := 0; n < sineFrames; n++ {
+				frame := sineFrame(rate, n)
+				sent = append(sent, frame...)
+				packet, err := transcoder.Encode(pcmBytes(frame))
+				if err != nil {
+					t.Fatalf("encoding frame %d: %v", n, err)
+				}
+				decoded := decodeOpus(t, decoder, packet)
+				if len(decoded) != len(frame) {
+					t.Fatalf("frame %d decoded to %d samples, want %d", n, len(decoded), len(frame))
+				}
+				got = append(got, decoded...)
+			}
+			// Up to two frames' delay
+			frame := rate / 50
+			if ratio := snr(sent, got, sineWarmUp*frame, 2*frame); ratio < minSNR {
+				t.Errorf("tone decoded at %.1f dB SNR, want at least %.0f", ratio, minSNR)
+			}
+		})
+	}
+}
+
+// Listeners joining with ?receive=opus get a tone sent as pcm16 as Opus
+// packets that decode back to it, encoded once a frame however many listen
+func TestOpusReceive(t *testing.T) {
+	tg := newTestGateway(t, nil)
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	listeners := make([]*websocket.Conn, 2)
+	for i := range listeners {
+		listeners[i], _ = tg.dial(t, "/ws?receive=opus", fmt.Sprintf("listener%d", i))
+		readControl(t, listeners[i], "joined")
+	}
+	hub := tg.hub(t, defaultRoom)
+	waitFor(t, "everyone to join", func() bool { return hub.ClientCount() == 3 })
+	go drain(talker, nil, nil)
+
+	var sent []int16
+	for n := 0; n < sineFrames; n++ {
+		frame := sineFrame(defaultSampleRate, n)
+		sent = append(sent, frame...)
+		if err := talker.WriteMessage(websocket.BinaryMessage, pcmBytes(frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, listener := range listeners {
+		decoder, err := opus.NewDecoder(defaultSampleRate, defaultChannels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int16
+		for n := 0; n < sineFrames; n++ {
+			got = append(got, decodeOpus(t, decoder, readAudio(t, listener))...)
+		}
+		frame := defaultSampleRate / 50
+		if ratio := snr(sent, got, sineWarmUp*frame, 2*frame); ratio < minSNR {
+			t.Errorf("listener %d decoded the tone at %.1f dB SNR, want at least %.0f", i, ratio, minSNR)
+		}
+	}
+	if transcoded := hub.Stats().Transcoded; transcoded != sineFrames {
+		t.Errorf("%d frames transcoded, want %d", transcoded, sineFrames)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originPattern is an allowed origin: a scheme and host, with a port if it
+// isn't the scheme's default, and with subdomains set if the host was given
+// as *.domain, in which case any subdomain of it matches but the domain
+// itself doesn't
+type originPattern struct {
+	scheme     string
+	host       string
+	subdomains bool
+}
+
+// originAllowlist lists the origins browsers may open WebSockets from
+type originAllowlist []originPattern
+
+// ParseOriginAllowlist parses a comma-separated list of origins such as
+// https://app.example.com and wildcard origins such as https://*.example.com
+func ParseOriginAllowlist(list string) (originAllowlist, error) {
+	var origins originAllowlist
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		scheme, host, ok := strings.Cut(strings.ToLower(entry), "://")
+		pattern := originPattern{scheme: scheme, host: host}
+		if rest, wildcard := strings.CutPrefix(host, "*."); wildcard {
+			pattern.host, pattern.subdomains = rest, true
+		}
+		if !ok || scheme == "" || pattern.host == "" || strings.ContainsAny(pattern.host, "*/?#@") {
+			return nil, fmt.Errorf("invalid allowed origin %q: want scheme://host[:port] or scheme://*.domain[:port]", entry)
+		}
+		origins = append(origins, pattern)
+	}
+	return origins, nil
+}
+
+// allows reports whether a browser's Origin header is on the list
+func (l originAllowlist) allows(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.User != nil {
+		return false
+	}
+	for _, pattern := range l {
+		switch {
+		case pattern.scheme != u.Scheme:
+		case pattern.subdomains && strings.HasSuffix(u.Host, "."+pattern.host):
+			return true
+		case !pattern.subdomains && u.Host == pattern.host:
+			return true
+		}
+	}
+	return false
+}
+
+// WithAllowedOrigins restricts upgrades to browsers on the listed origins,
+// and to clients that send no Origin, such as native apps, if allowNoOrigin
+// is set. With an empty list every origin is allowed.
+func WithAllowedOrigins(origins originAllowlist, allowNoOrigin bool) GatewayOption {
+	return func(g *Gateway) {
+		g.origins = origins
+		g.allowNoOrigin = allowNoOrigin
+	}
+}
+
+// checkOrigin is the upgrader's CheckOrigin: it stops web pages on other
+// sites from opening WebSockets with their visitors' credentials
+func (g *Gateway) checkOrigin(r *http.Request) bool {
+	if len(g.origins) == 0 {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return g.allowNoOrigin
+	}
+	return g.origins.allows(origin)
+}
+
+// admitOrigin reports whether the request's origin may upgrade, having
+// answered 403 and counted it if not. serveWS checks before registering, so
+// a refused page never takes a client's slot, and the upgrader checks again.
+func (g *Gateway) admitOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if g.checkOrigin(r) {
+		return true
+	}
+	g.rejectedOrigin.Add(1)
+	log.Printf("Upgrade from %s refused: origin %q not allowed", r.RemoteAddr, r.Header.Get("Origin"))
+	g.auditRefused(r, r.Header.Get("X-Client-ID"), "", fmt.Sprintf("origin %q not allowed", r.Header.Get("Origin")))
+	http.Error(w, "origin not allowed", http.StatusForbidden)
+	return false
+}
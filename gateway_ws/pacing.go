@@ -0,0 +1,229 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A sender that batches its audio sends a burst of frames at once, faster
+// than listeners' players can take it. With -pace, or a room config's
+// "pacing": true, the room releases each sender's audio to the fan-out no
+// faster than one frame a frame duration: the one the sender declared, or
+// else the room's frame_ms. A frame on schedule, or at most paceLead frames
+// ahead of it, goes straight through, so a sender keeping to real time give
+// or take some jitter sees no added latency; one further ahead waits in the
+// sender's pace queue for its turn. A full queue, holding -pace-queue
+// frames, drops what arrives, so a sender that keeps sending too fast loses
+// audio rather than falling ever further behind. The sender's end of
+// transmission marker waits behind its queued audio, which still goes out
+// if the sender leaves. A sender whose frame duration isn't known isn't
+// paced, nor is whispered or group audio.
+const (
+	defaultPaceQueue = 50
+
+	// paceLead is how many frames ahead of its schedule a sender's audio
+	// may run and still go straight through
+	paceLead = 2
+)
+
+// pacer holds a sender's room audio until it is due
+type pacer struct {
+	mutex sync.Mutex
+
+	// Queued frames and end of transmission markers, oldest first, the
+	// audio frames among them, and when the next may go
+	queue  []BroadcastMessage
+	frames int
+	next   time.Time
+
+	// Fires releasePaced when the head of the queue is due, and whether
+	// releasePaced is handing the hub a message with the mutex released
+	timer     *time.Timer
+	releasing bool
+
+	// Audio frames queued, for stats, and those dropped for a full queue
+	depth   atomic.Int32
+	dropped atomic.Uint64
+}
+
+// WithPacing has the room pace each sender's audio to its frame duration.
+// The room admin API may change it while the hub runs.
+func WithPacing(enabled bool) HubOption {
+	return func(h *Hub) {
+		h.pacing.Store(enabled)
+	}
+}
+
+// WithPaceQueue sets how many audio frames a sender running ahead of its
+// schedule may have waiting before more are dropped
+func WithPaceQueue(frames int) HubOption {
+	return func(h *Hub) {
+		if frames <= 0 {
+			frames = defaultPaceQueue
+		}
+		h.paceQueue = frames
+	}
+}
+
+// WithRoomPacing sets whether rooms whose config doesn't say pace their
+// senders' audio
+func WithRoomPacing(enabled bool) GatewayOption {
+	return func(g *Gateway) {
+		g.pacing = enabled
+	}
+}
+
+// pacingFor reports whether the named room paces its senders' audio. The
+// caller must hold the gateway's mutex.
+func (g *Gateway) pacingFor(name string) bool {
+	if pacing := g.roomConfigs[name].Pacing; pacing != nil {
+		return *pacing
+	}
+	return g.pacing
+}
+
+// updatePacing brings an open room's pacing in line with its config. The
+// caller must hold the mutex.
+func (g *Gateway) updatePacing(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.pacing.Store(g.pacingFor(name))
+	}
+}
+
+// frameInterval returns the duration of the client's audio frames, zero if
+// neither it nor its room states one
+func (c *Client) frameInterval() time.Duration {
+	ms := c.format.FrameMs
+	if ms == 0 {
+		ms = c.hub.audio.Load().FrameMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// pace hands the hub an audio frame the client is broadcasting, taking over
+// the caller's reference, at once if it is due and otherwise once it is.
+// Only the readPump of the client's connection may call it.
+func (c *Client) pace(message BroadcastMessage) {
+	h := c.hub
+	p := &c.pacer
+	interval := c.frameInterval()
+	now := time.Now()
+	p.mutex.Lock()
+	if len(p.queue) == 0 && !p.releasing && (!h.pacing.Load() || interval <= 0 || !now.Before(p.next)) {
+		p.advance(now, interval)
+		p.mutex.Unlock()
+		h.submit(message)
+		return
+	}
+	if p.frames >= h.paceQueue {
+		p.mutex.Unlock()
+		message.frame.release()
+		h.paceDropped.Add(1)
+		if n := p.dropped.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("Pace queue full, dropped %d frames from client %s", n, c.id)
+		}
+		return
+	}
+	p.push(message)
+	if len(p.queue) == 1 && !p.releasing {
+		c.armPacer(now)
+	}
+	p.mutex.Unlock()
+	h.pacedFrames.Add(1)
+}
+
+// holdEOT queues the client's end of transmission marker behind its queued
+// audio, if it has any, and reports whether it did
+func (c *Client) holdEOT(message BroadcastMessage) bool {
+	p := &c.pacer
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.queue) == 0 && !p.releasing {
+		return false
+	}
+	p.push(message)
+	return true
+}
+
+// releasePaced hands the hub the client's queued messages that are due, in
+// order, and arms the timer for the rest. The pacer's timer calls it.
+func (c *Client) releasePaced() {
+	h := c.hub
+	p := &c.pacer
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.releasing {
+		return
+	}
+	for len(p.queue) > 0 {
+		now := time.Now()
+		interval := c.frameInterval()
+		message := p.queue[0]
+		if !message.eot && h.pacing.Load() && interval > 0 && now.Before(p.next) {
+			c.armPacer(now)
+			return
+		}
+		p.pop()
+		if !message.eot {
+			p.advance(now, interval)
+		}
+		// Handed over with the mutex released: the hub may block the
+		// send while it ends the client's burst, which queues the
+		// marker here
+		p.releasing = true
+		p.mutex.Unlock()
+		if message.eot {
+			h.queueEOT(message)
+		} else {
+			h.submit(message)
+		}
+		p.mutex.Lock()
+		p.releasing = false
+	}
+}
+
+// armPacer sets the pacer's timer for when the next frame is due. The
+// caller must hold the pacer's mutex.
+func (c *Client) armPacer(now time.Time) {
+	p := &c.pacer
+	wait := p.next.Sub(now)
+	if p.timer == nil {
+		p.timer = time.AfterFunc(wait, c.releasePaced)
+		return
+	}
+	p.timer.Reset(wait)
+}
+
+// advance moves the schedule on past a frame released at now. A sender
+// that fell behind banks at most paceLead frames' worth of time to catch up
+// with. The caller must hold the mutex.
+func (p *pacer) advance(now time.Time, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if earliest := now.Add(-paceLead * interval); p.next.Before(earliest) {
+		p.next = earliest
+	}
+	p.next = p.next.Add(interval)
+}
+
+// push queues a message. The caller must hold the mutex.
+func (p *pacer) push(message BroadcastMessage) {
+	p.queue = append(p.queue, message)
+	if !message.eot {
+		p.frames++
+		p.depth.Store(int32(p.frames))
+	}
+}
+
+// pop drops the head of the queue. The caller must hold the mutex.
+func (p *pacer) pop() {
+	if !p.queue[0].eot {
+		p.frames--
+		p.depth.Store(int32(p.frames))
+	}
+	p.queue[0] = BroadcastMessage{}
+	p.queue = p.queue[1:]
+}
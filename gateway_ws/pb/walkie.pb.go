@@ -0,0 +1,5557 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: pb/walkie.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Envelope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Message:
+	//	*Envelope_Audio
+	//	*Envelope_Hb
+	//	*Envelope_Join
+	//	*Envelope_Leave
+	//	*Envelope_Kick
+	//	*Envelope_Mute
+	//	*Envelope_Unmute
+	//	*Envelope_Keyx
+	//	*Envelope_Block
+	//	*Envelope_Unblock
+	//	*Envelope_Group
+	//	*Envelope_Filter
+	//	*Envelope_FloorRequest
+	//	*Envelope_FloorRelease
+	//	*Envelope_FloorCancel
+	//	*Envelope_HandRaise
+	//	*Envelope_HandLower
+	//	*Envelope_HandGrant
+	//	*Envelope_HandsClear
+	//	*Envelope_FloorRevoke
+	//	*Envelope_FloorLock
+	//	*Envelope_FloorUnlock
+	//	*Envelope_Hello
+	//	*Envelope_Ping
+	//	*Envelope_Ack
+	//	*Envelope_Chat
+	//	*Envelope_Whisper
+	//	*Envelope_Roster
+	//	*Envelope_Tier
+	//	*Envelope_Joined
+	//	*Envelope_Error
+	//	*Envelope_Will
+	//	*Envelope_Muted
+	//	*Envelope_Unmuted
+	//	*Envelope_Left
+	//	*Envelope_RateLimited
+	//	*Envelope_TransmissionCutOff
+	//	*Envelope_RoomClosing
+	//	*Envelope_RoomReopened
+	//	*Envelope_Reconnect
+	//	*Envelope_Announcement
+	//	*Envelope_Quality
+	//	*Envelope_Pong
+	//	*Envelope_Tags
+	//	*Envelope_Presence
+	//	*Envelope_Talk
+	//	*Envelope_Floor
+	//	*Envelope_FloorGranted
+	//	*Envelope_FloorDenied
+	//	*Envelope_FloorQueued
+	//	*Envelope_FloorOffered
+	//	*Envelope_FloorRevoked
+	//	*Envelope_Eot
+	//	*Envelope_FloorModerated
+	//	*Envelope_Replay
+	//	*Envelope_Level
+	//	*Envelope_Json
+	Message isEnvelope_Message `protobuf_oneof:"message"`
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{0}
+}
+
+func (m *Envelope) GetMessage() isEnvelope_Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (x *Envelope) GetAudio() *Audio {
+	if x, ok := x.GetMessage().(*Envelope_Audio); ok {
+		return x.Audio
+	}
+	return nil
+}
+
+func (x *Envelope) GetHb() *Heartbeat {
+	if x, ok := x.GetMessage().(*Envelope_Hb); ok {
+		return x.Hb
+	}
+	return nil
+}
+
+func (x *Envelope) GetJoin() *Join {
+	if x, ok := x.GetMessage().(*Envelope_Join); ok {
+		return x.Join
+	}
+	return nil
+}
+
+func (x *Envelope) GetLeave() *Join {
+	if x, ok := x.GetMessage().(*Envelope_Leave); ok {
+		return x.Leave
+	}
+	return nil
+}
+
+func (x *Envelope) GetKick() *ModeratorAction {
+	if x, ok := x.GetMessage().(*Envelope_Kick); ok {
+		return x.Kick
+	}
+	return nil
+}
+
+func (x *Envelope) GetMute() *ModeratorAction {
+	if x, ok := x.GetMessage().(*Envelope_Mute); ok {
+		return x.Mute
+	}
+	return nil
+}
+
+func (x *Envelope) GetUnmute() *ModeratorAction {
+	if x, ok := x.GetMessage().(*Envelope_Unmute); ok {
+		return x.Unmute
+	}
+	return nil
+}
+
+func (x *Envelope) GetKeyx() *KeyExchange {
+	if x, ok := x.GetMessage().(*Envelope_Keyx); ok {
+		return x.Keyx
+	}
+	return nil
+}
+
+func (x *Envelope) GetBlock() *Block {
+	if x, ok := x.GetMessage().(*Envelope_Block); ok {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *Envelope) GetUnblock() *Block {
+	if x, ok := x.GetMessage().(*Envelope_Unblock); ok {
+		return x.Unblock
+	}
+	return nil
+}
+
+func (x *Envelope) GetGroup() *Group {
+	if x, ok := x.GetMessage().(*Envelope_Group); ok {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *Envelope) GetFilter() *Filter {
+	if x, ok := x.GetMessage().(*Envelope_Filter); ok {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorRequest() *FloorRequest {
+	if x, ok := x.GetMessage().(*Envelope_FloorRequest); ok {
+		return x.FloorRequest
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorRelease() *FloorRequest {
+	if x, ok := x.GetMessage().(*Envelope_FloorRelease); ok {
+		return x.FloorRelease
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorCancel() *FloorRequest {
+	if x, ok := x.GetMessage().(*Envelope_FloorCancel); ok {
+		return x.FloorCancel
+	}
+	return nil
+}
+
+func (x *Envelope) GetHandRaise() *Hand {
+	if x, ok := x.GetMessage().(*Envelope_HandRaise); ok {
+		return x.HandRaise
+	}
+	return nil
+}
+
+func (x *Envelope) GetHandLower() *Hand {
+	if x, ok := x.GetMessage().(*Envelope_HandLower); ok {
+		return x.HandLower
+	}
+	return nil
+}
+
+func (x *Envelope) GetHandGrant() *ModeratorAction {
+	if x, ok := x.GetMessage().(*Envelope_HandGrant); ok {
+		return x.HandGrant
+	}
+	return nil
+}
+
+func (x *Envelope) GetHandsClear() *Hand {
+	if x, ok := x.GetMessage().(*Envelope_HandsClear); ok {
+		return x.HandsClear
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorRevoke() *FloorModeration {
+	if x, ok := x.GetMessage().(*Envelope_FloorRevoke); ok {
+		return x.FloorRevoke
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorLock() *FloorModeration {
+	if x, ok := x.GetMessage().(*Envelope_FloorLock); ok {
+		return x.FloorLock
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorUnlock() *FloorModeration {
+	if x, ok := x.GetMessage().(*Envelope_FloorUnlock); ok {
+		return x.FloorUnlock
+	}
+	return nil
+}
+
+func (x *Envelope) GetHello() *Hello {
+	if x, ok := x.GetMessage().(*Envelope_Hello); ok {
+		return x.Hello
+	}
+	return nil
+}
+
+func (x *Envelope) GetPing() *Ping {
+	if x, ok := x.GetMessage().(*Envelope_Ping); ok {
+		return x.Ping
+	}
+	return nil
+}
+
+func (x *Envelope) GetAck() *Ack {
+	if x, ok := x.GetMessage().(*Envelope_Ack); ok {
+		return x.Ack
+	}
+	return nil
+}
+
+func (x *Envelope) GetChat() *Chat {
+	if x, ok := x.GetMessage().(*Envelope_Chat); ok {
+		return x.Chat
+	}
+	return nil
+}
+
+func (x *Envelope) GetWhisper() *Whisper {
+	if x, ok := x.GetMessage().(*Envelope_Whisper); ok {
+		return x.Whisper
+	}
+	return nil
+}
+
+func (x *Envelope) GetRoster() *Roster {
+	if x, ok := x.GetMessage().(*Envelope_Roster); ok {
+		return x.Roster
+	}
+	return nil
+}
+
+func (x *Envelope) GetTier() *Tier {
+	if x, ok := x.GetMessage().(*Envelope_Tier); ok {
+		return x.Tier
+	}
+	return nil
+}
+
+func (x *Envelope) GetJoined() *Joined {
+	if x, ok := x.GetMessage().(*Envelope_Joined); ok {
+		return x.Joined
+	}
+	return nil
+}
+
+func (x *Envelope) GetError() *Error {
+	if x, ok := x.GetMessage().(*Envelope_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *Envelope) GetWill() *Will {
+	if x, ok := x.GetMessage().(*Envelope_Will); ok {
+		return x.Will
+	}
+	return nil
+}
+
+func (x *Envelope) GetMuted() *RoomNotice {
+	if x, ok := x.GetMessage().(*Envelope_Muted); ok {
+		return x.Muted
+	}
+	return nil
+}
+
+func (x *Envelope) GetUnmuted() *RoomNotice {
+	if x, ok := x.GetMessage().(*Envelope_Unmuted); ok {
+		return x.Unmuted
+	}
+	return nil
+}
+
+func (x *Envelope) GetLeft() *Left {
+	if x, ok := x.GetMessage().(*Envelope_Left); ok {
+		return x.Left
+	}
+	return nil
+}
+
+func (x *Envelope) GetRateLimited() *RateLimited {
+	if x, ok := x.GetMessage().(*Envelope_RateLimited); ok {
+		return x.RateLimited
+	}
+	return nil
+}
+
+func (x *Envelope) GetTransmissionCutOff() *TransmissionCutOff {
+	if x, ok := x.GetMessage().(*Envelope_TransmissionCutOff); ok {
+		return x.TransmissionCutOff
+	}
+	return nil
+}
+
+func (x *Envelope) GetRoomClosing() *RoomClosing {
+	if x, ok := x.GetMessage().(*Envelope_RoomClosing); ok {
+		return x.RoomClosing
+	}
+	return nil
+}
+
+func (x *Envelope) GetRoomReopened() *RoomReopened {
+	if x, ok := x.GetMessage().(*Envelope_RoomReopened); ok {
+		return x.RoomReopened
+	}
+	return nil
+}
+
+func (x *Envelope) GetReconnect() *Reconnect {
+	if x, ok := x.GetMessage().(*Envelope_Reconnect); ok {
+		return x.Reconnect
+	}
+	return nil
+}
+
+func (x *Envelope) GetAnnouncement() *Announcement {
+	if x, ok := x.GetMessage().(*Envelope_Announcement); ok {
+		return x.Announcement
+	}
+	return nil
+}
+
+func (x *Envelope) GetQuality() *Quality {
+	if x, ok := x.GetMessage().(*Envelope_Quality); ok {
+		return x.Quality
+	}
+	return nil
+}
+
+func (x *Envelope) GetPong() *Pong {
+	if x, ok := x.GetMessage().(*Envelope_Pong); ok {
+		return x.Pong
+	}
+	return nil
+}
+
+func (x *Envelope) GetTags() *Tags {
+	if x, ok := x.GetMessage().(*Envelope_Tags); ok {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Envelope) GetPresence() *Presence {
+	if x, ok := x.GetMessage().(*Envelope_Presence); ok {
+		return x.Presence
+	}
+	return nil
+}
+
+func (x *Envelope) GetTalk() *Talk {
+	if x, ok := x.GetMessage().(*Envelope_Talk); ok {
+		return x.Talk
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloor() *Floor {
+	if x, ok := x.GetMessage().(*Envelope_Floor); ok {
+		return x.Floor
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorGranted() *FloorGrant {
+	if x, ok := x.GetMessage().(*Envelope_FloorGranted); ok {
+		return x.FloorGranted
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorDenied() *FloorGrant {
+	if x, ok := x.GetMessage().(*Envelope_FloorDenied); ok {
+		return x.FloorDenied
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorQueued() *FloorQueued {
+	if x, ok := x.GetMessage().(*Envelope_FloorQueued); ok {
+		return x.FloorQueued
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorOffered() *FloorOffered {
+	if x, ok := x.GetMessage().(*Envelope_FloorOffered); ok {
+		return x.FloorOffered
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorRevoked() *FloorGrant {
+	if x, ok := x.GetMessage().(*Envelope_FloorRevoked); ok {
+		return x.FloorRevoked
+	}
+	return nil
+}
+
+func (x *Envelope) GetEot() *Eot {
+	if x, ok := x.GetMessage().(*Envelope_Eot); ok {
+		return x.Eot
+	}
+	return nil
+}
+
+func (x *Envelope) GetFloorModerated() *FloorModerated {
+	if x, ok := x.GetMessage().(*Envelope_FloorModerated); ok {
+		return x.FloorModerated
+	}
+	return nil
+}
+
+func (x *Envelope) GetReplay() *Replay {
+	if x, ok := x.GetMessage().(*Envelope_Replay); ok {
+		return x.Replay
+	}
+	return nil
+}
+
+func (x *Envelope) GetLevel() *Level {
+	if x, ok := x.GetMessage().(*Envelope_Level); ok {
+		return x.Level
+	}
+	return nil
+}
+
+func (x *Envelope) GetJson() string {
+	if x, ok := x.GetMessage().(*Envelope_Json); ok {
+		return x.Json
+	}
+	return ""
+}
+
+type isEnvelope_Message interface {
+	isEnvelope_Message()
+}
+
+type Envelope_Audio struct {
+	Audio *Audio `protobuf:"bytes,1,opt,name=audio,proto3,oneof"`
+}
+
+type Envelope_Hb struct {
+	Hb *Heartbeat `protobuf:"bytes,2,opt,name=hb,proto3,oneof"`
+}
+
+type Envelope_Join struct {
+	Join *Join `protobuf:"bytes,3,opt,name=join,proto3,oneof"`
+}
+
+type Envelope_Leave struct {
+	Leave *Join `protobuf:"bytes,4,opt,name=leave,proto3,oneof"`
+}
+
+type Envelope_Kick struct {
+	Kick *ModeratorAction `protobuf:"bytes,5,opt,name=kick,proto3,oneof"`
+}
+
+type Envelope_Mute struct {
+	Mute *ModeratorAction `protobuf:"bytes,6,opt,name=mute,proto3,oneof"`
+}
+
+type Envelope_Unmute struct {
+	Unmute *ModeratorAction `protobuf:"bytes,7,opt,name=unmute,proto3,oneof"`
+}
+
+type Envelope_Keyx struct {
+	Keyx *KeyExchange `protobuf:"bytes,8,opt,name=keyx,proto3,oneof"`
+}
+
+type Envelope_Block struct {
+	Block *Block `protobuf:"bytes,14,opt,name=block,proto3,oneof"`
+}
+
+type Envelope_Unblock struct {
+	Unblock *Block `protobuf:"bytes,15,opt,name=unblock,proto3,oneof"`
+}
+
+type Envelope_Group struct {
+	Group *Group `protobuf:"bytes,31,opt,name=group,proto3,oneof"`
+}
+
+type Envelope_Filter struct {
+	Filter *Filter `protobuf:"bytes,32,opt,name=filter,proto3,oneof"`
+}
+
+type Envelope_FloorRequest struct {
+	FloorRequest *FloorRequest `protobuf:"bytes,36,opt,name=floor_request,json=floorRequest,proto3,oneof"`
+}
+
+type Envelope_FloorRelease struct {
+	FloorRelease *FloorRequest `protobuf:"bytes,37,opt,name=floor_release,json=floorRelease,proto3,oneof"`
+}
+
+type Envelope_FloorCancel struct {
+	FloorCancel *FloorRequest `protobuf:"bytes,41,opt,name=floor_cancel,json=floorCancel,proto3,oneof"`
+}
+
+type Envelope_HandRaise struct {
+	HandRaise *Hand `protobuf:"bytes,45,opt,name=hand_raise,json=handRaise,proto3,oneof"`
+}
+
+type Envelope_HandLower struct {
+	HandLower *Hand `protobuf:"bytes,46,opt,name=hand_lower,json=handLower,proto3,oneof"`
+}
+
+type Envelope_HandGrant struct {
+	HandGrant *ModeratorAction `protobuf:"bytes,47,opt,name=hand_grant,json=handGrant,proto3,oneof"`
+}
+
+type Envelope_HandsClear struct {
+	HandsClear *Hand `protobuf:"bytes,48,opt,name=hands_clear,json=handsClear,proto3,oneof"`
+}
+
+type Envelope_FloorRevoke struct {
+	FloorRevoke *FloorModeration `protobuf:"bytes,50,opt,name=floor_revoke,json=floorRevoke,proto3,oneof"`
+}
+
+type Envelope_FloorLock struct {
+	FloorLock *FloorModeration `protobuf:"bytes,51,opt,name=floor_lock,json=floorLock,proto3,oneof"`
+}
+
+type Envelope_FloorUnlock struct {
+	FloorUnlock *FloorModeration `protobuf:"bytes,52,opt,name=floor_unlock,json=floorUnlock,proto3,oneof"`
+}
+
+type Envelope_Hello struct {
+	Hello *Hello `protobuf:"bytes,9,opt,name=hello,proto3,oneof"`
+}
+
+type Envelope_Ping struct {
+	Ping *Ping `protobuf:"bytes,11,opt,name=ping,proto3,oneof"`
+}
+
+type Envelope_Ack struct {
+	Ack *Ack `protobuf:"bytes,10,opt,name=ack,proto3,oneof"`
+}
+
+type Envelope_Chat struct {
+	Chat *Chat `protobuf:"bytes,12,opt,name=chat,proto3,oneof"`
+}
+
+type Envelope_Whisper struct {
+	Whisper *Whisper `protobuf:"bytes,13,opt,name=whisper,proto3,oneof"`
+}
+
+type Envelope_Roster struct {
+	Roster *Roster `protobuf:"bytes,34,opt,name=roster,proto3,oneof"`
+}
+
+type Envelope_Tier struct {
+	Tier *Tier `protobuf:"bytes,54,opt,name=tier,proto3,oneof"`
+}
+
+type Envelope_Joined struct {
+	Joined *Joined `protobuf:"bytes,16,opt,name=joined,proto3,oneof"`
+}
+
+type Envelope_Error struct {
+	Error *Error `protobuf:"bytes,17,opt,name=error,proto3,oneof"`
+}
+
+type Envelope_Will struct {
+	Will *Will `protobuf:"bytes,18,opt,name=will,proto3,oneof"`
+}
+
+type Envelope_Muted struct {
+	Muted *RoomNotice `protobuf:"bytes,19,opt,name=muted,proto3,oneof"`
+}
+
+type Envelope_Unmuted struct {
+	Unmuted *RoomNotice `protobuf:"bytes,20,opt,name=unmuted,proto3,oneof"`
+}
+
+type Envelope_Left struct {
+	Left *Left `protobuf:"bytes,21,opt,name=left,proto3,oneof"`
+}
+
+type Envelope_RateLimited struct {
+	RateLimited *RateLimited `protobuf:"bytes,22,opt,name=rate_limited,json=rateLimited,proto3,oneof"`
+}
+
+type Envelope_TransmissionCutOff struct {
+	TransmissionCutOff *TransmissionCutOff `protobuf:"bytes,23,opt,name=transmission_cut_off,json=transmissionCutOff,proto3,oneof"`
+}
+
+type Envelope_RoomClosing struct {
+	RoomClosing *RoomClosing `protobuf:"bytes,24,opt,name=room_closing,json=roomClosing,proto3,oneof"`
+}
+
+type Envelope_RoomReopened struct {
+	RoomReopened *RoomReopened `protobuf:"bytes,25,opt,name=room_reopened,json=roomReopened,proto3,oneof"`
+}
+
+type Envelope_Reconnect struct {
+	Reconnect *Reconnect `protobuf:"bytes,26,opt,name=reconnect,proto3,oneof"`
+}
+
+type Envelope_Announcement struct {
+	Announcement *Announcement `protobuf:"bytes,27,opt,name=announcement,proto3,oneof"`
+}
+
+type Envelope_Quality struct {
+	Quality *Quality `protobuf:"bytes,28,opt,name=quality,proto3,oneof"`
+}
+
+type Envelope_Pong struct {
+	Pong *Pong `protobuf:"bytes,29,opt,name=pong,proto3,oneof"`
+}
+
+type Envelope_Tags struct {
+	Tags *Tags `protobuf:"bytes,30,opt,name=tags,proto3,oneof"`
+}
+
+type Envelope_Presence struct {
+	Presence *Presence `protobuf:"bytes,33,opt,name=presence,proto3,oneof"`
+}
+
+type Envelope_Talk struct {
+	Talk *Talk `protobuf:"bytes,35,opt,name=talk,proto3,oneof"`
+}
+
+type Envelope_Floor struct {
+	Floor *Floor `protobuf:"bytes,38,opt,name=floor,proto3,oneof"`
+}
+
+type Envelope_FloorGranted struct {
+	FloorGranted *FloorGrant `protobuf:"bytes,39,opt,name=floor_granted,json=floorGranted,proto3,oneof"`
+}
+
+type Envelope_FloorDenied struct {
+	FloorDenied *FloorGrant `protobuf:"bytes,40,opt,name=floor_denied,json=floorDenied,proto3,oneof"`
+}
+
+type Envelope_FloorQueued struct {
+	FloorQueued *FloorQueued `protobuf:"bytes,42,opt,name=floor_queued,json=floorQueued,proto3,oneof"`
+}
+
+type Envelope_FloorOffered struct {
+	FloorOffered *FloorOffered `protobuf:"bytes,43,opt,name=floor_offered,json=floorOffered,proto3,oneof"`
+}
+
+type Envelope_FloorRevoked struct {
+	FloorRevoked *FloorGrant `protobuf:"bytes,44,opt,name=floor_revoked,json=floorRevoked,proto3,oneof"`
+}
+
+type Envelope_Eot struct {
+	Eot *Eot `protobuf:"bytes,49,opt,name=eot,proto3,oneof"`
+}
+
+type Envelope_FloorModerated struct {
+	FloorModerated *FloorModerated `protobuf:"bytes,53,opt,name=floor_moderated,json=floorModerated,proto3,oneof"`
+}
+
+type Envelope_Replay struct {
+	Replay *Replay `protobuf:"bytes,55,opt,name=replay,proto3,oneof"`
+}
+
+type Envelope_Level struct {
+	Level *Level `protobuf:"bytes,56,opt,name=level,proto3,oneof"`
+}
+
+type Envelope_Json struct {
+	Json string `protobuf:"bytes,100,opt,name=json,proto3,oneof"`
+}
+
+func (*Envelope_Audio) isEnvelope_Message() {}
+
+func (*Envelope_Hb) isEnvelope_Message() {}
+
+func (*Envelope_Join) isEnvelope_Message() {}
+
+func (*Envelope_Leave) isEnvelope_Message() {}
+
+func (*Envelope_Kick) isEnvelope_Message() {}
+
+func (*Envelope_Mute) isEnvelope_Message() {}
+
+func (*Envelope_Unmute) isEnvelope_Message() {}
+
+func (*Envelope_Keyx) isEnvelope_Message() {}
+
+func (*Envelope_Block) isEnvelope_Message() {}
+
+func (*Envelope_Unblock) isEnvelope_Message() {}
+
+func (*Envelope_Group) isEnvelope_Message() {}
+
+func (*Envelope_Filter) isEnvelope_Message() {}
+
+func (*Envelope_FloorRequest) isEnvelope_Message() {}
+
+func (*Envelope_FloorRelease) isEnvelope_Message() {}
+
+func (*Envelope_FloorCancel) isEnvelope_Message() {}
+
+func (*Envelope_HandRaise) isEnvelope_Message() {}
+
+func (*Envelope_HandLower) isEnvelope_Message() {}
+
+func (*Envelope_HandGrant) isEnvelope_Message() {}
+
+func (*Envelope_HandsClear) isEnvelope_Message() {}
+
+func (*Envelope_FloorRevoke) isEnvelope_Message() {}
+
+func (*Envelope_FloorLock) isEnvelope_Message() {}
+
+func (*Envelope_FloorUnlock) isEnvelope_Message() {}
+
+func (*Envelope_Hello) isEnvelope_Message() {}
+
+func (*Envelope_Ping) isEnvelope_Message() {}
+
+func (*Envelope_Ack) isEnvelope_Message() {}
+
+func (*Envelope_Chat) isEnvelope_Message() {}
+
+func (*Envelope_Whisper) isEnvelope_Message() {}
+
+func (*Envelope_Roster) isEnvelope_Message() {}
+
+func (*Envelope_Tier) isEnvelope_Message() {}
+
+func (*Envelope_Joined) isEnvelope_Message() {}
+
+func (*Envelope_Error) isEnvelope_Message() {}
+
+func (*Envelope_Will) isEnvelope_Message() {}
+
+func (*Envelope_Muted) isEnvelope_Message() {}
+
+func (*Envelope_Unmuted) isEnvelope_Message() {}
+
+func (*Envelope_Left) isEnvelope_Message() {}
+
+func (*Envelope_RateLimited) isEnvelope_Message() {}
+
+func (*Envelope_TransmissionCutOff) isEnvelope_Message() {}
+
+func (*Envelope_RoomClosing) isEnvelope_Message() {}
+
+func (*Envelope_RoomReopened) isEnvelope_Message() {}
+
+func (*Envelope_Reconnect) isEnvelope_Message() {}
+
+func (*Envelope_Announcement) isEnvelope_Message() {}
+
+func (*Envelope_Quality) isEnvelope_Message() {}
+
+func (*Envelope_Pong) isEnvelope_Message() {}
+
+func (*Envelope_Tags) isEnvelope_Message() {}
+
+func (*Envelope_Presence) isEnvelope_Message() {}
+
+func (*Envelope_Talk) isEnvelope_Message() {}
+
+func (*Envelope_Floor) isEnvelope_Message() {}
+
+func (*Envelope_FloorGranted) isEnvelope_Message() {}
+
+func (*Envelope_FloorDenied) isEnvelope_Message() {}
+
+func (*Envelope_FloorQueued) isEnvelope_Message() {}
+
+func (*Envelope_FloorOffered) isEnvelope_Message() {}
+
+func (*Envelope_FloorRevoked) isEnvelope_Message() {}
+
+func (*Envelope_Eot) isEnvelope_Message() {}
+
+func (*Envelope_FloorModerated) isEnvelope_Message() {}
+
+func (*Envelope_Replay) isEnvelope_Message() {}
+
+func (*Envelope_Level) isEnvelope_Message() {}
+
+func (*Envelope_Json) isEnvelope_Message() {}
+
+type Audio struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string  `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Data     []byte  `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Sender   string  `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	Seq      uint64  `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`
+	Received int64   `protobuf:"varint,5,opt,name=received,proto3" json:"received,omitempty"`
+	Captured int64   `protobuf:"varint,6,opt,name=captured,proto3" json:"captured,omitempty"`
+	Private  bool    `protobuf:"varint,7,opt,name=private,proto3" json:"private,omitempty"`
+	Priority bool    `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	Level    float32 `protobuf:"fixed32,9,opt,name=level,proto3" json:"level,omitempty"`
+	Replay   bool    `protobuf:"varint,10,opt,name=replay,proto3" json:"replay,omitempty"`
+}
+
+func (x *Audio) Reset() {
+	*x = Audio{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Audio) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Audio) ProtoMessage() {}
+
+func (x *Audio) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Audio.ProtoReflect.Descriptor instead.
+func (*Audio) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Audio) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Audio) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Audio) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Audio) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Audio) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *Audio) GetCaptured() int64 {
+	if x != nil {
+		return x.Captured
+	}
+	return 0
+}
+
+func (x *Audio) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *Audio) GetPriority() bool {
+	if x != nil {
+		return x.Priority
+	}
+	return false
+}
+
+func (x *Audio) GetLevel() float32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *Audio) GetReplay() bool {
+	if x != nil {
+		return x.Replay
+	}
+	return false
+}
+
+type Heartbeat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seq uint64 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Ts  int64  `protobuf:"varint,2,opt,name=ts,proto3" json:"ts,omitempty"`
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Heartbeat) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+type Hello struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version int32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Hello) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Join struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room      string       `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	JoinToken string       `protobuf:"bytes,2,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
+	Format    *AudioFormat `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"`
+	Tags      []string     `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *Join) Reset() {
+	*x = Join{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Join) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Join) ProtoMessage() {}
+
+func (x *Join) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Join.ProtoReflect.Descriptor instead.
+func (*Join) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Join) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Join) GetJoinToken() string {
+	if x != nil {
+		return x.JoinToken
+	}
+	return ""
+}
+
+func (x *Join) GetFormat() *AudioFormat {
+	if x != nil {
+		return x.Format
+	}
+	return nil
+}
+
+func (x *Join) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Vox struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Attack  float64 `protobuf:"fixed64,1,opt,name=attack,proto3" json:"attack,omitempty"`
+	Release float64 `protobuf:"fixed64,2,opt,name=release,proto3" json:"release,omitempty"`
+	HoldMs  int64   `protobuf:"varint,3,opt,name=hold_ms,json=holdMs,proto3" json:"hold_ms,omitempty"`
+}
+
+func (x *Vox) Reset() {
+	*x = Vox{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Vox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vox) ProtoMessage() {}
+
+func (x *Vox) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vox.ProtoReflect.Descriptor instead.
+func (*Vox) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Vox) GetAttack() float64 {
+	if x != nil {
+		return x.Attack
+	}
+	return 0
+}
+
+func (x *Vox) GetRelease() float64 {
+	if x != nil {
+		return x.Release
+	}
+	return 0
+}
+
+func (x *Vox) GetHoldMs() int64 {
+	if x != nil {
+		return x.HoldMs
+	}
+	return 0
+}
+
+type AudioFormat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Codec      string `protobuf:"bytes,1,opt,name=codec,proto3" json:"codec,omitempty"`
+	SampleRate int32  `protobuf:"varint,2,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Channels   int32  `protobuf:"varint,3,opt,name=channels,proto3" json:"channels,omitempty"`
+	FrameMs    int32  `protobuf:"varint,4,opt,name=frame_ms,json=frameMs,proto3" json:"frame_ms,omitempty"`
+}
+
+func (x *AudioFormat) Reset() {
+	*x = AudioFormat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AudioFormat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AudioFormat) ProtoMessage() {}
+
+func (x *AudioFormat) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AudioFormat.ProtoReflect.Descriptor instead.
+func (*AudioFormat) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AudioFormat) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *AudioFormat) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *AudioFormat) GetChannels() int32 {
+	if x != nil {
+		return x.Channels
+	}
+	return 0
+}
+
+func (x *AudioFormat) GetFrameMs() int32 {
+	if x != nil {
+		return x.FrameMs
+	}
+	return 0
+}
+
+type ModeratorAction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Room   string `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+}
+
+func (x *ModeratorAction) Reset() {
+	*x = ModeratorAction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModeratorAction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModeratorAction) ProtoMessage() {}
+
+func (x *ModeratorAction) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModeratorAction.ProtoReflect.Descriptor instead.
+func (*ModeratorAction) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ModeratorAction) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *ModeratorAction) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type KeyExchange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string          `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Target  string          `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	From    string          `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	Payload *structpb.Value `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	Id      string          `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"`
+	Ack     bool            `protobuf:"varint,6,opt,name=ack,proto3" json:"ack,omitempty"`
+}
+
+func (x *KeyExchange) Reset() {
+	*x = KeyExchange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyExchange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyExchange) ProtoMessage() {}
+
+func (x *KeyExchange) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyExchange.ProtoReflect.Descriptor instead.
+func (*KeyExchange) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *KeyExchange) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *KeyExchange) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *KeyExchange) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *KeyExchange) GetPayload() *structpb.Value {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *KeyExchange) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *KeyExchange) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+type Chat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string   `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Text    string   `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Echo    bool     `protobuf:"varint,3,opt,name=echo,proto3" json:"echo,omitempty"`
+	From    string   `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	Ts      int64    `protobuf:"varint,5,opt,name=ts,proto3" json:"ts,omitempty"`
+	Target  string   `protobuf:"bytes,6,opt,name=target,proto3" json:"target,omitempty"`
+	Private bool     `protobuf:"varint,7,opt,name=private,proto3" json:"private,omitempty"`
+	Tags    []string `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *Chat) Reset() {
+	*x = Chat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Chat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chat) ProtoMessage() {}
+
+func (x *Chat) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chat.ProtoReflect.Descriptor instead.
+func (*Chat) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Chat) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Chat) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Chat) GetEcho() bool {
+	if x != nil {
+		return x.Echo
+	}
+	return false
+}
+
+func (x *Chat) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Chat) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+func (x *Chat) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Chat) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *Chat) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Whisper struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room   string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Target string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	From   string `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	Active bool   `protobuf:"varint,4,opt,name=active,proto3" json:"active,omitempty"`
+}
+
+func (x *Whisper) Reset() {
+	*x = Whisper{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Whisper) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Whisper) ProtoMessage() {}
+
+func (x *Whisper) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Whisper.ProtoReflect.Descriptor instead.
+func (*Whisper) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Whisper) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Whisper) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Whisper) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Whisper) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+type Group struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string   `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Tags []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Group) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Group) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Filter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string   `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Mode    string   `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	Senders []string `protobuf:"bytes,3,rep,name=senders,proto3" json:"senders,omitempty"`
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Filter) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Filter) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Filter) GetSenders() []string {
+	if x != nil {
+		return x.Senders
+	}
+	return nil
+}
+
+type Tags struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string   `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Tags []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *Tags) Reset() {
+	*x = Tags{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tags) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tags) ProtoMessage() {}
+
+func (x *Tags) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tags.ProtoReflect.Descriptor instead.
+func (*Tags) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Tags) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Tags) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Block struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Target string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *Block) Reset() {
+	*x = Block{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Block) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type Ack struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Room string `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	From string `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Ack) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Ack) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Ack) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+type Joined struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room              string           `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Id                string           `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	ResumeToken       string           `protobuf:"bytes,3,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	Resumed           bool             `protobuf:"varint,4,opt,name=resumed,proto3" json:"resumed,omitempty"`
+	Protocol          string           `protobuf:"bytes,5,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Codec             string           `protobuf:"bytes,6,opt,name=codec,proto3" json:"codec,omitempty"`
+	SampleRate        int32            `protobuf:"varint,7,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Channels          int32            `protobuf:"varint,8,opt,name=channels,proto3" json:"channels,omitempty"`
+	MaxMessageSize    int64            `protobuf:"varint,9,opt,name=max_message_size,json=maxMessageSize,proto3" json:"max_message_size,omitempty"`
+	Clients           int32            `protobuf:"varint,10,opt,name=clients,proto3" json:"clients,omitempty"`
+	Transmitting      bool             `protobuf:"varint,11,opt,name=transmitting,proto3" json:"transmitting,omitempty"`
+	Moderator         bool             `protobuf:"varint,12,opt,name=moderator,proto3" json:"moderator,omitempty"`
+	Muted             bool             `protobuf:"varint,13,opt,name=muted,proto3" json:"muted,omitempty"`
+	Role              string           `protobuf:"bytes,14,opt,name=role,proto3" json:"role,omitempty"`
+	Version           int32            `protobuf:"varint,15,opt,name=version,proto3" json:"version,omitempty"`
+	FrameMs           int32            `protobuf:"varint,16,opt,name=frame_ms,json=frameMs,proto3" json:"frame_ms,omitempty"`
+	AllowedFormats    []*AudioFormat   `protobuf:"bytes,17,rep,name=allowed_formats,json=allowedFormats,proto3" json:"allowed_formats,omitempty"`
+	FormatMismatch    bool             `protobuf:"varint,18,opt,name=format_mismatch,json=formatMismatch,proto3" json:"format_mismatch,omitempty"`
+	Chat              []*Chat          `protobuf:"bytes,19,rep,name=chat,proto3" json:"chat,omitempty"`
+	Tags              []string         `protobuf:"bytes,20,rep,name=tags,proto3" json:"tags,omitempty"`
+	Present           []*PresentClient `protobuf:"bytes,21,rep,name=present,proto3" json:"present,omitempty"`
+	RosterVersion     uint64           `protobuf:"varint,22,opt,name=roster_version,json=rosterVersion,proto3" json:"roster_version,omitempty"`
+	Capabilities      []string         `protobuf:"bytes,23,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	FloorControl      bool             `protobuf:"varint,24,opt,name=floor_control,json=floorControl,proto3" json:"floor_control,omitempty"`
+	FloorHolder       string           `protobuf:"bytes,25,opt,name=floor_holder,json=floorHolder,proto3" json:"floor_holder,omitempty"`
+	FloorLocked       bool             `protobuf:"varint,26,opt,name=floor_locked,json=floorLocked,proto3" json:"floor_locked,omitempty"`
+	Vox               *Vox             `protobuf:"bytes,27,opt,name=vox,proto3" json:"vox,omitempty"`
+	Mixing            bool             `protobuf:"varint,28,opt,name=mixing,proto3" json:"mixing,omitempty"`
+	Receive           string           `protobuf:"bytes,29,opt,name=receive,proto3" json:"receive,omitempty"`
+	Tier              string           `protobuf:"bytes,30,opt,name=tier,proto3" json:"tier,omitempty"`
+	ReceiveSampleRate int32            `protobuf:"varint,31,opt,name=receive_sample_rate,json=receiveSampleRate,proto3" json:"receive_sample_rate,omitempty"`
+	ReceiveChannels   int32            `protobuf:"varint,32,opt,name=receive_channels,json=receiveChannels,proto3" json:"receive_channels,omitempty"`
+	Integrity         string           `protobuf:"bytes,33,opt,name=integrity,proto3" json:"integrity,omitempty"`
+}
+
+func (x *Joined) Reset() {
+	*x = Joined{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Joined) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Joined) ProtoMessage() {}
+
+func (x *Joined) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Joined.ProtoReflect.Descriptor instead.
+func (*Joined) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Joined) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Joined) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Joined) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *Joined) GetResumed() bool {
+	if x != nil {
+		return x.Resumed
+	}
+	return false
+}
+
+func (x *Joined) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Joined) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *Joined) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *Joined) GetChannels() int32 {
+	if x != nil {
+		return x.Channels
+	}
+	return 0
+}
+
+func (x *Joined) GetMaxMessageSize() int64 {
+	if x != nil {
+		return x.MaxMessageSize
+	}
+	return 0
+}
+
+func (x *Joined) GetClients() int32 {
+	if x != nil {
+		return x.Clients
+	}
+	return 0
+}
+
+func (x *Joined) GetTransmitting() bool {
+	if x != nil {
+		return x.Transmitting
+	}
+	return false
+}
+
+func (x *Joined) GetModerator() bool {
+	if x != nil {
+		return x.Moderator
+	}
+	return false
+}
+
+func (x *Joined) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+func (x *Joined) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Joined) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Joined) GetFrameMs() int32 {
+	if x != nil {
+		return x.FrameMs
+	}
+	return 0
+}
+
+func (x *Joined) GetAllowedFormats() []*AudioFormat {
+	if x != nil {
+		return x.AllowedFormats
+	}
+	return nil
+}
+
+func (x *Joined) GetFormatMismatch() bool {
+	if x != nil {
+		return x.FormatMismatch
+	}
+	return false
+}
+
+func (x *Joined) GetChat() []*Chat {
+	if x != nil {
+		return x.Chat
+	}
+	return nil
+}
+
+func (x *Joined) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Joined) GetPresent() []*PresentClient {
+	if x != nil {
+		return x.Present
+	}
+	return nil
+}
+
+func (x *Joined) GetRosterVersion() uint64 {
+	if x != nil {
+		return x.RosterVersion
+	}
+	return 0
+}
+
+func (x *Joined) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Joined) GetFloorControl() bool {
+	if x != nil {
+		return x.FloorControl
+	}
+	return false
+}
+
+func (x *Joined) GetFloorHolder() string {
+	if x != nil {
+		return x.FloorHolder
+	}
+	return ""
+}
+
+func (x *Joined) GetFloorLocked() bool {
+	if x != nil {
+		return x.FloorLocked
+	}
+	return false
+}
+
+func (x *Joined) GetVox() *Vox {
+	if x != nil {
+		return x.Vox
+	}
+	return nil
+}
+
+func (x *Joined) GetMixing() bool {
+	if x != nil {
+		return x.Mixing
+	}
+	return false
+}
+
+func (x *Joined) GetReceive() string {
+	if x != nil {
+		return x.Receive
+	}
+	return ""
+}
+
+func (x *Joined) GetTier() string {
+	if x != nil {
+		return x.Tier
+	}
+	return ""
+}
+
+func (x *Joined) GetReceiveSampleRate() int32 {
+	if x != nil {
+		return x.ReceiveSampleRate
+	}
+	return 0
+}
+
+func (x *Joined) GetReceiveChannels() int32 {
+	if x != nil {
+		return x.ReceiveChannels
+	}
+	return 0
+}
+
+func (x *Joined) GetIntegrity() string {
+	if x != nil {
+		return x.Integrity
+	}
+	return ""
+}
+
+type PresentClient struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id           string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name         string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Role         string `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
+	HandRaisedAt int64  `protobuf:"varint,4,opt,name=hand_raised_at,json=handRaisedAt,proto3" json:"hand_raised_at,omitempty"`
+	Muted        bool   `protobuf:"varint,5,opt,name=muted,proto3" json:"muted,omitempty"`
+}
+
+func (x *PresentClient) Reset() {
+	*x = PresentClient{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PresentClient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresentClient) ProtoMessage() {}
+
+func (x *PresentClient) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresentClient.ProtoReflect.Descriptor instead.
+func (*PresentClient) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PresentClient) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PresentClient) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PresentClient) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *PresentClient) GetHandRaisedAt() int64 {
+	if x != nil {
+		return x.HandRaisedAt
+	}
+	return 0
+}
+
+func (x *PresentClient) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+type Presence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Event        string `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	Room         string `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	Id           string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Name         string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Role         string `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
+	Reason       string `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Version      uint64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	HandRaisedAt int64  `protobuf:"varint,8,opt,name=hand_raised_at,json=handRaisedAt,proto3" json:"hand_raised_at,omitempty"`
+	Muted        bool   `protobuf:"varint,9,opt,name=muted,proto3" json:"muted,omitempty"`
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Presence) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *Presence) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Presence) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Presence) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Presence) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Presence) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Presence) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Presence) GetHandRaisedAt() int64 {
+	if x != nil {
+		return x.HandRaisedAt
+	}
+	return 0
+}
+
+func (x *Presence) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+type Talk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room  string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Id    string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	State string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+}
+
+func (x *Talk) Reset() {
+	*x = Talk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Talk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Talk) ProtoMessage() {}
+
+func (x *Talk) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Talk.ProtoReflect.Descriptor instead.
+func (*Talk) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Talk) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Talk) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Talk) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+type Roster struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string          `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Version uint64          `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Clients []*RosterClient `protobuf:"bytes,3,rep,name=clients,proto3" json:"clients,omitempty"`
+}
+
+func (x *Roster) Reset() {
+	*x = Roster{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Roster) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Roster) ProtoMessage() {}
+
+func (x *Roster) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Roster.ProtoReflect.Descriptor instead.
+func (*Roster) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Roster) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Roster) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Roster) GetClients() []*RosterClient {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type RosterClient struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id           string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name         string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Role         string   `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
+	Tags         []string `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Muted        bool     `protobuf:"varint,5,opt,name=muted,proto3" json:"muted,omitempty"`
+	Since        int64    `protobuf:"varint,6,opt,name=since,proto3" json:"since,omitempty"`
+	Transmitting bool     `protobuf:"varint,7,opt,name=transmitting,proto3" json:"transmitting,omitempty"`
+	HandRaisedAt int64    `protobuf:"varint,8,opt,name=hand_raised_at,json=handRaisedAt,proto3" json:"hand_raised_at,omitempty"`
+}
+
+func (x *RosterClient) Reset() {
+	*x = RosterClient{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RosterClient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RosterClient) ProtoMessage() {}
+
+func (x *RosterClient) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RosterClient.ProtoReflect.Descriptor instead.
+func (*RosterClient) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RosterClient) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RosterClient) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RosterClient) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *RosterClient) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *RosterClient) GetMuted() bool {
+	if x != nil {
+		return x.Muted
+	}
+	return false
+}
+
+func (x *RosterClient) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *RosterClient) GetTransmitting() bool {
+	if x != nil {
+		return x.Transmitting
+	}
+	return false
+}
+
+func (x *RosterClient) GetHandRaisedAt() int64 {
+	if x != nil {
+		return x.HandRaisedAt
+	}
+	return 0
+}
+
+type Level struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Id   string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Db   int32  `protobuf:"varint,3,opt,name=db,proto3" json:"db,omitempty"`
+}
+
+func (x *Level) Reset() {
+	*x = Level{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Level) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Level) ProtoMessage() {}
+
+func (x *Level) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Level.ProtoReflect.Descriptor instead.
+func (*Level) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Level) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Level) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Level) GetDb() int32 {
+	if x != nil {
+		return x.Db
+	}
+	return 0
+}
+
+type Replay struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room   string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	State  string `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Frames int32  `protobuf:"varint,3,opt,name=frames,proto3" json:"frames,omitempty"`
+	Since  int64  `protobuf:"varint,4,opt,name=since,proto3" json:"since,omitempty"`
+}
+
+func (x *Replay) Reset() {
+	*x = Replay{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Replay) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Replay) ProtoMessage() {}
+
+func (x *Replay) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Replay.ProtoReflect.Descriptor instead.
+func (*Replay) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Replay) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Replay) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Replay) GetFrames() int32 {
+	if x != nil {
+		return x.Frames
+	}
+	return 0
+}
+
+func (x *Replay) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+type Tier struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room       string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Tier       string `protobuf:"bytes,2,opt,name=tier,proto3" json:"tier,omitempty"`
+	SampleRate int32  `protobuf:"varint,3,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Channels   int32  `protobuf:"varint,4,opt,name=channels,proto3" json:"channels,omitempty"`
+}
+
+func (x *Tier) Reset() {
+	*x = Tier{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tier) ProtoMessage() {}
+
+func (x *Tier) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tier.ProtoReflect.Descriptor instead.
+func (*Tier) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Tier) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Tier) GetTier() string {
+	if x != nil {
+		return x.Tier
+	}
+	return ""
+}
+
+func (x *Tier) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *Tier) GetChannels() int32 {
+	if x != nil {
+		return x.Channels
+	}
+	return 0
+}
+
+type FloorRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Priority int32  `protobuf:"varint,2,opt,name=priority,proto3" json:"priority,omitempty"`
+}
+
+func (x *FloorRequest) Reset() {
+	*x = FloorRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorRequest) ProtoMessage() {}
+
+func (x *FloorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorRequest.ProtoReflect.Descriptor instead.
+func (*FloorRequest) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *FloorRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *FloorRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type Floor struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Holder   string `protobuf:"bytes,2,opt,name=holder,proto3" json:"holder,omitempty"`
+	Reason   string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Priority int32  `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	Locked   bool   `protobuf:"varint,5,opt,name=locked,proto3" json:"locked,omitempty"`
+}
+
+func (x *Floor) Reset() {
+	*x = Floor{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Floor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Floor) ProtoMessage() {}
+
+func (x *Floor) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Floor.ProtoReflect.Descriptor instead.
+func (*Floor) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *Floor) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Floor) GetHolder() string {
+	if x != nil {
+		return x.Holder
+	}
+	return ""
+}
+
+func (x *Floor) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Floor) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Floor) GetLocked() bool {
+	if x != nil {
+		return x.Locked
+	}
+	return false
+}
+
+type FloorGrant struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room   string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Holder string `protobuf:"bytes,2,opt,name=holder,proto3" json:"holder,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *FloorGrant) Reset() {
+	*x = FloorGrant{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorGrant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorGrant) ProtoMessage() {}
+
+func (x *FloorGrant) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorGrant.ProtoReflect.Descriptor instead.
+func (*FloorGrant) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *FloorGrant) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *FloorGrant) GetHolder() string {
+	if x != nil {
+		return x.Holder
+	}
+	return ""
+}
+
+func (x *FloorGrant) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type FloorModeration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+}
+
+func (x *FloorModeration) Reset() {
+	*x = FloorModeration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorModeration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorModeration) ProtoMessage() {}
+
+func (x *FloorModeration) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorModeration.ProtoReflect.Descriptor instead.
+func (*FloorModeration) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *FloorModeration) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type FloorModerated struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Action  string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Revoked string `protobuf:"bytes,3,opt,name=revoked,proto3" json:"revoked,omitempty"`
+	Locked  bool   `protobuf:"varint,4,opt,name=locked,proto3" json:"locked,omitempty"`
+}
+
+func (x *FloorModerated) Reset() {
+	*x = FloorModerated{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorModerated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorModerated) ProtoMessage() {}
+
+func (x *FloorModerated) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorModerated.ProtoReflect.Descriptor instead.
+func (*FloorModerated) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *FloorModerated) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *FloorModerated) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *FloorModerated) GetRevoked() string {
+	if x != nil {
+		return x.Revoked
+	}
+	return ""
+}
+
+func (x *FloorModerated) GetLocked() bool {
+	if x != nil {
+		return x.Locked
+	}
+	return false
+}
+
+type Hand struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+}
+
+func (x *Hand) Reset() {
+	*x = Hand{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Hand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hand) ProtoMessage() {}
+
+func (x *Hand) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hand.ProtoReflect.Descriptor instead.
+func (*Hand) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Hand) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type FloorQueued struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Position int32  `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`
+	Reason   string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *FloorQueued) Reset() {
+	*x = FloorQueued{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorQueued) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorQueued) ProtoMessage() {}
+
+func (x *FloorQueued) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorQueued.ProtoReflect.Descriptor instead.
+func (*FloorQueued) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *FloorQueued) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *FloorQueued) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *FloorQueued) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type FloorOffered struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room      string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	ExpiresAt string `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+}
+
+func (x *FloorOffered) Reset() {
+	*x = FloorOffered{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FloorOffered) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FloorOffered) ProtoMessage() {}
+
+func (x *FloorOffered) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FloorOffered.ProtoReflect.Descriptor instead.
+func (*FloorOffered) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *FloorOffered) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *FloorOffered) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type Eot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room   string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Id     string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Seq    uint64 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	Reason string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Eot) Reset() {
+	*x = Eot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Eot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Eot) ProtoMessage() {}
+
+func (x *Eot) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Eot.ProtoReflect.Descriptor instead.
+func (*Eot) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *Eot) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Eot) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Eot) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Eot) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type Error struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code    string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Request string `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	Target  string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	OpensAt string `protobuf:"bytes,4,opt,name=opens_at,json=opensAt,proto3" json:"opens_at,omitempty"`
+	Detail  string `protobuf:"bytes,5,opt,name=detail,proto3" json:"detail,omitempty"`
+	Id      string `protobuf:"bytes,6,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Error) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Error) GetRequest() string {
+	if x != nil {
+		return x.Request
+	}
+	return ""
+}
+
+func (x *Error) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Error) GetOpensAt() string {
+	if x != nil {
+		return x.OpensAt
+	}
+	return ""
+}
+
+func (x *Error) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *Error) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Will struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room    string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Id      string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Code    int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Reason  string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Payload string `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+}
+
+func (x *Will) Reset() {
+	*x = Will{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Will) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Will) ProtoMessage() {}
+
+func (x *Will) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Will.ProtoReflect.Descriptor instead.
+func (*Will) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *Will) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Will) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Will) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Will) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Will) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+type RoomNotice struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+}
+
+func (x *RoomNotice) Reset() {
+	*x = RoomNotice{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[36]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RoomNotice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomNotice) ProtoMessage() {}
+
+func (x *RoomNotice) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[36]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomNotice.ProtoReflect.Descriptor instead.
+func (*RoomNotice) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RoomNotice) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type Left struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room   string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Code   int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Left) Reset() {
+	*x = Left{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[37]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Left) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Left) ProtoMessage() {}
+
+func (x *Left) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[37]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Left.ProtoReflect.Descriptor instead.
+func (*Left) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *Left) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Left) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Left) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RateLimited struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room              string  `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Dropped           uint64  `protobuf:"varint,2,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	MessagesPerSecond float64 `protobuf:"fixed64,3,opt,name=messages_per_second,json=messagesPerSecond,proto3" json:"messages_per_second,omitempty"`
+	BytesPerSecond    float64 `protobuf:"fixed64,4,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"`
+	Policy            string  `protobuf:"bytes,5,opt,name=policy,proto3" json:"policy,omitempty"`
+}
+
+func (x *RateLimited) Reset() {
+	*x = RateLimited{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[38]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RateLimited) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimited) ProtoMessage() {}
+
+func (x *RateLimited) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[38]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimited.ProtoReflect.Descriptor instead.
+func (*RateLimited) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *RateLimited) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RateLimited) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *RateLimited) GetMessagesPerSecond() float64 {
+	if x != nil {
+		return x.MessagesPerSecond
+	}
+	return 0
+}
+
+func (x *RateLimited) GetBytesPerSecond() float64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+func (x *RateLimited) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+type TransmissionCutOff struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room            string  `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	MaxBurstSeconds float64 `protobuf:"fixed64,2,opt,name=max_burst_seconds,json=maxBurstSeconds,proto3" json:"max_burst_seconds,omitempty"`
+	CooldownSeconds float64 `protobuf:"fixed64,3,opt,name=cooldown_seconds,json=cooldownSeconds,proto3" json:"cooldown_seconds,omitempty"`
+}
+
+func (x *TransmissionCutOff) Reset() {
+	*x = TransmissionCutOff{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[39]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransmissionCutOff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransmissionCutOff) ProtoMessage() {}
+
+func (x *TransmissionCutOff) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[39]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransmissionCutOff.ProtoReflect.Descriptor instead.
+func (*TransmissionCutOff) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *TransmissionCutOff) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *TransmissionCutOff) GetMaxBurstSeconds() float64 {
+	if x != nil {
+		return x.MaxBurstSeconds
+	}
+	return 0
+}
+
+func (x *TransmissionCutOff) GetCooldownSeconds() float64 {
+	if x != nil {
+		return x.CooldownSeconds
+	}
+	return 0
+}
+
+type RoomClosing struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	ClosesAt string `protobuf:"bytes,2,opt,name=closes_at,json=closesAt,proto3" json:"closes_at,omitempty"`
+	OpensAt  string `protobuf:"bytes,3,opt,name=opens_at,json=opensAt,proto3" json:"opens_at,omitempty"`
+}
+
+func (x *RoomClosing) Reset() {
+	*x = RoomClosing{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[40]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RoomClosing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomClosing) ProtoMessage() {}
+
+func (x *RoomClosing) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[40]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomClosing.ProtoReflect.Descriptor instead.
+func (*RoomClosing) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *RoomClosing) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RoomClosing) GetClosesAt() string {
+	if x != nil {
+		return x.ClosesAt
+	}
+	return ""
+}
+
+func (x *RoomClosing) GetOpensAt() string {
+	if x != nil {
+		return x.OpensAt
+	}
+	return ""
+}
+
+type RoomReopened struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Room     string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	ClosesAt string `protobuf:"bytes,2,opt,name=closes_at,json=closesAt,proto3" json:"closes_at,omitempty"`
+}
+
+func (x *RoomReopened) Reset() {
+	*x = RoomReopened{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[41]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RoomReopened) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomReopened) ProtoMessage() {}
+
+func (x *RoomReopened) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[41]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomReopened.ProtoReflect.Descriptor instead.
+func (*RoomReopened) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RoomReopened) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RoomReopened) GetClosesAt() string {
+	if x != nil {
+		return x.ClosesAt
+	}
+	return ""
+}
+
+type Reconnect struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AfterSeconds int32 `protobuf:"varint,1,opt,name=after_seconds,json=afterSeconds,proto3" json:"after_seconds,omitempty"`
+}
+
+func (x *Reconnect) Reset() {
+	*x = Reconnect{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[42]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Reconnect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reconnect) ProtoMessage() {}
+
+func (x *Reconnect) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[42]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reconnect.ProtoReflect.Descriptor instead.
+func (*Reconnect) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *Reconnect) GetAfterSeconds() int32 {
+	if x != nil {
+		return x.AfterSeconds
+	}
+	return 0
+}
+
+type Announcement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text string `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Ack  bool   `protobuf:"varint,4,opt,name=ack,proto3" json:"ack,omitempty"`
+}
+
+func (x *Announcement) Reset() {
+	*x = Announcement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[43]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Announcement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Announcement) ProtoMessage() {}
+
+func (x *Announcement) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[43]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Announcement.ProtoReflect.Descriptor instead.
+func (*Announcement) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *Announcement) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Announcement) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Announcement) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Announcement) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+type Quality struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UpstreamLossPct   float64 `protobuf:"fixed64,1,opt,name=upstream_loss_pct,json=upstreamLossPct,proto3" json:"upstream_loss_pct,omitempty"`
+	DownstreamLossPct float64 `protobuf:"fixed64,2,opt,name=downstream_loss_pct,json=downstreamLossPct,proto3" json:"downstream_loss_pct,omitempty"`
+	WindowSeconds     int32   `protobuf:"varint,3,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	RttMs             float64 `protobuf:"fixed64,4,opt,name=rtt_ms,json=rttMs,proto3" json:"rtt_ms,omitempty"`
+}
+
+func (x *Quality) Reset() {
+	*x = Quality{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[44]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Quality) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quality) ProtoMessage() {}
+
+func (x *Quality) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[44]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quality.ProtoReflect.Descriptor instead.
+func (*Quality) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *Quality) GetUpstreamLossPct() float64 {
+	if x != nil {
+		return x.UpstreamLossPct
+	}
+	return 0
+}
+
+func (x *Quality) GetDownstreamLossPct() float64 {
+	if x != nil {
+		return x.DownstreamLossPct
+	}
+	return 0
+}
+
+func (x *Quality) GetWindowSeconds() int32 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+func (x *Quality) GetRttMs() float64 {
+	if x != nil {
+		return x.RttMs
+	}
+	return 0
+}
+
+type Ping struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nonce *structpb.Value `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	T     int64           `protobuf:"varint,2,opt,name=t,proto3" json:"t,omitempty"`
+	Rtt   float64         `protobuf:"fixed64,3,opt,name=rtt,proto3" json:"rtt,omitempty"`
+}
+
+func (x *Ping) Reset() {
+	*x = Ping{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[45]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Ping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ping) ProtoMessage() {}
+
+func (x *Ping) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[45]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ping.ProtoReflect.Descriptor instead.
+func (*Ping) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *Ping) GetNonce() *structpb.Value {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *Ping) GetT() int64 {
+	if x != nil {
+		return x.T
+	}
+	return 0
+}
+
+func (x *Ping) GetRtt() float64 {
+	if x != nil {
+		return x.Rtt
+	}
+	return 0
+}
+
+type Pong struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nonce   *structpb.Value `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	T       int64           `protobuf:"varint,2,opt,name=t,proto3" json:"t,omitempty"`
+	ServerT int64           `protobuf:"varint,3,opt,name=server_t,json=serverT,proto3" json:"server_t,omitempty"`
+	Queued  int32           `protobuf:"varint,4,opt,name=queued,proto3" json:"queued,omitempty"`
+}
+
+func (x *Pong) Reset() {
+	*x = Pong{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pb_walkie_proto_msgTypes[46]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pong) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pong) ProtoMessage() {}
+
+func (x *Pong) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_walkie_proto_msgTypes[46]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pong.ProtoReflect.Descriptor instead.
+func (*Pong) Descriptor() ([]byte, []int) {
+	return file_pb_walkie_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *Pong) GetNonce() *structpb.Value {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *Pong) GetT() int64 {
+	if x != nil {
+		return x.T
+	}
+	return 0
+}
+
+func (x *Pong) GetServerT() int64 {
+	if x != nil {
+		return x.ServerT
+	}
+	return 0
+}
+
+func (x *Pong) GetQueued() int32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+var File_pb_walkie_proto protoreflect.FileDescriptor
+
+var file_pb_walkie_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x70, 0x62, 0x2f, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x09, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74,
+	0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xb7, 0x16, 0x0a, 0x08, 0x45,
+	0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x28, 0x0a, 0x05, 0x61, 0x75, 0x64, 0x69, 0x6f,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x48, 0x00, 0x52, 0x05, 0x61, 0x75, 0x64, 0x69,
+	0x6f, 0x12, 0x26, 0x0a, 0x02, 0x68, 0x62, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x48, 0x00, 0x52, 0x02, 0x68, 0x62, 0x12, 0x25, 0x0a, 0x04, 0x6a, 0x6f, 0x69,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x4a, 0x6f, 0x69, 0x6e, 0x48, 0x00, 0x52, 0x04, 0x6a, 0x6f, 0x69, 0x6e,
+	0x12, 0x27, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x76, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4a, 0x6f, 0x69, 0x6e,
+	0x48, 0x00, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x76, 0x65, 0x12, 0x30, 0x0a, 0x04, 0x6b, 0x69, 0x63,
+	0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x41, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x04, 0x6b, 0x69, 0x63, 0x6b, 0x12, 0x30, 0x0a, 0x04, 0x6d,
+	0x75, 0x74, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x41,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x04, 0x6d, 0x75, 0x74, 0x65, 0x12, 0x34, 0x0a,
+	0x06, 0x75, 0x6e, 0x6d, 0x75, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x6f, 0x72, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x06, 0x75, 0x6e, 0x6d,
+	0x75, 0x74, 0x65, 0x12, 0x2c, 0x0a, 0x04, 0x6b, 0x65, 0x79, 0x78, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4b, 0x65,
+	0x79, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x48, 0x00, 0x52, 0x04, 0x6b, 0x65, 0x79,
+	0x78, 0x12, 0x28, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x00, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x2c, 0x0a, 0x07, 0x75,
+	0x6e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x77,
+	0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x00,
+	0x52, 0x07, 0x75, 0x6e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x28, 0x0a, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x48, 0x00, 0x52, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x12, 0x2b, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x20, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x48, 0x00, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x12, 0x3e, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x18, 0x24, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x48, 0x00, 0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x3e, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x18, 0x25, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x48, 0x00, 0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
+	0x12, 0x3c, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x18, 0x29, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48,
+	0x00, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12, 0x30,
+	0x0a, 0x0a, 0x68, 0x61, 0x6e, 0x64, 0x5f, 0x72, 0x61, 0x69, 0x73, 0x65, 0x18, 0x2d, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x48,
+	0x61, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x09, 0x68, 0x61, 0x6e, 0x64, 0x52, 0x61, 0x69, 0x73, 0x65,
+	0x12, 0x30, 0x0a, 0x0a, 0x68, 0x61, 0x6e, 0x64, 0x5f, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x18, 0x2e,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31,
+	0x2e, 0x48, 0x61, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x09, 0x68, 0x61, 0x6e, 0x64, 0x4c, 0x6f, 0x77,
+	0x65, 0x72, 0x12, 0x3b, 0x0a, 0x0a, 0x68, 0x61, 0x6e, 0x64, 0x5f, 0x67, 0x72, 0x61, 0x6e, 0x74,
+	0x18, 0x2f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x41, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x68, 0x61, 0x6e, 0x64, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x12,
+	0x32, 0x0a, 0x0b, 0x68, 0x61, 0x6e, 0x64, 0x73, 0x5f, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x18, 0x30,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31,
+	0x2e, 0x48, 0x61, 0x6e, 0x64, 0x48, 0x00, 0x52, 0x0a, 0x68, 0x61, 0x6e, 0x64, 0x73, 0x43, 0x6c,
+	0x65, 0x61, 0x72, 0x12, 0x3f, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x76,
+	0x6f, 0x6b, 0x65, 0x18, 0x32, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65,
+	0x76, 0x6f, 0x6b, 0x65, 0x12, 0x3b, 0x0a, 0x0a, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x6c, 0x6f,
+	0x63, 0x6b, 0x18, 0x33, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x09, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x4c, 0x6f, 0x63,
+	0x6b, 0x12, 0x3f, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x75, 0x6e, 0x6c, 0x6f, 0x63,
+	0x6b, 0x18, 0x34, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x55, 0x6e, 0x6c, 0x6f,
+	0x63, 0x6b, 0x12, 0x28, 0x0a, 0x05, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x18, 0x09, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x65,
+	0x6c, 0x6c, 0x6f, 0x48, 0x00, 0x52, 0x05, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x25, 0x0a, 0x04,
+	0x70, 0x69, 0x6e, 0x67, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c,
+	0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x48, 0x00, 0x52, 0x04, 0x70,
+	0x69, 0x6e, 0x67, 0x12, 0x22, 0x0a, 0x03, 0x61, 0x63, 0x6b, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x63, 0x6b,
+	0x48, 0x00, 0x52, 0x03, 0x61, 0x63, 0x6b, 0x12, 0x25, 0x0a, 0x04, 0x63, 0x68, 0x61, 0x74, 0x18,
+	0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x48, 0x00, 0x52, 0x04, 0x63, 0x68, 0x61, 0x74, 0x12, 0x2e,
+	0x0a, 0x07, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x12, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x68, 0x69, 0x73,
+	0x70, 0x65, 0x72, 0x48, 0x00, 0x52, 0x07, 0x77, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x12, 0x2b,
+	0x0a, 0x06, 0x72, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x18, 0x22, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11,
+	0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x73, 0x74, 0x65,
+	0x72, 0x48, 0x00, 0x52, 0x06, 0x72, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x04, 0x74,
+	0x69, 0x65, 0x72, 0x18, 0x36, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x65, 0x72, 0x48, 0x00, 0x52, 0x04, 0x74, 0x69,
+	0x65, 0x72, 0x12, 0x2b, 0x0a, 0x06, 0x6a, 0x6f, 0x69, 0x6e, 0x65, 0x64, 0x18, 0x10, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4a,
+	0x6f, 0x69, 0x6e, 0x65, 0x64, 0x48, 0x00, 0x52, 0x06, 0x6a, 0x6f, 0x69, 0x6e, 0x65, 0x64, 0x12,
+	0x28, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x25, 0x0a, 0x04, 0x77, 0x69, 0x6c,
+	0x6c, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69, 0x6c, 0x6c, 0x48, 0x00, 0x52, 0x04, 0x77, 0x69, 0x6c, 0x6c,
+	0x12, 0x2d, 0x0a, 0x05, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x18, 0x13, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x15, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6f, 0x6d,
+	0x4e, 0x6f, 0x74, 0x69, 0x63, 0x65, 0x48, 0x00, 0x52, 0x05, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x12,
+	0x31, 0x0a, 0x07, 0x75, 0x6e, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x15, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6f,
+	0x6d, 0x4e, 0x6f, 0x74, 0x69, 0x63, 0x65, 0x48, 0x00, 0x52, 0x07, 0x75, 0x6e, 0x6d, 0x75, 0x74,
+	0x65, 0x64, 0x12, 0x25, 0x0a, 0x04, 0x6c, 0x65, 0x66, 0x74, 0x18, 0x15, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x65, 0x66,
+	0x74, 0x48, 0x00, 0x52, 0x04, 0x6c, 0x65, 0x66, 0x74, 0x12, 0x3b, 0x0a, 0x0c, 0x72, 0x61, 0x74,
+	0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x65, 0x64, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x16, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x61, 0x74, 0x65,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x65, 0x64, 0x48, 0x00, 0x52, 0x0b, 0x72, 0x61, 0x74, 0x65, 0x4c,
+	0x69, 0x6d, 0x69, 0x74, 0x65, 0x64, 0x12, 0x51, 0x0a, 0x14, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6d,
+	0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x75, 0x74, 0x5f, 0x6f, 0x66, 0x66, 0x18, 0x17,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31,
+	0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x43, 0x75, 0x74,
+	0x4f, 0x66, 0x66, 0x48, 0x00, 0x52, 0x12, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x43, 0x75, 0x74, 0x4f, 0x66, 0x66, 0x12, 0x3b, 0x0a, 0x0c, 0x72, 0x6f, 0x6f,
+	0x6d, 0x5f, 0x63, 0x6c, 0x6f, 0x73, 0x69, 0x6e, 0x67, 0x18, 0x18, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x16, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6f, 0x6d,
+	0x43, 0x6c, 0x6f, 0x73, 0x69, 0x6e, 0x67, 0x48, 0x00, 0x52, 0x0b, 0x72, 0x6f, 0x6f, 0x6d, 0x43,
+	0x6c, 0x6f, 0x73, 0x69, 0x6e, 0x67, 0x12, 0x3e, 0x0a, 0x0d, 0x72, 0x6f, 0x6f, 0x6d, 0x5f, 0x72,
+	0x65, 0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x18, 0x19, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e,
+	0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x52, 0x65,
+	0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x48, 0x00, 0x52, 0x0c, 0x72, 0x6f, 0x6f, 0x6d, 0x52, 0x65,
+	0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x12, 0x34, 0x0a, 0x09, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x48,
+	0x00, 0x52, 0x09, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x3d, 0x0a, 0x0c,
+	0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x1b, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x41,
+	0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x0c, 0x61,
+	0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x07, 0x71,
+	0x75, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x77,
+	0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x48, 0x00, 0x52, 0x07, 0x71, 0x75, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x25, 0x0a, 0x04, 0x70,
+	0x6f, 0x6e, 0x67, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x6e, 0x67, 0x48, 0x00, 0x52, 0x04, 0x70, 0x6f,
+	0x6e, 0x67, 0x12, 0x25, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67,
+	0x73, 0x48, 0x00, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x31, 0x0a, 0x08, 0x70, 0x72, 0x65,
+	0x73, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x21, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x77, 0x61,
+	0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65,
+	0x48, 0x00, 0x52, 0x08, 0x70, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x25, 0x0a, 0x04,
+	0x74, 0x61, 0x6c, 0x6b, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c,
+	0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x6c, 0x6b, 0x48, 0x00, 0x52, 0x04, 0x74,
+	0x61, 0x6c, 0x6b, 0x12, 0x28, 0x0a, 0x05, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x18, 0x26, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46,
+	0x6c, 0x6f, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x05, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x12, 0x3c, 0x0a,
+	0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x67, 0x72, 0x61, 0x6e, 0x74, 0x65, 0x64, 0x18, 0x27,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31,
+	0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x0c, 0x66,
+	0x6c, 0x6f, 0x6f, 0x72, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x65, 0x64, 0x12, 0x3a, 0x0a, 0x0c, 0x66,
+	0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x64, 0x65, 0x6e, 0x69, 0x65, 0x64, 0x18, 0x28, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c,
+	0x6f, 0x6f, 0x72, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x6f,
+	0x72, 0x44, 0x65, 0x6e, 0x69, 0x65, 0x64, 0x12, 0x3b, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72,
+	0x5f, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x51,
+	0x75, 0x65, 0x75, 0x65, 0x64, 0x48, 0x00, 0x52, 0x0b, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x51, 0x75,
+	0x65, 0x75, 0x65, 0x64, 0x12, 0x3e, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x6f, 0x66,
+	0x66, 0x65, 0x72, 0x65, 0x64, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61,
+	0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4f, 0x66, 0x66,
+	0x65, 0x72, 0x65, 0x64, 0x48, 0x00, 0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x4f, 0x66, 0x66,
+	0x65, 0x72, 0x65, 0x64, 0x12, 0x3c, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x72, 0x65,
+	0x76, 0x6f, 0x6b, 0x65, 0x64, 0x18, 0x2c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x77, 0x61,
+	0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x47, 0x72, 0x61,
+	0x6e, 0x74, 0x48, 0x00, 0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x76, 0x6f, 0x6b,
+	0x65, 0x64, 0x12, 0x22, 0x0a, 0x03, 0x65, 0x6f, 0x74, 0x18, 0x31, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x6f, 0x74, 0x48,
+	0x00, 0x52, 0x03, 0x65, 0x6f, 0x74, 0x12, 0x44, 0x0a, 0x0f, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f,
+	0x6d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x18, 0x35, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6c, 0x6f, 0x6f,
+	0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x48, 0x00, 0x52, 0x0e, 0x66, 0x6c,
+	0x6f, 0x6f, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x12, 0x2b, 0x0a, 0x06,
+	0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x18, 0x37, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x77,
+	0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x48,
+	0x00, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x12, 0x28, 0x0a, 0x05, 0x6c, 0x65, 0x76,
+	0x65, 0x6c, 0x18, 0x38, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x65,
+	0x76, 0x65, 0x6c, 0x12, 0x14, 0x0a, 0x04, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x64, 0x20, 0x01, 0x28,
+	0x09, 0x48, 0x00, 0x52, 0x04, 0x6a, 0x73, 0x6f, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x22, 0xf5, 0x01, 0x0a, 0x05, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x12, 0x12,
+	0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f,
+	0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x10,
+	0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x73, 0x65, 0x71,
+	0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x08, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08,
+	0x63, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
+	0x63, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61,
+	0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x09, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x6c,
+	0x65, 0x76, 0x65, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x22, 0x2d, 0x0a, 0x09,
+	0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x73, 0x65, 0x71, 0x12, 0x0e, 0x0a, 0x02, 0x74,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x22, 0x21, 0x0a, 0x05, 0x48,
+	0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x7d,
+	0x0a, 0x04, 0x4a, 0x6f, 0x69, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x6a, 0x6f,
+	0x69, 0x6e, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x6a, 0x6f, 0x69, 0x6e, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x2e, 0x0a, 0x06, 0x66, 0x6f, 0x72,
+	0x6d, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x77, 0x61, 0x6c, 0x6b,
+	0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x46, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x67,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x22, 0x50, 0x0a,
+	0x03, 0x56, 0x6f, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x74, 0x74, 0x61, 0x63, 0x6b, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x61, 0x74, 0x74, 0x61, 0x63, 0x6b, 0x12, 0x18, 0x0a, 0x07,
+	0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x68, 0x6f, 0x6c, 0x64, 0x5f, 0x6d,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x6f, 0x6c, 0x64, 0x4d, 0x73, 0x22,
+	0x7b, 0x0a, 0x0b, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63,
+	0x6f, 0x64, 0x65, 0x63, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72,
+	0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x73, 0x61, 0x6d, 0x70, 0x6c,
+	0x65, 0x52, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c,
+	0x73, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x4d, 0x73, 0x22, 0x3d, 0x0a, 0x0f,
+	0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x22, 0xa1, 0x01, 0x0a, 0x0b,
+	0x4b, 0x65, 0x79, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12,
+	0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x30, 0x0a, 0x07, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a,
+	0x03, 0x61, 0x63, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x63, 0x6b, 0x22,
+	0xac, 0x01, 0x0a, 0x04, 0x43, 0x68, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x65, 0x63, 0x68, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04,
+	0x65, 0x63, 0x68, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61,
+	0x67, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x22, 0x61,
+	0x0a, 0x07, 0x57, 0x68, 0x69, 0x73, 0x70, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f,
+	0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x16, 0x0a,
+	0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76,
+	0x65, 0x22, 0x2f, 0x0a, 0x05, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f,
+	0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x12,
+	0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61,
+	0x67, 0x73, 0x22, 0x4a, 0x0a, 0x06, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04,
+	0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d,
+	0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6d, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x73, 0x18,
+	0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x73, 0x22, 0x2e,
+	0x0a, 0x04, 0x54, 0x61, 0x67, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61,
+	0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x22, 0x1f,
+	0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22,
+	0x3d, 0x0a, 0x03, 0x41, 0x63, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72,
+	0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x22, 0xab,
+	0x08, 0x0a, 0x06, 0x4a, 0x6f, 0x69, 0x6e, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f,
+	0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x21, 0x0a,
+	0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x12, 0x1f, 0x0a, 0x0b,
+	0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0a, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x6d, 0x61, 0x78,
+	0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0e, 0x6d, 0x61, 0x78, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x53,
+	0x69, 0x7a, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x22, 0x0a,
+	0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x0b, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69, 0x74, 0x74, 0x69, 0x6e,
+	0x67, 0x12, 0x1c, 0x0a, 0x09, 0x6d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x6d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x12,
+	0x14, 0x0a, 0x05, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05,
+	0x6d, 0x75, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x0e, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x5f, 0x6d, 0x73, 0x18,
+	0x10, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x4d, 0x73, 0x12, 0x3f,
+	0x0a, 0x0f, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74,
+	0x73, 0x18, 0x11, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x52,
+	0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x73, 0x12,
+	0x27, 0x0a, 0x0f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x5f, 0x6d, 0x69, 0x73, 0x6d, 0x61, 0x74,
+	0x63, 0x68, 0x18, 0x12, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74,
+	0x4d, 0x69, 0x73, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x12, 0x23, 0x0a, 0x04, 0x63, 0x68, 0x61, 0x74,
+	0x18, 0x13, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x43, 0x68, 0x61, 0x74, 0x52, 0x04, 0x63, 0x68, 0x61, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x14, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67,
+	0x73, 0x12, 0x32, 0x0a, 0x07, 0x70, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x18, 0x15, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50,
+	0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x07, 0x70, 0x72,
+	0x65, 0x73, 0x65, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x5f,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x16, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x72,
+	0x6f, 0x73, 0x74, 0x65, 0x72, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c,
+	0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x18, 0x17, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x12, 0x23, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x18, 0x18, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x43, 0x6f,
+	0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x5f, 0x68,
+	0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x19, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x6c, 0x6f,
+	0x6f, 0x72, 0x48, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x12, 0x21, 0x0a, 0x0c, 0x66, 0x6c, 0x6f, 0x6f,
+	0x72, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b,
+	0x66, 0x6c, 0x6f, 0x6f, 0x72, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x12, 0x20, 0x0a, 0x03, 0x76,
+	0x6f, 0x78, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x77, 0x61, 0x6c, 0x6b, 0x69,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x6f, 0x78, 0x52, 0x03, 0x76, 0x6f, 0x78, 0x12, 0x16, 0x0a,
+	0x06, 0x6d, 0x69, 0x78, 0x69, 0x6e, 0x67, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6d,
+	0x69, 0x78, 0x69, 0x6e, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65,
+	0x18, 0x1d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x69, 0x65, 0x72, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x69, 0x65, 0x72, 0x12, 0x2e, 0x0a, 0x13, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x5f, 0x73,
+	0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x11, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x53, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52,
+	0x61, 0x74, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x5f, 0x63,
+	0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x20, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x72,
+	0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x69, 0x74, 0x79, 0x18, 0x21, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x69, 0x74, 0x79, 0x22, 0x83, 0x01, 0x0a,
+	0x0d, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x68, 0x61, 0x6e, 0x64, 0x5f, 0x72,
+	0x61, 0x69, 0x73, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c,
+	0x68, 0x61, 0x6e, 0x64, 0x52, 0x61, 0x69, 0x73, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x6d, 0x75, 0x74, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x6d, 0x75, 0x74,
+	0x65, 0x64, 0x22, 0xda, 0x01, 0x0a, 0x08, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x65, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6c,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0e, 0x68, 0x61, 0x6e, 0x64, 0x5f, 0x72, 0x61, 0x69, 0x73,
+	0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x68, 0x61, 0x6e,
+	0x64, 0x52, 0x61, 0x69, 0x73, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x75, 0x74,
+	0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x22,
+	0x40, 0x0a, 0x04, 0x54, 0x61, 0x6c, 0x6b, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x22, 0x69, 0x0a, 0x06, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12,
+	0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x31, 0x0a, 0x07, 0x63, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x77, 0x61, 0x6c,
+	0x6b, 0x69, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x43, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x22, 0xd0, 0x01, 0x0a,
+	0x0c, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x72, 0x6f, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x04, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x75, 0x74,
+	0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x6d, 0x75, 0x74, 0x65, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x73, 0x69, 0x6e, 0x63, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69,
+	0x74, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x6d, 0x69, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x24, 0x0a, 0x0e, 0x68, 0x61, 0x6e,
+	0x64, 0x5f, 0x72, 0x61, 0x69, 0x73, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0c, 0x68, 0x61, 0x6e, 0x64, 0x52, 0x61, 0x69, 0x73, 0x65, 0x64, 0x41, 0x74, 0x22,
+	0x3b, 0x0a, 0x05, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x0e, 0x0a, 0x02,
+	0x64, 0x62, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x64, 0x62, 0x22, 0x60, 0x0a, 0x06,
+	0x52, 0x65, 0x70, 0x6c, 0x61, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x06, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x6e, 0x63,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x22, 0x6b,
+	0x0a, 0x04, 0x54, 0x69, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x69, 0x65, 0x72, 0x12, 0x1f,
+	0x0a, 0x0b, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0a, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x52, 0x61, 0x74, 0x65, 0x12,
+	0x1a, 0x0a, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x73, 0x22, 0x3e, 0x0a, 0x0c, 0x46,
+	0x6c, 0x6f, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12,
+	0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x7f, 0x0a, 0x05, 0x46,
+	0x6c, 0x6f, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x6f, 0x6c, 0x64,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f,
+	0x72, 0x69, 0x74, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f,
+	0x72, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x22, 0x50, 0x0a, 0x0a,
+	0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f,
+	0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x16,
+	0x0a, 0x06, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x25,
+	0x0a, 0x0f, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x22, 0x6e, 0x0a, 0x0e, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x4d, 0x6f,
+	0x64, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x16, 0x0a, 0x06, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6c,
+	0x6f, 0x63, 0x6b, 0x65, 0x64, 0x22, 0x1a, 0x0a, 0x04, 0x48, 0x61, 0x6e, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f,
+	0x6d, 0x22, 0x55, 0x0a, 0x0b, 0x46, 0x6c, 0x6f, 0x6f, 0x72, 0x51, 0x75, 0x65, 0x75, 0x65, 0x64,
+	0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x41, 0x0a, 0x0c, 0x46, 0x6c, 0x6f, 0x6f,
+	0x72, 0x4f, 0x66, 0x66, 0x65, 0x72, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x1d, 0x0a, 0x0a,
+	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x22, 0x53, 0x0a, 0x03, 0x45,
+	0x6f, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x03, 0x73, 0x65, 0x71, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x22, 0x90, 0x01, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x12, 0x19, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x6e, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6f, 0x70, 0x65, 0x6e, 0x73, 0x41, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x64,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x65, 0x74,
+	0x61, 0x69, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x64, 0x22, 0x70, 0x0a, 0x04, 0x57, 0x69, 0x6c, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61,
+	0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x20, 0x0a, 0x0a, 0x52, 0x6f, 0x6f, 0x6d, 0x4e, 0x6f, 0x74,
+	0x69, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x22, 0x46, 0x0a, 0x04, 0x4c, 0x65, 0x66, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22,
+	0xad, 0x01, 0x0a, 0x0b, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x65, 0x64, 0x12,
+	0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x12, 0x2e, 0x0a,
+	0x13, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x12, 0x28, 0x0a,
+	0x10, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0e, 0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65,
+	0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x22,
+	0x7f, 0x0a, 0x12, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x43,
+	0x75, 0x74, 0x4f, 0x66, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12, 0x2a, 0x0a, 0x11, 0x6d, 0x61, 0x78,
+	0x5f, 0x62, 0x75, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x6d, 0x61, 0x78, 0x42, 0x75, 0x72, 0x73, 0x74, 0x53, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6f, 0x6c, 0x64, 0x6f, 0x77,
+	0x6e, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0f, 0x63, 0x6f, 0x6f, 0x6c, 0x64, 0x6f, 0x77, 0x6e, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73,
+	0x22, 0x59, 0x0a, 0x0b, 0x52, 0x6f, 0x6f, 0x6d, 0x43, 0x6c, 0x6f, 0x73, 0x69, 0x6e, 0x67, 0x12,
+	0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x5f, 0x61, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x41, 0x74,
+	0x12, 0x19, 0x0a, 0x08, 0x6f, 0x70, 0x65, 0x6e, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6f, 0x70, 0x65, 0x6e, 0x73, 0x41, 0x74, 0x22, 0x3f, 0x0a, 0x0c, 0x52,
+	0x6f, 0x6f, 0x6d, 0x52, 0x65, 0x6f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x6d, 0x12,
+	0x1b, 0x0a, 0x09, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73, 0x41, 0x74, 0x22, 0x30, 0x0a, 0x09,
+	0x52, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x66, 0x74,
+	0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0c, 0x61, 0x66, 0x74, 0x65, 0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x58,
+	0x0a, 0x0c, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65,
+	0x78, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x63, 0x6b, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x63, 0x6b, 0x22, 0xa3, 0x01, 0x0a, 0x07, 0x51, 0x75, 0x61,
+	0x6c, 0x69, 0x74, 0x79, 0x12, 0x2a, 0x0a, 0x11, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x5f, 0x6c, 0x6f, 0x73, 0x73, 0x5f, 0x70, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0f, 0x75, 0x70, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x73, 0x73, 0x50, 0x63, 0x74,
+	0x12, 0x2e, 0x0a, 0x13, 0x64, 0x6f, 0x77, 0x6e, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x6c,
+	0x6f, 0x73, 0x73, 0x5f, 0x70, 0x63, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11, 0x64,
+	0x6f, 0x77, 0x6e, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4c, 0x6f, 0x73, 0x73, 0x50, 0x63, 0x74,
+	0x12, 0x25, 0x0a, 0x0e, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77,
+	0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x15, 0x0a, 0x06, 0x72, 0x74, 0x74, 0x5f, 0x6d,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x72, 0x74, 0x74, 0x4d, 0x73, 0x22, 0x54,
+	0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x2c, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x6e,
+	0x6f, 0x6e, 0x63, 0x65, 0x12, 0x0c, 0x0a, 0x01, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x01, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x74, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x03, 0x72, 0x74, 0x74, 0x22, 0x75, 0x0a, 0x04, 0x50, 0x6f, 0x6e, 0x67, 0x12, 0x2c, 0x0a, 0x05,
+	0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x0c, 0x0a, 0x01, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x01, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x5f, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x54, 0x12, 0x16, 0x0a, 0x06, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x42, 0x1a, 0x5a, 0x18, 0x77,
+	0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2d, 0x74, 0x61, 0x6c, 0x6b, 0x69, 0x65, 0x2d, 0x67, 0x61, 0x74,
+	0x65, 0x77, 0x61, 0x79, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pb_walkie_proto_rawDescOnce sync.Once
+	file_pb_walkie_proto_rawDescData = file_pb_walkie_proto_rawDesc
+)
+
+func file_pb_walkie_proto_rawDescGZIP() []byte {
+	file_pb_walkie_proto_rawDescOnce.Do(func() {
+		file_pb_walkie_proto_rawDescData = protoimpl.X.CompressGZIP(file_pb_walkie_proto_rawDescData)
+	})
+	return file_pb_walkie_proto_rawDescData
+}
+
+var file_pb_walkie_proto_msgTypes = make([]protoimpl.MessageInfo, 47)
+var file_pb_walkie_proto_goTypes = []any{
+	(*Envelope)(nil),           // 0: walkie.v1.Envelope
+	(*Audio)(nil),              // 1: walkie.v1.Audio
+	(*Heartbeat)(nil),          // 2: walkie.v1.Heartbeat
+	(*Hello)(nil),              // 3: walkie.v1.Hello
+	(*Join)(nil),               // 4: walkie.v1.Join
+	(*Vox)(nil),                // 5: walkie.v1.Vox
+	(*AudioFormat)(nil),        // 6: walkie.v1.AudioFormat
+	(*ModeratorAction)(nil),    // 7: walkie.v1.ModeratorAction
+	(*KeyExchange)(nil),        // 8: walkie.v1.KeyExchange
+	(*Chat)(nil),               // 9: walkie.v1.Chat
+	(*Whisper)(nil),            // 10: walkie.v1.Whisper
+	(*Group)(nil),              // 11: walkie.v1.Group
+	(*Filter)(nil),             // 12: walkie.v1.Filter
+	(*Tags)(nil),               // 13: walkie.v1.Tags
+	(*Block)(nil),              // 14: walkie.v1.Block
+	(*Ack)(nil),                // 15: walkie.v1.Ack
+	(*Joined)(nil),             // 16: walkie.v1.Joined
+	(*PresentClient)(nil),      // 17: walkie.v1.PresentClient
+	(*Presence)(nil),           // 18: walkie.v1.Presence
+	(*Talk)(nil),               // 19: walkie.v1.Talk
+	(*Roster)(nil),             // 20: walkie.v1.Roster
+	(*RosterClient)(nil),       // 21: walkie.v1.RosterClient
+	(*Level)(nil),              // 22: walkie.v1.Level
+	(*Replay)(nil),             // 23: walkie.v1.Replay
+	(*Tier)(nil),               // 24: walkie.v1.Tier
+	(*FloorRequest)(nil),       // 25: walkie.v1.FloorRequest
+	(*Floor)(nil),              // 26: walkie.v1.Floor
+	(*FloorGrant)(nil),         // 27: walkie.v1.FloorGrant
+	(*FloorModeration)(nil),    // 28: walkie.v1.FloorModeration
+	(*FloorModerated)(nil),     // 29: walkie.v1.FloorModerated
+	(*Hand)(nil),               // 30: walkie.v1.Hand
+	(*FloorQueued)(nil),        // 31: walkie.v1.FloorQueued
+	(*FloorOffered)(nil),       // 32: walkie.v1.FloorOffered
+	(*Eot)(nil),                // 33: walkie.v1.Eot
+	(*Error)(nil),              // 34: walkie.v1.Error
+	(*Will)(nil),               // 35: walkie.v1.Will
+	(*RoomNotice)(nil),         // 36: walkie.v1.RoomNotice
+	(*Left)(nil),               // 37: walkie.v1.Left
+	(*RateLimited)(nil),        // 38: walkie.v1.RateLimited
+	(*TransmissionCutOff)(nil), // 39: walkie.v1.TransmissionCutOff
+	(*RoomClosing)(nil),        // 40: walkie.v1.RoomClosing
+	(*RoomReopened)(nil),       // 41: walkie.v1.RoomReopened
+	(*Reconnect)(nil),          // 42: walkie.v1.Reconnect
+	(*Announcement)(nil),       // 43: walkie.v1.Announcement
+	(*Quality)(nil),            // 44: walkie.v1.Quality
+	(*Ping)(nil),               // 45: walkie.v1.Ping
+	(*Pong)(nil),               // 46: walkie.v1.Pong
+	(*structpb.Value)(nil),     // 47: google.protobuf.Value
+}
+var file_pb_walkie_proto_depIdxs = []int32{
+	1,  // 0: walkie.v1.Envelope.audio:type_name -> walkie.v1.Audio
+	2,  // 1: walkie.v1.Envelope.hb:type_name -> walkie.v1.Heartbeat
+	4,  // 2: walkie.v1.Envelope.join:type_name -> walkie.v1.Join
+	4,  // 3: walkie.v1.Envelope.leave:type_name -> walkie.v1.Join
+	7,  // 4: walkie.v1.Envelope.kick:type_name -> walkie.v1.ModeratorAction
+	7,  // 5: walkie.v1.Envelope.mute:type_name -> walkie.v1.ModeratorAction
+	7,  // 6: walkie.v1.Envelope.unmute:type_name -> walkie.v1.ModeratorAction
+	8,  // 7: walkie.v1.Envelope.keyx:type_name -> walkie.v1.KeyExchange
+	14, // 8: walkie.v1.Envelope.block:type_name -> walkie.v1.Block
+	14, // 9: walkie.v1.Envelope.unblock:type_name -> walkie.v1.Block
+	11, // 10: walkie.v1.Envelope.group:type_name -> walkie.v1.Group
+	12, // 11: walkie.v1.Envelope.filter:type_name -> walkie.v1.Filter
+	25, // 12: walkie.v1.Envelope.floor_request:type_name -> walkie.v1.FloorRequest
+	25, // 13: walkie.v1.Envelope.floor_release:type_name -> walkie.v1.FloorRequest
+	25, // 14: walkie.v1.Envelope.floor_cancel:type_name -> walkie.v1.FloorRequest
+	30, // 15: walkie.v1.Envelope.hand_raise:type_name -> walkie.v1.Hand
+	30, // 16: walkie.v1.Envelope.hand_lower:type_name -> walkie.v1.Hand
+	7,  // 17: walkie.v1.Envelope.hand_grant:type_name -> walkie.v1.ModeratorAction
+	30, // 18: walkie.v1.Envelope.hands_clear:type_name -> walkie.v1.Hand
+	28, // 19: walkie.v1.Envelope.floor_revoke:type_name -> walkie.v1.FloorModeration
+	28, // 20: walkie.v1.Envelope.floor_lock:type_name -> walkie.v1.FloorModeration
+	28, // 21: walkie.v1.Envelope.floor_unlock:type_name -> walkie.v1.FloorModeration
+	3,  // 22: walkie.v1.Envelope.hello:type_name -> walkie.v1.Hello
+	45, // 23: walkie.v1.Envelope.ping:type_name -> walkie.v1.Ping
+	15, // 24: walkie.v1.Envelope.ack:type_name -> walkie.v1.Ack
+	9,  // 25: walkie.v1.Envelope.chat:type_name -> walkie.v1.Chat
+	10, // 26: walkie.v1.Envelope.whisper:type_name -> walkie.v1.Whisper
+	20, // 27: walkie.v1.Envelope.roster:type_name -> walkie.v1.Roster
+	24, // 28: walkie.v1.Envelope.tier:type_name -> walkie.v1.Tier
+	16, // 29: walkie.v1.Envelope.joined:type_name -> walkie.v1.Joined
+	34, // 30: walkie.v1.Envelope.error:type_name -> walkie.v1.Error
+	35, // 31: walkie.v1.Envelope.will:type_name -> walkie.v1.Will
+	36, // 32: walkie.v1.Envelope.muted:type_name -> walkie.v1.RoomNotice
+	36, // 33: walkie.v1.Envelope.unmuted:type_name -> walkie.v1.RoomNotice
+	37, // 34: walkie.v1.Envelope.left:type_name -> walkie.v1.Left
+	38, // 35: walkie.v1.Envelope.rate_limited:type_name -> walkie.v1.RateLimited
+	39, // 36: walkie.v1.Envelope.transmission_cut_off:type_name -> walkie.v1.TransmissionCutOff
+	40, // 37: walkie.v1.Envelope.room_closing:type_name -> walkie.v1.RoomClosing
+	41, // 38: walkie.v1.Envelope.room_reopened:type_name -> walkie.v1.RoomReopened
+	42, // 39: walkie.v1.Envelope.reconnect:type_name -> walkie.v1.Reconnect
+	43, // 40: walkie.v1.Envelope.announcement:type_name -> walkie.v1.Announcement
+	44, // 41: walkie.v1.Envelope.quality:type_name -> walkie.v1.Quality
+	46, // 42: walkie.v1.Envelope.pong:type_name -> walkie.v1.Pong
+	13, // 43: walkie.v1.Envelope.tags:type_name -> walkie.v1.Tags
+	18, // 44: walkie.v1.Envelope.presence:type_name -> walkie.v1.Presence
+	19, // 45: walkie.v1.Envelope.talk:type_name -> walkie.v1.Talk
+	26, // 46: walkie.v1.Envelope.floor:type_name -> walkie.v1.Floor
+	27, // 47: walkie.v1.Envelope.floor_granted:type_name -> walkie.v1.FloorGrant
+	27, // 48: walkie.v1.Envelope.floor_denied:type_name -> walkie.v1.FloorGrant
+	31, // 49: walkie.v1.Envelope.floor_queued:type_name -> walkie.v1.FloorQueued
+	32, // 50: walkie.v1.Envelope.floor_offered:type_name -> walkie.v1.FloorOffered
+	27, // 51: walkie.v1.Envelope.floor_revoked:type_name -> walkie.v1.FloorGrant
+	33, // 52: walkie.v1.Envelope.eot:type_name -> walkie.v1.Eot
+	29, // 53: walkie.v1.Envelope.floor_moderated:type_name -> walkie.v1.FloorModerated
+	23, // 54: walkie.v1.Envelope.replay:type_name -> walkie.v1.Replay
+	22, // 55: walkie.v1.Envelope.level:type_name -> walkie.v1.Level
+	6,  // 56: walkie.v1.Join.format:type_name -> walkie.v1.AudioFormat
+	47, // 57: walkie.v1.KeyExchange.payload:type_name -> google.protobuf.Value
+	6,  // 58: walkie.v1.Joined.allowed_formats:type_name -> walkie.v1.AudioFormat
+	9,  // 59: walkie.v1.Joined.chat:type_name -> walkie.v1.Chat
+	17, // 60: walkie.v1.Joined.present:type_name -> walkie.v1.PresentClient
+	5,  // 61: walkie.v1.Joined.vox:type_name -> walkie.v1.Vox
+	21, // 62: walkie.v1.Roster.clients:type_name -> walkie.v1.RosterClient
+	47, // 63: walkie.v1.Ping.nonce:type_name -> google.protobuf.Value
+	47, // 64: walkie.v1.Pong.nonce:type_name -> google.protobuf.Value
+	65, // [65:65] is the sub-list for method output_type
+	65, // [65:65] is the sub-list for method input_type
+	65, // [65:65] is the sub-list for extension type_name
+	65, // [65:65] is the sub-list for extension extendee
+	0,  // [0:65] is the sub-list for field type_name
+}
+
+func init() { file_pb_walkie_proto_init() }
+func file_pb_walkie_proto_init() {
+	if File_pb_walkie_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pb_walkie_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Envelope); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Audio); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*Heartbeat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*Hello); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*Join); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*Vox); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*AudioFormat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*ModeratorAction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*KeyExchange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*Chat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*Whisper); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*Group); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*Filter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*Tags); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*Block); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*Ack); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*Joined); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*PresentClient); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*Presence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*Talk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[20].Exporter = func(v any, i int) any {
+			switch v := v.(*Roster); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*RosterClient); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*Level); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*Replay); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*Tier); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*Floor); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[27].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorGrant); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[28].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorModeration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[29].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorModerated); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[30].Exporter = func(v any, i int) any {
+			switch v := v.(*Hand); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[31].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorQueued); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[32].Exporter = func(v any, i int) any {
+			switch v := v.(*FloorOffered); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[33].Exporter = func(v any, i int) any {
+			switch v := v.(*Eot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[34].Exporter = func(v any, i int) any {
+			switch v := v.(*Error); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[35].Exporter = func(v any, i int) any {
+			switch v := v.(*Will); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[36].Exporter = func(v any, i int) any {
+			switch v := v.(*RoomNotice); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[37].Exporter = func(v any, i int) any {
+			switch v := v.(*Left); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[38].Exporter = func(v any, i int) any {
+			switch v := v.(*RateLimited); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[39].Exporter = func(v any, i int) any {
+			switch v := v.(*TransmissionCutOff); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[40].Exporter = func(v any, i int) any {
+			switch v := v.(*RoomClosing); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[41].Exporter = func(v any, i int) any {
+			switch v := v.(*RoomReopened); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[42].Exporter = func(v any, i int) any {
+			switch v := v.(*Reconnect); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[43].Exporter = func(v any, i int) any {
+			switch v := v.(*Announcement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[44].Exporter = func(v any, i int) any {
+			switch v := v.(*Quality); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[45].Exporter = func(v any, i int) any {
+			switch v := v.(*Ping); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pb_walkie_proto_msgTypes[46].Exporter = func(v any, i int) any {
+			switch v := v.(*Pong); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_pb_walkie_proto_msgTypes[0].OneofWrappers = []any{
+		(*Envelope_Audio)(nil),
+		(*Envelope_Hb)(nil),
+		(*Envelope_Join)(nil),
+		(*Envelope_Leave)(nil),
+		(*Envelope_Kick)(nil),
+		(*Envelope_Mute)(nil),
+		(*Envelope_Unmute)(nil),
+		(*Envelope_Keyx)(nil),
+		(*Envelope_Block)(nil),
+		(*Envelope_Unblock)(nil),
+		(*Envelope_Group)(nil),
+		(*Envelope_Filter)(nil),
+		(*Envelope_FloorRequest)(nil),
+		(*Envelope_FloorRelease)(nil),
+		(*Envelope_FloorCancel)(nil),
+		(*Envelope_HandRaise)(nil),
+		(*Envelope_HandLower)(nil),
+		(*Envelope_HandGrant)(nil),
+		(*Envelope_HandsClear)(nil),
+		(*Envelope_FloorRevoke)(nil),
+		(*Envelope_FloorLock)(nil),
+		(*Envelope_FloorUnlock)(nil),
+		(*Envelope_Hello)(nil),
+		(*Envelope_Ping)(nil),
+		(*Envelope_Ack)(nil),
+		(*Envelope_Chat)(nil),
+		(*Envelope_Whisper)(nil),
+		(*Envelope_Roster)(nil),
+		(*Envelope_Tier)(nil),
+		(*Envelope_Joined)(nil),
+		(*Envelope_Error)(nil),
+		(*Envelope_Will)(nil),
+		(*Envelope_Muted)(nil),
+		(*Envelope_Unmuted)(nil),
+		(*Envelope_Left)(nil),
+		(*Envelope_RateLimited)(nil),
+		(*Envelope_TransmissionCutOff)(nil),
+		(*Envelope_RoomClosing)(nil),
+		(*Envelope_RoomReopened)(nil),
+		(*Envelope_Reconnect)(nil),
+		(*Envelope_Announcement)(nil),
+		(*Envelope_Quality)(nil),
+		(*Envelope_Pong)(nil),
+		(*Envelope_Tags)(nil),
+		(*Envelope_Presence)(nil),
+		(*Envelope_Talk)(nil),
+		(*Envelope_Floor)(nil),
+		(*Envelope_FloorGranted)(nil),
+		(*Envelope_FloorDenied)(nil),
+		(*Envelope_FloorQueued)(nil),
+		(*Envelope_FloorOffered)(nil),
+		(*Envelope_FloorRevoked)(nil),
+		(*Envelope_Eot)(nil),
+		(*Envelope_FloorModerated)(nil),
+		(*Envelope_Replay)(nil),
+		(*Envelope_Level)(nil),
+		(*Envelope_Json)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pb_walkie_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   47,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pb_walkie_proto_goTypes,
+		DependencyIndexes: file_pb_walkie_proto_depIdxs,
+		MessageInfos:      file_pb_walkie_proto_msgTypes,
+	}.Build()
+	File_pb_walkie_proto = out.File
+	file_pb_walkie_proto_rawDesc = nil
+	file_pb_walkie_proto_goTypes = nil
+	file_pb_walkie_proto_depIdxs = nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"walkie-talkie-gateway/pb"
+)
+
+// protocolPB carries everything, audio and control messages, as binary
+// frames holding a pb.Envelope, for clients that can't afford JSON. The
+// gateway still speaks JSON inside and translates at the edge, so these
+// clients share rooms with everyone else.
+const protocolPB = "walkie.pb.v1"
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative pb/walkie.proto
+
+var (
+	// envelopeMessage is the Envelope oneof every message is a field of
+	envelopeMessage = (&pb.Envelope{}).ProtoReflect().Descriptor().Oneofs().ByName("message")
+
+	// The Envelope field for control messages with no message of their own
+	envelopeJSON = envelopeMessage.Fields().ByName("json")
+
+	// Control message JSON carries the type, which has no field in the
+	// message it translates to
+	fromJSON = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// errPBText is returned for a text frame from a walkie.pb.v1 client
+var errPBText = errors.New("walkie.pb.v1 carries binary frames only")
+
+// controlToPB translates a control message from its JSON to an Envelope,
+// by its type. A type without a message of its own goes as the JSON.
+func controlToPB(data []byte) ([]byte, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	envelope := (&pb.Envelope{}).ProtoReflect()
+	field := envelopeMessage.Fields().ByName(protoreflect.Name(head.Type))
+	if field == nil || field.Message() == nil || head.Type == "audio" {
+		envelope.Set(envelopeJSON, protoreflect.ValueOfString(string(data)))
+		return proto.Marshal(envelope.Interface())
+	}
+	message := envelope.NewField(field)
+	if err := fromJSON.Unmarshal(data, message.Message().Interface()); err != nil {
+		return nil, err
+	}
+	envelope.Set(field, message)
+	return proto.Marshal(envelope.Interface())
+}
+
+// controlFromPB translates a client's control message from an Envelope to
+// the form every subprotocol's messages are handled in, by field name
+func controlFromPB(envelope *pb.Envelope) (controlMessage, error) {
+	var message controlMessage
+	reflected := envelope.ProtoReflect()
+	field := reflected.WhichOneof(envelopeMessage)
+	if field == nil || field == envelopeJSON {
+		return message, errors.New("missing message")
+	}
+	message.Type = string(field.Name())
+	var err error
+	reflected.Get(field).Message().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch fd.Name() {
+		case "seq":
+			message.Seq = value.Uint()
+		case "target":
+			message.Target = value.String()
+		case "room":
+			message.Room = value.String()
+		case "join_token":
+			message.JoinToken = value.String()
+		case "version":
+			message.Version = int(value.Int())
+		case "t":
+			message.T = value.Int()
+		case "rtt":
+			message.RTT = value.Float()
+		case "id":
+			message.ID = value.String()
+		case "ack":
+			message.Ack = value.Bool()
+		case "text":
+			message.Text = value.String()
+		case "echo":
+			message.Echo = value.Bool()
+		case "tags":
+			message.Tags = stringsFromPB(value.List())
+		case "mode":
+			message.Mode = value.String()
+		case "senders":
+			message.Senders = stringsFromPB(value.List())
+		case "priority":
+			message.Priority = int(value.Int())
+		case "tier":
+			message.Tier = value.String()
+		case "payload":
+			message.Payload, err = protojson.Marshal(value.Message().Interface().(*structpb.Value))
+		case "format":
+			message.Format = formatFromPB(value.Message().Interface().(*pb.AudioFormat))
+		case "nonce":
+			message.Nonce, err = protojson.Marshal(value.Message().Interface().(*structpb.Value))
+		}
+		return err == nil
+	})
+	return message, err
+}
+
+// stringsFromPB translates a repeated string field
+func stringsFromPB(list protoreflect.List) []string {
+	strings := make([]string, list.Len())
+	for i := range strings {
+		strings[i] = list.Get(i).String()
+	}
+	return strings
+}
+
+// formatFromPB translates an audio format a client declares
+func formatFromPB(format *pb.AudioFormat) *audioFormat {
+	return &audioFormat{
+		Codec:      format.Codec,
+		SampleRate: int(format.SampleRate),
+		Channels:   int(format.Channels),
+		FrameMs:    int(format.FrameMs),
+	}
+}
+
+// inboundPB is inbound for walkie.pb.v1 clients
+func (c *Client) inboundPB(frame *frameBuffer) (string, bool, error) {
+	if frame.messageType == websocket.TextMessage {
+		return "", false, errPBText
+	}
+	var envelope pb.Envelope
+	if err := proto.Unmarshal(frame.data, &envelope); err != nil {
+		return "", false, c.invalidControl("malformed", "", err)
+	}
+	if audio := envelope.GetAudio(); audio != nil {
+		frame.data = append(frame.data[:0], audio.Data...)
+		frame.header.seq = audio.Seq
+		frame.header.captured = audio.Captured
+		frame.header.priority = audio.Priority
+		frame.header.level = audio.Level
+		return audio.Room, true, nil
+	}
+	message, err := controlFromPB(&envelope)
+	if err != nil {
+		return "", false, c.invalidControl("malformed", "", err)
+	}
+	return "", false, c.dispatchControl(message)
+}
+
+// audioToPB wraps audio in an Envelope, tagged with the room it comes from
+// for a multi-room client
+func audioToPB(room string, frame *frameBuffer, replayed bool) ([]byte, error) {
+	return proto.Marshal(&pb.Envelope{Message: &pb.Envelope_Audio{Audio: &pb.Audio{
+		Room:     room,
+		Data:     frame.data,
+		Sender:   frame.header.sender,
+		Seq:      frame.header.seq,
+		Received: frame.header.received,
+		Captured: frame.header.captured,
+		Private:  frame.header.private,
+		Priority: frame.header.priority,
+		Replay:   replayed,
+	}}})
+}
+
+// pbMessage returns the frame as a walkie.pb.v1 PreparedMessage, translating
+// it on first use
+func (f *frameBuffer) pbMessage() (*websocket.PreparedMessage, error) {
+	f.pbOnce.Do(func() {
+		var data []byte
+		if f.messageType == websocket.TextMessage {
+			data, f.pbErr = controlToPB(f.data)
+		} else {
+			data, f.pbErr = audioToPB("", f, false)
+		}
+		if f.pbErr == nil {
+			f.pbPrepared, f.pbErr = websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+		}
+	})
+	return f.pbPrepared, f.pbErr
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"walkie-talkie-gateway/pb"
+)
+
+// fill sets every exported field of v, recursively, to a value that isn't
+// zero, so every key of a message marshals
+func fill(v reflect.Value) {
+	switch v.Type() {
+	case reflect.TypeOf(time.Time{}):
+		v.Set(reflect.ValueOf(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+		return
+	case reflect.TypeOf(json.RawMessage{}):
+		v.SetBytes(json.RawMessage(`{"k":"v"}`))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Uint64:
+		v.SetUint(7)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte{1, 2, 3})
+			return
+		}
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.IsExported() || f.Anonymous {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+// filled returns message with every field set, as fill does, and the type
+// given
+func filled[T any](typ string) any {
+	var message T
+	v := reflect.ValueOf(&message).Elem()
+	fill(v)
+	v.FieldByName("Type").SetString(typ)
+	return message
+}
+
+// decodeJSON decodes a JSON object with its numbers as strings, which is how
+// protojson writes 64 bit integers
+func decodeJSON(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return numbersAsStrings(object).(map[string]any)
+}
+
+// numbersAsStrings replaces the json.Numbers in v with their text
+func numbersAsStrings(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String()
+	case map[string]any:
+		for key, value := range v {
+			v[key] = numbersAsStrings(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = numbersAsStrings(value)
+		}
+	}
+	return v
+}
+
+// sameKeys reports every key of want, at any depth, that got lacks or holds
+// something else under. A nested message's type is the field it is in.
+func sameKeys(t *testing.T, path string, got, want map[string]any) {
+	t.Helper()
+	for key, value := range want {
+		other, ok := got[key]
+		switch {
+		case key == "type" && path != "":
+		case !ok:
+			t.Errorf("%s%s lost", path, key)
+		case reflect.TypeOf(value) != reflect.TypeOf(other):
+			t.Errorf("%s%s is %v, want %v", path, key, other, value)
+		default:
+			switch value := value.(type) {
+			case map[string]any:
+				sameKeys(t, path+key+".", other.(map[string]any), value)
+			case []any:
+				others := other.([]any)
+				if len(others) != len(value) {
+					t.Errorf("%s%s has %d elements, want %d", path, key, len(others), len(value))
+					continue
+				}
+				for i := range value {
+					if object, ok := value[i].(map[string]any); ok {
+						sameKeys(t, fmt.Sprintf("%s%s[%d].", path, key, i), others[i].(map[string]any), object)
+					} else if !reflect.DeepEqual(others[i], value[i]) {
+						t.Errorf("%s%s[%d] is %v, want %v", path, key, i, others[i], value[i])
+					}
+				}
+			default:
+				if !reflect.DeepEqual(other, value) {
+					t.Errorf("%s%s is %v, want %v", path, key, other, value)
+				}
+			}
+		}
+	}
+}
+
+// gatewayControls are the control messages the gateway sends, one of each
+// type, every field set
+var gatewayControls = []any{
+	filled[ackMessage]("ack"),
+	filled[announcementMessage]("announcement"),
+	filled[chatMessage]("chat"),
+	filled[controlError]("error"),
+	filled[eotMessage]("eot"),
+	filled[floorMessage]("floor"),
+	filled[floorGrantMessage]("floor_denied"),
+	filled[floorGrantMessage]("floor_granted"),
+	filled[floorGrantMessage]("floor_revoked"),
+	filled[floorModeratedMessage]("floor_moderated"),
+	filled[floorOfferedMessage]("floor_offered"),
+	filled[floorQueuedMessage]("floor_queued"),
+	filled[heartbeatMessage]("hb"),
+	filled[helloMessage]("hello"),
+	filled[joinedMessage]("joined"),
+	filled[keyExchangeMessage]("keyx"),
+	filled[leftMessage]("left"),
+	filled[levelMessage]("level"),
+	filled[muteMessage]("muted"),
+	filled[muteMessage]("unmuted"),
+	filled[pongMessage]("pong"),
+	filled[presenceMessage]("presence"),
+	filled[qualityMessage]("quality"),
+	filled[rateLimitWarning]("rate_limited"),
+	filled[reconnectMessage]("reconnect"),
+	filled[replayMessage]("replay"),
+	filled[roomClosingMessage]("room_closing"),
+	filled[roomReopenedMessage]("room_reopened"),
+	filled[rosterMessage]("roster"),
+	filled[tagsMessage]("tags"),
+	filled[talkCutOffMessage]("transmission_cut_off"),
+	filled[talkMessage]("talk"),
+	filled[tierMessage]("tier"),
+	filled[whisperMessage]("whisper"),
+	filled[willMessage]("will"),
+}
+
+// clientControls are the control messages clients send, one of each type,
+// with the fields the gateway reads from it
+var clientControls = []string{
+	`{"type":"ack","id":"m1"}`,
+	`{"type":"block","target":"c2"}`,
+	`{"type":"chat","room":"r","text":"hi","echo":true,"target":"c2"}`,
+	`{"type":"filter","room":"r","mode":"only","senders":["c2","c3"]}`,
+	`{"type":"floor_cancel","room":"r"}`,
+	`{"type":"floor_lock","room":"r"}`,
+	`{"type":"floor_release","room":"r"}`,
+	`{"type":"floor_request","room":"r","priority":3}`,
+	`{"type":"floor_revoke","room":"r"}`,
+	`{"type":"floor_unlock","room":"r"}`,
+	`{"type":"group","room":"r","tags":["a","b"]}`,
+	`{"type":"hand_grant","target":"c2","room":"r"}`,
+	`{"type":"hand_lower","room":"r"}`,
+	`{"type":"hand_raise","room":"r"}`,
+	`{"type":"hands_clear","room":"r"}`,
+	`{"type":"hb","seq":9}`,
+	`{"type":"hello","version":2}`,
+	`{"type":"join","room":"r","join_token":"tok","format":{"codec":"opus","sample_rate":48000,"channels":1,"frame_ms":20},"tags":["a"]}`,
+	`{"type":"keyx","room":"r","target":"c2","payload":{"k":"v"},"id":"m1","ack":true}`,
+	`{"type":"kick","target":"c2","room":"r"}`,
+	`{"type":"leave","room":"r"}`,
+	`{"type":"mute","target":"c2","room":"r"}`,
+	`{"type":"ping","nonce":"n1","t":1700000000000,"rtt":12.5}`,
+	`{"type":"roster","room":"r"}`,
+	`{"type":"tier","room":"r","tier":"low"}`,
+	`{"type":"unblock","target":"c2"}`,
+	`{"type":"unmute","target":"c2","room":"r"}`,
+	`{"type":"whisper","room":"r","target":"c2"}`,
+}
+
+// envelopeFor decodes what controlToPB made of a control message, failing
+// if it fell back to carrying the JSON
+func envelopeFor(t *testing.T, data []byte) (*pb.Envelope, protoreflect.FieldDescriptor) {
+	t.Helper()
+	encoded, err := controlToPB(data)
+	if err != nil {
+		t.Fatalf("controlToPB: %v", err)
+	}
+	var envelope pb.Envelope
+	if err := proto.Unmarshal(encoded, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	field := envelope.ProtoReflect().WhichOneof(envelopeMessage)
+	if field == nil || field == envelopeJSON {
+		t.Fatalf("%s went as JSON", data)
+	}
+	return &envelope, field
+}
+
+// Every control message the gateway sends reaches a walkie.pb.v1 client as
+// a message of its own carrying each of its JSON keys, with their values
+func TestGatewayControlsToPB(t *testing.T) {
+	marshal := protojson.MarshalOptions{UseProtoNames: true}
+	for _, message := range gatewayControls {
+		data, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := decodeJSON(t, data)
+		t.Run(want["type"].(string), func(t *testing.T) {
+			envelope, field := envelopeFor(t, data)
+			translated, err := marshal.Marshal(envelope.ProtoReflect().Get(field).Message().Interface())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := decodeJSON(t, translated)
+			got["type"] = string(field.Name())
+			sameKeys(t, "", got, want)
+		})
+	}
+}
+
+// Every control message a walkie.pb.v1 client sends reaches the gateway
+// with each of the keys the JSON would carry, with their values
+func TestClientControlsFromPB(t *testing.T) {
+	for _, data := range clientControls {
+		want := decodeJSON(t, []byte(data))
+		t.Run(want["type"].(string), func(t *testing.T) {
+			envelope, _ := envelopeFor(t, []byte(data))
+			message, err := controlFromPB(envelope)
+			if err != nil {
+				t.Fatalf("controlFromPB: %v", err)
+			}
+			translated, err := json.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameKeys(t, "", decodeJSON(t, translated), want)
+		})
+	}
+}
+
+// Every message the Envelope has a field for is among those translated
+func TestEnvelopeFieldsCovered(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, message := range gatewayControls {
+		covered[reflect.ValueOf(message).FieldByName("Type").String()] = true
+	}
+	for _, data := range clientControls {
+		var head struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(data), &head)
+		covered[head.Type] = true
+	}
+	fields := envelopeMessage.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if name := string(field.Name()); field != envelopeJSON && name != "audio" && !covered[name] {
+			t.Errorf("no %s message translated", name)
+		}
+	}
+}
+
+// BenchmarkRosterSize translates a 50 client roster snapshot to walkie.pb.v1,
+// reporting its size as JSON and as an Envelope
+func BenchmarkRosterSize(b *testing.B) {
+	roster := rosterMessage{Type: "roster", Room: defaultRoom, Version: 812}
+	for i := 0; i < 50; i++ {
+		roster.Clients = append(roster.Clients, rosterClient{
+			ID:    fmt.Sprintf("unit-%03d", i),
+			Name:  fmt.Sprintf("Unit %d", i),
+			Role:  "member",
+			Tags:  []string{"patrol", "north"},
+			Muted: i%10 == 0,
+			Since: 1700000000000 + int64(i)*1000,
+		})
+	}
+	data, err := json.Marshal(roster)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var encoded []byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if encoded, err = controlToPB(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data)), "json-bytes")
+	b.ReportMetric(float64(len(encoded)), "pb-bytes")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pingRate and pingBurst are how many pings a second a connection may
+// send, sustained and at once. The rest are refused.
+const (
+	pingRate  = 4
+	pingBurst = 4
+)
+
+// pongMessage answers a client's ping at once, ahead of anything queued for
+// the client, echoing its nonce and clock with the server's clock in Unix
+// milliseconds. Queued is how many messages the pong overtook, which would
+// otherwise have delayed it.
+type pongMessage struct {
+	Type    string          `json:"type"`
+	Nonce   json.RawMessage `json:"nonce,omitempty"`
+	T       int64           `json:"t"`
+	ServerT int64           `json:"server_t"`
+	Queued  int             `json:"queued"`
+}
+
+// ping carries a nonce and the client's clock, both echoed, and the round
+// trip the client measured from its last pong
+func init() {
+	registerControl("ping", controlHandler{
+		handle: func(c *Client, m controlMessage) { c.pong(m) },
+	})
+}
+
+// pong answers a ping from the client's connection, and records the round
+// trip it reports. Only readPump may call it.
+func (c *Client) pong(message controlMessage) {
+	now := time.Now()
+	if message.RTT > 0 {
+		c.clientRTT.record(time.Duration(message.RTT*float64(time.Millisecond)), now)
+	}
+
+	if c.pings.last.IsZero() {
+		c.pings = tokenBucket{tokens: pingBurst, last: now}
+	}
+	c.pings.refill(pingRate, pingBurst, now)
+	if c.pings.tokens < 1 {
+		c.fail("rate_limited", "ping", "")
+		return
+	}
+	c.pings.tokens--
+
+	data, err := json.Marshal(pongMessage{
+		Type:    "pong",
+		Nonce:   message.Nonce,
+		T:       message.T,
+		ServerT: now.UnixMilli(),
+		Queued:  len(c.send),
+	})
+	if err != nil {
+		log.Printf("Error encoding pong for client %s: %v", c.id, err)
+		return
+	}
+	// The priority queue holds a burst of pongs, so it is only full if
+	// writePump is stuck
+	select {
+	case c.priority <- outbound{messageType: websocket.TextMessage, data: data}:
+	default:
+		c.droppedOutbound.Add(1)
+	}
+}
+
+// roundTrip returns the client's round trip: the one it reports from its
+// pings if it does, or else the one measured by heartbeats
+func (c *Client) roundTrip(now time.Time) time.Duration {
+	if avg, _ := c.clientRTT.read(now); avg > 0 {
+		return avg
+	}
+	return time.Duration(c.heartbeatRTT.Load())
+}
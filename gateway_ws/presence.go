@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// Every client in a room is told when another joins or leaves it with a
+// presence message, and a joining client is told who is already there in
+// its joined message, then of its own join. Joins and leaves are announced
+// in the order the room's membership changes, so a client never hears of a
+// leave by a client it wasn't told of. A connection may opt out with the
+// presence query parameter.
+const (
+	presenceJoin  = "join"
+	presenceLeave = "leave"
+
+	// A moderator or the admin API muted or unmuted the client
+	presenceMuted   = "muted"
+	presenceUnmuted = "unmuted"
+
+	// maxDisplayName caps a client's display name, in bytes
+	maxDisplayName = 64
+)
+
+// Why a client left, as presence messages give it
+const (
+	leftClean        = "clean"
+	leftTimeout      = "timeout"
+	leftKicked       = "kicked"
+	leftSlowConsumer = "slow-consumer"
+	leftLost         = "lost"
+	leftClosed       = "closed"
+)
+
+// presenceMessage tells a room a client joined or left it
+type presenceMessage struct {
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Room  string `json:"room"`
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Role  string `json:"role"`
+
+	// Why a client left, when a raised hand was raised in Unix
+	// milliseconds, whether the client is muted, and the roster version the
+	// event makes
+	Reason       string `json:"reason,omitempty"`
+	HandRaisedAt int64  `json:"hand_raised_at,omitempty"`
+	Muted        bool   `json:"muted,omitempty"`
+	Version      uint64 `json:"version"`
+}
+
+// presentClient is a client in the room as the joined message lists it
+type presentClient struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Role         string `json:"role"`
+	HandRaisedAt int64  `json:"hand_raised_at,omitempty"`
+	Muted        bool   `json:"muted,omitempty"`
+}
+
+// presenceReason returns why a client disconnected for the reason left, as
+// presence messages give it
+func presenceReason(reason closeReason) string {
+	switch reason {
+	case reasonNormal, reasonSwitched, reasonLeft, reasonMaxAge, reasonTakenOver:
+		return leftClean
+	case reasonPingTimeout, reasonHeartbeatTimeout, reasonWriteTimeout, reasonIdle:
+		return leftTimeout
+	case reasonSlowConsumer:
+		return leftSlowConsumer
+	case reasonShutdown, reasonRoomClosed:
+		return leftClosed
+	case reasonLost, reasonInternal, reasonUpgradeFailed:
+		return leftLost
+	}
+	// Kicks, bans and every policy violation
+	return leftKicked
+}
+
+// requestedPresence reports whether the client wants presence messages: it
+// may turn them off with the presence query parameter
+func requestedPresence(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("presence")
+	if value == "" {
+		return true, nil
+	}
+	presence, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid presence value %q", value)
+	}
+	return presence, nil
+}
+
+// requestedName returns the display name the client gives in the name query
+// parameter, if any
+func requestedName(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("name")
+	if !validDisplayName(name) {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	return name, nil
+}
+
+// validDisplayName reports whether name, which may be empty, is printable
+// UTF-8 of at most maxDisplayName bytes
+func validDisplayName(name string) bool {
+	if len(name) > maxDisplayName || !utf8.ValidString(name) {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// presentClients returns every client registered in the hub but except. The
+// caller must hold the presence mutex.
+func (h *Hub) presentClients(except *Client) []presentClient {
+	var present []presentClient
+	for _, client := range h.snapshotClients() {
+		if client != except {
+			present = append(present, presentClient{ID: client.id, Name: client.name, Role: client.role(), HandRaisedAt: client.hand.Load(), Muted: client.muted.Load()})
+		}
+	}
+	return present
+}
+
+// announcePresence tells every client in the hub that wants to know that
+// client joined or left, raised or lowered its hand, or was muted or
+// unmuted. The caller must hold the presence mutex.
+func (h *Hub) announcePresence(client *Client, event string, reason closeReason) {
+	h.rosterVersion++
+	message := presenceMessage{Type: "presence", Event: event, Room: localRoom(h.room), ID: client.id, Name: client.name, Role: client.role(), Muted: client.muted.Load(), Version: h.rosterVersion}
+	switch event {
+	case presenceLeave:
+		message.Reason = presenceReason(reason)
+	case presenceHandRaised:
+		message.HandRaisedAt = client.hand.Load()
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding presence of client %s: %v", client.id, err)
+		return
+	}
+	out := outbound{messageType: websocket.TextMessage, data: data}
+	for _, other := range h.snapshotClients() {
+		if other.presence {
+			h.shardFor(other).send(h, other, out)
+		}
+	}
+	h.presenceEvents.Add(1)
+}
@@ -0,0 +1,60 @@
+package main
+
+// Emergency traffic has to reach listeners whose send buffers are full of
+// routine audio. A moderator marks an audio frame priority with its
+// envelope's priority field, on walkie.json.v1 and walkie.pb.v1, and the
+// fan-out queues it on each listener's urgent queue, which writePump empties
+// ahead of the send buffer. No slow consumer policy drops a priority frame
+// until the urgent queue holds maxUrgentFrames; past that it is queued like
+// any other. A frame marked priority by a client that isn't a moderator is
+// relayed as routine audio, and counted.
+const (
+	// maxUrgentFrames caps each client's urgent queue
+	maxUrgentFrames = 32
+)
+
+// urgent reports whether the message is a priority audio frame, and live:
+// a replayed one keeps its place among the rest
+func (m outbound) urgent() bool {
+	return m.frame != nil && m.frame.header.priority && !m.replayed
+}
+
+// checkPriority strips the priority mark off an audio frame from the client
+// if its role doesn't allow it, counting it. Only the readPump of the
+// client's connection may call it.
+func (c *Client) checkPriority(frame *frameBuffer) {
+	if !frame.header.priority {
+		return
+	}
+	if !c.moderator.Load() {
+		frame.header.priority = false
+		c.priorityStripped.Add(1)
+		c.hub.priorityStripped.Add(1)
+		return
+	}
+	c.hub.priorityFrames.Add(1)
+}
+
+// queueUrgent queues a priority message on the client's urgent queue, and
+// reports false if it is full
+func (c *Client) queueUrgent(message outbound) bool {
+	select {
+	case c.urgent <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// discardUrgent releases every message on the client's urgent queue. Only
+// once the client's send channel is closed is nothing more queued on it.
+func (c *Client) discardUrgent() {
+	for {
+		select {
+		case message := <-c.urgent:
+			message.release()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A moderator's priority frame reaches a listener ahead of a 200 frame
+// backlog of routine audio, behind only what was already on its way; one
+// marked priority by anyone else loses the mark and waits its turn
+func TestPriorityBypassesBacklog(t *testing.T) {
+	const (
+		routine = 250
+		backlog = 200
+	)
+	tg := newStallingGateway(t, []HubOption{WithSendBuffer(256, 256)}, WithModeratorSecrets(map[string]string{defaultRoom: "mod"}))
+	talker, _ := tg.dial(t, "/ws", "talker")
+	readControl(t, talker, "joined")
+	header := http.Header{"Sec-WebSocket-Protocol": {protocolJSON}}
+	moderator, _, err := tg.tryDial("/ws?join_token=mod", "moderator", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer moderator.Close()
+	readControl(t, moderator, "joined")
+	pretender, _, err := tg.tryDial("/ws", "pretender", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pretender.Close()
+	readControl(t, pretender, "joined")
+	// The listener doesn't read until the backlog has built up
+	listener, _ := tg.dial(t, "/ws", "listener")
+	hub := tg.hub(t, defaultRoom)
+	waitFor(t, "the listener to join", func() bool { return hub.ClientCount() == 4 })
+	go drain(talker, nil, nil)
+	go drain(moderator, nil, nil)
+	go drain(pretender, nil, nil)
+
+	payload := make([]byte, 16<<10)
+	for i := 0; i < routine; i++ {
+		if err := talker.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := hub.lookup("listener")
+	// Built once it stops growing
+	queued := -1
+	waitFor(t, "the listener's backlog to build", func() bool {
+		n := len(client.send)
+		settled := n == queued
+		queued = n
+		return settled && n >= backlog
+	})
+	// Frames written to the socket before the backlog stalled it, and at
+	// most one batch writePump took from the send buffer before the
+	// priority frame was there for it to take
+	ahead := routine - queued + maxCoalescedFrames
+
+	marked := func(marker byte) map[string]any {
+		audio := make([]byte, 16<<10)
+		audio[0] = marker
+		return map[string]any{"type": "audio", "data": audio, "priority": true}
+	}
+	if err := pretender.WriteJSON(marked(0xfe)); err != nil {
+		t.Fatal(err)
+	}
+	if err := moderator.WriteJSON(marked(0xff)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the priority frame to be queued", func() bool { return len(client.urgent) == 1 })
+	if stats := hub.Stats(); stats.PriorityFrames != 1 || stats.PriorityStripped != 1 {
+		t.Errorf("%d priority frames and %d marks stripped, want 1 and 1", stats.PriorityFrames, stats.PriorityStripped)
+	}
+
+	priorityAt, strippedAt := -1, -1
+	for received := 0; received < routine+2; received++ {
+		listener.SetReadDeadline(time.Now().Add(testTimeout))
+		data := readAudio(t, listener)
+		switch data[0] {
+		case 0xff:
+			priorityAt = received
+		case 0xfe:
+			strippedAt = received
+		}
+	}
+	if priorityAt < 0 || priorityAt > ahead {
+		t.Errorf("priority frame received after %d frames, want at most %d", priorityAt, ahead)
+	}
+	if strippedAt < routine {
+		t.Errorf("stripped frame received after %d frames, want after the %d routine frames", strippedAt, routine)
+	}
+}
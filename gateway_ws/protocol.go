@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Subprotocols negotiated with Sec-WebSocket-Protocol, in the server's order
+// of preference
+const (
+	// protocolRaw carries audio as binary frames and control messages as
+	// JSON text frames
+	protocolRaw = "walkie.raw.v1"
+
+	// protocolJSON carries everything as JSON text frames, audio as
+	// {"type":"audio","data":"<base64>"}
+	protocolJSON = "walkie.json.v1"
+)
+
+var subprotocols = []string{protocolFramed, protocolRaw, protocolJSON, protocolPB}
+
+// errUnsupportedData is returned for a frame the client's subprotocol
+// doesn't allow
+var errUnsupportedData = errors.New("frame type not allowed by subprotocol")
+
+// isSubprotocol reports whether name is a subprotocol the server speaks
+func isSubprotocol(name string) bool {
+	for _, p := range subprotocols {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithDefaultSubprotocol sets the subprotocol assumed for clients that offer
+// none the server speaks. An empty name refuses them with 426.
+func WithDefaultSubprotocol(name string) GatewayOption {
+	return func(g *Gateway) {
+		g.defaultProtocol = name
+	}
+}
+
+// negotiateSubprotocol picks the subprotocol for the request the same way
+// the upgrader does: the server's most preferred one that the client offers,
+// or else the default. It reports false if there is no default either.
+func (g *Gateway) negotiateSubprotocol(r *http.Request) (string, bool) {
+	requested := websocket.Subprotocols(r)
+	for _, p := range g.upgrader.Subprotocols {
+		for _, q := range requested {
+			if p == q {
+				return p, true
+			}
+		}
+	}
+	return g.defaultProtocol, g.defaultProtocol != ""
+}
+
+// refuseSubprotocol answers a client that offered no usable subprotocol
+func (g *Gateway) refuseSubprotocol(w http.ResponseWriter) {
+	w.Header().Set("Sec-WebSocket-Protocol", strings.Join(g.upgrader.Subprotocols, ", "))
+	http.Error(w, "supported subprotocols: "+strings.Join(g.upgrader.Subprotocols, ", "), http.StatusUpgradeRequired)
+}
+
+// audioEnvelope is how protocolJSON carries audio and control messages.
+// Room tags a multi-room client's audio.
+type audioEnvelope struct {
+	Type string `json:"type"`
+	Seq  uint64 `json:"seq,omitempty"`
+	Room string `json:"room,omitempty"`
+	Data []byte `json:"data,omitempty"`
+
+	// When the gateway received the audio, and when its sender captured
+	// it if it said, in Unix milliseconds
+	Received int64 `json:"received,omitempty"`
+	Captured int64 `json:"captured,omitempty"`
+
+	// Set on a whisper, with its sender
+	Private bool   `json:"private,omitempty"`
+	From    string `json:"from,omitempty"`
+
+	// Set on emergency traffic, which goes ahead of routine audio
+	Priority bool `json:"priority,omitempty"`
+
+	// The audio's RMS level as a share of full scale, which a sender keyed
+	// by VOX may declare
+	Level float32 `json:"level,omitempty"`
+
+	// Set on audio replayed to a resumed or late-joining client rather
+	// than live
+	Replay bool `json:"replay,omitempty"`
+}
+
+// envelope wraps the frame's audio for protocolJSON, tagged with room for a
+// multi-room client
+func (f *frameBuffer) envelope(room string) audioEnvelope {
+	envelope := audioEnvelope{Type: "audio", Room: room, Data: f.data, Received: f.header.received, Captured: f.header.captured, Priority: f.header.priority}
+	if f.header.private {
+		envelope.Private, envelope.From = true, f.header.sender
+	}
+	return envelope
+}
+
+// inbound decides what to do with a frame read from the client. It reports
+// true if the frame is audio to broadcast, now holding the raw audio bytes,
+// along with the room a multi-room client tagged it for; otherwise any
+// control message in it has been handled and the caller releases it.
+func (c *Client) inbound(frame *frameBuffer) (string, bool, error) {
+	switch {
+	case c.protocol == protocolPB:
+		return c.inboundPB(frame)
+	case c.protocol != protocolJSON:
+		if frame.messageType == websocket.TextMessage {
+			return "", false, c.handleControl(frame.data)
+		}
+		if c.protocol == protocolFramed {
+			if err := c.unframe(frame); err != nil {
+				return "", false, err
+			}
+		}
+		if c.multiRoom {
+			room, err := untag(frame)
+			return room, err == nil, err
+		}
+		return "", true, nil
+
+	case frame.messageType != websocket.TextMessage:
+		return "", false, errUnsupportedData
+	}
+
+	// A frame that doesn't parse is a malformed control message, not a
+	// reason to close
+	var envelope audioEnvelope
+	if err := json.Unmarshal(frame.data, &envelope); err != nil || envelope.Type != "audio" {
+		return "", false, c.handleControl(frame.data)
+	}
+	frame.data = append(frame.data[:0], envelope.Data...)
+	frame.header.seq = envelope.Seq
+	frame.header.captured = envelope.Captured
+	frame.header.priority = envelope.Priority
+	frame.header.level = envelope.Level
+	frame.messageType = websocket.BinaryMessage
+	return envelope.Room, true, nil
+}
+
+// jsonMessage returns the frame as a protocolJSON PreparedMessage, framing
+// it on first use. Audio is wrapped in an envelope; text frames are already
+// JSON.
+func (f *frameBuffer) jsonMessage() (*websocket.PreparedMessage, error) {
+	if f.messageType == websocket.TextMessage {
+		return f.preparedMessage()
+	}
+	f.jsonOnce.Do(func() {
+		var data []byte
+		data, f.jsonErr = json.Marshal(f.envelope(""))
+		if f.jsonErr == nil {
+			f.jsonPrepared, f.jsonErr = websocket.NewPreparedMessage(websocket.TextMessage, data)
+		}
+	})
+	return f.jsonPrepared, f.jsonErr
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+)
+
+// Rate limit policies
+const (
+	// rateLimitDrop drops the frames over the limit (default)
+	rateLimitDrop = "drop"
+	// rateLimitDisconnect drops them too, and closes a client that keeps
+	// going over its limit
+	rateLimitDisconnect = "disconnect"
+)
+
+const (
+	// rateLimitWarnInterval is the least time between the warnings a client
+	// over its limit gets. Each interval it goes over in is a violation.
+	rateLimitWarnInterval = 5 * time.Second
+
+	// defaultRateLimitViolations is how many violations the disconnect
+	// policy tolerates, one after the other
+	defaultRateLimitViolations = 3
+)
+
+// RateLimit caps the audio a client sends into a room, as token buckets that
+// refill at a sustained rate up to a burst. A zero rate is unlimited, and a
+// zero burst is a second's worth.
+type RateLimit struct {
+	MessagesPerSecond float64 `json:"messages_per_second,omitempty"`
+	MessageBurst      int     `json:"message_burst,omitempty"`
+	BytesPerSecond    float64 `json:"bytes_per_second,omitempty"`
+	ByteBurst         int     `json:"byte_burst,omitempty"`
+
+	// drop or disconnect, and for disconnect how many violations in a row
+	// close the client
+	Policy     string `json:"policy,omitempty"`
+	Violations int    `json:"violations,omitempty"`
+}
+
+// limited reports whether the limit caps anything
+func (l RateLimit) limited() bool {
+	return l.MessagesPerSecond > 0 || l.BytesPerSecond > 0
+}
+
+// validate checks a limit given on the command line or to the room admin API
+func (l RateLimit) validate() error {
+	switch {
+	case l.MessagesPerSecond < 0 || l.BytesPerSecond < 0:
+		return errors.New("rate limits must not be negative")
+	case l.MessageBurst < 0 || l.ByteBurst < 0:
+		return errors.New("rate limit bursts must not be negative")
+	case l.Policy != "" && l.Policy != rateLimitDrop && l.Policy != rateLimitDisconnect:
+		return errors.New("rate limit policy must be drop or disconnect")
+	case l.Violations < 0:
+		return errors.New("rate limit violations must not be negative")
+	}
+	return nil
+}
+
+// violations returns how many violations in a row close a client under the
+// disconnect policy
+func (l RateLimit) violations() int {
+	if l.Violations > 0 {
+		return l.Violations
+	}
+	return defaultRateLimitViolations
+}
+
+// burst returns a bucket's size: the burst, or a second's worth at rate
+func burst(rate float64, burst int) float64 {
+	if burst > 0 {
+		return float64(burst)
+	}
+	return math.Max(1, math.Ceil(rate))
+}
+
+// tokenBucket holds the tokens a client has left to spend
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned at rate since the last refill, up to size
+func (b *tokenBucket) refill(rate, size float64, now time.Time) {
+	b.tokens = math.Min(size, b.tokens+rate*now.Sub(b.last).Seconds())
+	b.last = now
+}
+
+// rateLimiter is a client's buckets under its room's limit, and its record
+// of going over it. Only the readPump of the client's connection touches it.
+type rateLimiter struct {
+	limit    *RateLimit
+	messages tokenBucket
+	bytes    tokenBucket
+
+	// Frames dropped since the last warning, when it was sent, and the
+	// violations in a row up to it
+	dropped    uint64
+	warned     time.Time
+	violations int
+}
+
+// Outcomes of checking a frame against the limit
+type rateVerdict int
+
+const (
+	rateAllowed rateVerdict = iota
+	rateDropped
+	// Dropped, and the sender is warned
+	rateWarned
+	// Dropped, and the sender is closed
+	rateExceeded
+)
+
+// check spends a frame of size bytes from the buckets, or returns why it
+// can't. A new limit starts with full buckets and a clean record.
+func (l *rateLimiter) check(limit *RateLimit, size int, now time.Time) rateVerdict {
+	if limit == nil {
+		return rateAllowed
+	}
+	messageBurst := burst(limit.MessagesPerSecond, limit.MessageBurst)
+	byteBurst := burst(limit.BytesPerSecond, limit.ByteBurst)
+	if l.limit != limit {
+		*l = rateLimiter{
+			limit:    limit,
+			messages: tokenBucket{tokens: messageBurst, last: now},
+			bytes:    tokenBucket{tokens: byteBurst, last: now},
+		}
+	}
+	l.messages.refill(limit.MessagesPerSecond, messageBurst, now)
+	l.bytes.refill(limit.BytesPerSecond, byteBurst, now)
+
+	// A frame larger than the byte burst passes on a full bucket, leaving
+	// it in debt, so it is late rather than never sent
+	messagesOK := limit.MessagesPerSecond <= 0 || l.messages.tokens >= 1
+	bytesOK := limit.BytesPerSecond <= 0 || l.bytes.tokens >= math.Min(float64(size), byteBurst)
+	if messagesOK && bytesOK {
+		l.messages.tokens--
+		l.bytes.tokens -= float64(size)
+		return rateAllowed
+	}
+
+	l.dropped++
+	if now.Sub(l.warned) < rateLimitWarnInterval {
+		return rateDropped
+	}
+	// Violations count one after the other unless a whole interval passed
+	// without any
+	if now.Sub(l.warned) > 2*rateLimitWarnInterval {
+		l.violations = 0
+	}
+	l.violations++
+	l.warned = now
+	if limit.Policy == rateLimitDisconnect && l.violations >= limit.violations() {
+		return rateExceeded
+	}
+	return rateWarned
+}
+
+// rateLimitWarning tells a client its frames are being dropped for going
+// over its room's rate limit
+type rateLimitWarning struct {
+	Type              string  `json:"type"`
+	Room              string  `json:"room"`
+	Dropped           uint64  `json:"dropped"`
+	MessagesPerSecond float64 `json:"messages_per_second,omitempty"`
+	BytesPerSecond    float64 `json:"bytes_per_second,omitempty"`
+	Policy            string  `json:"policy"`
+}
+
+// rateLimit checks an audio frame of size bytes the client is sending to
+// its room against the room's rate limit, dropping and warning as the
+// limit's policy says. It reports whether the frame may be broadcast, and
+// whether the connection must close.
+func (c *Client) rateLimit(size int) (allowed, exceeded bool) {
+	limit := c.hub.rateLimit.Load()
+	verdict := c.limiter.check(limit, size, time.Now())
+	if verdict == rateAllowed {
+		return true, false
+	}
+	c.rateLimited.Add(1)
+	c.hub.rateLimited.Add(1)
+	if verdict == rateDropped {
+		return false, false
+	}
+
+	dropped := c.limiter.dropped
+	c.limiter.dropped = 0
+	if verdict == rateExceeded {
+		c.hub.rateLimitDisconnects.Add(1)
+		log.Printf("Client %s closed after %d rate limit violations in room %s", c.id, c.limiter.violations, c.hub.room)
+		return false, true
+	}
+	log.Printf("Client %s over its rate limit in room %s, dropped %d frames", c.id, c.hub.room, dropped)
+	policy := limit.Policy
+	if policy == "" {
+		policy = rateLimitDrop
+	}
+	c.hub.sendControl(c, rateLimitWarning{
+		Type:              "rate_limited",
+		Room:              localRoom(c.hub.room),
+		Dropped:           dropped,
+		MessagesPerSecond: limit.MessagesPerSecond,
+		BytesPerSecond:    limit.BytesPerSecond,
+		Policy:            policy,
+	})
+	return false, false
+}
+
+// WithRateLimit caps the audio each client sends into the hub's room. The
+// room admin API may change it while the hub runs.
+func WithRateLimit(limit RateLimit) HubOption {
+	return func(h *Hub) {
+		h.setRateLimit(limit)
+	}
+}
+
+// setRateLimit replaces the room's rate limit. Clients start over under the
+// new one.
+func (h *Hub) setRateLimit(limit RateLimit) {
+	current := h.rateLimit.Load()
+	switch {
+	case !limit.limited():
+		h.rateLimit.Store(nil)
+	case current == nil || *current != limit:
+		h.rateLimit.Store(&limit)
+	}
+}
+
+// WithRoomRateLimit caps the audio each client sends into a room, unless its
+// config sets a limit of its own
+func WithRoomRateLimit(limit RateLimit) GatewayOption {
+	return func(g *Gateway) {
+		g.rateLimit = limit
+	}
+}
+
+// rateLimitFor returns the named room's rate limit. The caller must hold
+// the gateway's mutex.
+func (g *Gateway) rateLimitFor(name string) RateLimit {
+	if limit := g.roomConfigs[name].RateLimit; limit != nil {
+		return *limit
+	}
+	return g.rateLimit
+}
+
+// updateRateLimit brings an open room's rate limit in line with its config.
+// The caller must hold the mutex.
+func (g *Gateway) updateRateLimit(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.setRateLimit(g.rateLimitFor(name))
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRateLimiterCheck(t *testing.T) {
+	// frames of size bytes, interval apart, starting after a pause
+	type burstOf struct {
+		after    time.Duration
+		frames   int
+		interval time.Duration
+		size     int
+	}
+	tests := []struct {
+		name     string
+		limit    RateLimit
+		bursts   []burstOf
+		verdicts map[rateVerdict]int
+	}{
+		{"well behaved", RateLimit{MessagesPerSecond: 50}, []burstOf{{0, 500, 20 * time.Millisecond, 640}},
+			map[rateVerdict]int{rateAllowed: 500}},
+		{"over the burst", RateLimit{MessagesPerSecond: 10, MessageBurst: 5}, []burstOf{{0, 20, 0, 640}},
+			map[rateVerdict]int{rateAllowed: 5, rateWarned: 1, rateDropped: 14}},
+		{"over the byte burst", RateLimit{BytesPerSecond: 1000, ByteBurst: 1000}, []burstOf{{0, 5, 0, 400}},
+			map[rateVerdict]int{rateAllowed: 2, rateWarned: 1, rateDropped: 2}},
+		{"frame larger than the byte burst", RateLimit{BytesPerSecond: 1000, ByteBurst: 1000}, []burstOf{{0, 2, 0, 4000}},
+			map[rateVerdict]int{rateAllowed: 1, rateWarned: 1}},
+		// Warned once each interval it stays over, for 12 seconds
+		{"flooding under drop", RateLimit{MessagesPerSecond: 10, MessageBurst: 10}, []burstOf{{0, 1200, 10 * time.Millisecond, 640}},
+			map[rateVerdict]int{rateAllowed: 129, rateWarned: 3, rateDropped: 1068}},
+		// Closed at the third warning, ten seconds in
+		{"flooding under disconnect", RateLimit{MessagesPerSecond: 10, MessageBurst: 10, Policy: rateLimitDisconnect}, []burstOf{{0, 1200, 10 * time.Millisecond, 640}},
+			map[rateVerdict]int{rateAllowed: 110, rateWarned: 2, rateExceeded: 1, rateDropped: 898}},
+		{"violations in a row", RateLimit{MessagesPerSecond: 10, MessageBurst: 1, Policy: rateLimitDisconnect},
+			[]burstOf{{0, 2, 0, 640}, {rateLimitWarnInterval, 2, 0, 640}, {rateLimitWarnInterval, 2, 0, 640}},
+			map[rateVerdict]int{rateAllowed: 3, rateWarned: 2, rateExceeded: 1}},
+		// Two violations, then a quiet spell long enough to start over
+		{"forgiven after a quiet spell", RateLimit{MessagesPerSecond: 10, MessageBurst: 1, Policy: rateLimitDisconnect},
+			[]burstOf{{0, 2, 0, 640}, {rateLimitWarnInterval, 2, 0, 640}, {3 * rateLimitWarnInterval, 2, 0, 640}, {rateLimitWarnInterval, 2, 0, 640}},
+			map[rateVerdict]int{rateAllowed: 4, rateWarned: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l rateLimiter
+			limit := tt.limit
+			now := time.Now()
+			got := map[rateVerdict]int{}
+			for _, b := range tt.bursts {
+				now = now.Add(b.after)
+				for i := 0; i < b.frames; i++ {
+					if i > 0 {
+						now = now.Add(b.interval)
+					}
+					verdict := l.check(&limit, b.size, now)
+					got[verdict]++
+					if verdict == rateExceeded {
+						break
+					}
+				}
+			}
+			for _, verdict := range []rateVerdict{rateAllowed, rateDropped, rateWarned, rateExceeded} {
+				if got[verdict] != tt.verdicts[verdict] {
+					t.Errorf("verdicts %v, want %v", got, tt.verdicts)
+					break
+				}
+			}
+		})
+	}
+}
+
+// A client flooding its room has its excess dropped with a warning, or is
+// closed under the disconnect policy, while a client keeping to the limit
+// has every frame through
+func TestRateLimitPolicies(t *testing.T) {
+	tests := []struct {
+		policy     string
+		disconnect bool
+	}{
+		{rateLimitDrop, false},
+		{rateLimitDisconnect, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			limit := RateLimit{MessagesPerSecond: 10, MessageBurst: 5, Policy: tt.policy, Violations: 1}
+			tg := newTestGateway(t, nil, WithRoomRateLimit(limit))
+			listener, _ := tg.dial(t, "/ws", "listener")
+			readControl(t, listener, "joined")
+			flooder, _ := tg.dial(t, "/ws", "flooder")
+			readControl(t, flooder, "joined")
+			polite, _ := tg.dial(t, "/ws", "polite")
+			readControl(t, polite, "joined")
+
+			for i := 0; i < 20; i++ {
+				if err := flooder.WriteMessage(websocket.BinaryMessage, pcmFrame(1)); err != nil {
+					break
+				}
+			}
+			if tt.disconnect {
+				if code := expectClosed(t, flooder); code != websocket.ClosePolicyViolation {
+					t.Errorf("flooder closed with %d, want %d", code, websocket.ClosePolicyViolation)
+				}
+			} else {
+				warning := readControl(t, flooder, "rate_limited")
+				if warning["policy"] != rateLimitDrop || warning["dropped"].(float64) < 1 {
+					t.Errorf("warning %v", warning)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				time.Sleep(150 * time.Millisecond)
+				if err := polite.WriteMessage(websocket.BinaryMessage, pcmFrame(2)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			flooded, sent := 0, 0
+			for sent < 3 {
+				switch readAudio(t, listener)[0] {
+				case 1:
+					flooded++
+				case 2:
+					sent++
+				}
+			}
+			if flooded == 0 || flooded >= 20 {
+				t.Errorf("listener got %d of the flooder's 20 frames", flooded)
+			}
+			if stats := tg.clientStats(t, "polite"); stats.RateLimited != 0 {
+				t.Errorf("polite client had %d frames rate limited", stats.RateLimited)
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Recording defaults: files rotate at 64 MiB or an hour, whichever comes
+// first, and the recorder queues up to recordQueue frames for the disk
+const (
+	defaultRecordMaxBytes    = 64 << 20
+	defaultRecordMaxDuration = time.Hour
+	recordQueue              = 1024
+	recordBufferSize         = 64 << 10
+)
+
+// Recording file formats
+const (
+	// recordRaw keeps every frame with its timestamp and sender, see
+	// ReadRecording
+	recordRaw = "raw"
+
+	// recordWAV keeps just the audio, spliced together, for rooms carrying
+	// 16-bit PCM
+	recordWAV = "wav"
+
+	// recordAuto records rooms carrying 16-bit PCM as wav and the rest as
+	// raw
+	recordAuto = "auto"
+)
+
+// recordPartial follows the name of a recording file still being written
+const recordPartial = ".part"
+
+// recordMagic starts every raw recording: "WTREC", two zero bytes and the
+// format version
+var recordMagic = []byte("WTREC\x00\x00\x01")
+
+var (
+	errRecordingDisabled = errors.New("recording is disabled; start the gateway with -record-dir")
+	errNotRawRecording   = errors.New("not a raw recording")
+)
+
+// recordSettings says where and how rooms are recorded. An empty dir
+// disables recording.
+type recordSettings struct {
+	dir         string
+	format      string
+	gaps        string
+	maxBytes    int64
+	maxDuration time.Duration
+
+	// Where finished files go, if they are uploaded
+	uploader *uploader
+}
+
+// ParseRecordFormat checks a recording format name
+func ParseRecordFormat(name string) (string, error) {
+	switch name {
+	case recordRaw, recordWAV, recordAuto:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown recording format %q (want raw, wav or auto)", name)
+}
+
+// formatFor returns the format a room whose audio is in format is recorded
+// in, resolving auto
+func (s recordSettings) formatFor(format audioFormat) string {
+	if s.format != recordAuto {
+		return s.format
+	}
+	if strings.EqualFold(format.Codec, defaultCodec) {
+		return recordWAV
+	}
+	return recordRaw
+}
+
+// WithRecording lets rooms be recorded into files in dir, rotated once they
+// reach maxBytes or have been open for maxDuration, a wav file's gaps in
+// transmission being filled or split at as gaps says. Zero limits keep the
+// defaults. Which rooms are recorded is part of their config.
+func WithRecording(dir, format, gaps string, maxBytes int64, maxDuration time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		g.recording = recordSettings{
+			dir:         dir,
+			format:      format,
+			gaps:        gaps,
+			maxBytes:    defaultRecordMaxBytes,
+			maxDuration: defaultRecordMaxDuration,
+		}
+		if maxBytes > 0 {
+			g.recording.maxBytes = maxBytes
+		}
+		if maxDuration > 0 {
+			g.recording.maxDuration = maxDuration
+		}
+	}
+}
+
+// ParseRoomNames parses a comma-separated list of room names
+func ParseRoomNames(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !validRoomName(name) {
+			return nil, fmt.Errorf("invalid room name %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// WithRecordedRooms records the named rooms from the start
+func WithRecordedRooms(names []string) GatewayOption {
+	return func(g *Gateway) {
+		for _, name := range names {
+			g.configureRoom(name, func(config *RoomConfig) {
+				config.Record = true
+			})
+		}
+	}
+}
+
+// updateRecording starts or stops an open room's recorder to match its
+// config. The caller must hold the mutex.
+func (g *Gateway) updateRecording(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		// A stored config may ask for recording the gateway now runs without
+		on := g.roomConfigs[name].Record && g.recording.dir != ""
+		settings := g.recording
+		settings.uploader = g.uploader
+		rm.hub.setRecording(on, settings)
+	}
+}
+
+// recordStats counts what a hub's recorders have done, across restarts of
+// the recording
+type recordStats struct {
+	frames  atomic.Uint64
+	bytes   atomic.Uint64
+	files   atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// recordedFrame is a frame queued for the disk
+type recordedFrame struct {
+	at     time.Time
+	sender string
+	frame  *frameBuffer
+}
+
+// recorder appends a room's audio to rotating files. The hub loop hands it
+// frames without blocking; its own goroutine does the writing, and frames it
+// has no room for are dropped and counted.
+type recorder struct {
+	room     string
+	settings recordSettings
+	format   audioFormat
+	stats    *recordStats
+
+	// Guards closed, so a frame is never queued once frames is closed
+	mutex  sync.RWMutex
+	closed bool
+	frames chan recordedFrame
+
+	// Closed once the last file is finished
+	done chan struct{}
+
+	// The open file, owned by the writing goroutine, the name it takes once
+	// finished, and how many files the recorder has opened
+	file    *os.File
+	path    string
+	w       *bufio.Writer
+	size    int64
+	opened  time.Time
+	failing bool
+	seq     int
+}
+
+// newRecorder starts recording the room, whose audio is in format
+func newRecorder(room string, settings recordSettings, format audioFormat, stats *recordStats) *recorder {
+	settings.format = settings.formatFor(format)
+	r := &recorder{
+		room:     room,
+		settings: settings,
+		format:   format,
+		stats:    stats,
+		frames:   make(chan recordedFrame, recordQueue),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// record queues an audio frame for the disk, taking a reference on it, or
+// drops it if the disk is behind
+func (r *recorder) record(message BroadcastMessage, now time.Time) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.closed {
+		return
+	}
+	message.frame.retain()
+	select {
+	case r.frames <- recordedFrame{at: now, sender: message.sender.id, frame: message.frame}:
+	default:
+		message.frame.release()
+		r.stats.dropped.Add(1)
+	}
+}
+
+// close stops the recorder once what is queued has been written. done is
+// closed when the last file is finished.
+func (r *recorder) close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.closed {
+		r.closed = true
+		close(r.frames)
+	}
+}
+
+// run writes queued frames, flushing whenever the queue runs dry
+func (r *recorder) run() {
+	defer close(r.done)
+	for f := range r.frames {
+		r.write(f)
+		f.frame.release()
+		if len(r.frames) == 0 && r.w != nil {
+			if err := r.w.Flush(); err != nil {
+				r.failed(err)
+			}
+		}
+	}
+	r.finish()
+	log.Printf("Stopped recording room %s", r.room)
+}
+
+// write appends a frame to the current file, rotating first if it is due,
+// or for a wav file if a gap in transmission starts a new one
+func (r *recorder) write(f recordedFrame) {
+	if r.file == nil || r.size >= r.maxBytes() || f.at.Sub(r.opened) >= r.settings.maxDuration || !r.fillGap(f.at) {
+		r.finish()
+		if err := r.open(f.at); err != nil {
+			r.failed(err)
+			r.stats.dropped.Add(1)
+			return
+		}
+	}
+
+	var err error
+	n := len(f.frame.data)
+	if r.settings.format == recordWAV {
+		_, err = r.w.Write(f.frame.data)
+	} else {
+		sender := f.sender
+		if len(sender) > 0xffff {
+			sender = sender[:0xffff]
+		}
+		var header [8 + 2]byte
+		binary.BigEndian.PutUint64(header[:8], uint64(f.at.UnixNano()))
+		binary.BigEndian.PutUint16(header[8:], uint16(len(sender)))
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(n))
+		r.w.Write(header[:])
+		r.w.WriteString(sender)
+		r.w.Write(length[:])
+		_, err = r.w.Write(f.frame.data)
+		n += len(header) + len(sender) + len(length)
+	}
+	if err != nil {
+		r.failed(err)
+		r.stats.dropped.Add(1)
+		return
+	}
+	r.size += int64(n)
+	r.stats.frames.Add(1)
+	r.stats.bytes.Add(uint64(len(f.frame.data)))
+	r.failing = false
+}
+
+// open starts a new file named after the room, the time of its first frame
+// and its place among the recorder's files. It is written under that name
+// with recordPartial after it until it is finished.
+func (r *recorder) open(at time.Time) error {
+	ext := ".wtrec"
+	if r.settings.format == recordWAV {
+		ext = ".wav"
+	}
+	r.seq++
+	name := filepath.Join(r.settings.dir, fmt.Sprintf("%s-%s-%04d%s", r.room, at.UTC().Format("20060102T150405.000Z"), r.seq, ext))
+	file, err := os.OpenFile(name+recordPartial, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.path = name
+	r.w = bufio.NewWriterSize(file, recordBufferSize)
+	r.opened = at
+	if r.settings.format == recordWAV {
+		r.w.Write(wavHeader(r.format, 0))
+	} else {
+		r.w.Write(recordMagic)
+	}
+	r.size = 0
+	r.stats.files.Add(1)
+	log.Printf("Recording room %s to %s", r.room, name)
+	return nil
+}
+
+// finish flushes and closes the current file, filling in a WAV file's
+// sizes now they are known, and gives it its finished name, handing it to
+// the uploader if there is one. A file that fails to finish keeps its
+// partial name for the repair at the next startup.
+func (r *recorder) finish() {
+	if r.file == nil {
+		return
+	}
+	err := r.w.Flush()
+	if err == nil && r.settings.format == recordWAV {
+		_, err = r.file.WriteAt(wavHeader(r.format, r.size), 0)
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(r.path+recordPartial, r.path)
+	}
+	if err != nil {
+		r.failed(err)
+	} else {
+		r.settings.uploader.enqueue(r.path)
+	}
+	r.file = nil
+	r.w = nil
+}
+
+// failed counts a disk error, logging only the first of a run of them
+func (r *recorder) failed(err error) {
+	r.stats.errors.Add(1)
+	if !r.failing {
+		log.Printf("Error recording room %s: %v", r.room, err)
+	}
+	r.failing = true
+}
+
+// repairRecordings finishes the recording files in dir a crash left
+// partial, filling in a wav file's sizes from its length, and patches the
+// wav files of older gateways that disagree with their length, returning
+// how many files it repaired. A raw file needs nothing: ReadRecording
+// reads one cut short. Run it before any room records into dir.
+func repairRecordings(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	repaired := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		finished, partial := strings.CutSuffix(path, recordPartial)
+		if entry.IsDir() || !strings.HasSuffix(finished, ".wav") && !(partial && strings.HasSuffix(finished, ".wtrec")) {
+			continue
+		}
+		patched := false
+		if strings.HasSuffix(finished, ".wav") {
+			if patched, err = repairWAV(path); err != nil {
+				log.Printf("Error repairing recording %s: %v", path, err)
+				continue
+			}
+		}
+		if partial {
+			if err := os.Rename(path, finished); err != nil {
+				log.Printf("Error repairing recording %s: %v", path, err)
+				continue
+			}
+		}
+		if patched || partial {
+			log.Printf("Repaired recording %s", finished)
+			repaired++
+		}
+	}
+	return repaired, nil
+}
+
+// setRecording starts or stops the hub's recorder. A hub that has stopped
+// never starts one again.
+func (h *Hub) setRecording(on bool, settings recordSettings) {
+	h.recordMutex.Lock()
+	defer h.recordMutex.Unlock()
+	current := h.recorder.Load()
+	switch {
+	case on && current == nil && !h.recordingDone:
+		if err := os.MkdirAll(settings.dir, 0o750); err != nil {
+			log.Printf("Error recording room %s: %v", h.room, err)
+			h.recording.errors.Add(1)
+			return
+		}
+		h.recorder.Store(newRecorder(h.room, settings, h.audio.Load().audioFormat, &h.recording))
+		log.Printf("Started recording room %s", h.room)
+	case !on && current != nil:
+		h.recorder.Store(nil)
+		current.close()
+	}
+}
+
+// stopRecording stops the hub's recorder for good and waits for it to
+// finish its file. Run calls it on its way out.
+func (h *Hub) stopRecording() {
+	h.recordMutex.Lock()
+	current := h.recorder.Swap(nil)
+	h.recordingDone = true
+	h.recordMutex.Unlock()
+	if current != nil {
+		current.close()
+		<-current.done
+	}
+}
+
+// record hands an audio frame to the hub's recorder, if it is recording.
+// Only the Run goroutine may call it.
+func (h *Hub) record(message BroadcastMessage, now time.Time) {
+	if message.frame.messageType != websocket.BinaryMessage {
+		return
+	}
+	if r := h.recorder.Load(); r != nil {
+		r.record(message, now)
+	}
+}
+
+// RecordedFrame is a frame read back from a raw recording
+type RecordedFrame struct {
+	At     time.Time
+	Sender string
+	Data   []byte
+}
+
+// ReadRecording parses a raw recording, calling fn for each frame in turn.
+// It stops at the first error fn returns. A file cut short mid-frame, say
+// by a crash, yields the frames before the cut and io.ErrUnexpectedEOF.
+func ReadRecording(r io.Reader, fn func(RecordedFrame) error) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != string(recordMagic) {
+		return errNotRawRecording
+	}
+
+	for {
+		var header [8 + 2]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		}
+		sender := make([]byte, binary.BigEndian.Uint16(header[8:]))
+		var length [4]byte
+		if _, err := io.ReadFull(br, sender); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if _, err := io.ReadFull(br, length[:]); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		data := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(br, data); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		frame := RecordedFrame{
+			At:     time.Unix(0, int64(binary.BigEndian.Uint64(header[:8]))),
+			Sender: string(sender),
+			Data:   data,
+		}
+		if err := fn(frame); err != nil {
+			return err
+		}
+	}
+}
+
+// dumpRecording prints a raw recording's frames, one per line, for
+// -dump-recording
+func dumpRecording(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var frames, bytes int
+	err = ReadRecording(file, func(frame RecordedFrame) error {
+		frames++
+		bytes += len(frame.Data)
+		_, err := fmt.Printf("%s\t%s\t%d\n", frame.At.UTC().Format(time.RFC3339Nano), frame.Sender, len(frame.Data))
+		return err
+	})
+	if err != errNotRawRecording {
+		fmt.Printf("%d frames, %d bytes of audio\n", frames, bytes)
+	}
+	return err
+}
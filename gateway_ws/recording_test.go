@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// record writes frames from alternating senders through a raw recorder
+// rotating at maxBytes, and returns the files it left in order
+func record(t *testing.T, frames int, maxBytes int64) ([]string, []RecordedFrame) {
+	t.Helper()
+	settings := recordSettings{dir: t.TempDir(), format: recordRaw, maxBytes: maxBytes, maxDuration: time.Hour}
+	var stats recordStats
+	r := newRecorder("dispatch", settings, audioFormat{}, &stats)
+	senders := []*Client{{id: "alice"}, {id: "bob"}}
+	start := time.Now()
+	var want []RecordedFrame
+	for i := 0; i < frames; i++ {
+		frame := getFrame()
+		frame.data = append(frame.data[:0], pcmFrame(byte(i))...)
+		at := start.Add(time.Duration(i) * 20 * time.Millisecond)
+		sender := senders[i%len(senders)]
+		r.record(BroadcastMessage{frame: frame, sender: sender}, at)
+		want = append(want, RecordedFrame{At: at, Sender: sender.id, Data: pcmFrame(byte(i))})
+		frame.release()
+	}
+	r.close()
+	<-r.done
+	if got := stats.frames.Load(); got != uint64(frames) {
+		t.Fatalf("recorded %d frames, want %d (%d dropped)", got, frames, stats.dropped.Load())
+	}
+	files, err := filepath.Glob(filepath.Join(settings.dir, "dispatch-*.wtrec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files, want
+}
+
+// Every recorded frame reads back from the files, rotated or not, with its
+// time, sender and audio
+func TestRecordingRoundTrip(t *testing.T) {
+	// Each frame takes its header, a five byte sender and the audio
+	const frameSize = 10 + 5 + 4 + 640
+	tests := []struct {
+		name     string
+		maxBytes int64
+		files    int
+	}{
+		{"one file", 1 << 20, 1},
+		{"rotated by size", 4 * frameSize, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, want := record(t, 12, tt.maxBytes)
+			if len(files) != tt.files {
+				t.Fatalf("wrote %d files, want %d", len(files), tt.files)
+			}
+			var got []RecordedFrame
+			for _, path := range files {
+				file, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = ReadRecording(file, func(frame RecordedFrame) error {
+					got = append(got, frame)
+					return nil
+				})
+				file.Close()
+				if err != nil {
+					t.Fatalf("reading %s: %v", path, err)
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d frames, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !got[i].At.Equal(want[i].At) || got[i].Sender != want[i].Sender || !bytes.Equal(got[i].Data, want[i].Data) {
+					t.Errorf("frame %d read back as %v from %s, want %v from %s", i, got[i].At, got[i].Sender, want[i].At, want[i].Sender)
+				}
+			}
+		})
+	}
+}
+
+// A recording cut short mid-frame reads back up to the cut
+func TestRecordingTruncated(t *testing.T) {
+	files, _ := record(t, 3, 1<<20)
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := 0
+	err = ReadRecording(bytes.NewReader(data[:len(data)-100]), func(RecordedFrame) error {
+		frames++
+		return nil
+	})
+	if err != io.ErrUnexpectedEOF || frames != 2 {
+		t.Errorf("read %d frames and %v, want 2 and %v", frames, err, io.ErrUnexpectedEOF)
+	}
+	if err := ReadRecording(strings.NewReader("RIFF"), func(RecordedFrame) error { return nil }); err != errNotRawRecording {
+		t.Errorf("reading a non-recording: %v, want %v", err, errNotRawRecording)
+	}
+}
+
+// -dump-recording prints a line a frame and a summary
+func TestDumpRecording(t *testing.T) {
+	files, want := record(t, 5, 1<<20)
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	err = dumpRecording(files[0])
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(r)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != len(want)+1 {
+		t.Fatalf("printed %d lines, want %d:\n%s", len(lines), len(want)+1, out)
+	}
+	for i, frame := range want {
+		line := fmt.Sprintf("%s\t%s\t%d", frame.At.UTC().Format(time.RFC3339Nano), frame.Sender, len(frame.Data))
+		if lines[i] != line {
+			t.Errorf("line %d is %q, want %q", i, lines[i], line)
+		}
+	}
+	if summary := fmt.Sprintf("%d frames, %d bytes of audio", len(want), len(want)*640); lines[len(want)] != summary {
+		t.Errorf("summary %q, want %q", lines[len(want)], summary)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordingID matches a recording's ID: its file name without the
+// extension
+var recordingID = regexp.MustCompile(`^(.+)-(\d{8}T\d{6}\.\d{3}Z)-(\d+)$`)
+
+// Ogg stream settings for raw recordings of Opus rooms: Opus always counts
+// granule positions at 48kHz (RFC 7845, section 4)
+const (
+	oggOpusRate   = 48000
+	oggVendor     = "walkie-talkie-gateway"
+	oggBOS        = 0x02
+	oggEOS        = 0x04
+	oggMaxSegment = 255
+)
+
+// oggCRC is the CRC-32 an Ogg page is checked by: polynomial 0x04c11db7,
+// not reflected, starting from zero
+var oggCRC = func() *[256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return &table
+}()
+
+var errNoSuchRecording = errors.New("no such recording")
+
+// RecordingInfo describes a finished recording file. A wav file's header
+// gives its duration and format; a raw one's audio is read back in its
+// room's current format.
+type RecordingInfo struct {
+	ID         string    `json:"id"`
+	Room       string    `json:"room"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Format     string    `json:"format"`
+	Bytes      int64     `json:"bytes"`
+	Archived   bool      `json:"archived,omitempty"`
+	Duration   float64   `json:"duration_seconds,omitempty"`
+	SampleRate int       `json:"sample_rate,omitempty"`
+	Channels   int       `json:"channels,omitempty"`
+
+	path string
+	room string
+}
+
+// recordingDirs returns the directories finished recordings are found in:
+// the recording directory and any upload archive directory
+func (g *Gateway) recordingDirs() []string {
+	dirs := []string{g.recording.dir}
+	if g.uploader != nil && g.uploader.settings.ArchiveDir != "" {
+		dirs = append(dirs, g.uploader.settings.ArchiveDir)
+	}
+	return dirs
+}
+
+// recordingInfo describes the recording file at path, whose name
+// recordingName matched. Within a tenant's scope the room and ID leave the
+// tenant out, as its clients name the room.
+func recordingInfo(path string, match []string, scoped, archived bool) (RecordingInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return RecordingInfo{}, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return RecordingInfo{}, err
+	}
+
+	room := match[1]
+	if scoped {
+		room = localRoom(room)
+	}
+	start, _ := time.Parse("20060102T150405.000Z", match[2])
+	info := RecordingInfo{
+		ID:       room + "-" + match[2] + "-" + match[3],
+		Room:     room,
+		Start:    start,
+		End:      stat.ModTime().UTC(),
+		Format:   recordRaw,
+		Bytes:    stat.Size(),
+		Archived: archived,
+		path:     path,
+		room:     match[1],
+	}
+	if match[4] != ".wav" {
+		return info, nil
+	}
+	info.Format = recordWAV
+	header := make([]byte, wavHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return info, nil
+	}
+	info.Channels = int(binary.LittleEndian.Uint16(header[22:]))
+	info.SampleRate = int(binary.LittleEndian.Uint32(header[24:]))
+	if byteRate := binary.LittleEndian.Uint32(header[28:]); byteRate > 0 {
+		info.Duration = float64(binary.LittleEndian.Uint32(header[40:])) / float64(byteRate)
+	}
+	return info, nil
+}
+
+// Recordings lists the finished recordings of tenant's rooms, or of every
+// room if tenant is empty, optionally just those of room and those that
+// overlap from and to, oldest first
+func (g *Gateway) Recordings(tenant string, scoped bool, room string, from, to time.Time) ([]RecordingInfo, error) {
+	recordings := []RecordingInfo{}
+	for i, dir := range g.recordingDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			match := recordingName.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil {
+				continue
+			}
+			if tenant != "" && roomTenant(match[1]) != tenant || room != "" && match[1] != room {
+				continue
+			}
+			info, err := recordingInfo(filepath.Join(dir, entry.Name()), match, scoped, i > 0)
+			if err != nil {
+				continue
+			}
+			if !to.IsZero() && !info.Start.Before(to) || !from.IsZero() && info.End.Before(from) {
+				continue
+			}
+			recordings = append(recordings, info)
+		}
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		if !recordings[i].Start.Equal(recordings[j].Start) {
+			return recordings[i].Start.Before(recordings[j].Start)
+		}
+		return recordings[i].ID < recordings[j].ID
+	})
+	return recordings, nil
+}
+
+// findRecording returns the recording with the ID a management request
+// named it by
+func (g *Gateway) findRecording(r *http.Request, id string) (RecordingInfo, error) {
+	match := recordingID.FindStringSubmatch(id)
+	if match == nil {
+		return RecordingInfo{}, errNoSuchRecording
+	}
+	room, ok := scopedRoom(r, match[1])
+	if !ok {
+		return RecordingInfo{}, errNoSuchRecording
+	}
+	for i, dir := range g.recordingDirs() {
+		for _, ext := range []string{".wav", ".wtrec"} {
+			name := room + "-" + match[2] + "-" + match[3] + ext
+			info, err := recordingInfo(filepath.Join(dir, name), []string{name, room, match[2], match[3], ext}, tenantScoped(r), i > 0)
+			if err == nil {
+				return info, nil
+			}
+		}
+	}
+	return RecordingInfo{}, errNoSuchRecording
+}
+
+// serveRecordings handles GET /recordings, optionally filtered by ?room=
+// and by ?from= and ?to= in RFC 3339, and limited to a tenant's rooms when
+// the request is scoped to one, and the recordings under it: a
+// recording's description at /recordings/{id} and its audio at
+// /recordings/{id}/audio
+func (g *Gateway) serveRecordings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if g.recording.dir == "" {
+		http.Error(w, errRecordingDisabled.Error(), http.StatusNotFound)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/recordings"), "/"), "/")
+	switch {
+	case parts[0] == "" && len(parts) == 1:
+		g.listRecordings(w, r)
+	case len(parts) == 1 || len(parts) == 2 && parts[1] == "audio":
+		info, err := g.findRecording(r, parts[0])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if len(parts) == 1 {
+			writeJSON(w, http.StatusOK, info)
+			return
+		}
+		g.serveRecordingAudio(w, r, info)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listRecordings writes the recordings a GET /recordings asks for
+func (g *Gateway) listRecordings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var room string
+	if name := query.Get("room"); name != "" {
+		var ok bool
+		if room, ok = scopedRoom(r, name); !ok {
+			http.Error(w, "invalid room name", http.StatusBadRequest)
+			return
+		}
+	}
+	var bounds [2]time.Time
+	for i, key := range []string{"from", "to"} {
+		if value := query.Get(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, key+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			bounds[i] = t
+		}
+	}
+	recordings, err := g.Recordings(scopedTenant(r), tenantScoped(r), room, bounds[0], bounds[1])
+	if err != nil {
+		log.Printf("Error listing recordings: %v", err)
+		http.Error(w, "error listing recordings", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, recordings)
+}
+
+// serveRecordingAudio streams a recording's audio. A wav file is served as
+// it is, with ranges so players can seek. A raw recording of a pcm16 room
+// is converted to WAV as it is read, and one of an Opus room is wrapped in
+// an Ogg stream; a raw recording in any other codec can't be played.
+func (g *Gateway) serveRecordingAudio(w http.ResponseWriter, r *http.Request, info RecordingInfo) {
+	file, err := os.Open(info.path)
+	if err != nil {
+		http.Error(w, errNoSuchRecording.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	if info.Format == recordWAV {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Header().Set("Content-Disposition", `inline; filename="`+info.ID+`.wav"`)
+		http.ServeContent(w, r, "", info.End, file)
+		return
+	}
+
+	g.mutex.Lock()
+	format := g.audioFor(info.room).audioFormat
+	g.mutex.Unlock()
+
+	switch {
+	case strings.EqualFold(format.Codec, defaultCodec):
+		err = streamRecordingWAV(w, r, file, info, format)
+	case strings.EqualFold(format.Codec, "opus"):
+		err = streamRecordingOgg(w, r, file, info, format)
+	default:
+		http.Error(w, "recording of room "+info.Room+" is in codec "+format.Codec+", which can't be played", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		log.Printf("Error streaming recording %s: %v", info.ID, err)
+	}
+}
+
+// streamRecordingWAV writes a raw recording's audio as a WAV file in
+// format. A first pass over the file counts the audio for the header and
+// Content-Length; the second writes it out frame by frame.
+func streamRecordingWAV(w http.ResponseWriter, r *http.Request, file *os.File, info RecordingInfo, format audioFormat) error {
+	var size int64
+	err := ReadRecording(file, func(frame RecordedFrame) error {
+		size += int64(len(frame.Data))
+		return nil
+	})
+	if err != nil && err != io.ErrUnexpectedEOF {
+		http.Error(w, "error reading recording", http.StatusInternalServerError)
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "error reading recording", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", `inline; filename="`+info.ID+`.wav"`)
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("Content-Length", strconv.FormatInt(wavHeaderSize+size, 10))
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	bw := bufio.NewWriterSize(w, recordBufferSize)
+	if _, err := bw.Write(wavHeader(format, size)); err != nil {
+		return err
+	}
+	// Stop at the size the header claims, should the file have grown
+	left := size
+	err = ReadRecording(file, func(frame RecordedFrame) error {
+		data := frame.Data[:min(int64(len(frame.Data)), left)]
+		left -= int64(len(data))
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+		if left == 0 {
+			return io.EOF
+		}
+		return nil
+	})
+	if err == io.EOF {
+		err = nil
+	}
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// streamRecordingOgg writes a raw recording of an Opus room as an Ogg
+// Opus stream (RFC 7845), one packet to a page and the last page marked,
+// dropping any frame that isn't an Opus packet
+func streamRecordingOgg(w http.ResponseWriter, r *http.Request, file *os.File, info RecordingInfo, format audioFormat) error {
+	w.Header().Set("Content-Type", "audio/ogg; codecs=opus")
+	w.Header().Set("Content-Disposition", `inline; filename="`+info.ID+`.opus"`)
+	w.Header().Set("Accept-Ranges", "none")
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	ogg := &oggWriter{w: bufio.NewWriterSize(w, recordBufferSize), serial: crc32.ChecksumIEEE([]byte(info.ID))}
+	channels := max(format.Channels, 1)
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = byte(channels)
+	binary.LittleEndian.PutUint32(head[12:], uint32(format.SampleRate))
+	if err := ogg.page(head, 0, oggBOS); err != nil {
+		return err
+	}
+	tags := make([]byte, 8+4+len(oggVendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(oggVendor)))
+	copy(tags[12:], oggVendor)
+	if err := ogg.page(tags, 0, 0); err != nil {
+		return err
+	}
+
+	// Each packet waits for the next, so the last can end the stream
+	var pending []byte
+	var granule int64
+	err := ReadRecording(file, func(frame RecordedFrame) error {
+		if !validOpus(frame.Data) || len(frame.Data) >= oggMaxSegment*oggMaxSegment {
+			return nil
+		}
+		if pending != nil {
+			if err := ogg.page(pending, granule, 0); err != nil {
+				return err
+			}
+		}
+		pending = frame.Data
+		granule += int64(opusPacketDuration(frame.Data)) * oggOpusRate / int64(time.Second)
+		return nil
+	})
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if pending == nil {
+		pending = []byte{}
+	}
+	if err := ogg.page(pending, granule, oggEOS); err != nil {
+		return err
+	}
+	return ogg.w.Flush()
+}
+
+// opusPacketDuration returns how much audio an Opus packet that validOpus
+// accepted holds
+func opusPacketDuration(packet []byte) time.Duration {
+	frames := time.Duration(1)
+	switch packet[0] & 0x3 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		frames = time.Duration(packet[1] & 0x3f)
+	}
+	return frames * opusFrameDuration(packet[0]>>3)
+}
+
+// oggWriter writes the pages of one Ogg logical stream
+type oggWriter struct {
+	w        *bufio.Writer
+	serial   uint32
+	sequence uint32
+}
+
+// page writes a page holding packet, which must be shorter than 255
+// segments, ending at granule
+func (o *oggWriter) page(packet []byte, granule int64, flags byte) error {
+	segments := len(packet)/oggMaxSegment + 1
+	page := make([]byte, 27+segments, 27+segments+len(packet))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], o.serial)
+	binary.LittleEndian.PutUint32(page[18:], o.sequence)
+	page[26] = byte(segments)
+	for i := 0; i < segments-1; i++ {
+		page[27+i] = oggMaxSegment
+	}
+	page[27+segments-1] = byte(len(packet) % oggMaxSegment)
+	page = append(page, packet...)
+
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRC[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(page[22:], crc)
+	o.sequence++
+	_, err := o.w.Write(page)
+	return err
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRoom is the room for clients connecting to plain /ws
+	defaultRoom = "default"
+
+	// maxRoomNameLength caps room names
+	maxRoomNameLength = 64
+
+	// defaultRoomLinger is how long an empty room stays open, so a quick
+	// reconnect doesn't tear it down and recreate it
+	defaultRoomLinger = 30 * time.Second
+
+	// roomSweepInterval is how often rooms that emptied without a client
+	// leaving, or while sessions awaited resume, are checked
+	roomSweepInterval = 10 * time.Second
+)
+
+var errGatewayStopped = errors.New("gateway stopped")
+
+// room is a broadcast domain: one hub, isolated from every other room
+type room struct {
+	name    string
+	hub     *Hub
+	created time.Time
+
+	// Upgrades between joinRoom and the end of their registration, and
+	// when the room was opened or last emptied. Guarded by the gateway's
+	// mutex; a room with joiners is never torn down.
+	joining   int
+	idleSince time.Time
+
+	// When the clients of a room closed by its schedule are disconnected,
+	// zero while it is open. Guarded by the gateway's mutex.
+	closesAt time.Time
+}
+
+// WithRoomLinger sets how long a room stays open once its last client has
+// left. Zero tears it down straight away.
+func WithRoomLinger(d time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		if d >= 0 {
+			g.roomLinger = d
+		}
+	}
+}
+
+// WithEmptyHook installs a func the hub calls when its last client is
+// removed. It must not block or call back into the hub.
+func WithEmptyHook(fn func()) HubOption {
+	return func(h *Hub) {
+		h.emptyHook = fn
+	}
+}
+
+// WithRoom names the room the hub serves, for stats and logs
+func WithRoom(name string) HubOption {
+	return func(h *Hub) {
+		h.room = name
+	}
+}
+
+// validRoomName reports whether name is 1 to maxRoomNameLength letters,
+// digits, '-', '_' or '.'
+func validRoomName(name string) bool {
+	if name == "" || len(name) > maxRoomNameLength {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// roomName returns the room a request asks for: the path segment after
+// /ws/, else the room query parameter, else the default room
+func roomName(r *http.Request) (string, error) {
+	name := strings.TrimPrefix(r.URL.Path, "/ws")
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		name = r.URL.Query().Get("room")
+	}
+	if name == "" {
+		return defaultRoom, nil
+	}
+	if !validRoomName(name) {
+		return "", fmt.Errorf("invalid room name %q: use up to %d letters, digits, '-', '_' or '.'", name, maxRoomNameLength)
+	}
+	return name, nil
+}
+
+// joinRoom returns the named room, starting its hub on first join unless
+// that would take the API key's tenant past the key's max_rooms. The caller
+// must call joined once the client's registration has been decided, until
+// which the room can't be swept. Concurrent first joins of a room get the
+// same hub.
+func (g *Gateway) joinRoom(name string, key *apiKey) (*room, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.stopped {
+		return nil, errGatewayStopped
+	}
+
+	rm := g.rooms[name]
+	if rm == nil && key != nil && key.MaxRooms > 0 && g.tenantRoomCount(key.Tenant) >= key.MaxRooms {
+		return nil, errTooManyRooms
+	}
+	if rm == nil {
+		now := time.Now()
+		rm = &room{name: name, created: now, idleSince: now}
+		opts := append(g.hubOpts[:len(g.hubOpts):len(g.hubOpts)],
+			WithRoom(name),
+			WithCapacity(g.capacityFor(name)),
+			WithRateLimit(g.rateLimitFor(name)),
+			WithTalkLimit(g.talkLimitFor(name)),
+			WithSilenceSuppression(g.silenceFor(name)),
+			WithFloorControl(g.floorControlFor(name)),
+			WithFloorQueue(g.floorQueueFor(name)),
+			WithMixing(g.mixingFor(name)),
+			WithPacing(g.pacingFor(name)),
+			WithAudioPolicy(g.audioFor(name)),
+			WithEmptyHook(func() { g.roomEmptied(rm) }),
+		)
+		rm.hub = NewHub(opts...)
+		go rm.hub.Run()
+		g.rooms[name] = rm
+		g.roomsCreated.Add(1)
+		log.Printf("Room %s created", name)
+		g.updateRecording(name)
+	}
+	rm.joining++
+	return rm, nil
+}
+
+// joined ends a join started by joinRoom
+func (g *Gateway) joined(rm *room) {
+	g.mutex.Lock()
+	rm.joining--
+	g.mutex.Unlock()
+}
+
+// roomEmptied is the hub's empty hook. It starts the room's linger period
+// and schedules the teardown for its end.
+func (g *Gateway) roomEmptied(rm *room) {
+	if rm.name == defaultRoom {
+		return
+	}
+	g.mutex.Lock()
+	rm.idleSince = time.Now()
+	g.mutex.Unlock()
+
+	log.Printf("Room %s is empty, closing in %s unless someone joins", rm.name, g.roomLinger)
+	time.AfterFunc(g.roomLinger, func() {
+		g.reapRooms(time.Now())
+	})
+}
+
+// expired reports whether the room has nothing left to do and its linger
+// period is over. The caller must hold the gateway's mutex, which joinRoom
+// takes too, so a client can't join a room as it is being torn down.
+func (g *Gateway) expired(rm *room, now time.Time) bool {
+	return rm.name != defaultRoom &&
+		rm.joining == 0 &&
+		rm.hub.ClientCount() == 0 &&
+		rm.hub.sessions.pending() == 0 &&
+		now.Sub(rm.idleSince) >= g.roomLinger
+}
+
+// reapRooms tears down every expired room
+func (g *Gateway) reapRooms(now time.Time) {
+	var expired []*room
+	g.mutex.Lock()
+	for name, rm := range g.rooms {
+		if g.expired(rm, now) {
+			delete(g.rooms, name)
+			expired = append(expired, rm)
+		}
+	}
+	g.mutex.Unlock()
+
+	for _, rm := range expired {
+		// Nobody can reach the hub any more, so it has no clients to drain
+		rm.hub.Stop(context.Background())
+		g.roomsDestroyed.Add(1)
+		log.Printf("Room %s destroyed", rm.name)
+	}
+}
+
+// sweepRooms reaps rooms that expired without an empty hook to schedule
+// it, such as rooms whose only joiner was refused or whose sessions have
+// since expired, until Stop
+func (g *Gateway) sweepRooms() {
+	ticker := time.NewTicker(roomSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.quit:
+			return
+		case now := <-ticker.C:
+			g.reapRooms(now)
+			g.mutex.Lock()
+			g.expireInvites(now)
+			g.expireBans(now)
+			g.mutex.Unlock()
+		}
+	}
+}
+
+// eachRoom calls fn for every open room, in name order
+func (g *Gateway) eachRoom(fn func(*room)) {
+	g.mutex.Lock()
+	rooms := make([]*room, 0, len(g.rooms))
+	for _, rm := range g.rooms {
+		rooms = append(rooms, rm)
+	}
+	g.mutex.Unlock()
+
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].name < rooms[j].name })
+	for _, rm := range rooms {
+		fn(rm)
+	}
+}
+
+// ClientCount returns the number of clients across all rooms
+func (g *Gateway) ClientCount() int {
+	total := 0
+	g.eachRoom(func(rm *room) {
+		total += rm.hub.ClientCount()
+	})
+	return total
+}
+
+// Clients returns per-client statistics for every room, or only the
+// tenant's rooms if tenant is set
+func (g *Gateway) Clients(tenant string) []ClientStats {
+	var stats []ClientStats
+	g.eachRoom(func(rm *room) {
+		if tenant == "" || roomTenant(rm.name) == tenant {
+			stats = append(stats, rm.hub.Clients()...)
+		}
+	})
+	if stats == nil {
+		stats = []ClientStats{}
+	}
+	return stats
+}
+
+// Stop closes every room the way Hub.Stop closes one, concurrently, and
+// refuses new rooms from then on. It returns once every room has drained or
+// ctx is done.
+func (g *Gateway) Stop(ctx context.Context) error {
+	g.mutex.Lock()
+	if !g.stopped {
+		g.stopped = true
+		close(g.quit)
+	}
+	rooms := make([]*room, 0, len(g.rooms))
+	for _, rm := range g.rooms {
+		rooms = append(rooms, rm)
+	}
+	g.mutex.Unlock()
+
+	errs := make(chan error, len(rooms))
+	var wg sync.WaitGroup
+	for _, rm := range rooms {
+		wg.Add(1)
+		go func(rm *room) {
+			defer wg.Done()
+			if err := rm.hub.Stop(ctx); err != nil {
+				errs <- fmt.Errorf("room %s: %w", rm.name, err)
+			}
+		}(rm)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A room whose hub loop is saturated, its broadcast queue full and its
+// talker's frames dropping, adds no latency to another room's broadcasts:
+// each room's hub loop runs on its own
+func TestBusyRoomIsolated(t *testing.T) {
+	const listeners = 20
+	tg := newTestGateway(t, []HubOption{WithBroadcastQueue(4), WithBroadcastPolicy(BroadcastDrop)})
+	hotTalker, _ := tg.dial(t, "/ws/hot", "hot-talker")
+	readControl(t, hotTalker, "joined")
+	for i := 0; i < listeners; i++ {
+		conn, _ := tg.dial(t, "/ws/hot", fmt.Sprintf("hot%d", i))
+		readControl(t, conn, "joined")
+		go drain(conn, nil, nil)
+	}
+	idleTalker, _ := tg.dial(t, "/ws/idle", "idle-talker")
+	readControl(t, idleTalker, "joined")
+	idleListener, _ := tg.dial(t, "/ws/idle", "idle-listener")
+	readControl(t, idleListener, "joined")
+	hot, idle := tg.hub(t, "hot"), tg.hub(t, "idle")
+
+	// median returns the middle of 20 of the idle room's talker to listener
+	// round trips
+	median := func() time.Duration {
+		var took []time.Duration
+		for i := 0; i < 20; i++ {
+			start := time.Now()
+			if err := idleTalker.WriteMessage(websocket.BinaryMessage, pcmFrame(byte(i))); err != nil {
+				t.Fatal(err)
+			}
+			readAudio(t, idleListener)
+			took = append(took, time.Since(start))
+			time.Sleep(5 * time.Millisecond)
+		}
+		sort.Slice(took, func(i, j int) bool { return took[i] < took[j] })
+		return took[len(took)/2]
+	}
+	baseline := median()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		payload := make([]byte, 16<<10)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if hotTalker.WriteMessage(websocket.BinaryMessage, payload) != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	// A join blocks the hot room's loop while the test holds its presence
+	// mutex, standing in for a loop that can't keep up
+	hot.presenceMutex.Lock()
+	joined := make(chan error, 1)
+	go func() {
+		conn, _, err := tg.tryDial("/ws/hot", "late", nil)
+		if err == nil {
+			conn.Close()
+		}
+		joined <- err
+	}()
+	waitFor(t, "the hot room to back up", func() bool { return hot.Stats().DroppedFrames > 0 })
+	loaded := median()
+	hot.presenceMutex.Unlock()
+	close(done)
+	<-stopped
+	if err := <-joined; err != nil {
+		t.Fatalf("late join: %v", err)
+	}
+
+	t.Logf("idle room median latency %v alone, %v beside the hot room", baseline, loaded)
+	if loaded > baseline+20*time.Millisecond {
+		t.Errorf("idle room median latency went from %v to %v", baseline, loaded)
+	}
+	if l := idle.latency(time.Now()); l.queueWaitMax > 20*time.Millisecond {
+		t.Errorf("idle room frames waited up to %v for its hub loop", l.queueWaitMax)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RoomInfo describes an open room for the room listing
+type RoomInfo struct {
+	Name               string    `json:"room"`
+	Clients            int       `json:"clients"`
+	Capacity           int       `json:"capacity"`
+	CreatedAt          time.Time `json:"created_at"`
+	Transmitting       bool      `json:"transmitting"`
+	MessagesLastMinute uint64    `json:"messages_last_minute"`
+	BytesLastMinute    uint64    `json:"bytes_last_minute"`
+	Recording          bool      `json:"recording"`
+	FloorHolder        string    `json:"floor_holder,omitempty"`
+	FloorLocked        bool      `json:"floor_locked,omitempty"`
+}
+
+// Rooms lists the open rooms whose names start with prefix, in name order.
+// It only reads counters, so polling it never waits on a hub loop.
+func (g *Gateway) Rooms(prefix string) []RoomInfo {
+	now := time.Now()
+	rooms := []RoomInfo{}
+	g.eachRoom(func(rm *room) {
+		if !strings.HasPrefix(rm.name, prefix) {
+			return
+		}
+		messages, bytes := rm.hub.activity.lastMinute(now)
+		var holder string
+		if floor := rm.hub.floorHolder.Load(); floor != nil {
+			holder = floor.id
+		}
+		rooms = append(rooms, RoomInfo{
+			Name:               rm.name,
+			Clients:            rm.hub.ClientCount(),
+			Capacity:           int(rm.hub.capacity.Load()),
+			CreatedAt:          rm.created,
+			Transmitting:       rm.hub.activity.transmitting(now),
+			MessagesLastMinute: messages,
+			BytesLastMinute:    bytes,
+			Recording:          rm.hub.recorder.Load() != nil,
+			FloorHolder:        holder,
+			FloorLocked:        rm.hub.floorLock.Load(),
+		})
+	})
+	return rooms
+}
+
+// serveRooms handles GET /rooms, optionally filtered by ?prefix=, and
+// limited to a tenant's rooms when the request is scoped to one
+func (g *Gateway) serveRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	if tenant := scopedTenant(r); tenant != "" {
+		prefix = tenantRoom(tenant, prefix)
+	}
+	writeJSON(w, http.StatusOK, g.Rooms(prefix))
+}
+
+// serveRoom handles the room admin API under /rooms/: room configs at
+// /rooms/{room}, moderators at /rooms/{room}/moderators/{client}, listeners
+// at /rooms/{room}/listeners/{client}, mutes at /rooms/{room}/mutes/{client},
+// invites at /rooms/{room}/invites, availability at /rooms/{room}/open and
+// talkers at /rooms/{room}/talkers
+func (g *Gateway) serveRoom(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	name, ok := scopedRoom(r, parts[0])
+	if !ok {
+		http.Error(w, "invalid room name", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		g.serveRoomConfig(w, r, name)
+	case len(parts) == 3 && parts[1] == "moderators":
+		g.serveModerator(w, r, name, parts[2])
+	case len(parts) == 3 && parts[1] == "listeners":
+		g.serveListener(w, r, name, parts[2])
+	case len(parts) == 3 && parts[1] == "mutes":
+		g.serveMute(w, r, name, parts[2])
+	case len(parts) == 2 && parts[1] == "invites":
+		g.serveInvites(w, r, name, "")
+	case len(parts) == 3 && parts[1] == "invites":
+		g.serveInvites(w, r, name, parts[2])
+	case len(parts) == 2 && parts[1] == "open":
+		g.serveAvailability(w, r, name)
+	case len(parts) == 2 && parts[1] == "talkers":
+		g.serveTalkers(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveRoomConfig creates (POST), creates or replaces (PUT) and deletes
+// (DELETE) a room's config. Changes apply to the room straight away if it
+// is open.
+func (g *Gateway) serveRoomConfig(w http.ResponseWriter, r *http.Request, name string) {
+	var config RoomConfig
+	var err error
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			http.Error(w, "invalid room config: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// A tenant's config may name its room without the tenant
+		if config.Name != "" && config.Name != name && tenantRoom(roomTenant(name), config.Name) != name {
+			http.Error(w, "room name doesn't match the path", http.StatusBadRequest)
+			return
+		}
+		config.Name = name
+		if err := g.validate(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = g.putRoomConfig(config, r.Method == http.MethodPut)
+	case http.MethodDelete:
+		err = g.deleteRoomConfig(name)
+	default:
+		w.Header().Set("Allow", "POST, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case errors.Is(err, errRoomExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errRoomNotConfigured):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		log.Printf("Error updating the config of room %s: %v", name, err)
+		http.Error(w, "room store error", http.StatusInternalServerError)
+	case r.Method == http.MethodDelete:
+		log.Printf("Room %s config deleted", name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost:
+		log.Printf("Room %s config created (persistent: %t)", name, config.Persistent)
+		writeJSON(w, http.StatusCreated, config)
+	default:
+		log.Printf("Room %s config saved (persistent: %t)", name, config.Persistent)
+		writeJSON(w, http.StatusOK, config)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// rosterMessage answers a roster request with every client in the room, the
+// requester included, sorted by ID. A client that reconnects, or missed
+// presence messages, asks for it to resync. Its version counts the room's
+// joins, leaves and hands raised or lowered, and each presence message
+// carries the one it makes, so a client that sees a gap in them knows to
+// ask again. Presence messages may arrive ahead of the roster that already
+// reflects them; a client applies only those with a later version.
+type rosterMessage struct {
+	Type    string         `json:"type"`
+	Room    string         `json:"room"`
+	Version uint64         `json:"version"`
+	Clients []rosterClient `json:"clients"`
+}
+
+// rosterClient is a client in the room as the roster lists it. Since is
+// when it joined the room, in Unix milliseconds.
+type rosterClient struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name,omitempty"`
+	Role         string   `json:"role"`
+	Tags         []string `json:"tags,omitempty"`
+	Muted        bool     `json:"muted"`
+	Since        int64    `json:"since"`
+	Transmitting bool     `json:"transmitting"`
+	HandRaisedAt int64    `json:"hand_raised_at,omitempty"`
+}
+
+// roster asks for the room's clients, on a multi-room connection the
+// room's
+func init() {
+	registerControl("roster", controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.hub.sendControl(member, member.hub.roster())
+			}
+		},
+	})
+}
+
+// roster returns the hub's clients and the roster version they make. Only
+// the client list is taken under the presence mutex, so a large room holds
+// up joins and leaves for no longer than it takes to copy it; a client that
+// leaves while the rest is read is listed, and its leave follows with a
+// later version.
+func (h *Hub) roster() rosterMessage {
+	h.presenceMutex.Lock()
+	clients := h.snapshotClients()
+	version := h.rosterVersion
+	h.presenceMutex.Unlock()
+
+	now := time.Now()
+	listed := make([]rosterClient, 0, len(clients))
+	for _, client := range clients {
+		listed = append(listed, rosterClient{
+			ID:           client.id,
+			Name:         client.name,
+			Role:         client.role(),
+			Tags:         h.tagsOf(client),
+			Muted:        client.muted.Load(),
+			Since:        client.joinedAt.UnixMilli(),
+			Transmitting: client.talking(now),
+			HandRaisedAt: client.hand.Load(),
+		})
+	}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].ID < listed[j].ID })
+	return rosterMessage{Type: "roster", Room: localRoom(h.room), Version: version, Clients: listed}
+}
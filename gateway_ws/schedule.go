@@ -0,0 +1,530 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	// Room schedules name IANA time zones, and the image has no zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	// defaultScheduleGrace is how long clients of a room whose window has
+	// closed have to finish up before they are disconnected
+	defaultScheduleGrace = 30 * time.Second
+
+	// scheduleInterval is how often open rooms are checked against their
+	// schedules
+	scheduleInterval = time.Second
+
+	// Force-opens last an hour unless asked for longer, up to a week
+	defaultForceOpen = time.Hour
+	maxForceOpen     = 7 * 24 * time.Hour
+
+	// scheduleHorizon bounds the search for a schedule's next opening, so
+	// an expression that never matches, such as 31 February, gives up
+	scheduleHorizon = 5 * 366 * 24 * time.Hour
+
+	// maxChainedWindows bounds how many back to back or overlapping windows
+	// are followed to find when an open room closes
+	maxChainedWindows = 1000
+)
+
+// RoomSchedule is when a scheduled room is open: daily between Open and
+// Close, as HH:MM on the given days or every day, or for Duration from
+// every time matching a five-field cron expression. Times are in TimeZone,
+// an IANA name, or UTC. A Close earlier than Open closes the next day.
+type RoomSchedule struct {
+	Open  string   `json:"open,omitempty"`
+	Close string   `json:"close,omitempty"`
+	Days  []string `json:"days,omitempty"`
+
+	Cron     string `json:"cron,omitempty"`
+	Duration string `json:"duration,omitempty"`
+
+	TimeZone string `json:"timezone,omitempty"`
+}
+
+// schedule is a compiled RoomSchedule: windows of length that start at every
+// minute matching the cron fields, one bit per value
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Cron matches a day on either of day of month and day of week when
+	// both are restricted
+	domAny, dowAny bool
+
+	length   time.Duration
+	location *time.Location
+}
+
+// weekdays are the day names RoomSchedule.Days takes
+var weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// compileSchedule checks a room schedule and compiles it
+func compileSchedule(spec RoomSchedule) (*schedule, error) {
+	sched := &schedule{location: time.UTC}
+	if spec.TimeZone != "" {
+		location, err := time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule timezone %q", spec.TimeZone)
+		}
+		sched.location = location
+	}
+
+	daily := spec.Open != "" || spec.Close != "" || len(spec.Days) > 0
+	switch {
+	case daily && (spec.Cron != "" || spec.Duration != ""):
+		return nil, errors.New("schedule takes open and close, or cron and duration, not both")
+	case daily:
+		return sched, sched.compileDaily(spec)
+	case spec.Cron != "":
+		return sched, sched.compileCron(spec)
+	}
+	return nil, errors.New("schedule needs open and close, or cron and duration")
+}
+
+// compileDaily compiles an open and close time on some days of the week
+func (s *schedule) compileDaily(spec RoomSchedule) error {
+	open, err := parseClock(spec.Open)
+	if err != nil {
+		return fmt.Errorf("invalid schedule open: %w", err)
+	}
+	closing, err := parseClock(spec.Close)
+	if err != nil {
+		return fmt.Errorf("invalid schedule close: %w", err)
+	}
+	s.length = closing - open
+	if s.length <= 0 {
+		s.length += 24 * time.Hour
+	}
+	s.minute = 1 << (int(open.Minutes()) % 60)
+	s.hour = 1 << int(open.Hours())
+	s.dom, s.domAny = bits(1, 31), true
+	s.month = bits(1, 12)
+
+	if len(spec.Days) == 0 {
+		s.dow, s.dowAny = bits(0, 6), true
+		return nil
+	}
+	for _, days := range spec.Days {
+		first, last, isRange := strings.Cut(strings.ToLower(days), "-")
+		from, okFrom := weekdays[first]
+		to, okTo := from, okFrom
+		if isRange {
+			to, okTo = weekdays[last]
+		}
+		if !okFrom || !okTo || to < from {
+			return fmt.Errorf("invalid schedule day %q: want mon to sun, or a range such as mon-fri", days)
+		}
+		s.dow |= bits(from, to)
+	}
+	return nil
+}
+
+// parseClock parses HH:MM as the time since midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// compileCron compiles a cron expression and the length of the windows it
+// opens
+func (s *schedule) compileCron(spec RoomSchedule) error {
+	length, err := time.ParseDuration(spec.Duration)
+	if err != nil || length <= 0 {
+		return errors.New("schedule cron needs a positive duration such as \"8h\"")
+	}
+	s.length = length
+
+	fields := strings.Fields(spec.Cron)
+	if len(fields) != 5 {
+		return fmt.Errorf("invalid schedule cron %q: want minute hour day-of-month month day-of-week", spec.Cron)
+	}
+	targets := []struct {
+		set      *uint64
+		any      *bool
+		min, max int
+	}{
+		{&s.minute, nil, 0, 59},
+		{&s.hour, nil, 0, 23},
+		{&s.dom, &s.domAny, 1, 31},
+		{&s.month, nil, 1, 12},
+		{&s.dow, &s.dowAny, 0, 7},
+	}
+	for i, target := range targets {
+		set, err := parseCronField(fields[i], target.min, target.max)
+		if err != nil {
+			return fmt.Errorf("invalid schedule cron %q: %w", spec.Cron, err)
+		}
+		*target.set = set
+		if target.any != nil {
+			*target.any = strings.HasPrefix(fields[i], "*")
+		}
+	}
+	// Both 0 and 7 are Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return nil
+}
+
+// parseCronField parses a comma-separated list of *, values and ranges, each
+// optionally stepped with /n, into a bit per value
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		from, to := min, max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if from, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if stepped {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// bits returns a set with every value from first to last
+func bits(first, last int) uint64 {
+	return (1<<(last+1) - 1) &^ (1<<first - 1)
+}
+
+// matchesDay reports whether a window may start on t's day
+func (s *schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first start of a window after t, or the zero time if
+// there is none within scheduleHorizon
+func (s *schedule) next(t time.Time) time.Time {
+	limit := t.Add(scheduleHorizon)
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		var skip time.Time
+		switch {
+		case s.month&(1<<int(m)) == 0:
+			skip = time.Date(y, m+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			skip = time.Date(y, m, d+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<t.Hour()) == 0:
+			skip = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, s.location)
+		case s.minute&(1<<t.Minute()) == 0:
+			skip = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// Daylight saving changes can normalise a skip back onto the hour
+		// it left
+		if !skip.After(t) {
+			skip = t.Add(time.Minute)
+		}
+		t = skip
+	}
+	return time.Time{}
+}
+
+// openAt reports whether a window is open at now, and if so when the room
+// closes, following windows that start before the previous one ends
+func (s *schedule) openAt(now time.Time) (bool, time.Time) {
+	start := s.next(now.Add(-s.length))
+	if start.IsZero() || start.After(now) {
+		return false, time.Time{}
+	}
+	end := start.Add(s.length)
+	for i := 0; i < maxChainedWindows; i++ {
+		start = s.next(start)
+		if start.IsZero() || start.After(end) {
+			break
+		}
+		end = start.Add(s.length)
+	}
+	return true, end
+}
+
+// WithScheduleGrace sets how long clients of a scheduled room have between
+// the warning that it is closing and being disconnected
+func WithScheduleGrace(d time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		if d >= 0 {
+			g.scheduleGrace = d
+		}
+	}
+}
+
+// RoomAvailability is whether a room is open to joins and until when, by
+// its schedule or an admin's force-open
+type RoomAvailability struct {
+	Room            string     `json:"room"`
+	Scheduled       bool       `json:"scheduled"`
+	Open            bool       `json:"open"`
+	ClosesAt        *time.Time `json:"closes_at,omitempty"`
+	OpensAt         *time.Time `json:"opens_at,omitempty"`
+	ForcedOpenUntil *time.Time `json:"forced_open_until,omitempty"`
+}
+
+// availability works out whether the room is open at now. The caller must
+// hold the mutex.
+func (g *Gateway) availability(name string, now time.Time) RoomAvailability {
+	availability := RoomAvailability{Room: name, Open: true}
+	sched := g.schedules[name]
+	if sched == nil {
+		return availability
+	}
+	availability.Scheduled = true
+
+	open, closes := sched.openAt(now)
+	if forced := g.forcedOpen[name]; forced.After(now) {
+		availability.ForcedOpenUntil = &forced
+		if !open || forced.After(closes) {
+			closes = forced
+		}
+		open = true
+	}
+	availability.Open = open
+	if open {
+		availability.ClosesAt = &closes
+	} else if opens := sched.next(now); !opens.IsZero() {
+		availability.OpensAt = &opens
+	}
+	return availability
+}
+
+// roomAvailability is availability for callers that don't hold the mutex
+func (g *Gateway) roomAvailability(name string, now time.Time) RoomAvailability {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.availability(name, now)
+}
+
+// updateSchedule compiles the room's schedule from its config, dropping any
+// force-open along with the schedule. The caller must hold the mutex.
+func (g *Gateway) updateSchedule(name string) {
+	config, ok := g.roomConfigs[name]
+	if !ok || config.Schedule == nil {
+		delete(g.schedules, name)
+		delete(g.forcedOpen, name)
+		return
+	}
+	sched, err := compileSchedule(*config.Schedule)
+	if err != nil {
+		// Only a hand-edited store can get here, as the admin API validates
+		log.Printf("Room %s schedule ignored: %v", name, err)
+		delete(g.schedules, name)
+		return
+	}
+	g.schedules[name] = sched
+}
+
+// roomClosedError refuses a join to a room outside its schedule
+type roomClosedError struct {
+	Error   string     `json:"error"`
+	Room    string     `json:"room"`
+	OpensAt *time.Time `json:"opens_at,omitempty"`
+}
+
+// admitScheduled reports whether the room's schedule lets clients join now,
+// having answered 403 with when it next opens if not
+func (g *Gateway) admitScheduled(w http.ResponseWriter, room, clientID string) bool {
+	availability := g.roomAvailability(room, time.Now())
+	if availability.Open {
+		return true
+	}
+	log.Printf("Client %s refused from room %s: closed by its schedule", clientID, room)
+	writeJSON(w, http.StatusForbidden, roomClosedError{Error: "room_closed", Room: localRoom(room), OpensAt: availability.OpensAt})
+	return false
+}
+
+// roomClosingMessage warns a room's clients that its window has closed and
+// they will be disconnected at ClosesAt. roomReopenedMessage calls that off
+// when the room is forced open, or rescheduled, before then.
+type roomClosingMessage struct {
+	Type     string     `json:"type"`
+	Room     string     `json:"room"`
+	ClosesAt time.Time  `json:"closes_at"`
+	OpensAt  *time.Time `json:"opens_at,omitempty"`
+}
+
+type roomReopenedMessage struct {
+	Type     string    `json:"type"`
+	Room     string    `json:"room"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// enforceSchedules runs until Stop, closing rooms whose windows have
+// closed
+func (g *Gateway) enforceSchedules() {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.quit:
+			return
+		case now := <-ticker.C:
+			g.checkSchedules(now)
+		}
+	}
+}
+
+// checkSchedules brings open rooms in line with their schedules. A room
+// found closed is warned and given a grace period, then its clients are
+// closed, along with any that join before it has gone. The state is the
+// room's own, so re-checking after a config change or on every tick never
+// warns the same closing twice, and a restart starts from rooms that
+// clients can no longer join.
+func (g *Gateway) checkSchedules(now time.Time) {
+	type change struct {
+		rm           *room
+		availability RoomAvailability
+		closesAt     time.Time
+	}
+	var warn, reopen, shut []change
+
+	g.mutex.Lock()
+	for name, rm := range g.rooms {
+		if g.schedules[name] == nil && rm.closesAt.IsZero() {
+			continue
+		}
+		availability := g.availability(name, now)
+		switch {
+		case availability.Open:
+			if !rm.closesAt.IsZero() {
+				reopen = append(reopen, change{rm, availability, *availability.ClosesAt})
+				rm.closesAt = time.Time{}
+			}
+		case rm.closesAt.IsZero():
+			rm.closesAt = now.Add(g.scheduleGrace)
+			warn = append(warn, change{rm, availability, rm.closesAt})
+		case !now.Before(rm.closesAt):
+			shut = append(shut, change{rm, availability, rm.closesAt})
+		}
+	}
+	g.mutex.Unlock()
+
+	for _, c := range warn {
+		clients := c.rm.hub.snapshotClients()
+		if len(clients) > 0 {
+			log.Printf("Room %s closed by its schedule, disconnecting %d clients at %s", c.rm.name, len(clients), c.closesAt.Format(time.RFC3339))
+		}
+		for _, client := range clients {
+			c.rm.hub.sendControl(client, roomClosingMessage{Type: "room_closing", Room: localRoom(c.rm.name), ClosesAt: c.closesAt, OpensAt: c.availability.OpensAt})
+		}
+	}
+	for _, c := range reopen {
+		log.Printf("Room %s open again until %s", c.rm.name, c.closesAt.Format(time.RFC3339))
+		for _, client := range c.rm.hub.snapshotClients() {
+			c.rm.hub.sendControl(client, roomReopenedMessage{Type: "room_reopened", Room: localRoom(c.rm.name), ClosesAt: c.closesAt})
+		}
+	}
+	for _, c := range shut {
+		for _, client := range c.rm.hub.snapshotClients() {
+			client.leave(reasonRoomClosed)
+		}
+	}
+}
+
+// forceOpenRequest is the body of a request to force a room open. Duration
+// is a Go duration such as "2h".
+type forceOpenRequest struct {
+	Duration string `json:"duration"`
+}
+
+// serveAvailability reports (GET) a room's availability, forces it open
+// (POST) for a while whatever its schedule, and ends a force-open (DELETE):
+// /rooms/{room}/open
+func (g *Gateway) serveAvailability(w http.ResponseWriter, r *http.Request, name string) {
+	now := time.Now()
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, g.roomAvailability(name, now))
+
+	case http.MethodPost:
+		var request forceOpenRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		// An empty body forces the room open for the default time
+		if err := decoder.Decode(&request); err != nil && err != io.EOF {
+			http.Error(w, "invalid force-open request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		d := defaultForceOpen
+		if request.Duration != "" {
+			var err error
+			d, err = time.ParseDuration(request.Duration)
+			if err != nil || d <= 0 || d > maxForceOpen {
+				http.Error(w, "duration must be a duration up to "+maxForceOpen.String(), http.StatusBadRequest)
+				return
+			}
+		}
+		g.mutex.Lock()
+		if g.schedules[name] == nil {
+			g.mutex.Unlock()
+			http.Error(w, "room has no schedule", http.StatusConflict)
+			return
+		}
+		g.forcedOpen[name] = now.Add(d)
+		availability := g.availability(name, now)
+		g.mutex.Unlock()
+		log.Printf("Room %s forced open until %s", name, now.Add(d).Format(time.RFC3339))
+		writeJSON(w, http.StatusOK, availability)
+
+	case http.MethodDelete:
+		g.mutex.Lock()
+		delete(g.forcedOpen, name)
+		// A force-open ended before the next check would otherwise find the
+		// room still past its last grace period and close it unwarned
+		if rm := g.rooms[name]; rm != nil && !rm.closesAt.IsZero() && !now.Before(rm.closesAt) {
+			rm.closesAt = time.Time{}
+		}
+		availability := g.availability(name, now)
+		g.mutex.Unlock()
+		log.Printf("Room %s force-open ended", name)
+		writeJSON(w, http.StatusOK, availability)
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
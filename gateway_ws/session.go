@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Resume defaults
+const (
+	defaultResumeWindow = 30 * time.Second
+	defaultReplayFrames = 256
+	defaultReplayBytes  = 1 << 20
+)
+
+// session is a client identity that outlives its connection. When the
+// connection drops the session is detached and buffers the broadcasts the
+// client misses; a reconnect presenting the session's token within the
+// resume window re-attaches to it and replays them.
+type session struct {
+	id    string
+	token string
+
+	// Attached client, nil while detached
+	client *Client
+
+	// When a detached session is garbage collected
+	expires time.Time
+
+	// Frames missed while detached, oldest first. Each holds a frame
+	// reference.
+	replay      []outbound
+	replayBytes int
+
+	// Broadcast sequence number of the last frame offered to the session
+	// while detached
+	replaySeq uint64
+}
+
+// sessionStore tracks the hub's resumable sessions. A nil store disables
+// resumption.
+type sessionStore struct {
+	window    time.Duration
+	maxFrames int
+	maxBytes  int
+
+	mutex    sync.Mutex
+	byToken  map[string]*session
+	detached map[*session]bool
+
+	// Number of detached sessions, so the broadcast path can skip the lock
+	// when there are none
+	detachedCount atomic.Int32
+
+	// Sessions successfully resumed
+	resumed atomic.Uint64
+}
+
+func newSessionStore(window time.Duration, maxFrames, maxBytes int) *sessionStore {
+	return &sessionStore{
+		window:    window,
+		maxFrames: maxFrames,
+		maxBytes:  maxBytes,
+		byToken:   make(map[string]*session),
+		detached:  make(map[*session]bool),
+	}
+}
+
+// newToken returns a random, URL-safe resume token
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// open starts a new session for the client and hands it the resume token
+func (s *sessionStore) open(client *Client) {
+	if s == nil {
+		return
+	}
+	sess := &session{id: client.id, token: newToken(), client: client}
+
+	s.mutex.Lock()
+	s.byToken[sess.token] = sess
+	s.mutex.Unlock()
+
+	client.session = sess
+	client.resumeToken = sess.token
+}
+
+// claim re-attaches the session for client.resumeToken to the client and
+// gives the client the session's ID. It returns the previous client if it is
+// still attached, and the frames buffered while detached, now owned by the
+// caller. ok is false if the token is unknown or expired. The caller must
+// follow up with either rotate or abandon.
+func (s *sessionStore) claim(client *Client, now time.Time) (previous *Client, replay []outbound, ok bool) {
+	if s == nil || client.resumeToken == "" {
+		return nil, nil, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess := s.byToken[client.resumeToken]
+	if sess == nil {
+		return nil, nil, false
+	}
+	if sess.client == nil && !now.Before(sess.expires) {
+		s.discard(sess)
+		return nil, nil, false
+	}
+
+	previous = sess.client
+	if previous != nil {
+		// Starts the resume window should the claim be abandoned
+		sess.expires = now.Add(s.window)
+	}
+	replay = sess.replay
+	sess.replay, sess.replayBytes = nil, 0
+	if s.detached[sess] {
+		delete(s.detached, sess)
+		s.detachedCount.Add(-1)
+	}
+	sess.client = client
+
+	client.id = sess.id
+	client.session = sess
+	client.replayedThrough = sess.replaySeq
+	return previous, replay, true
+}
+
+// rotate completes a claim: the session takes the client's final ID and a
+// fresh token, so each token resumes at most once
+func (s *sessionStore) rotate(client *Client) {
+	sess := client.session
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.byToken, sess.token)
+	sess.id = client.id
+	sess.token = newToken()
+	s.byToken[sess.token] = sess
+	client.resumeToken = sess.token
+	s.resumed.Add(1)
+}
+
+// abandon undoes a claim whose registration was refused, detaching the
+// session again with its replay buffer and token intact
+func (s *sessionStore) abandon(client *Client, replay []outbound) {
+	sess := client.session
+	if sess == nil {
+		return
+	}
+	client.session = nil
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sess.client != client {
+		return
+	}
+	sess.client = nil
+	for _, message := range replay {
+		sess.replay = append(sess.replay, message)
+		sess.replayBytes += len(message.frame.data)
+	}
+	s.detached[sess] = true
+	s.detachedCount.Add(1)
+}
+
+// detach marks the client's session as waiting for a reconnect, or ends it
+// if the reason means the client should not come back. It does nothing if
+// another connection has already claimed the session.
+func (s *sessionStore) detach(client *Client, reason closeReason, now time.Time) {
+	if s == nil {
+		return
+	}
+	sess := client.session
+	if sess == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sess.client != client {
+		return
+	}
+	if !reason.resumable() {
+		s.discard(sess)
+		return
+	}
+	sess.client = nil
+	sess.id = client.id
+	sess.expires = now.Add(s.window)
+	s.detached[sess] = true
+	s.detachedCount.Add(1)
+}
+
+// buffer appends the broadcast to every detached session other than the
+// sender's, discarding the oldest frames beyond the replay caps
+func (s *sessionStore) buffer(message BroadcastMessage) {
+	if s == nil || s.detachedCount.Load() == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for sess := range s.detached {
+		if message.sender != nil && message.sender.session == sess {
+			continue
+		}
+		message.frame.retain()
+		sess.replay = append(sess.replay, message.outbound())
+		sess.replayBytes += len(message.frame.data)
+		sess.replaySeq = message.seq
+
+		for len(sess.replay) > s.maxFrames || (len(sess.replay) > 1 && sess.replayBytes > s.maxBytes) {
+			oldest := sess.replay[0]
+			sess.replay[0] = outbound{}
+			sess.replay = sess.replay[1:]
+			sess.replayBytes -= len(oldest.frame.data)
+			oldest.release()
+		}
+	}
+}
+
+// expire garbage collects detached sessions whose resume window has passed
+func (s *sessionStore) expire(now time.Time) int {
+	if s == nil {
+		return 0
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	expired := 0
+	for sess := range s.detached {
+		if !now.Before(sess.expires) {
+			s.discard(sess)
+			expired++
+		}
+	}
+	return expired
+}
+
+// clear discards every session
+func (s *sessionStore) clear() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, sess := range s.byToken {
+		s.discard(sess)
+	}
+}
+
+// discard forgets the session and releases its buffered frames. The caller
+// must hold the mutex.
+func (s *sessionStore) discard(sess *session) {
+	delete(s.byToken, sess.token)
+	if s.detached[sess] {
+		delete(s.detached, sess)
+		s.detachedCount.Add(-1)
+	}
+	for _, message := range sess.replay {
+		message.release()
+	}
+	sess.replay, sess.replayBytes = nil, 0
+	sess.client = nil
+}
+
+// pending returns the number of detached sessions
+func (s *sessionStore) pending() int {
+	if s == nil {
+		return 0
+	}
+	return int(s.detachedCount.Load())
+}
+
+// resumes returns the number of sessions resumed so far
+func (s *sessionStore) resumes() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.resumed.Load()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// buffered returns how many frames the hub's detached sessions hold
+func buffered(s *sessionStore) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	n := 0
+	for sess := range s.detached {
+		n += len(sess.replay)
+	}
+	return n
+}
+
+// A resumed client whose backlog overflows its send buffer gets what fits
+// behind the join ack, and the room goes on: the hub must not block on a
+// buffer whose writePump isn't running yet
+func TestResumeWithFullBacklog(t *testing.T) {
+	tg := newTestGateway(t, []HubOption{WithResume(time.Minute, 64, 1<<20)})
+	listener, resp := tg.dial(t, "/ws?send_buffer=4", "l")
+	token := resp.Header.Get("X-Resume-Token")
+	readControl(t, listener, "joined")
+	talker, _ := tg.dial(t, "/ws", "t")
+	readControl(t, talker, "joined")
+	hub := tg.hub(t, defaultRoom)
+
+	listener.UnderlyingConn().Close()
+	waitFor(t, "the listener's session to detach", func() bool { return hub.sessions.pending() == 1 })
+	for i := 0; i < 10; i++ {
+		if err := talker.WriteMessage(websocket.BinaryMessage, pcmFrame(byte(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the missed frames to be buffered", func() bool { return buffered(hub.sessions) == 10 })
+
+	resumed, resp := tg.dial(t, "/ws?send_buffer=4&resume_token="+token, "l")
+	if resp.Header.Get("X-Session-Resumed") != "true" {
+		t.Fatalf("session not resumed")
+	}
+	if messageType, data := readMessage(t, resumed); messageType != websocket.TextMessage || !bytes.Contains(data, []byte(`"joined"`)) {
+		t.Fatalf("first message is %s, want the join ack", data)
+	}
+	for i := 0; i < 3; i++ {
+		if frame := readAudio(t, resumed); frame[0] != byte(i) {
+			t.Fatalf("replayed frame %d is frame %d", i, frame[0])
+		}
+	}
+	if dropped := tg.clientStats(t, "l").DroppedOutbound; dropped != 7 {
+		t.Errorf("dropped %d replayed frames, want 7", dropped)
+	}
+
+	// The room still relays and registers
+	if err := talker.WriteMessage(websocket.BinaryMessage, pcmFrame(0xaa)); err != nil {
+		t.Fatal(err)
+	}
+	if frame := readAudio(t, resumed); frame[0] != 0xaa {
+		t.Fatalf("got frame %d after the replay, want the live one", frame[0])
+	}
+	other, _ := tg.dial(t, "/ws", "o")
+	readControl(t, other, "joined")
+}
+
+// A client that reconnects with its resume token within the window gets its
+// session back, ID and missed frames included, as does one whose old
+// connection hasn't noticed it dropped yet; past the window the session is
+// collected and the token starts a fresh one
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		// Whether the old connection drops before the reconnect, or is
+		// still registered when it comes in
+		drop    bool
+		resumed bool
+	}{
+		{"within the window", time.Minute, true, true},
+		{"after expiry", 100 * time.Millisecond, true, false},
+		{"old connection lingering", time.Minute, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGateway(t, []HubOption{WithResume(tt.window, 64, 1<<20)})
+			old, resp := tg.dial(t, "/ws", "l")
+			token := resp.Header.Get("X-Resume-Token")
+			if token == "" {
+				t.Fatal("no resume token issued")
+			}
+			readControl(t, old, "joined")
+			talker, _ := tg.dial(t, "/ws", "t")
+			readControl(t, talker, "joined")
+			hub := tg.hub(t, defaultRoom)
+
+			missed := 0
+			if tt.drop {
+				old.UnderlyingConn().Close()
+				waitFor(t, "the session to detach", func() bool { return hub.sessions.pending() == 1 })
+				for ; missed < 3; missed++ {
+					if err := talker.WriteMessage(websocket.BinaryMessage, pcmFrame(byte(missed))); err != nil {
+						t.Fatal(err)
+					}
+				}
+				waitFor(t, "the missed frames to be buffered", func() bool { return buffered(hub.sessions) == missed })
+			}
+			if !tt.resumed {
+				waitFor(t, "the session to be collected", func() bool { return hub.sessions.pending() == 0 })
+			}
+
+			// Under another ID, which the session overrides
+			conn, resp := tg.dial(t, "/ws?resume_token="+token, "other")
+			if got := resp.Header.Get("X-Session-Resumed") == "true"; got != tt.resumed {
+				t.Fatalf("resumed %t, want %t", got, tt.resumed)
+			}
+			if next := resp.Header.Get("X-Resume-Token"); next == "" || next == token {
+				t.Errorf("resume token %q not replaced", next)
+			}
+			readControl(t, conn, "joined")
+			if !tt.resumed {
+				if hub.lookup("other") == nil {
+					t.Error("fresh session not under the client's own ID")
+				}
+				return
+			}
+			if hub.lookup("l") == nil || hub.lookup("other") != nil {
+				t.Error("resumed session lost its ID")
+			}
+			for i := 0; i < missed; i++ {
+				if frame := readAudio(t, conn); frame[0] != byte(i) {
+					t.Fatalf("replayed frame %d is frame %d", i, frame[0])
+				}
+			}
+			if !tt.drop {
+				if code := expectClosed(t, old); code != CloseTakenOver {
+					t.Errorf("old connection closed with %d, want %d", code, CloseTakenOver)
+				}
+			}
+			waitFor(t, "the room to settle", func() bool { return hub.ClientCount() == 2 })
+
+			// Each token resumes once
+			again, resp := tg.dial(t, "/ws?resume_token="+token, "again")
+			if resp.Header.Get("X-Session-Resumed") == "true" {
+				t.Error("spent token resumed the session again")
+			}
+			readControl(t, again, "joined")
+		})
+	}
+}
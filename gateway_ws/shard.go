@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sync"
+)
+
+const defaultShards = 1
+
+// shard holds a subset of a hub's clients. Each shard has its own lock, so
+// adding or removing a client never contends with the other shards.
+type shard struct {
+	mutex   sync.RWMutex
+	clients map[*Client]bool
+}
+
+func newShard() *shard {
+	return &shard{clients: make(map[*Client]bool)}
+}
+
+// add inserts the client
+func (s *shard) add(client *Client) {
+	s.mutex.Lock()
+	s.clients[client] = true
+	s.mutex.Unlock()
+}
+
+// remove deletes the client and reports whether it was present
+func (s *shard) remove(client *Client) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.clients[client]; !ok {
+		return false
+	}
+	delete(s.clients, client)
+	return true
+}
+
+// has reports whether the shard holds the client
+func (s *shard) has(client *Client) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.clients[client]
+}
+
+// drain empties the shard and returns the clients it held
+func (s *shard) drain() map[*Client]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clients := s.clients
+	s.clients = make(map[*Client]bool)
+	return clients
+}
+
+// len returns the number of clients in the shard
+func (s *shard) len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.clients)
+}
+
+// each calls fn for every client under the read lock. fn must not add or
+// remove clients.
+func (s *shard) each(fn func(*Client)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for client := range s.clients {
+		fn(client)
+	}
+}
+
+// send delivers a message to one client, provided it is still in the shard;
+// holding the read lock keeps its send channel open. It reports false if the
+// client is gone or the message was not queued.
+func (s *shard) send(h *Hub, client *Client, message outbound) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.clients[client] {
+		return false
+	}
+	return h.deliver(client, message)
+}
+
+// broadcast delivers the message to every client in the shard except the
+// sender and returns the clients that should be evicted as slow consumers
+func (s *shard) broadcast(h *Hub, message BroadcastMessage) []*Client {
+	var slow []*Client
+	out := message.outbound()
+	s.each(func(client *Client) {
+		// Don't send the message back to the sender
+		if client == message.sender {
+			return
+		}
+		// A resumed client already had this frame replayed
+		if message.seq <= client.replayedThrough {
+			return
+		}
+		if client.filters(out, message.sender) {
+			return
+		}
+		// End of transmission markers go with talk messages
+		if out.eot && !client.caps.has(capTalk) {
+			return
+		}
+		// A talker in a tick of mixed audio gets the mix without its own
+		out := out
+		if frame, ok := message.mix[client]; ok {
+			if frame == nil {
+				return
+			}
+			out.frame = frame
+		}
+		// A late joiner gets the live audio once its replay is through
+		if client.holding(out) {
+			return
+		}
+		if !h.deliver(client, out) {
+			slow = append(slow, client)
+		}
+	})
+	return slow
+}
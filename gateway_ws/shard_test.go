@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmarkFanOut measures broadcasting a 20ms frame to clients in-memory
+// listeners, until every one of them has it
+func benchmarkFanOut(b *testing.B, clients int, opts ...HubOption) {
+	th := newTestHub(b, append([]HubOption{WithSendBuffer(1024, 1024)}, opts...)...)
+	th.attach(clients, SlowDropNewest)
+	sender := &Client{id: "talker", hub: th.Hub}
+	payload := pcmFrame(1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		th.broadcast(sender, payload)
+	}
+	want := int64(b.N) * int64(clients)
+	for th.handled() < want {
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(clients), "ns/delivery")
+}
+
+// 10k listeners in 1 shard against 8, each with a worker of its own
+func BenchmarkShards(b *testing.B) {
+	for _, shards := range []int{1, 8} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkFanOut(b, 10000, WithShards(shards), WithWorkers(shards))
+		})
+	}
+}
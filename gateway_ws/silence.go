@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// A room may drop the silence clients that transmit continuously send, so
+// it doesn't cost every listener bandwidth. A frame whose RMS level is
+// below the room's threshold is dropped, unless its sender's last loud
+// frame came within the hangover, which keeps word endings from being cut.
+// The level is read off the samples of pcm16 audio; for another codec the
+// gateway trusts the level the sender declares with the frame, and relays
+// a frame that declares none. Suppression is off unless a room's config or
+// -silence-threshold turns it on, and costs a room without it one atomic
+// load a frame.
+const defaultSilenceHangover = 200 * time.Millisecond
+
+// SilenceSuppression drops audio quieter than Threshold, in dBFS, except
+// within Hangover, a Go duration such as "200ms", of the sender's last
+// louder frame. A zero Threshold is no suppression.
+type SilenceSuppression struct {
+	Threshold float64 `json:"threshold,omitempty"`
+	Hangover  string  `json:"hangover,omitempty"`
+}
+
+// silenceSuppression is a compiled SilenceSuppression
+type silenceSuppression struct {
+	threshold float64
+	hangover  time.Duration
+}
+
+// compile parses the suppression's hangover. One without a Threshold
+// compiles to nil.
+func (s SilenceSuppression) compile() (*silenceSuppression, error) {
+	if s.Threshold == 0 {
+		return nil, nil
+	}
+	if s.Threshold > 0 {
+		return nil, errors.New("silence_suppression threshold must be below 0 dBFS")
+	}
+	compiled := &silenceSuppression{threshold: s.Threshold, hangover: defaultSilenceHangover}
+	if s.Hangover != "" {
+		var err error
+		if compiled.hangover, err = time.ParseDuration(s.Hangover); err != nil || compiled.hangover < 0 {
+			return nil, errors.New("silence_suppression hangover must not be a negative duration")
+		}
+	}
+	return compiled, nil
+}
+
+// validate checks a suppression given on the command line or to the room
+// admin API
+func (s SilenceSuppression) validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// WithSilenceSuppression sets the room's silence suppression. The room
+// admin API may change it while the hub runs.
+func WithSilenceSuppression(suppression SilenceSuppression) HubOption {
+	return func(h *Hub) {
+		h.setSilenceSuppression(suppression)
+	}
+}
+
+// setSilenceSuppression replaces the room's silence suppression, which
+// must compile
+func (h *Hub) setSilenceSuppression(suppression SilenceSuppression) {
+	compiled, err := suppression.compile()
+	if err != nil {
+		log.Printf("Ignoring the silence suppression of room %s: %v", h.room, err)
+		return
+	}
+	h.silence.Store(compiled)
+}
+
+// WithRoomSilenceSuppression sets the silence suppression of rooms whose
+// config sets none
+func WithRoomSilenceSuppression(suppression SilenceSuppression) GatewayOption {
+	return func(g *Gateway) {
+		g.silence = suppression
+	}
+}
+
+// silenceFor returns the named room's silence suppression. The caller must
+// hold the gateway's mutex.
+func (g *Gateway) silenceFor(name string) SilenceSuppression {
+	if suppression := g.roomConfigs[name].SilenceSuppression; suppression != nil {
+		return *suppression
+	}
+	return g.silence
+}
+
+// updateSilence brings an open room's silence suppression in line with its
+// config. The caller must hold the mutex.
+func (g *Gateway) updateSilence(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.setSilenceSuppression(g.silenceFor(name))
+	}
+}
+
+// audible reports whether an audio frame the client sent its room at now
+// is loud enough for the room's silence suppression, or comes within the
+// hangover of one that was, counting those it drops. A dropped frame still
+// shows the connection is there. Only the readPump of the client's
+// connection may call it.
+func (c *Client) audible(frame *frameBuffer, now time.Time) bool {
+	suppression := c.hub.silence.Load()
+	if suppression == nil {
+		return true
+	}
+	if level, known := c.frameLevel(frame); !known || level >= suppression.threshold {
+		c.loudAt = now
+		return true
+	}
+	if now.Sub(c.loudAt) < suppression.hangover {
+		return true
+	}
+	c.touch()
+	c.silenceSuppressed.Add(1)
+	c.hub.silenceSuppressed.Add(1)
+	return false
+}
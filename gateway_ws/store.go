@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RoomConfig is a room's settings. Rooms without one use the gateway's
+// defaults and are open to anyone.
+type RoomConfig struct {
+	Name string `json:"name"`
+
+	// Participant cap, overriding the gateway default when set. Zero means
+	// no cap.
+	Capacity *int `json:"capacity,omitempty"`
+
+	// Join credential: a shared secret, or a signed join token. At most one
+	// may be set.
+	Secret       string `json:"secret,omitempty"`
+	RequireToken bool   `json:"require_token,omitempty"`
+
+	// Secret that admits clients as moderators
+	ModeratorSecret string `json:"moderator_secret,omitempty"`
+
+	// Whether the room's audio is recorded
+	Record bool `json:"record,omitempty"`
+
+	// Audio each client may send, overriding the gateway default when set
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+
+	// How long each client may talk without a break, overriding the
+	// gateway default when set
+	TalkLimit *TalkLimit `json:"talk_limit,omitempty"`
+
+	// What audio is dropped as silence, overriding the gateway default
+	// when set
+	SilenceSuppression *SilenceSuppression `json:"silence_suppression,omitempty"`
+
+	// Whether only the client holding the floor may talk, overriding the
+	// gateway default when set
+	FloorControl *bool `json:"floor_control,omitempty"`
+
+	// How many clients may wait in line for the floor, overriding the
+	// gateway default when set. Zero means no line.
+	FloorQueue *int `json:"floor_queue,omitempty"`
+
+	// Whether talkers overlapping are mixed, overriding the gateway
+	// default when set
+	Mixing *bool `json:"mixing,omitempty"`
+
+	// Whether senders' audio is paced to its frame duration, overriding
+	// the gateway default when set
+	Pacing *bool `json:"pacing,omitempty"`
+
+	// The audio format clients are told to send and the ones they may
+	// declare, overriding the gateway default when set
+	Audio *AudioPolicy `json:"audio,omitempty"`
+
+	// When the room may be joined, any time if unset
+	Schedule *RoomSchedule `json:"schedule,omitempty"`
+
+	// Whether the config is written to the room store and reloaded at
+	// startup
+	Persistent bool `json:"persistent,omitempty"`
+}
+
+// guarded reports whether the room requires a join credential
+func (c RoomConfig) guarded() bool {
+	return c.Secret != "" || c.RequireToken
+}
+
+// RoomStore keeps the configs of persistent rooms across restarts
+type RoomStore interface {
+	LoadRooms() ([]RoomConfig, error)
+	SaveRoom(config RoomConfig) error
+	DeleteRoom(name string) error
+	Close() error
+}
+
+var (
+	errRoomExists          = errors.New("room already configured")
+	errRoomNotConfigured   = errors.New("room not configured")
+	errPersistenceDisabled = errors.New("persistence is disabled; start the gateway with -room-store")
+)
+
+// roomsBucket holds one JSON RoomConfig per room name
+var roomsBucket = []byte("rooms")
+
+// boltRoomStore is a RoomStore in a bbolt file
+type boltRoomStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltRoomStore opens, or creates, the room store at path
+func OpenBoltRoomStore(path string) (RoomStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening room store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(roomsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bansBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initialising room store %s: %w", path, err)
+	}
+	return &boltRoomStore{db: db}, nil
+}
+
+func (s *boltRoomStore) LoadRooms() ([]RoomConfig, error) {
+	var configs []RoomConfig
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).ForEach(func(name, data []byte) error {
+			var config RoomConfig
+			if err := json.Unmarshal(data, &config); err != nil {
+				return fmt.Errorf("room %s: %w", name, err)
+			}
+			configs = append(configs, config)
+			return nil
+		})
+	})
+	return configs, err
+}
+
+func (s *boltRoomStore) SaveRoom(config RoomConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).Put([]byte(config.Name), data)
+	})
+}
+
+func (s *boltRoomStore) DeleteRoom(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).Delete([]byte(name))
+	})
+}
+
+func (s *boltRoomStore) LoadBans() ([]Ban, error) {
+	var bans []Ban
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).ForEach(func(id, data []byte) error {
+			var ban Ban
+			if err := json.Unmarshal(data, &ban); err != nil {
+				return fmt.Errorf("ban %s: %w", id, err)
+			}
+			bans = append(bans, ban)
+			return nil
+		})
+	})
+	return bans, err
+}
+
+func (s *boltRoomStore) SaveBan(ban Ban) error {
+	data, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Put([]byte(ban.BanID), data)
+	})
+}
+
+func (s *boltRoomStore) DeleteBan(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Delete([]byte(id))
+	})
+}
+
+func (s *boltRoomStore) Close() error {
+	return s.db.Close()
+}
+
+// WithRoomStore persists the configs of rooms flagged persistent in store,
+// and bans if it keeps them too. Without one, persistence is disabled.
+func WithRoomStore(store RoomStore) GatewayOption {
+	return func(g *Gateway) {
+		g.store = store
+		if bans, ok := store.(BanStore); ok {
+			g.banStore = bans
+		}
+	}
+}
+
+// LoadRooms applies the configs in the room store, which take precedence
+// over the ones set by options
+func (g *Gateway) LoadRooms() error {
+	if g.store == nil {
+		return nil
+	}
+	configs, err := g.store.LoadRooms()
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	for _, config := range configs {
+		g.applyRoomConfig(config)
+	}
+	log.Printf("Loaded %d persistent rooms", len(configs))
+	return nil
+}
+
+// configureRoom edits the named room's config in place. Only options may
+// call it, before the gateway is shared.
+func (g *Gateway) configureRoom(name string, edit func(*RoomConfig)) {
+	config := g.roomConfigs[name]
+	config.Name = name
+	edit(&config)
+	g.roomConfigs[name] = config
+}
+
+// roomConfig returns the named room's config, or the zero config with just
+// its name
+func (g *Gateway) roomConfig(name string) RoomConfig {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	config, ok := g.roomConfigs[name]
+	if !ok {
+		config.Name = name
+	}
+	return config
+}
+
+// validate checks a config the room admin API was given
+func (g *Gateway) validate(config RoomConfig) error {
+	switch {
+	case config.Capacity != nil && *config.Capacity < 0:
+		return errors.New("capacity must not be negative")
+	case config.FloorQueue != nil && *config.FloorQueue < 0:
+		return errors.New("floor_queue must not be negative")
+	case config.Secret != "" && config.RequireToken:
+		return errors.New("secret and require_token are mutually exclusive")
+	case config.RequireToken && len(g.joinKey) == 0:
+		return errors.New("require_token needs the gateway started with -join-token-key")
+	case config.Persistent && g.store == nil:
+		return errPersistenceDisabled
+	case config.Record && g.recording.dir == "":
+		return errRecordingDisabled
+	}
+	if config.Schedule != nil {
+		if _, err := compileSchedule(*config.Schedule); err != nil {
+			return err
+		}
+	}
+	if config.RateLimit != nil {
+		if err := config.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+	if config.TalkLimit != nil {
+		if err := config.TalkLimit.validate(); err != nil {
+			return err
+		}
+	}
+	if config.SilenceSuppression != nil {
+		if err := config.SilenceSuppression.validate(); err != nil {
+			return err
+		}
+	}
+	if config.Audio != nil {
+		if err := config.Audio.validate(); err != nil {
+			return err
+		}
+		if g.recording.format == recordWAV && config.Audio.Codec != defaultCodec {
+			return fmt.Errorf("rooms are recorded as wav, which needs %s audio, not %s", defaultCodec, config.Audio.Codec)
+		}
+	}
+	if config.Mixing != nil && *config.Mixing {
+		audio := g.audio
+		if config.Audio != nil {
+			audio = *config.Audio
+		}
+		if audio.Codec != defaultCodec {
+			return errMixingCodec
+		}
+	}
+	return nil
+}
+
+// putRoomConfig sets the room's config, which must have passed validate,
+// writing it through to the store if it is persistent and refusing to
+// replace an existing one unless replace is set. The mutex is held across
+// the store write so the store and memory agree however admin calls
+// interleave.
+func (g *Gateway) putRoomConfig(config RoomConfig, replace bool) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	previous, exists := g.roomConfigs[config.Name]
+	if exists && !replace {
+		return errRoomExists
+	}
+	switch {
+	case config.Persistent:
+		if err := g.store.SaveRoom(config); err != nil {
+			return err
+		}
+	case previous.Persistent && g.store != nil:
+		if err := g.store.DeleteRoom(config.Name); err != nil {
+			return err
+		}
+	}
+	g.applyRoomConfig(config)
+	return nil
+}
+
+// deleteRoomConfig drops the room's config, so it reverts to the gateway's
+// defaults, and removes it from the store
+func (g *Gateway) deleteRoomConfig(name string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	previous, exists := g.roomConfigs[name]
+	if !exists {
+		return errRoomNotConfigured
+	}
+	if previous.Persistent && g.store != nil {
+		if err := g.store.DeleteRoom(name); err != nil {
+			return err
+		}
+	}
+	delete(g.roomConfigs, name)
+	g.updateCapacity(name)
+	g.updateRecording(name)
+	g.updateSchedule(name)
+	g.updateRateLimit(name)
+	g.updateTalkLimit(name)
+	g.updateSilence(name)
+	g.updateFloorControl(name)
+	g.updateMixing(name)
+	g.updatePacing(name)
+	g.updateAudio(name)
+	return nil
+}
+
+// applyRoomConfig installs the config and updates the room's hub if it is
+// open. The caller must hold the mutex.
+func (g *Gateway) applyRoomConfig(config RoomConfig) {
+	g.roomConfigs[config.Name] = config
+	g.updateCapacity(config.Name)
+	g.updateRecording(config.Name)
+	g.updateSchedule(config.Name)
+	g.updateRateLimit(config.Name)
+	g.updateTalkLimit(config.Name)
+	g.updateSilence(config.Name)
+	g.updateFloorControl(config.Name)
+	g.updateMixing(config.Name)
+	g.updatePacing(config.Name)
+	g.updateAudio(config.Name)
+}
+
+// updateCapacity brings an open room's cap in line with its config. The
+// caller must hold the mutex.
+func (g *Gateway) updateCapacity(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.capacity.Store(int64(g.capacityFor(name)))
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// successor returns a client for the same connection in another hub, which
+// takes over once the client hands off to it
+func (c *Client) successor(hub *Hub, moderator bool) *Client {
+	next := &Client{
+		conn:        c.conn,
+		send:        make(chan outbound, cap(c.send)),
+		priority:    make(chan outbound, pingBurst),
+		urgent:      make(chan outbound, maxUrgentFrames),
+		hub:         hub,
+		gateway:     c.gateway,
+		claims:      c.claims,
+		key:         c.key,
+		id:          c.id,
+		remoteIP:    c.remoteIP,
+		releaseIP:   c.releaseIP,
+		will:        c.will,
+		protocol:    c.protocol,
+		compression: c.compression,
+		readDone:    make(chan struct{}),
+		writeDone:   make(chan struct{}),
+		slowPolicy:  c.slowPolicy,
+		verifier:    c.verifier,
+		blocks:      c.blocks,
+		name:        c.name,
+		presence:    c.presence,
+		integrity:   c.integrity,
+		vox:         c.vox,
+		receive:     c.receive,
+		caps:        c.caps,
+	}
+	next.batch.Store(c.batch.Load())
+	next.moderator.Store(moderator)
+	next.listener.Store(c.listener.Load())
+	next.lowTier.Store(c.lowTier.Load())
+	next.version.Store(c.version.Load())
+	next.touch()
+	return next
+}
+
+// fail answers a control request the server refused
+func (c *Client) fail(code, request, target string) {
+	c.hub.sendControl(c, controlError{Type: "error", Code: code, Request: request, Target: target})
+}
+
+// admit registers a client made by newClient in the named room for a join
+// or leave request, subject to the room's credential, capacity and audio
+// formats, format and tags being the ones the client declares for it. The
+// new hub queues it the joined message. On failure the client gets an error and
+// admit returns nil. name is the room as the client knows it, within its
+// tenant if it has one.
+func (c *Client) admit(request, name, credential string, format audioFormat, tags []string, newClient func(hub *Hub, moderator bool) *Client) *Client {
+	g := c.gateway
+	room := c.qualify(name)
+	switch {
+	case !validRoomName(name):
+		c.fail("invalid_room", request, name)
+		return nil
+	case room == c.hub.room || c.lookupMember(room) != nil:
+		c.fail("already_joined", request, name)
+		return nil
+	case !c.claims.allows(name):
+		c.auditJoinRefused(room, "room not in token")
+		c.fail("room_not_allowed", request, name)
+		return nil
+	case g.draining.Load():
+		c.fail("unavailable", request, name)
+		return nil
+	case g.banned(c.id, c.remoteIP, time.Now()) != nil:
+		c.auditJoinRefused(room, "banned")
+		c.fail("banned", request, name)
+		return nil
+	}
+	if availability := g.roomAvailability(room, time.Now()); !availability.Open {
+		c.hub.sendControl(c, controlError{Type: "error", Code: "room_closed", Request: request, Target: name, OpensAt: availability.OpensAt})
+		return nil
+	}
+
+	moderator, reason := g.checkCredential(room, c.id, credential)
+	if reason != "" {
+		c.auditJoinRefused(room, reason)
+		c.fail("forbidden", request, name)
+		return nil
+	}
+	if c.claims != nil && c.claims.Moderator {
+		moderator = true
+	}
+	rm, err := g.joinRoom(room, c.key)
+	if err == errTooManyRooms {
+		c.fail("too_many_rooms", request, name)
+		return nil
+	}
+	if err != nil {
+		c.fail("unavailable", request, name)
+		return nil
+	}
+	defer g.joined(rm)
+	mismatch, err := rm.hub.admitFormat(format)
+	if err != nil {
+		c.hub.sendControl(c, controlError{Type: "error", Code: "unsupported_format", Request: request, Target: name, Detail: err.Error()})
+		return nil
+	}
+
+	client := newClient(rm.hub, moderator)
+	client.format, client.formatMismatch = format, mismatch
+	client.tags = tags
+	switch err := rm.hub.registerClient(client); err {
+	case nil:
+		g.auditClient("join", client, "", auditOK, "")
+		return client
+	case errRoomFull:
+		c.fail("room_full", request, name)
+	case errHubFull:
+		c.fail("server_full", request, name)
+	case errDuplicateID:
+		c.fail("duplicate_id", request, name)
+	default:
+		c.fail("unavailable", request, name)
+	}
+	return nil
+}
+
+// join and leave switch rooms, or for a multi-room client add and drop
+// memberships
+func init() {
+	registerControl("join", controlHandler{
+		validate: requireField("room", func(m controlMessage) bool { return m.Room != "" }),
+		handle: func(c *Client, m controlMessage) {
+			format := c.format
+			if m.Format != nil {
+				format = *m.Format
+			}
+			// A token's tags can't be replaced
+			tags := c.hub.tagsOf(c)
+			if m.Tags != nil && !c.claims.tagsFixed() {
+				var err error
+				if tags, err = parseTags(m.Tags); err != nil {
+					c.hub.sendControl(c, controlError{Type: "error", Code: "invalid_tags", Request: m.Type, Target: m.Room, Detail: err.Error()})
+					return
+				}
+			}
+			if c.multiRoom {
+				c.subscribe(m.Room, m.JoinToken, format, tags)
+				return
+			}
+			c.switchRoom(m.Type, m.Room, m.JoinToken, format, tags)
+		},
+	})
+	registerControl("leave", controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if c.multiRoom {
+				c.unsubscribe(m.Room)
+				return
+			}
+			c.switchRoom(m.Type, defaultRoom, m.JoinToken, c.format, c.hub.tagsOf(c))
+		},
+	})
+}
+
+// switchRoom handles a join or leave control message by registering a
+// successor in the named room, declaring format and tags. On success
+// readPump hands the connection off to it; on failure the client stays
+// where it is. Only readPump may call it.
+func (c *Client) switchRoom(request, name, credential string, format audioFormat, tags []string) {
+	next := c.admit(request, name, credential, format, tags, c.successor)
+	if next == nil {
+		return
+	}
+	log.Printf("Client %s switching from room %s to %s", c.id, c.hub.room, name)
+	c.next = next
+}
+
+// handOff leaves the client's room and, once writePump has let go of the
+// connection, starts the successor's pumps on it. Frames still queued from
+// the old room are discarded. Only readPump may call it, as it returns.
+func (c *Client) handOff() {
+	next := c.next
+	c.leave(reasonSwitched)
+	<-c.writeDone
+
+	if !c.handedOff.Load() {
+		// The client was closed for another reason before it could switch,
+		// taking the connection with it
+		next.leave(reasonLost)
+		next.hub.pumps.Done()
+		return
+	}
+	go next.writePump()
+	go next.readPump()
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Tags name the groups a client belongs to within its room, such as
+// "medic" or "north-sector", so part of the room can be addressed without a
+// room of its own. A group message naming tags addresses the client's
+// audio to every other client with any of them until a group message
+// naming none ends it, and a chat message naming tags goes to them alone.
+// Each hub indexes its clients by tag, so addressing a group costs its
+// members rather than a scan of the room.
+const (
+	// maxTags caps how many tags a client carries
+	maxTags = 32
+
+	// maxTagLength caps a tag's length
+	maxTagLength = 64
+)
+
+// validTag reports whether tag may be used: letters, digits, '-', '_', '.'
+// and ':', up to maxTagLength
+func validTag(tag string) bool {
+	if tag == "" || len(tag) > maxTagLength {
+		return false
+	}
+	for _, r := range tag {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseTags checks a list of tags and returns it sorted, without
+// duplicates
+func parseTags(tags []string) ([]string, error) {
+	parsed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !validTag(tag) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		parsed = append(parsed, tag)
+	}
+	sort.Strings(parsed)
+	unique := parsed[:0]
+	for i, tag := range parsed {
+		if i == 0 || tag != parsed[i-1] {
+			unique = append(unique, tag)
+		}
+	}
+	if len(unique) > maxTags {
+		return nil, fmt.Errorf("more than %d tags", maxTags)
+	}
+	return unique, nil
+}
+
+// requestedTags returns the tags a client declares in its upgrade's tags
+// parameter, a comma-separated list
+func requestedTags(r *http.Request) ([]string, error) {
+	value := r.URL.Query().Get("tags")
+	if value == "" {
+		return nil, nil
+	}
+	return parseTags(strings.Split(value, ","))
+}
+
+// tagsFixed reports whether the claims set the client's tags, which it then
+// can't declare its own
+func (c *tokenClaims) tagsFixed() bool {
+	return c != nil && c.Tags != nil
+}
+
+// index adds the client to the hub's tag index. Only the Run goroutine may
+// call it, as the client registers.
+func (h *Hub) index(client *Client) {
+	h.tagsMutex.Lock()
+	defer h.tagsMutex.Unlock()
+	client.indexed = true
+	h.indexTags(client)
+}
+
+// unindex removes the client from the hub's tag index
+func (h *Hub) unindex(client *Client) {
+	h.tagsMutex.Lock()
+	defer h.tagsMutex.Unlock()
+	if client.indexed {
+		h.unindexTags(client)
+		client.indexed = false
+	}
+}
+
+// indexTags adds the client under each of its tags. The caller must hold
+// the tags mutex.
+func (h *Hub) indexTags(client *Client) {
+	for _, tag := range client.tags {
+		members := h.tagged[tag]
+		if members == nil {
+			members = make(map[*Client]bool)
+			h.tagged[tag] = members
+		}
+		members[client] = true
+	}
+}
+
+// unindexTags removes the client from under each of its tags. The caller
+// must hold the tags mutex.
+func (h *Hub) unindexTags(client *Client) {
+	for _, tag := range client.tags {
+		delete(h.tagged[tag], client)
+		if len(h.tagged[tag]) == 0 {
+			delete(h.tagged, tag)
+		}
+	}
+}
+
+// setTags replaces the client's tags, reindexing it if it is registered
+func (h *Hub) setTags(client *Client, tags []string) {
+	h.tagsMutex.Lock()
+	defer h.tagsMutex.Unlock()
+	if client.indexed {
+		h.unindexTags(client)
+	}
+	client.tags = tags
+	if client.indexed {
+		h.indexTags(client)
+	}
+}
+
+// tagsOf returns the client's tags
+func (h *Hub) tagsOf(client *Client) []string {
+	h.tagsMutex.RLock()
+	defer h.tagsMutex.RUnlock()
+	return client.tags
+}
+
+// matching returns the clients with any of the tags, but not except
+func (h *Hub) matching(tags []string, except *Client) []*Client {
+	h.tagsMutex.RLock()
+	defer h.tagsMutex.RUnlock()
+	var matched []*Client
+	seen := make(map[*Client]bool)
+	for _, tag := range tags {
+		for client := range h.tagged[tag] {
+			if client != except && !seen[client] {
+				seen[client] = true
+				matched = append(matched, client)
+			}
+		}
+	}
+	return matched
+}
+
+// tagsMessage tells a client its tags changed
+type tagsMessage struct {
+	Type string   `json:"type"`
+	Room string   `json:"room"`
+	Tags []string `json:"tags"`
+}
+
+// group names the tags to address, or none to end it
+func init() {
+	registerControl("group", controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.openGroup(m.Tags)
+			}
+		},
+	})
+}
+
+// openGroup addresses the client's audio to the clients with any of the
+// tags, closing its whisper if it has one, or to its room again if there
+// are none. Only the readPump of the client's connection may call it.
+func (c *Client) openGroup(tags []string) {
+	if len(tags) == 0 {
+		c.group = nil
+		return
+	}
+	tags, err := parseTags(tags)
+	if err != nil {
+		c.hub.sendControl(c, controlError{Type: "error", Code: "invalid_tags", Request: "group", Target: localRoom(c.hub.room), Detail: err.Error()})
+		return
+	}
+	c.closeWhisper()
+	c.group = tags
+	c.gateway.debugf("Client %s addressed its audio to tags %s in room %s", c.id, strings.Join(tags, ","), c.hub.room)
+}
+
+// groupAudio sends an audio frame from the client to the clients with any
+// of its group's tags, taking over the reader's reference. Like a
+// whisper's, the frame is numbered apart from the client's audio to the
+// room. Only the readPump of the client's connection may call it.
+func (c *Client) groupAudio(frame *frameBuffer, now time.Time) {
+	defer frame.release()
+	c.addressedSeq++
+	frame.header = frameHeader{sender: c.id, seq: c.addressedSeq, received: now.UnixMilli(), captured: frame.header.captured, priority: frame.header.priority}
+	frame.receivedAt = now
+	c.transcode(frame)
+	out := outbound{messageType: frame.messageType, frame: frame}
+	for _, client := range c.hub.matching(c.group, c) {
+		if !client.filters(out, c) {
+			c.hub.shardFor(client).send(c.hub, client, out)
+		}
+	}
+	c.hub.tagAddressed.Add(1)
+}
+
+// groupChat sends a chat message from the client to the clients with any
+// of message.Tags alone, and to itself as well if it asked. Only the
+// readPump of the client's connection may call it.
+func (c *Client) groupChat(message controlMessage, now time.Time) {
+	tags, err := parseTags(message.Tags)
+	if err != nil {
+		c.hub.sendControl(c, controlError{Type: "error", Code: "invalid_tags", Request: "chat", Target: localRoom(c.hub.room), Detail: err.Error()})
+		return
+	}
+	relayed := chatMessage{Type: "chat", Room: localRoom(c.hub.room), From: c.id, Text: message.Text, TS: now.UnixMilli(), Tags: tags}
+	data, err := json.Marshal(relayed)
+	if err != nil {
+		log.Printf("Error encoding chat message from client %s: %v", c.id, err)
+		return
+	}
+	out := outbound{messageType: websocket.TextMessage, data: data}
+	for _, client := range c.hub.matching(tags, c) {
+		if client.caps.has(capChat) {
+			c.hub.shardFor(client).send(c.hub, client, out)
+		}
+	}
+	if message.Echo {
+		c.hub.shardFor(c).send(c.hub, c, out)
+	}
+	c.hub.tagAddressed.Add(1)
+}
+
+// TaggedClient is a connection whose tags the admin set
+type TaggedClient struct {
+	ID   string   `json:"id"`
+	Room string   `json:"room"`
+	Tags []string `json:"tags"`
+}
+
+// tagClient sets the tags of every connection and membership of the client
+// with the ID, or just its one in roomName if it isn't empty, and tells
+// each
+func (g *Gateway) tagClient(id, roomName string, tags []string) []TaggedClient {
+	tagged := []TaggedClient{}
+	g.eachRoom(func(rm *room) {
+		if roomName != "" && rm.name != roomName {
+			return
+		}
+		client := rm.hub.lookup(id)
+		if client == nil {
+			return
+		}
+		rm.hub.setTags(client, tags)
+		rm.hub.sendControl(client, tagsMessage{Type: "tags", Room: localRoom(rm.name), Tags: tags})
+		tagged = append(tagged, TaggedClient{ID: client.id, Room: rm.name, Tags: tags})
+	})
+	return tagged
+}
+
+// serveClientTags replaces a connected client's tags (PUT
+// /admin/clients/{id}/tags) with the body's tags, in every room it is in or
+// in one with ?room=. It answers with the connections it tagged, or 404 if
+// the client isn't connected, and is for the gateway's operator, not its
+// tenants.
+func (g *Gateway) serveClientTags(w http.ResponseWriter, r *http.Request, id string) {
+	if tenantScoped(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", "PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	room := ""
+	if name := r.URL.Query().Get("room"); name != "" {
+		var ok bool
+		room, ok = scopedRoom(r, name)
+		if !ok {
+			http.Error(w, "invalid room name", http.StatusBadRequest)
+			return
+		}
+	}
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(w, "invalid tags request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := parseTags(body.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tagged := g.tagClient(id, room, tags)
+	if len(tagged) == 0 {
+		http.Error(w, "no such client", http.StatusNotFound)
+		return
+	}
+	log.Printf("Client %s tagged %s by admin in %d rooms", id, strings.Join(tags, ","), len(tagged))
+	for _, client := range tagged {
+		g.audit.record(AuditEvent{Event: "tags", ClientID: client.ID, Room: client.Room, Actor: auditActor(r), Outcome: auditOK, Reason: strings.Join(tags, ",")})
+	}
+	writeJSON(w, http.StatusOK, tagged)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// defaultTalkSilence is the gap in a client's audio that ends its talk burst
+const defaultTalkSilence = time.Second
+
+// TalkLimit caps how long a client may transmit without a break, so a stuck
+// push-to-talk button can't hold a room. A burst is frames arriving without
+// a gap longer than Silence; one longer than MaxBurst is cut off, and the
+// client may then not transmit again until Cooldown after it stops. The
+// fields are Go durations such as "60s". An empty MaxBurst is no limit.
+type TalkLimit struct {
+	MaxBurst string `json:"max_burst,omitempty"`
+	Silence  string `json:"silence,omitempty"`
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// talkLimit is a compiled TalkLimit
+type talkLimit struct {
+	maxBurst time.Duration
+	silence  time.Duration
+	cooldown time.Duration
+}
+
+// compile parses the limit's durations. A limit without a MaxBurst compiles
+// to nil.
+func (l TalkLimit) compile() (*talkLimit, error) {
+	if l.MaxBurst == "" {
+		return nil, nil
+	}
+	compiled := &talkLimit{silence: defaultTalkSilence}
+	var err error
+	if compiled.maxBurst, err = time.ParseDuration(l.MaxBurst); err != nil || compiled.maxBurst <= 0 {
+		return nil, errors.New("talk_limit max_burst must be a positive duration")
+	}
+	if l.Silence != "" {
+		if compiled.silence, err = time.ParseDuration(l.Silence); err != nil || compiled.silence <= 0 {
+			return nil, errors.New("talk_limit silence must be a positive duration")
+		}
+	}
+	if l.Cooldown != "" {
+		if compiled.cooldown, err = time.ParseDuration(l.Cooldown); err != nil || compiled.cooldown < 0 {
+			return nil, errors.New("talk_limit cooldown must not be a negative duration")
+		}
+	}
+	return compiled, nil
+}
+
+// validate checks a limit given on the command line or to the room admin API
+func (l TalkLimit) validate() error {
+	_, err := l.compile()
+	return err
+}
+
+// talkBurst is a client's current transmission under its room's talk limit.
+// Only the readPump of the client's connection touches it.
+type talkBurst struct {
+	limit *talkLimit
+
+	// When the burst began and its last frame arrived, whether it was cut
+	// off, and when the cooldown after a cut-off burst ends
+	start   time.Time
+	last    time.Time
+	cut     bool
+	coolEnd time.Time
+}
+
+// Outcomes of checking a frame against the talk limit
+type talkVerdict int
+
+const (
+	talkAllowed talkVerdict = iota
+	// Dropped: the burst was cut off, or the client is cooling down
+	talkDropped
+	// Dropped, and this frame's burst is cut off now
+	talkCutOff
+)
+
+// check places a frame arriving at now in the client's burst. A new limit
+// starts afresh.
+func (b *talkBurst) check(limit *talkLimit, now time.Time) talkVerdict {
+	if limit == nil {
+		return talkAllowed
+	}
+	if b.limit != limit {
+		*b = talkBurst{limit: limit}
+	}
+	if b.last.IsZero() || now.Sub(b.last) > limit.silence {
+		if b.cut {
+			// The cut-off burst is over; the cooldown runs from its end
+			b.cut = false
+			b.coolEnd = b.last.Add(limit.cooldown)
+		}
+		b.start = now
+	}
+	b.last = now
+
+	switch {
+	case b.cut || now.Before(b.coolEnd):
+		return talkDropped
+	case now.Sub(b.start) > limit.maxBurst:
+		b.cut = true
+		return talkCutOff
+	}
+	return talkAllowed
+}
+
+// coolsAt returns the earliest the client may transmit again after a burst
+// that was cut off, as far as the frames so far tell
+func (b *talkBurst) coolsAt() time.Time {
+	if !b.cut {
+		return b.coolEnd
+	}
+	wait := b.limit.cooldown
+	if wait < b.limit.silence {
+		wait = b.limit.silence
+	}
+	return b.last.Add(wait)
+}
+
+// coolingDown reports whether the client is still waiting out the room's
+// talk limit at now, after one of its bursts was cut off
+func (c *Client) coolingDown(now time.Time) bool {
+	return c.hub.talkLimit.Load() != nil && now.UnixNano() < c.coolsAt.Load()
+}
+
+// talkCutOffMessage tells a client its transmission was cut off for going
+// on too long, and how long it must then wait before transmitting again
+type talkCutOffMessage struct {
+	Type            string  `json:"type"`
+	Room            string  `json:"room"`
+	MaxBurstSeconds float64 `json:"max_burst_seconds"`
+	CooldownSeconds float64 `json:"cooldown_seconds"`
+}
+
+// talkLimited checks an audio frame the client is sending to its room
+// against the room's talk limit, and reports whether it may be broadcast
+func (c *Client) talkLimited() bool {
+	limit := c.hub.talkLimit.Load()
+	verdict := c.talk.check(limit, time.Now())
+	if verdict != talkAllowed {
+		c.coolsAt.Store(c.talk.coolsAt().UnixNano())
+	}
+	switch verdict {
+	case talkAllowed:
+		return false
+	case talkDropped:
+		return true
+	}
+	c.talkCutoffs.Add(1)
+	c.hub.talkCutoffs.Add(1)
+	log.Printf("Client %s cut off after transmitting for %s in room %s", c.id, limit.maxBurst, c.hub.room)
+	c.hub.sendControl(c, talkCutOffMessage{
+		Type:            "transmission_cut_off",
+		Room:            localRoom(c.hub.room),
+		MaxBurstSeconds: limit.maxBurst.Seconds(),
+		CooldownSeconds: limit.cooldown.Seconds(),
+	})
+	return true
+}
+
+// WithTalkLimit caps each client's talk bursts in the hub's room. The room
+// admin API may change it while the hub runs.
+func WithTalkLimit(limit TalkLimit) HubOption {
+	return func(h *Hub) {
+		h.setTalkLimit(limit)
+	}
+}
+
+// setTalkLimit replaces the room's talk limit, which must compile. Clients'
+// bursts start over under the new one.
+func (h *Hub) setTalkLimit(limit TalkLimit) {
+	compiled, err := limit.compile()
+	if err != nil {
+		log.Printf("Ignoring the talk limit of room %s: %v", h.room, err)
+		return
+	}
+	current := h.talkLimit.Load()
+	switch {
+	case compiled == nil:
+		h.talkLimit.Store(nil)
+	case current == nil || *current != *compiled:
+		h.talkLimit.Store(compiled)
+	}
+}
+
+// WithRoomTalkLimit caps each client's talk bursts in a room, unless its
+// config sets a limit of its own
+func WithRoomTalkLimit(limit TalkLimit) GatewayOption {
+	return func(g *Gateway) {
+		g.talkLimit = limit
+	}
+}
+
+// talkLimitFor returns the named room's talk limit. The caller must hold
+// the gateway's mutex.
+func (g *Gateway) talkLimitFor(name string) TalkLimit {
+	if limit := g.roomConfigs[name].TalkLimit; limit != nil {
+		return *limit
+	}
+	return g.talkLimit
+}
+
+// updateTalkLimit brings an open room's talk limit in line with its config.
+// The caller must hold the mutex.
+func (g *Gateway) updateTalkLimit(name string) {
+	if rm := g.rooms[name]; rm != nil {
+		rm.hub.setTalkLimit(g.talkLimitFor(name))
+	}
+}
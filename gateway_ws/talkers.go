@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The room hears when a client starts and stops talking, so a push-to-talk
+// UI can light up the speaker's name. A client's first audio frame to the
+// room after a gap of the room's talk silence starts its burst, and a timer
+// ends it once the client has sent nothing for that long, whether or not
+// anyone else is talking. Whispers and audio addressed to tags aren't
+// announced. A frame in a running burst only moves its end, so detection
+// costs the fan-out no allocations.
+const (
+	talkStart = "start"
+	talkStop  = "stop"
+)
+
+// WithTalkSilence sets the gap in a client's audio that ends its burst for
+// talk messages
+func WithTalkSilence(d time.Duration) HubOption {
+	return func(h *Hub) {
+		if d > 0 {
+			h.talkSilence = d
+		}
+	}
+}
+
+// talkMessage tells the room a client started or stopped talking
+type talkMessage struct {
+	Type  string `json:"type"`
+	Room  string `json:"room"`
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+
+// speaking tracks a client's talk burst for talk messages. The client's
+// readPump starts it and its timer ends it, unless it releases the floor or
+// leaves first. Seq is the burst's last frame's.
+type speaking struct {
+	mutex  sync.Mutex
+	active bool
+	last   time.Time
+	seq    uint64
+	timer  *time.Timer
+}
+
+// spoke records audio frame seq the client sent its room at now, starting
+// its burst if it isn't talking. Only the readPump of the client's
+// connection may call it.
+func (c *Client) spoke(now time.Time, seq uint64) {
+	s := &c.speaking
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.last = now
+	s.seq = seq
+	if s.active {
+		return
+	}
+	s.active = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(c.hub.talkSilence, c.fallSilent)
+	} else {
+		s.timer.Reset(c.hub.talkSilence)
+	}
+	c.hub.startTalking(c, now)
+}
+
+// fallSilent ends the client's burst if it has sent nothing for the talk
+// silence, and otherwise waits until it will have
+func (c *Client) fallSilent() {
+	s := &c.speaking
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.active {
+		return
+	}
+	if quiet := time.Since(s.last); quiet < c.hub.talkSilence {
+		s.timer.Reset(c.hub.talkSilence - quiet)
+		return
+	}
+	s.active = false
+	c.hub.stopTalking(c, eotSilence)
+}
+
+// startTalking adds the client to the room's talkers and tells the room.
+// The caller must hold the client's speaking mutex.
+func (h *Hub) startTalking(client *Client, now time.Time) {
+	h.talkersMutex.Lock()
+	h.talkers[client] = now
+	h.talkersMutex.Unlock()
+	h.talkBursts.Add(1)
+	h.announceTalk(client, talkStart)
+}
+
+// stopTalking removes the client from the room's talkers and tells the
+// room, marking the end of the burst for the reason. The caller must hold
+// the client's speaking mutex.
+func (h *Hub) stopTalking(client *Client, reason string) {
+	h.talkersMutex.Lock()
+	delete(h.talkers, client)
+	h.talkersMutex.Unlock()
+	h.announceTalk(client, talkStop)
+	h.publishEOT(client, reason)
+}
+
+// announceTalk tells every other client in the hub with the talk
+// capability the client started or stopped talking
+func (h *Hub) announceTalk(client *Client, state string) {
+	data, err := json.Marshal(talkMessage{Type: "talk", Room: localRoom(h.room), ID: client.id, State: state})
+	if err != nil {
+		log.Printf("Error encoding talk message of client %s: %v", client.id, err)
+		return
+	}
+	out := outbound{messageType: websocket.TextMessage, data: data}
+	for _, other := range h.snapshotClients() {
+		if other != client && other.caps.has(capTalk) {
+			h.shardFor(other).send(h, other, out)
+		}
+	}
+}
+
+// Talker is a client talking in a room. Since is when its burst started.
+type Talker struct {
+	ID    string    `json:"id"`
+	Name  string    `json:"name,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// Talkers returns who is talking in the hub's room, longest first
+func (h *Hub) Talkers() []Talker {
+	h.talkersMutex.Lock()
+	talkers := make([]Talker, 0, len(h.talkers))
+	for client, since := range h.talkers {
+		talkers = append(talkers, Talker{ID: client.id, Name: client.name, Since: since})
+	}
+	h.talkersMutex.Unlock()
+	sort.Slice(talkers, func(i, j int) bool { return talkers[i].Since.Before(talkers[j].Since) })
+	return talkers
+}
+
+// serveTalkers lists who is talking in an open room (GET
+// /rooms/{room}/talkers)
+func (g *Gateway) serveTalkers(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	g.mutex.Lock()
+	rm := g.rooms[name]
+	g.mutex.Unlock()
+	if rm == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rm.hub.Talkers())
+}
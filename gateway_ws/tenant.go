@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tenantSeparator joins a tenant's name to its rooms' names. Room names
+	// can't contain it, so no client can name another tenant's room.
+	tenantSeparator = ":"
+
+	// keysReloadInterval is how often the key file is checked for changes
+	keysReloadInterval = 5 * time.Second
+)
+
+var (
+	errTooManyRooms = errors.New("tenant room limit reached")
+	errKeyRequired  = errors.New("missing or invalid API key")
+)
+
+// apiKey admits connections to one tenant's rooms, and management requests
+// to its resources. A key's settings never change: a reload replaces it.
+type apiKey struct {
+	ID         string `json:"id"`
+	Tenant     string `json:"tenant"`
+	Key        string `json:"key"`
+	MaxClients int    `json:"max_clients"`
+	MaxRooms   int    `json:"max_rooms"`
+	Revoked    bool   `json:"revoked"`
+}
+
+// keyring holds the API keys from a JSON key file, reloaded when the file
+// changes, and the connections open with each
+type keyring struct {
+	path string
+
+	mutex   sync.Mutex
+	byHash  map[[sha256.Size]byte]*apiKey
+	clients map[string]int
+	modTime time.Time
+	size    int64
+}
+
+// LoadTenantKeys reads the key file at path: a JSON list of keys, each with
+// an id, a tenant, the key itself and optional max_clients and max_rooms
+func LoadTenantKeys(path string) (*keyring, error) {
+	k := &keyring{path: path, clients: make(map[string]int)}
+	if _, err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// parseKeys checks the keys in a key file
+func parseKeys(data []byte) (map[[sha256.Size]byte]*apiKey, error) {
+	var keys []*apiKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	byHash := make(map[[sha256.Size]byte]*apiKey)
+	ids := make(map[string]bool)
+	for _, key := range keys {
+		switch {
+		case key.ID == "" || ids[key.ID]:
+			return nil, fmt.Errorf("key %q: every key needs a unique id", key.ID)
+		case !validRoomName(key.Tenant):
+			return nil, fmt.Errorf("key %s: invalid tenant %q", key.ID, key.Tenant)
+		case len(key.Key) < 16:
+			return nil, fmt.Errorf("key %s: keys must be at least 16 characters", key.ID)
+		case key.MaxClients < 0 || key.MaxRooms < 0:
+			return nil, fmt.Errorf("key %s: limits must not be negative", key.ID)
+		}
+		ids[key.ID] = true
+		byHash[sha256.Sum256([]byte(key.Key))] = key
+	}
+	return byHash, nil
+}
+
+// reload rereads the key file if it changed since the last read, and
+// returns the IDs of keys that were revoked or removed by the change
+func (k *keyring) reload() (revoked []string, err error) {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	k.mutex.Lock()
+	unchanged := info.ModTime().Equal(k.modTime) && info.Size() == k.size
+	k.mutex.Unlock()
+	if unchanged {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	byHash, err := parseKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", k.path, err)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	active := make(map[string]bool)
+	for _, key := range byHash {
+		active[key.ID] = !key.Revoked
+	}
+	for _, key := range k.byHash {
+		if !key.Revoked && !active[key.ID] {
+			revoked = append(revoked, key.ID)
+		}
+	}
+	k.byHash, k.modTime, k.size = byHash, info.ModTime(), info.Size()
+	return revoked, nil
+}
+
+// lookup returns the active key presented, or nil
+func (k *keyring) lookup(presented string) *apiKey {
+	if k == nil || presented == "" {
+		return nil
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	key := k.byHash[sha256.Sum256([]byte(presented))]
+	if key == nil || key.Revoked {
+		return nil
+	}
+	return key
+}
+
+// acquire reserves a connection slot for the key. It returns false if the
+// key is at its max_clients; otherwise the returned release func gives the
+// slot back and is safe to call more than once.
+func (k *keyring) acquire(key *apiKey) (func(), bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if key.MaxClients > 0 && k.clients[key.ID] >= key.MaxClients {
+		return nil, false
+	}
+	k.clients[key.ID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			k.mutex.Lock()
+			defer k.mutex.Unlock()
+			if k.clients[key.ID]--; k.clients[key.ID] <= 0 {
+				delete(k.clients, key.ID)
+			}
+		})
+	}, true
+}
+
+// WithTenantKeys makes every upgrade present an API key from keys, which
+// confines the connection to the key's tenant's rooms, and lets management
+// requests with a key see the tenant's resources
+func WithTenantKeys(keys *keyring) GatewayOption {
+	return func(g *Gateway) {
+		g.keys = keys
+	}
+}
+
+// presentedKey returns the API key a request carries in the X-API-Key header
+// or api_key query parameter
+func presentedKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// tenantRoom returns the name the tenant's room goes by in the gateway, or
+// the room's own name for connections without a tenant
+func tenantRoom(tenant, room string) string {
+	if tenant == "" {
+		return room
+	}
+	return tenant + tenantSeparator + room
+}
+
+// localRoom strips the tenant from a room's name, as clients know it
+func localRoom(name string) string {
+	if _, room, ok := strings.Cut(name, tenantSeparator); ok {
+		return room
+	}
+	return name
+}
+
+// roomTenant returns the tenant a room belongs to, or ""
+func roomTenant(name string) string {
+	tenant, _, _ := strings.Cut(name, tenantSeparator)
+	if tenant == name {
+		return ""
+	}
+	return tenant
+}
+
+// tenant returns the tenant the client's API key confines it to, or ""
+func (c *Client) tenant() string {
+	if c.key == nil {
+		return ""
+	}
+	return c.key.Tenant
+}
+
+// qualify returns the gateway's name for a room the client named. An empty
+// name, for the client's own room, stays empty.
+func (c *Client) qualify(room string) string {
+	if room == "" {
+		return ""
+	}
+	return tenantRoom(c.tenant(), room)
+}
+
+// admitKey checks the request's API key when the gateway requires one. ok
+// is false, having answered 401, if there is no valid key; otherwise key is
+// the key, or nil if the gateway requires none.
+func (g *Gateway) admitKey(w http.ResponseWriter, r *http.Request) (key *apiKey, ok bool) {
+	if g.keys == nil {
+		return nil, true
+	}
+	key = g.keys.lookup(presentedKey(r))
+	if key == nil {
+		g.unauthorized.Add(1)
+		log.Printf("Upgrade from %s refused: %v", r.RemoteAddr, errKeyRequired)
+		g.auditRefused(r, r.Header.Get("X-Client-ID"), "", "unauthorized: "+errKeyRequired.Error())
+		http.Error(w, "unauthorized: "+errKeyRequired.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return key, true
+}
+
+// tenantRoomCount counts the tenant's open rooms. The caller must hold the
+// mutex.
+func (g *Gateway) tenantRoomCount(tenant string) int {
+	n := 0
+	for name := range g.rooms {
+		if roomTenant(name) == tenant {
+			n++
+		}
+	}
+	return n
+}
+
+// watchKeys reloads the key file when it changes, until Stop, and closes
+// the connections of keys the change revoked or removed
+func (g *Gateway) watchKeys() {
+	ticker := time.NewTicker(keysReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.quit:
+			return
+		case <-ticker.C:
+			revoked, err := g.keys.reload()
+			if err != nil {
+				// The keys already loaded stay in force
+				log.Printf("Error reloading API keys: %v", err)
+				continue
+			}
+			for _, id := range revoked {
+				g.closeKey(id)
+			}
+		}
+	}
+}
+
+// closeKey disconnects every connection opened with the key. Multi-room
+// memberships end with their connection.
+func (g *Gateway) closeKey(id string) {
+	closed := 0
+	g.eachRoom(func(rm *room) {
+		for _, client := range rm.hub.snapshotClients() {
+			if client.key != nil && client.key.ID == id && client.owner == nil {
+				client.leave(reasonKeyRevoked)
+				closed++
+			}
+		}
+	})
+	log.Printf("API key %s revoked, closed %d connections", id, closed)
+	g.audit.record(AuditEvent{Event: "key_revoked", Actor: "system", Outcome: auditOK, Reason: fmt.Sprintf("key %s, closed %d connections", id, closed)})
+}
+
+// tenantScopeKey is the context key a management request's tenant is
+// stored under
+type tenantScopeKey struct{}
+
+// withTenantScope limits a management request to the tenant's resources
+func withTenantScope(r *http.Request, tenant string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantScopeKey{}, tenant))
+}
+
+// scopedTenant returns the tenant a management request is limited to: its
+// API key's, else the one the admin asked for with ?tenant=, else "" for
+// all of them
+func scopedTenant(r *http.Request) string {
+	if tenant, ok := r.Context().Value(tenantScopeKey{}).(string); ok {
+		return tenant
+	}
+	return r.URL.Query().Get("tenant")
+}
+
+// tenantScoped reports whether a management request came with a tenant's
+// API key rather than as the admin
+func tenantScoped(r *http.Request) bool {
+	_, ok := r.Context().Value(tenantScopeKey{}).(string)
+	return ok
+}
+
+// scopedRoom returns the gateway's name for a room named in a management
+// request's path. A request with a tenant's key names the tenant's rooms as
+// its clients do; the admin names a tenant's room as tenant:room.
+func scopedRoom(r *http.Request, name string) (string, bool) {
+	if tenantScoped(r) {
+		return tenantRoom(scopedTenant(r), name), validRoomName(name)
+	}
+	if tenant, room, ok := strings.Cut(name, tenantSeparator); ok {
+		return name, validRoomName(tenant) && validRoomName(room)
+	}
+	return name, validRoomName(name)
+}
+
+// TenantStats is a tenant's share of the gateway's stats: its rooms and
+// their clients
+type TenantStats struct {
+	Tenant  string      `json:"tenant"`
+	Clients int         `json:"clients"`
+	Rooms   []RoomStats `json:"rooms"`
+
+	// Joins refused per room of the tenant that requires a credential
+	JoinDenied map[string]uint64 `json:"join_denied"`
+}
+
+// TenantStats returns counters for the tenant's rooms
+func (g *Gateway) TenantStats(tenant string) TenantStats {
+	stats := TenantStats{Tenant: tenant, Rooms: []RoomStats{}, JoinDenied: map[string]uint64{}}
+	for name, denied := range g.deniedJoins() {
+		if roomTenant(name) == tenant {
+			stats.JoinDenied[name] = denied
+		}
+	}
+	g.eachRoom(func(rm *room) {
+		if roomTenant(rm.name) != tenant {
+			return
+		}
+		hubStats := rm.hub.Stats()
+		stats.Clients += hubStats.Clients
+		stats.Rooms = append(stats.Rooms, RoomStats{Name: rm.name, HubStats: hubStats})
+	})
+	return stats
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// A listener on a poor link would rather hear a pcm16 room at 8kHz mono
+// than stutter at 48kHz, so it may join with ?tier=low, or switch with a
+// tier message, to get the room's audio at the low tier: downmixed to
+// mono, low-pass filtered below the new Nyquist frequency and decimated by
+// the smallest whole factor that brings it to 8kHz or under. Like the
+// renditions of listeners receiving another codec, each sender's frames
+// are resampled once, on its connection's readPump and only while the room
+// has a low-tier listener, and shared by all of them. The full tier, the
+// default, gets the audio untouched. A tier follows the listener across
+// room switches and into the rooms of a multi-room connection, where a
+// tier message names the room it is for.
+const (
+	tierFull = "full"
+	tierLow  = "low"
+
+	// lowTierRate is the highest sample rate the low tier delivers
+	lowTierRate = 8000
+
+	// lowTierTaps is how many taps the anti-alias filter has either side
+	// of its centre for each step of the decimation factor
+	lowTierTaps = 8
+)
+
+// tierMessage answers a tier message with the tier the client's audio now
+// comes at, and the sample rate and channel count of the room's audio at
+// it
+type tierMessage struct {
+	Type       string `json:"type"`
+	Room       string `json:"room"`
+	Tier       string `json:"tier"`
+	SampleRate int    `json:"sample_rate"`
+	Channels   int    `json:"channels"`
+}
+
+// tier switches the client's tier, full or low, in its room or on a
+// multi-room connection the named one
+func init() {
+	registerControl("tier", controlHandler{
+		validate: func(m controlMessage) error {
+			if m.Tier != tierFull && m.Tier != tierLow {
+				return fmt.Errorf("invalid tier %q: want %s or %s", m.Tier, tierFull, tierLow)
+			}
+			return nil
+		},
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.setTier(m.Tier == tierLow)
+			}
+		},
+	})
+}
+
+// requestedTier reports whether the client asked with the tier query
+// parameter for the low tier, which needs the room's audio to be pcm16 and
+// as sent
+func requestedTier(r *http.Request, canonical audioFormat, receive string) (bool, error) {
+	switch tier := strings.ToLower(r.URL.Query().Get("tier")); tier {
+	case "", tierFull:
+		return false, nil
+	case tierLow:
+		return true, lowTierAllowed(canonical, receive)
+	default:
+		return false, fmt.Errorf("invalid tier %q: want %s or %s", tier, tierFull, tierLow)
+	}
+}
+
+// lowTierAllowed checks that a listener receiving audio as receive may
+// take a room with the canonical format at the low tier
+func lowTierAllowed(canonical audioFormat, receive string) error {
+	if !strings.EqualFold(canonical.Codec, defaultCodec) {
+		return fmt.Errorf("the %s tier needs the room's audio to be %s, not %s", tierLow, defaultCodec, canonical.Codec)
+	}
+	if receive != "" {
+		return fmt.Errorf("the %s tier is for audio received as %s, not %s", tierLow, defaultCodec, receive)
+	}
+	return nil
+}
+
+// setTier moves the client to the low tier, or back to the full one, and
+// tells it the format its audio now comes in. Only readPump may call it.
+func (c *Client) setTier(low bool) {
+	if low {
+		if err := lowTierAllowed(c.hub.audio.Load().audioFormat, c.receive); err != nil {
+			c.hub.sendControl(c, controlError{Type: "error", Code: "unavailable", Request: "tier", Detail: err.Error()})
+			return
+		}
+	}
+	c.hub.setLowTier(c, low)
+	sampleRate, channels := c.deliveredFormat()
+	c.gateway.debugf("Client %s switched to the %s tier in room %s", c.id, c.tier(), c.hub.room)
+	c.hub.sendControl(c, tierMessage{Type: "tier", Room: localRoom(c.hub.room), Tier: c.tier(), SampleRate: sampleRate, Channels: channels})
+}
+
+// tier returns the client's tier, full or low
+func (c *Client) tier() string {
+	if c.lowTier.Load() {
+		return tierLow
+	}
+	return tierFull
+}
+
+// deliveredFormat returns the sample rate and channel count the client
+// gets the room's audio at
+func (c *Client) deliveredFormat() (sampleRate, channels int) {
+	format := c.hub.audio.Load().audioFormat
+	if !c.lowTier.Load() || !strings.EqualFold(format.Codec, defaultCodec) {
+		return format.SampleRate, format.Channels
+	}
+	return format.SampleRate / lowTierFactor(format.SampleRate), 1
+}
+
+// lowTierFactor returns the smallest whole factor sampleRate divides by to
+// come to lowTierRate or under, 1 for audio already there
+func lowTierFactor(sampleRate int) int {
+	factor := 1
+	for sampleRate/factor > lowTierRate || sampleRate%factor != 0 {
+		factor++
+	}
+	return factor
+}
+
+// downsampler brings one stream of pcm16 audio to the low tier. It carries
+// its filter's history and the decimation's phase from frame to frame.
+type downsampler struct {
+	channels int
+	factor   int
+	taps     []float64
+
+	// The last len(taps)-1 mono samples, the input samples to skip before
+	// the next output one, and the buffers Encode reuses
+	history []float64
+	skip    int
+	mono    []float64
+	out     []byte
+}
+
+// newDownsampler opens a downsampler for audio at sampleRate with channels
+// interleaved. Its anti-alias filter is a Hamming-windowed sinc cutting
+// off at 90% of the new Nyquist frequency.
+func newDownsampler(sampleRate, channels int) *downsampler {
+	d := &downsampler{channels: max(channels, 1), factor: lowTierFactor(sampleRate), taps: []float64{1}}
+	if d.factor > 1 {
+		n := 2*lowTierTaps*d.factor + 1
+		cutoff := 0.9 * 0.5 / float64(d.factor)
+		d.taps = make([]float64, n)
+		var sum float64
+		for i := range d.taps {
+			x := float64(i - n/2)
+			tap := 2 * cutoff
+			if x != 0 {
+				tap = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+			}
+			tap *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+			d.taps[i] = tap
+			sum += tap
+		}
+		for i := range d.taps {
+			d.taps[i] /= sum
+		}
+	}
+	d.history = make([]float64, len(d.taps)-1)
+	return d
+}
+
+// Encode returns a frame of little-endian 16-bit samples at the low tier,
+// saturating at full scale
+func (d *downsampler) Encode(pcm []byte) ([]byte, error) {
+	d.mono = append(d.mono[:0], d.history...)
+	for i := 0; i+2*d.channels <= len(pcm); i += 2 * d.channels {
+		var sum float64
+		for ch := 0; ch < d.channels; ch++ {
+			sum += float64(int16(binary.LittleEndian.Uint16(pcm[i+2*ch:])))
+		}
+		d.mono = append(d.mono, sum/float64(d.channels))
+	}
+	d.out = d.out[:0]
+	for n := len(d.history); n < len(d.mono); n++ {
+		if d.skip > 0 {
+			d.skip--
+			continue
+		}
+		d.skip = d.factor - 1
+		var sample float64
+		for k, tap := range d.taps {
+			sample += tap * d.mono[n-k]
+		}
+		sample = max(math.MinInt16, min(math.MaxInt16, math.Round(sample)))
+		d.out = binary.LittleEndian.AppendUint16(d.out, uint16(int16(sample)))
+	}
+	d.history = append(d.history[:0], d.mono[len(d.mono)-len(d.history):]...)
+	return d.out, nil
+}
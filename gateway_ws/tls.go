@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certReloader serves a certificate and key read from files, reread on
+// SIGHUP so a renewal doesn't need a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex sync.RWMutex
+	cert  *tls.Certificate
+}
+
+// LoadCertificate reads a PEM certificate chain and its private key
+func LoadCertificate(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload rereads the certificate and key. If they don't load, the ones
+// already loaded stay in use.
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+// getCertificate is the TLS config's GetCertificate, so every handshake
+// gets the certificate loaded last
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.cert, nil
+}
+
+// ParseTLSVersion parses the minimum TLS version the listener accepts
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q: want 1.2 or 1.3", version)
+}
+
+// ParseHostList parses a comma-separated list of host names
+func ParseHostList(list string) []string {
+	var hosts []string
+	for _, host := range strings.Split(list, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, strings.ToLower(host))
+		}
+	}
+	return hosts
+}
+
+// serverTLSConfig is the listener's TLS config: forward-secret AEAD cipher
+// suites only, for TLS 1.2 (TLS 1.3's can't be configured and are all
+// fine), and certificates from getCertificate
+func serverTLSConfig(minVersion uint16, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: getCertificate,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+
+		// WebSockets upgrade over HTTP/1.1, so there is no point offering h2
+		NextProtos: []string{"http/1.1"},
+	}
+}
+
+// acmeTLSConfig is the listener's TLS config with certificates for hosts
+// obtained from Let's Encrypt and cached in cacheDir. The returned manager's
+// HTTPHandler answers HTTP-01 challenges on the plain listener; TLS-ALPN-01
+// challenges are answered on the TLS one.
+func acmeTLSConfig(minVersion uint16, hosts []string, cacheDir, email string) (*tls.Config, *autocert.Manager) {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      email,
+	}
+	config := serverTLSConfig(minVersion, manager.GetCertificate)
+	config.NextProtos = append(config.NextProtos, acme.ALPNProto)
+	return config, manager
+}
+
+// redirectHandler serves the plain listener next to a TLS one: the probes
+// answer as usual, so health checks don't need certificates, and every other
+// request is redirected to the same URL over HTTPS on tlsAddr's port
+func redirectHandler(tlsAddr string, probes *http.ServeMux) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := probes.Handler(r); pattern != "" {
+			probes.ServeHTTP(w, r)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Raw pcm16 costs a listener 256kbps at 16kHz, more than a cellular link can
+// hold, so a listener on one may join a pcm16 room with ?receive=opus to get
+// the room's pcm16 audio as Opus instead. Each sender's frames are encoded
+// once, on its connection's readPump and only while the room has a listener
+// wanting the codec, whatever the number of listeners: every frame carries
+// its renditions, and each listener is sent the one in its codec. Mixed
+// audio is encoded on the room's mixer. Audio a sender sends in another
+// codec goes as it is. Encoders are compiled in by build tag, Opus with
+// -tags opus and libopus through cgo, so the base build has none and
+// refuses ?receive with 400.
+const defaultTranscodeBitrate = 24000
+
+// Transcoder encodes one stream of pcm16 audio, a frame at a time, to
+// another codec
+type Transcoder interface {
+	// Encode returns the frame's little-endian 16-bit samples encoded. The
+	// result is only good until the next call.
+	Encode(pcm []byte) ([]byte, error)
+}
+
+// TranscoderFactory opens a Transcoder for audio at the sample rate and
+// channel count, encoding at bitrate bits per second
+type TranscoderFactory func(sampleRate, channels, bitrate int) (Transcoder, error)
+
+// transcoders holds the codecs listeners may receive audio in, by
+// lowercase name. It is written only from init functions.
+var transcoders = make(map[string]TranscoderFactory)
+
+// RegisterTranscoder lets listeners receive pcm16 audio as codec. Call it
+// from an init function.
+func RegisterTranscoder(codec string, factory TranscoderFactory) {
+	transcoders[strings.ToLower(codec)] = factory
+}
+
+// transcoderNames returns the codecs listeners may receive audio in,
+// sorted
+func transcoderNames() []string {
+	names := make([]string, 0, len(transcoders))
+	for name := range transcoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithTranscodeBitrate sets the bitrate, in bits per second, audio is
+// encoded at for listeners receiving it in another codec
+func WithTranscodeBitrate(bitrate int) HubOption {
+	return func(h *Hub) {
+		if bitrate > 0 {
+			h.transcodeBitrate = bitrate
+		}
+	}
+}
+
+// rendition is a frame's audio encoded for listeners receiving it as codec,
+// or for those at the low tier if codec is tierLow, nil if it failed to
+// encode
+type rendition struct {
+	codec string
+	frame *frameBuffer
+}
+
+// encoders holds one stream's Transcoders by codec, and its downsampler
+// under tierLow, nil for a codec that failed to open. Only the goroutine
+// encoding the stream touches it.
+type encoders map[string]Transcoder
+
+// requestedReceive returns the codec the client asked with the receive
+// query parameter to get the room's audio in, "" for audio as sent. The
+// room's audio must be pcm16 and the gateway able to encode the codec.
+func requestedReceive(r *http.Request, canonical audioFormat) (string, error) {
+	codec := strings.ToLower(r.URL.Query().Get("receive"))
+	if codec == "" || strings.EqualFold(codec, canonical.Codec) {
+		return "", nil
+	}
+	if !strings.EqualFold(canonical.Codec, defaultCodec) {
+		return "", fmt.Errorf("receive needs the room's audio to be %s, not %s", defaultCodec, canonical.Codec)
+	}
+	if transcoders[codec] == nil {
+		if len(transcoders) == 0 {
+			return "", fmt.Errorf("unsupported receive codec %q: this gateway was built without transcoders", codec)
+		}
+		return "", fmt.Errorf("unsupported receive codec %q: want one of %s", codec, strings.Join(transcoderNames(), ", "))
+	}
+	return codec, nil
+}
+
+// addReceiver counts a client receiving audio in another codec or at the
+// low tier, so the room's audio is encoded for it, and any tier it moves to
+// while in the room
+func (h *Hub) addReceiver(client *Client) {
+	h.receiversMutex.Lock()
+	defer h.receiversMutex.Unlock()
+	client.receiving = true
+	h.countReceiver(client.renderedAs(), 1)
+}
+
+// removeReceiver stops counting a client addReceiver counted
+func (h *Hub) removeReceiver(client *Client) {
+	h.receiversMutex.Lock()
+	defer h.receiversMutex.Unlock()
+	if client.receiving {
+		client.receiving = false
+		h.countReceiver(client.renderedAs(), -1)
+	}
+}
+
+// setLowTier moves the client to the low tier, or back to the full one,
+// counting it under its new rendition while it is in the room
+func (h *Hub) setLowTier(client *Client, low bool) {
+	h.receiversMutex.Lock()
+	defer h.receiversMutex.Unlock()
+	if client.receiving {
+		h.countReceiver(client.renderedAs(), -1)
+	}
+	client.lowTier.Store(low)
+	if client.receiving {
+		h.countReceiver(client.renderedAs(), 1)
+	}
+}
+
+// countReceiver adds delta to the clients receiving the rendition, "" for
+// audio as sent. The caller must hold the receivers mutex.
+func (h *Hub) countReceiver(rendition string, delta int) {
+	if rendition == "" {
+		return
+	}
+	if h.receivers == nil {
+		h.receivers = make(map[string]int)
+	}
+	if h.receivers[rendition] += delta; h.receivers[rendition] <= 0 {
+		delete(h.receivers, rendition)
+	}
+	h.storeReceiveCodecs()
+}
+
+// storeReceiveCodecs publishes the renditions the room's audio is encoded
+// to, nil for none. The caller must hold the receivers mutex.
+func (h *Hub) storeReceiveCodecs() {
+	if len(h.receivers) == 0 {
+		h.receiveCodecs.Store(nil)
+		return
+	}
+	codecs := make([]string, 0, len(h.receivers))
+	for codec := range h.receivers {
+		codecs = append(codecs, codec)
+	}
+	h.receiveCodecs.Store(&codecs)
+}
+
+// transcode adds the renditions of a pcm16 frame at the sample rate and
+// channel count that the room's listeners want, encoding with the stream's
+// encoders and opening those it lacks. The frame's header must be set.
+// Only the goroutine encoding the stream may call it.
+func (h *Hub) transcode(frame *frameBuffer, encoders *encoders, sampleRate, channels int) {
+	codecs := h.receiveCodecs.Load()
+	if codecs == nil {
+		return
+	}
+	for _, codec := range *codecs {
+		encoder, opened := (*encoders)[codec]
+		if !opened {
+			var err error
+			if encoder, err = h.openTranscoder(codec, sampleRate, channels); err != nil {
+				log.Printf("Error opening %s encoder for %d Hz, %d channel audio in room %s: %v", codec, sampleRate, channels, h.room, err)
+				encoder = nil
+			}
+			if *encoders == nil {
+				*encoders = make(map[string]Transcoder)
+			}
+			(*encoders)[codec] = encoder
+		}
+		encoded := rendition{codec: codec}
+		if encoder != nil {
+			if data, err := encoder.Encode(frame.data); err == nil {
+				encoded.frame = getFrame()
+				encoded.frame.data = append(encoded.frame.data, data...)
+				encoded.frame.header = frame.header
+				encoded.frame.receivedAt = frame.receivedAt
+				if codec == tierLow {
+					h.downsampled.Add(1)
+				} else {
+					h.transcoded.Add(1)
+				}
+			} else if n := h.transcodeFailed.Add(1); n == 1 || n%100 == 0 {
+				log.Printf("Error encoding %s audio in room %s, %d frames failed: %v", codec, h.room, n, err)
+			}
+		} else {
+			h.transcodeFailed.Add(1)
+		}
+		frame.renditions = append(frame.renditions, encoded)
+	}
+}
+
+// openTranscoder opens a stream's encoder for a rendition: the codec's, or
+// a downsampler for the low tier
+func (h *Hub) openTranscoder(rendition string, sampleRate, channels int) (Transcoder, error) {
+	if rendition == tierLow {
+		return newDownsampler(sampleRate, channels), nil
+	}
+	return transcoders[rendition](sampleRate, channels, h.transcodeBitrate)
+}
+
+// transcode adds the renditions of an audio frame the client sent, once its
+// header is set, if the client sends pcm16. Only the readPump of the
+// client's connection may call it.
+func (c *Client) transcode(frame *frameBuffer) {
+	if c.hub.receiveCodecs.Load() == nil || !strings.EqualFold(c.codec(), defaultCodec) {
+		return
+	}
+	policy := c.hub.audio.Load()
+	sampleRate, channels := policy.SampleRate, policy.Channels
+	if c.format.SampleRate != 0 {
+		sampleRate = c.format.SampleRate
+	}
+	if c.format.Channels != 0 {
+		channels = c.format.Channels
+	}
+	c.hub.transcode(frame, &c.encoders, sampleRate, channels)
+}
+
+// renderedAs returns the rendition the client receives audio as: the codec
+// it asked for, tierLow at the low tier, or "" for audio as sent
+func (c *Client) renderedAs() string {
+	if c.lowTier.Load() {
+		return tierLow
+	}
+	return c.receive
+}
+
+// rendered returns a message queued for the client as it receives it: for a
+// client receiving audio in another codec or at the low tier, a frame with
+// a rendition in it swapped for that. It reports false if the frame failed
+// to encode, and takes no reference.
+func (c *Client) rendered(message outbound) (outbound, bool) {
+	as := c.renderedAs()
+	if as == "" || message.frame == nil {
+		return message, true
+	}
+	for _, encoded := range message.frame.renditions {
+		if encoded.codec == as {
+			message.frame = encoded.frame
+			return message, encoded.frame != nil
+		}
+	}
+	return message, true
+}
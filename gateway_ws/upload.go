@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// With -upload-endpoint set, finished recordings don't stay on the
+// gateway's disk: they are uploaded to a bucket of an S3-compatible object
+// store as each file is finished, a few at a time, and deleted, or moved to
+// -upload-archive-dir, only once the store confirms it holds the whole
+// file. A failed upload is retried with exponential backoff; one that runs
+// out of attempts stays on disk and is tried again by the next scan of the
+// recording directory, which also picks up what a restart left behind.
+const (
+	defaultUploadConcurrency = 4
+	defaultUploadRegion      = "us-east-1"
+
+	// uploadAttempts is how many times a file is tried before it waits for
+	// the next scan, backing off from uploadBackoff to uploadMaxBackoff
+	uploadAttempts   = 6
+	uploadBackoff    = time.Second
+	uploadMaxBackoff = time.Minute
+
+	// uploadScanInterval is how often the recording directory is scanned
+	// for finished files that aren't uploading, and uploadQueue how many
+	// may wait for an uploader
+	uploadScanInterval = time.Minute
+	uploadQueue        = 1024
+
+	// uploadTimeout bounds one request to the store
+	uploadTimeout = 10 * time.Minute
+)
+
+// recordingName matches the name of a finished recording file: the room,
+// within its tenant if it has one, the time of its first frame, its number
+// in the recording and its format
+var recordingName = regexp.MustCompile(`^(.+)-(\d{8}T\d{6}\.\d{3}Z)-(\d+)(\.wav|\.wtrec)$`)
+
+// UploadSettings says where finished recordings are uploaded: a bucket on
+// an S3-compatible endpoint, addressed by path, keys starting with Prefix,
+// and the credentials to sign with. An empty ArchiveDir deletes each file
+// once it is uploaded.
+type UploadSettings struct {
+	Endpoint     string
+	Bucket       string
+	Prefix       string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	ArchiveDir   string
+	Concurrency  int
+}
+
+// validate checks that the settings name an endpoint, a bucket and
+// credentials
+func (s UploadSettings) validate() error {
+	endpoint, err := url.Parse(s.Endpoint)
+	switch {
+	case err != nil || endpoint.Host == "" || endpoint.Scheme != "http" && endpoint.Scheme != "https":
+		return fmt.Errorf("invalid upload endpoint %q: want an http or https URL", s.Endpoint)
+	case s.Bucket == "":
+		return errors.New("uploads need a bucket")
+	case s.AccessKey == "" || s.SecretKey == "":
+		return errors.New("uploads need an access key and a secret key")
+	}
+	return nil
+}
+
+// uploader moves the finished recordings in a directory to object storage.
+// Its workers take files off a queue; a file is queued once however it is
+// found, and stays counted as pending until it is uploaded or given up on.
+type uploader struct {
+	dir      string
+	settings UploadSettings
+	client   *http.Client
+
+	// Guards queued, the files waiting or uploading
+	mutex  sync.Mutex
+	queued map[string]bool
+	queue  chan string
+
+	// Cancelled to stop, which interrupts uploads and backoffs, and done
+	// once the workers and scanner have
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	uploaded atomic.Uint64
+	failed   atomic.Uint64
+	retries  atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// StartUploader starts uploading the finished recordings in dir, those
+// there already first
+func StartUploader(dir string, settings UploadSettings) (*uploader, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	if settings.Region == "" {
+		settings.Region = defaultUploadRegion
+	}
+	if settings.Concurrency <= 0 {
+		settings.Concurrency = defaultUploadConcurrency
+	}
+	settings.Endpoint = strings.TrimRight(settings.Endpoint, "/")
+	if settings.ArchiveDir != "" {
+		if err := os.MkdirAll(settings.ArchiveDir, 0o750); err != nil {
+			return nil, fmt.Errorf("creating upload archive directory: %w", err)
+		}
+	}
+	u := &uploader{
+		dir:      dir,
+		settings: settings,
+		client:   &http.Client{Timeout: uploadTimeout},
+		queued:   make(map[string]bool),
+		queue:    make(chan string, uploadQueue),
+	}
+	u.ctx, u.cancel = context.WithCancel(context.Background())
+	for i := 0; i < settings.Concurrency; i++ {
+		u.wg.Add(1)
+		go u.work()
+	}
+	u.wg.Add(1)
+	go u.scan()
+	return u, nil
+}
+
+// WithUploader has finished recordings uploaded by u
+func WithUploader(u *uploader) GatewayOption {
+	return func(g *Gateway) {
+		g.uploader = u
+	}
+}
+
+// Close stops the uploader, abandoning uploads under way to the next
+// startup
+func (u *uploader) Close() {
+	u.cancel()
+	u.wg.Wait()
+}
+
+// enqueue queues a finished recording for upload, unless it is queued
+// already. A file the queue has no room for waits for the next scan. A nil
+// uploader uploads nothing.
+func (u *uploader) enqueue(path string) {
+	if u == nil || !recordingName.MatchString(filepath.Base(path)) {
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.queued[path] {
+		return
+	}
+	select {
+	case u.queue <- path:
+		u.queued[path] = true
+	default:
+	}
+}
+
+// dequeue forgets a file enqueue queued, so a scan may queue it again
+func (u *uploader) dequeue(path string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	delete(u.queued, path)
+}
+
+// scan queues the finished recordings in the directory at once and every
+// uploadScanInterval after
+func (u *uploader) scan() {
+	defer u.wg.Done()
+	ticker := time.NewTicker(uploadScanInterval)
+	defer ticker.Stop()
+	for {
+		entries, err := os.ReadDir(u.dir)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Error scanning %s for recordings to upload: %v", u.dir, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				u.enqueue(filepath.Join(u.dir, entry.Name()))
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
+
+// work uploads queued files until the uploader stops
+func (u *uploader) work() {
+	defer u.wg.Done()
+	for {
+		select {
+		case path := <-u.queue:
+			u.process(path)
+			u.dequeue(path)
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
+
+// process uploads a file, retrying with exponential backoff and jitter,
+// then deletes or archives it. A file that won't upload is left for the
+// next scan.
+func (u *uploader) process(path string) {
+	match := recordingName.FindStringSubmatch(filepath.Base(path))
+	var err error
+	backoff := uploadBackoff
+	for attempt := 1; ; attempt++ {
+		err = u.upload(path, match)
+		if err == nil || u.ctx.Err() != nil {
+			break
+		}
+		if attempt == uploadAttempts {
+			u.failed.Add(1)
+			log.Printf("Error uploading recording %s, retrying in %s: %v", path, uploadScanInterval, err)
+			return
+		}
+		u.retries.Add(1)
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+		backoff = min(2*backoff, uploadMaxBackoff)
+		select {
+		case <-time.After(wait):
+		case <-u.ctx.Done():
+			return
+		}
+	}
+	if err != nil {
+		return
+	}
+	if u.settings.ArchiveDir != "" {
+		err = os.Rename(path, filepath.Join(u.settings.ArchiveDir, filepath.Base(path)))
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		log.Printf("Error clearing uploaded recording %s: %v", path, err)
+	}
+}
+
+// key returns the object key a recording file whose name recordingName
+// matched is uploaded under: tenant=<tenant>/room=<room>/<start>-<number>
+// and its extension after the prefix, the tenant empty for a room without
+// one
+func (u *uploader) key(match []string) string {
+	return fmt.Sprintf("%stenant=%s/room=%s/%s-%s%s", u.settings.Prefix, roomTenant(match[1]), localRoom(match[1]), match[2], match[3], match[4])
+}
+
+// upload puts a recording file whose name recordingName matched in the
+// bucket, with its MD5 and SHA-256 for the store to check the body by, and
+// confirms the stored size
+func (u *uploader) upload(path string, match []string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	sha, md, size := sha256.New(), md5.New(), int64(0)
+	if size, err = io.Copy(io.MultiWriter(sha, md), file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := u.key(match)
+	objectURL := u.settings.Endpoint + "/" + u.settings.Bucket + "/" + key
+	ctx, cancel := context.WithTimeout(u.ctx, uploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, io.NopCloser(file))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md.Sum(nil)))
+	req.Header.Set("X-Amz-Meta-Tenant", roomTenant(match[1]))
+	req.Header.Set("X-Amz-Meta-Room", localRoom(match[1]))
+	req.Header.Set("X-Amz-Meta-Start", match[2])
+	u.sign(req, hex.EncodeToString(sha.Sum(nil)), time.Now())
+	if err := u.do(req); err != nil {
+		return err
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodHead, objectURL, nil)
+	if err != nil {
+		return err
+	}
+	emptySum := sha256.Sum256(nil)
+	u.sign(req, hex.EncodeToString(emptySum[:]), time.Now())
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("store answered %s to confirm the upload", resp.Status)
+	}
+	if stored, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err != nil || stored != size {
+		return fmt.Errorf("store holds %q bytes of the %d uploaded", resp.Header.Get("Content-Length"), size)
+	}
+	u.uploaded.Add(1)
+	u.bytes.Add(uint64(size))
+	log.Printf("Uploaded recording %s to %s", path, key)
+	return nil
+}
+
+// do sends a request to the store, which must answer with a 2xx
+func (u *uploader) do(req *http.Request) error {
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("store answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign signs a request with AWS Signature Version 4, covering every header
+// set on it and the payload's SHA-256
+func (u *uploader) sign(req *http.Request, payloadHash string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	day := stamp[:8]
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if u.settings.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", u.settings.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n" + s3CanonicalPath(req.URL.Path) + "\n\n")
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical.WriteString("\n" + signedHeaders + "\n" + payloadHash)
+
+	scope := day + "/" + u.settings.Region + "/s3/aws4_request"
+	canonicalSum := sha256.Sum256([]byte(canonical.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+	key := []byte("AWS4" + u.settings.SecretKey)
+	for _, part := range []string{day, u.settings.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.settings.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalPath URI-encodes each segment of a path as S3 signs it
+func s3CanonicalPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var encoded strings.Builder
+		for _, b := range []byte(segment) {
+			switch {
+			case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_', b == '.', b == '~':
+				encoded.WriteByte(b)
+			default:
+				fmt.Fprintf(&encoded, "%%%02X", b)
+			}
+		}
+		segments[i] = encoded.String()
+	}
+	return strings.Join(segments, "/")
+}
+
+// UploadStats counts the recordings waiting to upload or uploading, and
+// those uploaded, given up on until the next scan and retried
+type UploadStats struct {
+	Pending  int    `json:"pending"`
+	Uploaded uint64 `json:"uploaded"`
+	Bytes    uint64 `json:"bytes"`
+	Failed   uint64 `json:"failed"`
+	Retries  uint64 `json:"retries"`
+}
+
+// Stats returns the uploader's counters
+func (u *uploader) Stats() UploadStats {
+	u.mutex.Lock()
+	pending := len(u.queued)
+	u.mutex.Unlock()
+	return UploadStats{
+		Pending:  pending,
+		Uploaded: u.uploaded.Load(),
+		Bytes:    u.bytes.Load(),
+		Failed:   u.failed.Load(),
+		Retries:  u.retries.Load(),
+	}
+}
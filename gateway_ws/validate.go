@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Audio frames are sanity-checked on the read path, cheaply and without
+// decoding them: a walkie.framed.v1 header's version and lengths, a
+// multi-room room tag's length, that there is audio at all, that the
+// sender's own sequence numbers don't leap backwards, and for a client
+// sending Opus that each frame opens with a TOC byte that could be Opus.
+// An invalid frame is dropped and counted by why. The first since the
+// client last behaved gets it an invalid_frame error, and after the
+// gateway's limit in a row the connection is closed with 1008.
+const (
+	// defaultFrameViolations is how many invalid frames in a row close a
+	// connection
+	defaultFrameViolations = 10
+
+	// frameViolationWindow is how long a client must go without an invalid
+	// frame for its violations to be forgiven
+	frameViolationWindow = time.Minute
+
+	// maxSeqRewind is the furthest a sender's sequence numbers may jump
+	// back before a frame is invalid
+	maxSeqRewind = 1 << 20
+
+	// maxOpusPacket is the most audio one Opus packet may hold
+	maxOpusPacket = 120 * time.Millisecond
+)
+
+// frameInvalid is why an audio frame failed validation
+type frameInvalid int
+
+const (
+	frameBadHeader frameInvalid = iota
+	frameBadLength
+	frameBadSender
+	frameSeqRewind
+	frameBadOpus
+	frameInvalidReasons
+)
+
+// frameInvalidNames are the reasons as /stats reports them
+var frameInvalidNames = [frameInvalidReasons]string{
+	frameBadHeader: "bad_header",
+	frameBadLength: "bad_length",
+	frameBadSender: "bad_sender",
+	frameSeqRewind: "seq_rewind",
+	frameBadOpus:   "bad_opus",
+}
+
+var (
+	errNoAudio   = errors.New("audio frame is empty")
+	errSeqRewind = errors.New("audio frame sequence number jumps backwards")
+	errBadOpus   = errors.New("audio frame is not an Opus packet")
+)
+
+// invalidFrames counts audio frames dropped as invalid, by why
+type invalidFrames [frameInvalidReasons]atomic.Uint64
+
+// stats returns the counts by reason, leaving out those that are zero, or
+// nil if all are
+func (f *invalidFrames) stats() map[string]uint64 {
+	var counts map[string]uint64
+	for reason := range f {
+		if n := f[reason].Load(); n > 0 {
+			if counts == nil {
+				counts = make(map[string]uint64)
+			}
+			counts[frameInvalidNames[reason]] = n
+		}
+	}
+	return counts
+}
+
+// frameGuard is a connection's record of invalid audio frames. Only its
+// readPump touches it.
+type frameGuard struct {
+	violations int
+	last       time.Time
+}
+
+// WithFrameViolations closes a connection after that many invalid audio
+// frames in a row. Zero only ever drops them.
+func WithFrameViolations(violations int) GatewayOption {
+	return func(g *Gateway) {
+		if violations >= 0 {
+			g.frameViolations = violations
+		}
+	}
+}
+
+// invalidReason returns why a frame inbound or checkSeq refused is invalid,
+// or reports false if the error isn't about its contents
+func invalidReason(err error) (frameInvalid, bool) {
+	switch err {
+	case errFramedVersion:
+		return frameBadHeader, true
+	case errFramedShort, errMissingRoomTag, errNoAudio:
+		return frameBadLength, true
+	case errFramedSender:
+		return frameBadSender, true
+	case errSeqRewind:
+		return frameSeqRewind, true
+	}
+	return 0, false
+}
+
+// checkSeq returns errSeqRewind if the client's own number on a frame
+// leaps back from its last. Only readPump may call it, before the frame is
+// counted for loss.
+func (c *Client) checkSeq(frame *frameBuffer) error {
+	if frame.header.seq != 0 && c.upstream.rewound(frame.header.seq) {
+		return errSeqRewind
+	}
+	return nil
+}
+
+// checkAudio returns why the audio in a frame the client is about to relay
+// can't be what it sends
+func (c *Client) checkAudio(frame *frameBuffer) (frameInvalid, error) {
+	if len(frame.data) == 0 {
+		return frameBadLength, errNoAudio
+	}
+	if strings.EqualFold(c.codec(), "opus") && !validOpus(frame.data) {
+		return frameBadOpus, errBadOpus
+	}
+	return 0, nil
+}
+
+// codec returns the codec the client sends: the one it declared, or else
+// its room's
+func (c *Client) codec() string {
+	if c.format.Codec != "" {
+		return c.format.Codec
+	}
+	return c.hub.audio.Load().Codec
+}
+
+// rejectFrame counts an invalid frame from the client's connection, sent
+// into sender's room, and reports whether the connection must close. Only
+// readPump may call it.
+func (c *Client) rejectFrame(sender *Client, reason frameInvalid, err error) (exceeded bool) {
+	c.invalidFrames[reason].Add(1)
+	sender.hub.invalidFrames[reason].Add(1)
+	guard := &c.frameGuard
+	now := time.Now()
+	if now.Sub(guard.last) > frameViolationWindow {
+		guard.violations = 0
+	}
+	guard.violations++
+	guard.last = now
+
+	if limit := c.gateway.frameViolations; limit > 0 && guard.violations >= limit {
+		sender.hub.invalidFrameDisconnects.Add(1)
+		log.Printf("Client %s closed after %d invalid audio frames in room %s", c.id, guard.violations, sender.hub.room)
+		return true
+	}
+	if guard.violations == 1 {
+		log.Printf("Client %s sent an invalid audio frame in room %s: %v", c.id, sender.hub.room, err)
+		c.hub.sendControl(c, controlError{Type: "error", Code: "invalid_frame", Request: "audio", Target: localRoom(sender.hub.room),
+			Detail: fmt.Sprintf("%s: %v", frameInvalidNames[reason], err)})
+	} else {
+		c.gateway.debugf("Client %s sent an invalid audio frame in room %s: %v", c.id, sender.hub.room, err)
+	}
+	return false
+}
+
+// validOpus reports whether packet could be an Opus packet, by its TOC byte
+// and frame count alone (RFC 6716, section 3)
+func validOpus(packet []byte) bool {
+	if len(packet) == 0 {
+		return false
+	}
+	toc := packet[0]
+	switch toc & 0x3 {
+	case 0:
+		// One frame
+		return true
+	case 1:
+		// Two frames of the same size
+		return (len(packet)-1)%2 == 0
+	case 2:
+		// Two frames, the first's size in one or two bytes
+		if len(packet) < 2 {
+			return false
+		}
+		size, n := int(packet[1]), 2
+		if size >= 252 {
+			if len(packet) < 3 {
+				return false
+			}
+			size, n = size+4*int(packet[2]), 3
+		}
+		return size <= len(packet)-n
+	}
+	// Any number of frames, counted in the next byte, up to maxOpusPacket
+	// of audio
+	if len(packet) < 2 {
+		return false
+	}
+	frames := time.Duration(packet[1] & 0x3f)
+	return frames > 0 && frames*opusFrameDuration(toc>>3) <= maxOpusPacket
+}
+
+// opusFrameDuration returns the duration of each frame of an Opus packet
+// whose TOC byte has the configuration number config
+func opusFrameDuration(config byte) time.Duration {
+	switch {
+	case config < 12:
+		// SILK: 10, 20, 40 or 60 ms
+		return [...]time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16:
+		// Hybrid: 10 or 20 ms
+		return [...]time.Duration{10, 20}[config%2] * time.Millisecond
+	}
+	// CELT: 2.5, 5, 10 or 20 ms
+	return [...]time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// validOpus takes a packet by its TOC byte's frame count code, refusing
+// those whose lengths or frame counts no encoder produces
+func TestValidOpus(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		valid  bool
+	}{
+		{"empty", nil, false},
+		{"one frame", []byte{0x78, 1, 2, 3}, true},
+		{"one frame, TOC alone", []byte{0x78}, true},
+		{"two equal frames", []byte{0x79, 1, 2, 3, 4}, true},
+		{"two equal frames, odd length", []byte{0x79, 1, 2, 3}, false},
+		{"two frames", []byte{0x7a, 2, 1, 2, 3}, true},
+		{"two frames, no size", []byte{0x7a}, false},
+		{"two frames, first too long", []byte{0x7a, 4, 1, 2, 3}, false},
+		{"two frames, two byte size", []byte{0x7a, 252, 0, 1, 2}, false},
+		{"two frames, two byte size missing", []byte{0x7a, 252}, false},
+		{"counted frames", []byte{0x7b, 3, 1, 2, 3}, true},
+		{"counted frames, no count", []byte{0x7b}, false},
+		{"counted frames, none", []byte{0x7b, 0}, false},
+		// 48 frames of 2.5 ms is the most there may be
+		{"counted frames, most", []byte{0x83, 48}, true},
+		{"counted frames, too long", []byte{0x83, 49}, false},
+		// SILK frames of 60 ms, two of which is the limit
+		{"counted SILK frames, too long", []byte{0x1b, 3}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validOpus(tt.packet); got != tt.valid {
+				t.Errorf("validOpus(%x) = %t, want %t", tt.packet, got, tt.valid)
+			}
+		})
+	}
+}
+
+// checkSeq lets a sender's numbers run on, restart or wrap, and refuses a
+// jump back by more than maxSeqRewind
+func TestCheckSeq(t *testing.T) {
+	tests := []struct {
+		name      string
+		last, seq uint64
+		rewound   bool
+	}{
+		{"first frame", 0, 1 << 40, false},
+		{"unnumbered", 1 << 40, 0, false},
+		{"next", 1 << 40, 1<<40 + 1, false},
+		{"reordered", 1 << 40, 1<<40 - 3, false},
+		{"restarted", 1 << 40, 1, false},
+		{"small jump back", 1 << 40, 1<<40 - maxSeqRewind, false},
+		{"jump back by millions", 1 << 40, 1<<40 - 5_000_000, true},
+		{"16 bit numbers", 1<<16 - 2, 1500, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{id: "c"}
+			c.upstream.observe(tt.last, time.Now())
+			frame := &frameBuffer{header: frameHeader{seq: tt.seq}}
+			err := c.checkSeq(frame)
+			if got := err == errSeqRewind; got != tt.rewound {
+				t.Errorf("last %d, seq %d: got %v, want rewound %t", tt.last, tt.seq, err, tt.rewound)
+			}
+		})
+	}
+}
+
+// validateFrame runs a binary frame from the client through the checks
+// readPump makes before relaying it
+func validateFrame(c *Client, frame *frameBuffer) (frameInvalid, error) {
+	_, audio, err := c.inbound(frame)
+	if err == nil && audio {
+		err = c.checkSeq(frame)
+	}
+	if err != nil {
+		reason, _ := invalidReason(err)
+		return reason, err
+	}
+	return c.checkAudio(frame)
+}
+
+// The read path's validation never panics on what a client sends, whatever
+// its framing, and refuses a frame only for a reason it counts or a CRC that
+// doesn't match; what it passes is audio that could be Opus
+func FuzzValidateFrame(f *testing.F) {
+	h := frameHeader{sender: "c", seq: 1 << 40, received: 1700000000000}
+	audio := []byte{0x78, 1, 2, 3}
+	tagged := append([]byte{4, 'r', 'o', 'o', 'm'}, audio...)
+	f.Add(append(appendFrameHeader(nil, h), audio...), false, false, uint64(0))
+	f.Add(append(appendFrameHeader(nil, h), tagged...), true, false, uint64(1<<40-1))
+	f.Add(append(appendChecksummedHeader(nil, h, audio), audio...), false, true, uint64(0))
+	f.Add(append(appendFrameHeader(nil, h), audio...), false, false, uint64(1<<41))
+	f.Add(appendFrameHeader(nil, frameHeader{sender: "other"}), false, false, uint64(0))
+	f.Add(appendFrameHeader(nil, h), false, false, uint64(0))
+	f.Add([]byte{framedVersion, 0, 0}, true, false, uint64(0))
+	f.Add([]byte{0x7b, 0}, false, false, uint64(0))
+	f.Fuzz(func(t *testing.T, data []byte, multiRoom, integrity bool, last uint64) {
+		c := &Client{id: "c", protocol: protocolFramed, multiRoom: multiRoom, integrity: integrity}
+		c.format.Codec = "opus"
+		c.upstream.observe(last, time.Now())
+		frame := getFrame()
+		defer frame.release()
+		frame.messageType = websocket.BinaryMessage
+		frame.data = append(frame.data[:0], data...)
+
+		reason, err := validateFrame(c, frame)
+		if err != nil {
+			if err == errFramedCorrupt {
+				return
+			}
+			if _, counted := invalidReason(err); !counted && err != errBadOpus {
+				t.Fatalf("refused with uncounted error %v", err)
+			}
+			if reason < 0 || reason >= frameInvalidReasons {
+				t.Fatalf("reason %d out of range", reason)
+			}
+			return
+		}
+		if len(frame.data) == 0 || len(frame.data) > len(data) || !validOpus(frame.data) {
+			t.Fatalf("passed audio %x from %x", frame.data, data)
+		}
+	})
+}
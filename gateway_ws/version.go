@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Protocol versions are the revisions of the wire protocol, whatever the
+// subprotocol. A client states the one it speaks with a walkie.version.N
+// subprotocol offered alongside its real one, a version query parameter, or
+// a hello control message sent before anything else; one that states none
+// speaks version 1. The gateway speaks the lower of the client's and its
+// own, and tells the client which in its joined message and in reply to a
+// hello.
+const (
+	// protocolVersion is the newest version the gateway speaks
+	protocolVersion = 2
+
+	// versionControlErrors is the version from which control messages
+	// refused as malformed, unknown or invalid are answered with an error.
+	// Older clients predate them and aren't sent one.
+	versionControlErrors = 2
+
+	// legacyProtocolVersion is the version of a client that states none
+	legacyProtocolVersion = 1
+
+	// versionSubprotocolPrefix starts the subprotocol a client offers to
+	// state its version. It is never chosen as the connection's
+	// subprotocol.
+	versionSubprotocolPrefix = "walkie.version."
+)
+
+// errVersion is returned for a version that isn't a positive integer
+var errVersion = errors.New("protocol version must be a positive integer")
+
+// parseVersion parses a stated protocol version
+func parseVersion(s string) (int, error) {
+	version, err := strconv.Atoi(s)
+	if err != nil || version < 1 {
+		return 0, errVersion
+	}
+	return version, nil
+}
+
+// offeredVersion returns the protocol version the request states, from its
+// subprotocols or else its version parameter, or 0 if it states none
+func offeredVersion(r *http.Request) (int, error) {
+	for _, p := range websocket.Subprotocols(r) {
+		if s, ok := strings.CutPrefix(p, versionSubprotocolPrefix); ok {
+			return parseVersion(s)
+		}
+	}
+	if s := r.URL.Query().Get("version"); s != "" {
+		return parseVersion(s)
+	}
+	return 0, nil
+}
+
+// negotiateVersion returns the version the gateway speaks with a client
+// offering offered
+func negotiateVersion(offered int) int {
+	if offered == 0 {
+		return legacyProtocolVersion
+	}
+	return min(offered, protocolVersion)
+}
+
+// WithMinProtocolVersion closes clients that speak a protocol version below
+// version, telling them to update their app
+func WithMinProtocolVersion(version int) GatewayOption {
+	return func(g *Gateway) {
+		if version > 0 {
+			g.minVersion = version
+		}
+	}
+}
+
+// versionCounts counts the protocol versions clients offered: none stated,
+// each version the gateway speaks, and newer ones
+type versionCounts struct {
+	none  atomic.Uint64
+	known [protocolVersion]atomic.Uint64
+	newer atomic.Uint64
+}
+
+// count records a client offering version, 0 for none
+func (v *versionCounts) count(version int, delta uint64) {
+	switch {
+	case version == 0:
+		v.none.Add(delta)
+	case version > protocolVersion:
+		v.newer.Add(delta)
+	default:
+		v.known[version-1].Add(delta)
+	}
+}
+
+// stats returns the counts keyed by version, "none" and "newer"
+func (v *versionCounts) stats() map[string]uint64 {
+	stats := map[string]uint64{"none": v.none.Load(), "newer": v.newer.Load()}
+	for i := range v.known {
+		stats[strconv.Itoa(i+1)] = v.known[i].Load()
+	}
+	return stats
+}
+
+// outdated reports whether a client speaking version is below the gateway's
+// minimum
+func (g *Gateway) outdated(version int) bool {
+	return version < g.minVersion
+}
+
+// refuseOutdated upgrades a client offering an outdated version only to
+// close it with CloseOutdatedProtocol, which a browser client sees where it
+// wouldn't see an HTTP error. The client is never registered.
+func (g *Gateway) refuseOutdated(w http.ResponseWriter, r *http.Request, clientID string, version int) {
+	log.Printf("Client %s refused: protocol version %d is below %d", clientID, version, g.minVersion)
+	g.auditRefused(r, clientID, "", fmt.Sprintf("protocol version %d", version))
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.WriteControl(websocket.CloseMessage, reasonOutdatedProtocol.message(), time.Now().Add(closeAckWait))
+	conn.Close()
+}
+
+// helloMessage states a protocol version: the client's, sent as its first
+// control message, and the one negotiated, in reply
+type helloMessage struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+func init() {
+	registerControl("hello", controlHandler{
+		validate: requireField("version", func(m controlMessage) bool { return m.Version > 0 }),
+		handle:   helloReply,
+	})
+}
+
+// helloReply settles the version of a client that stated none at upgrade by
+// its first message, and answers with the negotiated version unless it is
+// outdated. A later hello changes nothing, because the client's encoding is
+// settled by then.
+func helloReply(c *Client, message controlMessage) {
+	if c.versionPending {
+		c.gateway.versions.count(0, ^uint64(0))
+		c.gateway.versions.count(message.Version, 1)
+		c.version.Store(int32(negotiateVersion(message.Version)))
+		if c.gateway.outdated(int(c.version.Load())) {
+			// settleVersion closes it
+			return
+		}
+	}
+	c.hub.sendControl(c, helloMessage{Type: "hello", Version: int(c.version.Load())})
+}
+
+// settleVersion is called by readPump once the client's first frame is
+// handled. It reports false if the version the client speaks, stated in a
+// hello or assumed without one, is below the gateway's minimum.
+func (c *Client) settleVersion() bool {
+	c.versionPending = false
+	return !c.gateway.outdated(int(c.version.Load()))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A bridge streaming audio without a push-to-talk button may join with
+// ?vox=true to have the gateway key it by its audio's level instead. Each
+// frame's RMS level is read off its pcm16 samples, or for another codec
+// taken from the level the client declares with it, a frame declaring none
+// counting as silence. A frame at or above the attack threshold opens a
+// burst; the burst's frames are relayed until its audio has stayed below
+// the release threshold for the hold time, which ends it, and everything
+// else is dropped. The client may set its own thresholds, in dBFS, and
+// hold time with vox_attack, vox_release and vox_hold; VOX is off unless it
+// asks for it.
+const (
+	defaultVOXAttack  = -30.0
+	defaultVOXRelease = -40.0
+	defaultVOXHold    = 500 * time.Millisecond
+)
+
+// voxSettings are a client's VOX thresholds, in dBFS, and hold time
+type voxSettings struct {
+	Attack  float64 `json:"attack"`
+	Release float64 `json:"release"`
+	HoldMs  int64   `json:"hold_ms"`
+}
+
+// validate checks that the thresholds are at or below full scale, release
+// no higher than attack, and the hold time positive
+func (s voxSettings) validate() error {
+	switch {
+	case s.Attack > 0 || s.Release > 0:
+		return fmt.Errorf("VOX thresholds must be at or below 0 dBFS")
+	case s.Release > s.Attack:
+		return fmt.Errorf("VOX release threshold %g dBFS above attack threshold %g dBFS", s.Release, s.Attack)
+	case s.HoldMs <= 0:
+		return fmt.Errorf("VOX hold time must be positive")
+	}
+	return nil
+}
+
+// WithVOXDefaults sets the thresholds and hold time of clients that ask for
+// VOX without setting their own
+func WithVOXDefaults(attack, release float64, hold time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		g.vox = voxSettings{Attack: attack, Release: release, HoldMs: hold.Milliseconds()}
+	}
+}
+
+// voxGate keys a VOX client's audio by its level. Only the readPump of the
+// client's connection touches it.
+type voxGate struct {
+	voxSettings
+
+	// Whether a burst is open, and when its audio was last at or above the
+	// release threshold
+	open bool
+	loud time.Time
+}
+
+// requestedVOX returns the VOX gate the client asked for with the vox query
+// parameter, with its thresholds and hold time or else defaults, or nil if
+// it didn't ask for one
+func requestedVOX(r *http.Request, defaults voxSettings) (*voxGate, error) {
+	query := r.URL.Query()
+	if value := query.Get("vox"); value == "" {
+		return nil, nil
+	} else if on, err := strconv.ParseBool(value); err != nil {
+		return nil, fmt.Errorf("invalid vox value %q", value)
+	} else if !on {
+		return nil, nil
+	}
+	settings := defaults
+	for _, threshold := range []struct {
+		name  string
+		value *float64
+	}{
+		{"vox_attack", &settings.Attack},
+		{"vox_release", &settings.Release},
+	} {
+		if value := query.Get(threshold.name); value != "" {
+			dbfs, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(dbfs) {
+				return nil, fmt.Errorf("invalid %s %q", threshold.name, value)
+			}
+			*threshold.value = dbfs
+		}
+	}
+	if value := query.Get("vox_hold"); value != "" {
+		hold, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vox_hold %q", value)
+		}
+		settings.HoldMs = hold.Milliseconds()
+	}
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	return &voxGate{voxSettings: settings}, nil
+}
+
+// passes reports whether a frame at level, in dBFS, received at now is part
+// of a burst, and whether it ended one, which it isn't part of
+func (g *voxGate) passes(level float64, now time.Time) (pass, ended bool) {
+	if !g.open {
+		if level < g.Attack {
+			return false, false
+		}
+		g.open = true
+		g.loud = now
+		return true, false
+	}
+	if level >= g.Release {
+		g.loud = now
+		return true, false
+	}
+	if now.Sub(g.loud) < time.Duration(g.HoldMs)*time.Millisecond {
+		return true, false
+	}
+	g.open = false
+	return false, true
+}
+
+// frameLevel returns the frame's RMS level in dBFS, taken from the level
+// the client declared, as a share of full scale, or else read off its
+// samples if the client sends pcm16, and whether it knows one. Silence is
+// -Inf. The level is read once a frame, for VOX, silence suppression and
+// metering alike; only the readPump of the client's connection may call
+// it.
+func (c *Client) frameLevel(frame *frameBuffer) (float64, bool) {
+	if !frame.levelRead {
+		frame.level, frame.levelKnown = c.readLevel(frame)
+		frame.levelRead = true
+	}
+	return frame.level, frame.levelKnown
+}
+
+// readLevel reads the frame's level for frameLevel
+func (c *Client) readLevel(frame *frameBuffer) (float64, bool) {
+	rms := float64(frame.header.level)
+	if rms == 0 {
+		if !strings.EqualFold(c.codec(), defaultCodec) {
+			return math.Inf(-1), false
+		}
+		rms = pcm16RMS(frame.data)
+	}
+	return 20 * math.Log10(rms), true
+}
+
+// pcm16RMS returns the RMS of little-endian 16-bit samples as a share of
+// full scale
+func pcm16RMS(data []byte) float64 {
+	samples := len(data) / 2
+	if samples == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < samples; i++ {
+		sample := float64(int16(binary.LittleEndian.Uint16(data[2*i:])))
+		sum += sample * sample
+	}
+	return math.Sqrt(sum/float64(samples)) / 32768
+}
+
+// voxAllows reports whether the VOX gate of the client's connection lets an
+// audio frame through to sender's room, ending sender's talk burst with the
+// gate's own and counting what it drops. The gate comes ahead of the talk
+// limit, which sees only bursts. A dropped frame still shows the client is
+// there, so that one streaming silence isn't closed as idle. Only readPump
+// may call it.
+func (c *Client) voxAllows(sender *Client, frame *frameBuffer, now time.Time) bool {
+	if c.vox == nil {
+		return true
+	}
+	level, _ := sender.frameLevel(frame)
+	pass, ended := c.vox.passes(level, now)
+	if ended {
+		sender.endBurst(eotSilence)
+	}
+	if !pass {
+		c.touch()
+		sender.touch()
+		sender.hub.voxDropped.Add(1)
+	}
+	return pass
+}
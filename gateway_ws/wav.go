@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// A wav recording keeps to the wall clock: audio that arrives at least
+// recordGap after the file's audio so far would have ended marks a gap in
+// transmission, which -record-gaps either fills with silence or starts a
+// new file at. A file's sizes are only filled in once it is finished, so
+// one a crash left open claims no audio; the gateway patches such files in
+// its recording directory when it starts, see repairRecordings.
+const (
+	recordGap = 250 * time.Millisecond
+
+	// recordGapsSilence fills a gap with silence, and recordGapsSplit
+	// starts a new file after it
+	recordGapsSilence = "silence"
+	recordGapsSplit   = "split"
+
+	// maxWAVBytes is where a wav file is rotated whatever -record-max-bytes
+	// says, short of the 4 GiB its sizes can count by room for a frame
+	maxWAVBytes = 1<<32 - 1<<24
+
+	// wavHeaderSize is the size of the header wavHeader writes
+	wavHeaderSize = 44
+)
+
+// wavSilence is written out to fill gaps
+var wavSilence [4096]byte
+
+// ParseRecordGaps checks how gaps in a wav recording are handled
+func ParseRecordGaps(name string) (string, error) {
+	switch name {
+	case recordGapsSilence, recordGapsSplit:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown recording gap handling %q (want silence or split)", name)
+}
+
+// wavHeader returns the 44-byte header of a 16-bit PCM WAV file holding
+// dataSize bytes of audio
+func wavHeader(format audioFormat, dataSize int64) []byte {
+	blockAlign := format.Channels * 2
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+dataSize))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(format.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(dataSize))
+	return h
+}
+
+// maxBytes returns the size at which the recorder's files are rotated
+func (r *recorder) maxBytes() int64 {
+	if r.settings.format == recordWAV {
+		return min(r.settings.maxBytes, maxWAVBytes)
+	}
+	return r.settings.maxBytes
+}
+
+// fillGap writes silence into the current wav file for any gap in
+// transmission before audio arriving at, so the file keeps to the wall
+// clock. It reports false if the gap should start a new file instead:
+// with -record-gaps split, or where the silence would take the file past
+// its size limit.
+func (r *recorder) fillGap(at time.Time) bool {
+	if r.settings.format != recordWAV {
+		return true
+	}
+	blockAlign := int64(r.format.Channels * 2)
+	sampleRate := int64(r.format.SampleRate)
+	lag := at.Sub(r.opened) - time.Duration(r.size/blockAlign*int64(time.Second)/sampleRate)
+	if lag < recordGap {
+		return true
+	}
+	if r.settings.gaps == recordGapsSplit {
+		return false
+	}
+	silence := int64(lag) * sampleRate / int64(time.Second) * blockAlign
+	if r.size+silence >= r.maxBytes() {
+		return false
+	}
+	for left := silence; left > 0; left -= int64(len(wavSilence)) {
+		if _, err := r.w.Write(wavSilence[:min(left, int64(len(wavSilence)))]); err != nil {
+			r.failed(err)
+			return true
+		}
+	}
+	r.size += silence
+	return true
+}
+
+// repairWAV fills in the sizes of a wav file the recorder wrote from its
+// length, dropping any partial sample frame at its end, and reports whether
+// it had to. A file with another layout is left alone.
+func repairWAV(path string) (bool, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	header := make([]byte, wavHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false, nil
+	}
+	blockAlign := int64(binary.LittleEndian.Uint16(header[32:]))
+	if string(header[0:4]) != "RIFF" || string(header[8:16]) != "WAVEfmt " || string(header[36:40]) != "data" || blockAlign == 0 {
+		return false, nil
+	}
+	dataSize := info.Size() - wavHeaderSize
+	dataSize = min(dataSize-dataSize%blockAlign, math.MaxUint32-36)
+	if int64(binary.LittleEndian.Uint32(header[40:])) == dataSize && info.Size() == wavHeaderSize+dataSize {
+		return false, nil
+	}
+	if err := file.Truncate(wavHeaderSize + dataSize); err != nil {
+		return false, err
+	}
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+dataSize))
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataSize))
+	if _, err := file.WriteAt(header, 0); err != nil {
+		return false, err
+	}
+	return true, file.Sync()
+}
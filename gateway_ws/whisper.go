@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// A whisper is audio for one other client in the room rather than all of
+// it. A whisper message naming a target opens one, and the client's audio
+// then goes only to that target until a whisper message naming none closes
+// it. The target is told when a whisper opens and closes, and gets the
+// audio marked private with its sender. A chat message naming a target is
+// a whisper of text.
+const (
+	// maxBlocked caps how many clients a connection may block
+	maxBlocked = 256
+)
+
+// blockList is the clients a connection takes no whispers from. A room
+// switch's successor and a multi-room client's memberships share their
+// connection's.
+type blockList struct {
+	mutex sync.RWMutex
+	ids   map[string]bool
+}
+
+// has reports whether the client with id is blocked
+func (b *blockList) has(id string) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.ids[id]
+}
+
+// set blocks or unblocks the client with id. It reports false if the list
+// is already full.
+func (b *blockList) set(id string, blocked bool) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !blocked {
+		delete(b.ids, id)
+		return true
+	}
+	if b.ids[id] {
+		return true
+	}
+	if len(b.ids) >= maxBlocked {
+		return false
+	}
+	if b.ids == nil {
+		b.ids = make(map[string]bool)
+	}
+	b.ids[id] = true
+	return true
+}
+
+// count returns how many clients are blocked
+func (b *blockList) count() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.ids)
+}
+
+// whisperMessage tells a whisper's target that it opened or closed. From is
+// the sender as the server knows it.
+type whisperMessage struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	From   string `json:"from"`
+	Active bool   `json:"active"`
+}
+
+// whisper names its target, or none to close the whisper; block and unblock
+// name the client
+func init() {
+	registerControl("whisper", controlHandler{
+		handle: func(c *Client, m controlMessage) {
+			if member := c.memberFor(m.Type, m.Room); member != nil {
+				member.openWhisper(m.Target)
+			}
+		},
+	})
+	block := controlHandler{
+		validate: func(m controlMessage) error {
+			if m.Target == "" {
+				return errors.New("missing target")
+			}
+			return nil
+		},
+		handle: func(c *Client, m controlMessage) {
+			if !c.blocks.set(m.Target, m.Type == "block") {
+				c.fail("limit_reached", m.Type, m.Target)
+			}
+		},
+	}
+	registerControl("block", block)
+	registerControl("unblock", block)
+}
+
+// whisperTarget returns the client with id in c's room, if it takes
+// whispers from c, or else the error code saying why not
+func (c *Client) whisperTarget(id string) (*Client, string) {
+	target := c.hub.lookup(id)
+	switch {
+	case target == nil || target == c:
+		return nil, "not_found"
+	case target.blocks.has(c.id):
+		return nil, "blocked"
+	}
+	return target, ""
+}
+
+// openWhisper closes the client's whisper, if it has one, and opens one to
+// the client with id, unless id is empty. Only the readPump of the client's
+// connection may call it.
+func (c *Client) openWhisper(id string) {
+	if id != "" && id == c.whisper {
+		return
+	}
+	c.closeWhisper()
+	if id == "" {
+		return
+	}
+	target, code := c.whisperTarget(id)
+	if target == nil {
+		c.hub.whispersRefused.Add(1)
+		c.fail(code, "whisper", id)
+		return
+	}
+	c.whisper = id
+	c.group = nil
+	c.hub.sendControl(target, whisperMessage{Type: "whisper", Room: localRoom(c.hub.room), From: c.id, Active: true})
+	c.gateway.debugf("Client %s opened a whisper to %s in room %s", c.id, id, c.hub.room)
+}
+
+// closeWhisper closes the client's whisper, telling its target if it is
+// still there. Only the readPump of the client's connection may call it.
+func (c *Client) closeWhisper() {
+	if c.whisper == "" {
+		return
+	}
+	if target := c.hub.lookup(c.whisper); target != nil {
+		c.hub.sendControl(target, whisperMessage{Type: "whisper", Room: localRoom(c.hub.room), From: c.id})
+	}
+	c.whisper = ""
+}
+
+// closeWhispers closes the whispers of the connection and all its
+// memberships, as readPump returns
+func (c *Client) closeWhispers() {
+	c.closeWhisper()
+	c.membersMutex.Lock()
+	members := make([]*Client, 0, len(c.members))
+	for _, member := range c.members {
+		members = append(members, member)
+	}
+	c.membersMutex.Unlock()
+	for _, member := range members {
+		member.closeWhisper()
+	}
+}
+
+// whisperAudio sends an audio frame from the client to its whisper's target
+// alone, taking over the reader's reference. The frame is numbered apart
+// from the client's audio to the room, so its room's listeners see no gap.
+// If the target has gone or blocked the client, the whisper closes. Only
+// the readPump of the client's connection may call it.
+func (c *Client) whisperAudio(frame *frameBuffer, now time.Time) {
+	defer frame.release()
+	target, code := c.whisperTarget(c.whisper)
+	if target == nil {
+		c.hub.whispersRefused.Add(1)
+		c.fail(code, "whisper", c.whisper)
+		c.closeWhisper()
+		return
+	}
+	c.addressedSeq++
+	frame.header = frameHeader{sender: c.id, seq: c.addressedSeq, received: now.UnixMilli(), captured: frame.header.captured, private: true, priority: frame.header.priority}
+	frame.receivedAt = now
+	c.transcode(frame)
+	c.whispersSent.Add(1)
+	c.hub.whispers.Add(1)
+	if c.hub.shardFor(target).send(c.hub, target, outbound{messageType: frame.messageType, frame: frame}) {
+		target.whispersReceived.Add(1)
+	}
+}
+
+// whisperChat sends a chat message from the client to message.Target alone,
+// marked private. Only the readPump of the client's connection may call it.
+func (c *Client) whisperChat(message controlMessage, now time.Time) {
+	target, code := c.whisperTarget(message.Target)
+	if target != nil && !target.caps.has(capChat) {
+		target, code = nil, "unsupported"
+	}
+	if target == nil {
+		c.hub.whispersRefused.Add(1)
+		c.fail(code, "chat", message.Target)
+		return
+	}
+	relayed := chatMessage{Type: "chat", Room: localRoom(c.hub.room), From: c.id, Text: message.Text, TS: now.UnixMilli(), Private: true}
+	c.hub.sendControl(target, relayed)
+	if message.Echo {
+		c.hub.sendControl(c, relayed)
+	}
+	c.whispersSent.Add(1)
+	target.whispersReceived.Add(1)
+	c.hub.whispers.Add(1)
+}
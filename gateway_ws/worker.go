@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultWorkers = 1
+
+// fanOutWorker delivers broadcasts to the clients of the shards it owns.
+// Every shard is owned by exactly one worker and each worker handles its
+// queue in order, so frames never reorder for a given listener.
+type fanOutWorker struct {
+	hub    *Hub
+	shards []*shard
+	queue  chan BroadcastMessage
+
+	// Time from a broadcast being queued for the hub to this worker having
+	// handed it to all its listeners
+	latency latencyGauge
+}
+
+// newWorkers splits the hub's shards between up to n workers
+func (h *Hub) newWorkers(n int) []*fanOutWorker {
+	if n > len(h.shards) {
+		n = len(h.shards)
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	workers := make([]*fanOutWorker, n)
+	for i := range workers {
+		workers[i] = &fanOutWorker{
+			hub:   h,
+			queue: make(chan BroadcastMessage, h.broadcastQueue),
+		}
+	}
+	for i, s := range h.shards {
+		w := workers[i%n]
+		w.shards = append(w.shards, s)
+	}
+	return workers
+}
+
+// startWorkers starts the hub's workers. The returned WaitGroup is done once
+// every worker queue has been closed and drained.
+func (h *Hub) startWorkers() *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, w := range h.fanOutWorkers {
+		wg.Add(1)
+		go func(w *fanOutWorker) {
+			defer wg.Done()
+			w.run()
+		}(w)
+	}
+	return &wg
+}
+
+// run delivers queued broadcasts until the queue is closed, releasing the
+// worker's reference on each frame once it has been handed to every listener
+func (w *fanOutWorker) run() {
+	for message := range w.queue {
+		for _, s := range w.shards {
+			for _, client := range s.broadcast(w.hub, message) {
+				if w.hub.removeClient(client, reasonSlowConsumer) {
+					log.Printf("Client %s evicted: send buffer full. Total clients: %d", client.id, w.hub.ClientCount())
+				}
+			}
+		}
+		if !message.queued.IsZero() {
+			now := time.Now()
+			w.latency.record(now.Sub(message.queued), now)
+		}
+		message.release()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// BenchmarkHubLoop measures how long the hub loop is busy with each frame
+// broadcast to 500 listeners: fanning it out inline, as the loop did before
+// fan-out moved to workers, against handing it to them
+func BenchmarkHubLoop(b *testing.B) {
+	const clients = 500
+	payload := pcmFrame(1)
+	b.Run("inline", func(b *testing.B) {
+		th := newTestHub(b, WithSendBuffer(1024, 1024))
+		th.attach(clients, SlowDropNewest)
+		sender := &Client{id: "talker", hub: th.Hub}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			frame := getFrame()
+			frame.data = append(frame.data[:0], payload...)
+			message := BroadcastMessage{frame: frame, sender: sender}
+			for _, s := range th.shards {
+				s.broadcast(th.Hub, message)
+			}
+			message.release()
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N), "loop-ns/frame")
+	})
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			th := newTestHub(b, WithSendBuffer(1024, 1024), WithShards(workers), WithWorkers(workers))
+			th.attach(clients, SlowDropNewest)
+			sender := &Client{id: "talker", hub: th.Hub}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				th.broadcast(sender, payload)
+			}
+			for th.handled() < int64(b.N)*clients {
+				time.Sleep(time.Millisecond)
+			}
+			avg, _ := th.loopLatency.read(time.Now())
+			b.ReportMetric(float64(avg.Nanoseconds()), "loop-ns/frame")
+		})
+	}
+}